@@ -14,7 +14,9 @@ require (
 	github.com/goki/ki v1.1.15
 	github.com/goki/mat32 v1.0.15
 	github.com/goki/vgpu v1.0.33
+	github.com/klauspost/compress v1.13.1
 	golang.org/x/image v0.6.0
+	gonum.org/v1/plot v0.12.0
 )
 
 require (
@@ -50,6 +52,7 @@ require (
 	github.com/goki/vci v1.0.1 // indirect
 	github.com/goki/vulkan v1.0.6 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
+	github.com/golang/snappy v0.0.3 // indirect
 	github.com/gorilla/css v1.0.0 // indirect
 	github.com/h2non/filetype v1.1.3 // indirect
 	github.com/iancoleman/strcase v0.2.0 // indirect
@@ -65,5 +68,4 @@ require (
 	golang.org/x/tools v0.7.0 // indirect
 	golang.org/x/xerrors v0.0.0-20220907171357-04be3eba64a2 // indirect
 	gonum.org/v1/gonum v0.12.0 // indirect
-	gonum.org/v1/plot v0.12.0 // indirect
 )
@@ -0,0 +1,204 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// export.go has the export of the epoch logs to a standardized long-format
+// results schema, in CSV and / or Parquet, for downstream analysis
+// pipelines (pandas, R), with one row per run, epoch, and metric:
+//
+//	Sim     string  network name, which is the prefix of the log file names
+//	RunName string  name of the run (params, tag), from the RunName column
+//	Mode    string  Train or Test
+//	Run     int32   run number
+//	Epoch   int32   epoch number within the run
+//	Metric  string  name of the log column, e.g., PctErr, TstPctErr
+//	Value   double  value of the metric
+//
+// All scalar numeric columns other than the counters are metrics, and NaN
+// values (stats not computed in a given epoch) are omitted.  The epoch logs
+// of each run are exported at the end of the run per the -export arg, and
+// the -exportlogs arg converts saved epoch log files from any of the lvis
+// sims, which all use the same etable .tsv format.  The logs of this sim
+// have no RunName column, so the RunName is set from the Tag and ParamSet
+// of the run, or from the log file name.  Everything other than the Sim
+// methods is a copy of export.go in the axon version of this sim, which
+// defines the schema -- any change must be made to both copies.
+
+// ResultCols are the column names of the long-format results
+var ResultCols = []string{"Sim", "RunName", "Mode", "Run", "Epoch", "Metric", "Value"}
+
+// ResultCounters are the log columns that are not exported as metrics
+var ResultCounters = map[string]bool{"Run": true, "Epoch": true, "Trial": true, "Cycle": true}
+
+// ResultRow is one row of the long-format results
+type ResultRow struct {
+	Sim, RunName, Mode string
+	Run, Epoch         int
+	Metric             string
+	Value              float64
+}
+
+// ResultRows returns the long-format results for the given
+// epoch log table, for given sim name and mode
+func ResultRows(dt *etable.Table, sim, mode string) []ResultRow {
+	var rows []ResultRow
+	for ri := 0; ri < dt.Rows; ri++ {
+		run := int(dt.CellFloat("Run", ri))
+		epc := int(dt.CellFloat("Epoch", ri))
+		runName := dt.CellString("RunName", ri)
+		for ci, cl := range dt.Cols {
+			nm := dt.ColNames[ci]
+			if ResultCounters[nm] || cl.NumDims() != 1 || cl.DataType() == etensor.STRING {
+				continue
+			}
+			v := cl.FloatVal1D(ri)
+			if math.IsNaN(v) {
+				continue
+			}
+			rows = append(rows, ResultRow{Sim: sim, RunName: runName, Mode: mode, Run: run, Epoch: epc, Metric: nm, Value: v})
+		}
+	}
+	return rows
+}
+
+// SaveResultsCSV saves the results rows to a CSV file, with a header
+func SaveResultsCSV(fnm string, rows []ResultRow) error {
+	f, err := os.Create(fnm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write(ResultCols)
+	for _, r := range rows {
+		w.Write([]string{r.Sim, r.RunName, r.Mode, strconv.Itoa(r.Run), strconv.Itoa(r.Epoch), r.Metric, strconv.FormatFloat(r.Value, 'g', -1, 64)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// SaveResultsParquet saves the results rows to a Parquet file
+func SaveResultsParquet(fnm string, rows []ResultRow) error {
+	n := len(rows)
+	sim := &ParquetCol{Name: "Sim", Type: ParquetByteArray, Strings: make([]string, n)}
+	runName := &ParquetCol{Name: "RunName", Type: ParquetByteArray, Strings: make([]string, n)}
+	mode := &ParquetCol{Name: "Mode", Type: ParquetByteArray, Strings: make([]string, n)}
+	run := &ParquetCol{Name: "Run", Type: ParquetInt32, Ints: make([]int32, n)}
+	epc := &ParquetCol{Name: "Epoch", Type: ParquetInt32, Ints: make([]int32, n)}
+	metric := &ParquetCol{Name: "Metric", Type: ParquetByteArray, Strings: make([]string, n)}
+	val := &ParquetCol{Name: "Value", Type: ParquetDouble, Floats: make([]float64, n)}
+	for i, r := range rows {
+		sim.Strings[i] = r.Sim
+		runName.Strings[i] = r.RunName
+		mode.Strings[i] = r.Mode
+		run.Ints[i] = int32(r.Run)
+		epc.Ints[i] = int32(r.Epoch)
+		metric.Strings[i] = r.Metric
+		val.Floats[i] = r.Value
+	}
+	return SaveParquet(fnm, []*ParquetCol{sim, runName, mode, run, epc, metric, val})
+}
+
+// SaveResults saves the results rows to files with given base name
+// (without extension) in each of the given formats: csv and / or parquet,
+// which defaults to csv if empty
+func SaveResults(base string, rows []ResultRow, formats []string) error {
+	if len(formats) == 0 {
+		formats = []string{"csv"}
+	}
+	for _, fm := range formats {
+		var err error
+		fnm := base + "." + fm
+		switch fm {
+		case "csv":
+			err = SaveResultsCSV(fnm, rows)
+		case "parquet":
+			err = SaveResultsParquet(fnm, rows)
+		default:
+			err = fmt.Errorf("unknown results format: %s (csv or parquet)", fm)
+		}
+		if err != nil {
+			return err
+		}
+		mpi.Printf("Saved %d results rows to: %s\n", len(rows), fnm)
+	}
+	return nil
+}
+
+// ExportResults exports the train and test epoch logs of the current run
+// in the long-format results schema, if ExportFmts is set (in nogui mode,
+// on MPI rank 0) -- called at the end of the run
+func (ss *Sim) ExportResults() {
+	if len(ss.ExportFmts) == 0 || !ss.NoGui || mpi.WorldRank() != 0 {
+		return
+	}
+	sim := ss.Net.Nm
+	runName := ss.RunName()
+	rows := ResultRows(ss.TrnEpcLog, sim, "Train")
+	rows = append(rows, ResultRows(ss.TstEpcLog, sim, "Test")...)
+	for i := range rows {
+		rows[i].RunName = runName
+	}
+	base := fmt.Sprintf("%s_%s_results_%03d", sim, runName, ss.TrainEnv.Run.Cur)
+	if err := SaveResults(base, rows, ss.ExportFmts); err != nil {
+		mpi.Println(err)
+	}
+}
+
+// ExportLogs converts the given saved epoch log files, from any of the
+// lvis sims, to the long-format results schema, saved next to each file
+// with a _results suffix per ExportFmts.  Test epoch logs are recognized
+// by tst in the file name, the sim is the file name prefix, and the
+// RunName, if there is no RunName column, is the rest of the file name
+// before the _trn_epc or _tst_epc suffix of LogFileName.
+func (ss *Sim) ExportLogs(fnms []string) error {
+	for _, fnm := range fnms {
+		dt := &etable.Table{}
+		if err := dt.OpenCSV(gi.FileName(fnm), etable.Tab); err != nil {
+			return err
+		}
+		base := strings.TrimSuffix(fnm, filepath.Ext(fnm))
+		nm := filepath.Base(base)
+		mode := "Train"
+		if strings.Contains(nm, "tst") {
+			mode = "Test"
+		}
+		sim, runName := nm, ""
+		if i := strings.Index(nm, "_"); i > 0 {
+			sim = nm[:i]
+			runName = nm[i+1:]
+			for _, sfx := range []string{"_trn_epc", "_tst_epc"} {
+				if j := strings.LastIndex(runName, sfx); j >= 0 {
+					runName = runName[:j]
+				}
+			}
+		}
+		rows := ResultRows(dt, sim, mode)
+		if dt.ColByName("RunName") == nil {
+			for i := range rows {
+				rows[i].RunName = runName
+			}
+		}
+		if err := SaveResults(base+"_results", rows, ss.ExportFmts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExportLogs(t *testing.T) {
+	dir := t.TempDir()
+	fnm := filepath.Join(dir, "LVis_Base_tst_epc.tsv")
+	tsv := "|Run\t|Epoch\t#PctErr\t#CosDiff\n0\t5\t0.25\tNaN\n"
+	if err := os.WriteFile(fnm, []byte(tsv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ss := &Sim{}
+	if err := ss.ExportLogs([]string{fnm}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filepath.Join(dir, "LVis_Base_tst_epc_results.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	recs, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{ResultCols, {"LVis", "Base", "Test", "0", "5", "PctErr", "0.25"}}
+	if !reflect.DeepEqual(recs, want) {
+		t.Errorf("ExportLogs: got: %v, want: %v", recs, want)
+	}
+}
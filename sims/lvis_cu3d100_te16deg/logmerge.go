@@ -0,0 +1,150 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// logmerge.go has the merging of the per-proc log files saved with
+// SaveProcLog (-proclog) under MPI, into a single log file, run with
+// the -merge arg.  The rows from all procs are concatenated, with a Rank
+// column added, rows duplicated across procs (all values other than Rank
+// the same) are removed, the rows are sorted by Run, Epoch, Trial, and
+// Rank, and the number of rows per epoch is verified to be the same on
+// every proc, and to match the expected total, if known.
+
+// MergeSortCols are the columns used to sort the merged log, if present
+var MergeSortCols = []string{"Run", "Epoch", "Trial", "Rank"}
+
+// ProcLogFileName returns the name of the log file for given MPI rank,
+// from the rank 0 file name, per LogFileName
+func ProcLogFileName(fnm string, rank int) string {
+	if rank == 0 {
+		return fnm
+	}
+	return strings.TrimSuffix(fnm, ".tsv") + fmt.Sprintf("_%d.tsv", rank)
+}
+
+// MergeRowKey returns a string key with all the values in given row,
+// except the Rank column, for detecting rows duplicated across procs
+func MergeRowKey(dt *etable.Table, row int) string {
+	var b strings.Builder
+	for ci, cl := range dt.Cols {
+		if dt.ColNames[ci] == "Rank" {
+			continue
+		}
+		csz := cl.Len() / dt.Rows
+		for i := row * csz; i < (row+1)*csz; i++ {
+			b.WriteString(cl.StringVal1D(i))
+			b.WriteByte('\t')
+		}
+	}
+	return b.String()
+}
+
+// MergeProcLogs merges the per-proc log files for nprocs MPI procs, given
+// the rank 0 file name, saving the result with a _merged suffix.  If expect
+// is > 0, it is the expected number of rows per Run, Epoch across all
+// procs, e.g., the total trials per epoch for a trial log.  Returns the
+// merged file name, and the number of consistency problems found.
+func MergeProcLogs(fnm string, nprocs, expect int) (string, int, error) {
+	var dt *etable.Table
+	for rank := 0; rank < nprocs; rank++ {
+		pfnm := ProcLogFileName(fnm, rank)
+		pdt := &etable.Table{}
+		if err := pdt.OpenCSV(gi.FileName(pfnm), etable.Tab); err != nil {
+			return "", 0, err
+		}
+		rk := etensor.NewInt64([]int{pdt.Rows}, nil, nil)
+		for i := range rk.Values {
+			rk.Values[i] = int64(rank)
+		}
+		pdt.AddCol(rk, "Rank")
+		fmt.Printf("Read %d rows from: %s\n", pdt.Rows, pfnm)
+		if dt == nil {
+			dt = pdt
+			continue
+		}
+		if strings.Join(pdt.ColNames, ",") != strings.Join(dt.ColNames, ",") {
+			return "", 0, fmt.Errorf("MergeProcLogs: %s columns do not match %s", pfnm, fnm)
+		}
+		dt.AppendRows(pdt)
+	}
+	ix := etable.NewIdxView(dt)
+	seen := make(map[string]bool, dt.Rows)
+	ndup := 0
+	ix.Filter(func(et *etable.Table, row int) bool {
+		key := MergeRowKey(et, row)
+		if seen[key] {
+			ndup++
+			return false
+		}
+		seen[key] = true
+		return true
+	})
+	if ndup > 0 {
+		fmt.Printf("Removed %d duplicate rows\n", ndup)
+	}
+	var scols []string
+	for _, cn := range MergeSortCols {
+		if dt.ColIdx(cn) >= 0 {
+			scols = append(scols, cn)
+		}
+	}
+	ix.SortColNames(scols, true)
+	mdt := ix.NewTable()
+
+	nprob := 0
+	if mdt.ColIdx("Epoch") >= 0 {
+		type epcKey struct{ run, epc int }
+		counts := make(map[epcKey][]int)
+		var keys []epcKey
+		hasRun := mdt.ColIdx("Run") >= 0
+		for row := 0; row < mdt.Rows; row++ {
+			k := epcKey{epc: int(mdt.CellFloat("Epoch", row))}
+			if hasRun {
+				k.run = int(mdt.CellFloat("Run", row))
+			}
+			cts, has := counts[k]
+			if !has {
+				cts = make([]int, nprocs)
+				counts[k] = cts
+				keys = append(keys, k)
+			}
+			cts[int(mdt.CellFloat("Rank", row))]++
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].run < keys[j].run || (keys[i].run == keys[j].run && keys[i].epc < keys[j].epc)
+		})
+		for _, k := range keys {
+			cts := counts[k]
+			tot := 0
+			eq := true
+			for _, c := range cts {
+				tot += c
+				eq = eq && c == cts[0]
+			}
+			if !eq || (expect > 0 && tot != expect) {
+				fmt.Printf("Run: %d Epoch: %d: %d rows, expected: %d, per rank: %v\n", k.run, k.epc, tot, expect, cts)
+				nprob++
+			}
+		}
+		fmt.Printf("Verified %d epochs: %d with inconsistent row counts\n", len(keys), nprob)
+	}
+
+	mfnm := strings.TrimSuffix(fnm, ".tsv") + "_merged.tsv"
+	if err := mdt.SaveCSV(gi.FileName(mfnm), etable.Tab, etable.Headers); err != nil {
+		return "", nprob, err
+	}
+	fmt.Printf("Saved %d merged rows to: %s\n", mdt.Rows, mfnm)
+	return mfnm, nprob, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+func TestMergeProcLogs(t *testing.T) {
+	fnm := filepath.Join(t.TempDir(), "LVis_Base_trl.tsv")
+	// Epoch, Trial rows of each rank: rank 1 repeats the last row of rank 0
+	procs := [][][2]int{
+		{{0, 0}, {0, 1}, {1, 0}, {1, 1}},
+		{{0, 3}, {0, 2}, {1, 2}, {1, 1}},
+	}
+	for rank, rows := range procs {
+		dt := &etable.Table{}
+		dt.SetFromSchema(etable.Schema{
+			{"Run", etensor.INT64, nil, nil},
+			{"Epoch", etensor.INT64, nil, nil},
+			{"Trial", etensor.INT64, nil, nil},
+			{"TrialName", etensor.STRING, nil, nil},
+		}, len(rows))
+		for ri, et := range rows {
+			dt.SetCellFloat("Epoch", ri, float64(et[0]))
+			dt.SetCellFloat("Trial", ri, float64(et[1]))
+			dt.SetCellString("TrialName", ri, "img")
+		}
+		if err := dt.SaveCSV(gi.FileName(ProcLogFileName(fnm, rank)), etable.Tab, etable.Headers); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		expect, nprob int
+	}{
+		{0, 1}, // epoch 1 has 2 rows on rank 0 and 1 on rank 1
+		{4, 1}, // and 3 rows in total
+		{3, 2}, // and epoch 0 has 4
+	}
+	for _, tt := range tests {
+		mfnm, nprob, err := MergeProcLogs(fnm, 2, tt.expect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nprob != tt.nprob {
+			t.Errorf("MergeProcLogs expect: %d problems: %d, want: %d", tt.expect, nprob, tt.nprob)
+		}
+		mdt := &etable.Table{}
+		if err := mdt.OpenCSV(gi.FileName(mfnm), etable.Tab); err != nil {
+			t.Fatal(err)
+		}
+		want := [][3]int{{0, 0, 0}, {0, 1, 0}, {0, 2, 1}, {0, 3, 1}, {1, 0, 0}, {1, 1, 0}, {1, 2, 1}}
+		if mdt.Rows != len(want) {
+			t.Fatalf("merged rows: %d, want: %d", mdt.Rows, len(want))
+		}
+		for ri, w := range want {
+			got := [3]int{int(mdt.CellFloat("Epoch", ri)), int(mdt.CellFloat("Trial", ri)), int(mdt.CellFloat("Rank", ri))}
+			if got != w {
+				t.Errorf("merged row %d: Epoch, Trial, Rank: %v, want: %v", ri, got, w)
+			}
+		}
+	}
+}
+
+func TestProcLogFileName(t *testing.T) {
+	if fnm := ProcLogFileName("LVis_Base_trl.tsv", 0); fnm != "LVis_Base_trl.tsv" {
+		t.Errorf("ProcLogFileName rank 0: %s", fnm)
+	}
+	if fnm := ProcLogFileName("LVis_Base_trl.tsv", 3); fnm != "LVis_Base_trl_3.tsv" {
+		t.Errorf("ProcLogFileName rank 3: %s", fnm)
+	}
+}
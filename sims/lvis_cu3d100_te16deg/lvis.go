@@ -215,6 +215,26 @@ var ParamSets = params.Sets{
 				}},
 		},
 	}},
+	{Name: "LongMinus", Desc: "longer minus phase settling: 5 quarters, for settling-time experiments", Sheets: params.Sheets{
+		"Sim": &params.Sheet{
+			{Sel: "Sim", Desc: "quarters and cycles per trial",
+				Params: params.Params{
+					"Sim.MinusQtrs": "5",
+					"Sim.PlusQtrs":  "1",
+					"Sim.CycPerQtr": "25",
+				}},
+		},
+	}},
+	{Name: "ShortMinus", Desc: "shorter minus phase settling: 2 quarters, for settling-time experiments", Sheets: params.Sheets{
+		"Sim": &params.Sheet{
+			{Sel: "Sim", Desc: "quarters and cycles per trial",
+				Params: params.Params{
+					"Sim.MinusQtrs": "2",
+					"Sim.PlusQtrs":  "1",
+					"Sim.CycPerQtr": "25",
+				}},
+		},
+	}},
 }
 
 // Sim encapsulates the entire simulation model, and we define all the
@@ -247,33 +267,46 @@ type Sim struct {
 	StartRun         int               `desc:"starting run number -- typically 0 but can be set in command args for parallel runs on a cluster"`
 	MaxRuns          int               `desc:"maximum number of model runs to perform"`
 	MaxEpcs          int               `desc:"maximum number of epochs to run per model run"`
-	MaxTrls          int               `desc:"maximum number of training trials per epoch"`
+	MaxTrls          int               `desc:"maximum number of training trials per epoch, on each MPI proc -- computed from TotTrls if 0"`
+	TotTrls          int               `desc:"total number of training trials per epoch across all MPI procs, which is allocated to MaxTrls per proc by Trials -- default 512"`
+	Trials           TrialsAllocator   `view:"-" desc:"allocation of TotTrls across MPI procs"`
 	NZeroStop        int               `desc:"if a positive number, training will stop after this many epochs with zero SSE"`
 	TrainEnv         ImagesEnv         `desc:"Training environment"`
 	TestEnv          ImagesEnv         `desc:"Testing environment"`
+	EnvType          string            `desc:"type of env: Images (or empty) for the standard ImagesEnv, or the name of a custom LvisEnv type registered in LvisEnvTypes -- see lvisenv.go"`
+	TrainLvEnv       LvisEnv           `view:"-" desc:"env that drives training: TrainEnv or a custom EnvType env"`
+	TestLvEnv        LvisEnv           `view:"-" desc:"env that drives testing: TestEnv or a custom EnvType env"`
 	Time             leabra.Time       `desc:"leabra timing parameters and state"`
+	MinusQtrs        int               `desc:"number of quarters in the minus phase of each trial (alpha cycle) -- 3 is standard -- ActM is recorded at the end of the last one, and ActQ1, ActQ2 at the end of the two before it -- can be set in the Sim params sheet for settling-time experiments"`
+	PlusQtrs         int               `desc:"number of quarters in the plus phase of each trial (alpha cycle) -- 1 is standard -- ActP is recorded at the end of the last one"`
+	CycPerQtr        int               `desc:"number of cycles per quarter -- 25 is standard -- sets Time.CycPerQtr"`
 	TestInterval     int               `desc:"how often to run through the test patterns, in terms of training epochs -- can use 0 or -1 for no testing"`
 	ViewOn           bool              `desc:"whether to update the network view while running"`
 	TrainUpdt        leabra.TimeScales `desc:"at what time scale to update the display during training?  Anything longer than Epoch updates at Epoch in this model"`
 	TestUpdt         leabra.TimeScales `desc:"at what time scale to update the display during testing?  Anything longer than Epoch updates at Epoch in this model"`
 	OutLays          []string          `view:"-" desc:"output layers -- for stats"`
 	HidLays          []string          `view:"-" desc:"hidden layers -- for all main stats"`
+	FirstCycLays     []string          `view:"-" desc:"hidden and output layers -- for first activity cycle stats"`
+	FirstActThr      float32           `desc:"threshold on layer-wide maximum activation for the first activity cycle stat"`
 	ActRFNms         []string          `desc:"names of layers to compute activation rfields on"`
 
 	// statistics: note use float64 as that is best for etable.Table
-	TrlErr        float64   `inactive:"+" desc:"1 if trial was error, 0 if correct -- based on max out unit"`
-	TrlSSE        float64   `inactive:"+" desc:"current trial's sum squared error"`
-	TrlAvgSSE     float64   `inactive:"+" desc:"current trial's average sum squared error"`
-	TrlCosDiff    float64   `inactive:"+" desc:"current trial's cosine difference"`
-	EpcSSE        float64   `inactive:"+" desc:"last epoch's total sum squared error"`
-	EpcAvgSSE     float64   `inactive:"+" desc:"last epoch's average sum squared error (average over trials, and over units within layer)"`
-	EpcPctErr     float64   `inactive:"+" desc:"last epoch's average TrlErr"`
-	EpcPctCor     float64   `inactive:"+" desc:"1 - last epoch's average TrlErr"`
-	EpcCosDiff    float64   `inactive:"+" desc:"last epoch's average cosine difference for output layer (a normalized error measure, maximum of 1 when the minus phase exactly matches the plus)"`
-	EpcPerTrlMSec float64   `inactive:"+" desc:"how long did the epoch take per trial in wall-clock milliseconds"`
-	FirstZero     int       `inactive:"+" desc:"epoch at when SSE first went to zero"`
-	NZero         int       `inactive:"+" desc:"number of epochs in a row with zero SSE"`
-	HidGeMaxM     []float64 `view:"-" desc:"trial-level GeMaxM (minus phase Ge max)"`
+	TrlErr         float64   `inactive:"+" desc:"1 if trial was error, 0 if correct -- based on max out unit"`
+	TrlSSE         float64   `inactive:"+" desc:"current trial's sum squared error"`
+	TrlAvgSSE      float64   `inactive:"+" desc:"current trial's average sum squared error"`
+	TrlCosDiff     float64   `inactive:"+" desc:"current trial's cosine difference"`
+	EpcSSE         float64   `inactive:"+" desc:"last epoch's total sum squared error"`
+	EpcAvgSSE      float64   `inactive:"+" desc:"last epoch's average sum squared error (average over trials, and over units within layer)"`
+	EpcPctErr      float64   `inactive:"+" desc:"last epoch's average TrlErr"`
+	EpcPctCor      float64   `inactive:"+" desc:"1 - last epoch's average TrlErr"`
+	EpcCosDiff     float64   `inactive:"+" desc:"last epoch's average cosine difference for output layer (a normalized error measure, maximum of 1 when the minus phase exactly matches the plus)"`
+	EpcPerTrlMSec  float64   `inactive:"+" desc:"how long did the epoch take per trial in wall-clock milliseconds"`
+	EpcPerCycMSec  float64   `inactive:"+" desc:"EpcPerTrlMSec divided by the number of cycles per trial, for comparing runs with different MinusQtrs, PlusQtrs, CycPerQtr"`
+	FirstZero      int       `inactive:"+" desc:"epoch at when SSE first went to zero"`
+	NZero          int       `inactive:"+" desc:"number of epochs in a row with zero SSE"`
+	HidGeMaxM      []float64 `view:"-" desc:"trial-level GeMaxM (minus phase Ge max)"`
+	TrlFirstCorCyc float64   `inactive:"+" desc:"first cycle in the minus phase at which the maximally active Output unit is the correct target -- number of cycles if never"`
+	TrlFirstActCyc []float64 `view:"-" desc:"first cycle at which the maximum activation of each FirstCycLays layer exceeds FirstActThr -- number of cycles if never"`
 
 	// internal state - view:"-"
 	Win          *gi.Window                    `view:"-" desc:"main GUI window"`
@@ -303,6 +336,7 @@ type Sim struct {
 
 	UseMPI      bool      `view:"-" desc:"if true, use MPI to distribute computation across nodes"`
 	SaveProcLog bool      `view:"-" desc:"if true, save logs per processor"`
+	ExportFmts  []string  `view:"-" desc:"formats to export the train and test epoch logs of each run to, in the long-format results schema (Sim, RunName, Mode, Run, Epoch, Metric, Value), for downstream analysis: csv and / or parquet -- none if empty.  Saved as results_<run> files at the end of each run (in nogui mode) -- see export.go"`
 	Comm        *mpi.Comm `view:"-" desc:"mpi communicator"`
 	AllDWts     []float32 `view:"-" desc:"buffer of all dwt weight changes -- for mpi sharing"`
 	SumDWts     []float32 `view:"-" desc:"buffer of MPI summed dwt weight changes"`
@@ -380,6 +414,10 @@ func (ss *Sim) New() {
 	ss.ViewOn = true
 	ss.TrainUpdt = leabra.Quarter
 	ss.TestUpdt = leabra.Quarter
+	ss.MinusQtrs = 3
+	ss.PlusQtrs = 1
+	ss.CycPerQtr = 25
+	ss.FirstActThr = 0.5
 	ss.ActRFNms = []string{"V4f16:Image", "V4f8:Output", "TEO8:Image", "TEO8:Output", "TEO16:Image", "TEO16:Output"}
 }
 
@@ -408,7 +446,12 @@ func (ss *Sim) ConfigEnv() {
 		ss.NZeroStop = -1
 	}
 	if ss.MaxTrls == 0 { // allow user override
-		ss.MaxTrls = 512 / mpi.WorldSize()
+		if ss.TotTrls == 0 {
+			ss.TotTrls = 512
+		}
+		ss.Trials = *NewTrialsAllocator(ss.TotTrls, 1, mpi.WorldSize())
+		mpi.Printf("%s\n", ss.Trials.String())
+		ss.MaxTrls = ss.Trials.PerProc
 	}
 
 	path := "images/CU3D_100_plus_renders"
@@ -464,6 +507,14 @@ func (ss *Sim) ConfigEnv() {
 
 	ss.TrainEnv.Init(0)
 	ss.TestEnv.Init(0)
+
+	ss.TrainLvEnv = &ss.TrainEnv
+	ss.TestLvEnv = &ss.TestEnv
+	if !ss.IsImagesEnv() {
+		if err := ss.ConfigCustomEnv(); err != nil {
+			log.Fatalln(err)
+		}
+	}
 }
 
 func (ss *Sim) ConfigNet(net *leabra.Network) {
@@ -597,6 +648,7 @@ func (ss *Sim) ConfigNet(net *leabra.Network) {
 			ss.HidLays = append(ss.HidLays, ly.Name())
 		}
 	}
+	ss.FirstCycLays = append(append([]string{}, ss.HidLays...), ss.OutLays...)
 
 	v4f16.SetThread(1)
 	v4f8.SetThread(1)
@@ -638,6 +690,7 @@ func (ss *Sim) Init() {
 	ss.InitRndSeed()
 	ss.StopNow = false
 	ss.SetParams("", false) // all sheets
+	ss.CheckQtrs()
 	ss.NewRun()
 	ss.UpdateView(true)
 }
@@ -664,9 +717,9 @@ func (ss *Sim) NewRndSeed() {
 // and add a few tabs at the end to allow for expansion..
 func (ss *Sim) Counters(train bool) string {
 	if train {
-		return fmt.Sprintf("Run:\t%d\tEpoch:\t%d\tTrial:\t%d\tCycle:\t%d\tName:\t%s\t\t\t", ss.TrainEnv.Run.Cur, ss.TrainEnv.Epoch.Cur, ss.TrainEnv.Trial.Cur, ss.Time.Cycle, ss.TrainEnv.String())
+		return fmt.Sprintf("Run:\t%d\tEpoch:\t%d\tTrial:\t%d\tCycle:\t%d\tName:\t%s\t\t\t", ss.TrainEnv.Run.Cur, ss.TrainEnv.Epoch.Cur, ss.TrainEnv.Trial.Cur, ss.Time.Cycle, ss.TrainLvEnv.String())
 	} else {
-		return fmt.Sprintf("Run:\t%d\tEpoch:\t%d\tTrial:\t%d\tCycle:\t%d\tName:\t%s\t\t\t", ss.TrainEnv.Run.Cur, ss.TrainEnv.Epoch.Cur, ss.TestEnv.Trial.Cur, ss.Time.Cycle, ss.TestEnv.String())
+		return fmt.Sprintf("Run:\t%d\tEpoch:\t%d\tTrial:\t%d\tCycle:\t%d\tName:\t%s\t\t\t", ss.TrainEnv.Run.Cur, ss.TrainEnv.Epoch.Cur, ss.TestEnv.Trial.Cur, ss.Time.Cycle, ss.TestLvEnv.String())
 	}
 }
 
@@ -681,7 +734,38 @@ func (ss *Sim) UpdateView(train bool) {
 ////////////////////////////////////////////////////////////////////////////////
 // 	    Running the Network, starting bottom-up..
 
-// AlphaCyc runs one alpha-cycle (100 msec, 4 quarters) of processing.
+// NCycles returns the number of cycles per trial (alpha cycle)
+func (ss *Sim) NCycles() int {
+	return (ss.MinusQtrs + ss.PlusQtrs) * ss.CycPerQtr
+}
+
+// CheckQtrs ensures that there is at least one quarter in each phase,
+// and one cycle per quarter, restoring the standard values otherwise
+func (ss *Sim) CheckQtrs() {
+	if ss.MinusQtrs >= 1 && ss.PlusQtrs >= 1 && ss.CycPerQtr >= 1 {
+		return
+	}
+	mpi.Printf("MinusQtrs: %d, PlusQtrs: %d, CycPerQtr: %d must all be >= 1 -- using standard 3, 1, 25\n", ss.MinusQtrs, ss.PlusQtrs, ss.CycPerQtr)
+	ss.MinusQtrs, ss.PlusQtrs, ss.CycPerQtr = 3, 1, 25
+}
+
+// LeabraQtr returns the leabra quarter (0-3) for given quarter of the trial,
+// which determines the activations recorded by QuarterFinal: the last
+// minus phase quarter is 2 (ActM), the two before it 1 (ActQ2) and
+// 0 (ActQ1), with any earlier ones also 0, and the plus phase quarters 3
+func (ss *Sim) LeabraQtr(qtr int) int {
+	if qtr >= ss.MinusQtrs {
+		return 3
+	}
+	lq := qtr - (ss.MinusQtrs - 3)
+	if lq < 0 {
+		lq = 0
+	}
+	return lq
+}
+
+// AlphaCyc runs one alpha-cycle (MinusQtrs + PlusQtrs quarters of
+// CycPerQtr cycles, standard = 100 msec, 4 quarters) of processing.
 // External inputs must have already been applied prior to calling,
 // using ApplyExt method on relevant layers (see TrainTrial, TestTrial).
 // If train is true, then learning DWt or WtFmDWt calls are made.
@@ -703,10 +787,17 @@ func (ss *Sim) AlphaCyc(train bool) {
 
 	ss.Net.AlphaCycInit()
 	ss.Time.AlphaCycStart()
-	for qtr := 0; qtr < 4; qtr++ {
+	ss.Time.CycPerQtr = ss.CycPerQtr
+	ss.InitFirstCycStats()
+	nqtr := ss.MinusQtrs + ss.PlusQtrs
+	for qtr := 0; qtr < nqtr; qtr++ {
+		minus := qtr < ss.MinusQtrs
+		ss.Time.Quarter = ss.LeabraQtr(qtr)
+		ss.Time.PlusPhase = !minus
 		for cyc := 0; cyc < ss.Time.CycPerQtr; cyc++ {
 			ss.Net.Cycle(&ss.Time)
 			ss.Time.CycleInc()
+			ss.FirstCycStats(minus)
 			if ss.ViewOn {
 				switch viewUpdt {
 				case leabra.Cycle:
@@ -720,23 +811,26 @@ func (ss *Sim) AlphaCyc(train bool) {
 				}
 			}
 		}
-		ss.Net.QuarterFinal(&ss.Time)
-		if qtr == 2 {
+		if minus || qtr == nqtr-1 { // plus phase only at the end, as it updates ActAvg
+			ss.Net.QuarterFinal(&ss.Time)
+		}
+		if qtr == ss.MinusQtrs-1 {
 			ss.MinusStats()
 		}
-		ss.Time.QuarterInc()
 		if ss.ViewOn {
 			switch {
 			case viewUpdt <= leabra.Quarter:
 				ss.UpdateView(train)
 			case viewUpdt == leabra.Phase:
-				if qtr >= 2 {
+				if qtr >= ss.MinusQtrs-1 {
 					ss.UpdateView(train)
 				}
 			}
 		}
 	}
 
+	ss.FinalFirstCycStats()
+
 	if train {
 		ss.Net.DWt()
 	}
@@ -770,11 +864,12 @@ func (ss *Sim) TrainTrial() {
 		ss.NewRun()
 	}
 
-	ss.TrainEnv.Step() // the Env encapsulates and manages all counter state
+	ss.TrainLvEnv.Step() // the Env encapsulates and manages all counter state
+	SyncEnvCtrs(ss.TrainLvEnv, &ss.TrainEnv)
 
 	// Key to query counters FIRST because current state is in NEXT epoch
 	// if epoch counter has changed
-	epc, _, chg := ss.TrainEnv.Counter(env.Epoch)
+	epc, _, chg := ss.TrainLvEnv.Counter(env.Epoch)
 	if chg {
 		ss.LogTrnEpc(ss.TrnEpcLog)
 		ss.LrateSched(epc)
@@ -799,7 +894,7 @@ func (ss *Sim) TrainTrial() {
 
 	// note: type must be in place before apply inputs
 	ss.Net.LayerByName("Output").SetType(emer.Target)
-	ss.ApplyInputs(&ss.TrainEnv)
+	ss.ApplyInputs(ss.TrainLvEnv)
 	ss.AlphaCyc(true)   // train
 	ss.TrialStats(true) // accumulate
 	ss.LogTrnTrl(ss.TrnTrlLog)
@@ -816,6 +911,7 @@ func (ss *Sim) RunEnd() {
 		mpi.Printf("Saving Weights to: %s\n", fnm)
 		ss.Net.SaveWtsJSON(gi.FileName(fnm))
 	}
+	ss.ExportResults()
 }
 
 // NewRun intializes a new run of the model, using the TrainEnv.Run counter
@@ -823,8 +919,7 @@ func (ss *Sim) RunEnd() {
 func (ss *Sim) NewRun() {
 	ss.InitRndSeed()
 	run := ss.TrainEnv.Run.Cur
-	ss.TrainEnv.Init(run)
-	ss.TestEnv.Init(run)
+	ss.InitEnvs(run)
 	ss.Time.Reset()
 	ss.InitWts(ss.Net)
 	ss.InitStats()
@@ -852,6 +947,7 @@ func (ss *Sim) InitStats() {
 
 	nh := len(ss.HidLays)
 	ss.HidGeMaxM = make([]float64, nh)
+	ss.TrlFirstActCyc = make([]float64, len(ss.FirstCycLays))
 }
 
 // TrialStats computes the trial-level statistics and adds them to the epoch accumulators if
@@ -886,6 +982,63 @@ func (ss *Sim) MinusStats() {
 	}
 }
 
+// InitFirstCycStats resets the first cycle stats to -1 = not yet reached,
+// at the start of the alpha cycle
+func (ss *Sim) InitFirstCycStats() {
+	if len(ss.TrlFirstActCyc) != len(ss.FirstCycLays) {
+		ss.TrlFirstActCyc = make([]float64, len(ss.FirstCycLays))
+	}
+	for li := range ss.TrlFirstActCyc {
+		ss.TrlFirstActCyc[li] = -1
+	}
+	ss.TrlFirstCorCyc = -1
+}
+
+// FirstCycStats records the first cycle at which each FirstCycLays layer
+// has a maximum activation above FirstActThr, and if minus is true,
+// the first cycle at which the maximally active Output unit is the target.
+// Called every cycle within AlphaCyc.
+func (ss *Sim) FirstCycStats(minus bool) {
+	cyc := float64(ss.Time.Cycle)
+	for li, lnm := range ss.FirstCycLays {
+		if ss.TrlFirstActCyc[li] >= 0 {
+			continue
+		}
+		ly := ss.Net.LayerByName(lnm).(leabra.LeabraLayer).AsLeabra()
+		if ly.Pools[0].Inhib.Act.Max > ss.FirstActThr {
+			ss.TrlFirstActCyc[li] = cyc
+		}
+	}
+	if !minus || ss.TrlFirstCorCyc >= 0 {
+		return
+	}
+	out := ss.Net.LayerByName("Output").(leabra.LeabraLayer).AsLeabra()
+	if out.Pools[0].Inhib.Act.Max < 0.1 {
+		return
+	}
+	ovt := ss.ValsTsr("OutputCyc")
+	out.UnitValsTensor(ovt, "Act")
+	_, mxi := norm.MaxIdx32(ovt.Values)
+	if mxi >= 0 && out.Neurons[mxi].Targ > 0.5 {
+		ss.TrlFirstCorCyc = cyc
+	}
+}
+
+// FinalFirstCycStats replaces first cycle stats that were never reached
+// with the total number of cycles, at the end of the alpha cycle,
+// so that averages remain meaningful.
+func (ss *Sim) FinalFirstCycStats() {
+	ncyc := float64(ss.Time.Cycle)
+	for li := range ss.TrlFirstActCyc {
+		if ss.TrlFirstActCyc[li] < 0 {
+			ss.TrlFirstActCyc[li] = ncyc
+		}
+	}
+	if ss.TrlFirstCorCyc < 0 {
+		ss.TrlFirstCorCyc = ncyc
+	}
+}
+
 // TrainEpoch runs training trials for remainder of this epoch
 func (ss *Sim) TrainEpoch() {
 	ss.StopNow = false
@@ -984,10 +1137,11 @@ func (ss *Sim) OpenTrainedWts() {
 
 // TestTrial runs one trial of testing -- always sequentially presented inputs
 func (ss *Sim) TestTrial(returnOnChg bool) {
-	ss.TestEnv.Step()
+	ss.TestLvEnv.Step()
+	SyncEnvCtrs(ss.TestLvEnv, &ss.TestEnv)
 
 	// Query counters FIRST
-	_, _, chg := ss.TestEnv.Counter(env.Epoch)
+	_, _, chg := ss.TestLvEnv.Counter(env.Epoch)
 	if chg {
 		if ss.ViewOn && ss.TestUpdt > leabra.AlphaCycle {
 			ss.UpdateView(false)
@@ -1000,7 +1154,7 @@ func (ss *Sim) TestTrial(returnOnChg bool) {
 
 	// note: type must be in place before apply inputs
 	ss.Net.LayerByName("Output").SetType(emer.Compare)
-	ss.ApplyInputs(&ss.TestEnv)
+	ss.ApplyInputs(ss.TestLvEnv)
 	ss.AlphaCyc(false)   // !train
 	ss.TrialStats(false) // !accumulate
 	ss.LogTstTrl(ss.TstTrlLog)
@@ -1008,10 +1162,10 @@ func (ss *Sim) TestTrial(returnOnChg bool) {
 
 // TestAll runs through the full set of testing items
 func (ss *Sim) TestAll() {
-	ss.TestEnv.Init(ss.TrainEnv.Run.Cur)
+	ss.TestLvEnv.Init(ss.TrainEnv.Run.Cur)
 	for {
 		ss.TestTrial(true) // return on chg, don't present
-		_, _, chg := ss.TestEnv.Counter(env.Epoch)
+		_, _, chg := ss.TestLvEnv.Counter(env.Epoch)
 		if chg || ss.StopNow {
 			break
 		}
@@ -1027,12 +1181,12 @@ func (ss *Sim) RunTestAll() {
 
 // TestRFs runs test for receptive fields
 func (ss *Sim) TestRFs() {
-	ss.TestEnv.Init(ss.TrainEnv.Run.Cur)
+	ss.TestLvEnv.Init(ss.TrainEnv.Run.Cur)
 	ss.ActRFs.Reset()
 	for {
 		ss.TestTrial(true) // return on chg, don't present
 		ss.UpdtActRFs()
-		_, _, chg := ss.TestEnv.Counter(env.Epoch)
+		_, _, chg := ss.TestLvEnv.Counter(env.Epoch)
 		if chg || ss.StopNow {
 			break
 		}
@@ -1233,13 +1387,18 @@ func (ss *Sim) LogTrnTrl(dt *etable.Table) {
 	dt.SetCellFloat("Epoch", row, float64(epc))
 	dt.SetCellFloat("Trial", row, float64(trl))
 	dt.SetCellFloat("Idx", row, float64(row))
-	dt.SetCellString("Cat", row, ss.TrainEnv.CurCat)
-	dt.SetCellString("TrialName", row, ss.TrainEnv.String())
+	cat, _ := ss.TrainLvEnv.CurCatName()
+	dt.SetCellString("Cat", row, cat)
+	dt.SetCellString("TrialName", row, ss.TrainLvEnv.String())
 
 	dt.SetCellFloat("Err", row, ss.TrlErr)
 	dt.SetCellFloat("SSE", row, ss.TrlSSE)
 	dt.SetCellFloat("AvgSSE", row, ss.TrlAvgSSE)
 	dt.SetCellFloat("CosDiff", row, ss.TrlCosDiff)
+	dt.SetCellFloat("FirstCorCyc", row, ss.TrlFirstCorCyc)
+	for li, lnm := range ss.FirstCycLays {
+		dt.SetCellFloat(lnm+"_FirstActCyc", row, ss.TrlFirstActCyc[li])
+	}
 
 	if ss.TrnTrlFile != nil && (!ss.UseMPI || ss.SaveProcLog) { // otherwise written at end of epoch, integrated
 		if ss.TrainEnv.Run.Cur == ss.StartRun && epc == 0 && row == 0 {
@@ -1269,6 +1428,10 @@ func (ss *Sim) ConfigTrnTrlLog(dt *etable.Table) {
 		{"SSE", etensor.FLOAT64, nil, nil},
 		{"AvgSSE", etensor.FLOAT64, nil, nil},
 		{"CosDiff", etensor.FLOAT64, nil, nil},
+		{"FirstCorCyc", etensor.FLOAT64, nil, nil},
+	}
+	for _, lnm := range ss.FirstCycLays {
+		sch = append(sch, etable.Column{lnm + "_FirstActCyc", etensor.FLOAT64, nil, nil})
 	}
 	dt.SetFromSchema(sch, 0)
 }
@@ -1289,6 +1452,10 @@ func (ss *Sim) ConfigTrnTrlPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot
 	plt.SetColParams("SSE", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
 	plt.SetColParams("AvgSSE", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
 	plt.SetColParams("CosDiff", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+	plt.SetColParams("FirstCorCyc", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	for _, lnm := range ss.FirstCycLays {
+		plt.SetColParams(lnm+"_FirstActCyc", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	}
 	return plt
 }
 
@@ -1391,6 +1558,7 @@ func (ss *Sim) LogTrnEpc(dt *etable.Table) {
 		iv := time.Now().Sub(ss.LastEpcTime)
 		ss.EpcPerTrlMSec = float64(iv) / (nt * float64(time.Millisecond))
 	}
+	ss.EpcPerCycMSec = ss.EpcPerTrlMSec / float64(ss.NCycles())
 	ss.LastEpcTime = time.Now()
 
 	dt.SetCellFloat("Run", row, float64(ss.TrainEnv.Run.Cur))
@@ -1401,6 +1569,12 @@ func (ss *Sim) LogTrnEpc(dt *etable.Table) {
 	dt.SetCellFloat("PctCor", row, ss.EpcPctCor)
 	dt.SetCellFloat("CosDiff", row, ss.EpcCosDiff)
 	dt.SetCellFloat("PerTrlMSec", row, ss.EpcPerTrlMSec)
+	dt.SetCellFloat("NCycles", row, float64(ss.NCycles()))
+	dt.SetCellFloat("PerCycMSec", row, ss.EpcPerCycMSec)
+	dt.SetCellFloat("FirstCorCyc", row, agg.Mean(tix, "FirstCorCyc")[0])
+	for _, lnm := range ss.FirstCycLays {
+		dt.SetCellFloat(lnm+"_FirstActCyc", row, agg.Mean(tix, lnm+"_FirstActCyc")[0])
+	}
 
 	tst := ss.TstEpcLog
 	if tst.Rows > 0 {
@@ -1410,6 +1584,7 @@ func (ss *Sim) LogTrnEpc(dt *etable.Table) {
 		dt.SetCellFloat("TstPctErr", row, tst.CellFloat("PctErr", trow))
 		dt.SetCellFloat("TstPctCor", row, tst.CellFloat("PctCor", trow))
 		dt.SetCellFloat("TstCosDiff", row, tst.CellFloat("CosDiff", trow))
+		dt.SetCellFloat("TstFirstCorCyc", row, tst.CellFloat("FirstCorCyc", trow))
 	}
 
 	for li, lnm := range ss.HidLays {
@@ -1457,11 +1632,18 @@ func (ss *Sim) ConfigTrnEpcLog(dt *etable.Table) {
 		{"PctCor", etensor.FLOAT64, nil, nil},
 		{"CosDiff", etensor.FLOAT64, nil, nil},
 		{"PerTrlMSec", etensor.FLOAT64, nil, nil},
+		{"NCycles", etensor.FLOAT64, nil, nil},
+		{"PerCycMSec", etensor.FLOAT64, nil, nil},
+		{"FirstCorCyc", etensor.FLOAT64, nil, nil},
 		{"TstSSE", etensor.FLOAT64, nil, nil},
 		{"TstAvgSSE", etensor.FLOAT64, nil, nil},
 		{"TstPctErr", etensor.FLOAT64, nil, nil},
 		{"TstPctCor", etensor.FLOAT64, nil, nil},
 		{"TstCosDiff", etensor.FLOAT64, nil, nil},
+		{"TstFirstCorCyc", etensor.FLOAT64, nil, nil},
+	}
+	for _, lnm := range ss.FirstCycLays {
+		sch = append(sch, etable.Column{lnm + "_FirstActCyc", etensor.FLOAT64, nil, nil})
 	}
 	for _, lnm := range ss.HidLays {
 		sch = append(sch, etable.Column{lnm + "_Dead", etensor.FLOAT64, nil, nil})
@@ -1485,12 +1667,19 @@ func (ss *Sim) ConfigTrnEpcPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot
 	plt.SetColParams("PctCor", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
 	plt.SetColParams("CosDiff", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
 	plt.SetColParams("PerTrlMSec", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	plt.SetColParams("NCycles", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	plt.SetColParams("PerCycMSec", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
 	plt.SetColParams("TstSSE", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
 	plt.SetColParams("TstAvgSSE", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
 	plt.SetColParams("TstPctErr", eplot.On, eplot.FixMin, 0, eplot.FixMax, 1) // default plot
 	plt.SetColParams("TstPctCor", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
 	plt.SetColParams("TstCosDiff", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+	plt.SetColParams("FirstCorCyc", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	plt.SetColParams("TstFirstCorCyc", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
 
+	for _, lnm := range ss.FirstCycLays {
+		plt.SetColParams(lnm+"_FirstActCyc", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	}
 	for _, lnm := range ss.HidLays {
 		plt.SetColParams(lnm+"_Dead", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 0.5)
 		plt.SetColParams(lnm+"_Hog", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 0.5)
@@ -1520,17 +1709,22 @@ func (ss *Sim) LogTstTrl(dt *etable.Table) {
 	dt.SetCellFloat("Run", row, float64(ss.TrainEnv.Run.Cur))
 	dt.SetCellFloat("Epoch", row, float64(epc))
 	dt.SetCellFloat("Trial", row, float64(trl))
-	dt.SetCellString("Cat", row, ss.TestEnv.CurCat)
-	dt.SetCellString("TrialName", row, ss.TestEnv.String())
+	cat, _ := ss.TestLvEnv.CurCatName()
+	dt.SetCellString("Cat", row, cat)
+	dt.SetCellString("TrialName", row, ss.TestLvEnv.String())
 	dt.SetCellFloat("Err", row, ss.TrlErr)
 	dt.SetCellFloat("SSE", row, ss.TrlSSE)
 	dt.SetCellFloat("AvgSSE", row, ss.TrlAvgSSE)
 	dt.SetCellFloat("CosDiff", row, ss.TrlCosDiff)
+	dt.SetCellFloat("FirstCorCyc", row, ss.TrlFirstCorCyc)
 
 	for _, lnm := range ss.HidLays {
 		ly := ss.Net.LayerByName(lnm).(leabra.LeabraLayer).AsLeabra()
 		dt.SetCellFloat(ly.Nm+" ActM.Avg", row, float64(ly.Pools[0].ActM.Avg))
 	}
+	for li, lnm := range ss.FirstCycLays {
+		dt.SetCellFloat(lnm+"_FirstActCyc", row, ss.TrlFirstActCyc[li])
+	}
 	// note: essential to use Go version of update when called from another goroutine
 	ss.TstTrlPlot.GoUpdate()
 
@@ -1562,10 +1756,14 @@ func (ss *Sim) ConfigTstTrlLog(dt *etable.Table) {
 		{"SSE", etensor.FLOAT64, nil, nil},
 		{"AvgSSE", etensor.FLOAT64, nil, nil},
 		{"CosDiff", etensor.FLOAT64, nil, nil},
+		{"FirstCorCyc", etensor.FLOAT64, nil, nil},
 	}
 	for _, lnm := range ss.HidLays {
 		sch = append(sch, etable.Column{lnm + " ActM.Avg", etensor.FLOAT64, nil, nil})
 	}
+	for _, lnm := range ss.FirstCycLays {
+		sch = append(sch, etable.Column{lnm + "_FirstActCyc", etensor.FLOAT64, nil, nil})
+	}
 	dt.SetFromSchema(sch, nt)
 }
 
@@ -1584,9 +1782,14 @@ func (ss *Sim) ConfigTstTrlPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot
 	plt.SetColParams("AvgSSE", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
 	plt.SetColParams("CosDiff", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
 
+	plt.SetColParams("FirstCorCyc", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+
 	for _, lnm := range ss.HidLays {
 		plt.SetColParams(lnm+" ActM.Avg", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 0.5)
 	}
+	for _, lnm := range ss.FirstCycLays {
+		plt.SetColParams(lnm+"_FirstActCyc", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	}
 	return plt
 }
 
@@ -1612,6 +1815,10 @@ func (ss *Sim) LogTstEpc(dt *etable.Table) {
 	dt.SetCellFloat("PctErr", row, agg.Mean(tix, "Err")[0])
 	dt.SetCellFloat("PctCor", row, 1-agg.Mean(tix, "Err")[0])
 	dt.SetCellFloat("CosDiff", row, agg.Mean(tix, "CosDiff")[0])
+	dt.SetCellFloat("FirstCorCyc", row, agg.Mean(tix, "FirstCorCyc")[0])
+	for _, lnm := range ss.FirstCycLays {
+		dt.SetCellFloat(lnm+"_FirstActCyc", row, agg.Mean(tix, lnm+"_FirstActCyc")[0])
+	}
 
 	spl := split.GroupBy(tix, []string{"Cat"})
 	_, err := split.AggTry(spl, "Err", agg.AggMean)
@@ -1659,8 +1866,12 @@ func (ss *Sim) ConfigTstEpcLog(dt *etable.Table) {
 		{"PctErr", etensor.FLOAT64, nil, nil},
 		{"PctCor", etensor.FLOAT64, nil, nil},
 		{"CosDiff", etensor.FLOAT64, nil, nil},
+		{"FirstCorCyc", etensor.FLOAT64, nil, nil},
 	}
-	for _, cat := range ss.TestEnv.Images.Cats {
+	for _, lnm := range ss.FirstCycLays {
+		sch = append(sch, etable.Column{lnm + "_FirstActCyc", etensor.FLOAT64, nil, nil})
+	}
+	for _, cat := range ss.TestLvEnv.CatNames() {
 		sch = append(sch, etable.Column{cat, etensor.FLOAT64, nil, nil})
 	}
 	dt.SetFromSchema(sch, 0)
@@ -1679,8 +1890,12 @@ func (ss *Sim) ConfigTstEpcPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot
 	plt.SetColParams("PctErr", eplot.On, eplot.FixMin, 0, eplot.FixMax, 1) // default plot
 	plt.SetColParams("PctCor", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
 	plt.SetColParams("CosDiff", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+	plt.SetColParams("FirstCorCyc", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
 
-	for _, cat := range ss.TestEnv.Images.Cats {
+	for _, lnm := range ss.FirstCycLays {
+		plt.SetColParams(lnm+"_FirstActCyc", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	}
+	for _, cat := range ss.TestLvEnv.CatNames() {
 		plt.SetColParams(cat, eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
 	}
 	return plt
@@ -1715,6 +1930,10 @@ func (ss *Sim) LogRun(dt *etable.Table) {
 	dt.SetCellFloat("PctErr", row, agg.Mean(epcix, "PctErr")[0])
 	dt.SetCellFloat("PctCor", row, agg.Mean(epcix, "PctCor")[0])
 	dt.SetCellFloat("CosDiff", row, agg.Mean(epcix, "CosDiff")[0])
+	dt.SetCellFloat("FirstCorCyc", row, agg.Mean(epcix, "FirstCorCyc")[0])
+	for _, lnm := range ss.FirstCycLays {
+		dt.SetCellFloat(lnm+"_FirstActCyc", row, agg.Mean(epcix, lnm+"_FirstActCyc")[0])
+	}
 
 	runix := etable.NewIdxView(dt)
 	spl := split.GroupBy(runix, []string{"Params"})
@@ -1747,6 +1966,10 @@ func (ss *Sim) ConfigRunLog(dt *etable.Table) {
 		{"PctErr", etensor.FLOAT64, nil, nil},
 		{"PctCor", etensor.FLOAT64, nil, nil},
 		{"CosDiff", etensor.FLOAT64, nil, nil},
+		{"FirstCorCyc", etensor.FLOAT64, nil, nil},
+	}
+	for _, lnm := range ss.FirstCycLays {
+		sch = append(sch, etable.Column{lnm + "_FirstActCyc", etensor.FLOAT64, nil, nil})
 	}
 	dt.SetFromSchema(sch, 0)
 }
@@ -1763,6 +1986,10 @@ func (ss *Sim) ConfigRunPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot2D
 	plt.SetColParams("PctErr", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
 	plt.SetColParams("PctCor", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
 	plt.SetColParams("CosDiff", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+	plt.SetColParams("FirstCorCyc", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	for _, lnm := range ss.FirstCycLays {
+		plt.SetColParams(lnm+"_FirstActCyc", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	}
 	return plt
 }
 
@@ -2047,12 +2274,21 @@ func (ss *Sim) CmdArgs() {
 	var saveTrnTrlLog bool
 	var saveTstTrlLog bool
 	var note string
+	var merge string
+	var nprocs int
+	var export string
+	var exportLogs string
 	flag.StringVar(&ss.ParamSet, "params", "", "ParamSet name to use -- must be valid name as listed in compiled-in params or loaded params")
 	flag.StringVar(&ss.Tag, "tag", "", "extra tag to add to file names saved from this run")
 	flag.StringVar(&note, "note", "", "user note -- describe the run params etc")
 	flag.IntVar(&ss.StartRun, "run", 0, "starting run number -- determines the random seed -- runs counts from there -- can do all runs in parallel by launching separate jobs with each run, runs = 1")
 	flag.IntVar(&ss.MaxEpcs, "epcs", 1000, "number of epochs per run")
 	flag.IntVar(&ss.MaxRuns, "runs", 1, "number of runs to do")
+	flag.IntVar(&ss.TotTrls, "trls", 512, "total number of training trials per epoch across all MPI procs")
+	flag.IntVar(&ss.MinusQtrs, "minusqtrs", 3, "number of quarters in the minus phase of each trial -- Sim params sheet values override")
+	flag.IntVar(&ss.PlusQtrs, "plusqtrs", 1, "number of quarters in the plus phase of each trial -- Sim params sheet values override")
+	flag.IntVar(&ss.CycPerQtr, "cycperqtr", 25, "number of cycles per quarter -- Sim params sheet values override")
+	flag.StringVar(&ss.EnvType, "env", EnvTypeImages, "type of env: Images or the name of a custom LvisEnv type registered in LvisEnvTypes")
 	flag.BoolVar(&ss.LogSetParams, "setparams", false, "if true, print a record of each parameter that is set")
 	flag.BoolVar(&ss.SaveWts, "wts", false, "if true, save final weights after each run")
 	flag.BoolVar(&saveEpcLog, "epclog", true, "if true, save train epoch log to file")
@@ -2061,8 +2297,40 @@ func (ss *Sim) CmdArgs() {
 	flag.BoolVar(&saveTstTrlLog, "tsttrllog", false, "if true, save testing trial log to file")
 	flag.BoolVar(&nogui, "nogui", true, "if not passing any other args and want to run nogui, use nogui")
 	flag.BoolVar(&ss.UseMPI, "mpi", false, "if set, use MPI for distributed computation")
+	flag.BoolVar(&ss.SaveProcLog, "proclog", false, "if set, each MPI proc saves its own logs, with a _rank suffix on ranks > 0 -- use -merge to merge them")
+	flag.StringVar(&merge, "merge", "", "merge the per-proc log files saved with -proclog, given the rank 0 file name, into a single _merged.tsv file, verifying the rows per epoch, and exit -- use with -nprocs, and -trls for a train trial log")
+	flag.IntVar(&nprocs, "nprocs", 1, "number of MPI procs for -merge")
+	flag.StringVar(&export, "export", "", "comma-separated formats to export the train and test epoch logs of each run to, in the long-format results schema: csv and / or parquet -- see export.go")
+	flag.StringVar(&exportLogs, "exportlogs", "", "comma-separated saved epoch log files (.tsv) from any of the lvis sims to convert to the long-format results schema, in the -export formats (csv if empty), saved next to each file with a _results suffix, and exit")
 	flag.Parse()
 
+	if export != "" {
+		ss.ExportFmts = strings.Split(export, ",")
+	}
+	if exportLogs != "" {
+		if err := ss.ExportLogs(strings.Split(exportLogs, ",")); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if merge != "" {
+		expect := 0
+		if strings.Contains(merge, "trn_trl") {
+			expect = NewTrialsAllocator(ss.TotTrls, 1, nprocs).EffTotal
+		}
+		_, nprob, err := MergeProcLogs(merge, nprocs, expect)
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		if nprob > 0 {
+			os.Exit(2)
+		}
+		return
+	}
+
 	if ss.UseMPI {
 		ss.MPIInit()
 	}
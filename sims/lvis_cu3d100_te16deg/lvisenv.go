@@ -0,0 +1,132 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/emer/emergent/env"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etensor"
+)
+
+// lvisenv.go has the LvisEnv interface, for plugging a custom environment
+// into the sim in place of the ImagesEnv, to reuse the network and training
+// with a different stimulus generator, without modifying images_env.go.
+//
+// To add one, write a type implementing LvisEnv in a new file in this
+// package, and register it in an init() function:
+//
+//	func init() {
+//		LvisEnvTypes["MyEnv"] = func(test bool) LvisEnv { return &MyEnv{} }
+//	}
+//
+// and select it with the EnvType field (-env MyEnv).
+//
+// The TrainEnv and TestEnv ImagesEnvs remain the record of the Run, Epoch
+// and Trial counters used for logging and run control: the Epoch and Trial
+// counters of a custom env are copied to them after each Step.
+
+// EnvTypeImages is the EnvType for the standard ImagesEnv
+const EnvTypeImages = "Images"
+
+// LvisEnv is the interface for environments that drive the Lvis network.
+// In addition to the standard env.Env methods, it provides the category
+// labels for logging.
+type LvisEnv interface {
+	env.Env
+
+	// State must return the input pattern for each of the input layers
+	// of the network, by layer name: V1h16, V1m16, V1h8, V1m8, with the
+	// 4D shape of the corresponding layer, and the target pattern for the
+	// 10x10 Output layer (1D or 2D, with 100 units), for the current trial,
+	// after Step.  Counter must support env.Epoch and env.Trial.
+	State(element string) etensor.Tensor
+
+	// CatNames returns the category labels, in category index order --
+	// the number of categories must fit in the Output layer
+	CatNames() []string
+
+	// CurCatName returns the category label and index for the current trial
+	CurCatName() (string, int)
+
+	// MPIAlloc allocates the trials for this MPI proc, so each proc runs
+	// a different subset
+	MPIAlloc()
+
+	// String returns the name of the current trial, for logging
+	String() string
+}
+
+// LvisEnvTypes are the constructors of custom LvisEnv types, by
+// EnvType name -- see the lvisenv.go comments for how to add one.
+// The constructor is called for training (test = false) and testing.
+var LvisEnvTypes = map[string]func(test bool) LvisEnv{}
+
+// CatNames returns the category labels, in category index order
+func (ev *ImagesEnv) CatNames() []string {
+	return ev.Images.Cats
+}
+
+// CurCatName returns the current category label and index
+func (ev *ImagesEnv) CurCatName() (string, int) {
+	return ev.CurCat, ev.CurCatIdx
+}
+
+// IsImagesEnv returns true if the standard ImagesEnv is being used
+func (ss *Sim) IsImagesEnv() bool {
+	return ss.EnvType == "" || ss.EnvType == EnvTypeImages
+}
+
+// ConfigCustomEnv configures the TrainLvEnv and TestLvEnv of the custom
+// EnvType -- called at the end of ConfigEnv
+func (ss *Sim) ConfigCustomEnv() error {
+	newEnv, ok := LvisEnvTypes[ss.EnvType]
+	if !ok {
+		var typs []string
+		for nm := range LvisEnvTypes {
+			typs = append(typs, nm)
+		}
+		sort.Strings(typs)
+		return fmt.Errorf("EnvType: %s not found, available: %s %v", ss.EnvType, EnvTypeImages, typs)
+	}
+	ss.TrainLvEnv = newEnv(false)
+	ss.TestLvEnv = newEnv(true)
+	for _, ev := range []LvisEnv{ss.TrainLvEnv, ss.TestLvEnv} {
+		if err := ev.Validate(); err != nil {
+			return err
+		}
+		if ss.UseMPI {
+			ev.MPIAlloc()
+		}
+		ev.Init(0)
+	}
+	mpi.Printf("Using custom env: %s with %d categories\n", ss.EnvType, len(ss.TrainLvEnv.CatNames()))
+	return nil
+}
+
+// InitEnvs initializes the train and test envs for given run
+func (ss *Sim) InitEnvs(run int) {
+	ss.TrainEnv.Init(run)
+	ss.TestEnv.Init(run)
+	if !ss.IsImagesEnv() {
+		ss.TrainLvEnv.Init(run)
+		ss.TestLvEnv.Init(run)
+	}
+}
+
+// SyncEnvCtrs copies the Epoch and Trial counters from the given custom
+// env to the given ImagesEnv, after Step, so they are available for
+// logging and run control -- a no-op for the ImagesEnv itself.
+func SyncEnvCtrs(lev LvisEnv, iev *ImagesEnv) {
+	if lev == LvisEnv(iev) {
+		return
+	}
+	epc, _, _ := lev.Counter(env.Epoch)
+	trl, _, _ := lev.Counter(env.Trial)
+	iev.Epoch.Set(epc)
+	iev.Trial.Set(trl)
+}
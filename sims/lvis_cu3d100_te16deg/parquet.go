@@ -0,0 +1,288 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"math"
+	"os"
+)
+
+// parquet.go has a minimal Apache Parquet file writer for flat tables of
+// required INT32, DOUBLE, and UTF8 string columns, for the long-format
+// results export (see export.go), without adding a dependency: each column
+// is written as a single uncompressed, PLAIN encoded data page in a single
+// row group, which all Parquet readers (pyarrow, pandas, R arrow) support.
+// The file metadata is encoded with the Thrift compact protocol.
+// This is a copy of parquet.go in the axon version of this sim (each sim
+// is a self-contained main package), where it is tested -- any change
+// must be made to both copies.
+
+// ParquetType is the Parquet physical type of a column
+type ParquetType int32
+
+const (
+	ParquetInt32     ParquetType = 1
+	ParquetDouble    ParquetType = 5
+	ParquetByteArray ParquetType = 6
+)
+
+// ParquetCol is one column of values to write: exactly one of the
+// value slices is used, per the Type
+type ParquetCol struct {
+
+	// name of the column
+	Name string
+
+	// physical type of the column -- ByteArray columns are UTF8 strings
+	Type ParquetType
+
+	// values for Int32 columns
+	Ints []int32
+
+	// values for Double columns
+	Floats []float64
+
+	// values for ByteArray columns
+	Strings []string
+}
+
+// Len returns the number of values in the column
+func (pc *ParquetCol) Len() int {
+	switch pc.Type {
+	case ParquetInt32:
+		return len(pc.Ints)
+	case ParquetDouble:
+		return len(pc.Floats)
+	}
+	return len(pc.Strings)
+}
+
+// PlainValues returns the values of the column in PLAIN encoding
+func (pc *ParquetCol) PlainValues() []byte {
+	var b []byte
+	switch pc.Type {
+	case ParquetInt32:
+		for _, v := range pc.Ints {
+			b = appendU32(b, uint32(v))
+		}
+	case ParquetDouble:
+		for _, v := range pc.Floats {
+			b = appendU64(b, math.Float64bits(v))
+		}
+	default:
+		for _, v := range pc.Strings {
+			b = appendU32(b, uint32(len(v)))
+			b = append(b, v...)
+		}
+	}
+	return b
+}
+
+func appendU32(b []byte, v uint32) []byte {
+	var e [4]byte
+	binary.LittleEndian.PutUint32(e[:], v)
+	return append(b, e[:]...)
+}
+
+func appendU64(b []byte, v uint64) []byte {
+	var e [8]byte
+	binary.LittleEndian.PutUint64(e[:], v)
+	return append(b, e[:]...)
+}
+
+// thrift compact protocol field types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// ThriftWriter encodes structs with the Thrift compact protocol
+type ThriftWriter struct {
+
+	// encoded bytes
+	Buf []byte
+
+	// last field id written in the current struct
+	LastID int16
+
+	// stack of last field ids of enclosing structs
+	Stack []int16
+}
+
+func (tw *ThriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	tw.Buf = append(tw.Buf, b[:n]...)
+}
+
+func (tw *ThriftWriter) zigzag(v int64) {
+	tw.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+// Field writes the header for field of given id and type
+func (tw *ThriftWriter) Field(id int16, typ byte) {
+	if delta := id - tw.LastID; delta > 0 && delta <= 15 {
+		tw.Buf = append(tw.Buf, byte(delta)<<4|typ)
+	} else {
+		tw.Buf = append(tw.Buf, typ)
+		tw.zigzag(int64(id))
+	}
+	tw.LastID = id
+}
+
+// I32 writes an i32 field
+func (tw *ThriftWriter) I32(id int16, v int32) {
+	tw.Field(id, thriftI32)
+	tw.zigzag(int64(v))
+}
+
+// I64 writes an i64 field
+func (tw *ThriftWriter) I64(id int16, v int64) {
+	tw.Field(id, thriftI64)
+	tw.zigzag(v)
+}
+
+// String writes a binary (string) field
+func (tw *ThriftWriter) String(id int16, v string) {
+	tw.Field(id, thriftBinary)
+	tw.varint(uint64(len(v)))
+	tw.Buf = append(tw.Buf, v...)
+}
+
+// List writes a list field header, for n elements of given type
+func (tw *ThriftWriter) List(id int16, elTyp byte, n int) {
+	tw.Field(id, thriftList)
+	if n < 15 {
+		tw.Buf = append(tw.Buf, byte(n)<<4|elTyp)
+	} else {
+		tw.Buf = append(tw.Buf, 0xF0|elTyp)
+		tw.varint(uint64(n))
+	}
+}
+
+// ListI32 writes an i32 list element
+func (tw *ThriftWriter) ListI32(v int32) {
+	tw.zigzag(int64(v))
+}
+
+// ListString writes a string list element
+func (tw *ThriftWriter) ListString(v string) {
+	tw.varint(uint64(len(v)))
+	tw.Buf = append(tw.Buf, v...)
+}
+
+// Begin begins a struct: a struct field with given id, or a
+// list element if id is 0
+func (tw *ThriftWriter) Begin(id int16) {
+	if id > 0 {
+		tw.Field(id, thriftStruct)
+	}
+	tw.Stack = append(tw.Stack, tw.LastID)
+	tw.LastID = 0
+}
+
+// End ends the current struct
+func (tw *ThriftWriter) End() {
+	tw.Buf = append(tw.Buf, 0) // stop
+	n := len(tw.Stack) - 1
+	tw.LastID = tw.Stack[n]
+	tw.Stack = tw.Stack[:n]
+}
+
+// SaveParquet saves the given columns, which must all have the
+// same number of values, to a Parquet file
+func SaveParquet(fnm string, cols []*ParquetCol) error {
+	f, err := os.Create(fnm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	const magic = "PAR1"
+	w.WriteString(magic)
+	off := int64(len(magic))
+	nrows := 0
+	if len(cols) > 0 {
+		nrows = cols[0].Len()
+	}
+	offs := make([]int64, len(cols))
+	sizes := make([]int64, len(cols))
+	for ci, pc := range cols {
+		vals := pc.PlainValues()
+		hw := &ThriftWriter{} // PageHeader
+		hw.Begin(0)
+		hw.I32(1, 0) // DATA_PAGE
+		hw.I32(2, int32(len(vals)))
+		hw.I32(3, int32(len(vals)))
+		hw.Begin(5) // DataPageHeader
+		hw.I32(1, int32(pc.Len()))
+		hw.I32(2, 0) // PLAIN
+		hw.I32(3, 3) // RLE levels, none for required
+		hw.I32(4, 3)
+		hw.End()
+		hw.End()
+		w.Write(hw.Buf)
+		w.Write(vals)
+		offs[ci] = off
+		sizes[ci] = int64(len(hw.Buf) + len(vals))
+		off += sizes[ci]
+	}
+	tw := &ThriftWriter{} // FileMetaData
+	tw.Begin(0)
+	tw.I32(1, 1) // version
+	tw.List(2, thriftStruct, len(cols)+1)
+	tw.Begin(0) // root SchemaElement
+	tw.String(4, "schema")
+	tw.I32(5, int32(len(cols)))
+	tw.End()
+	for _, pc := range cols {
+		tw.Begin(0)
+		tw.I32(1, int32(pc.Type))
+		tw.I32(3, 0) // REQUIRED
+		tw.String(4, pc.Name)
+		if pc.Type == ParquetByteArray {
+			tw.I32(6, 0) // UTF8
+		}
+		tw.End()
+	}
+	tw.I64(3, int64(nrows))
+	tw.List(4, thriftStruct, 1)
+	tw.Begin(0) // RowGroup
+	tw.List(1, thriftStruct, len(cols))
+	total := int64(0)
+	for ci, pc := range cols {
+		tw.Begin(0) // ColumnChunk
+		tw.I64(2, offs[ci])
+		tw.Begin(3) // ColumnMetaData
+		tw.I32(1, int32(pc.Type))
+		tw.List(2, thriftI32, 2)
+		tw.ListI32(0) // PLAIN
+		tw.ListI32(3) // RLE
+		tw.List(3, thriftBinary, 1)
+		tw.ListString(pc.Name)
+		tw.I32(4, 0) // UNCOMPRESSED
+		tw.I64(5, int64(pc.Len()))
+		tw.I64(6, sizes[ci])
+		tw.I64(7, sizes[ci])
+		tw.I64(9, offs[ci])
+		tw.End()
+		tw.End()
+		total += sizes[ci]
+	}
+	tw.I64(2, total)
+	tw.I64(3, int64(nrows))
+	tw.End()
+	tw.String(6, "lvis")
+	tw.End()
+	w.Write(tw.Buf)
+	w.Write(appendU32(nil, uint32(len(tw.Buf))))
+	w.WriteString(magic)
+	return w.Flush()
+}
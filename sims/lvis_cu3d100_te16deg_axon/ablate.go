@@ -0,0 +1,116 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// ablate.go has the layer ablation sweep, a one-shot causal importance map
+// of the architecture: each Super layer in turn is turned off (SetOff,
+// with all of its neurons flagged as off, so it is also silenced on the
+// GPU), and the FastTest subset of the test images is tested, recording
+// the FastTestStats and their increase relative to the intact network,
+// saved as an ablate log.  Each layer is restored before the next.
+
+// SetLayerAblated turns given layer off if off, and back on otherwise,
+// setting the Off flag of all its neurons for all data indexes
+func (ss *Sim) SetLayerAblated(ly *axon.Layer, off bool) {
+	ctx := &ss.Context
+	ly.SetOff(off)
+	for lni := uint32(0); lni < ly.NNeurons; lni++ {
+		ni := ly.NeurStIdx + lni
+		for di := uint32(0); di < ly.MaxData; di++ {
+			if off {
+				axon.NrnSetFlag(ctx, ni, di, axon.NeuronOff)
+			} else {
+				axon.NrnClearFlag(ctx, ni, di, axon.NeuronOff)
+			}
+		}
+	}
+	ss.Net.GPU.SyncNeuronsToGPU()
+}
+
+// AblateRow runs the FastTest and adds a row to given ablation table
+// for given layer ("None" for the intact network), with the change in
+// each stat relative to the first row
+func (ss *Sim) AblateRow(dt *etable.Table, lay string) {
+	ss.FastTest()
+	et := ss.Logs.Table(etime.Test, etime.Epoch)
+	if et.Rows == 0 {
+		return
+	}
+	row := dt.Rows
+	dt.SetNumRows(row + 1)
+	dt.SetCellString("Layer", row, lay)
+	for _, st := range FastTestStats {
+		v := et.CellFloat(st, et.Rows-1)
+		dt.SetCellFloat(st, row, v)
+		dt.SetCellFloat("D"+st, row, v-dt.CellFloat(st, 0))
+	}
+	mpi.Printf("Ablate: %s  PctErr: %g  DPctErr: %g\n", lay, dt.CellFloat("PctErr", row), dt.CellFloat("DPctErr", row))
+}
+
+// Ablate tests the intact network and then each Super layer turned off
+// in turn, on the FastTest subset, returning the table of the
+// FastTestStats and their change from the intact network for each
+func (ss *Sim) Ablate() *etable.Table {
+	sch := etable.Schema{
+		{"Layer", etensor.STRING, nil, nil},
+	}
+	for _, st := range FastTestStats {
+		sch = append(sch, etable.Column{Name: st, Type: etensor.FLOAT64})
+		sch = append(sch, etable.Column{Name: "D" + st, Type: etensor.FLOAT64})
+	}
+	dt := &etable.Table{}
+	dt.SetMetaData("name", "Ablate")
+	dt.SetMetaData("desc", "test error with each Super layer turned off")
+	dt.SetFromSchema(sch, 0)
+	ss.AblateRow(dt, "None")
+	for _, lnm := range ss.Net.LayersByType(axon.SuperLayer) {
+		ly := ss.Net.AxonLayerByName(lnm)
+		if ly.IsOff() {
+			continue
+		}
+		ss.SetLayerAblated(ly, true)
+		ss.AblateRow(dt, lnm)
+		ss.SetLayerAblated(ly, false)
+		if ss.GUI.StopNow {
+			break
+		}
+	}
+	return dt
+}
+
+// RunAblate runs the Ablate sweep, and saves the table as an ablate log
+// file (in nogui mode, on rank 0)
+func (ss *Sim) RunAblate() error {
+	dt := ss.Ablate()
+	ss.Logs.MiscTables["Ablate"] = dt
+	if ss.Config.GUI || ss.MPIRank() != 0 {
+		return nil
+	}
+	fnm := elog.LogFileName("ablate", ss.Net.Name(), ss.Stats.String("RunName"))
+	if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	mpi.Printf("Saved layer ablation sweep to: %s\n", fnm)
+	return nil
+}
+
+// RunAblateGUI runs the Ablate sweep, has stop running = false at end -- for gui
+func (ss *Sim) RunAblateGUI() {
+	ss.GUI.StopNow = false
+	if err := ss.RunAblate(); err != nil {
+		mpi.Println(err)
+	}
+	ss.GUI.Stopped()
+}
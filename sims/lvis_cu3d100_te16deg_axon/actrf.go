@@ -0,0 +1,187 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/actrf"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// actrf.go has fast accumulation of the activation-based receptive fields
+// (ActRFs), which is cheap enough to run routinely during testing in
+// nogui cluster runs.  The 2D display indexes for the activity and source
+// values are precomputed once, each source is read once per sample, and
+// only the active units are added, into the flat SumProd values,
+// instead of indexing each activity x source element separately.
+
+// ActRFVar is the neuron variable used for the ActRFs
+const ActRFVar = "ActM"
+
+// ActRFThr is the threshold on source values for adding to the ActRFs
+const ActRFThr = 0.01
+
+// FastRF has the precomputed indexes for fast accumulation of one ActRF
+type FastRF struct {
+
+	// the RF being accumulated
+	RF *actrf.RF
+
+	// activity layer
+	Act *axon.Layer
+
+	// source layer, or nil if the source is the Image
+	Src *axon.Layer
+
+	// flat unit index in Act, for each 2D display position
+	ActIdxs []int
+
+	// flat index in the source, for each 2D display position
+	SrcIdxs []int
+
+	// activity values for the current data index
+	actVals []float32
+
+	// source values for the current data index
+	srcVals []float32
+
+	// 2D indexes of the sources above threshold
+	srcOn []int
+
+	// values of the sources above threshold
+	srcOnVals []float32
+}
+
+// Prjn2DIdxs returns the flat index for each 2D display position of
+// given shape, in the standard Prjn2D row-major order used by actrf.RF
+func Prjn2DIdxs(shp *etensor.Shape) []int {
+	rows, cols, _, _ := etensor.Prjn2DShape(shp, false)
+	idxs := make([]int, rows*cols)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			idxs[y*cols+x] = etensor.Prjn2DIdx(shp, false, y, x)
+		}
+	}
+	return idxs
+}
+
+// Add adds one sample from given activity and source values
+// (in flat layer / tensor order), for sources >= thr
+func (fr *FastRF) Add(act, src []float32, thr float32) {
+	rf := fr.RF
+	sumSrc := rf.SumSrc.Values
+	sumProd := rf.SumProd.Values
+	ns := len(fr.SrcIdxs)
+	fr.srcOn = fr.srcOn[:0]
+	fr.srcOnVals = fr.srcOnVals[:0]
+	for s, si := range fr.SrcIdxs {
+		tv := src[si]
+		if tv < thr {
+			continue
+		}
+		sumSrc[s] += tv
+		fr.srcOn = append(fr.srcOn, s)
+		fr.srcOnVals = append(fr.srcOnVals, tv)
+	}
+	if len(fr.srcOn) == 0 {
+		return
+	}
+	for a, ai := range fr.ActIdxs {
+		av := act[ai]
+		if av == 0 {
+			continue
+		}
+		prod := sumProd[a*ns : (a+1)*ns]
+		for k, s := range fr.srcOn {
+			prod[s] += av * fr.srcOnVals[k]
+		}
+	}
+}
+
+// ActRFsOn returns true if the ActRFs are recorded during testing:
+// always in the GUI, and with Config.Log.ActRFs in nogui mode
+func (ss *Sim) ActRFsOn() bool {
+	return ss.Config.GUI || ss.Config.Log.ActRFs
+}
+
+// ConfigFastRFs configures the FastRFs for the ActRFs --
+// called at the end of ConfigActRFs
+func (ss *Sim) ConfigFastRFs() {
+	ss.FastRFs = nil
+	for _, rf := range ss.Stats.ActRFs.RFs {
+		sp := strings.Split(rf.Name, ":")
+		fr := &FastRF{RF: rf, Act: ss.Net.AxonLayerByName(sp[0])}
+		fr.ActIdxs = Prjn2DIdxs(fr.Act.Shape())
+		if sly, err := ss.Net.LayerByNameTry(sp[1]); err == nil {
+			fr.Src = sly.(*axon.Layer)
+			fr.SrcIdxs = Prjn2DIdxs(fr.Src.Shape())
+		} else {
+			fr.SrcIdxs = Prjn2DIdxs(ss.Stats.F32Tensor("Image").ShapeObj())
+		}
+		ss.FastRFs = append(ss.FastRFs, fr)
+	}
+}
+
+// RecordActRFImage saves a copy of the current image for given data
+// index, as the ActRF source for that data index -- the env image
+// only has the last one applied.  Called in ApplyInputs.
+func (ss *Sim) RecordActRFImage(di int, ev *ImagesEnv) {
+	if ss.Context.Mode != etime.Test || !ss.ActRFsOn() {
+		return
+	}
+	if len(ss.ActRFImgs) <= di {
+		ss.ActRFImgs = append(ss.ActRFImgs, make([][]float32, di+1-len(ss.ActRFImgs))...)
+	}
+	ss.ActRFImgs[di] = append(ss.ActRFImgs[di][:0], ev.Img.Tsr.Values...)
+}
+
+// UpdateActRFs adds the current test trial activity for all data
+// indexes to the ActRFs
+func (ss *Sim) UpdateActRFs() {
+	for di := 0; di < int(ss.Context.NetIdxs.NData); di++ {
+		for _, fr := range ss.FastRFs {
+			fr.Act.UnitVals(&fr.actVals, ActRFVar, di)
+			src := fr.srcVals
+			if fr.Src != nil {
+				fr.Src.UnitVals(&fr.srcVals, ActRFVar, di)
+				src = fr.srcVals
+			} else if di < len(ss.ActRFImgs) {
+				src = ss.ActRFImgs[di]
+			}
+			if len(src) == 0 {
+				continue
+			}
+			fr.Add(fr.actVals, src, ActRFThr)
+		}
+	}
+}
+
+// ActRFsAvgNorm sums the ActRFs across MPI procs, computes the
+// normalized RFs, and in nogui mode saves the NormRF for each,
+// on rank 0.  Called at the end of TestAll.
+func (ss *Sim) ActRFsAvgNorm() {
+	if !ss.ActRFsOn() {
+		return
+	}
+	if ss.Config.Run.MPI {
+		ss.Stats.ActRFs.MPISum(ss.Comm)
+	}
+	ss.Stats.ActRFsAvgNorm()
+	if ss.Config.GUI || ss.MPIRank() != 0 {
+		return
+	}
+	for _, rf := range ss.Stats.ActRFs.RFs {
+		fnm := elog.LogFileName("actrf_"+strings.ReplaceAll(rf.Name, ":", "_"), ss.Net.Name(), ss.Stats.String("RunName"))
+		if err := etensor.SaveCSV(&rf.NormRF, gi.FileName(fnm), '\t'); err != nil {
+			mpi.Println(err)
+		}
+	}
+}
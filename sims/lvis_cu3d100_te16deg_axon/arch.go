@@ -0,0 +1,155 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/emer/axon/axon"
+	"github.com/goki/gi/gi"
+)
+
+// arch.go has the export of the network architecture summary,
+// as Graphviz DOT and JSON, for documenting and checking the wiring.
+
+// ArchLayer is the architecture summary for one layer
+type ArchLayer struct {
+
+	// layer name
+	Name string
+
+	// layer type, e.g., SuperLayer
+	Type string
+
+	// additional classes set on the layer, used for params
+	Class string
+
+	// shape of the layer: Y, X or PoolY, PoolX, UnitY, UnitX
+	Shape []int
+
+	// number of neurons
+	NNeurons int
+}
+
+// ArchPrjn is the architecture summary for one projection
+type ArchPrjn struct {
+
+	// projection name: SendToRecv
+	Name string
+
+	// sending layer name
+	Send string
+
+	// receiving layer name
+	Recv string
+
+	// projection type, e.g., ForwardPrjn, BackPrjn
+	Type string
+
+	// additional classes set on the projection, used for params
+	Class string
+
+	// name of the connectivity pattern
+	Pattern string
+
+	// PrjnScale.Abs absolute scaling factor
+	Abs float32
+
+	// PrjnScale.Rel relative scaling factor
+	Rel float32
+
+	// number of synapses
+	NSyns int
+}
+
+// Arch is the architecture summary of a built network:
+// all the layers and projections, in order
+type Arch struct {
+
+	// network name
+	Name string
+
+	// all layers
+	Layers []ArchLayer
+
+	// all projections, in order of receiving layer
+	Prjns []ArchPrjn
+}
+
+// Arch returns the architecture summary of the network,
+// which must be built and have params applied
+func (ss *Sim) Arch() *Arch {
+	net := ss.Net
+	ar := &Arch{Name: net.Name()}
+	for _, ly := range net.Layers {
+		ar.Layers = append(ar.Layers, ArchLayer{Name: ly.Name(), Type: ly.LayerType().String(), Class: ly.Cls, Shape: ly.Shp.Shp, NNeurons: int(ly.NNeurons)})
+		for _, pj := range ly.RcvPrjns {
+			ps := &pj.Params.PrjnScale
+			ar.Prjns = append(ar.Prjns, ArchPrjn{Name: pj.Name(), Send: pj.Send.Name(), Recv: ly.Name(), Type: pj.PrjnTypeName(), Class: pj.Cls, Pattern: pj.Pat.Name(), Abs: ps.Abs, Rel: ps.Rel, NSyns: int(pj.NSyns)})
+		}
+	}
+	return ar
+}
+
+// SaveJSON saves the architecture summary to given file, as JSON
+func (ar *Arch) SaveJSON(filename string) error {
+	b, err := json.MarshalIndent(ar, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b, 0644)
+}
+
+// DOT returns the architecture summary as a Graphviz DOT digraph:
+// layers are labeled with their shape, and projections with their
+// pattern and scaling, with Back projections dashed and Inhib red.
+func (ar *Arch) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n\trankdir=BT;\n\tnode [shape=box];\n", ar.Name)
+	for _, ly := range ar.Layers {
+		lbl := fmt.Sprintf("%s\\n%v", ly.Name, ly.Shape)
+		if ly.Class != "" {
+			lbl += "\\n" + ly.Class
+		}
+		style := ""
+		if ly.Type == axon.InputLayer.String() || ly.Type == axon.TargetLayer.String() {
+			style = ", style=filled, fillcolor=lightgrey"
+		}
+		fmt.Fprintf(&b, "\t%q [label=%q%s];\n", ly.Name, lbl, style)
+	}
+	for _, pj := range ar.Prjns {
+		lbl := fmt.Sprintf("%s\\nAbs: %g Rel: %g", pj.Pattern, pj.Abs, pj.Rel)
+		style := ""
+		switch pj.Type {
+		case axon.BackPrjn.String():
+			style = ", style=dashed"
+		case axon.InhibPrjn.String():
+			style = ", color=red"
+		}
+		fmt.Fprintf(&b, "\t%q -> %q [label=%q%s];\n", pj.Send, pj.Recv, lbl, style)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// SaveDOT saves the architecture summary to given file, as Graphviz DOT
+func (ar *Arch) SaveDOT(filename string) error {
+	return os.WriteFile(filename, []byte(ar.DOT()), 0644)
+}
+
+// SaveArch saves the network architecture summary to the given
+// file name, with any extension replaced by .dot and .json
+func (ss *Sim) SaveArch(filename gi.FileName) error {
+	fnm := strings.TrimSuffix(string(filename), ".dot")
+	fnm = strings.TrimSuffix(fnm, ".json")
+	ar := ss.Arch()
+	if err := ar.SaveDOT(fnm + ".dot"); err != nil {
+		return err
+	}
+	return ar.SaveJSON(fnm + ".json")
+}
@@ -0,0 +1,497 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etensor"
+)
+
+// baseline.go has the CNN baseline, a small convolutional network trained
+// by backprop in parallel with the LVis network, on exactly the same
+// filtered V1 inputs with the same transforms (the Ext input of all the
+// V1 input layers), as a matched baseline for architectural comparisons.
+// Each V1 layer feeds its own conv stream, as the layers differ in the
+// number of pools: the pools are the spatial positions and the units
+// within each pool are the input channels, feeding:
+//
+//	Conv     NFilters 3x3 filters over the pools, with zero padding, ReLU
+//	Pool     2x2 max pooling
+//
+// and the pooled features of all the streams feed:
+//
+//	Softmax  fully connected to one unit per category
+//
+// trained with the cross-entropy loss, by SGD on each training trial:
+// the gradients are summed over the data parallel inputs of the trial.
+// The error is logged as BaseErr in the same logs as the LVis error, with
+// the test epoch values copied to the training epoch log as TstBaseErr.
+// Under MPI, the gradients are summed across procs once per trial, as for
+// the decoders.  See Config.Run.Baseline.
+
+// BaselineSeed is the random seed for the CNN baseline initial weights,
+// plus the run, so that the weights are the same on all MPI procs
+const BaselineSeed = 5113
+
+// BaselineStream is the conv stream of the CNN baseline for one input layer
+type BaselineStream struct {
+
+	// input layer
+	Layer *axon.Layer
+
+	// number of input pools along Y and X
+	PY, PX int
+
+	// number of input channels: units per pool
+	C int
+
+	// number of pooled positions along Y and X
+	QY, QX int
+
+	// offset of the pooled activations in the Baseline Pool features
+	Off int
+
+	// conv weights: [K][3][3][C]
+	CW []float32
+
+	// conv biases: [K]
+	CB []float32
+
+	// input values for the current trial: [PY][PX][C]
+	In etensor.Float32
+
+	// conv activations after ReLU: [PY][PX][K]
+	Conv []float32
+
+	// index of the max conv activation in Conv for each pooled unit: [QY][QX][K]
+	PoolIdx []int
+
+	// gradients of the conv weights and biases, in the same layout
+	DCW, DCB []float32
+
+	// gradients of the conv activations
+	DConv []float32
+}
+
+// Baseline is the CNN baseline network
+type Baseline struct {
+
+	// conv streams, one per input layer
+	Streams []*BaselineStream
+
+	// number of conv filters
+	K int
+
+	// number of categories
+	NCats int
+
+	// softmax weights: [NCats][Pool]
+	FW []float32
+
+	// softmax biases: [NCats]
+	FB []float32
+
+	// pooled activations of all the streams, in order: [QY][QX][K] each
+	Pool []float32
+
+	// softmax output probabilities
+	Out []float32
+
+	// gradients of the softmax weights and biases, in the same layout
+	DFW, DFB []float32
+
+	// gradients of the pooled activations
+	DPool []float32
+
+	// all the gradients, packed for summing across procs
+	DAll, DSum []float32
+
+	// mpi communicator -- nil if not using MPI
+	Comm *mpi.Comm
+}
+
+// Config configures the network for given input layers, number of
+// filters, and number of categories
+func (bl *Baseline) Config(lys []*axon.Layer, nfilt, ncats int) {
+	shps := make([][3]int, len(lys))
+	for i, ly := range lys {
+		shp := ly.Shp.Shp
+		shps[i] = [3]int{shp[0], shp[1], shp[2] * shp[3]}
+	}
+	bl.ConfigShapes(shps, nfilt, ncats)
+	for i, ly := range lys {
+		bl.Streams[i].Layer = ly
+	}
+}
+
+// ConfigShapes configures the network for inputs of given numbers of
+// pools along Y and X and channels, one per stream, without layers
+func (bl *Baseline) ConfigShapes(shps [][3]int, nfilt, ncats int) {
+	bl.K = nfilt
+	bl.NCats = ncats
+	bl.Streams = make([]*BaselineStream, len(shps))
+	np := 0
+	for i, shp := range shps {
+		st := &BaselineStream{PY: shp[0], PX: shp[1], C: shp[2]}
+		st.QY, st.QX = (st.PY+1)/2, (st.PX+1)/2
+		st.Off = np
+		np += st.QY * st.QX * nfilt
+		st.CW = make([]float32, nfilt*9*st.C)
+		st.CB = make([]float32, nfilt)
+		st.DCW = make([]float32, len(st.CW))
+		st.DCB = make([]float32, len(st.CB))
+		st.In.SetShape([]int{st.PY, st.PX, st.C}, nil, nil)
+		st.Conv = make([]float32, st.PY*st.PX*nfilt)
+		st.DConv = make([]float32, len(st.Conv))
+		st.PoolIdx = make([]int, st.QY*st.QX*nfilt)
+		bl.Streams[i] = st
+	}
+	bl.FW = make([]float32, ncats*np)
+	bl.FB = make([]float32, ncats)
+	bl.DFW = make([]float32, len(bl.FW))
+	bl.DFB = make([]float32, len(bl.FB))
+	bl.Pool = make([]float32, np)
+	bl.DPool = make([]float32, np)
+	bl.Out = make([]float32, ncats)
+	nw := 0
+	for _, wts := range bl.Wts() {
+		nw += len(wts)
+	}
+	bl.DAll = make([]float32, nw)
+	bl.DSum = make([]float32, nw)
+}
+
+// Wts returns the weights and biases: CW, CB of each stream, then FW, FB
+func (bl *Baseline) Wts() [][]float32 {
+	var wts [][]float32
+	for _, st := range bl.Streams {
+		wts = append(wts, st.CW, st.CB)
+	}
+	return append(wts, bl.FW, bl.FB)
+}
+
+// DWts returns the gradients, in the same order as Wts
+func (bl *Baseline) DWts() [][]float32 {
+	var dws [][]float32
+	for _, st := range bl.Streams {
+		dws = append(dws, st.DCW, st.DCB)
+	}
+	return append(dws, bl.DFW, bl.DFB)
+}
+
+// InitWts initializes the weights with He-scaled gaussian values from
+// given random source, and zero biases and gradients
+func (bl *Baseline) InitWts(rnd *rand.Rand) {
+	for _, st := range bl.Streams {
+		csd := math.Sqrt(2 / float64(9*st.C))
+		for i := range st.CW {
+			st.CW[i] = float32(rnd.NormFloat64() * csd)
+		}
+	}
+	fsd := math.Sqrt(2 / float64(len(bl.Pool)))
+	for i := range bl.FW {
+		bl.FW[i] = float32(rnd.NormFloat64() * fsd)
+	}
+	for _, st := range bl.Streams {
+		for i := range st.CB {
+			st.CB[i] = 0
+		}
+	}
+	for i := range bl.FB {
+		bl.FB[i] = 0
+	}
+	for _, dws := range bl.DWts() {
+		for i := range dws {
+			dws[i] = 0
+		}
+	}
+}
+
+// Forward computes the conv and pooled activations for the current In
+// values, into given pooled activations
+func (st *BaselineStream) Forward(k int, pool []float32) {
+	in := st.In.Values
+	py, px, c := st.PY, st.PX, st.C
+	for y := 0; y < py; y++ {
+		for x := 0; x < px; x++ {
+			for f := 0; f < k; f++ {
+				s := st.CB[f]
+				for dy := 0; dy < 3; dy++ {
+					iy := y + dy - 1
+					if iy < 0 || iy >= py {
+						continue
+					}
+					for dx := 0; dx < 3; dx++ {
+						ix := x + dx - 1
+						if ix < 0 || ix >= px {
+							continue
+						}
+						wts := st.CW[((f*3+dy)*3+dx)*c : ((f*3+dy)*3+dx+1)*c]
+						ivs := in[(iy*px+ix)*c : (iy*px+ix+1)*c]
+						for ci, w := range wts {
+							s += w * ivs[ci]
+						}
+					}
+				}
+				if s < 0 {
+					s = 0
+				}
+				st.Conv[(y*px+x)*k+f] = s
+			}
+		}
+	}
+	for qy := 0; qy < st.QY; qy++ {
+		for qx := 0; qx < st.QX; qx++ {
+			for f := 0; f < k; f++ {
+				pi := (qy*st.QX+qx)*k + f
+				mi := -1
+				for y := 2 * qy; y < 2*qy+2 && y < py; y++ {
+					for x := 2 * qx; x < 2*qx+2 && x < px; x++ {
+						ci := (y*px+x)*k + f
+						if mi < 0 || st.Conv[ci] > st.Conv[mi] {
+							mi = ci
+						}
+					}
+				}
+				st.PoolIdx[pi] = mi
+				pool[pi] = st.Conv[mi]
+			}
+		}
+	}
+}
+
+// Backward adds the gradients of the conv weights and biases for given
+// gradients of the pooled activations, for the most recent Forward
+func (st *BaselineStream) Backward(k int, dpool []float32) {
+	px, c := st.PX, st.C
+	for i := range st.DConv {
+		st.DConv[i] = 0
+	}
+	for pi, ci := range st.PoolIdx {
+		if st.Conv[ci] > 0 {
+			st.DConv[ci] += dpool[pi]
+		}
+	}
+	in := st.In.Values
+	for y := 0; y < st.PY; y++ {
+		for x := 0; x < px; x++ {
+			for f := 0; f < k; f++ {
+				d := st.DConv[(y*px+x)*k+f]
+				if d == 0 {
+					continue
+				}
+				st.DCB[f] += d
+				for dy := 0; dy < 3; dy++ {
+					iy := y + dy - 1
+					if iy < 0 || iy >= st.PY {
+						continue
+					}
+					for dx := 0; dx < 3; dx++ {
+						ix := x + dx - 1
+						if ix < 0 || ix >= px {
+							continue
+						}
+						dws := st.DCW[((f*3+dy)*3+dx)*c : ((f*3+dy)*3+dx+1)*c]
+						ivs := in[(iy*px+ix)*c : (iy*px+ix+1)*c]
+						for ci := range dws {
+							dws[ci] += d * ivs[ci]
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// Forward computes the output for the current In values of the streams,
+// returning the index of the most probable category
+func (bl *Baseline) Forward() int {
+	for _, st := range bl.Streams {
+		st.Forward(bl.K, bl.Pool[st.Off:st.Off+len(st.PoolIdx)])
+	}
+	np := len(bl.Pool)
+	mx := float32(-math.MaxFloat32)
+	maxi := 0
+	for j := range bl.Out {
+		s := bl.FB[j]
+		for i, w := range bl.FW[j*np : (j+1)*np] {
+			s += w * bl.Pool[i]
+		}
+		bl.Out[j] = s
+		if s > mx {
+			mx, maxi = s, j
+		}
+	}
+	sum := float32(0)
+	for j, s := range bl.Out {
+		e := float32(math.Exp(float64(s - mx)))
+		bl.Out[j] = e
+		sum += e
+	}
+	for j := range bl.Out {
+		bl.Out[j] /= sum
+	}
+	return maxi
+}
+
+// Backward adds the gradients of the cross-entropy loss for given
+// target category, for the most recent Forward, to those accumulated
+// since the last Update
+func (bl *Baseline) Backward(targ int) {
+	np := len(bl.Pool)
+	for i := range bl.DPool {
+		bl.DPool[i] = 0
+	}
+	for j, o := range bl.Out {
+		dz := o
+		if j == targ {
+			dz -= 1
+		}
+		bl.DFB[j] += dz
+		fw := bl.FW[j*np : (j+1)*np]
+		dfw := bl.DFW[j*np : (j+1)*np]
+		for i, p := range bl.Pool {
+			dfw[i] += dz * p
+			bl.DPool[i] += dz * fw[i]
+		}
+	}
+	for _, st := range bl.Streams {
+		st.Backward(bl.K, bl.DPool[st.Off:st.Off+len(st.PoolIdx)])
+	}
+}
+
+// Update applies the gradients accumulated since the last Update with
+// given learning rate, summing them across procs first if using MPI,
+// in one call, and zeros them.  Must be called on all procs.
+func (bl *Baseline) Update(lrate float32) {
+	dws := bl.DWts()
+	if bl.Comm != nil {
+		n := 0
+		for _, dw := range dws {
+			n += copy(bl.DAll[n:], dw)
+		}
+		bl.Comm.AllReduceF32(mpi.OpSum, bl.DSum, bl.DAll)
+		n = 0
+		for _, dw := range dws {
+			n += copy(dw, bl.DSum[n:])
+		}
+	}
+	for wi, wts := range bl.Wts() {
+		dw := dws[wi]
+		for i, d := range dw {
+			wts[i] -= lrate * d
+			dw[i] = 0
+		}
+	}
+}
+
+// BaselineLayers returns the names of the input layers for the CNN
+// baseline: Config.Run.Baseline.Layers if set, else all the 4D V1
+// input layers
+func (ss *Sim) BaselineLayers() []string {
+	if lays := ss.Config.Run.Baseline.Layers; len(lays) > 0 {
+		return lays
+	}
+	var lays []string
+	for _, lnm := range ss.Net.LayersByType(axon.InputLayer) {
+		if strings.HasPrefix(lnm, "V1") && ss.Net.AxonLayerByName(lnm).Shp.NumDims() == 4 {
+			lays = append(lays, lnm)
+		}
+	}
+	return lays
+}
+
+// ConfigBaseline configures the CNN baseline per Config.Run.Baseline
+func (ss *Sim) ConfigBaseline() {
+	bc := &ss.Config.Run.Baseline
+	bl := &ss.Baseline
+	bl.Streams = nil
+	if !bc.On {
+		return
+	}
+	var lys []*axon.Layer
+	for _, lnm := range ss.BaselineLayers() {
+		ly := ss.Net.AxonLayerByName(lnm)
+		if ly == nil || ly.Shp.NumDims() != 4 {
+			mpi.Printf("Baseline: layer %s not found or not 4D\n", lnm)
+			return
+		}
+		lys = append(lys, ly)
+	}
+	if len(lys) == 0 {
+		mpi.Printf("Baseline: no input layers\n")
+		return
+	}
+	bl.Config(lys, bc.NFilters, len(ss.LvisEnv(etime.Train).CatNames()))
+	if ss.Config.Run.MPI {
+		bl.Comm = ss.Comm
+	}
+	for _, st := range bl.Streams {
+		mpi.Printf("Baseline: CNN stream on %s: %d x %d x %d inputs\n", st.Layer.Name(), st.PY, st.PX, st.C)
+	}
+	mpi.Printf("Baseline: %d streams, %d filters, %d pooled features, %d weights\n", len(bl.Streams), bl.K, len(bl.Pool), len(bl.DAll))
+}
+
+// InitBaseline initializes the CNN baseline weights, for a new run
+func (ss *Sim) InitBaseline() {
+	bl := &ss.Baseline
+	if len(bl.Streams) == 0 {
+		return
+	}
+	run := ss.Loops.GetLoop(etime.Train, etime.Run).Counter.Cur
+	bl.InitWts(rand.New(rand.NewSource(int64(BaselineSeed + run))))
+}
+
+// BaselineTrial runs the CNN baseline on the input for given data index,
+// and computes its gradients on training trials, setting the BaseErr stat.
+// Called when logging the trial, after TrialStats: see BaselineUpdate.
+func (ss *Sim) BaselineTrial(di int) {
+	bl := &ss.Baseline
+	if len(bl.Streams) == 0 {
+		return
+	}
+	for _, st := range bl.Streams {
+		st.Layer.UnitValsTensor(&st.In, "Ext", di)
+	}
+	cat := ss.Stats.IntDi("TrlCatIdx", di)
+	rsp := bl.Forward()
+	if ss.Context.Mode == etime.Train && cat >= 0 && cat < bl.NCats {
+		bl.Backward(cat)
+	}
+	err := 0.0
+	if rsp != cat {
+		err = 1
+	}
+	ss.Stats.SetFloat("BaseErr", err)
+}
+
+// BaselineUpdate updates the CNN baseline weights from the gradients of
+// all the data parallel inputs, on training trials.  Called once per
+// trial after BaselineTrial for each data index.
+func (ss *Sim) BaselineUpdate() {
+	bl := &ss.Baseline
+	if len(bl.Streams) == 0 || ss.Context.Mode != etime.Train {
+		return
+	}
+	bl.Update(ss.Config.Run.Baseline.Lrate)
+}
+
+// ConfigBaselineLogs adds the BaseErr log items for the CNN baseline,
+// with the test epoch values copied to the train epoch and run logs
+// with a Tst prefix
+func (ss *Sim) ConfigBaselineLogs() {
+	if len(ss.Baseline.Streams) == 0 {
+		return
+	}
+	ss.Stats.SetFloat("BaseErr", 0)
+	ss.Logs.AddStatAggItem("BaseErr", etime.Run, etime.Epoch, etime.Trial)
+	ss.Logs.AddCopyFromFloatItems(etime.Train, []etime.Times{etime.Epoch, etime.Run}, etime.Test, etime.Epoch, "Tst", "BaseErr")
+}
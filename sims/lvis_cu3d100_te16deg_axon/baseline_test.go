@@ -0,0 +1,62 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// baselineLoss returns the cross-entropy loss for given target category
+func baselineLoss(bl *Baseline, targ int) float64 {
+	bl.Forward()
+	return -math.Log(float64(bl.Out[targ]))
+}
+
+func TestBaselineGrad(t *testing.T) {
+	bl := &Baseline{}
+	bl.ConfigShapes([][3]int{{4, 5, 3}, {3, 3, 2}}, 2, 4)
+	rnd := rand.New(rand.NewSource(1))
+	bl.InitWts(rnd)
+	for _, st := range bl.Streams {
+		for i := range st.In.Values {
+			st.In.Values[i] = rnd.Float32()
+		}
+		for i := range st.CB {
+			st.CB[i] = 0.1
+		}
+	}
+	if len(bl.Pool) != 2*(2*3+2*2) {
+		t.Fatalf("Pool features: %d, want: %d", len(bl.Pool), 2*(2*3+2*2))
+	}
+	targ := 2
+	bl.Forward()
+	bl.Backward(targ)
+	const eps = 1e-3
+	dws := bl.DWts()
+	for wi, wts := range bl.Wts() {
+		for i := range wts {
+			w := wts[i]
+			wts[i] = w + eps
+			lp := baselineLoss(bl, targ)
+			wts[i] = w - eps
+			lm := baselineLoss(bl, targ)
+			wts[i] = w
+			num := (lp - lm) / (2 * eps)
+			if d := math.Abs(num - float64(dws[wi][i])); d > 1e-3+0.02*math.Abs(num) {
+				t.Errorf("weights %d [%d]: gradient: %g, numerical: %g", wi, i, dws[wi][i], num)
+			}
+		}
+	}
+	// gradients accumulate until Update, which applies and zeros them
+	g := bl.DFB[0]
+	bl.Forward()
+	bl.Backward(targ)
+	if math.Abs(float64(bl.DFB[0]-2*g)) > 1e-5 {
+		t.Errorf("accumulated DFB[0]: %g, want: %g", bl.DFB[0], 2*g)
+	}
+	fb := bl.FB[0]
+	bl.Update(0.5)
+	if math.Abs(float64(bl.FB[0]-(fb-g))) > 1e-5 || bl.DFB[0] != 0 {
+		t.Errorf("Update: FB[0]: %g, want: %g, DFB[0]: %g", bl.FB[0], fb-g, bl.DFB[0])
+	}
+}
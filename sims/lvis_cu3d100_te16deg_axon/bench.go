@@ -0,0 +1,124 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/emergent/looper"
+	"github.com/emer/emergent/timer"
+	"github.com/emer/empi/empi"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// bench.go has the per-rank timing of the Bench mode, for diagnosing
+// node-to-node imbalance under MPI: the time spent in the env (stepping
+// and filtering the images and applying them as inputs), the network
+// cycles, the MPI allreduce of the weight changes, and in total, is
+// recorded on each proc (PerTrlMSec is the total time per trial, as
+// printed at the end of the run), and gathered to rank 0 into a table with one row
+// per rank, saved as a bench log.  The max / min ratio across ranks of
+// each time is printed.
+
+// BenchTimes has the timers of the Bench mode
+type BenchTimes struct {
+
+	// time stepping the env and applying the inputs
+	Env timer.Time
+
+	// time running the network cycles of the training trials
+	Cycle timer.Time
+
+	// time in the MPI allreduce of the weight changes
+	AllReduce timer.Time
+
+	// total time of the run
+	Total timer.Time
+}
+
+// BenchCols are the timing columns of the bench table, in seconds
+var BenchCols = []string{"EnvSecs", "CycleSecs", "AllReduceSecs", "TotalSecs"}
+
+// ConfigBenchLoops adds the timing of the network cycles of the
+// training trials to the loops: from the end of the trial OnStart, after
+// ApplyInputs, to the start of the trial OnEnd, before the weight update
+func (ss *Sim) ConfigBenchLoops(man *looper.Manager) {
+	trial := man.GetLoop(etime.Train, etime.Trial)
+	trial.OnStart.Add("BenchCycleStart", func() {
+		ss.BenchTimes.Cycle.Start()
+	})
+	trial.OnEnd.Prepend("BenchCycleStop", func() {
+		ss.BenchTimes.Cycle.Stop()
+	})
+}
+
+// BenchTable returns the table of the Bench times of each rank,
+// gathered to all procs
+func (ss *Sim) BenchTable() *etable.Table {
+	bt := &ss.BenchTimes
+	sch := etable.Schema{
+		{"Rank", etensor.INT64, nil, nil},
+		{"Host", etensor.STRING, nil, nil},
+	}
+	for _, cl := range BenchCols {
+		sch = append(sch, etable.Column{Name: cl, Type: etensor.FLOAT64})
+	}
+	sch = append(sch, etable.Column{Name: "PerTrlMSec", Type: etensor.FLOAT64})
+	dt := &etable.Table{}
+	dt.SetFromSchema(sch, 1)
+	host, _ := os.Hostname()
+	dt.SetCellFloat("Rank", 0, float64(ss.MPIRank()))
+	dt.SetCellString("Host", 0, host)
+	for i, tm := range []*timer.Time{&bt.Env, &bt.Cycle, &bt.AllReduce, &bt.Total} {
+		dt.SetCellFloat(BenchCols[i], 0, tm.TotalSecs())
+	}
+	if ss.Trials.EffTotal > 0 {
+		dt.SetCellFloat("PerTrlMSec", 0, 1000*bt.Total.TotalSecs()/float64(ss.Trials.EffTotal))
+	}
+	if !ss.Config.Run.MPI {
+		return dt
+	}
+	all := &etable.Table{}
+	empi.GatherTableRows(all, dt, ss.Comm)
+	return all
+}
+
+// SaveBench gathers the Bench times of each rank, and saves them as a
+// bench log on rank 0, printing the max / min ratio of each across ranks
+func (ss *Sim) SaveBench(netName, runName string) {
+	dt := ss.BenchTable()
+	ss.Logs.MiscTables["Bench"] = dt
+	if ss.MPIRank() != 0 {
+		return
+	}
+	for _, cl := range BenchCols {
+		mn, mx := dt.CellFloat(cl, 0), dt.CellFloat(cl, 0)
+		for ri := 1; ri < dt.Rows; ri++ {
+			v := dt.CellFloat(cl, ri)
+			if v < mn {
+				mn = v
+			}
+			if v > mx {
+				mx = v
+			}
+		}
+		ratio := 1.0
+		if mn > 0 {
+			ratio = mx / mn
+		}
+		mpi.Printf("Bench %s: min: %6.3g  max: %6.3g  max / min: %6.3g\n", cl, mn, mx, ratio)
+	}
+	fnm := elog.LogFileName("bench", netName, runName)
+	if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		mpi.Println(err)
+		return
+	}
+	mpi.Printf("Saved per-rank bench times to: %s\n", fnm)
+}
@@ -11,8 +11,8 @@ import (
 // note: using nthread = 0 = default = GOMAXPROCS
 
 // RunBench runs the lvis benchmark
-func RunBench(b *testing.B, gpu bool, ndata, nthread int) {
-	fmt.Printf("bench: gpu: %v  ndata: %d  nthread: %d\n", gpu, ndata, nthread)
+func RunBench(b *testing.B, gpu bool, ndata, nthread int, high16 bool) {
+	fmt.Printf("bench: gpu: %v  ndata: %d  nthread: %d  high16: %v\n", gpu, ndata, nthread, high16)
 	sim := &Sim{}
 
 	sim.New()
@@ -21,6 +21,7 @@ func RunBench(b *testing.B, gpu bool, ndata, nthread int) {
 	sim.Config.Bench = true
 	sim.Config.Run.GPU = gpu
 	sim.Config.Run.NData = ndata
+	sim.Config.Env.High16 = high16
 	sim.Config.Run.NThreads = nthread
 	sim.Config.Run.NRuns = 1
 	sim.Config.Run.NEpochs = 1
@@ -35,34 +36,43 @@ func RunBench(b *testing.B, gpu bool, ndata, nthread int) {
 // GPU
 
 func BenchmarkGPUnData1(b *testing.B) {
-	RunBench(b, true, 1, 0)
+	RunBench(b, true, 1, 0, false)
 }
 
 func BenchmarkGPUnData2(b *testing.B) {
-	RunBench(b, true, 2, 0)
+	RunBench(b, true, 2, 0, false)
 }
 func BenchmarkGPUnData4(b *testing.B) {
-	RunBench(b, true, 4, 0)
+	RunBench(b, true, 4, 0, false)
 }
 func BenchmarkGPUnData8(b *testing.B) {
-	RunBench(b, true, 8, 0)
+	RunBench(b, true, 8, 0, false)
 }
 func BenchmarkGPUnData16(b *testing.B) {
-	RunBench(b, true, 16, 0)
+	RunBench(b, true, 16, 0, false)
 }
 
 func BenchmarkCPUnData1(b *testing.B) {
-	RunBench(b, false, 1, 0)
+	RunBench(b, false, 1, 0, false)
 }
 func BenchmarkCPUnData2(b *testing.B) {
-	RunBench(b, false, 2, 0)
+	RunBench(b, false, 2, 0, false)
 }
 func BenchmarkCPUnData4(b *testing.B) {
-	RunBench(b, false, 4, 0)
+	RunBench(b, false, 4, 0, false)
 }
 func BenchmarkCPUnData8(b *testing.B) {
-	RunBench(b, false, 8, 0)
+	RunBench(b, false, 8, 0, false)
 }
 func BenchmarkCPUnData16(b *testing.B) {
-	RunBench(b, false, 16, 0)
+	RunBench(b, false, 16, 0, false)
+}
+
+// High16: compare with the corresponding NData8 benchmarks above
+
+func BenchmarkGPUnData8High16(b *testing.B) {
+	RunBench(b, true, 8, 0, true)
+}
+func BenchmarkCPUnData8High16(b *testing.B) {
+	RunBench(b, false, 8, 0, true)
 }
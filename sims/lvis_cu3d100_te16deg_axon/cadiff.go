@@ -0,0 +1,102 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/etable/agg"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/mat32"
+)
+
+// cadiff.go has the learning signal flow analysis: the magnitude of the
+// error-driven learning signal at each level of the hierarchy, as the
+// mean over units of |CaDiff| = |CaP - CaD|, the difference between the
+// plus and minus phases that drives learning in each receiving neuron
+// (the gradient-equivalent in backprop terms).  This is logged per
+// training trial as <lay>_CaDiff, averaged per epoch and run, along with
+// <lay>_CaDiffRel, the ratio of the epoch average to that of the Output
+// layer, which shows how much of the error signal reaches each layer:
+// values that fall off sharply in the lower layers indicate vanishing
+// credit assignment.  See Config.Run.CaDiff.
+
+// CaDiffLays returns the names of the layers for the CaDiff stats:
+// all Super layers and the Output layer, as for the FirstCyc stats
+func (ss *Sim) CaDiffLays() []string {
+	return ss.FirstCycLays()
+}
+
+// CaDiffStats records the mean |CaDiff| of each CaDiffLays layer for each
+// data index -- called at the end of the training trial
+func (ss *Sim) CaDiffStats() {
+	if !ss.Config.Run.CaDiff {
+		return
+	}
+	ctx := &ss.Context
+	if ss.Config.Run.GPU {
+		ss.Net.GPU.SyncNeuronsFmGPU()
+	}
+	for _, lnm := range ss.CaDiffLays() {
+		ly := ss.Net.AxonLayerByName(lnm)
+		nm := lnm + "_CaDiff"
+		for di := uint32(0); di < ctx.NetIdxs.NData; di++ {
+			sum := float32(0)
+			for lni := uint32(0); lni < ly.NNeurons; lni++ {
+				ni := ly.NeurStIdx + lni
+				if axon.NrnIsOff(ctx, ni) {
+					continue
+				}
+				sum += mat32.Abs(axon.NrnV(ctx, ni, di, axon.CaDiff))
+			}
+			ss.Stats.SetFloatDi(nm, int(di), float64(sum/float32(ly.NNeurons)))
+		}
+	}
+}
+
+// ConfigCaDiffLogs adds the CaDiff stats to the training logs, at trial,
+// epoch and run levels, and the CaDiffRel ratios at epoch and run levels
+func (ss *Sim) ConfigCaDiffLogs() {
+	if !ss.Config.Run.CaDiff {
+		return
+	}
+	lays := ss.CaDiffLays()
+	for _, lnm := range lays {
+		stnm := lnm + "_CaDiff"
+		ss.Logs.AddItem(&elog.Item{
+			Name: stnm,
+			Type: etensor.FLOAT64,
+			Plot: elog.DFalse,
+			Write: elog.WriteMap{
+				etime.Scope(etime.Train, etime.Trial): func(ctx *elog.Context) {
+					ctx.SetFloat64(ss.Stats.FloatDi(stnm, ctx.Di))
+				}, etime.Scope(etime.Train, etime.Epoch): func(ctx *elog.Context) {
+					ctx.SetAgg(ctx.Mode, etime.Trial, agg.AggMean)
+				}, etime.Scope(etime.Train, etime.Run): func(ctx *elog.Context) {
+					ix := ctx.LastNRows(ctx.Mode, etime.Epoch, 5)
+					ctx.SetFloat64(agg.Mean(ix, ctx.Item.Name)[0])
+				}}})
+	}
+	for _, lnm := range lays {
+		stnm := lnm + "_CaDiff"
+		ss.Logs.AddItem(&elog.Item{
+			Name: lnm + "_CaDiffRel",
+			Type: etensor.FLOAT64,
+			Plot: elog.DFalse,
+			Write: elog.WriteMap{
+				etime.Scope(etime.Train, etime.Epoch): func(ctx *elog.Context) {
+					out := ctx.ItemFloat(ctx.Mode, ctx.Time, "Output_CaDiff")
+					if out <= 0 {
+						ctx.SetFloat64(0)
+						return
+					}
+					ctx.SetFloat64(ctx.ItemFloat(ctx.Mode, ctx.Time, stnm) / out)
+				}, etime.Scope(etime.Train, etime.Run): func(ctx *elog.Context) {
+					ix := ctx.LastNRows(ctx.Mode, etime.Epoch, 5)
+					ctx.SetFloat64(agg.Mean(ix, ctx.Item.Name)[0])
+				}}})
+	}
+}
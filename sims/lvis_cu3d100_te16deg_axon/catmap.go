@@ -0,0 +1,169 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+)
+
+// catmap.go has the stable ordering of the categories, which determines
+// the category indexes used by the output patterns, decoders, confusion
+// matrices, and everything else indexed by category: new splits order the
+// categories canonically, sorted by name, instead of by the directory
+// listing or the order of renames, and the category -> index mapping of
+// each image set is saved in an explicit <ImageFile>_catmap.json file the
+// first time it is used.  Thereafter, the saved mapping determines the
+// order, so the indexes are the same across machines and for splits
+// generated at different times, and a category set that differs from
+// the saved one is reported.  A split saved before the mapping file keeps
+// its order, which is then saved as the mapping, so prior weights remain
+// valid.  The categories saved with the weights (SplitManifest, and the
+// .wtsz header) and with the checkpoint stats are checked against the
+// current order when loaded.
+
+// CatMapFileName returns the file name for the saved category -> index
+// mapping of given env's image set
+func (ev *ImagesEnv) CatMapFileName() string {
+	return fmt.Sprintf("%s_catmap.json", ev.ImageFile)
+}
+
+// OrderCats sets the order of the categories to the given list, which
+// must have the same categories, permuting the image lists to match
+func (im *Images) OrderCats(cats []string) {
+	im.MakeCatMap()
+	perm := func(lists [][]string) [][]string {
+		if lists == nil {
+			return nil
+		}
+		nl := make([][]string, len(cats))
+		for ci, c := range cats {
+			nl[ci] = lists[im.CatMap[c]]
+		}
+		return nl
+	}
+	im.ImagesAll = perm(im.ImagesAll)
+	im.ImagesTrain = perm(im.ImagesTrain)
+	im.ImagesTest = perm(im.ImagesTest)
+	im.Cats = cats
+	im.MakeCatMap()
+}
+
+// SortCats sorts the categories by name, for a canonical order
+// independent of the order in which they were found
+func (im *Images) SortCats() {
+	cats := make([]string, len(im.Cats))
+	copy(cats, im.Cats)
+	sort.Strings(cats)
+	im.OrderCats(cats)
+}
+
+// SaveCatMap saves the current category -> index mapping to given file
+func (im *Images) SaveCatMap(filename string) error {
+	im.MakeCatMap()
+	b, err := json.MarshalIndent(im.CatMap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b, 0644)
+}
+
+// OpenCatMap opens a category -> index mapping from given file, and
+// returns the categories in index order
+func OpenCatMap(filename string) ([]string, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	cm := map[string]int{}
+	if err := json.Unmarshal(b, &cm); err != nil {
+		return nil, fmt.Errorf("OpenCatMap: %s: %w", filename, err)
+	}
+	cats := make([]string, len(cm))
+	for c, ci := range cm {
+		if ci < 0 || ci >= len(cats) || cats[ci] != "" {
+			return nil, fmt.Errorf("OpenCatMap: %s: invalid index: %d for category: %s", filename, ci, c)
+		}
+		cats[ci] = c
+	}
+	return cats, nil
+}
+
+// CatsDiff returns a description of the differences between the current
+// and saved lists of categories, or "" if they are the same
+func CatsDiff(cur, saved []string) string {
+	if ListHash(cur) != ListHash(saved) {
+		cm := make(map[string]bool, len(cur))
+		for _, c := range cur {
+			cm[c] = true
+		}
+		sm := make(map[string]bool, len(saved))
+		var missing, added []string
+		for _, c := range saved {
+			sm[c] = true
+			if !cm[c] {
+				missing = append(missing, c)
+			}
+		}
+		for _, c := range cur {
+			if !sm[c] {
+				added = append(added, c)
+			}
+		}
+		return fmt.Sprintf("categories differ: current n = %d, saved n = %d, not saved: [%s], not current: [%s]", len(cur), len(saved), strings.Join(added, " "), strings.Join(missing, " "))
+	}
+	for ci, c := range cur {
+		if saved[ci] != c {
+			return fmt.Sprintf("category order differs at: %d: current: %s saved: %s", ci, c, saved[ci])
+		}
+	}
+	return ""
+}
+
+// ApplyCatMap orders the categories per the saved mapping file, if it
+// exists, or saves the current order as the mapping otherwise.  Returns
+// true if the order was changed, and an error if the categories differ
+// from the saved ones, in which case the current order is kept.
+func (ev *ImagesEnv) ApplyCatMap() (bool, error) {
+	im := &ev.Images
+	fnm := ev.CatMapFileName()
+	if _, err := os.Stat(fnm); os.IsNotExist(err) {
+		mpi.Printf("Saving category -> index mapping of %d categories to: %s\n", len(im.Cats), fnm)
+		return false, im.SaveCatMap(fnm)
+	}
+	cats, err := OpenCatMap(fnm)
+	if err != nil {
+		return false, err
+	}
+	if ListHash(cats) != ListHash(im.Cats) {
+		return false, fmt.Errorf("ImagesEnv.ApplyCatMap: %s: %s: %s -- keeping the current order", ev.Nm, fnm, CatsDiff(im.Cats, cats))
+	}
+	if CatsDiff(im.Cats, cats) == "" {
+		return false, nil
+	}
+	mpi.Printf("Ordering categories per the saved mapping in: %s\n", fnm)
+	im.OrderCats(cats)
+	im.Flats()
+	return true, nil
+}
+
+// CheckCats checks the current categories against those saved with
+// given artifact, returning an error describing the differences --
+// nothing is checked if saved is empty (not recorded)
+func (ss *Sim) CheckCats(artifact string, saved []string) error {
+	if len(saved) == 0 {
+		return nil
+	}
+	if df := CatsDiff(ss.LvisEnv(etime.Train).CatNames(), saved); df != "" {
+		return fmt.Errorf("%s: %s", artifact, df)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+)
+
+// ckptstats.go has the saving and restoring of the stats state that
+// accumulates over training along with the resume checkpoint (see
+// SaveCheckpoint), so that the analyses of a resumed run are continuous
+// with those of the interrupted run, instead of starting cold: the
+// confusion matrix counts, and the weights of the category Decoder, the
+// additional Decoders, the Recon decoder, and the CNN Baseline.  The state
+// is saved by each MPI proc, as the confusion counts are per proc, in
+// gzipped json files named by the Checkpoint.Stats base name and the
+// rank.  If the file for a rank is missing on resume (e.g., with a
+// different number of procs), the decoder weights, which are the same on
+// all procs, are restored from that of rank 0, and the confusion counts
+// start cold.  The categories are saved too, and the category-indexed
+// stats are not restored if they differ from the current ones (see
+// catmap.go).
+
+// CkptStats is the stats state saved with the resume checkpoint
+type CkptStats struct {
+
+	// categories, in index order, for checking the category-indexed stats
+	Cats []string
+
+	// confusion matrix sums, per ground truth x response
+	ConfSum []float64
+
+	// confusion matrix counts, per ground truth
+	ConfN []float64
+
+	// weights of the category Decoder
+	Decoder []float32
+
+	// weights of the additional Decoders, by DecoderName
+	Decoders map[string][]float32
+
+	// weights of the Recon decoder, if on
+	Recon []float32
+
+	// weights of the CNN Baseline, if on: CW, CB of each stream, then FW, FB
+	Baseline [][]float32
+}
+
+// BaselineWts returns the weights of the CNN Baseline, for saving
+// and restoring, or nil if off
+func (ss *Sim) BaselineWts() [][]float32 {
+	bl := &ss.Baseline
+	if len(bl.Streams) == 0 {
+		return nil
+	}
+	return bl.Wts()
+}
+
+// CkptStatsFile returns the file name of the stats state of given rank,
+// for given base name
+func CkptStatsFile(base string, rank int) string {
+	return fmt.Sprintf("%s_%d.json.gz", base, rank)
+}
+
+// SaveCkptStats saves the stats state of this proc, for given base name
+func (ss *Sim) SaveCkptStats(base string) error {
+	cs := &CkptStats{}
+	cs.Cats = ss.LvisEnv(etime.Train).CatNames()
+	cs.ConfSum = ss.Stats.Confusion.Sum.Values
+	cs.ConfN = ss.Stats.Confusion.N.Values
+	cs.Decoder = ss.Decoder.Weights.Values
+	cs.Decoders = make(map[string][]float32)
+	for i, dec := range ss.Decoders {
+		cs.Decoders[ss.DecoderName(i)] = dec.Weights.Values
+	}
+	if ss.Recon.Target != nil {
+		cs.Recon = ss.Recon.Decoder.Weights.Values
+	}
+	cs.Baseline = ss.BaselineWts()
+	f, err := os.Create(CkptStatsFile(base, ss.MPIRank()))
+	if err != nil {
+		return fmt.Errorf("SaveCkptStats: %w", err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	if err := json.NewEncoder(gw).Encode(cs); err != nil {
+		return fmt.Errorf("SaveCkptStats: %w", err)
+	}
+	return gw.Close()
+}
+
+// openCkptStats opens the stats state from given file
+func openCkptStats(fnm string) (*CkptStats, error) {
+	f, err := os.Open(fnm)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fnm, err)
+	}
+	cs := &CkptStats{}
+	if err := json.NewDecoder(gr).Decode(cs); err != nil {
+		return nil, fmt.Errorf("%s: %w", fnm, err)
+	}
+	return cs, nil
+}
+
+// restoreVals copies the saved values into given values, if they
+// are the same size, and reports a mismatch otherwise
+func restoreVals(name string, vals, saved []float32) {
+	if saved == nil {
+		return
+	}
+	if len(saved) != len(vals) {
+		mpi.Printf("OpenCkptStats: %s: saved size: %d != current size: %d, not restored\n", name, len(saved), len(vals))
+		return
+	}
+	copy(vals, saved)
+}
+
+// OpenCkptStats restores the stats state of this proc from given base
+// name -- called when resuming the run, after the stats are initialized
+func (ss *Sim) OpenCkptStats(base string) error {
+	conf := true
+	cs, err := openCkptStats(CkptStatsFile(base, ss.MPIRank()))
+	if err != nil && os.IsNotExist(err) && ss.MPIRank() != 0 {
+		conf = false
+		cs, err = openCkptStats(CkptStatsFile(base, 0))
+	}
+	if err != nil {
+		return fmt.Errorf("OpenCkptStats: %w", err)
+	}
+	if err := ss.CheckCats("OpenCkptStats", cs.Cats); err != nil {
+		mpi.Printf("%s -- category-indexed stats not restored\n", err)
+		if ss.Recon.Target != nil {
+			restoreVals("Recon", ss.Recon.Decoder.Weights.Values, cs.Recon)
+		}
+		return nil
+	}
+	cm := &ss.Stats.Confusion
+	if conf && len(cs.ConfSum) == len(cm.Sum.Values) && len(cs.ConfN) == len(cm.N.Values) {
+		copy(cm.Sum.Values, cs.ConfSum)
+		copy(cm.N.Values, cs.ConfN)
+	} else if conf {
+		mpi.Printf("OpenCkptStats: Confusion: saved size: %d != current size: %d, not restored\n", len(cs.ConfN), len(cm.N.Values))
+	}
+	restoreVals("Decoder", ss.Decoder.Weights.Values, cs.Decoder)
+	for i, dec := range ss.Decoders {
+		nm := ss.DecoderName(i)
+		restoreVals("Decoder "+nm, dec.Weights.Values, cs.Decoders[nm])
+	}
+	if ss.Recon.Target != nil {
+		restoreVals("Recon", ss.Recon.Decoder.Weights.Values, cs.Recon)
+	}
+	if bw := ss.BaselineWts(); bw != nil && len(cs.Baseline) == len(bw) {
+		for i, wts := range bw {
+			restoreVals("Baseline", wts, cs.Baseline[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// clamp.go has the layer clamping intervention, for causal tests of the
+// function of hidden layer representations: an arbitrary activity pattern
+// is clamped onto any non-input layer over a range of cycles of each test
+// trial (see SetClamp), driving the units as external inputs in place of
+// their synaptic inputs (see SetLayerExt), while the rest of the network
+// settles as usual.  The clamp test (Config.Run.Clamp) injects the
+// prototype (mean test activity) of Config.Run.ClampCat in
+// Config.Run.ClampLay, or the pattern in Config.Run.ClampFile, and
+// measures the output bias toward ClampCat: the proportion of trials of
+// each category with a ClampCat response, without and with the clamp.
+// The cycle range is rounded up to multiples of 10, as the GPU only
+// updates inputs at this interval.
+
+// Clamp has the state of the layer clamping intervention
+type Clamp struct {
+
+	// layer to clamp -- nil if none
+	Lay *axon.Layer
+
+	// activity pattern to clamp, one value per unit
+	Pat []float32
+
+	// cycle within the trial to start clamping
+	Start int
+
+	// cycle within the trial to stop clamping
+	End int
+
+	// true while the clamp is applied
+	On bool
+}
+
+// clampCyc rounds the given cycle up to a multiple of 10
+func clampCyc(cyc int) int {
+	return ((cyc + 9) / 10) * 10
+}
+
+// SetClamp sets the given activity pattern to be clamped onto the given
+// layer from the start up to the end cycle of each test trial, until
+// ClearClamp is called.  The layer must not be an input or target layer.
+func (ss *Sim) SetClamp(lay string, pat []float32, start, end int) error {
+	ly := ss.Net.AxonLayerByName(lay)
+	if ly == nil {
+		return fmt.Errorf("SetClamp: layer %s not found", lay)
+	}
+	if ly.LayerType().IsExt() {
+		return fmt.Errorf("SetClamp: layer %s is an input layer", lay)
+	}
+	if len(pat) != int(ly.NNeurons) {
+		return fmt.Errorf("SetClamp: pattern has %d values, layer %s has %d units", len(pat), lay, ly.NNeurons)
+	}
+	ss.ClearClamp()
+	cl := &ss.Clamp
+	cl.Lay = ly
+	cl.Pat = pat
+	cl.Start = clampCyc(start)
+	cl.End = clampCyc(end)
+	return nil
+}
+
+// ClearClamp removes the clamp, if set
+func (ss *Sim) ClearClamp() {
+	ss.ClampOff()
+	ss.Clamp.Lay = nil
+	ss.Clamp.Pat = nil
+}
+
+// ClampOn applies the clamp pattern to all data indexes
+func (ss *Sim) ClampOn() {
+	cl := &ss.Clamp
+	ctx := &ss.Context
+	ss.Net.GPU.SyncNeuronsFmGPU()
+	for di := uint32(0); di < ctx.NetIdxs.NData; di++ {
+		SetLayerExt(ctx, cl.Lay, di, cl.Pat)
+	}
+	ss.Net.GPU.SyncNeuronsToGPU()
+	cl.On = true
+}
+
+// ClampOff removes the clamp pattern, if applied
+func (ss *Sim) ClampOff() {
+	cl := &ss.Clamp
+	if !cl.On {
+		return
+	}
+	ss.Net.GPU.SyncNeuronsFmGPU()
+	ClearLayerExt(&ss.Context, cl.Lay)
+	ss.Net.GPU.SyncNeuronsToGPU()
+	cl.On = false
+}
+
+// ClampCycle applies and removes the clamp at its start and end cycles --
+// called at the start of each test cycle
+func (ss *Sim) ClampCycle() {
+	cl := &ss.Clamp
+	if cl.Lay == nil {
+		return
+	}
+	cyc := ss.Loops.GetLoop(etime.Test, etime.Cycle).Counter.Cur
+	switch {
+	case cyc == cl.End:
+		ss.ClampOff()
+	case cyc == cl.Start:
+		ss.ClampOn()
+	}
+}
+
+// ClampProto returns the prototype of given category in given layer:
+// its mean activity over the most recent test epoch, from the category
+// accumulators (see ProtoRecord)
+func (ss *Sim) ClampProto(lay, cat string) ([]float32, error) {
+	cp, ok := ss.Protos[lay]
+	if !ok {
+		return nil, fmt.Errorf("Clamp: layer %s has no prototypes, must be one of: %v", lay, ss.ProtoLays())
+	}
+	ci := -1
+	for i, cn := range ss.LvisEnv(etime.Test).CatNames() {
+		if cn == cat {
+			ci = i
+			break
+		}
+	}
+	if ci < 0 || ci >= len(cp.N) {
+		return nil, fmt.Errorf("Clamp: ClampCat %s not found", cat)
+	}
+	if cp.N[ci] == 0 {
+		return nil, fmt.Errorf("Clamp: ClampCat %s has no test items", cat)
+	}
+	pat := make([]float32, cp.NUnits)
+	sum := cp.Sum[ci*cp.NUnits : (ci+1)*cp.NUnits]
+	for i, s := range sum {
+		pat[i] = float32(s / cp.N[ci])
+	}
+	return pat, nil
+}
+
+// OpenClampPat opens an activity pattern from given file, of
+// whitespace-separated values, one per unit
+func OpenClampPat(fnm string) ([]float32, error) {
+	b, err := os.ReadFile(fnm)
+	if err != nil {
+		return nil, err
+	}
+	flds := strings.Fields(string(b))
+	pat := make([]float32, len(flds))
+	for i, fs := range flds {
+		v, err := strconv.ParseFloat(fs, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Clamp: %s: %w", fnm, err)
+		}
+		pat[i] = float32(v)
+	}
+	return pat, nil
+}
+
+// ClampResp returns the proportion of trials with a response of the given
+// category per trial category, and overall, from the Test Trial log of the
+// most recent test epoch
+func (ss *Sim) ClampResp(cat string) (map[string]float64, float64) {
+	dt := ss.Logs.Table(etime.Test, etime.Trial)
+	n := map[string]float64{}
+	nr := map[string]float64{}
+	tot := 0.0
+	for ri := 0; ri < dt.Rows; ri++ {
+		tc := dt.CellString("TrlCat", ri)
+		n[tc]++
+		if dt.CellString("TrlResp", ri) == cat {
+			nr[tc]++
+			tot++
+		}
+	}
+	for tc := range n {
+		nr[tc] /= n[tc]
+	}
+	if dt.Rows > 0 {
+		tot /= float64(dt.Rows)
+	}
+	return nr, tot
+}
+
+// ConfigClampTable configures the table of clamp test results per category
+func (ss *Sim) ConfigClampTable(dt *etable.Table, rows int) {
+	dt.SetMetaData("name", "ClampCats")
+	dt.SetMetaData("desc", "layer clamping test proportion of ClampCat responses per category")
+	dt.SetFromSchema(etable.Schema{
+		{"Cat", etensor.STRING, nil, nil},
+		{"BaseResp", etensor.FLOAT64, nil, nil},
+		{"ClampResp", etensor.FLOAT64, nil, nil},
+		{"DResp", etensor.FLOAT64, nil, nil},
+	}, rows)
+}
+
+// RunClamp runs the layer clamping test: a baseline test, which also
+// computes the ClampCat prototype, and a test with the ClampCat prototype
+// or ClampFile pattern clamped onto ClampLay from ClampStart to ClampEnd.
+// The proportion of ClampCat responses per category is printed and saved
+// (in nogui mode).
+func (ss *Sim) RunClamp() error {
+	rc := &ss.Config.Run
+	if rc.ClampCat == "" {
+		return fmt.Errorf("Clamp: ClampCat must be set")
+	}
+	clamp := rc.Clamp
+	rc.Clamp = true // turns on the category accumulators
+	defer func() { rc.Clamp = clamp }()
+	ss.ClearClamp()
+	ss.TestAll()
+	basePct := ss.TestEpochFloat("PctErr", false)
+	baseResp, baseTot := ss.ClampResp(rc.ClampCat)
+	var pat []float32
+	var err error
+	if rc.ClampFile != "" {
+		pat, err = OpenClampPat(rc.ClampFile)
+	} else {
+		pat, err = ss.ClampProto(rc.ClampLay, rc.ClampCat)
+	}
+	if err != nil {
+		return err
+	}
+	for i := range pat {
+		pat[i] *= rc.ClampGain
+	}
+	if err := ss.SetClamp(rc.ClampLay, pat, rc.ClampStart, rc.ClampEnd); err != nil {
+		return err
+	}
+	ss.TestAll()
+	ss.ClearClamp()
+	clampPct := ss.TestEpochFloat("PctErr", false)
+	clampResp, clampTot := ss.ClampResp(rc.ClampCat)
+
+	dt := &etable.Table{}
+	ss.ConfigClampTable(dt, 0)
+	for _, cn := range ss.LvisEnv(etime.Test).CatNames() {
+		br, ok := baseResp[cn]
+		if !ok {
+			continue
+		}
+		cr := clampResp[cn]
+		row := dt.Rows
+		dt.SetNumRows(row + 1)
+		dt.SetCellString("Cat", row, cn)
+		dt.SetCellFloat("BaseResp", row, br)
+		dt.SetCellFloat("ClampResp", row, cr)
+		dt.SetCellFloat("DResp", row, cr-br)
+	}
+	ss.Logs.MiscTables["ClampCats"] = dt
+	mpi.Printf("Clamp: %s in %s, cycles %d-%d: %s responses: %g -> %g  PctErr: %g -> %g\n", rc.ClampCat, rc.ClampLay, ss.Clamp.Start, ss.Clamp.End, rc.ClampCat, baseTot, clampTot, basePct, clampPct)
+	if ss.Config.GUI || ss.MPIRank() != 0 {
+		return nil
+	}
+	fnm := elog.LogFileName("clamp_cats", ss.Net.Name(), ss.Stats.String("RunName"))
+	if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	mpi.Printf("Saved clamp results to: %s\n", fnm)
+	return nil
+}
+
+// RunClampGUI runs the layer clamping test, has stop running = false at end -- for gui
+func (ss *Sim) RunClampGUI() {
+	ss.GUI.StopNow = false
+	if err := ss.RunClamp(); err != nil {
+		mpi.Println(err)
+	}
+	ss.GUI.Stopped()
+}
@@ -26,14 +26,23 @@ type ColorVis struct {
 	DoGOnGains []float32                   `desc:"OnGain factors -- 1 = perfect balance, otherwise has relative imbalance for capturing main effects"`
 	Geom       vfilter.Geom                `inactive:"+" view:"inline" desc:"geometry of input, output"`
 	KWTA       kwta.KWTA                   `desc:"kwta parameters"`
+	SepKWTA    bool                        `desc:"if true, compute kwta separately for each color channel (Red-Green, Blue-Yellow), each with its own ChanGi pool inhibition -- otherwise one kwta pools over both channels, so the stronger channel suppresses the weaker one"`
+	ChanGi     [2]float32                  `desc:"pool-level inhibition gain for each color channel (Red-Green, Blue-Yellow), used instead of KWTA.PoolFFFB.Gi if SepKWTA -- higher = sparser"`
 	DoGTsr     etensor.Float32             `view:"no-inline" desc:"DoG filter tensor -- has 3 filters (on, off, net)"`
 	DoGTab     etable.Table                `view:"no-inline" desc:"DoG filter table (view only)"`
 	KwtaTsr    etensor.Float32             `view:"no-inline" desc:"kwta output tensor"`
 	OutAll     etensor.Float32             `view:"no-inline" desc:"output from 3 dogs with different tuning -- this is what goes into input layer"`
 	OutTsrs    map[string]*etensor.Float32 `view:"no-inline" desc:"DoG filter output tensors"`
 	Inhibs     fffb.Inhibs                 `view:"no-inline" desc:"inhibition values for KWTA"`
+	ChanRaw    [2]etensor.Float32          `view:"-" desc:"per color channel input to kwta, if SepKWTA"`
+	ChanKwta   [2]etensor.Float32          `view:"-" desc:"per color channel kwta output, if SepKWTA"`
+	ChanInhibs [2]fffb.Inhibs              `view:"-" desc:"per color channel inhibition values for kwta, if SepKWTA"`
 }
 
+// ColorChans are the names of the color opponent channels, which
+// alternate in the RGBY dimension of OutAll and KwtaTsr
+var ColorChans = []string{"RG", "BY"}
+
 func (vi *ColorVis) Defaults(bord_ex, sz, spc int, img *V1Img) {
 	vi.Img = img
 	vi.DoGNames = []string{"Bal"} // , "On", "Off"} // balanced, gain toward On, gain toward Off
@@ -50,6 +59,8 @@ func (vi *ColorVis) Defaults(bord_ex, sz, spc int, img *V1Img) {
 	vi.KWTA.PoolFFFB.Gi = 1.2
 	vi.KWTA.XX1.Gain = 80
 	vi.KWTA.XX1.NVar = 0.01
+	vi.SepKWTA = false
+	vi.ChanGi = [2]float32{1.2, 1.2}
 
 	// note: first arg is border -- we are relying on Geom
 	// to set border to .5 * filter size
@@ -138,13 +149,68 @@ func (vi *ColorVis) AggAll() {
 		vfilter.OuterAgg(i*2, 0, rgtsr, &vi.OutAll)
 		vfilter.OuterAgg(i*2+1, 0, bytsr, &vi.OutAll)
 	}
-	if vi.KWTA.On {
+	if vi.KWTA.On && vi.SepKWTA {
+		vi.KWTAChans()
+	} else if vi.KWTA.On {
 		vi.KWTA.KWTAPool(&vi.OutAll, &vi.KwtaTsr, &vi.Inhibs, nil)
 	} else {
 		vi.KwtaTsr.CopyFrom(&vi.OutAll)
 	}
 }
 
+// KWTAChans computes kwta separately for each color channel,
+// using the ChanGi pool inhibition for each
+func (vi *ColorVis) KWTAChans() {
+	ny := vi.OutAll.Dim(0)
+	nx := vi.OutAll.Dim(1)
+	nf := vi.OutAll.Dim(3)
+	nc := len(ColorChans)
+	vi.KwtaTsr.CopyShapeFrom(&vi.OutAll)
+	cshp := []int{ny, nx, 2, nf / nc}
+	for ch := range ColorChans {
+		raw := &vi.ChanRaw[ch]
+		if !etensor.EqualInts(cshp, raw.Shp) {
+			raw.SetShape(cshp, nil, []string{"Y", "X", "OnOff", "DoG"})
+		}
+		for i, v := range vi.OutAll.Values {
+			if f := i % nf; f%nc == ch {
+				raw.Values[(i/nf)*(nf/nc)+f/nc] = v
+			}
+		}
+		kw := vi.KWTA
+		kw.PoolFFFB.Gi = vi.ChanGi[ch]
+		kw.KWTAPool(raw, &vi.ChanKwta[ch], &vi.ChanInhibs[ch], nil)
+		act := vi.ChanKwta[ch].Values
+		for i := range vi.KwtaTsr.Values {
+			if f := i % nf; f%nc == ch {
+				vi.KwtaTsr.Values[i] = act[(i/nf)*(nf/nc)+f/nc]
+			}
+		}
+	}
+}
+
+// ChanAct returns the average kwta output activity of given color
+// channel, and the proportion of units with activity above thr
+func (vi *ColorVis) ChanAct(ch int, thr float32) (avg, pctAct float32) {
+	nf := vi.KwtaTsr.Dim(3)
+	nc := len(ColorChans)
+	n := 0
+	for i, v := range vi.KwtaTsr.Values {
+		if (i%nf)%nc != ch {
+			continue
+		}
+		avg += v
+		if v > thr {
+			pctAct++
+		}
+		n++
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return avg / float32(n), pctAct / float32(n)
+}
+
 // Filter is overall method to run filters on image set by SetImage*
 func (vi *ColorVis) Filter() {
 	vi.ColorDoG()
@@ -0,0 +1,188 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"log"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// compare.go has A/B evaluation of two weight files on the identical test set.
+
+// TestWts loads the given weights file and runs the full test set, with
+// the results in the Test Trial log.  The test env is re-initialized with
+// its fixed random seed, so the same image transforms are presented for
+// every call.
+func (ss *Sim) TestWts(fname string) error {
+	err := ss.OpenWeights(gi.FileName(fname))
+	if err != nil {
+		return err
+	}
+	ss.Net.InitActs(&ss.Context)
+	ss.TestAll()
+	return nil
+}
+
+// TestWtsTable runs TestWts on the given weights file, returning a copy
+// of the resulting test trial log.
+func (ss *Sim) TestWtsTable(fname string) (*etable.Table, error) {
+	if err := ss.TestWts(fname); err != nil {
+		return nil, err
+	}
+	return ss.Logs.Table(etime.Test, etime.Trial).Clone(), nil
+}
+
+// CompareWts runs the identical test set through the network with weights
+// from file a and then from file b (sequentially, in the same network),
+// and saves a paired comparison of per-category accuracy (cmp_cat) and
+// the per-image decision flips, where the response differs (cmp_flips).
+// The current weights are saved first and restored at the end.
+func (ss *Sim) CompareWts(a, b gi.FileName) (err error) {
+	mpi.Printf("Comparing weights: A: %s  B: %s\n", a, b)
+	var cur bytes.Buffer
+	if err := ss.Net.WriteWtsJSON(&cur); err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := ss.Net.ReadWtsJSON(&cur); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+	ta, err := ss.TestWtsTable(string(a))
+	if err != nil {
+		return err
+	}
+	tb, err := ss.TestWtsTable(string(b))
+	if err != nil {
+		return err
+	}
+	cats, flips := ss.CompareTables(ta, tb)
+	ss.Logs.MiscTables["CompareCats"] = cats
+	ss.Logs.MiscTables["CompareFlips"] = flips
+	if ss.MPIRank() != 0 {
+		return nil
+	}
+	runName := ss.Stats.String("RunName")
+	fnm := elog.LogFileName("cmp_cat", ss.Net.Name(), runName)
+	if err := cats.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	mpi.Printf("Saved per-category comparison to: %s\n", fnm)
+	fnm = elog.LogFileName("cmp_flips", ss.Net.Name(), runName)
+	if err := flips.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	mpi.Printf("Saved %d decision flips to: %s\n", flips.Rows, fnm)
+	return nil
+}
+
+// CompareTables returns paired comparison tables from two test trial logs
+// for the same test set: per-category accuracy for each, and the
+// per-image decision flips, matched by TrialName.
+func (ss *Sim) CompareTables(ta, tb *etable.Table) (cats, flips *etable.Table) {
+	catNames := ss.LvisEnv(etime.Test).CatNames()
+	ncats := len(catNames)
+	catMap := make(map[string]int, ncats)
+	for ci, cat := range catNames {
+		catMap[cat] = ci
+	}
+
+	cats = &etable.Table{}
+	cats.SetFromSchema(etable.Schema{
+		{"Cat", etensor.STRING, nil, nil},
+		{"N", etensor.INT64, nil, nil},
+		{"PctCorA", etensor.FLOAT64, nil, nil},
+		{"PctCorB", etensor.FLOAT64, nil, nil},
+		{"DiffBA", etensor.FLOAT64, nil, nil},
+		{"NFlips", etensor.INT64, nil, nil},
+	}, ncats)
+	flips = &etable.Table{}
+	flips.SetFromSchema(etable.Schema{
+		{"TrialName", etensor.STRING, nil, nil},
+		{"Cat", etensor.STRING, nil, nil},
+		{"RespA", etensor.STRING, nil, nil},
+		{"RespB", etensor.STRING, nil, nil},
+		{"ErrA", etensor.FLOAT64, nil, nil},
+		{"ErrB", etensor.FLOAT64, nil, nil},
+	}, 0)
+
+	brows := make(map[string]int, tb.Rows)
+	for ri := 0; ri < tb.Rows; ri++ {
+		brows[tb.CellString("TrialName", ri)] = ri
+	}
+	ns := make([]int, ncats)
+	corA := make([]float64, ncats)
+	corB := make([]float64, ncats)
+	nflips := make([]int, ncats)
+	for ri := 0; ri < ta.Rows; ri++ {
+		tnm := ta.CellString("TrialName", ri)
+		bi, ok := brows[tnm]
+		if !ok {
+			continue
+		}
+		cat := ta.CellString("TrlCat", ri)
+		ci, ok := catMap[cat]
+		if !ok {
+			continue
+		}
+		errA := ta.CellFloat("Err", ri)
+		errB := tb.CellFloat("Err", bi)
+		ns[ci]++
+		corA[ci] += 1 - errA
+		corB[ci] += 1 - errB
+		rspA := ta.CellString("TrlResp", ri)
+		rspB := tb.CellString("TrlResp", bi)
+		if rspA == rspB {
+			continue
+		}
+		nflips[ci]++
+		row := flips.Rows
+		flips.SetNumRows(row + 1)
+		flips.SetCellString("TrialName", row, tnm)
+		flips.SetCellString("Cat", row, cat)
+		flips.SetCellString("RespA", row, rspA)
+		flips.SetCellString("RespB", row, rspB)
+		flips.SetCellFloat("ErrA", row, errA)
+		flips.SetCellFloat("ErrB", row, errB)
+	}
+	for ci, cat := range catNames {
+		cats.SetCellString("Cat", ci, cat)
+		cats.SetCellFloat("N", ci, float64(ns[ci]))
+		cats.SetCellFloat("NFlips", ci, float64(nflips[ci]))
+		if ns[ci] == 0 {
+			continue
+		}
+		pa := corA[ci] / float64(ns[ci])
+		pb := corB[ci] / float64(ns[ci])
+		cats.SetCellFloat("PctCorA", ci, pa)
+		cats.SetCellFloat("PctCorB", ci, pb)
+		cats.SetCellFloat("DiffBA", ci, pb-pa)
+	}
+	return
+}
+
+// CompareWtsGUI runs CompareWts in a separate goroutine, for the GUI.
+// The resulting tables are in Logs.MiscTables.
+func (ss *Sim) CompareWtsGUI(a, b gi.FileName) {
+	if ss.GUI.IsRunning {
+		return
+	}
+	ss.GUI.IsRunning = true
+	ss.GUI.StopNow = false
+	ss.GUI.ToolBar.UpdateActions()
+	go func() {
+		if err := ss.CompareWts(a, b); err != nil {
+			log.Println(err)
+		}
+		ss.GUI.Stopped()
+	}()
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/etable/agg"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// confent.go has the per-epoch summary of the structure of the residual
+// category errors, complementing the full confusion matrix saved every
+// ConfusionEpc epochs: the errors of the epoch (from the TrlCat and TrlResp
+// of the trial log, gathered across MPI procs) are counted per pair of
+// categories, in either direction, and summarized as:
+//
+//	ConfEnt  entropy (bits) of the distribution of errors over the pairs:
+//	         0 if all errors are between one pair, higher as they spread
+//	ConfTop  the ConfTopN most confused pairs, as CatA/CatB:n
+//
+// These are computed once the training epoch is past Config.Run.ConfusionEpc,
+// and are NaN and empty before then.  Trials without a response are not
+// counted.
+
+// ConfTopN is the number of most confused category pairs listed in ConfTop
+const ConfTopN = 3
+
+// ConfPair is the number of errors between a pair of categories
+type ConfPair struct {
+
+	// category names, in sorted order
+	A, B string
+
+	// number of errors in either direction
+	N int
+}
+
+// ConfPairs returns the error counts per pair of categories for the
+// trials in given trial log view, sorted by descending count, and
+// then by name
+func ConfPairs(ix *etable.IdxView) []ConfPair {
+	dt := ix.Table
+	if dt.ColIdx("TrlCat") < 0 || dt.ColIdx("TrlResp") < 0 {
+		return nil
+	}
+	cnt := map[[2]string]int{}
+	for _, ri := range ix.Idxs {
+		cat := dt.CellString("TrlCat", ri)
+		rsp := dt.CellString("TrlResp", ri)
+		if rsp == cat || rsp == "none" || rsp == "" {
+			continue
+		}
+		if rsp < cat {
+			cat, rsp = rsp, cat
+		}
+		cnt[[2]string{cat, rsp}]++
+	}
+	prs := make([]ConfPair, 0, len(cnt))
+	for k, n := range cnt {
+		prs = append(prs, ConfPair{A: k[0], B: k[1], N: n})
+	}
+	sort.Slice(prs, func(i, j int) bool {
+		if prs[i].N != prs[j].N {
+			return prs[i].N > prs[j].N
+		}
+		if prs[i].A != prs[j].A {
+			return prs[i].A < prs[j].A
+		}
+		return prs[i].B < prs[j].B
+	})
+	return prs
+}
+
+// ConfEntropy returns the entropy in bits of the distribution of
+// errors over given category pairs, and a listing of the top n pairs
+func ConfEntropy(prs []ConfPair, n int) (ent float64, top string) {
+	tot := 0
+	for _, pr := range prs {
+		tot += pr.N
+	}
+	if tot == 0 {
+		return 0, ""
+	}
+	for _, pr := range prs {
+		p := float64(pr.N) / float64(tot)
+		ent -= p * math.Log2(p)
+	}
+	var tops []string
+	for i, pr := range prs {
+		if i >= n {
+			break
+		}
+		tops = append(tops, fmt.Sprintf("%s/%s:%d", pr.A, pr.B, pr.N))
+	}
+	return ent, strings.Join(tops, " ")
+}
+
+// ConfEntStats computes the ConfEnt and ConfTop stats from the trial log
+// of given mode -- called in Log at the epoch level, after the trial log
+// is gathered across MPI procs
+func (ss *Sim) ConfEntStats(mode etime.Modes) {
+	trnEpc := ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur
+	if trnEpc <= ss.Config.Run.ConfusionEpc {
+		ss.Stats.SetFloat("ConfEnt", math.NaN())
+		ss.Stats.SetString("ConfTop", "")
+		return
+	}
+	ent, top := ConfEntropy(ConfPairs(ss.Logs.IdxView(mode, etime.Trial)), ConfTopN)
+	ss.Stats.SetFloat("ConfEnt", ent)
+	ss.Stats.SetString("ConfTop", top)
+}
+
+// ConfigConfEntLogs adds the ConfEnt and ConfTop items to the epoch logs,
+// with the mean ConfEnt over the last 5 epochs in the train run log
+func (ss *Sim) ConfigConfEntLogs() {
+	ss.Stats.SetFloat("ConfEnt", math.NaN())
+	ss.Stats.SetString("ConfTop", "")
+	ss.Logs.AddItem(&elog.Item{
+		Name: "ConfEnt",
+		Type: etensor.FLOAT64,
+		Plot: true,
+		Write: elog.WriteMap{
+			etime.Scope(etime.AllModes, etime.Epoch): func(ctx *elog.Context) {
+				ctx.SetStatFloat(ctx.Item.Name)
+			}, etime.Scope(etime.Train, etime.Run): func(ctx *elog.Context) {
+				ix := ctx.LastNRows(ctx.Mode, etime.Epoch, 5)
+				ctx.SetFloat64(agg.Mean(ix, ctx.Item.Name)[0])
+			}}})
+	ss.Logs.AddStatStringItem(etime.AllModes, etime.Epoch, "ConfTop")
+}
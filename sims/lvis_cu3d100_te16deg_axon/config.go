@@ -14,6 +14,9 @@ type EnvConfig struct {
 	// env parameters -- can set any field/subfield on Env struct, using standard TOML formatting
 	Env map[string]any `desc:"env parameters -- can set any field/subfield on Env struct, using standard TOML formatting"`
 
+	// [def: Images] type of env: Images for the standard ImagesEnv, or the name of a custom LvisEnv type registered in LvisEnvTypes -- see lvisenv.go
+	Type string `def:"Images" desc:"type of env: Images for the standard ImagesEnv, or the name of a custom LvisEnv type registered in LvisEnvTypes -- see lvisenv.go"`
+
 	// other option: "images/CU3D_100_plus_renders", ImageFile = "cu3d100plus"
 	// works somewhat worse
 
@@ -23,11 +26,215 @@ type EnvConfig struct {
 	// [def: cu3d100old] file with list of images
 	ImageFile string `def:"cu3d100old" desc:"file with list of images"`
 
+	// [def: 1] random seed for generating the train / test split of images -- recorded in the split info file and verified when the split is loaded
+	SplitSeed int64 `def:"1" desc:"random seed for generating the train / test split of images -- recorded in the split info file and verified when the split is loaded"`
+
+	// if true, generate a new train / test split from the images in Path using SplitSeed, and save it, replacing any existing split files
+	NewSplit bool `desc:"if true, generate a new train / test split from the images in Path using SplitSeed, and save it, replacing any existing split files"`
+
+	// if true, when opening weights that were saved with a different train / test split than the current one, restore the split saved with the weights, instead of just warning about it
+	RestoreSplit bool `desc:"if true, when opening weights that were saved with a different train / test split than the current one, restore the split saved with the weights, instead of just warning about it"`
+
+	// if true, when the saved train / test split is opened, check its image lists against the images actually present in Path, which rescans the whole image directory at startup -- done for the training env only
+	CheckFiles bool `desc:"if true, when the saved train / test split is opened, check its image lists against the images actually present in Path, which rescans the whole image directory at startup -- done for the training env only"`
+
+	// if true, include the high-resolution 16 degree pathway: V1h16 -> V2h16 -> V3h16 -> V4f16, which roughly doubles the number of V1 and V2 units -- see the size report printed at startup and compare -bench timing with and without
+	High16 bool `desc:"if true, include the high-resolution 16 degree pathway: V1h16 -> V2h16 -> V3h16 -> V4f16, which roughly doubles the number of V1 and V2 units -- see the size report printed at startup and compare -bench timing with and without"`
+
+	// [view: add-fields] color channels of the V1 inputs: separate color rows in the V1 layers, and the kWTA inhibition of the V1C color DoG layers for each color channel
+	V1Color V1ColorConfig `view:"add-fields" desc:"color channels of the V1 inputs: separate color rows in the V1 layers, and the kWTA inhibition of the V1C color DoG layers for each color channel"`
+
+	// file name of a table (.tsv or .csv) with a Cat column and Freq and / or Targ columns, specifying per-category training trial frequency and output target strength weights, for imbalanced datasets -- categories not listed have weight 1
+	CatWeights string `desc:"file name of a table (.tsv or .csv) with a Cat column and Freq and / or Targ columns, specifying per-category training trial frequency and output target strength weights, for imbalanced datasets -- categories not listed have weight 1"`
+
+	// if true, weight training trial frequency inversely by the number of images in each category, so all categories are presented equally often -- multiplies any Freq weights from CatWeights
+	CatFreqByN bool `desc:"if true, weight training trial frequency inversely by the number of images in each category, so all categories are presented equally often -- multiplies any Freq weights from CatWeights"`
+
+	// file name of a TOML file mapping category names in the image file names to the names used in the model, with one entry per line of the old name = the quoted new name -- categories with the same new name are merged, e.g., for synonyms and case inconsistencies, and the merged categories are used for the output patterns, scoring, and logs -- only for category names in file names
+	CatRename string `desc:"file name of a TOML file mapping category names in the image file names to the names used in the model, with one entry per line of the old name = the quoted new name -- categories with the same new name are merged, e.g., for synonyms and case inconsistencies, and the merged categories are used for the output patterns, scoring, and logs -- only for category names in file names"`
+
+	// [def: 1] [min: 0] [max: 1] fraction of the training images in each category to use, e.g., 0.1 or 0.25, for studying sample efficiency and quick experiments -- selected deterministically per category with TrainFracSeed, after the train / test split, so the test set is unchanged.  The counts actually used are printed, saved in a train_frac log file (in nogui mode), and logged as the NTrainImgs stat
+	TrainFrac float32 `def:"1" min:"0" max:"1" desc:"fraction of the training images in each category to use, e.g., 0.1 or 0.25, for studying sample efficiency and quick experiments -- selected deterministically per category with TrainFracSeed, after the train / test split, so the test set is unchanged.  The counts actually used are printed, saved in a train_frac log file (in nogui mode), and logged as the NTrainImgs stat"`
+
+	// [def: 1] minimum number of training images (or items, if TrainFracItems) per category to use when TrainFrac < 1
+	TrainFracMin int `def:"1" desc:"minimum number of training images (or items, if TrainFracItems) per category to use when TrainFrac < 1"`
+
+	// if true, TrainFrac selects a fraction of the object items (e.g., 3D models) in each category, with all of their images, instead of a fraction of the images
+	TrainFracItems bool `desc:"if true, TrainFrac selects a fraction of the object items (e.g., 3D models) in each category, with all of their images, instead of a fraction of the images"`
+
+	// [def: 1] random seed for the TrainFrac selection, combined with a hash of each category name -- for a given seed, the images for smaller fractions are a subset of those for larger ones
+	TrainFracSeed int64 `def:"1" desc:"random seed for the TrainFrac selection, combined with a hash of each category name -- for a given seed, the images for smaller fractions are a subset of those for larger ones"`
+
+	// [def: Stretch] how to fit non-square images to the square input, prior to the transforms: Stretch = resize, distorting the aspect ratio, Letterbox = pad the shorter side with the background fill so the whole image is visible, Crop = crop the longer side at the center -- the visible proportion of each image and the proportion of the input it fills are logged as VisFrac and FillFrac
+	Aspect string `def:"Stretch" desc:"how to fit non-square images to the square input, prior to the transforms: Stretch = resize, distorting the aspect ratio, Letterbox = pad the shorter side with the background fill so the whole image is visible, Crop = crop the longer side at the center -- the visible proportion of each image and the proportion of the input it fills are logged as VisFrac and FillFrac"`
+
 	// [def: 5] number of units per localist output unit
 	NOutPer int `def:"5" desc:"number of units per localist output unit"`
 
+	// [def: Block] spatial layout of the localist output patterns, which interacts with the inhibition in the Output layer: Block = NOutPer contiguous units per category, Scatter = NOutPer units per category at fixed random positions across the layer, Gaussian = graded Gaussian blob of OutSigma width centered on the Block units of each category
+	OutLayout string `def:"Block" desc:"spatial layout of the localist output patterns, which interacts with the inhibition in the Output layer: Block = NOutPer contiguous units per category, Scatter = NOutPer units per category at fixed random positions across the layer, Gaussian = graded Gaussian blob of OutSigma width centered on the Block units of each category"`
+
+	// [def: 1.5] width (sigma) of the Gaussian OutLayout patterns, in units
+	OutSigma float32 `def:"1.5" desc:"width (sigma) of the Gaussian OutLayout patterns, in units"`
+
 	// [def: false] if true, use random output patterns -- else localist
 	RndOutPats bool `def:"false" desc:"if true, use random output patterns -- else localist"`
+
+	// [def: Regen] how to handle a saved rndpats_*.tsv random output pattern file (used with RndOutPats) that does not match the current output size, number of categories, and pattern activity and minimum difference: Regen = print a warning and regenerate the patterns, overwriting the file, Error = exit with an error -- see rndpats.go
+	RndPatsCheck string `def:"Regen" desc:"how to handle a saved rndpats_*.tsv random output pattern file (used with RndOutPats) that does not match the current output size, number of categories, and pattern activity and minimum difference: Regen = print a warning and regenerate the patterns, overwriting the file, Error = exit with an error -- see rndpats.go"`
+
+	// file name of precomputed text embeddings of the category labels (one line per category: name followed by the values, as in the GloVe text format), used as distributed output targets instead of localist or random patterns, with cosine-based scoring of the output -- the EmbedOutPats params are applied -- see embed.go
+	OutEmbed string `desc:"file name of precomputed text embeddings of the category labels (one line per category: name followed by the values, as in the GloVe text format), used as distributed output targets instead of localist or random patterns, with cosine-based scoring of the output -- the EmbedOutPats params are applied -- see embed.go"`
+
+	// [view: add-fields] object pose / viewpoint metadata parsed from the image file names, and analysis of test error vs. rotation from the canonical view
+	Pose PoseConfig `view:"add-fields" desc:"object pose / viewpoint metadata parsed from the image file names, and analysis of test error vs. rotation from the canonical view"`
+
+	// [view: add-fields] same / different two-alternative forced choice task, with a second output head
+	SameDiff SameDiffConfig `view:"add-fields" desc:"same / different two-alternative forced choice task, with a second output head"`
+
+	// [view: add-fields] identity output head, trained on the specific object in each image alongside the category, with identity generalization to held-out views
+	Id IdentityConfig `view:"add-fields" desc:"identity output head, trained on the specific object in each image alongside the category, with identity generalization to held-out views"`
+
+	// [view: add-fields] illumination transforms (color temperature shift and global gain), for training augmentation and a test sweep of color constancy
+	Illum IllumConfig `view:"add-fields" desc:"illumination transforms (color temperature shift and global gain), for training augmentation and a test sweep of color constancy"`
+
+	// [view: add-fields] normalization of the images prior to the V1 filtering, for image sets with different luminance and contrast statistics
+	Norm InputNormConfig `view:"add-fields" desc:"normalization of the images prior to the V1 filtering, for image sets with different luminance and contrast statistics"`
+}
+
+// V1ColorConfig has config parameters for the color channels (Red-Green,
+// Blue-Yellow) of the V1 inputs, which are summarized in the V1 input
+// report printed at startup with Stats -- see v1color.go
+type V1ColorConfig struct {
+
+	// apply the SepColor, SepKWTA, RGGi and BYGi params here to the V1 filters of the envs -- otherwise the filters keep their own settings (which can also be set by the Env params)
+	On bool `desc:"apply the SepColor, SepKWTA, RGGi and BYGi params here to the V1 filters of the envs -- otherwise the filters keep their own settings (which can also be set by the Env params)"`
+
+	// print the V1 input report at startup, and log the V1C color input activity stats (V1C_RGAct etc) per trial, with the epoch averages, if the color DoG inputs are used
+	Stats bool `desc:"print the V1 input report at startup, and log the V1C color input activity stats (V1C_RGAct etc) per trial, with the epoch averages, if the color DoG inputs are used"`
+
+	// record separate rows for each color channel in the V1 simple-cell pools of the V1 layers (V1m16 etc), instead of the max over all colors, so each pool has 9 rows instead of 5
+	SepColor bool `desc:"record separate rows for each color channel in the V1 simple-cell pools of the V1 layers (V1m16 etc), instead of the max over all colors, so each pool has 9 rows instead of 5"`
+
+	// compute the kWTA of the V1C color DoG layers separately for each color channel, using RGGi and BYGi -- otherwise one kWTA pools over both channels, so the stronger channel suppresses the weaker one
+	SepKWTA bool `desc:"compute the kWTA of the V1C color DoG layers separately for each color channel, using RGGi and BYGi -- otherwise one kWTA pools over both channels, so the stronger channel suppresses the weaker one"`
+
+	// [def: 1.2] kWTA pool inhibition for the Red-Green channel, if SepKWTA -- higher = sparser: adjust to get the target activity in the V1C_RGAct stat
+	RGGi float32 `def:"1.2" desc:"kWTA pool inhibition for the Red-Green channel, if SepKWTA -- higher = sparser: adjust to get the target activity in the V1C_RGAct stat"`
+
+	// [def: 1.2] kWTA pool inhibition for the Blue-Yellow channel, if SepKWTA -- higher = sparser: adjust to get the target activity in the V1C_BYAct stat
+	BYGi float32 `def:"1.2" desc:"kWTA pool inhibition for the Blue-Yellow channel, if SepKWTA -- higher = sparser: adjust to get the target activity in the V1C_BYAct stat"`
+
+	// [def: 0.1] activity threshold for counting a V1C unit as active, in the V1C_RGPctAct and V1C_BYPctAct stats
+	ActThr float32 `def:"0.1" desc:"activity threshold for counting a V1C unit as active, in the V1C_RGPctAct and V1C_BYPctAct stats"`
+}
+
+// SameDiffConfig has config parameters for the same / different task,
+// where images are presented in sample, probe pairs of trials, and the
+// SameDiff output layer reports whether the probe is from the same
+// category as the sample -- see samediff.go
+type SameDiffConfig struct {
+
+	// if true, add the SameDiff output layer, receiving from TE, and present the images in sample, probe pairs of trials, training the SameDiff layer on the probe trials to report whether the probe is from the same category as the sample -- requires the Images env
+	On bool `desc:"if true, add the SameDiff output layer, receiving from TE, and present the images in sample, probe pairs of trials, training the SameDiff layer on the probe trials to report whether the probe is from the same category as the sample -- requires the Images env"`
+
+	// [def: 0.5] [min: 0] [max: 1] probability that the probe is from the same category as the sample (a different image if possible) -- otherwise it is from a random other category
+	PSame float32 `def:"0.5" min:"0" max:"1" desc:"probability that the probe is from the same category as the sample (a different image if possible) -- otherwise it is from a random other category"`
+}
+
+// PoseConfig has config parameters for parsing the object pose / viewpoint
+// from the image file names, and the analysis of test error vs. rotation
+// from the canonical view -- see pose.go
+type PoseConfig struct {
+
+	// [def: false] parse the pose of each image, log it per trial (View, Az, El, Rot), and compute the Pose and PoseView tables of test error vs. rotation from the canonical view and render view at the end of each test epoch, with the PoseSlope stat
+	On bool `def:"false" desc:"parse the pose of each image, log it per trial (View, Az, El, Rot), and compute the Pose and PoseView tables of test error vs. rotation from the canonical view and render view at the end of each test epoch, with the PoseSlope stat"`
+
+	// [def: _(?P<view>\d+)\.\w+$] regular expression matched against the image file name, with optional named groups: az = azimuth (left-right rotation) and el = elevation, in degrees, and view = render view index, which is mapped to az, el with ViewFile -- the default gets the view index from CU3D names such as airplane_001_00005.png
+	Regexp string `def:"_(?P<view>\\d+)\\.\\w+$" desc:"regular expression matched against the image file name, with optional named groups: az = azimuth (left-right rotation) and el = elevation, in degrees, and view = render view index, which is mapped to az, el with ViewFile -- the default gets the view index from CU3D names such as airplane_001_00005.png"`
+
+	// file name of a table (.tsv or .csv) with View, Az, El columns giving the azimuth and elevation in degrees of each render view index, for file names that only have the view index
+	ViewFile string `desc:"file name of a table (.tsv or .csv) with View, Az, El columns giving the azimuth and elevation in degrees of each render view index, for file names that only have the view index"`
+
+	// [def: 0] azimuth of the canonical view, in degrees
+	CanonAz float32 `def:"0" desc:"azimuth of the canonical view, in degrees"`
+
+	// [def: 0] elevation of the canonical view, in degrees
+	CanonEl float32 `def:"0" desc:"elevation of the canonical view, in degrees"`
+
+	// [def: 10] [min: 1] bin size in degrees of rotation from the canonical view for the Pose table
+	Bin float32 `def:"10" min:"1" desc:"bin size in degrees of rotation from the canonical view for the Pose table"`
+}
+
+// IdentityConfig has config parameters for the identity output head,
+// where the IdOutput layer is trained on the identity of the object in
+// each image, in addition to the category -- see identity.go
+type IdentityConfig struct {
+
+	// if true, add the IdOutput layer, receiving from TE, with one unit per object in the training images, trained on the object of each image alongside the category on the Output layer -- requires the Images env
+	On bool `desc:"if true, add the IdOutput layer, receiving from TE, with one unit per object in the training images, trained on the object of each image alongside the category on the Output layer -- requires the Images env"`
+
+	// [def: ^(.+)_\d+\.\w+$] regular expression matched against the image file name, with the first group giving the object -- the default gets airplane_001 from CU3D names such as airplane_001_00005.png
+	Regexp string `def:"^(.+)_\\d+\\.\\w+$" desc:"regular expression matched against the image file name, with the first group giving the object -- the default gets airplane_001 from CU3D names such as airplane_001_00005.png"`
+
+	// [def: 5] every GenEvery-th image of each object (in name order) is held out from the identity training, and the identity error on these is logged as IdGenErr, for generalization to new views -- 0 = none held out
+	GenEvery int `def:"5" desc:"every GenEvery-th image of each object (in name order) is held out from the identity training, and the identity error on these is logged as IdGenErr, for generalization to new views -- 0 = none held out"`
+}
+
+// IllumConfig has the config for the illumination transforms of the
+// images, for training augmentation and a test sweep -- see illum.go
+type IllumConfig struct {
+
+	// [def: 0] maximum random color temperature shift of the training images, plus or minus: the red channel is scaled by 1+t and the blue by 1-t -- 0 = none
+	TempMax float32 `def:"0" desc:"maximum random color temperature shift of the training images, plus or minus: the red channel is scaled by 1+t and the blue by 1-t -- 0 = none"`
+
+	// [def: 1] minimum random global gain of the training images
+	GainMin float32 `def:"1" desc:"minimum random global gain of the training images"`
+
+	// [def: 1] maximum random global gain of the training images
+	GainMax float32 `def:"1" desc:"maximum random global gain of the training images"`
+
+	// run the illumination sweep on the test images, with the OpenWts weights if set, save the illum log of test error vs. illumination, and quit (in nogui mode)
+	Sweep bool `desc:"run the illumination sweep on the test images, with the OpenWts weights if set, save the illum log of test error vs. illumination, and quit (in nogui mode)"`
+
+	// [def: [-0.4,-0.2,0,0.2,0.4]] color temperature shifts tested in the sweep
+	SweepTemps []float32 `def:"[-0.4,-0.2,0,0.2,0.4]" desc:"color temperature shifts tested in the sweep"`
+
+	// [def: [0.5,1,1.5]] global gains tested in the sweep, for each of the SweepTemps
+	SweepGains []float32 `def:"[0.5,1,1.5]" desc:"global gains tested in the sweep, for each of the SweepTemps"`
+}
+
+// InputNormConfig has the config for the normalization of the images
+// prior to the V1 filtering -- see inputnorm.go
+type InputNormConfig struct {
+
+	// [def: None] normalization of each image: None, ZScore = luminance set to Mean mean and SD standard deviation, Contrast = RMS contrast (luminance standard deviation / mean) set to Contrast, keeping the mean, HistEq = luminance histogram equalization, preserving the hue
+	Method string `def:"None" desc:"normalization of each image: None, ZScore = luminance set to Mean mean and SD standard deviation, Contrast = RMS contrast (luminance standard deviation / mean) set to Contrast, keeping the mean, HistEq = luminance histogram equalization, preserving the hue"`
+
+	// [def: 0.5] mean luminance for ZScore, 0-1
+	Mean float32 `def:"0.5" desc:"mean luminance for ZScore, 0-1"`
+
+	// [def: 0.2] luminance standard deviation for ZScore, 0-1
+	SD float32 `def:"0.2" desc:"luminance standard deviation for ZScore, 0-1"`
+
+	// [def: 0.3] RMS contrast for Contrast
+	Contrast float32 `def:"0.3" desc:"RMS contrast for Contrast"`
+}
+
+// InDriftConfig has config parameters for the input drift monitor
+type InDriftConfig struct {
+
+	// [def: false] log the average activity (ActAvg.ActMAvg) of each input layer at each training epoch, and alert (log and stderr) when it differs from the reference by more than Tol
+	On bool `def:"false" desc:"log the average activity (ActAvg.ActMAvg) of each input layer at each training epoch, and alert (log and stderr) when it differs from the reference by more than Tol"`
+
+	// [def: 0.25] tolerance for the difference from the reference, relative to the reference value
+	Tol float32 `def:"0.25" desc:"tolerance for the difference from the reference, relative to the reference value"`
+
+	// [def: 1] training epoch at the end of which the reference values are taken, if no Ref file -- they are saved as an in_ref log
+	RefEpoch int `def:"1" desc:"training epoch at the end of which the reference values are taken, if no Ref file -- they are saved as an in_ref log"`
+
+	// file with the reference values (Layer, ActAvg columns), as saved in the in_ref log of a previous run known to be good -- if empty, the values at RefEpoch are used
+	Ref string `desc:"file with the reference values (Layer, ActAvg columns), as saved in the in_ref log of a previous run known to be good -- if empty, the values at RefEpoch are used"`
 }
 
 // ParamConfig has config parameters related to sim params
@@ -51,6 +258,45 @@ type ParamConfig struct {
 	// [def: true] if true, organize layers and connectivity with 2x2 sub-pools within each topological pool
 	SubPools bool `def:"true" desc:"if true, organize layers and connectivity with 2x2 sub-pools within each topological pool"`
 
+	// [def: standard] network size preset: tiny, small, standard, or large, which consistently scales the number of units per pool in V2, V4, TEO, and TE, and the number of pools in TEO and TE, for scaling experiments -- see NetSizes in netsize.go
+	NetSize string `def:"standard" desc:"network size preset: tiny, small, standard, or large, which consistently scales the number of units per pool in V2, V4, TEO, and TE, and the number of pools in TEO and TE, for scaling experiments -- see NetSizes in netsize.go"`
+
+	// [def: FSFFFB] inhibition configuration: FFFB = fast-only pooled FFFB (no slow SS component), FSFFFB = standard pooled fast and slow FFFB, Lateral = learned lateral inhibitory projections within V2, V4, TEO, TE (standard error-driven learning, not Hebbian), with weaker pooled inhibition, Mixed = lateral projections plus standard pooled inhibition -- see InhibSheets in params.go
+	Inhib string `def:"FSFFFB" desc:"inhibition configuration: FFFB = fast-only pooled FFFB (no slow SS component), FSFFFB = standard pooled fast and slow FFFB, Lateral = learned lateral inhibitory projections within V2, V4, TEO, TE (standard error-driven learning, not Hebbian), with weaker pooled inhibition, Mixed = lateral projections plus standard pooled inhibition -- see InhibSheets in params.go"`
+
+	// params-style selector for the inhibitory projections sent directly by excitatory layers (e.g., .LatInhib for Inhib Lateral or Mixed) to route through a separate population of inhibitory interneurons for each sending layer, enforcing Dale's law, so that each neuron is either excitatory or inhibitory -- all other projections are excitatory-only, as axon weights are always positive -- empty = off.  See dale.go
+	Dale string `desc:"params-style selector for the inhibitory projections sent directly by excitatory layers (e.g., .LatInhib for Inhib Lateral or Mixed) to route through a separate population of inhibitory interneurons for each sending layer, enforcing Dale's law, so that each neuron is either excitatory or inhibitory -- all other projections are excitatory-only, as axon weights are always positive -- empty = off.  See dale.go"`
+
+	// [def: 2] number of inhibitory interneurons per pool along each dimension, for the Dale interneuron layers
+	DaleNInh int `def:"2" min:"1" desc:"number of inhibitory interneurons per pool along each dimension, for the Dale interneuron layers"`
+
+	// tie the weights of each projection in the 16 deg pathway to the corresponding one in the 8 deg pathway (e.g., V2m16 -> V4f16 with V2m8 -> V4f8), which start with the same weights and learn from their averaged DWts, halving the number of free parameters -- to test whether weight sharing across scales improves scale invariance.  Projections with different connectivity (random V1 shortcuts) are not tied.  See sharewts.go
+	ShareWts bool `desc:"tie the weights of each projection in the 16 deg pathway to the corresponding one in the 8 deg pathway (e.g., V2m16 -> V4f16 with V2m8 -> V4f8), which start with the same weights and learn from their averaged DWts, halving the number of free parameters -- to test whether weight sharing across scales improves scale invariance.  Projections with different connectivity (random V1 shortcuts) are not tied.  See sharewts.go"`
+
+	// optional weight decay and soft weight bounding for selected projections, applied to DWt prior to WtFmDWt -- e.g., for .ToOut projections where weights tend to saturate
+	WtDecay []WtDecayConfig `desc:"optional weight decay and soft weight bounding for selected projections, applied to DWt prior to WtFmDWt -- e.g., for .ToOut projections where weights tend to saturate"`
+
+	// [def: 1] interval in training trials between applications of the WtDecay Decay, which is multiplied by the interval so the overall rate is the same -- the SoftMax bounding is applied on every trial.  Each trial with WtDecay changes requires syncing synapses from the GPU, which is expensive.
+	WtDecayInterval int `def:"1" desc:"interval in training trials between applications of the WtDecay Decay, which is multiplied by the interval so the overall rate is the same -- the SoftMax bounding is applied on every trial.  Each trial with WtDecay changes requires syncing synapses from the GPU, which is expensive."`
+
+	// optional activity-dependent structural plasticity for selected projections: weak synapses are periodically pruned (made silent) and replaced by new randomly initialized synapses within the same receiving unit's topographic footprint
+	Rewire []RewireConfig `desc:"optional activity-dependent structural plasticity for selected projections: weak synapses are periodically pruned (made silent) and replaced by new randomly initialized synapses within the same receiving unit's topographic footprint"`
+
+	// [def: 10] interval in training epochs between Rewire pruning and growth steps.  Rewire also requires syncing synapses from the GPU every trial to keep silent synapses silent, which is expensive.
+	RewireInterval int `def:"10" desc:"interval in training epochs between Rewire pruning and growth steps.  Rewire also requires syncing synapses from the GPU every trial to keep silent synapses silent, which is expensive."`
+
+	// optional projection-level dropout for selected projections: entire projections are silenced (no conductance and no learning) with some probability on each training trial, e.g., for .BackPrjn projections or shortcut pathways, to study the role of each pathway and regularize learning.  Not free on the GPU: each trial on which the set of dropped projections changes uploads all the params to the GPU
+	Dropout []DropoutConfig `desc:"optional projection-level dropout for selected projections: entire projections are silenced (no conductance and no learning) with some probability on each training trial, e.g., for .BackPrjn projections or shortcut pathways, to study the role of each pathway and regularize learning.  Not free on the GPU: each trial on which the set of dropped projections changes uploads all the params to the GPU"`
+
+	// optional learning rule ablations for selected projections, switching them to a mix of Hebbian (CPCA) and error-driven learning, e.g., pure Hebbian or pure error-driven, to quantify the contribution of each learning component to the final representation -- see ConfigLearnRule
+	LearnRule []LearnRuleConfig `desc:"optional learning rule ablations for selected projections, switching them to a mix of Hebbian (CPCA) and error-driven learning, e.g., pure Hebbian or pure error-driven, to quantify the contribution of each learning component to the final representation -- see ConfigLearnRule"`
+
+	// optional scheduled growth of selected projections, which are built but disabled (zero scale, no learning) until a given training epoch, e.g., to turn on .BackPrjn projections after the V4 features stabilize
+	Grow []GrowConfig `desc:"optional scheduled growth of selected projections, which are built but disabled (zero scale, no learning) until a given training epoch, e.g., to turn on .BackPrjn projections after the V4 features stabilize"`
+
+	// optional schedules of param values over training epochs, e.g., annealing Layer.Inhib.Layer.Gi from higher values early in training to lower values later, learning rate drops, or turning off the env TransSigma -- see sched.go
+	Schedule []ScheduleConfig `desc:"optional schedules of param values over training epochs, e.g., annealing Layer.Inhib.Layer.Gi from higher values early in training to lower values later, learning rate drops, or turning off the env TransSigma -- see sched.go"`
+
 	// Save a snapshot of all current param and config settings in a directory named params_<datestamp> (or _good if Good is true), then quit -- useful for comparing to later changes and seeing multiple views of current params
 	SaveAll bool `nest:"+" desc:"Save a snapshot of all current param and config settings in a directory named params_<datestamp> (or _good if Good is true), then quit -- useful for comparing to later changes and seeing multiple views of current params"`
 
@@ -58,12 +304,107 @@ type ParamConfig struct {
 	Good bool `nest:"+" desc:"for SaveAll, save to params_good for a known good params state.  This can be done prior to making a new release after all tests are passing -- add results to git to provide a full diff record of all params over time."`
 }
 
+// WtDecayConfig specifies weight decay and soft weight bounding
+// for projections matching a params-style selector
+type WtDecayConfig struct {
+
+	// params-style selector for projections to apply to: .Class, #Name, or Type
+	Sel string `desc:"params-style selector for projections to apply to: .Class, #Name, or Type"`
+
+	// L2-style weight decay rate per trial, pulling the linear weight LWt toward its neutral 0.5 value, so Wt decays toward SWt
+	Decay float32 `desc:"L2-style weight decay rate per trial, pulling the linear weight LWt toward its neutral 0.5 value, so Wt decays toward SWt"`
+
+	// if > 0, positive weight changes are multiplied by (1 - Wt / SoftMax), clipped at 0, softly bounding weights below this value
+	SoftMax float32 `desc:"if > 0, positive weight changes are multiplied by (1 - Wt / SoftMax), clipped at 0, softly bounding weights below this value"`
+}
+
+// RewireConfig specifies structural plasticity for projections
+// matching a params-style selector.  The connectivity of the network
+// is fixed, so absent synapses are represented by silent synapses,
+// with a weight of 0 and no learning.
+type RewireConfig struct {
+
+	// params-style selector for projections to apply to: .Class, #Name, or Type
+	Sel string `desc:"params-style selector for projections to apply to: .Class, #Name, or Type"`
+
+	// active synapses with Wt below this value are pruned at each rewiring step, e.g., 0.05
+	PruneThr float32 `desc:"active synapses with Wt below this value are pruned at each rewiring step, e.g., 0.05"`
+
+	// proportion of synapses within each receiving unit's footprint that start out silent, providing the pool from which new synapses are grown, e.g., 0.2
+	InitSilent float32 `desc:"proportion of synapses within each receiving unit's footprint that start out silent, providing the pool from which new synapses are grown, e.g., 0.2"`
+}
+
+// DropoutConfig specifies projection-level dropout for projections
+// matching a params-style selector
+type DropoutConfig struct {
+
+	// params-style selector for projections to apply to: .Class, #Name, or Type
+	Sel string `desc:"params-style selector for projections to apply to: .Class, #Name, or Type"`
+
+	// probability of dropping each projection on each training trial
+	P float32 `desc:"probability of dropping each projection on each training trial"`
+
+	// if true, all the selected projections are dropped together on a given trial, e.g., to silence an entire pathway -- otherwise each is dropped independently
+	Joint bool `desc:"if true, all the selected projections are dropped together on a given trial, e.g., to silence an entire pathway -- otherwise each is dropped independently"`
+}
+
+// LearnRuleConfig specifies the learning rule for projections
+// matching a params-style selector
+type LearnRuleConfig struct {
+
+	// params-style selector for projections to apply to: .Class, #Name, or Type
+	Sel string `desc:"params-style selector for projections to apply to: .Class, #Name, or Type"`
+
+	// proportion of Hebbian learning, with the remainder error-driven: 1 = pure Hebbian, 0 = pure error-driven
+	Hebb float32 `min:"0" max:"1" desc:"proportion of Hebbian learning, with the remainder error-driven: 1 = pure Hebbian, 0 = pure error-driven"`
+}
+
+// GrowConfig specifies the training epoch at which projections
+// matching a params-style selector are enabled
+type GrowConfig struct {
+
+	// params-style selector for projections to apply to: .Class, #Name, or Type
+	Sel string `desc:"params-style selector for projections to apply to: .Class, #Name, or Type"`
+
+	// training epoch at which the projections are enabled -- they have zero scale and no learning before this epoch
+	Epoch int `desc:"training epoch at which the projections are enabled -- they have zero scale and no learning before this epoch"`
+}
+
+// ScheduleConfig specifies a schedule of values over training epochs
+// for a param, for objects matching a params-style selector
+type ScheduleConfig struct {
+
+	// params-style selector for the objects to apply to: .Class, #Name, or Type -- or Env for a field of the training env
+	Sel string `desc:"params-style selector for the objects to apply to: .Class, #Name, or Type -- or Env for a field of the training env"`
+
+	// param path, e.g., Layer.Inhib.Layer.Gi, Prjn.Learn.LRate.Base, or TransSigma for the Env
+	Path string `desc:"param path, e.g., Layer.Inhib.Layer.Gi, Prjn.Learn.LRate.Base, or TransSigma for the Env"`
+
+	// training epochs at which the Vals apply, in increasing order -- before the first one, the base params apply
+	Epochs []int `desc:"training epochs at which the Vals apply, in increasing order -- before the first one, the base params apply"`
+
+	// param values at each of the Epochs
+	Vals []float32 `desc:"param values at each of the Epochs"`
+
+	// if true, values are linearly interpolated between the Epochs -- else each value applies until the next epoch
+	Interp bool `desc:"if true, values are linearly interpolated between the Epochs -- else each value applies until the next epoch"`
+}
+
 // RunConfig has config parameters related to running the sim
 type RunConfig struct {
 
 	// use MPI message passing interface for data parallel computation between nodes running identical copies of the same sim, sharing DWt changes
 	MPI bool `desc:"use MPI message passing interface for data parallel computation between nodes running identical copies of the same sim, sharing DWt changes"`
 
+	// file name of a hyperparameter search list, for distributed search over MPI colors (requires MPI): each non-empty line (# = comment) has the command-line args for one configuration, applied on top of the base config.  The MPI world is split into one communicator per configuration, each training independently on an equal share of the procs, and rank 0 saves the aggregated results table
+	Search string `desc:"file name of a hyperparameter search list, for distributed search over MPI colors (requires MPI): each non-empty line (# = comment) has the command-line args for one configuration, applied on top of the base config.  The MPI world is split into one communicator per configuration, each training independently on an equal share of the procs, and rank 0 saves the aggregated results table"`
+
+	// [def: 0] interval in training epochs between checks that the weights of the MPI procs have not diverged, by comparing a hash of the weights across procs -- 0 = off, except in Debug mode, which checks every epoch
+	ReplicaCheck int `def:"0" desc:"interval in training epochs between checks that the weights of the MPI procs have not diverged, by comparing a hash of the weights across procs -- 0 = off, except in Debug mode, which checks every epoch"`
+
+	// [def: true] if the ReplicaCheck detects divergence, broadcast the rank 0 weights to all procs, log the incident, and continue -- otherwise the job is aborted
+	ReplicaRecover bool `def:"true" desc:"if the ReplicaCheck detects divergence, broadcast the rank 0 weights to all procs, log the incident, and continue -- otherwise the job is aborted"`
+
 	// [def: true] use the GPU for computation -- generally faster even for small models if NData ~16
 	GPU bool `def:"true" desc:"use the GPU for computation -- generally faster even for small models if NData ~16"`
 
@@ -73,6 +414,12 @@ type RunConfig struct {
 	// [def: 16] [min: 1] number of data-parallel items to process in parallel per trial -- works (and is significantly faster) for both CPU and GPU.  Results in an effective mini-batch of learning.
 	NData int `def:"16" min:"1" desc:"number of data-parallel items to process in parallel per trial -- works (and is significantly faster) for both CPU and GPU.  Results in an effective mini-batch of learning."`
 
+	// training epochs at which the number of data-parallel trials per step changes to the corresponding NDataVals, e.g., for smaller mini-batches early in training -- NData applies before the first epoch
+	NDataEpochs []int `desc:"training epochs at which the number of data-parallel trials per step changes to the corresponding NDataVals, e.g., for smaller mini-batches early in training -- NData applies before the first epoch"`
+
+	// values of the number of data-parallel trials per training step, starting at the corresponding NDataEpochs -- must evenly divide NData, which is the max allocated.  Testing always uses NData.
+	NDataVals []int `desc:"values of the number of data-parallel trials per training step, starting at the corresponding NDataEpochs -- must evenly divide NData, which is the max allocated.  Testing always uses NData."`
+
 	// [def: 0] number of parallel threads for CPU computation -- 0 = use default
 	NThreads int `def:"0" desc:"number of parallel threads for CPU computation -- 0 = use default"`
 
@@ -82,11 +429,14 @@ type RunConfig struct {
 	// [def: 1] [min: 1] total number of runs to do when running Train
 	NRuns int `def:"1" min:"1" desc:"total number of runs to do when running Train"`
 
+	// base random seed from which the seeds of all the random components (network, envs, decoder and other global rand users, odd-one-out triplets, split-half partitions) are derived for each run, to fully reproduce a run -- the seeds are printed and logged in the training run log.  0 = the original seeds: the run number for the network and global rand, and fixed env seeds.
+	Seed int64 `desc:"base random seed from which the seeds of all the random components (network, envs, decoder and other global rand users, odd-one-out triplets, split-half partitions) are derived for each run, to fully reproduce a run -- the seeds are printed and logged in the training run log.  0 = the original seeds: the run number for the network and global rand, and fixed env seeds."`
+
 	// [def: 500] total number of epochs per run -- mostly asymptotes at 1,000 with small continued improvements out to 2,000.  500 is fine for most purposes
 	NEpochs int `def:"500" desc:"total number of epochs per run -- mostly asymptotes at 1,000 with small continued improvements out to 2,000.  500 is fine for most purposes"`
 
-	// [def: 512] total number of trials per epoch.  Should be an even multiple of NData.
-	NTrials int `def:"512" desc:"total number of trials per epoch.  Should be an even multiple of NData."`
+	// [def: 512] total number of trials per epoch, across all MPI procs -- rounded up to an even multiple of NData times the number of procs, see TrialsAllocator
+	NTrials int `def:"512" desc:"total number of trials per epoch, across all MPI procs -- rounded up to an even multiple of NData times the number of procs, see TrialsAllocator"`
 
 	// [def: 10] how frequently (in epochs) to compute PCA on hidden representations to measure variance?
 	PCAInterval int `def:"10" desc:"how frequently (in epochs) to compute PCA on hidden representations to measure variance?"`
@@ -94,8 +444,291 @@ type RunConfig struct {
 	// [def: 500] epoch to start recording confusion matrix
 	ConfusionEpc int `def:"500" desc:"epoch to start recording confusion matrix"`
 
+	// [def: 0] minimum peak Output activity (ActM) for the closest category to be taken as the response -- below this, the trial is scored as no response, which counts as an error, logged separately as NoResp.  0 = always respond.
+	RespThr float32 `def:"0" desc:"minimum peak Output activity (ActM) for the closest category to be taken as the response -- below this, the trial is scored as no response, which counts as an error, logged separately as NoResp.  0 = always respond."`
+
+	// [def: 0.1] output margin (see OutErrMargin) at or above which a correct response is logged as Confident, and below which as a Guess
+	RespConfMargin float32 `def:"0.1" desc:"output margin (see OutErrMargin) at or above which a correct response is logged as Confident, and below which as a Guess"`
+
 	// [def: 20] how often to run through all the test patterns, in terms of training epochs -- can use 0 or -1 for no testing
 	TestInterval int `def:"20" desc:"how often to run through all the test patterns, in terms of training epochs -- can use 0 or -1 for no testing"`
+
+	// how often to run a FastTest, in terms of training epochs, testing a fixed random subset of FastTestNPerCat test images per category, for cheap intermediate checks between the full tests every TestInterval epochs -- 0 = off.  FastTest errors are logged in the training epoch log with a Fst prefix, and Tst items only reflect full tests.
+	FastTestInterval int `desc:"how often to run a FastTest, in terms of training epochs, testing a fixed random subset of FastTestNPerCat test images per category, for cheap intermediate checks between the full tests every TestInterval epochs -- 0 = off.  FastTest errors are logged in the training epoch log with a Fst prefix, and Tst items only reflect full tests."`
+
+	// [def: 2] number of test images per category in the FastTest subset
+	FastTestNPerCat int `def:"2" min:"1" desc:"number of test images per category in the FastTest subset"`
+
+	// inference only mode, for testing saved weights: the per-data synapse learning state is not allocated (reducing memory by more than half for NData > 1), learning is turned off, and in nogui mode the OpenWts weights are tested on the full test set, and it quits
+	Infer bool `desc:"inference only mode, for testing saved weights: the per-data synapse learning state is not allocated (reducing memory by more than half for NData > 1), learning is turned off, and in nogui mode the OpenWts weights are tested on the full test set, and it quits"`
+
+	// weights file to open for Infer, Prime, Cool, Clamp, Gain, Ablate, and RFSize modes
+	OpenWts string `desc:"weights file to open for Infer, Prime, Cool, Clamp, Gain, Ablate, and RFSize modes"`
+
+	// resume checkpoint file saved when a nogui run is interrupted by SIGTERM or SIGUSR1, to continue the run from the start of the epoch in which it was interrupted, with the checkpoint weights -- logs are saved to new files with a resume suffix
+	Resume string `desc:"resume checkpoint file saved when a nogui run is interrupted by SIGTERM or SIGUSR1, to continue the run from the start of the epoch in which it was interrupted, with the checkpoint weights -- logs are saved to new files with a resume suffix"`
+
+	// [def: 10] [min: 1] number of training or testing trials between checks for a SIGTERM or SIGUSR1 in nogui mode, after which the interrupted run stops -- under MPI each check is an AllReduce across procs, so this limits the communication per trial
+	SignalInterval int `def:"10" min:"1" desc:"number of training or testing trials between checks for a SIGTERM or SIGUSR1 in nogui mode, after which the interrupted run stops -- under MPI each check is an AllReduce across procs, so this limits the communication per trial"`
+
+	// run the priming paradigm on the test items, with the OpenWts weights if set, save the results, and quit (in nogui mode): on each trial a related (same category) or unrelated prime image is presented for PrimeCycles, followed by the target without resetting activity -- see Env.PrimeRelP
+	Prime bool `desc:"run the priming paradigm on the test items, with the OpenWts weights if set, save the results, and quit (in nogui mode): on each trial a related (same category) or unrelated prime image is presented for PrimeCycles, followed by the target without resetting activity -- see Env.PrimeRelP"`
+
+	// [def: 50] number of cycles to present the prime image before the target, for the priming paradigm -- rounded up to a multiple of 10
+	PrimeCycles int `def:"50" desc:"number of cycles to present the prime image before the target, for the priming paradigm -- rounded up to a multiple of 10"`
+
+	// run the virtual cooling test, with the OpenWts weights if set, save the results, and quit (in nogui mode): the category selectivity of the CoolLay units is measured on a separate pass over CoolNPerCat training images per category, and the test set is run with and without the CoolK most selective units silenced, to measure the impact on the error for each category, as in cooling or optogenetic silencing experiments
+	Cool bool `desc:"run the virtual cooling test, with the OpenWts weights if set, save the results, and quit (in nogui mode): the category selectivity of the CoolLay units is measured on a separate pass over CoolNPerCat training images per category, and the test set is run with and without the CoolK most selective units silenced, to measure the impact on the error for each category, as in cooling or optogenetic silencing experiments"`
+
+	// [def: TE] layer to silence units in for the virtual cooling test: one of the ProtoLays layers, TEOf16, TEOf8, or TE
+	CoolLay string `def:"TE" desc:"layer to silence units in for the virtual cooling test: one of the ProtoLays layers, TEOf16, TEOf8, or TE"`
+
+	// [def: 20] number of units to silence for the virtual cooling test, with the highest d-prime category selectivity
+	CoolK int `def:"20" min:"1" desc:"number of units to silence for the virtual cooling test, with the highest d-prime category selectivity"`
+
+	// [def: 10] [min: 1] number of training images per category used to measure the category selectivity of the units for the virtual cooling test, separate from the test images used to measure the impact of silencing them
+	CoolNPerCat int `def:"10" min:"1" desc:"number of training images per category used to measure the category selectivity of the units for the virtual cooling test, separate from the test images used to measure the impact of silencing them"`
+
+	// if set, only units whose best category is this one are silenced for the virtual cooling test, for category-targeted silencing
+	CoolCat string `desc:"if set, only units whose best category is this one are silenced for the virtual cooling test, for category-targeted silencing"`
+
+	// run the layer clamping test, with the OpenWts weights if set, save the results, and quit (in nogui mode): the test set is run once to compute the ClampCat prototype in ClampLay, and again with the prototype (or ClampFile pattern) clamped onto ClampLay from ClampStart to ClampEnd, to measure the bias toward ClampCat responses, as a causal intervention on the layer representation
+	Clamp bool `desc:"run the layer clamping test, with the OpenWts weights if set, save the results, and quit (in nogui mode): the test set is run once to compute the ClampCat prototype in ClampLay, and again with the prototype (or ClampFile pattern) clamped onto ClampLay from ClampStart to ClampEnd, to measure the bias toward ClampCat responses, as a causal intervention on the layer representation"`
+
+	// [def: TEOf16] layer to clamp for the layer clamping test: any non-input layer, but one of the ProtoLays layers, TEOf16, TEOf8, or TE, for the ClampCat prototype
+	ClampLay string `def:"TEOf16" desc:"layer to clamp for the layer clamping test: any non-input layer, but one of the ProtoLays layers, TEOf16, TEOf8, or TE, for the ClampCat prototype"`
+
+	// category whose prototype (mean test activity in ClampLay) is clamped for the layer clamping test, and toward which the output bias is measured
+	ClampCat string `desc:"category whose prototype (mean test activity in ClampLay) is clamped for the layer clamping test, and toward which the output bias is measured"`
+
+	// if set, file with the activity pattern to clamp for the layer clamping test, in place of the ClampCat prototype: whitespace-separated values, one per unit of ClampLay
+	ClampFile string `desc:"if set, file with the activity pattern to clamp for the layer clamping test, in place of the ClampCat prototype: whitespace-separated values, one per unit of ClampLay"`
+
+	// [def: 1] multiplier on the clamped activity pattern for the layer clamping test
+	ClampGain float32 `def:"1" desc:"multiplier on the clamped activity pattern for the layer clamping test"`
+
+	// [def: 0] cycle within each trial to start clamping for the layer clamping test -- rounded up to a multiple of 10
+	ClampStart int `def:"0" desc:"cycle within each trial to start clamping for the layer clamping test -- rounded up to a multiple of 10"`
+
+	// [def: 150] cycle within each trial to stop clamping for the layer clamping test, 200 for the whole trial -- rounded up to a multiple of 10
+	ClampEnd int `def:"150" desc:"cycle within each trial to stop clamping for the layer clamping test, 200 for the whole trial -- rounded up to a multiple of 10"`
+
+	// run the layer gain test, with the OpenWts weights if set, save the results, and quit (in nogui mode): the excitatory conductance (Gbar.E, the multiplier on Ge) of GainLay is scaled by each of the GainFactors, and the test set is run at each, recording the test error and response time, mimicking neuromodulatory gain manipulations
+	Gain bool `desc:"run the layer gain test, with the OpenWts weights if set, save the results, and quit (in nogui mode): the excitatory conductance (Gbar.E, the multiplier on Ge) of GainLay is scaled by each of the GainFactors, and the test set is run at each, recording the test error and response time, mimicking neuromodulatory gain manipulations"`
+
+	// [def: TEOf16] layer whose gain is scaled for the layer gain test
+	GainLay string `def:"TEOf16" desc:"layer whose gain is scaled for the layer gain test"`
+
+	// [def: [0.8,0.9,1,1.1,1.2]] gain factors for the layer gain test
+	GainFactors []float32 `def:"[0.8,0.9,1,1.1,1.2]" desc:"gain factors for the layer gain test"`
+
+	// run the layer ablation sweep, with the OpenWts weights if set, save the results, and quit (in nogui mode): each Super layer in turn is turned off and the FastTestNPerCat subset of the test images is tested, recording the increase in error relative to the intact network, as a causal importance map of the layers
+	Ablate bool `desc:"run the layer ablation sweep, with the OpenWts weights if set, save the results, and quit (in nogui mode): each Super layer in turn is turned off and the FastTestNPerCat subset of the test images is tested, recording the increase in error relative to the intact network, as a causal importance map of the layers"`
+
+	// weights file to transplant into the TransplantSel layers and projections at the start of each run, after the weights are initialized -- the network can be configured differently from the one that saved the weights, and shapes are checked.  Can be .wts, .wts.gz or .wtsz.
+	Transplant string `desc:"weights file to transplant into the TransplantSel layers and projections at the start of each run, after the weights are initialized -- the network can be configured differently from the one that saved the weights, and shapes are checked.  Can be .wts, .wts.gz or .wtsz."`
+
+	// selectors for the layers and projections to transplant weights into from the Transplant file: #Name for a layer or projection (SendToRecv), .Class for a class or type -- a selected layer gets all of its receiving projections
+	TransplantSel []string `desc:"selectors for the layers and projections to transplant weights into from the Transplant file: #Name for a layer or projection (SendToRecv), .Class for a class or type -- a selected layer gets all of its receiving projections"`
+
+	// [def: false] accumulate per-image training difficulty stats across epochs: number of presentations, mean error, running average error rate (RunErr), and mean output margin, saved as a difficulty table sorted from hardest to easiest at the end of each run
+	Difficulty bool `def:"false" desc:"accumulate per-image training difficulty stats across epochs: number of presentations, mean error, running average error rate (RunErr), and mean output margin, saved as a difficulty table sorted from hardest to easiest at the end of each run"`
+
+	// [def: 0.2] rate constant for the per-image running average error rate, updated each epoch in which the image is presented
+	DifficultyDt float64 `def:"0.2" desc:"rate constant for the per-image running average error rate, updated each epoch in which the image is presented"`
+
+	// [def: 0] if > 0, sample hard training images more often, with frequency weight 1 + HardSample * RunErr from the Difficulty stats, updated each epoch -- turns on Difficulty
+	HardSample float32 `def:"0" desc:"if > 0, sample hard training images more often, with frequency weight 1 + HardSample * RunErr from the Difficulty stats, updated each epoch -- turns on Difficulty"`
+
+	// scan the configured image set, save per-image and per-category statistics (counts, image dimensions, mean luminance and contrast) in dataset_imgs and dataset_cats files, flag categories with fewer than DatasetMinN images, and quit (in nogui mode) -- for catching dataset problems before training
+	DatasetStats bool `desc:"scan the configured image set, save per-image and per-category statistics (counts, image dimensions, mean luminance and contrast) in dataset_imgs and dataset_cats files, flag categories with fewer than DatasetMinN images, and quit (in nogui mode) -- for catching dataset problems before training"`
+
+	// [def: 10] minimum number of images per category for DatasetStats, below which the category is flagged
+	DatasetMinN int `def:"10" desc:"minimum number of images per category for DatasetStats, below which the category is flagged"`
+
+	// if two weight file names are given, runs the test set through the network with each set of weights, saves a paired comparison of per-category accuracy and per-image decision flips, and quits (in nogui mode)
+	CompareWts []string `desc:"if two weight file names are given, runs the test set through the network with each set of weights, saves a paired comparison of per-category accuracy and per-image decision flips, and quits (in nogui mode)"`
+
+	// glob pattern of saved weights files (e.g., from different epochs or runs) to evaluate in sorted order on the full test set, with identical image transforms, saving a summary table of the test stats by file, run, and epoch as an eval_wts log, and quit (in nogui mode)
+	EvalWts string `desc:"glob pattern of saved weights files (e.g., from different epochs or runs) to evaluate in sorted order on the full test set, with identical image transforms, saving a summary table of the test stats by file, run, and epoch as an eval_wts log, and quit (in nogui mode)"`
+
+	// glob pattern of saved weights files from networks trained from different random seeds (e.g., the final weights of different runs) to test on the identical test sequence, saving the per-image decision agreement across seeds as a seed_agree log, and the agreement and error pattern correlation for each pair of seeds as a seed_pairs log, and quit (in nogui mode) -- see seedagree.go
+	SeedAgree string `desc:"glob pattern of saved weights files from networks trained from different random seeds (e.g., the final weights of different runs) to test on the identical test sequence, saving the per-image decision agreement across seeds as a seed_agree log, and the agreement and error pattern correlation for each pair of seeds as a seed_pairs log, and quit (in nogui mode) -- see seedagree.go"`
+
+	// glob pattern of saved weights files, of which the last SWAK periodic checkpoints of the last run, by epoch (excluding tagged files such as _best), are averaged into a new weights file with a _swa tag (stochastic weight averaging), then both the last and the averaged weights are tested on the full test set, saving the test stats as a swa log, and quit (in nogui mode) -- see swa.go
+	SWA string `desc:"glob pattern of saved weights files, of which the last SWAK periodic checkpoints of the last run, by epoch (excluding tagged files such as _best), are averaged into a new weights file with a _swa tag (stochastic weight averaging), then both the last and the averaged weights are tested on the full test set, saving the test stats as a swa log, and quit (in nogui mode) -- see swa.go"`
+
+	// [def: 5] number of the last SWA weights files to average -- it is an error if fewer are found -- 0 = all
+	SWAK int `def:"5" desc:"number of the last SWA weights files to average -- it is an error if fewer are found -- 0 = all"`
+
+	// distribute the EvalWts evaluation across MPI procs by condition instead of by test image: each proc tests the full test set for its share of the weights files x EvalGrid conditions, and the results are gathered on rank 0 and saved as an eval_grid log -- see evaldist.go
+	EvalDist bool `desc:"distribute the EvalWts evaluation across MPI procs by condition instead of by test image: each proc tests the full test set for its share of the weights files x EvalGrid conditions, and the results are gathered on rank 0 and saved as an eval_grid log -- see evaldist.go"`
+
+	// grid of test transform settings crossed with the EvalWts files in EvalDist: space-separated Dim=val1,val2,.. items, with Dim = Trans (max translation), Rot (max rotation), or Scale (scale range, as min:max or a single value), e.g., Trans=0,0.15,0.3 Rot=0,16 -- the test env settings are used for dims not given
+	EvalGrid string `desc:"grid of test transform settings crossed with the EvalWts files in EvalDist: space-separated Dim=val1,val2,.. items, with Dim = Trans (max translation), Rot (max rotation), or Scale (scale range, as min:max or a single value), e.g., Trans=0,0.15,0.3 Rot=0,16 -- the test env settings are used for dims not given"`
+
+	// saved epoch log files (.tsv) from any of the lvis sims to convert to the long-format results schema, in the Log.Export formats (csv if empty), saved next to each file with a _results suffix, and quits (in nogui mode)
+	ExportLogs []string `desc:"saved epoch log files (.tsv) from any of the lvis sims to convert to the long-format results schema, in the Log.Export formats (csv if empty), saved next to each file with a _results suffix, and quits (in nogui mode)"`
+
+	// [def: 0] interval in cycles for checking the first activity and first correct output cycle stats -- 0 = off.  On the GPU, neuron state must be synced at this interval, and layer state is only updated every 10 cycles, so 10 is the effective resolution.
+	FirstCycInterval int `def:"0" desc:"interval in cycles for checking the first activity and first correct output cycle stats -- 0 = off.  On the GPU, neuron state must be synced at this interval, and layer state is only updated every 10 cycles, so 10 is the effective resolution."`
+
+	// [def: 0.5] threshold on the layer-wide maximum CaSpkP for the first activity cycle stat
+	FirstActThr float32 `def:"0.5" desc:"threshold on the layer-wide maximum CaSpkP for the first activity cycle stat"`
+
+	// if true, log the magnitude of the error-driven learning signal in each hidden and output layer per training trial and epoch: the mean over units of |CaDiff| (CaP - CaD, the plus - minus phase difference that drives learning), and its ratio to that of the Output layer, to diagnose vanishing credit assignment down the hierarchy, e.g., in the High16 pathway.  On the GPU, neuron state is synced every training trial.
+	CaDiff bool `desc:"if true, log the magnitude of the error-driven learning signal in each hidden and output layer per training trial and epoch: the mean over units of |CaDiff| (CaP - CaD, the plus - minus phase difference that drives learning), and its ratio to that of the Output layer, to diagnose vanishing credit assignment down the hierarchy, e.g., in the High16 pathway.  On the GPU, neuron state is synced every training trial."`
+
+	// [def: false] compute category prototype stats at each test epoch: the mean TEO and TE activity per category (prototype), the mean within-category exemplar to prototype distance, and the mean between-category prototype distance, which are logged to track the emergence of categorical clustering
+	Protos bool `def:"false" desc:"compute category prototype stats at each test epoch: the mean TEO and TE activity per category (prototype), the mean within-category exemplar to prototype distance, and the mean between-category prototype distance, which are logged to track the emergence of categorical clustering"`
+
+	// [def: false] compute unit category selectivity stats at each test epoch, for the TEO and TE layers: the sparseness of each unit's mean response across categories, and the d-prime of its response to its best category vs. all others, logged as tensors in the test epoch log and as layer means
+	Selectivity bool `def:"false" desc:"compute unit category selectivity stats at each test epoch, for the TEO and TE layers: the sparseness of each unit's mean response across categories, and the d-prime of its response to its best category vs. all others, logged as tensors in the test epoch log and as layer means"`
+
+	// [def: false] compute the population and lifetime sparseness of the activity at each test epoch, by the normalized Treves-Rolls measure: population sparseness over the units in a layer per trial, averaged over trials, and lifetime sparseness over the trials per unit, averaged over units
+	Sparseness bool `def:"false" desc:"compute the population and lifetime sparseness of the activity at each test epoch, by the normalized Treves-Rolls measure: population sparseness over the units in a layer per trial, averaged over trials, and lifetime sparseness over the trials per unit, averaged over units"`
+
+	// layers for the Sparseness stats -- defaults to all hidden (Super) layers if empty
+	SparseLays []string `desc:"layers for the Sparseness stats -- defaults to all hidden (Super) layers if empty"`
+
+	// [def: 0] number of odd-one-out triplets to evaluate at each test epoch, across all MPI procs: two test items from the same category and one from another, scored as correct if the same-category pair has the most similar representation (by cosine), logged as the proportion correct (chance = 1/3) -- 0 = off
+	OddOneOut int `def:"0" desc:"number of odd-one-out triplets to evaluate at each test epoch, across all MPI procs: two test items from the same category and one from another, scored as correct if the same-category pair has the most similar representation (by cosine), logged as the proportion correct (chance = 1/3) -- 0 = off"`
+
+	// layers whose representations are used for the OddOneOut triplets -- defaults to TE if empty
+	OddOneOutLays []string `desc:"layers whose representations are used for the OddOneOut triplets -- defaults to TE if empty"`
+
+	// [def: 10] max number of test items per category recorded on each MPI proc for drawing the OddOneOut triplets
+	OddOneOutMaxPer int `def:"10" desc:"max number of test items per category recorded on each MPI proc for drawing the OddOneOut triplets"`
+
+	// [def: 0] number of random split-half partitions of the test exemplars of each category, over which the split-half reliability of the category-mean activation patterns is computed at each test epoch: the correlation of the category dissimilarity matrices of the two halves, and its Spearman-Brown correction, which is the noise ceiling for RSA correlations with external data -- 0 = off
+	SplitHalf int `def:"0" desc:"number of random split-half partitions of the test exemplars of each category, over which the split-half reliability of the category-mean activation patterns is computed at each test epoch: the correlation of the category dissimilarity matrices of the two halves, and its Spearman-Brown correction, which is the noise ceiling for RSA correlations with external data -- 0 = off"`
+
+	// layers for the SplitHalf reliability stats -- defaults to TEOf16, TEOf8, TE if empty
+	SplitHalfLays []string `desc:"layers for the SplitHalf reliability stats -- defaults to TEOf16, TEOf8, TE if empty"`
+
+	// [def: 0] interval in training trials (counting NData items as one) between scans of all neuron variables, and if NaNCheckWts, synaptic weights, for NaN / Inf values -- 0 = off.  On detection, the state of the offending layer is saved to a nandump file, the weights are saved, and the run is aborted.  On the GPU, the state must be synced at this interval, which is expensive for the weights.
+	NaNCheck int `def:"0" desc:"interval in training trials (counting NData items as one) between scans of all neuron variables, and if NaNCheckWts, synaptic weights, for NaN / Inf values -- 0 = off.  On detection, the state of the offending layer is saved to a nandump file, the weights are saved, and the run is aborted.  On the GPU, the state must be synced at this interval, which is expensive for the weights."`
+
+	// [def: true] include the synaptic weights in the NaNCheck scan
+	NaNCheckWts bool `def:"true" desc:"include the synaptic weights in the NaNCheck scan"`
+
+	// [view: add-fields] monitor of the average activity of the input layers over training epochs, alerting on drift from a reference -- catches env and filtering misconfigurations
+	InDrift InDriftConfig `view:"add-fields" desc:"monitor of the average activity of the input layers over training epochs, alerting on drift from a reference -- catches env and filtering misconfigurations"`
+
+	// [def: 0] number of initial training epochs for supervised clamped pretraining, in which the Output layer is driven as an input, fully clamped to the category pattern in both phases, to shape the top-down weights before switching to the standard Target mode -- testing always uses Target mode
+	OutClampEpochs int `def:"0" desc:"number of initial training epochs for supervised clamped pretraining, in which the Output layer is driven as an input, fully clamped to the category pattern in both phases, to shape the top-down weights before switching to the standard Target mode -- testing always uses Target mode"`
+
+	// linear reconstruction decoder, trained to map hidden layer activity back to the V1 input
+	Recon ReconConfig `view:"add-fields" desc:"linear reconstruction decoder, trained to map hidden layer activity back to the V1 input"`
+
+	// additional softmax category decoders, run in parallel with the main decoder, each with its own learning rate, L2 weight decay, and input layers, and logged as DecErr_Name, so that decoder hyperparameters do not confound comparisons of representation quality
+	Decoders []DecoderConfig `desc:"additional softmax category decoders, run in parallel with the main decoder, each with its own learning rate, L2 weight decay, and input layers, and logged as DecErr_Name, so that decoder hyperparameters do not confound comparisons of representation quality"`
+
+	// names of the registered plugin hooks to enable -- all are enabled if empty -- see hooks.go
+	Hooks []string `desc:"names of the registered plugin hooks to enable -- all are enabled if empty -- see hooks.go"`
+
+	// [view: add-fields] CNN baseline, trained by backprop in parallel with the network on the same filtered V1 inputs and transforms, and logged as BaseErr, as a matched baseline for architectural comparisons
+	Baseline BaselineConfig `view:"add-fields" desc:"CNN baseline, trained by backprop in parallel with the network on the same filtered V1 inputs and transforms, and logged as BaseErr, as a matched baseline for architectural comparisons"`
+
+	// receptive field size analysis, estimating the spatial RF of units from their responses to probe images at a grid of positions and sizes
+	RFSize RFSizeConfig `view:"add-fields" desc:"receptive field size analysis, estimating the spatial RF of units from their responses to probe images at a grid of positions and sizes"`
+
+	// [view: add-fields] replay (sleep) phase after each training epoch, in which stored activity patterns of higher layers are clamped and replayed, with learning only in selected projections
+	Replay ReplayConfig `view:"add-fields" desc:"replay (sleep) phase after each training epoch, in which stored activity patterns of higher layers are clamped and replayed, with learning only in selected projections"`
+}
+
+// ReplayConfig has the config for the replay phase after each training
+// epoch, for exploring consolidation hypotheses -- see replay.go
+type ReplayConfig struct {
+
+	// [def: 0] number of training items per MPI proc whose activity patterns are stored over each epoch and replayed at the end of it -- 0 = off
+	N int `def:"0" desc:"number of training items per MPI proc whose activity patterns are stored over each epoch and replayed at the end of it -- 0 = off"`
+
+	// layers whose ActP patterns are stored and clamped in the replay -- defaults to TEOf16, TEOf8, TE if empty
+	Layers []string `desc:"layers whose ActP patterns are stored and clamped in the replay -- defaults to TEOf16, TEOf8, TE if empty"`
+
+	// [def: .ToOut] params-style selector of the projections that learn during the replay (e.g., .ToOut, #TEToOutput, .Back) -- all others are fixed.  Learning is error-driven, so it requires a difference between the minus and plus phases: the default .ToOut learns from the Target Output patterns, and without them, it learns almost nothing
+	Learn string `def:".ToOut" desc:"params-style selector of the projections that learn during the replay (e.g., .ToOut, #TEToOutput, .Back) -- all others are fixed.  Learning is error-driven, so it requires a difference between the minus and plus phases: the default .ToOut learns from the Target Output patterns, and without them, it learns almost nothing"`
+
+	// [def: true] also store the Output patterns of the replayed items, and present them as the Output targets in the plus phase of the replay, so the Learn projections into the Output learn to reproduce the original responses -- otherwise the Output is free in both phases
+	Target bool `def:"true" desc:"also store the Output patterns of the replayed items, and present them as the Output targets in the plus phase of the replay, so the Learn projections into the Output learn to reproduce the original responses -- otherwise the Output is free in both phases"`
+}
+
+// RFSizeConfig has the config for the receptive field size analysis,
+// for comparison to the RF sizes in macaque V4 and TEO -- see rfsize.go
+type RFSizeConfig struct {
+
+	// run the RF size analysis on the test images, with the OpenWts weights if set, save the results, and quit (in nogui mode)
+	On bool `desc:"run the RF size analysis on the test images, with the OpenWts weights if set, save the results, and quit (in nogui mode)"`
+
+	// layers to estimate the RFs of -- defaults to V4f16, V4f8, TEOf16, TEOf8 if empty
+	Layers []string `desc:"layers to estimate the RFs of -- defaults to V4f16, V4f8, TEOf16, TEOf8 if empty"`
+
+	// [def: 10] number of test images used as probes, from different categories
+	NImgs int `def:"10" min:"1" desc:"number of test images used as probes, from different categories"`
+
+	// [def: 7] number of probe positions along each axis of the grid
+	NPos int `def:"7" min:"2" desc:"number of probe positions along each axis of the grid"`
+
+	// [def: 0.8] maximum probe translation from the center, as a proportion of the image half-width: the grid spans -Range to Range
+	Range float32 `def:"0.8" desc:"maximum probe translation from the center, as a proportion of the image half-width: the grid spans -Range to Range"`
+
+	// probe sizes, as image scaling factors -- defaults to [0.25, 0.5] if empty
+	Scales []float32 `desc:"probe sizes, as image scaling factors -- defaults to [0.25, 0.5] if empty"`
+}
+
+// DecoderConfig has the config for one of the additional category
+// decoders -- see decoders.go
+type DecoderConfig struct {
+
+	// name of the decoder, for its DecErr_Name stat -- defaults to its index if empty
+	Name string `desc:"name of the decoder, for its DecErr_Name stat -- defaults to its index if empty"`
+
+	// layers to decode from -- defaults to the layers of the main decoder if empty: V4f16, V4f8, TEOf16, TEOf8, Output
+	Layers []string `desc:"layers to decode from -- defaults to the layers of the main decoder if empty: V4f16, V4f8, TEOf16, TEOf8, Output"`
+
+	// learning rate -- defaults to that of the main decoder, 0.05, if 0
+	Lrate float32 `desc:"learning rate -- defaults to that of the main decoder, 0.05, if 0"`
+
+	// L2 regularization: the weights decay by Lrate * L2 of their value after each training trial
+	L2 float32 `min:"0" desc:"L2 regularization: the weights decay by Lrate * L2 of their value after each training trial"`
+}
+
+// ReconConfig has the config for the linear reconstruction decoder,
+// which maps hidden layer activity back to an input layer, to visualize
+// what information each layer preserves -- see recon.go
+type ReconConfig struct {
+
+	// train and test the reconstruction decoder, logging the ReconErr stat
+	On bool `desc:"train and test the reconstruction decoder, logging the ReconErr stat"`
+
+	// hidden layers to reconstruct from -- defaults to V4f16 if empty.  The decoder has a weight from each unit to each target unit, so large layers and targets take a lot of memory, and time to share the weight changes under MPI.
+	Layers []string `desc:"hidden layers to reconstruct from -- defaults to V4f16 if empty.  The decoder has a weight from each unit to each target unit, so large layers and targets take a lot of memory, and time to share the weight changes under MPI."`
+
+	// [def: V1l16] input layer to reconstruct
+	Target string `def:"V1l16" desc:"input layer to reconstruct"`
+
+	// [def: ActM] variable of the hidden layers to decode
+	Var string `def:"ActM" desc:"variable of the hidden layers to decode"`
+
+	// [def: 0.001] learning rate for the delta rule -- must be small relative to the number of active inputs
+	LRate float32 `def:"0.001" desc:"learning rate for the delta rule -- must be small relative to the number of active inputs"`
+}
+
+// BaselineConfig has config parameters for the CNN baseline
+type BaselineConfig struct {
+
+	// train and test the CNN baseline, logging the BaseErr stat
+	On bool `desc:"train and test the CNN baseline, logging the BaseErr stat"`
+
+	// V1 input layers for the CNN baseline, each feeding its own conv stream: their pools are the spatial positions, and the units within each pool the input channels -- all the 4D V1 input layers if empty
+	Layers []string `desc:"V1 input layers for the CNN baseline, each feeding its own conv stream: their pools are the spatial positions, and the units within each pool the input channels -- all the 4D V1 input layers if empty"`
+
+	// [def: 16] number of 3x3 convolution filters
+	NFilters int `def:"16" min:"1" desc:"number of 3x3 convolution filters"`
+
+	// [def: 0.01] learning rate for SGD on each training trial, on the gradients summed over the data parallel inputs and procs
+	Lrate float32 `def:"0.01" desc:"learning rate for SGD on each training trial, on the gradients summed over the data parallel inputs and procs"`
 }
 
 // LogConfig has config parameters related to logging data
@@ -104,6 +737,30 @@ type LogConfig struct {
 	// if true, save final weights after each run
 	SaveWts bool `desc:"if true, save final weights after each run"`
 
+	// [def: json] format for saved weights: json = standard .wts.gz gzipped JSON, wtsz = .wtsz format with a metadata header (run, epoch, config hash, categories) and separately compressed layers, which can be loaded selectively
+	WtsFormat string `def:"json" desc:"format for saved weights: json = standard .wts.gz gzipped JSON, wtsz = .wtsz format with a metadata header (run, epoch, config hash, categories) and separately compressed layers, which can be loaded selectively"`
+
+	// [def: [10,100,500,1000,1500]] training epochs at which to save weights during the run (at the start of the epoch), if SaveWts -- see also WtsInterval, WtsKeep
+	WtsEpochs []int `def:"[10,100,500,1000,1500]" desc:"training epochs at which to save weights during the run (at the start of the epoch), if SaveWts -- see also WtsInterval, WtsKeep"`
+
+	// [def: 0] if > 0, also save weights every this many training epochs during the run, if SaveWts
+	WtsInterval int `def:"0" desc:"if > 0, also save weights every this many training epochs during the run, if SaveWts"`
+
+	// [def: 0] if > 0, only the last this many weights files saved during the run (at WtsEpochs and WtsInterval) are kept, and older ones are deleted -- the final weights at the end of the run and the WtsBest weights are always kept
+	WtsKeep int `def:"0" desc:"if > 0, only the last this many weights files saved during the run (at WtsEpochs and WtsInterval) are kept, and older ones are deleted -- the final weights at the end of the run and the WtsBest weights are always kept"`
+
+	// name of a test epoch log stat (e.g., PctErr) to save the best weights by: whenever it improves at a test epoch, the weights are saved with a _best tag, replacing the previous best file, if SaveWts
+	WtsBest string `desc:"name of a test epoch log stat (e.g., PctErr) to save the best weights by: whenever it improves at a test epoch, the weights are saved with a _best tag, replacing the previous best file, if SaveWts"`
+
+	// if true, higher values of the WtsBest stat are better -- else lower
+	WtsBestMax bool `desc:"if true, higher values of the WtsBest stat are better -- else lower"`
+
+	// [def: zstd] compression for the .wtsz weights format: zstd, gzip, or none
+	WtsCompress string `def:"zstd" desc:"compression for the .wtsz weights format: zstd, gzip, or none"`
+
+	// [def: 3] compression level for the .wtsz weights format: 1-22 for zstd (higher = smaller and slower), 1-9 for gzip
+	WtsLevel int `def:"3" desc:"compression level for the .wtsz weights format: 1-22 for zstd (higher = smaller and slower), 1-9 for gzip"`
+
 	// [def: true] if true, save train epoch log to file, as .epc.tsv typically
 	Epoch bool `def:"true" nest:"+" desc:"if true, save train epoch log to file, as .epc.tsv typically"`
 
@@ -119,8 +776,207 @@ type LogConfig struct {
 	// [def: false] if true, save testing trial log to file, as .tst_trl.tsv typically. May be large.
 	TestTrial bool `def:"false" nest:"+" desc:"if true, save testing trial log to file, as .tst_trl.tsv typically. May be large."`
 
+	// names of the items to include in the train trial log file, for slim trial logs, e.g., [Epoch, Trial, TrialName, TrlCat, TrlResp, Err] -- all items if empty
+	TrialCols []string `desc:"names of the items to include in the train trial log file, for slim trial logs, e.g., [Epoch, Trial, TrialName, TrlCat, TrlResp, Err] -- all items if empty"`
+
+	// names of the items to include in the testing trial log file, for slim trial logs, e.g., [Epoch, Trial, TrialName, TrlCat, TrlResp, Err] -- all items if empty
+	TestTrialCols []string `desc:"names of the items to include in the testing trial log file, for slim trial logs, e.g., [Epoch, Trial, TrialName, TrlCat, TrlResp, Err] -- all items if empty"`
+
+	// formats to export the train and test epoch logs of each run to, in the long-format results schema (Sim, RunName, Mode, Run, Epoch, Metric, Value), for downstream analysis: csv and / or parquet -- none if empty.  Saved as results_<run> files at the end of each run (in nogui mode) -- see export.go
+	Export []string `desc:"formats to export the train and test epoch logs of each run to, in the long-format results schema (Sim, RunName, Mode, Run, Epoch, Metric, Value), for downstream analysis: csv and / or parquet -- none if empty.  Saved as results_<run> files at the end of each run (in nogui mode) -- see export.go"`
+
+	// log plots to render as PNG images at the end of each run (in nogui mode), each as Mode:Time, optionally with :Col1,Col2,.. to select the columns (else those plotted by default in the GUI), e.g., [Train:Epoch, Test:Epoch:PctErr,DecErr] -- see plotpng.go
+	Plots []string `desc:"log plots to render as PNG images at the end of each run (in nogui mode), each as Mode:Time, optionally with :Col1,Col2,.. to select the columns (else those plotted by default in the GUI), e.g., [Train:Epoch, Test:Epoch:PctErr,DecErr] -- see plotpng.go"`
+
+	// if true, record the activation-based receptive fields (ActRFs) during testing in nogui mode (they are always recorded in the GUI), summed across MPI procs, and save the normalized RFs as actrf_*.tsv files after each test
+	ActRFs bool `desc:"if true, record the activation-based receptive fields (ActRFs) during testing in nogui mode (they are always recorded in the GUI), summed across MPI procs, and save the normalized RFs as actrf_*.tsv files after each test"`
+
+	// if true, save a summary of the network architecture (layers, shapes, classes, and projections with their patterns and scaling) as Graphviz .dot and .json files, named by the network and run, at the start of the run (in nogui mode)
+	Arch bool `desc:"if true, save a summary of the network architecture (layers, shapes, classes, and projections with their patterns and scaling) as Graphviz .dot and .json files, named by the network and run, at the start of the run (in nogui mode)"`
+
 	// if true, save network activation etc data from testing trials, for later viewing in netview
 	NetData bool `desc:"if true, save network activation etc data from testing trials, for later viewing in netview"`
+
+	// [def: 200] number of testing trial records of NetData kept in memory, which are then written to the next of the NetDataFiles on-disk files, so that the data survives a crash
+	NetDataRecs int `def:"200" desc:"number of testing trial records of NetData kept in memory, which are then written to the next of the NetDataFiles on-disk files, so that the data survives a crash"`
+
+	// [def: 10] number of NetData files in the on-disk ring buffer, each with NetDataRecs records, with the oldest file overwritten once all are written -- if 0, files are never overwritten
+	NetDataFiles int `def:"10" desc:"number of NetData files in the on-disk ring buffer, each with NetDataRecs records, with the oldest file overwritten once all are written -- if 0, files are never overwritten"`
+
+	// selection of the representative pools of units (RepIdxs) used for PCA and other rep-tensor-based stats, and the GUI raster plots, for 4D layers matching a params-style selector -- the first matching entry applies, and layers without a match use the central 2x2 pools
+	RepPools []RepPoolsConfig `desc:"selection of the representative pools of units (RepIdxs) used for PCA and other rep-tensor-based stats, and the GUI raster plots, for 4D layers matching a params-style selector -- the first matching entry applies, and layers without a match use the central 2x2 pools"`
+
+	// recording of a movie of layer activity over the cycles of a selected test trial
+	Movie MovieConfig `view:"add-fields" desc:"recording of a movie of layer activity over the cycles of a selected test trial"`
+
+	// NetView-style snapshot images of layer activity in testing trials at selected epochs, rendered offscreen in nogui mode
+	Snapshot SnapshotConfig `view:"add-fields" desc:"NetView-style snapshot images of layer activity in testing trials at selected epochs, rendered offscreen in nogui mode"`
+
+	// optional experiment tracking sink, pushing the config, train epoch log rows, and final run metrics to an external tracking server
+	Track TrackConfig `view:"add-fields" desc:"optional experiment tracking sink, pushing the config, train epoch log rows, and final run metrics to an external tracking server"`
+
+	// if true, log the L2 norm of the weight change over each training epoch, ||ΔW||, and its ratio to the norm of the weights, aggregated across the projections in each class given by WtChangeSels, to localize learning progress to specific pathways.  Keeps a copy of the selected weights, and syncs synapses from the GPU twice per epoch.
+	WtChange bool `desc:"if true, log the L2 norm of the weight change over each training epoch, ||ΔW||, and its ratio to the norm of the weights, aggregated across the projections in each class given by WtChangeSels, to localize learning progress to specific pathways.  Keeps a copy of the selected weights, and syncs synapses from the GPU twice per epoch."`
+
+	// params-style selectors for the projections aggregated in the WtChange stats, one stat per selector -- defaults to the first class name of each projection if empty
+	WtChangeSels []string `desc:"params-style selectors for the projections aggregated in the WtChange stats, one stat per selector -- defaults to the first class name of each projection if empty"`
+
+	// optional trial-synchronized trigger output, with the onset time, image, and transforms of each trial, for synchronizing external analysis or presentation software
+	Trigger TriggerConfig `view:"add-fields" desc:"optional trial-synchronized trigger output, with the onset time, image, and transforms of each trial, for synchronizing external analysis or presentation software"`
+
+	// optional trial event stream, with one JSON object per trial with the category, response, errors, and RT, for custom dashboards and alerting
+	Events EventsConfig `view:"add-fields" desc:"optional trial event stream, with one JSON object per trial with the category, response, errors, and RT, for custom dashboards and alerting"`
+
+	// [view: add-fields] integrated profiling: pprof HTTP endpoints, and CPU, heap, and per-function GPU timing profiles over a range of training epochs
+	Profile ProfileConfig `view:"add-fields" desc:"integrated profiling: pprof HTTP endpoints, and CPU, heap, and per-function GPU timing profiles over a range of training epochs"`
+}
+
+// RepPoolsConfig specifies the representative pools of units
+// for layers matching a params-style selector
+type RepPoolsConfig struct {
+
+	// params-style selector for layers to apply to: .Class, #Name, or Type
+	Sel string `desc:"params-style selector for layers to apply to: .Class, #Name, or Type"`
+
+	// how to select the pools: Center = central N x N pools, Random = N randomly selected pools (fixed for each layer), Full = all units in the layer
+	Mode string `desc:"how to select the pools: Center = central N x N pools, Random = N randomly selected pools (fixed for each layer), Full = all units in the layer"`
+
+	// number of pools per side for Center, or total number of pools for Random
+	N int `desc:"number of pools per side for Center, or total number of pools for Random"`
+}
+
+// MovieConfig has the config for recording a movie of the activity of
+// selected layers over the cycles of one test trial, to show the temporal
+// sweep of activation up the hierarchy -- see movie.go
+type MovieConfig struct {
+
+	// [def: -1] index of the test trial to record at each test epoch, on MPI rank 0 -- -1 = off
+	Trial int `def:"-1" desc:"index of the test trial to record at each test epoch, on MPI rank 0 -- -1 = off"`
+
+	// layers to record, shown left to right in the montage -- defaults to V1m16, V2m16, V4f16, TEOf16, TE, Output if empty
+	Layers []string `desc:"layers to record, shown left to right in the montage -- defaults to V1m16, V2m16, V4f16, TEOf16, TE, Output if empty"`
+
+	// [def: CaSpkP] neuron variable to record
+	Var string `def:"CaSpkP" desc:"neuron variable to record"`
+
+	// [def: 5] [min: 1] interval in cycles between frames -- on the GPU, the recorded trial is run cycle by cycle to sync the neuron state
+	Interval int `def:"5" min:"1" desc:"interval in cycles between frames -- on the GPU, the recorded trial is run cycle by cycle to sync the neuron state"`
+
+	// [def: 1] value of Var shown at the top of the color map, with 0 at the bottom
+	Max float32 `def:"1" desc:"value of Var shown at the top of the color map, with 0 at the bottom"`
+
+	// [def: Viridis] name of the color map
+	ColorMap string `def:"Viridis" desc:"name of the color map"`
+
+	// [def: 4] [min: 1] size in pixels of each unit
+	Scale int `def:"4" min:"1" desc:"size in pixels of each unit"`
+
+	// [def: 10] delay between frames in the animated GIF, in 100ths of a second
+	Delay int `def:"10" desc:"delay between frames in the animated GIF, in 100ths of a second"`
+
+	// [def: gif] output format: gif = animated GIF, png = a directory of numbered PNG frames, e.g., for making an MP4 with ffmpeg -i %04d.png
+	Format string `def:"gif" desc:"output format: gif = animated GIF, png = a directory of numbered PNG frames, e.g., for making an MP4 with ffmpeg -i %04d.png"`
+}
+
+// SnapshotConfig has the config for saving NetView-style snapshot images
+// of the activity of selected layers at the end of testing trials, from
+// the NetData recording, in nogui mode -- see snapshot.go
+type SnapshotConfig struct {
+
+	// training epochs at which to save snapshots, at the test epoch
+	Epochs []int `desc:"training epochs at which to save snapshots, at the test epoch"`
+
+	// [def: 0] also save snapshots every Interval training epochs, at the test epoch -- 0 = only at Epochs
+	Interval int `def:"0" desc:"also save snapshots every Interval training epochs, at the test epoch -- 0 = only at Epochs"`
+
+	// [def: 4] number of testing trials to save snapshots of, at each snapshot epoch: the first data index of each of the first NTrials batches of NData trials, as recorded in the NetData
+	NTrials int `def:"4" desc:"number of testing trials to save snapshots of, at each snapshot epoch: the first data index of each of the first NTrials batches of NData trials, as recorded in the NetData"`
+
+	// layers to show, left to right -- defaults to the Movie default layers if empty
+	Layers []string `desc:"layers to show, left to right -- defaults to the Movie default layers if empty"`
+
+	// [def: ActM] neuron variable to show, which must be one of the variables recorded in the NetData
+	Var string `def:"ActM" desc:"neuron variable to show, which must be one of the variables recorded in the NetData"`
+
+	// [def: 1] value of Var shown at the top of the color map, with 0 at the bottom
+	Max float32 `def:"1" desc:"value of Var shown at the top of the color map, with 0 at the bottom"`
+
+	// [def: Viridis] name of the color map
+	ColorMap string `def:"Viridis" desc:"name of the color map"`
+
+	// [def: 4] [min: 1] size in pixels of each unit
+	Scale int `def:"4" min:"1" desc:"size in pixels of each unit"`
+}
+
+// TrackConfig has the config for the experiment tracking sink,
+// on MPI rank 0 -- see tracker.go
+type TrackConfig struct {
+
+	// tracking backend, from TrackerTypes: http = generic JSON events posted to URL, mlflow = MLflow REST API at URL -- empty = off
+	Type string `desc:"tracking backend, from TrackerTypes: http = generic JSON events posted to URL, mlflow = MLflow REST API at URL -- empty = off"`
+
+	// URL of the tracking server
+	URL string `desc:"URL of the tracking server"`
+
+	// project or experiment name to record runs under -- defaults to the network name
+	Project string `desc:"project or experiment name to record runs under -- defaults to the network name"`
+
+	// [def: TRACKER_TOKEN] name of the environment variable with the bearer token for the server, if needed -- the token is not stored in the config
+	TokenEnv string `def:"TRACKER_TOKEN" desc:"name of the environment variable with the bearer token for the server, if needed -- the token is not stored in the config"`
+
+	// [def: 10] timeout for each request, in seconds
+	Timeout int `def:"10" desc:"timeout for each request, in seconds"`
+}
+
+// TriggerConfig has the config for the trial-synchronized trigger output,
+// emitted at the onset of each test (and optionally training) trial -- see trigger.go
+type TriggerConfig struct {
+
+	// if true, save the triggers for each trial to a sidecar file per MPI proc, named by the network and run with a trigger_<rank>.tsv suffix (in nogui mode), with the onset wall clock time, counters, image, category, and transforms
+	File bool `desc:"if true, save the triggers for each trial to a sidecar file per MPI proc, named by the network and run with a trigger_<rank>.tsv suffix (in nogui mode), with the onset wall clock time, counters, image, category, and transforms"`
+
+	// host:port address to send each trigger to as a JSON UDP message, with the same fields as the sidecar file -- empty = off
+	UDP string `desc:"host:port address to send each trigger to as a JSON UDP message, with the same fields as the sidecar file -- empty = off"`
+
+	// if true, also emit triggers for training trials -- otherwise only for testing trials
+	Train bool `desc:"if true, also emit triggers for training trials -- otherwise only for testing trials"`
+}
+
+// EventsConfig has the config for the trial event stream, emitted at
+// the end of each training and testing trial -- see events.go
+type EventsConfig struct {
+
+	// if true, save the events for each trial to a newline-delimited JSON file per MPI proc, named by the network and run with an events_<rank>.jsonl suffix (in nogui mode)
+	File bool `desc:"if true, save the events for each trial to a newline-delimited JSON file per MPI proc, named by the network and run with an events_<rank>.jsonl suffix (in nogui mode)"`
+
+	// host:port address to stream the events to, as newline-delimited JSON -- empty = off
+	Addr string `desc:"host:port address to stream the events to, as newline-delimited JSON -- empty = off"`
+
+	// [def: tcp] network for the Addr connection: tcp, or udp for best-effort messages, one per event
+	Net string `def:"tcp" desc:"network for the Addr connection: tcp, or udp for best-effort messages, one per event"`
+}
+
+// ProfileConfig has the config for the integrated profiling hooks
+// -- see profile.go
+type ProfileConfig struct {
+
+	// host:port address to serve the standard pprof HTTP endpoints at, on MPI rank 0, for the whole run (e.g., localhost:6060) -- empty = off
+	Addr string `desc:"host:port address to serve the standard pprof HTTP endpoints at, on MPI rank 0, for the whole run (e.g., localhost:6060) -- empty = off"`
+
+	// save a CPU profile over the profiled epochs, per MPI proc
+	CPU bool `desc:"save a CPU profile over the profiled epochs, per MPI proc"`
+
+	// save a heap profile at the end of the profiled epochs, per MPI proc
+	Heap bool `desc:"save a heap profile at the end of the profiled epochs, per MPI proc"`
+
+	// record and save the time spent in each network function over the profiled epochs, including each GPU kernel, which are run separately and waited on, so this is slower
+	Times bool `desc:"record and save the time spent in each network function over the profiled epochs, including each GPU kernel, which are run separately and waited on, so this is slower"`
+
+	// [def: 1] first training epoch profiled
+	Start int `def:"1" desc:"first training epoch profiled"`
+
+	// [def: 2] training epoch at which profiling stops (exclusive)
+	End int `def:"2" desc:"training epoch at which profiling stops (exclusive)"`
 }
 
 // Config is a standard Sim config -- use as a starting point.
@@ -135,6 +991,9 @@ type Config struct {
 	// log debugging information
 	Debug bool `desc:"log debugging information"`
 
+	// if > 0, runs the GPU vs. CPU numerical consistency check for this number of cycles (max 50) on the first training trial of each run, reporting the max divergence across neuron variables per layer per cycle, in the GPUCheck MiscTable and gpucheck log file -- requires the GPU
+	GPUCheck int `desc:"if > 0, runs the GPU vs. CPU numerical consistency check for this number of cycles (max 50) on the first training trial of each run, reporting the max divergence across neuron variables per layer per cycle, in the GPUCheck MiscTable and gpucheck log file -- requires the GPU"`
+
 	// run a standard benchmarking configuration: runs 64 trials (512 for MPI which can run more data parallel) for 1 epoch and reports timing
 	Bench bool `desc:"run a standard benchmarking configuration: runs 64 trials (512 for MPI which can run more data parallel) for 1 epoch and reports timing "`
 
@@ -0,0 +1,258 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// cooling.go has the virtual cooling test, modeling cooling or optogenetic
+// silencing experiments: the category selectivity (d-prime) of the units
+// in Config.Run.CoolLay is measured from the category accumulators (see
+// ProtoRecord) on a separate selection pass over Config.Run.CoolNPerCat
+// training images per category, so the units are not selected on the
+// same items they are evaluated on.  Then the test set is run for the
+// baseline, and again with the Config.Run.CoolK most selective units
+// (optionally only those preferring Config.Run.CoolCat) silenced by
+// turning them off, to measure the change in error for each category.
+// The units are restored at the end.
+
+// CoolUnit is a unit selected for silencing in the cooling test
+type CoolUnit struct {
+
+	// index of the unit within the layer
+	Unit int
+
+	// index of the category with the highest mean response
+	BestCat int
+
+	// d-prime selectivity for the best category vs. all others
+	DPrime float32
+}
+
+// CoolSelectPass runs the selection pass of the cooling test: the test
+// loop is run on the ProcSubset of CoolNPerCat training images per
+// category, as a FastTest so it is not taken as a full test, accumulating
+// the category responses of the units for CoolUnits
+func (ss *Sim) CoolSelectPass() error {
+	ev := ss.ImagesEnv(etime.Test)
+	if ev == nil {
+		return fmt.Errorf("Cool: requires the Images env")
+	}
+	ev.Test = false // training images
+	ev.Init(0)
+	sub := ss.ProcSubset(ev, ss.Config.Run.CoolNPerCat)
+	if sub == nil {
+		ev.Test = true
+		return fmt.Errorf("Cool: not enough training images for CoolNPerCat: %d", ss.Config.Run.CoolNPerCat)
+	}
+	mpi.Printf("Cool: measuring selectivity on %d training images\n", len(sub)*ss.MPISize())
+	ev.SetSubset(sub)
+	trl := ss.Loops.GetLoop(etime.Test, etime.Trial)
+	trl.Counter.Max = len(sub)
+	ss.Stats.SetInt("FastTest", 1)
+	ss.Loops.ResetAndRun(etime.Test)
+	ss.Loops.Mode = etime.Train // as in TestAll
+	ss.Stats.SetInt("FastTest", 0)
+	trl.Counter.Max = ss.Trials.PerProc
+	ev.SetSubset(nil)
+	ev.Test = true
+	return nil
+}
+
+// CoolUnits returns the CoolK most category-selective units in the CoolLay
+// layer, per the category accumulators of the CoolSelectPass, gathered
+// across MPI procs, in order of descending d-prime
+func (ss *Sim) CoolUnits() ([]CoolUnit, error) {
+	rc := &ss.Config.Run
+	cp, ok := ss.Protos[rc.CoolLay]
+	if !ok {
+		return nil, fmt.Errorf("Cool: layer %s not found, must be one of: %v", rc.CoolLay, ss.ProtoLays())
+	}
+	if rc.MPI {
+		cp.MPIReduce(ss.Comm) // not done in ProtoStats for a FastTest
+	}
+	cats := ss.LvisEnv(etime.Test).CatNames()
+	cat := -1
+	if rc.CoolCat != "" {
+		for ci, cn := range cats {
+			if cn == rc.CoolCat {
+				cat = ci
+				break
+			}
+		}
+		if cat < 0 {
+			return nil, fmt.Errorf("Cool: CoolCat %s not found", rc.CoolCat)
+		}
+	}
+	ly := ss.Net.AxonLayerByName(rc.CoolLay)
+	var sparse, dprime etensor.Float32
+	cp.Selectivity(ly.Shp.Shp, &sparse, &dprime)
+	var units []CoolUnit
+	for ui, dp := range dprime.Values {
+		bc := cp.BestCat(ui)
+		if bc < 0 || (cat >= 0 && bc != cat) {
+			continue
+		}
+		units = append(units, CoolUnit{Unit: ui, BestCat: bc, DPrime: dp})
+	}
+	sort.SliceStable(units, func(i, j int) bool {
+		return units[i].DPrime > units[j].DPrime
+	})
+	if len(units) > rc.CoolK {
+		units = units[:rc.CoolK]
+	}
+	return units, nil
+}
+
+// SetUnitsOff sets the Off flag of given units in given layer if off,
+// and clears it otherwise, for all data indexes
+func (ss *Sim) SetUnitsOff(ly *axon.Layer, units []CoolUnit, off bool) {
+	ctx := &ss.Context
+	for _, cu := range units {
+		ni := ly.NeurStIdx + uint32(cu.Unit)
+		for di := uint32(0); di < ly.MaxData; di++ {
+			if off {
+				axon.NrnSetFlag(ctx, ni, di, axon.NeuronOff)
+			} else {
+				axon.NrnClearFlag(ctx, ni, di, axon.NeuronOff)
+			}
+		}
+	}
+	ss.Net.GPU.SyncNeuronsToGPU()
+}
+
+// CoolCatErr returns the test error per category from the CatErr
+// table of the most recent test epoch
+func (ss *Sim) CoolCatErr() map[string]float64 {
+	ce := map[string]float64{}
+	dt := ss.Logs.MiscTables["CatErr"]
+	if dt == nil {
+		return ce
+	}
+	for ri := 0; ri < dt.Rows; ri++ {
+		ce[dt.CellString("TrlCat", ri)] = dt.CellFloat("Err", ri)
+	}
+	return ce
+}
+
+// ConfigCoolTable configures the table of cooling results per category
+func (ss *Sim) ConfigCoolTable(dt *etable.Table, rows int) {
+	dt.SetMetaData("name", "CoolCats")
+	dt.SetMetaData("desc", "virtual cooling test error per category")
+	dt.SetFromSchema(etable.Schema{
+		{"Cat", etensor.STRING, nil, nil},
+		{"NCooled", etensor.FLOAT64, nil, nil},
+		{"BaseErr", etensor.FLOAT64, nil, nil},
+		{"CoolErr", etensor.FLOAT64, nil, nil},
+		{"DErr", etensor.FLOAT64, nil, nil},
+	}, rows)
+}
+
+// RunCool runs the virtual cooling test: the CoolSelectPass to select the
+// CoolK most selective units in CoolLay, a baseline test, and a test with
+// them silenced.
+// The error per category, with the number of silenced units preferring
+// each category, is printed and saved (in nogui mode), along with the
+// silenced units.
+func (ss *Sim) RunCool() error {
+	rc := &ss.Config.Run
+	ly := ss.Net.AxonLayerByName(rc.CoolLay)
+	if ly == nil {
+		return fmt.Errorf("Cool: layer %s not found", rc.CoolLay)
+	}
+	cool := rc.Cool
+	rc.Cool = true // turns on the category accumulators
+	defer func() { rc.Cool = cool }()
+	if err := ss.CoolSelectPass(); err != nil {
+		return err
+	}
+	units, err := ss.CoolUnits()
+	if err != nil {
+		return err
+	}
+	ss.TestAll()
+	basePct := ss.TestEpochFloat("PctErr", false)
+	baseErr := ss.CoolCatErr()
+	cats := ss.LvisEnv(etime.Test).CatNames()
+	ncool := make([]int, len(cats))
+	udt := &etable.Table{}
+	udt.SetMetaData("name", "CoolUnits")
+	udt.SetMetaData("desc", "units silenced in the virtual cooling test")
+	udt.SetFromSchema(etable.Schema{
+		{"Unit", etensor.INT64, nil, nil},
+		{"BestCat", etensor.STRING, nil, nil},
+		{"DPrime", etensor.FLOAT64, nil, nil},
+	}, len(units))
+	for ri, cu := range units {
+		ncool[cu.BestCat]++
+		udt.SetCellFloat("Unit", ri, float64(cu.Unit))
+		udt.SetCellString("BestCat", ri, cats[cu.BestCat])
+		udt.SetCellFloat("DPrime", ri, float64(cu.DPrime))
+	}
+
+	ss.SetUnitsOff(ly, units, true)
+	ss.TestAll()
+	ss.SetUnitsOff(ly, units, false)
+	coolPct := ss.TestEpochFloat("PctErr", false)
+	coolErr := ss.CoolCatErr()
+
+	dt := &etable.Table{}
+	ss.ConfigCoolTable(dt, 0)
+	for ci, cn := range cats {
+		be, ok := baseErr[cn]
+		if !ok {
+			continue
+		}
+		ce := coolErr[cn]
+		row := dt.Rows
+		dt.SetNumRows(row + 1)
+		dt.SetCellString("Cat", row, cn)
+		dt.SetCellFloat("NCooled", row, float64(ncool[ci]))
+		dt.SetCellFloat("BaseErr", row, be)
+		dt.SetCellFloat("CoolErr", row, ce)
+		dt.SetCellFloat("DErr", row, ce-be)
+	}
+	ss.Logs.MiscTables["CoolCats"] = dt
+	ss.Logs.MiscTables["CoolUnits"] = udt
+	mpi.Printf("Cool: silenced %d units in %s: PctErr: %g -> %g\n", len(units), rc.CoolLay, basePct, coolPct)
+	for ci, cn := range cats {
+		if ncool[ci] > 0 {
+			mpi.Printf("Cool: %s\tNCooled: %d\tErr: %g -> %g\n", cn, ncool[ci], baseErr[cn], coolErr[cn])
+		}
+	}
+	if ss.Config.GUI || ss.MPIRank() != 0 {
+		return nil
+	}
+	netName := ss.Net.Name()
+	runName := ss.Stats.String("RunName")
+	for nm, tb := range map[string]*etable.Table{"cool_cats": dt, "cool_units": udt} {
+		fnm := elog.LogFileName(nm, netName, runName)
+		if err := tb.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+			mpi.Println(err)
+		} else {
+			fmt.Printf("Saved cooling results to: %s\n", fnm)
+		}
+	}
+	return nil
+}
+
+// RunCoolGUI runs the virtual cooling test, has stop running = false at end -- for gui
+func (ss *Sim) RunCoolGUI() {
+	ss.GUI.StopNow = false
+	if err := ss.RunCool(); err != nil {
+		mpi.Println(err)
+	}
+	ss.GUI.Stopped()
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/params"
+	"github.com/emer/emergent/prjn"
+	"github.com/emer/empi/mpi"
+)
+
+// dale.go has the Dale's law option, for testing the impact of this
+// biological constraint on performance: each neuron is either excitatory
+// or inhibitory, in all of its outgoing synapses.  In axon, weights are
+// always positive, and the sign of a projection is set by its type, so
+// all the standard (Forward, Back, Lateral) projections are excitatory-only
+// throughout learning.  The exception is an inhibitory projection sent
+// directly from an excitatory layer, such as the LatInhib projections of
+// Config.Params.Inhib Lateral and Mixed, in which the same neurons
+// excite other layers and inhibit their own.  With Config.Params.Dale,
+// the selected inhibitory projections are instead routed through a
+// separate population of inhibitory interneurons for each sending layer,
+// named <Layer>Inh, with the same pools and DaleNInh x DaleNInh units per
+// pool: an excitatory projection from each pool of the layer to the
+// interneurons of the same pool (class ToDaleInh), and the inhibitory
+// projection from the interneurons, with the original pattern and classes,
+// plus DaleInh.  See the Dale params sheet.
+
+// DaleInhName returns the name of the interneuron layer for given layer
+func DaleInhName(lnm string) string {
+	return lnm + "Inh"
+}
+
+// DaleSel returns true if the inhibitory projection of given classes
+// from send to recv is selected by Config.Params.Dale
+func (ss *Sim) DaleSel(send, recv *axon.Layer, cls string) bool {
+	sel := ss.Config.Params.Dale
+	if sel == "" {
+		return false
+	}
+	nm := send.Name() + "To" + recv.Name()
+	return params.SelMatch(sel, nm, cls, axon.InhibPrjn.String(), "Prjn")
+}
+
+// ConnectInhib adds an inhibitory projection from send to recv with given
+// pattern and classes -- if selected by Config.Params.Dale, it is sent by
+// the interneurons of send, which are added as needed, instead of send
+// itself.  Returns the inhibitory projection.
+func (ss *Sim) ConnectInhib(net *axon.Network, send, recv *axon.Layer, pat prjn.Pattern, cls string) *axon.Prjn {
+	if !ss.DaleSel(send, recv, cls) {
+		pj := net.ConnectLayers(send, recv, pat, axon.InhibPrjn)
+		pj.SetClass(cls)
+		return pj
+	}
+	if send.Shp.NumDims() != 4 {
+		mpi.Printf("Dale: layer %s does not have pools, using direct inhibitory projection\n", send.Name())
+		pj := net.ConnectLayers(send, recv, pat, axon.InhibPrjn)
+		pj.SetClass(cls)
+		return pj
+	}
+	inm := DaleInhName(send.Name())
+	inh := net.AxonLayerByName(inm)
+	if inh == nil {
+		ni := ss.Config.Params.DaleNInh
+		inh = net.AddLayer4D(inm, send.Shp.Dim(0), send.Shp.Dim(1), ni, ni, axon.SuperLayer)
+		inh.SetClass("DaleInh")
+		net.ConnectLayers(send, inh, prjn.NewPoolOneToOne(), axon.ForwardPrjn).SetClass("ToDaleInh")
+	}
+	pj := net.ConnectLayers(inh, recv, pat, axon.InhibPrjn)
+	pj.SetClass(cls + " DaleInh")
+	return pj
+}
+
+// DaleViolations returns the names of the layers that send both
+// excitatory and inhibitory projections, violating Dale's law
+func DaleViolations(net *axon.Network) []string {
+	var lnms []string
+	for _, ly := range net.Layers {
+		exc, inh := false, false
+		for _, pj := range ly.SndPrjns {
+			if pj.Typ == axon.InhibPrjn {
+				inh = true
+			} else {
+				exc = true
+			}
+		}
+		if exc && inh {
+			lnms = append(lnms, ly.Name())
+		}
+	}
+	return lnms
+}
+
+// DaleReport prints the interneuron layers and any remaining violations
+// of Dale's law, if Config.Params.Dale is set -- called after Build
+func (ss *Sim) DaleReport(net *axon.Network) {
+	if ss.Config.Params.Dale == "" {
+		return
+	}
+	var inms []string
+	for _, ly := range net.Layers {
+		if params.ClassMatch("DaleInh", ly.Cls) {
+			inms = append(inms, ly.Name())
+		}
+	}
+	mpi.Printf("Dale: %s: %d interneuron layers: %s\n", ss.Config.Params.Dale, len(inms), strings.Join(inms, " "))
+	if vl := DaleViolations(net); len(vl) > 0 {
+		mpi.Printf("Dale: layers sending both excitatory and inhibitory projections: %s\n", strings.Join(vl, " "))
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"path/filepath"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+	"github.com/goki/ki/ints"
+)
+
+// datastats.go has the dataset statistics report, for catching problems
+// with the image set before spending cluster time on training: it scans
+// all of the configured images (train and test), and saves per-image
+// dimensions, mean luminance and RMS contrast in a dataset_imgs file,
+// and per-category counts and distributions of these in a dataset_cats
+// file, flagging categories with fewer than Config.Run.DatasetMinN images.
+// See Config.Run.DatasetStats.
+
+// ImageLumStats returns the mean luminance (Rec. 601 luma, 0-1) and the
+// RMS contrast (standard deviation of the luminance) of given image
+func ImageLumStats(img image.Image) (lum, contrast float64) {
+	bounds := img.Bounds()
+	n := bounds.Dx() * bounds.Dy()
+	if n == 0 {
+		return
+	}
+	sum, sumSq := 0.0, 0.0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			l := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xffff
+			sum += l
+			sumSq += l * l
+		}
+	}
+	lum = sum / float64(n)
+	contrast = math.Sqrt(math.Max(sumSq/float64(n)-lum*lum, 0))
+	return
+}
+
+// DatasetStats scans the images in the train env's image set and saves
+// the per-image and per-category statistics tables, printing a summary
+// and the categories with fewer than Config.Run.DatasetMinN images.
+// Only runs on MPI rank 0.  Requires the Images env.
+func (ss *Sim) DatasetStats() error {
+	ev := ss.ImagesEnv(etime.Train)
+	if ev == nil {
+		return fmt.Errorf("DatasetStats: requires the Images env")
+	}
+	if ss.MPIRank() != 0 {
+		return nil
+	}
+	im := &ev.Images
+	ncats := len(im.Cats)
+	imgs := &etable.Table{}
+	imgs.SetFromSchema(etable.Schema{
+		{"Cat", etensor.STRING, nil, nil},
+		{"Image", etensor.STRING, nil, nil},
+		{"Test", etensor.INT64, nil, nil},
+		{"Width", etensor.INT64, nil, nil},
+		{"Height", etensor.INT64, nil, nil},
+		{"Lum", etensor.FLOAT64, nil, nil},
+		{"Contrast", etensor.FLOAT64, nil, nil},
+	}, 0)
+	cats := &etable.Table{}
+	cats.SetFromSchema(etable.Schema{
+		{"Cat", etensor.STRING, nil, nil},
+		{"N", etensor.INT64, nil, nil},
+		{"NTrain", etensor.INT64, nil, nil},
+		{"NTest", etensor.INT64, nil, nil},
+		{"NErr", etensor.INT64, nil, nil},
+		{"Low", etensor.INT64, nil, nil},
+		{"MinWidth", etensor.INT64, nil, nil},
+		{"MaxWidth", etensor.INT64, nil, nil},
+		{"MinHeight", etensor.INT64, nil, nil},
+		{"MaxHeight", etensor.INT64, nil, nil},
+		{"Lum", etensor.FLOAT64, nil, nil},
+		{"LumSD", etensor.FLOAT64, nil, nil},
+		{"Contrast", etensor.FLOAT64, nil, nil},
+		{"ContrastSD", etensor.FLOAT64, nil, nil},
+	}, ncats)
+
+	minN := ss.Config.Run.DatasetMinN
+	nimg, nerr := 0, 0
+	var low []string
+	for ci, cat := range im.Cats {
+		var lums, cons []float64
+		minW, maxW, minH, maxH := math.MaxInt, 0, math.MaxInt, 0
+		ncerr := 0
+		for tst, fls := range [][][]string{im.ImagesTrain, im.ImagesTest} {
+			if ci >= len(fls) {
+				continue
+			}
+			for _, fn := range fls[ci] {
+				flat := im.FlatImplCats([][]string{{fn}}, []string{cat})[0]
+				img, err := OpenImageFile(filepath.Join(im.Path, flat))
+				if err != nil {
+					mpi.Println(err)
+					ncerr++
+					continue
+				}
+				b := img.Bounds()
+				w, h := b.Dx(), b.Dy()
+				lum, con := ImageLumStats(img)
+				lums = append(lums, lum)
+				cons = append(cons, con)
+				minW, maxW = ints.MinInt(minW, w), ints.MaxInt(maxW, w)
+				minH, maxH = ints.MinInt(minH, h), ints.MaxInt(maxH, h)
+				row := imgs.Rows
+				imgs.AddRows(1)
+				imgs.SetCellString("Cat", row, cat)
+				imgs.SetCellString("Image", row, flat)
+				imgs.SetCellFloat("Test", row, float64(tst))
+				imgs.SetCellFloat("Width", row, float64(w))
+				imgs.SetCellFloat("Height", row, float64(h))
+				imgs.SetCellFloat("Lum", row, lum)
+				imgs.SetCellFloat("Contrast", row, con)
+			}
+		}
+		ntrn, ntst := 0, 0
+		if ci < len(im.ImagesTrain) {
+			ntrn = len(im.ImagesTrain[ci])
+		}
+		if ci < len(im.ImagesTest) {
+			ntst = len(im.ImagesTest[ci])
+		}
+		n := ntrn + ntst
+		nimg += n
+		nerr += ncerr
+		lw := 0.0
+		if n-ncerr < minN {
+			lw = 1
+			low = append(low, fmt.Sprintf("%s (%d)", cat, n-ncerr))
+		}
+		if len(lums) == 0 {
+			minW, minH = 0, 0
+		}
+		lm, lsd := meanSD(lums)
+		cm, csd := meanSD(cons)
+		cats.SetCellString("Cat", ci, cat)
+		for col, v := range map[string]float64{"N": float64(n), "NTrain": float64(ntrn), "NTest": float64(ntst), "NErr": float64(ncerr), "Low": lw, "MinWidth": float64(minW), "MaxWidth": float64(maxW), "MinHeight": float64(minH), "MaxHeight": float64(maxH), "Lum": lm, "LumSD": lsd, "Contrast": cm, "ContrastSD": csd} {
+			cats.SetCellFloat(col, ci, v)
+		}
+	}
+
+	runName := ss.Stats.String("RunName")
+	fnm := elog.LogFileName("dataset_imgs", ss.Net.Name(), runName)
+	if err := imgs.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	mpi.Printf("Saved per-image dataset stats to: %s\n", fnm)
+	fnm = elog.LogFileName("dataset_cats", ss.Net.Name(), runName)
+	if err := cats.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	mpi.Printf("Saved per-category dataset stats to: %s\n", fnm)
+	mpi.Printf("Dataset: %s  categories: %d  images: %d (train: %d  test: %d)  errors: %d\n", im.Path, ncats, nimg, len(im.FlatTrain), len(im.FlatTest), nerr)
+	if len(low) > 0 {
+		mpi.Printf("WARNING: %d categories with fewer than %d images: %v\n", len(low), minN, low)
+	}
+	return nil
+}
+
+// meanSD returns the mean and standard deviation of given values
+func meanSD(vals []float64) (mean, sd float64) {
+	if len(vals) == 0 {
+		return
+	}
+	sumSq := 0.0
+	for _, v := range vals {
+		mean += v
+		sumSq += v * v
+	}
+	n := float64(len(vals))
+	mean /= n
+	sd = math.Sqrt(math.Max(sumSq/n-mean*mean, 0))
+	return
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/emer/emergent/decoder"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+)
+
+// decoders.go has the additional softmax category decoders, which run
+// in parallel with the main Decoder, each with its own learning rate,
+// L2 weight decay, and input layers, so that conclusions about the
+// quality of the representations in different layers do not depend
+// on the decoder hyperparameters.  Each is trained on the training
+// trials and logged as DecErr_Name.  See Config.Run.Decoders.
+
+// DecoderName returns the name of the additional decoder of given index,
+// which defaults to the index if not set
+func (ss *Sim) DecoderName(i int) string {
+	if nm := ss.Config.Run.Decoders[i].Name; nm != "" {
+		return nm
+	}
+	return fmt.Sprintf("%d", i)
+}
+
+// ConfigDecoders configures the additional decoders per
+// Config.Run.Decoders -- called in ConfigNet after the main Decoder
+func (ss *Sim) ConfigDecoders() {
+	ss.Decoders = nil
+	ncats := len(ss.LvisEnv(etime.Train).CatNames())
+	for i, dc := range ss.Config.Run.Decoders {
+		lays := ss.Decoder.Layers
+		if len(dc.Layers) > 0 {
+			lays = nil
+			for _, lnm := range dc.Layers {
+				ly, err := ss.Net.LayByNameTry(lnm)
+				if err != nil {
+					mpi.Println("Decoders:", err)
+					continue
+				}
+				lays = append(lays, ly)
+			}
+		}
+		dec := &decoder.SoftMax{}
+		dec.InitLayer(ncats, lays)
+		dec.Lrate = ss.Decoder.Lrate
+		if dc.Lrate > 0 {
+			dec.Lrate = dc.Lrate
+		}
+		if ss.Config.Run.MPI {
+			dec.Comm = ss.Comm
+		}
+		ss.Decoders = append(ss.Decoders, dec)
+		mpi.Printf("Decoder %s: Lrate: %g  L2: %g  from %d inputs\n", ss.DecoderName(i), dec.Lrate, dc.L2, dec.NInputs)
+	}
+}
+
+// DecodersTrial decodes the category for given data index with each of
+// the additional decoders, and trains them on training trials, setting
+// their DecErr_Name stats.  Called when logging the trial, after
+// TrialStats, so they are only trained once per trial.
+func (ss *Sim) DecodersTrial(di int) {
+	if len(ss.Decoders) == 0 {
+		return
+	}
+	cat := ss.Stats.IntDi("TrlCatIdx", di)
+	for i, dec := range ss.Decoders {
+		rsp := dec.Decode("ActM", di)
+		if ss.Context.Mode == etime.Train {
+			if ss.Config.Run.MPI {
+				dec.TrainMPI(cat)
+			} else {
+				dec.Train(cat)
+			}
+			if l2 := ss.Config.Run.Decoders[i].L2; l2 > 0 {
+				decay := 1 - dec.Lrate*l2
+				for wi := range dec.Weights.Values {
+					dec.Weights.Values[wi] *= decay
+				}
+			}
+		}
+		err := 0.0
+		if rsp != cat {
+			err = 1
+		}
+		ss.Stats.SetFloat("DecErr_"+ss.DecoderName(i), err)
+	}
+}
+
+// ConfigDecodersLogs adds the DecErr_Name log items for the additional
+// decoders, with the test epoch values copied to the train epoch and run
+// logs with a Tst prefix
+func (ss *Sim) ConfigDecodersLogs() {
+	var nms []string
+	for i := range ss.Decoders {
+		nm := "DecErr_" + ss.DecoderName(i)
+		ss.Stats.SetFloat(nm, 0)
+		ss.Logs.AddStatAggItem(nm, etime.Run, etime.Epoch, etime.Trial)
+		nms = append(nms, nm)
+	}
+	if len(nms) == 0 {
+		return
+	}
+	ss.Logs.AddCopyFromFloatItems(etime.Train, []etime.Times{etime.Epoch, etime.Run}, etime.Test, etime.Epoch, "Tst", nms...)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/etable/agg"
+	"github.com/emer/etable/etensor"
+)
+
+// dims.go has the representational dimensionality stats for each layer,
+// computed from the eigenvalues of the activity covariance in the PCA
+// stats every Config.Run.PCAInterval epochs, to track the expansion and
+// compression of dimensionality across the hierarchy over training:
+//
+//	PR     = (sum(l))^2 / sum(l^2)            participation ratio
+//	EffDim = exp(-sum(p log p)), p = l / sum(l)  entropy effective rank
+//
+// Both range from 1 (a single dimension) to the number of units (all
+// dimensions equal), and are logged as _PCA_PR and _PCA_EffDim, along with
+// the standard _PCA_NStrong, _PCA_Top5, _PCA_Next5, and _PCA_Rest items.
+
+// PCALays returns the layers for the PCA stats
+func (ss *Sim) PCALays() []string {
+	return ss.Net.LayersByType(axon.SuperLayer, axon.TargetLayer, axon.CTLayer, axon.PTPredLayer)
+}
+
+// DimStats returns the participation ratio and entropy effective
+// dimensionality of given covariance eigenvalues -- negative values
+// due to numerical error are treated as 0
+func DimStats(vals []float64) (pr, effDim float64) {
+	var sum, sumSq float64
+	for _, v := range vals {
+		if v > 0 {
+			sum += v
+			sumSq += v * v
+		}
+	}
+	if sum <= 0 {
+		return 0, 0
+	}
+	pr = sum * sum / sumSq
+	ent := 0.0
+	for _, v := range vals {
+		if v > 0 {
+			p := v / sum
+			ent -= p * math.Log(p)
+		}
+	}
+	return pr, math.Exp(ent)
+}
+
+// PCAStats computes the standard PCA stats on the Analyze trial log, as
+// in axon.PCAStats, and the dimensionality stats, one layer at a time
+// so the eigenvalues of each layer are available
+func (ss *Sim) PCAStats() {
+	ix := ss.Logs.IdxView(etime.Analyze, etime.Trial)
+	for _, lnm := range ss.PCALays() {
+		ss.Stats.PCAStats(ix, "ActM", []string{lnm})
+		pr, ed := DimStats(ss.Stats.SVD.Values)
+		ss.Stats.SetFloat(lnm+"_PCA_PR", pr)
+		ss.Stats.SetFloat(lnm+"_PCA_EffDim", ed)
+	}
+}
+
+// ConfigDimLogs adds the dimensionality stats to the train epoch and
+// run logs, as for the standard PCA items
+func (ss *Sim) ConfigDimLogs() {
+	for _, lnm := range ss.PCALays() {
+		for _, st := range []string{"_PCA_PR", "_PCA_EffDim"} {
+			nm := lnm + st
+			ss.Stats.SetFloat(nm, 0)
+			ss.Logs.AddItem(&elog.Item{
+				Name: nm,
+				Type: etensor.FLOAT64,
+				Write: elog.WriteMap{
+					etime.Scope(etime.Train, etime.Epoch): func(ctx *elog.Context) {
+						ctx.SetStatFloat(ctx.Item.Name)
+					}, etime.Scope(etime.Train, etime.Run): func(ctx *elog.Context) {
+						ix := ctx.LastNRows(ctx.Mode, etime.Epoch, 5)
+						ctx.SetFloat64(agg.Mean(ix, ctx.Item.Name)[0])
+					}}})
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDimStats(t *testing.T) {
+	tests := []struct {
+		vals       []float64
+		pr, effDim float64
+	}{
+		{[]float64{1, 1, 1, 1}, 4, 4},
+		{[]float64{5, 0, 0}, 1, 1},
+		{[]float64{2, -1e-9, 0}, 1, 1},
+		{[]float64{3, 1}, 16.0 / 10, math.Exp(-(0.75*math.Log(0.75) + 0.25*math.Log(0.25)))},
+		{[]float64{0, -1}, 0, 0},
+		{nil, 0, 0},
+	}
+	for _, tt := range tests {
+		pr, ed := DimStats(tt.vals)
+		if math.Abs(pr-tt.pr) > 1e-9 || math.Abs(ed-tt.effDim) > 1e-9 {
+			t.Errorf("DimStats(%v) = %g, %g, want: %g, %g", tt.vals, pr, ed, tt.pr, tt.effDim)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// embed.go has the output mode using precomputed text embeddings of the
+// category labels (e.g., word2vec or GloVe vectors) as distributed output
+// targets, instead of localist patterns, to study the effects of semantic
+// structure on the visual representations.  Each embedding dimension d
+// maps to two output units, 2d for the positive part and 2d+1 for the
+// negative part, so the targets are non-negative, and all values are
+// divided by the max abs value across embeddings, so the max is 1.
+// The output is scored with the cosine, instead of the correlation,
+// against the category targets.  See Config.Env.OutEmbed.
+
+// OpenEmbeds opens the category label embeddings from the OutEmbed file,
+// which has one line per category: the category name followed by the
+// embedding values, separated by spaces or tabs, as in the standard GloVe
+// text format -- lines starting with # are comments.  All categories must
+// have an embedding, with the same number of dimensions.
+func (ev *ImagesEnv) OpenEmbeds() error {
+	fnm := ev.OutEmbed
+	f, err := os.Open(fnm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	ev.Embeds = make(map[string][]float32)
+	ndim := 0
+	scan := bufio.NewScanner(f)
+	scan.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	ln := 0
+	for scan.Scan() {
+		ln++
+		fs := strings.Fields(scan.Text())
+		if len(fs) == 0 || strings.HasPrefix(fs[0], "#") {
+			continue
+		}
+		vals := make([]float32, len(fs)-1)
+		for i, s := range fs[1:] {
+			v, err := strconv.ParseFloat(s, 32)
+			if err != nil {
+				return fmt.Errorf("OpenEmbeds: %s line %d: %w", fnm, ln, err)
+			}
+			vals[i] = float32(v)
+		}
+		if ndim == 0 {
+			ndim = len(vals)
+		}
+		if len(vals) != ndim || ndim == 0 {
+			return fmt.Errorf("OpenEmbeds: %s line %d: %s has %d dimensions, expected %d", fnm, ln, fs[0], len(vals), ndim)
+		}
+		ev.Embeds[fs[0]] = vals
+	}
+	if err := scan.Err(); err != nil {
+		return err
+	}
+	var missing []string
+	for _, cat := range ev.Images.Cats {
+		if _, has := ev.Embeds[cat]; !has {
+			missing = append(missing, cat)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("OpenEmbeds: %s: no embedding for categories: %v", fnm, missing)
+	}
+	return nil
+}
+
+// EmbedDims returns the number of embedding dimensions
+func (ev *ImagesEnv) EmbedDims() int {
+	for _, emb := range ev.Embeds {
+		return len(emb)
+	}
+	return 0
+}
+
+// ConfigPatsEmbed configures the output patterns from the category label
+// embeddings, increasing OutSize.X if needed to fit 2 units per dimension.
+// Rows beyond the number of categories are zeros.
+func (ev *ImagesEnv) ConfigPatsEmbed() {
+	nu := 2 * ev.EmbedDims()
+	if ev.OutSize.X*ev.OutSize.Y < nu {
+		ev.OutSize.X = (nu + ev.OutSize.Y - 1) / ev.OutSize.Y
+	}
+	oshp := []int{ev.OutSize.Y, ev.OutSize.X}
+	oshpnm := []string{"Y", "X"}
+	ev.Output.SetShape(oshp, nil, oshpnm)
+	sch := etable.Schema{
+		{"Name", etensor.STRING, nil, nil},
+		{"Output", etensor.FLOAT32, oshp, oshpnm},
+	}
+	ev.Pats.SetFromSchema(sch, ev.MaxOut)
+	maxAbs := float32(0)
+	for _, cat := range ev.Images.Cats {
+		for _, v := range ev.Embeds[cat] {
+			maxAbs = float32(math.Max(float64(maxAbs), math.Abs(float64(v))))
+		}
+	}
+	if maxAbs == 0 {
+		maxAbs = 1
+	}
+	for pi, cat := range ev.Images.Cats {
+		out := ev.Pats.CellTensor("Output", pi)
+		for d, v := range ev.Embeds[cat] {
+			if v > 0 {
+				out.SetFloat1D(2*d, float64(v/maxAbs))
+			} else {
+				out.SetFloat1D(2*d+1, float64(-v/maxAbs))
+			}
+		}
+	}
+	ev.ConfigPatsName()
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/emer/empi/mpi"
+)
+
+// envbcast.go has the MPI broadcast of the train / test split of the
+// images: only world rank 0 opens the saved split files, or generates and
+// saves a new split, and the resulting categories and train and test lists
+// are broadcast in JSON to all the other procs, including those of other
+// Config.Run.Search configurations.  This avoids races on shared
+// filesystems, where a proc could read the files while rank 0 is writing
+// them, and guarantees identical lists across procs.
+
+// SplitLists are the categories and image lists of a split, as saved
+// in the split config files -- see ConfigFileNames
+type SplitLists struct {
+	Cats  []string
+	Test  [][]string
+	Train [][]string
+}
+
+// SplitJSON returns the current categories and test and train lists,
+// in JSON
+func (ev *ImagesEnv) SplitJSON() ([]byte, error) {
+	im := &ev.Images
+	return json.Marshal(&SplitLists{Cats: im.Cats, Test: im.ImagesTest, Train: im.ImagesTrain})
+}
+
+// SetSplitJSON sets the categories and test and train lists from JSON
+// as returned by SplitJSON, as in OpenConfig
+func (ev *ImagesEnv) SetSplitJSON(b []byte) error {
+	sl := &SplitLists{}
+	if err := json.Unmarshal(b, sl); err != nil {
+		return err
+	}
+	im := &ev.Images
+	im.Cats = sl.Cats
+	im.ImagesTest = sl.Test
+	im.ImagesTrain = sl.Train
+	im.ToTrainAll()
+	im.Flats()
+	return nil
+}
+
+// SplitRoot returns true if this proc is the one that opens or
+// generates the split: world rank 0 under MPI
+func (ss *Sim) SplitRoot() bool {
+	return !ss.Config.Run.MPI || mpi.WorldRank() == 0
+}
+
+// BcastSplit broadcasts the split of given env from world rank 0 to all
+// the other procs, and returns it in JSON, for setting other envs.
+// An encoding error on rank 0 is broadcast as a negative size, so all
+// procs return an error instead of waiting on the split.
+func (ss *Sim) BcastSplit(ev *ImagesEnv) ([]byte, error) {
+	comm := ss.Comm
+	if ss.WorldComm != nil {
+		comm = ss.WorldComm
+	}
+	var b []byte
+	var err error
+	if mpi.WorldRank() == 0 {
+		b, err = ev.SplitJSON()
+	}
+	n := []int{len(b)}
+	if err != nil {
+		n[0] = -1
+	}
+	comm.BcastInt(0, n)
+	if n[0] < 0 {
+		if err == nil {
+			err = fmt.Errorf("BcastSplit: rank 0 failed to encode the split")
+		}
+		return nil, err
+	}
+	buf := make([]uint8, n[0])
+	copy(buf, b)
+	comm.BcastU8(0, buf)
+	if mpi.WorldRank() != 0 {
+		if err := ev.SetSplitJSON(buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
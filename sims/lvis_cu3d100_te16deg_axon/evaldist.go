@@ -0,0 +1,245 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/empi"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// evaldist.go has the distributed evaluation-only mode, for large test
+// sweeps of weights files (checkpoints) x test transform conditions: the
+// evaluation grid is split across the MPI procs, each of which tests the
+// full test set for each of its conditions in turn, independent of the
+// others, and the results are gathered on rank 0 into one table, saved as
+// an eval_grid log.  The conditions are the Config.Run.EvalWts files
+// crossed with the Config.Run.EvalGrid transform settings of the test env:
+//
+//	Trans  max translation (TransMax, X and Y), as a proportion of the half-width
+//	Rot    max rotation (RotateMax), in degrees
+//	Scale  scale range (ScaleRange), as min:max, or a single value
+//
+// e.g., Trans=0,0.15,0.3 Rot=0,16 Scale=1,0.5:1.1.  The test env is
+// reinitialized for each condition, with the random transforms of rank 0,
+// so the transforms for given settings are the same for all files and procs.  Without Config.Run.EvalDist, EvalWts splits
+// the test set across the procs as usual.
+
+// EvalCond is one condition of the distributed evaluation grid
+type EvalCond struct {
+
+	// weights file
+	File string
+
+	// test env transform settings
+	Trans, Rot, ScaleMin, ScaleMax float32
+}
+
+// EvalGridConds returns the conditions for each of the given weights
+// files crossed with the transform values of given grid, of space-separated
+// Dim=val1,val2,.. items, with defaults from the given test env, with the
+// files varying slowest
+func EvalGridConds(fnms []string, grid string, ev *ImagesEnv) ([]EvalCond, error) {
+	conds := make([]EvalCond, len(fnms))
+	for i, fnm := range fnms {
+		conds[i] = EvalCond{File: fnm, Trans: ev.TransMax.X, Rot: ev.RotateMax, ScaleMin: ev.ScaleRange.Min, ScaleMax: ev.ScaleRange.Max}
+	}
+	for _, it := range strings.Fields(grid) {
+		ei := strings.Index(it, "=")
+		if ei <= 0 || ei == len(it)-1 {
+			return nil, fmt.Errorf("EvalGrid: item is not Dim=val1,val2,..: %s", it)
+		}
+		dim := it[:ei]
+		var nconds []EvalCond
+		for _, cd := range conds {
+			for _, vs := range strings.Split(it[ei+1:], ",") {
+				mn, mx, err := evalGridVal(vs)
+				if err != nil {
+					return nil, fmt.Errorf("EvalGrid: %s: %w", it, err)
+				}
+				switch dim {
+				case "Trans":
+					cd.Trans = mn
+				case "Rot":
+					cd.Rot = mn
+				case "Scale":
+					cd.ScaleMin, cd.ScaleMax = mn, mx
+				default:
+					return nil, fmt.Errorf("EvalGrid: dim %s is not one of: Trans, Rot, Scale", dim)
+				}
+				nconds = append(nconds, cd)
+			}
+		}
+		conds = nconds
+	}
+	return conds, nil
+}
+
+// evalGridVal parses a grid value, as min:max or a single value
+func evalGridVal(vs string) (mn, mx float32, err error) {
+	vs = strings.TrimSpace(vs)
+	ms, xs := vs, vs
+	if ci := strings.Index(vs, ":"); ci >= 0 {
+		ms, xs = vs[:ci], vs[ci+1:]
+	}
+	m, err := strconv.ParseFloat(ms, 32)
+	if err != nil {
+		return
+	}
+	x, err := strconv.ParseFloat(xs, 32)
+	return float32(m), float32(x), err
+}
+
+// ConfigEvalDistTable configures the table of the distributed evaluation
+// results, with given number of rows
+func ConfigEvalDistTable(dt *etable.Table, rows int) {
+	sch := etable.Schema{
+		{"File", etensor.STRING, nil, nil},
+		{"Run", etensor.INT64, nil, nil},
+		{"Epoch", etensor.INT64, nil, nil},
+		{"Trans", etensor.FLOAT64, nil, nil},
+		{"Rot", etensor.FLOAT64, nil, nil},
+		{"ScaleMin", etensor.FLOAT64, nil, nil},
+		{"ScaleMax", etensor.FLOAT64, nil, nil},
+		{"Rank", etensor.INT64, nil, nil},
+	}
+	for _, st := range EvalWtsStats {
+		sch = append(sch, etable.Column{Name: st, Type: etensor.FLOAT64})
+	}
+	dt.SetFromSchema(sch, rows)
+}
+
+// EvalDist evaluates the conditions of the given weights files glob pattern
+// crossed with the given transform grid, distributed across the MPI procs,
+// and returns the table of results, gathered on all procs, in the order of
+// the conditions.  Conditions that fail are reported and omitted.
+func (ss *Sim) EvalDist(pattern, grid string) (*etable.Table, error) {
+	ev := ss.ImagesEnv(etime.Test)
+	if ev == nil {
+		return nil, fmt.Errorf("EvalDist: requires the Images env")
+	}
+	fnms, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(fnms) == 0 {
+		return nil, fmt.Errorf("EvalDist: no weights files match: %s", pattern)
+	}
+	sort.Strings(fnms)
+	conds, err := EvalGridConds(fnms, grid, ev)
+	if err != nil {
+		return nil, err
+	}
+	rank, nproc := 0, 1
+	if ss.Config.Run.MPI {
+		rank, nproc = ss.MPIRank(), ss.MPISize()
+	}
+	nper := (len(conds) + nproc - 1) / nproc // same rows on all procs, for gathering
+	dt := &etable.Table{}
+	ConfigEvalDistTable(dt, nper)
+	for i := 0; i < nper; i++ {
+		dt.SetCellFloat("Rank", i, -1) // not run
+	}
+
+	// each proc tests the full test set, with the transforms of rank 0,
+	// all of the trials of all procs, and no MPI sharing of stats
+	mpiOn := ss.Config.Run.MPI
+	stRow, edRow, evRank := ev.StRow, ev.EdRow, ev.Rank
+	trans, rot, scl := ev.TransMax, ev.RotateMax, ev.ScaleRange
+	trl := ss.Loops.GetLoop(etime.Test, etime.Trial)
+	ss.Config.Run.MPI = false
+	ev.StRow, ev.EdRow, ev.Rank = 0, 0, 0
+	trl.Counter.Max = ss.Trials.EffTotal
+	for i := 0; i < nper; i++ {
+		ci := rank + i*nproc
+		if ci >= len(conds) {
+			break
+		}
+		cd := &conds[ci]
+		ev.TransMax.Set(cd.Trans, cd.Trans)
+		ev.RotateMax = cd.Rot
+		ev.ScaleRange.Set(cd.ScaleMin, cd.ScaleMax)
+		mpi.AllPrintf("EvalDist: %d / %d: testing: %s  Trans: %g  Rot: %g  Scale: %g-%g\n", ci, len(conds), cd.File, cd.Trans, cd.Rot, cd.ScaleMin, cd.ScaleMax)
+		if err := ss.TestWts(cd.File); err != nil {
+			mpi.AllPrintf("%s\n", err)
+			continue
+		}
+		et := ss.Logs.Table(etime.Test, etime.Epoch)
+		if et.Rows == 0 {
+			continue
+		}
+		run, epc := WtsFileCtrs(cd.File)
+		dt.SetCellString("File", i, cd.File)
+		dt.SetCellFloat("Run", i, float64(run))
+		dt.SetCellFloat("Epoch", i, float64(epc))
+		dt.SetCellFloat("Trans", i, float64(cd.Trans))
+		dt.SetCellFloat("Rot", i, float64(cd.Rot))
+		dt.SetCellFloat("ScaleMin", i, float64(cd.ScaleMin))
+		dt.SetCellFloat("ScaleMax", i, float64(cd.ScaleMax))
+		dt.SetCellFloat("Rank", i, float64(rank))
+		for _, st := range EvalWtsStats {
+			dt.SetCellFloat(st, i, et.CellFloat(st, et.Rows-1))
+		}
+	}
+	ss.Config.Run.MPI = mpiOn
+	ev.StRow, ev.EdRow, ev.Rank = stRow, edRow, evRank
+	trl.Counter.Max = ss.Trials.PerProc
+	ev.TransMax, ev.RotateMax, ev.ScaleRange = trans, rot, scl
+
+	all := dt
+	if mpiOn {
+		all = &etable.Table{}
+		empi.GatherTableRows(all, dt, ss.Comm)
+	}
+	// gathered rows are in proc order: restore the condition order, skipping those not run
+	res := &etable.Table{}
+	ConfigEvalDistTable(res, 0)
+	for ci := range conds {
+		ri := (ci%nproc)*nper + ci/nproc
+		if ri >= all.Rows || all.CellFloat("Rank", ri) < 0 {
+			continue
+		}
+		row := res.Rows
+		res.SetNumRows(row + 1)
+		for _, cl := range res.ColNames {
+			if cl == "File" {
+				res.SetCellString(cl, row, all.CellString(cl, ri))
+			} else {
+				res.SetCellFloat(cl, row, all.CellFloat(cl, ri))
+			}
+		}
+	}
+	return res, nil
+}
+
+// RunEvalDist runs EvalDist on the Config.Run.EvalWts files and
+// Config.Run.EvalGrid, and saves the results table as an eval_grid
+// log file on rank 0
+func (ss *Sim) RunEvalDist() error {
+	dt, err := ss.EvalDist(ss.Config.Run.EvalWts, ss.Config.Run.EvalGrid)
+	if err != nil {
+		return err
+	}
+	ss.Logs.MiscTables["EvalGrid"] = dt
+	if ss.MPIRank() != 0 {
+		return nil
+	}
+	fnm := elog.LogFileName("eval_grid", ss.Net.Name(), ss.Stats.String("RunName"))
+	if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	mpi.Printf("Saved distributed evaluation of %d conditions to: %s\n", dt.Rows, fnm)
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvalGridConds(t *testing.T) {
+	ev := &ImagesEnv{}
+	ev.TransMax.Set(0.3, 0.3)
+	ev.RotateMax = 8
+	ev.ScaleRange.Set(0.5, 1.1)
+	cond := func(fnm string, trans, rot, smin, smax float32) EvalCond {
+		return EvalCond{File: fnm, Trans: trans, Rot: rot, ScaleMin: smin, ScaleMax: smax}
+	}
+	tests := []struct {
+		fnms []string
+		grid string
+		want []EvalCond
+		err  bool
+	}{
+		{[]string{"a.wts.gz"}, "", []EvalCond{cond("a.wts.gz", 0.3, 8, 0.5, 1.1)}, false},
+		{[]string{"a", "b"}, "Trans=0,0.15 Scale=1,0.5:0.9", []EvalCond{
+			cond("a", 0, 8, 1, 1),
+			cond("a", 0, 8, 0.5, 0.9),
+			cond("a", 0.15, 8, 1, 1),
+			cond("a", 0.15, 8, 0.5, 0.9),
+			cond("b", 0, 8, 1, 1),
+			cond("b", 0, 8, 0.5, 0.9),
+			cond("b", 0.15, 8, 1, 1),
+			cond("b", 0.15, 8, 0.5, 0.9),
+		}, false},
+		{[]string{"a"}, "Rot=0, 16", nil, true}, // space splits items
+		{[]string{"a"}, "Rot=0,16", []EvalCond{cond("a", 0.3, 0, 0.5, 1.1), cond("a", 0.3, 16, 0.5, 1.1)}, false},
+		{[]string{"a"}, "Size=1", nil, true},
+		{[]string{"a"}, "Trans=x", nil, true},
+		{[]string{"a"}, "Scale=0.5:y", nil, true},
+		{nil, "Trans=0,0.15", nil, false},
+	}
+	for _, tt := range tests {
+		got, err := EvalGridConds(tt.fnms, tt.grid, ev)
+		if (err != nil) != tt.err {
+			t.Errorf("EvalGridConds(%v, %q) error: %v, want error: %v", tt.fnms, tt.grid, err, tt.err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("EvalGridConds(%v, %q) = %v, want: %v", tt.fnms, tt.grid, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// evalwts.go has the batch evaluation of many saved weights files, e.g.,
+// from different epochs or runs, on the identical test set (see TestWts),
+// saving a summary table of the test stats by checkpoint, in one command.
+// See Config.Run.EvalWts.
+
+// EvalWtsStats are the test epoch log stats recorded for each weights file
+var EvalWtsStats = []string{"PctErr", "PctErr2", "DecErr", "DecErr2", "CorSim", "UnitErr"}
+
+// wtsCtrsRe matches the run and epoch counters in the weights file names,
+// as saved by SaveWeights: <net>_<runName>_<run>_<epoch>[tag].wts.gz
+var wtsCtrsRe = regexp.MustCompile(`_(\d{3,})_(\d{5,})`)
+
+// WtsFileCtrs returns the run and epoch parsed from the given weights
+// file name, or -1 if not found
+func WtsFileCtrs(fname string) (run, epoch int) {
+	ms := wtsCtrsRe.FindAllStringSubmatch(filepath.Base(fname), -1)
+	if len(ms) == 0 {
+		return -1, -1
+	}
+	m := ms[len(ms)-1]
+	run, _ = strconv.Atoi(m[1])
+	epoch, _ = strconv.Atoi(m[2])
+	return
+}
+
+// EvalWts evaluates each of the weights files matching the given glob
+// pattern, in sorted order, on the full test set, and returns a table
+// with the file, its run and epoch, and the EvalWtsStats from the test
+// epoch log.  Files that fail to open are reported and skipped.
+// The current weights are replaced by those from the last file.
+func (ss *Sim) EvalWts(pattern string) (*etable.Table, error) {
+	fnms, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(fnms) == 0 {
+		return nil, fmt.Errorf("EvalWts: no weights files match: %s", pattern)
+	}
+	sort.Strings(fnms)
+	sch := etable.Schema{
+		{"File", etensor.STRING, nil, nil},
+		{"Run", etensor.INT64, nil, nil},
+		{"Epoch", etensor.INT64, nil, nil},
+	}
+	for _, st := range EvalWtsStats {
+		sch = append(sch, etable.Column{Name: st, Type: etensor.FLOAT64})
+	}
+	dt := &etable.Table{}
+	dt.SetFromSchema(sch, 0)
+	for _, fnm := range fnms {
+		mpi.Printf("EvalWts: testing: %s\n", fnm)
+		if err := ss.TestWts(fnm); err != nil {
+			mpi.Println(err)
+			continue
+		}
+		et := ss.Logs.Table(etime.Test, etime.Epoch)
+		if et.Rows == 0 {
+			continue
+		}
+		run, epc := WtsFileCtrs(fnm)
+		row := dt.Rows
+		dt.SetNumRows(row + 1)
+		dt.SetCellString("File", row, fnm)
+		dt.SetCellFloat("Run", row, float64(run))
+		dt.SetCellFloat("Epoch", row, float64(epc))
+		for _, st := range EvalWtsStats {
+			dt.SetCellFloat(st, row, et.CellFloat(st, et.Rows-1))
+		}
+		mpi.Printf("EvalWts: %s  PctErr: %g\n", fnm, dt.CellFloat("PctErr", row))
+	}
+	return dt, nil
+}
+
+// RunEvalWts evaluates the Config.Run.EvalWts weights files, and saves the
+// summary table as an eval_wts log file on rank 0
+func (ss *Sim) RunEvalWts() error {
+	dt, err := ss.EvalWts(ss.Config.Run.EvalWts)
+	if err != nil {
+		return err
+	}
+	ss.Logs.MiscTables["EvalWts"] = dt
+	if ss.MPIRank() != 0 {
+		return nil
+	}
+	fnm := elog.LogFileName("eval_wts", ss.Net.Name(), ss.Stats.String("RunName"))
+	if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	mpi.Printf("Saved evaluation of %d weights files to: %s\n", dt.Rows, fnm)
+	return nil
+}
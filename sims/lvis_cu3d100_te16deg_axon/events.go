@@ -0,0 +1,146 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+)
+
+// events.go has the trial event stream, for custom dashboards and
+// alerting without parsing the TSV logs post hoc: at the end of each
+// trial, after the trial stats are computed, one JSON object per data
+// index is written as a line to a per-proc events file and / or sent
+// to a socket, as newline-delimited JSON.  See Config.Log.Events.
+
+// TrialEvent is the event record for one trial and data index
+type TrialEvent struct {
+
+	// wall clock time of the end of the trial, in RFC3339 format with nanoseconds
+	Time string `json:"time"`
+
+	// MPI rank of the proc running the trial
+	Rank int `json:"rank"`
+
+	// Train or Test
+	Mode string `json:"mode"`
+
+	// run, epoch, trial counters, as in the trial logs
+	Run   int `json:"run"`
+	Epoch int `json:"epoch"`
+	Trial int `json:"trial"`
+
+	// data index within the trial
+	Di int `json:"di"`
+
+	// category of the image, and the network response, none if no response
+	Cat  string `json:"cat"`
+	Resp string `json:"resp"`
+
+	// trial error of the network response, and of the decoder
+	Err    float64 `json:"err"`
+	DecErr float64 `json:"decErr"`
+
+	// reaction time of the Output layer, in cycles, -1 if no response
+	RT float64 `json:"rt"`
+}
+
+// Events has the outputs for the trial event stream
+type Events struct {
+
+	// events file, if open
+	File *os.File
+
+	// socket connection, if open
+	Conn net.Conn
+}
+
+// ConfigEvents opens the socket connection for the trial events,
+// if Config.Log.Events.Addr is set
+func (ss *Sim) ConfigEvents() {
+	ec := &ss.Config.Log.Events
+	if ec.Addr == "" || ss.Events.Conn != nil {
+		return
+	}
+	conn, err := net.Dial(ec.Net, ec.Addr)
+	if err != nil {
+		mpi.Println("Events:", err)
+		return
+	}
+	ss.Events.Conn = conn
+}
+
+// OpenEvents opens the events file for this proc, named by the network
+// and run, if Config.Log.Events.File is set (in nogui mode)
+func (ss *Sim) OpenEvents(netName, runName string) {
+	if !ss.Config.Log.Events.File {
+		return
+	}
+	fnm := fmt.Sprintf("%s_%s_events_%d.jsonl", netName, runName, ss.MPIRank())
+	f, err := os.Create(fnm)
+	if err != nil {
+		mpi.Println("Events:", err)
+		return
+	}
+	ss.Events.File = f
+	mpi.Printf("Saving trial events to: %s\n", fnm)
+}
+
+// CloseEvents closes the events file and socket connection
+func (ss *Sim) CloseEvents() {
+	ev := &ss.Events
+	if ev.File != nil {
+		ev.File.Close()
+		ev.File = nil
+	}
+	if ev.Conn != nil {
+		ev.Conn.Close()
+		ev.Conn = nil
+	}
+}
+
+// EmitTrialEvent emits the event for the current trial of given mode,
+// for given data index, from the trial stats.  Called in Log after
+// TrialStats.
+func (ss *Sim) EmitTrialEvent(mode etime.Modes, di int) {
+	evs := &ss.Events
+	if evs.File == nil && evs.Conn == nil {
+		return
+	}
+	if mode != etime.Train && mode != etime.Test {
+		return
+	}
+	st := ss.Loops.Stacks[mode]
+	te := &TrialEvent{Time: time.Now().Format(time.RFC3339Nano), Rank: ss.MPIRank(), Mode: mode.String(), Di: di}
+	te.Run = ss.Loops.GetLoop(etime.Train, etime.Run).Counter.Cur
+	te.Epoch = st.Loops[etime.Epoch].Counter.Cur
+	te.Trial = st.Loops[etime.Trial].Counter.Cur + di
+	te.Cat = ss.Stats.String("TrlCat")
+	te.Resp = ss.Stats.String("TrlResp")
+	te.Err = ss.Stats.Float("TrlErr")
+	te.DecErr = ss.Stats.Float("TrlDecErr")
+	te.RT = ss.Stats.Float("TrlOutRT")
+	b, _ := json.Marshal(te)
+	b = append(b, '\n')
+	if evs.File != nil {
+		if _, err := evs.File.Write(b); err != nil {
+			mpi.Println("Events:", err)
+			evs.File = nil
+		}
+	}
+	if evs.Conn != nil {
+		if _, err := evs.Conn.Write(b); err != nil && ss.Config.Log.Events.Net != "udp" {
+			mpi.Println("Events:", err) // stream closed: stop sending
+			evs.Conn.Close()
+			evs.Conn = nil
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+func TestResultRows(t *testing.T) {
+	dt := &etable.Table{}
+	dt.SetFromSchema(etable.Schema{
+		{"Run", etensor.INT64, nil, nil},
+		{"Epoch", etensor.INT64, nil, nil},
+		{"RunName", etensor.STRING, nil, nil},
+		{"PctErr", etensor.FLOAT64, nil, nil},
+		{"Layer_Act", etensor.FLOAT32, []int{2}, nil},
+		{"TstPctErr", etensor.FLOAT64, nil, nil},
+	}, 2)
+	for ri := 0; ri < 2; ri++ {
+		dt.SetCellFloat("Run", ri, 1)
+		dt.SetCellFloat("Epoch", ri, float64(ri*10))
+		dt.SetCellString("RunName", ri, "Base")
+		dt.SetCellFloat("PctErr", ri, 0.5-0.25*float64(ri))
+	}
+	dt.SetCellFloat("TstPctErr", 0, math.NaN())
+	dt.SetCellFloat("TstPctErr", 1, 0.125)
+	want := []ResultRow{
+		{"LVis", "Base", "Train", 1, 0, "PctErr", 0.5},
+		{"LVis", "Base", "Train", 1, 10, "PctErr", 0.25},
+		{"LVis", "Base", "Train", 1, 10, "TstPctErr", 0.125},
+	}
+	if rows := ResultRows(dt, "LVis", "Train"); !reflect.DeepEqual(rows, want) {
+		t.Errorf("ResultRows: got: %v, want: %v", rows, want)
+	}
+}
+
+func TestSaveResultsParquet(t *testing.T) {
+	rows := []ResultRow{
+		{"LVis", "Base", "Train", 0, 1, "PctErr", 0.5},
+		{"LVis", "Base", "Test", 0, 1, "PctErr", 0.75},
+	}
+	fnm := filepath.Join(t.TempDir(), "results.parquet")
+	if err := SaveResultsParquet(fnm, rows); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(fnm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := len(b)
+	flen := int(binary.LittleEndian.Uint32(b[n-8 : n-4]))
+	tr := &thriftReader{buf: b[n-8-flen : n-8]}
+	md := tr.structure()
+	if tr.err || md[3] != int64(len(rows)) {
+		t.Fatalf("footer: num_rows: %v, want: %d", md[3], len(rows))
+	}
+	var names []string
+	for _, el := range md[2].([]any)[1:] {
+		names = append(names, el.(map[int16]any)[4].(string))
+	}
+	if !reflect.DeepEqual(names, ResultCols) {
+		t.Errorf("schema: %v, want: %v", names, ResultCols)
+	}
+}
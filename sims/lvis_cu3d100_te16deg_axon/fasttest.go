@@ -0,0 +1,154 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/erand"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/etable/etensor"
+)
+
+// fasttest.go has the FastTest mode, for cheap intermediate checks during
+// long runs: a fixed random subset of the test images, FastTestNPerCat
+// per category, is tested every FastTestInterval training epochs, with
+// the full TestAll only every TestInterval epochs.  FastTest rows in the
+// Test Epoch log have FastTest = 1, and their errors are copied to the
+// training epoch log with a Fst prefix, while the Tst items only reflect
+// the full tests, as do the Test Epoch analyses and SaveWeightsBest (see
+// FullTestOnly).  See Config.Run.FastTestInterval.
+
+// FastTestStats are the Test Epoch stats copied to the training
+// epoch log with a Fst prefix
+var FastTestStats = []string{"PctErr", "PctErr2", "DecErr"}
+
+// FastTestSubset returns this proc's portion of the FastTest subset of
+// the images in given test env, FastTestNPerCat per category -- see
+// ProcSubset
+func (ss *Sim) FastTestSubset(ev *ImagesEnv) []int {
+	return ss.ProcSubset(ev, ss.Config.Run.FastTestNPerCat)
+}
+
+// ProcSubset returns this proc's portion of a fixed random subset of
+// nPerCat images per category in the current ImageList of given env,
+// topped up with other random images to an even multiple of NData times
+// the number of procs, so every proc tests the same number of distinct
+// images, without dropping or repeating any.  Returns nil if there are
+// not enough images for that.
+func (ss *Sim) ProcSubset(ev *ImagesEnv, nPerCat int) []int {
+	sub := ev.SubsetNPerCat(nPerCat, ev.RndSeed)
+	np := ss.MPISize()
+	stepN := ss.Config.Run.NData * np
+	n := ((len(sub) + stepN - 1) / stepN) * stepN
+	nimg := len(ev.ImageList())
+	if n > nimg {
+		return nil
+	}
+	if n > len(sub) {
+		has := make(map[int]bool, len(sub))
+		for _, i := range sub {
+			has[i] = true
+		}
+		rnd := erand.NewSysRand(ev.RndSeed + 1)
+		for _, i := range rnd.Perm(nimg, -1) {
+			if len(sub) == n {
+				break
+			}
+			if !has[i] {
+				sub = append(sub, i)
+			}
+		}
+		sort.Ints(sub)
+	}
+	pt := n / np
+	st := pt * ss.MPIRank()
+	return sub[st : st+pt]
+}
+
+// FastTest runs through the FastTest subset of the test images, with
+// the FastTest stat = 1.  Requires the Images env with enough test images
+// for the FastTestSubset: otherwise it runs the full TestAll, still as
+// a FastTest.
+func (ss *Sim) FastTest() {
+	ss.Stats.SetInt("FastTest", 1)
+	ev := ss.ImagesEnv(etime.Test)
+	var sub []int
+	if ev != nil {
+		ev.Init(0)
+		sub = ss.FastTestSubset(ev)
+	}
+	if sub == nil {
+		ss.TestAll()
+		ss.Stats.SetInt("FastTest", 0)
+		return
+	}
+	ev.SetSubset(sub)
+	trl := ss.Loops.GetLoop(etime.Test, etime.Trial)
+	trl.Counter.Max = len(sub)
+	ss.Loops.ResetAndRun(etime.Test)
+	ss.Loops.Mode = etime.Train // as in TestAll
+	ss.Stats.SetInt("FastTest", 0)
+	trl.Counter.Max = ss.Trials.PerProc
+	ev.SetSubset(nil)
+}
+
+// FullTestOnly returns a function calling given function only if the
+// current test is not a FastTest, for the Test Epoch analyses and
+// SaveWeightsBest, which only reflect the full test set
+func (ss *Sim) FullTestOnly(fun func()) func() {
+	return func() {
+		if ss.Stats.Int("FastTest") == 0 {
+			fun()
+		}
+	}
+}
+
+// TestEpochFloat returns the value of given stat in the last row of the
+// Test Epoch log that is from a FastTest if fast is true, or from a full
+// test otherwise -- 0 if there are no such rows
+func (ss *Sim) TestEpochFloat(stat string, fast bool) float64 {
+	dt := ss.Logs.Table(etime.Test, etime.Epoch)
+	for row := dt.Rows - 1; row >= 0; row-- {
+		if (dt.CellFloat("FastTest", row) == 1) == fast {
+			return dt.CellFloat(stat, row)
+		}
+	}
+	return 0
+}
+
+// ConfigFastTestLogs adds the FastTest stat to the Test Epoch log, and the
+// Fst copies of the FastTestStats to the training epoch log, and makes the
+// training epoch Tst items copy from the last full test only -- called
+// after all the Tst items have been added.
+func (ss *Sim) ConfigFastTestLogs() {
+	ss.Stats.SetInt("FastTest", 0) // also for Ablate, see FullTestOnly
+	if ss.Config.Run.FastTestInterval <= 0 {
+		return
+	}
+	ss.Logs.AddStatIntNoAggItem(etime.Test, etime.Epoch, "FastTest")
+	trnEpc := etime.Scope(etime.Train, etime.Epoch)
+	for _, itm := range ss.Logs.Items {
+		if _, has := itm.Write[trnEpc]; !has || !strings.HasPrefix(itm.Name, "Tst") {
+			continue
+		}
+		stnm := strings.TrimPrefix(itm.Name, "Tst")
+		itm.Write[trnEpc] = func(ctx *elog.Context) {
+			ctx.SetFloat64(ss.TestEpochFloat(stnm, false))
+		}
+	}
+	for _, st := range FastTestStats {
+		stnm := st
+		ss.Logs.AddItem(&elog.Item{
+			Name: "Fst" + st,
+			Type: etensor.FLOAT64,
+			Write: elog.WriteMap{
+				trnEpc: func(ctx *elog.Context) {
+					ctx.SetFloat64(ss.TestEpochFloat(stnm, true))
+				}}})
+	}
+}
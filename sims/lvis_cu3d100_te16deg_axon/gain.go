@@ -0,0 +1,137 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/agg"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/minmax"
+	"github.com/goki/gi/gi"
+)
+
+// gain.go has the layer gain manipulation test, mimicking neuromodulatory
+// gain manipulations: the excitatory conductance (Acts.Gbar.E, the
+// multiplier on Ge) of Config.Run.GainLay is scaled by each of the
+// Config.Run.GainFactors in turn, and the full test set is run at each,
+// recording the test error and the Output response time (OutRT, in cycles,
+// over the trials with a response -- see Acts.AttnMod.RTThr), saved as a gain
+// log.  The original gain is restored at the end.
+
+// GainStats are the test epoch log stats recorded at each gain factor
+var GainStats = []string{"PctErr", "PctErr2", "DecErr", "OutRT"}
+
+// SetLayerGain sets the excitatory conductance of given layer to given
+// factor times the original value, syncing the params to the GPU
+func (ss *Sim) SetLayerGain(lay string, gbarE, factor float32) error {
+	ly := ss.Net.AxonLayerByName(lay)
+	if ly == nil {
+		return fmt.Errorf("Gain: layer %s not found", lay)
+	}
+	ly.Params.Acts.Gbar.E = gbarE * factor
+	ss.Net.GPU.SyncParamsToGPU()
+	return nil
+}
+
+// GainTest tests the full test set at each of the Config.Run.GainFactors
+// scaling of the gain of Config.Run.GainLay, and returns the table of
+// Factor and the GainStats of the test epoch log
+func (ss *Sim) GainTest() (*etable.Table, error) {
+	rc := &ss.Config.Run
+	ly := ss.Net.AxonLayerByName(rc.GainLay)
+	if ly == nil {
+		return nil, fmt.Errorf("Gain: layer %s not found", rc.GainLay)
+	}
+	facts := rc.GainFactors
+	if len(facts) == 0 {
+		facts = []float32{1}
+	}
+	sch := etable.Schema{
+		{"Factor", etensor.FLOAT64, nil, nil},
+	}
+	for _, st := range GainStats {
+		sch = append(sch, etable.Column{Name: st, Type: etensor.FLOAT64})
+	}
+	dt := &etable.Table{}
+	dt.SetMetaData("name", "Gain")
+	dt.SetMetaData("desc", "test error and response time vs. layer gain")
+	dt.SetFromSchema(sch, 0)
+	gbarE := ly.Params.Acts.Gbar.E
+	defer ss.SetLayerGain(rc.GainLay, gbarE, 1)
+	for _, f := range facts {
+		ss.SetLayerGain(rc.GainLay, gbarE, f)
+		ss.TestAll()
+		et := ss.Logs.Table(etime.Test, etime.Epoch)
+		if et.Rows == 0 {
+			continue
+		}
+		row := dt.Rows
+		dt.SetNumRows(row + 1)
+		dt.SetCellFloat("Factor", row, float64(f))
+		for _, st := range GainStats {
+			dt.SetCellFloat(st, row, et.CellFloat(st, et.Rows-1))
+		}
+		mpi.Printf("Gain: %s x %g  PctErr: %g  OutRT: %g\n", rc.GainLay, f, dt.CellFloat("PctErr", row), dt.CellFloat("OutRT", row))
+		if ss.GUI.StopNow {
+			break
+		}
+	}
+	return dt, nil
+}
+
+// RunGainTest runs the GainTest, and saves the table as a gain log file
+// (in nogui mode, on rank 0)
+func (ss *Sim) RunGainTest() error {
+	dt, err := ss.GainTest()
+	if err != nil {
+		return err
+	}
+	ss.Logs.MiscTables["Gain"] = dt
+	if ss.Config.GUI || ss.MPIRank() != 0 {
+		return nil
+	}
+	fnm := elog.LogFileName("gain", ss.Net.Name(), ss.Stats.String("RunName"))
+	if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	mpi.Printf("Saved gain test to: %s\n", fnm)
+	return nil
+}
+
+// RunGainTestGUI runs the GainTest, has stop running = false at end -- for gui
+func (ss *Sim) RunGainTestGUI() {
+	ss.GUI.StopNow = false
+	if err := ss.RunGainTest(); err != nil {
+		mpi.Println(err)
+	}
+	ss.GUI.Stopped()
+}
+
+// ConfigGainLogs adds the OutRT item to the Test logs: the Output
+// response time, NaN for trials without a response, so the epoch mean is
+// over those with a response
+func (ss *Sim) ConfigGainLogs() {
+	ss.Logs.AddItem(&elog.Item{
+		Name:  "OutRT",
+		Type:  etensor.FLOAT64,
+		Plot:  elog.DFalse,
+		Range: minmax.F64{Min: 0},
+		Write: elog.WriteMap{
+			etime.Scope(etime.Test, etime.Trial): func(ctx *elog.Context) {
+				rt := ss.Stats.Float("TrlOutRT")
+				if rt < 0 {
+					rt = math.NaN()
+				}
+				ctx.SetFloat64(rt)
+			}, etime.Scope(etime.Test, etime.Epoch): func(ctx *elog.Context) {
+				ctx.SetAgg(ctx.Mode, etime.Trial, agg.AggMean)
+			}}})
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// gpucheck.go has the GPU vs. CPU numerical consistency check, which runs
+// the same trial on both from identical state, and reports the max
+// divergence across all neuron variables, per layer per cycle, to localize
+// discrepancies in the GPU kernels.  Each cycle, the GPU runs one cycle
+// in CycleByCycle mode, which syncs all of the state back, and then the
+// CPU state is restored and runs the same cycle on the CPU, so the two
+// trajectories evolve independently.  The check runs with Testing on, so
+// synaptic Ca is not updated -- it is for the neuron-level state.  The
+// network state is restored afterward, so the trial then runs as usual.
+
+// GPUCheckMaxCycles is the max number of cycles for the GPUCheck:
+// up to the Beta1 event, which is not replicated in the check.
+const GPUCheckMaxCycles = 50
+
+// NetState is a copy of the network state that is updated by Cycle
+type NetState struct {
+
+	// network context
+	Ctx axon.Context
+
+	// global vars
+	Globals []float32
+
+	// layer values
+	LayVals []axon.LayerVals
+
+	// inhibitory pools
+	Pools []axon.Pool
+
+	// neuron vars
+	Neurons []float32
+
+	// projection conductance buffers
+	PrjnGBuf []int32
+
+	// projection synaptic conductances
+	PrjnGSyns []float32
+}
+
+// Save copies the state from given network and context
+func (st *NetState) Save(net *axon.Network, ctx *axon.Context) {
+	st.Ctx = *ctx
+	st.Globals = append(st.Globals[:0], net.Globals...)
+	st.LayVals = append(st.LayVals[:0], net.LayVals...)
+	st.Pools = append(st.Pools[:0], net.Pools...)
+	st.Neurons = append(st.Neurons[:0], net.Neurons...)
+	st.PrjnGBuf = append(st.PrjnGBuf[:0], net.PrjnGBuf...)
+	st.PrjnGSyns = append(st.PrjnGSyns[:0], net.PrjnGSyns...)
+}
+
+// Restore copies the saved state back into given network and context
+func (st *NetState) Restore(net *axon.Network, ctx *axon.Context) {
+	*ctx = st.Ctx
+	copy(net.Globals, st.Globals)
+	copy(net.LayVals, st.LayVals)
+	copy(net.Pools, st.Pools)
+	copy(net.Neurons, st.Neurons)
+	copy(net.PrjnGBuf, st.PrjnGBuf)
+	copy(net.PrjnGSyns, st.PrjnGSyns)
+}
+
+// GPUCheckDiff returns the abs difference between CPU and GPU values,
+// which is Inf if only one of them is NaN
+func GPUCheckDiff(cv, gv float32) float64 {
+	cn := math.IsNaN(float64(cv))
+	gn := math.IsNaN(float64(gv))
+	switch {
+	case cn && gn:
+		return 0
+	case cn || gn:
+		return math.Inf(1)
+	}
+	return math.Abs(float64(cv) - float64(gv))
+}
+
+// GPUCheck runs the GPU vs. CPU consistency check for given number of
+// cycles of the current trial, after the inputs have been applied,
+// returning a table with the max abs difference across neuron variables
+// and data indexes for each layer on each cycle, and the variable with
+// that max.  The network state is restored at the end.
+func (ss *Sim) GPUCheck(ncyc int) *etable.Table {
+	net := ss.Net
+	ctx := &ss.Context
+	if !net.GPU.On {
+		mpi.Println("GPUCheck: the GPU is not on")
+		return nil
+	}
+	if ncyc > GPUCheckMaxCycles {
+		ncyc = GPUCheckMaxCycles
+	}
+	dt := &etable.Table{}
+	dt.SetFromSchema(etable.Schema{
+		{"Cycle", etensor.INT64, nil, nil},
+		{"Layer", etensor.STRING, nil, nil},
+		{"MaxDiff", etensor.FLOAT64, nil, nil},
+		{"MaxVar", etensor.STRING, nil, nil},
+	}, 0)
+
+	net.GPU.SyncAllFmGPU()
+	var start, cpu NetState
+	start.Save(net, ctx)
+	net.GPU.SyncStateGBufToGPU() // GBuf is not synced back from the GPU
+	cycByCyc := net.GPU.CycleByCycle
+	net.GPU.CycleByCycle = true
+	ctx.Testing.SetBool(true)
+	ctx.PlusPhase.SetBool(false)
+	ctx.NewPhase(false)
+	cpu.Save(net, ctx)
+	var gpuNrns []float32
+	for cyc := 0; cyc < ncyc; cyc++ {
+		net.Cycle(ctx) // GPU: syncs all state back
+		gpuNrns = append(gpuNrns[:0], net.Neurons...)
+		cpu.Restore(net, ctx)
+		net.GPU.On = false
+		net.Cycle(ctx)
+		net.GPU.On = true
+		ss.GPUCheckDiffs(dt, cyc, gpuNrns)
+		ctx.CycleInc()
+		cpu.Save(net, ctx)
+	}
+	start.Restore(net, ctx)
+	net.GPU.CycleByCycle = cycByCyc
+	net.GPU.SyncStateGBufToGPU()
+	net.GPU.SyncContextToGPU()
+	return dt
+}
+
+// GPUCheckDiffs adds a row to the GPUCheck table for each layer,
+// comparing the current CPU neuron state with the GPU one
+func (ss *Sim) GPUCheckDiffs(dt *etable.Table, cyc int, gpuNrns []float32) {
+	net := ss.Net
+	ctx := &ss.Context
+	nd := ctx.NetIdxs.NData
+	for _, ly := range net.Layers {
+		if ly.IsOff() {
+			continue
+		}
+		maxd := 0.0
+		maxv := axon.NeuronVars(0)
+		for lni := uint32(0); lni < ly.NNeurons; lni++ {
+			ni := ly.NeurStIdx + lni
+			for vi := axon.NeuronVars(0); vi < axon.NeuronVarsN; vi++ {
+				for di := uint32(0); di < nd; di++ {
+					idx := ctx.NeuronVars.Idx(ni, di, vi)
+					if d := GPUCheckDiff(net.Neurons[idx], gpuNrns[idx]); d > maxd {
+						maxd = d
+						maxv = vi
+					}
+				}
+			}
+		}
+		row := dt.Rows
+		dt.SetNumRows(row + 1)
+		dt.SetCellFloat("Cycle", row, float64(cyc))
+		dt.SetCellString("Layer", row, ly.Name())
+		dt.SetCellFloat("MaxDiff", row, maxd)
+		dt.SetCellString("MaxVar", row, maxv.String())
+	}
+}
+
+// GPUCheckSummary prints, for each layer, the max difference over all
+// cycles, with the cycle and variable where it first occurred
+func GPUCheckSummary(dt *etable.Table) {
+	type lmax struct {
+		diff float64
+		cyc  int
+		vr   string
+	}
+	var lays []string
+	maxs := make(map[string]*lmax)
+	for ri := 0; ri < dt.Rows; ri++ {
+		lnm := dt.CellString("Layer", ri)
+		lm, ok := maxs[lnm]
+		if !ok {
+			lm = &lmax{}
+			maxs[lnm] = lm
+			lays = append(lays, lnm)
+		}
+		if d := dt.CellFloat("MaxDiff", ri); d > lm.diff {
+			lm.diff = d
+			lm.cyc = int(dt.CellFloat("Cycle", ri))
+			lm.vr = dt.CellString("MaxVar", ri)
+		}
+	}
+	mpi.Printf("GPUCheck: max GPU vs. CPU divergence per layer:\n")
+	for _, lnm := range lays {
+		lm := maxs[lnm]
+		mpi.Printf("  %s:\t%g\tcycle: %d\tvar: %s\n", lnm, lm.diff, lm.cyc, lm.vr)
+	}
+}
+
+// GPUCheckTrial runs the GPUCheck for Config.GPUCheck cycles on the first
+// training trial of each run, saving the table in MiscTables and, in nogui
+// mode, to the gpucheck log file on rank 0.
+func (ss *Sim) GPUCheckTrial() {
+	if ss.Config.GPUCheck <= 0 {
+		return
+	}
+	if ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur != 0 || ss.Loops.GetLoop(etime.Train, etime.Trial).Counter.Cur != 0 {
+		return
+	}
+	dt := ss.GPUCheck(ss.Config.GPUCheck)
+	if dt == nil {
+		return
+	}
+	ss.Logs.MiscTables["GPUCheck"] = dt
+	GPUCheckSummary(dt)
+	if ss.MPIRank() != 0 || ss.Config.GUI {
+		return
+	}
+	fnm := elog.LogFileName("gpucheck", ss.Net.Name(), ss.Stats.String("RunName"))
+	if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		mpi.Println(err)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/emergent/etime"
+	"github.com/emer/emergent/looper"
+	"github.com/emer/empi/mpi"
+)
+
+// hooks.go has the plugin hooks, for layering lab-specific analyses onto
+// the sim as separate Go files, without modifying ConfigLoops or
+// ConfigLogs.  A plugin registers its callbacks in an init() function:
+//
+//	func init() {
+//		AddLogsHook("MyStat", func(ss *Sim) {
+//			ss.Stats.SetFloat("MyStat", 0)
+//			ss.Logs.AddStatAggItem("MyStat", etime.Run, etime.Epoch, etime.Trial)
+//		})
+//		AddHook("MyStat", etime.AllModes, etime.Trial, HookEnd, func(ss *Sim) {
+//			ss.Stats.SetFloat("MyStat", ...)
+//		})
+//	}
+//
+// The callbacks get the Sim, with full access to its Net, Stats, Logs, and
+// Config.  They are called at the start or end of each trial, epoch, or
+// run of the given mode: HookEnd callbacks are called before the Log of
+// that level, so they can set stats to be logged, and HookLogged ones
+// after it, so they can use the logged values.  Note that trial-level
+// stats are logged for each data parallel item in turn (see
+// Context.NetIdxs.NData), and the trial log row for each is only complete
+// in HookLogged.  Config.Run.Hooks selects the hooks by name, all by default.
+
+// HookWhen is when a hook is called, relative to the loop iteration
+type HookWhen int32
+
+const (
+	// HookStart is at the start of the iteration
+	HookStart HookWhen = iota
+
+	// HookEnd is at the end of the iteration, before the Log
+	HookEnd
+
+	// HookLogged is at the end of the iteration, after the Log
+	HookLogged
+)
+
+// Hook is a callback registered with AddHook
+type Hook struct {
+
+	// name of the hook, for selecting with Config.Run.Hooks
+	Name string
+
+	// mode of the loop, or etime.AllModes for all
+	Mode etime.Modes
+
+	// time scale of the loop: Trial, Epoch, or Run
+	Time etime.Times
+
+	// when the hook is called within the loop iteration
+	When HookWhen
+
+	// the callback
+	Func func(ss *Sim)
+}
+
+// Hooks are the loop hooks registered with AddHook, in order
+var Hooks []*Hook
+
+// LogsHooks are the logs hooks registered with AddLogsHook, in order
+var LogsHooks []*Hook
+
+// AddHook registers a callback to be called at given point in the loops
+// of given mode and time scale -- call in an init() function
+func AddHook(name string, mode etime.Modes, time etime.Times, when HookWhen, fun func(ss *Sim)) {
+	Hooks = append(Hooks, &Hook{Name: name, Mode: mode, Time: time, When: when, Func: fun})
+}
+
+// AddLogsHook registers a callback to be called when configuring the logs,
+// before the log tables are created, to add log items -- call in an
+// init() function
+func AddLogsHook(name string, fun func(ss *Sim)) {
+	LogsHooks = append(LogsHooks, &Hook{Name: name, Func: fun})
+}
+
+// HookOn returns true if the hook of given name is selected by
+// Config.Run.Hooks
+func (ss *Sim) HookOn(name string) bool {
+	if len(ss.Config.Run.Hooks) == 0 {
+		return true
+	}
+	for _, nm := range ss.Config.Run.Hooks {
+		if nm == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigHooks adds the registered loop hooks to the loops -- called at
+// the end of ConfigLoops, after the Log functions are added
+func (ss *Sim) ConfigHooks(man *looper.Manager) {
+	for _, hk := range Hooks {
+		if !ss.HookOn(hk.Name) {
+			continue
+		}
+		hook := hk // for closure
+		fun := func() { hook.Func(ss) }
+		nm := "Hook:" + hook.Name
+		n := 0
+		for mode, stack := range man.Stacks {
+			if hook.Mode != etime.AllModes && hook.Mode != mode {
+				continue
+			}
+			lp, ok := stack.Loops[hook.Time]
+			if !ok {
+				continue
+			}
+			switch hook.When {
+			case HookStart:
+				lp.OnStart.Add(nm, fun)
+			case HookEnd:
+				if err := lp.OnEnd.InsertBefore("Log", nm, fun); err != nil {
+					lp.OnEnd.Add(nm, fun)
+				}
+			case HookLogged:
+				lp.OnEnd.Add(nm, fun)
+			}
+			n++
+		}
+		if n == 0 {
+			mpi.Printf("Hook %s: no %s %s loop\n", hook.Name, hook.Mode, hook.Time)
+		}
+	}
+}
+
+// ConfigLogsHooks calls the registered logs hooks -- called in ConfigLogs
+// before the log tables are created
+func (ss *Sim) ConfigLogsHooks() {
+	for _, hk := range LogsHooks {
+		if ss.HookOn(hk.Name) {
+			hk.Func(ss)
+		}
+	}
+}
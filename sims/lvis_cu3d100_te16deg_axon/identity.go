@@ -0,0 +1,240 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/emergent/prjn"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etensor"
+)
+
+// identity.go has the identity output head, for studies of identity vs.
+// category representations in TEO / TE: the IdOutput layer, which
+// receives from TE alongside the Output layer, is trained on the identity
+// of the specific object (exemplar) in each image, as a localist pattern
+// with one unit per training object, in addition to the category on the
+// Output layer.  The object is parsed from the image file name per
+// Config.Env.Id.Regexp (airplane_001 for airplane_001_00005.png).
+//
+// To measure the generalization of identity across transforms, every
+// GenEvery-th image (view) of each object is held out from the identity
+// training (the IdOutput layer has no target for it, while the category
+// is trained as usual), and the identity error on these images is logged
+// as IdGenErr, separately from the IdErr on the trained images.  As the
+// training trials present each image with new random transforms, IdErr
+// also reflects generalization across the 2D transforms, while IdGenErr
+// adds new 3D views.  The test images are novel objects, so the identity
+// stats are NaN in testing.  See Config.Env.Id.
+
+// Identity has the state of the identity output head
+type Identity struct {
+
+	// compiled Config.Env.Id.Regexp
+	Re *regexp.Regexp
+
+	// object names, in sorted order = unit index in the IdOutput layer
+	Objs []string
+
+	// index of each object in Objs, by name
+	ObjMap map[string]int
+
+	// images held out from the identity training, by image name
+	Gen map[string]bool
+
+	// target pattern for the IdOutput layer
+	Targ etensor.Float32
+}
+
+// Obj returns the object name for given image name, or "" if the
+// image name does not match the Regexp
+func (id *Identity) Obj(img string) string {
+	m := id.Re.FindStringSubmatch(filepath.Base(img))
+	if len(m) < 2 {
+		return ""
+	}
+	return m[1]
+}
+
+// Shape returns the 2D shape of the IdOutput layer, close to square
+func (id *Identity) Shape() (y, x int) {
+	n := len(id.Objs)
+	y = int(math.Ceil(math.Sqrt(float64(n))))
+	x = (n + y - 1) / y
+	return
+}
+
+// ConfigIdentity sets the objects from the training images, and the
+// images held out from the identity training -- called at the end of
+// ConfigEnv.  Requires the Images env.
+func (ss *Sim) ConfigIdentity() {
+	ic := &ss.Config.Env.Id
+	if !ic.On {
+		return
+	}
+	trn := ss.ImagesEnv(etime.Train)
+	if trn == nil {
+		mpi.Println("Id: the identity output head requires the Images env")
+		ic.On = false
+		return
+	}
+	id := &ss.Identity
+	re, err := regexp.Compile(ic.Regexp)
+	if err != nil {
+		mpi.Printf("Id.Regexp: %v -- identity output head is off\n", err)
+		ic.On = false
+		return
+	}
+	id.Re = re
+	imgs := make(map[string][]string)
+	for _, img := range trn.Images.FlatTrain {
+		if obj := id.Obj(img); obj != "" {
+			imgs[obj] = append(imgs[obj], img)
+		}
+	}
+	if len(imgs) == 0 {
+		mpi.Printf("Id: no training image names match Regexp: %s -- identity output head is off\n", ic.Regexp)
+		ic.On = false
+		return
+	}
+	id.Objs = make([]string, 0, len(imgs))
+	for obj := range imgs {
+		id.Objs = append(id.Objs, obj)
+	}
+	sort.Strings(id.Objs)
+	id.ObjMap = make(map[string]int, len(id.Objs))
+	id.Gen = make(map[string]bool)
+	for oi, obj := range id.Objs {
+		id.ObjMap[obj] = oi
+		if ic.GenEvery <= 1 {
+			continue
+		}
+		ol := imgs[obj]
+		sort.Strings(ol)
+		for i := ic.GenEvery - 1; i < len(ol); i += ic.GenEvery {
+			id.Gen[ol[i]] = true
+		}
+	}
+	y, x := id.Shape()
+	id.Targ.SetShape([]int{y, x}, nil, nil)
+	mpi.Printf("Id: %d objects in %d training images, %d held out for IdGenErr\n", len(id.Objs), len(trn.Images.FlatTrain), len(id.Gen))
+}
+
+// ConfigIdentityNet adds the IdOutput layer, with one unit per object,
+// bidirectionally connected to given TE layer, placed to the right of
+// given Output layer -- called in ConfigNet prior to Build
+func (ss *Sim) ConfigIdentityNet(net *axon.Network, te, out *axon.Layer) {
+	if !ss.Config.Env.Id.On {
+		return
+	}
+	y, x := ss.Identity.Shape()
+	idl := net.AddLayer2D("IdOutput", y, x, axon.TargetLayer)
+	teid, idte := net.BidirConnectLayers(te, idl, prjn.NewFull())
+	teid.SetClass("ToOut ToIdOutput")
+	idte.SetClass("FmOut FmIdOutput")
+	idl.PlaceRightOf(out, 2)
+}
+
+// IdentityRecord records the object of the current image in the env for
+// given data index, in the TrlObjIdx stat (-1 if not a training object),
+// and whether it is held out from the identity training, in TrlIdGen.
+// Called in ApplyInputs.
+func (ss *Sim) IdentityRecord(di int, ev *ImagesEnv) {
+	if !ss.Config.Env.Id.On {
+		return
+	}
+	id := &ss.Identity
+	oi, ok := id.ObjMap[id.Obj(ev.CurImg)]
+	if !ok {
+		oi = -1
+	}
+	gen := 0
+	if id.Gen[ev.CurImg] {
+		gen = 1
+	}
+	ss.Stats.SetIntDi("TrlObjIdx", di, oi)
+	ss.Stats.SetIntDi("TrlIdGen", di, gen)
+}
+
+// ApplyIdentity applies the IdOutput target for given data index, for
+// training objects not held out -- called in ApplyInputs after the
+// other layers
+func (ss *Sim) ApplyIdentity(di uint32) {
+	if !ss.Config.Env.Id.On {
+		return
+	}
+	oi := ss.Stats.IntDi("TrlObjIdx", int(di))
+	if oi < 0 || ss.Stats.IntDi("TrlIdGen", int(di)) == 1 {
+		return
+	}
+	id := &ss.Identity
+	for i := range id.Targ.Values {
+		id.Targ.Values[i] = 0
+	}
+	id.Targ.Values[oi] = 1
+	ss.Net.AxonLayerByName("IdOutput").ApplyExt(&ss.Context, di, &id.Targ)
+}
+
+// IdentityTrialStats sets the IdResp, IdErr and IdGenErr stats for given
+// data index: the response is the object of the IdOutput unit with the
+// highest ActM, and the error is 1 if it is not the current object.  The
+// error goes to IdGenErr for held-out images and to IdErr otherwise, and
+// both are NaN for objects not in training.  Called at the end of TrialStats.
+func (ss *Sim) IdentityTrialStats(di int) {
+	if !ss.Config.Env.Id.On {
+		return
+	}
+	id := &ss.Identity
+	ss.Stats.SetFloat("IdErr", math.NaN())
+	ss.Stats.SetFloat("IdGenErr", math.NaN())
+	var vals []float32
+	ss.Net.AxonLayerByName("IdOutput").UnitVals(&vals, "ActM", di)
+	resp := -1
+	mx := float32(0)
+	for i, v := range vals[:len(id.Objs)] {
+		if v > mx {
+			mx = v
+			resp = i
+		}
+	}
+	if resp >= 0 {
+		ss.Stats.SetString("IdResp", id.Objs[resp])
+	} else {
+		ss.Stats.SetString("IdResp", "none")
+	}
+	oi := ss.Stats.IntDi("TrlObjIdx", di)
+	if oi < 0 {
+		return
+	}
+	err := 1.0
+	if resp == oi {
+		err = 0
+	}
+	if ss.Stats.IntDi("TrlIdGen", di) == 1 {
+		ss.Stats.SetFloat("IdGenErr", err)
+	} else {
+		ss.Stats.SetFloat("IdErr", err)
+	}
+}
+
+// ConfigIdentityLogs adds the identity stats to the logs: the object
+// response per trial, and the IdErr and IdGenErr aggregated over trials
+func (ss *Sim) ConfigIdentityLogs() {
+	if !ss.Config.Env.Id.On {
+		return
+	}
+	ss.Stats.SetFloat("IdErr", 0)
+	ss.Stats.SetFloat("IdGenErr", 0)
+	ss.Stats.SetString("IdResp", "")
+	ss.Logs.AddStatStringItem(etime.AllModes, etime.Trial, "IdResp")
+	ss.Logs.AddStatAggItem("IdErr", etime.Run, etime.Epoch, etime.Trial)
+	ss.Logs.AddStatAggItem("IdGenErr", etime.Run, etime.Epoch, etime.Trial)
+}
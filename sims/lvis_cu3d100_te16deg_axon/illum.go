@@ -0,0 +1,154 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// illum.go has the illumination transforms of the images, for measuring
+// and training color constancy, through the color DoG pathway: a color
+// temperature shift t scales the red channel by 1+t and the blue channel
+// by 1-t (t > 0 is warmer, t < 0 cooler), and a global gain scales all
+// channels, applied after the spatial transforms and clipped to the valid
+// range.  For training augmentation, random shifts up to
+// Config.Env.Illum.TempMax and gains in the GainMin..GainMax range are
+// drawn for each training image.  The illumination sweep tests the full
+// test set at each of the SweepTemps x SweepGains, saving a table of the
+// test error vs. illumination shift (illum log).
+
+// IllumStats are the test epoch log stats recorded at each illumination
+// of the sweep
+var IllumStats = []string{"PctErr", "PctErr2", "DecErr"}
+
+// RandIllum generates the random illumination of the current image:
+// the IllumTemp shift, plus a random shift up to IllumTempMax, and a
+// gain in the IllumGain range -- no random numbers are drawn if these
+// are not ranges, so the other transforms are not affected
+func (ev *ImagesEnv) RandIllum() {
+	ev.CurTemp = ev.IllumTemp
+	if ev.IllumTempMax > 0 {
+		ev.CurTemp += (ev.Rand.Float32(-1)*2 - 1) * ev.IllumTempMax
+	}
+	ev.CurGain = ev.IllumGain.Min
+	if ev.IllumGain.Range() > 0 {
+		ev.CurGain += ev.IllumGain.Range() * ev.Rand.Float32(-1)
+	}
+}
+
+// IllumImage applies the current illumination to the image
+func (ev *ImagesEnv) IllumImage() {
+	if ev.CurTemp == 0 && ev.CurGain == 1 {
+		return
+	}
+	img, ok := ev.Image.(*image.RGBA)
+	if !ok {
+		img = image.NewRGBA(ev.Image.Bounds())
+		draw.Draw(img, img.Bounds(), ev.Image, ev.Image.Bounds().Min, draw.Src)
+	}
+	gains := [3]float32{ev.CurGain * (1 + ev.CurTemp), ev.CurGain, ev.CurGain * (1 - ev.CurTemp)}
+	for i := 0; i < len(img.Pix); i += 4 {
+		for c, g := range gains {
+			v := float32(img.Pix[i+c]) * g
+			if v > 255 {
+				v = 255
+			} else if v < 0 {
+				v = 0
+			}
+			img.Pix[i+c] = uint8(v + 0.5)
+		}
+	}
+	ev.Image = img
+}
+
+// IllumSweep tests the full test set at each of the
+// Config.Env.Illum.SweepTemps x SweepGains illuminations, and returns
+// the table of Temp, Gain, and the IllumStats of the test epoch log
+func (ss *Sim) IllumSweep() (*etable.Table, error) {
+	ev := ss.ImagesEnv(etime.Test)
+	if ev == nil {
+		return nil, fmt.Errorf("IllumSweep: requires the Images env")
+	}
+	ic := &ss.Config.Env.Illum
+	temps, gains := ic.SweepTemps, ic.SweepGains
+	if len(temps) == 0 {
+		temps = []float32{0}
+	}
+	if len(gains) == 0 {
+		gains = []float32{1}
+	}
+	sch := etable.Schema{
+		{"Temp", etensor.FLOAT64, nil, nil},
+		{"Gain", etensor.FLOAT64, nil, nil},
+	}
+	for _, st := range IllumStats {
+		sch = append(sch, etable.Column{Name: st, Type: etensor.FLOAT64})
+	}
+	dt := &etable.Table{}
+	dt.SetFromSchema(sch, 0)
+	temp, tempMax, gain := ev.IllumTemp, ev.IllumTempMax, ev.IllumGain
+	ev.IllumTempMax = 0
+	for _, t := range temps {
+		for _, g := range gains {
+			ev.IllumTemp = t
+			ev.IllumGain.Set(g, g)
+			ss.TestAll()
+			et := ss.Logs.Table(etime.Test, etime.Epoch)
+			if et.Rows == 0 {
+				continue
+			}
+			row := dt.Rows
+			dt.SetNumRows(row + 1)
+			dt.SetCellFloat("Temp", row, float64(t))
+			dt.SetCellFloat("Gain", row, float64(g))
+			for _, st := range IllumStats {
+				dt.SetCellFloat(st, row, et.CellFloat(st, et.Rows-1))
+			}
+			mpi.Printf("IllumSweep: Temp: %g  Gain: %g  PctErr: %g\n", t, g, dt.CellFloat("PctErr", row))
+			if ss.GUI.StopNow {
+				break
+			}
+		}
+	}
+	ev.IllumTemp, ev.IllumTempMax, ev.IllumGain = temp, tempMax, gain
+	return dt, nil
+}
+
+// RunIllumSweep runs the IllumSweep, and saves the table as an
+// illum log file (in nogui mode, on rank 0)
+func (ss *Sim) RunIllumSweep() error {
+	dt, err := ss.IllumSweep()
+	if err != nil {
+		return err
+	}
+	ss.Logs.MiscTables["Illum"] = dt
+	if ss.Config.GUI || ss.MPIRank() != 0 {
+		return nil
+	}
+	fnm := elog.LogFileName("illum", ss.Net.Name(), ss.Stats.String("RunName"))
+	if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	mpi.Printf("Saved illumination sweep to: %s\n", fnm)
+	return nil
+}
+
+// RunIllumSweepGUI runs the IllumSweep, has stop running = false at end -- for gui
+func (ss *Sim) RunIllumSweepGUI() {
+	ss.GUI.StopNow = false
+	if err := ss.RunIllumSweep(); err != nil {
+		mpi.Println(err)
+	}
+	ss.GUI.Stopped()
+}
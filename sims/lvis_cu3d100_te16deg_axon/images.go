@@ -5,32 +5,39 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"math"
 	"math/rand"
 	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/goki/ki/dirs"
+	"github.com/goki/ki/toml"
 )
 
 // Images implements management of lists of image files,
 // with category names or organized in directories by category.
 type Images struct {
-	Path        string         `desc:"path to image files -- this should point to a directory that has files or subdirectories that then have image files in them"`
-	Exts        []string       `desc:"extensions of image files to find (lowercase)"`
-	CatSep      string         `desc:"separator in file name for category label -- if empty then must have subdirs"`
-	SplitByItm  bool           `desc:"split by item -- each file name has an item label after CatSep"`
-	NTestPerCat int            `desc:"number of testing images per category -- if SplitByItem images are split by item id"`
-	Cats        []string       `desc:"list of image categories"`
-	CatMap      map[string]int `desc:"map of categories to indexes in Cats list"`
-	ImagesAll   [][]string     `desc:"full list of images, organized by category (directory) and then filename"`
-	ImagesTrain [][]string     `desc:"list of training images, organized by category (directory) and then filename"`
-	ImagesTest  [][]string     `desc:"list of testing images, organized by category (directory) and then filename"`
-	FlatAll     []string       `desc:"flat list of all images, as cat/filename.ext -- Flats() makes from above"`
-	FlatTrain   []string       `desc:"flat list of all training images, as cat/filename.ext -- Flats() makes from above"`
-	FlatTest    []string       `desc:"flat list of all testing images, as cat/filename.ext -- Flats() makes from above"`
+	Path        string            `desc:"path to image files -- this should point to a directory that has files or subdirectories that then have image files in them"`
+	Exts        []string          `desc:"extensions of image files to find (lowercase)"`
+	CatSep      string            `desc:"separator in file name for category label -- if empty then must have subdirs"`
+	SplitByItm  bool              `desc:"split by item -- each file name has an item label after CatSep"`
+	NTestPerCat int               `desc:"number of testing images per category -- if SplitByItem images are split by item id"`
+	SplitSeed   int64             `desc:"random seed for the train / test split -- combined with a hash of each category name so the split for a given category does not depend on what other categories are present"`
+	CatRename   map[string]string `desc:"map of category renames, from the name in the image file names to the name used in the model -- categories with the same new name are merged, e.g., for synonyms and case inconsistencies -- only for category names in file names (CatSep)"`
+	Cats        []string          `desc:"list of image categories"`
+	CatMap      map[string]int    `desc:"map of categories to indexes in Cats list"`
+	ImagesAll   [][]string        `desc:"full list of images, organized by category (directory) and then filename"`
+	ImagesTrain [][]string        `desc:"list of training images, organized by category (directory) and then filename"`
+	ImagesTest  [][]string        `desc:"list of testing images, organized by category (directory) and then filename"`
+	FlatAll     []string          `desc:"flat list of all images, as cat/filename.ext -- Flats() makes from above"`
+	FlatTrain   []string          `desc:"flat list of all training images, as cat/filename.ext -- Flats() makes from above"`
+	FlatTest    []string          `desc:"flat list of all testing images, as cat/filename.ext -- Flats() makes from above"`
 }
 
 // SetPath sets path, with given extensions, and separator
@@ -70,7 +77,7 @@ func (im *Images) OpenDirs() error {
 		}
 		im.ImagesAll[ci] = fls
 	}
-	im.MakeCatMap()
+	im.SortCats()
 	im.Split()
 	return nil
 }
@@ -83,7 +90,14 @@ func (im *Images) MakeCatMap() {
 	}
 }
 
+// Cat returns the category of given image file name, renamed per CatRename
 func (im *Images) Cat(f string) string {
+	return im.RenameCat(im.RawCat(f))
+}
+
+// RawCat returns the category of given image file name as it appears
+// in the name, without CatRename
+func (im *Images) RawCat(f string) string {
 	if im.CatSep == "" {
 		dir, _ := filepath.Split(f)
 		return dir
@@ -113,7 +127,7 @@ func (im *Images) OpenNames() error {
 	curcat := ""
 	si := 0
 	for ni, nm := range fls {
-		cat := im.Cat(nm)
+		cat := im.RawCat(nm)
 		if cat != curcat {
 			if curcat != "" {
 				im.Cats = append(im.Cats, curcat)
@@ -125,11 +139,148 @@ func (im *Images) OpenNames() error {
 	}
 	im.Cats = append(im.Cats, curcat)
 	im.ImagesAll = append(im.ImagesAll, fls[si:len(fls)])
-	im.MakeCatMap()
+	im.RenameCats()
+	im.SortCats()
 	im.Split()
 	return nil
 }
 
+// RenameCat returns the new name for given category per CatRename,
+// or the category itself if it is not renamed
+func (im *Images) RenameCat(cat string) string {
+	if nc, ok := im.CatRename[cat]; ok {
+		return nc
+	}
+	return cat
+}
+
+// RenameCats applies CatRename to the current list of categories, merging
+// the image lists of categories with the same new name, in the order of
+// the first one.  Called in OpenNames, and after loading a saved split,
+// which may have been saved with different renames.  Each image goes to
+// the renamed category in its file name, so this is idempotent, including
+// for chained renames (e.g., a = b, b = c) applied to a saved split that
+// was already renamed.  Returns the number of categories removed by
+// merging.  Only for category names in file names, as the file names in
+// category directories do not have the directory name.
+func (im *Images) RenameCats() int {
+	if len(im.CatRename) == 0 {
+		return 0
+	}
+	if im.CatSep == "" {
+		log.Println("Images.RenameCats: CatRename is only supported for category names in file names, with CatSep")
+		return 0
+	}
+	var cats []string
+	cmap := make(map[string]int)
+	catIdx := func(nc string) int {
+		ni, has := cmap[nc]
+		if !has {
+			ni = len(cats)
+			cmap[nc] = ni
+			cats = append(cats, nc)
+		}
+		return ni
+	}
+	lists := [][][]string{im.ImagesAll, im.ImagesTrain, im.ImagesTest}
+	for ci, oc := range im.Cats {
+		nfl := 0
+		for _, ls := range lists {
+			if ci >= len(ls) {
+				continue
+			}
+			for _, f := range ls[ci] {
+				catIdx(im.Cat(f))
+				nfl++
+			}
+		}
+		if nfl == 0 {
+			catIdx(oc)
+		}
+	}
+	merge := func(lists [][]string) [][]string {
+		if lists == nil {
+			return nil
+		}
+		nl := make([][]string, len(cats))
+		for _, fls := range lists {
+			for _, f := range fls {
+				ni := cmap[im.Cat(f)]
+				nl[ni] = append(nl[ni], f)
+			}
+		}
+		return nl
+	}
+	im.ImagesAll = merge(im.ImagesAll)
+	im.ImagesTrain = merge(im.ImagesTrain)
+	im.ImagesTest = merge(im.ImagesTest)
+	nmerge := len(im.Cats) - len(cats)
+	im.Cats = cats
+	im.MakeCatMap()
+	return nmerge
+}
+
+// OpenCatRename opens a TOML file with the map of category renames,
+// with one old = new name entry per line, for Images.CatRename
+func OpenCatRename(filename string) (map[string]string, error) {
+	rn := make(map[string]string)
+	if err := toml.Open(&rn, filename); err != nil {
+		return nil, fmt.Errorf("OpenCatRename: %s: %w", filename, err)
+	}
+	return rn, nil
+}
+
+// SplitVersion is the version of the train / test split algorithm,
+// recorded in the SplitInfo file -- increment whenever Split logic changes
+// in a way that would produce a different split from the same seed.
+const SplitVersion = 1
+
+// SplitInfo records how a train / test split was generated, so that
+// a saved split can be verified against the current image list.
+type SplitInfo struct {
+	Version     int    `desc:"SplitVersion used to generate the split"`
+	Seed        int64  `desc:"SplitSeed used to generate the split"`
+	NTestPerCat int    `desc:"number of testing images per category"`
+	SplitByItm  bool   `desc:"split by item"`
+	NCats       int    `desc:"number of categories"`
+	NImages     int    `desc:"total number of images, train + test"`
+	Hash        string `desc:"hash of the full sorted list of images"`
+}
+
+// SplitInfo returns the SplitInfo for the current split
+func (im *Images) SplitInfo() *SplitInfo {
+	return &SplitInfo{Version: SplitVersion, Seed: im.SplitSeed, NTestPerCat: im.NTestPerCat, SplitByItm: im.SplitByItm, NCats: len(im.Cats), NImages: len(im.FlatAll), Hash: ListHash(im.FlatAll)}
+}
+
+// ListHash returns a hex-encoded sha256 hash of given list of names,
+// which are sorted first so the hash does not depend on order.
+func ListHash(list []string) string {
+	sl := make([]string, len(list))
+	copy(sl, list)
+	sort.Strings(sl)
+	h := sha256.New()
+	for _, s := range sl {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SplitRand returns a new random number generator for splitting given category,
+// seeded from SplitSeed and a hash of the category name.
+func (im *Images) SplitRand(cat string) *rand.Rand {
+	return CatRand(im.SplitSeed, cat)
+}
+
+// CatRand returns a new random number generator for given category,
+// seeded from given seed and a hash of the category name, so the
+// sequence for a category does not depend on the other categories.
+func CatRand(seed int64, cat string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(cat))
+	return rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
+}
+
 // Split does the train / test split
 func (im *Images) Split() {
 	if im.SplitByItm {
@@ -157,7 +308,8 @@ func (im *Images) SplitItems() {
 			itms[i] = it
 			i++
 		}
-		pi := rand.Perm(nitm)
+		sort.Strings(itms) // map order is random
+		pi := im.SplitRand(im.Cats[ci]).Perm(nitm)
 		ntst := im.NTestPerCat
 		if ntst >= nitm {
 			ntst = nitm / 2
@@ -192,7 +344,7 @@ func (im *Images) SplitNoItems() {
 			ntst = nitm / 2
 		}
 		ntrn := nitm - ntst
-		slist := rand.Perm(nitm)
+		slist := im.SplitRand(im.Cats[ci]).Perm(nitm)
 		for i := 0; i < ntrn; i++ {
 			im.ImagesTrain[ci] = append(im.ImagesTrain[ci], fls[slist[i]])
 		}
@@ -203,6 +355,81 @@ func (im *Images) SplitNoItems() {
 	im.Flats()
 }
 
+// SubsampleTrain keeps only the given fraction of the training images in
+// each category, and at least minN if available, selected with CatRand
+// from given seed, so smaller fractions are subsets of larger ones.
+// If byItem, the fraction of the items (SplitByItm) is kept, with all of
+// their images.  The order of the images is unchanged.
+func (im *Images) SubsampleTrain(frac float32, minN int, seed int64, byItem bool) {
+	keepN := func(n int) int {
+		k := int(math.Round(float64(frac) * float64(n)))
+		if k < minN {
+			k = minN
+		}
+		if k < 1 {
+			k = 1
+		}
+		if k > n {
+			k = n
+		}
+		return k
+	}
+	for ci, fls := range im.ImagesTrain {
+		if len(fls) == 0 {
+			continue
+		}
+		rnd := CatRand(seed, im.Cats[ci])
+		keep := make(map[string]bool)
+		if byItem {
+			var itms []string
+			for _, f := range fls {
+				if itm := im.Item(f); !keep[itm] {
+					keep[itm] = true
+					itms = append(itms, itm)
+				}
+			}
+			sort.Strings(itms)
+			keep = make(map[string]bool)
+			for _, pi := range rnd.Perm(len(itms))[:keepN(len(itms))] {
+				keep[itms[pi]] = true
+			}
+		} else {
+			for _, pi := range rnd.Perm(len(fls))[:keepN(len(fls))] {
+				keep[fls[pi]] = true
+			}
+		}
+		var kept []string
+		for _, f := range fls {
+			if (byItem && keep[im.Item(f)]) || (!byItem && keep[f]) {
+				kept = append(kept, f)
+			}
+		}
+		im.ImagesTrain[ci] = kept
+	}
+	im.Flats()
+}
+
+// CheckFiles compares the current full list of images against given list
+// of files actually present (e.g., from a fresh OpenPath), returning an error
+// listing the number of missing and extra images if they differ.
+func (im *Images) CheckFiles(files []string) error {
+	have := make(map[string]bool, len(files))
+	for _, f := range files {
+		have[f] = true
+	}
+	nmiss := 0
+	for _, f := range im.FlatAll {
+		if !have[f] {
+			nmiss++
+		}
+		delete(have, f)
+	}
+	if nmiss == 0 && len(have) == 0 {
+		return nil
+	}
+	return fmt.Errorf("Images.CheckFiles: split list does not match images in: %s -- %d missing, %d extra", im.Path, nmiss, len(have))
+}
+
 // SelectCats filters the list of images to those within given list of categories.
 func (im *Images) SelectCats(cats []string) {
 	nc := len(im.Cats)
@@ -306,9 +533,15 @@ func (im *Images) Flats() {
 
 // FlatImpl generates flat lists from categorized lists, in form categ/fname.obj
 func (im *Images) FlatImpl(images [][]string) []string {
+	return im.FlatImplCats(images, im.Cats)
+}
+
+// FlatImplCats generates flat lists from categorized lists, in form categ/fname.obj,
+// using given list of categories
+func (im *Images) FlatImplCats(images [][]string, cats []string) []string {
 	var flat []string
 	for ci, fls := range images {
-		cat := im.Cats[ci]
+		cat := cats[ci]
 		for _, fn := range fls {
 			if im.CatSep == "" {
 				fn = cat + "/" + fn
@@ -7,24 +7,26 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"image"
+	"image/color"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/emer/emergent/env"
 	"github.com/emer/emergent/erand"
 	"github.com/emer/emergent/evec"
 	"github.com/emer/emergent/patgen"
-	"github.com/emer/empi/empi"
-	"github.com/emer/empi/mpi"
 	"github.com/emer/etable/etable"
 	"github.com/emer/etable/etensor"
 	"github.com/emer/etable/metric"
 	"github.com/emer/etable/minmax"
 	"github.com/goki/gi/gi"
+	"github.com/goki/gi/gist"
 	"github.com/goki/ki/ints"
 	"github.com/goki/mat32"
 	"golang.org/x/image/draw"
@@ -43,12 +45,18 @@ type ImagesEnv struct {
 	// image file name
 	ImageFile string `desc:"image file name"`
 
+	// if true, when a saved split is opened, check its image lists against the images actually present in Images.Path, which rescans the whole directory -- see VerifyConfig
+	CheckFiles bool `desc:"if true, when a saved split is opened, check its image lists against the images actually present in Images.Path, which rescans the whole directory -- see VerifyConfig"`
+
 	// present test items, else train
 	Test bool `desc:"present test items, else train"`
 
 	// present items in sequential order -- else shuffled
 	Sequential bool `desc:"present items in sequential order -- else shuffled"`
 
+	// present items grouped by category, in a shuffled order of categories, with the items within each category in sequential order -- with MPI, each proc presents a contiguous block of this list, so it sees a subset of the categories on each pass
+	SeqCat bool `desc:"present items grouped by category, in a shuffled order of categories, with the items within each category in sequential order -- with MPI, each proc presents a contiguous block of this list, so it sees a subset of the categories on each pass"`
+
 	// compute high-res full field filtering
 	High16 bool `desc:"compute high-res full field filtering"`
 
@@ -70,6 +78,36 @@ type ImagesEnv struct {
 	// [def: 8] def 8 maximum degrees of rotation in plane -- image is rotated plus or minus in this range
 	RotateMax float32 `def:"8" desc:"def 8 maximum degrees of rotation in plane -- image is rotated plus or minus in this range"`
 
+	// color temperature shift of the illumination: the red channel is scaled by 1+IllumTemp and the blue by 1-IllumTemp -- see illum.go
+	IllumTemp float32 `desc:"color temperature shift of the illumination: the red channel is scaled by 1+IllumTemp and the blue by 1-IllumTemp -- see illum.go"`
+
+	// maximum random color temperature shift of the illumination, added to IllumTemp plus or minus in this range, for augmentation
+	IllumTempMax float32 `desc:"maximum random color temperature shift of the illumination, added to IllumTemp plus or minus in this range, for augmentation"`
+
+	// range of the global gain of the illumination, drawn at random if a range, for augmentation
+	IllumGain minmax.F32 `desc:"range of the global gain of the illumination, drawn at random if a range, for augmentation"`
+
+	// [def: Corner] how to fill the background exposed by the image transforms: Corner = color of the upper left pixel (appropriate for rendered objects on a uniform background), Border = mean color of the border pixels (better for photos), Color = BgColor
+	BgFill string `def:"Corner" desc:"how to fill the background exposed by the image transforms: Corner = color of the upper left pixel (appropriate for rendered objects on a uniform background), Border = mean color of the border pixels (better for photos), Color = BgColor"`
+
+	// [def: gray] background fill color for BgFill = Color, as a color name or hex value
+	BgColor string `def:"gray" desc:"background fill color for BgFill = Color, as a color name or hex value"`
+
+	// [def: Stretch] how to fit non-square images to the square input, prior to the transforms: Stretch = resize, distorting the aspect ratio, Letterbox = pad the shorter side with the BgFill color so the whole image is visible, Crop = crop the longer side at the center so the image fills the input
+	Aspect string `def:"Stretch" desc:"how to fit non-square images to the square input, prior to the transforms: Stretch = resize, distorting the aspect ratio, Letterbox = pad the shorter side with the BgFill color so the whole image is visible, Crop = crop the longer side at the center so the image fills the input"`
+
+	// [def: None] normalization of each image prior to the filtering: None, ZScore = luminance to NormMean mean and NormSD standard deviation, Contrast = RMS contrast to NormContrast, HistEq = luminance histogram equalization -- see inputnorm.go
+	InputNorm string `def:"None" desc:"normalization of each image prior to the filtering: None, ZScore = luminance to NormMean mean and NormSD standard deviation, Contrast = RMS contrast to NormContrast, HistEq = luminance histogram equalization -- see inputnorm.go"`
+
+	// [def: 0.5] mean luminance for InputNorm = ZScore
+	NormMean float32 `def:"0.5" desc:"mean luminance for InputNorm = ZScore"`
+
+	// [def: 0.2] luminance standard deviation for InputNorm = ZScore
+	NormSD float32 `def:"0.2" desc:"luminance standard deviation for InputNorm = ZScore"`
+
+	// [def: 0.3] RMS contrast (luminance standard deviation / mean) for InputNorm = Contrast
+	NormContrast float32 `def:"0.3" desc:"RMS contrast (luminance standard deviation / mean) for InputNorm = Contrast"`
+
 	// image that we operate upon -- one image shared among all filters
 	Img V1Img `desc:"image that we operate upon -- one image shared among all filters"`
 
@@ -112,12 +150,27 @@ type ImagesEnv struct {
 	// proportion minimum difference for random patterns
 	RndMinDiff float32 `desc:"proportion minimum difference for random patterns"`
 
+	// how to handle a saved random pattern file that does not match the current settings: Regen = warn and regenerate, Error = exit with an error -- see rndpats.go
+	RndPatsCheck string `desc:"how to handle a saved random pattern file that does not match the current settings: Regen = warn and regenerate, Error = exit with an error -- see rndpats.go"`
+
+	// file name of precomputed text embeddings of the category labels, to use as distributed output patterns, instead of localist or random ones -- see embed.go
+	OutEmbed string `desc:"file name of precomputed text embeddings of the category labels, to use as distributed output patterns, instead of localist or random ones -- see embed.go"`
+
+	// [view: -] category label embeddings loaded from OutEmbed
+	Embeds map[string][]float32 `view:"-" desc:"category label embeddings loaded from OutEmbed"`
+
 	// the output tensor geometry -- must be >= number of cats
 	OutSize evec.Vec2i `desc:"the output tensor geometry -- must be >= number of cats"`
 
 	// number of output units per category -- spiking may benefit from replication -- is Y inner dim of output tensor
 	NOutPer int `desc:"number of output units per category -- spiking may benefit from replication -- is Y inner dim of output tensor"`
 
+	// spatial layout of the localist output patterns: Block = NOutPer contiguous units per category, Scatter = NOutPer units per category at random positions across the layer, Gaussian = graded Gaussian blob per category -- see outlayout.go
+	OutLayout string `desc:"spatial layout of the localist output patterns: Block = NOutPer contiguous units per category, Scatter = NOutPer units per category at random positions across the layer, Gaussian = graded Gaussian blob per category -- see outlayout.go"`
+
+	// width (sigma) of the Gaussian output patterns, in units
+	OutSigma float32 `desc:"width (sigma) of the Gaussian output patterns, in units"`
+
 	// [view: no-inline] output patterns: either localist or random
 	Pats etable.Table `view:"no-inline" desc:"output patterns: either localist or random"`
 
@@ -127,6 +180,15 @@ type ImagesEnv struct {
 	// random seed
 	RndSeed int64 `inactive:"+" desc:"random seed"`
 
+	// training run, for deriving the shuffle seeds -- set by the sim
+	SeedRun int `inactive:"+" desc:"training run, for deriving the shuffle seeds -- set by the sim"`
+
+	// MPI rank of this proc, for deriving the per-proc random transform seeds -- set by MPIAlloc
+	Rank int `inactive:"+" desc:"MPI rank of this proc, for deriving the per-proc random transform seeds -- set by MPIAlloc"`
+
+	// number of passes through the items since Init, each with a new shuffle
+	Pass int `inactive:"+" desc:"number of passes through the items since Init, each with a new shuffle"`
+
 	// output pattern for current item
 	Output etensor.Float32 `desc:"output pattern for current item"`
 
@@ -139,9 +201,27 @@ type ImagesEnv struct {
 	// suffled list of entire set of images -- re-shuffle every time through imgidxs
 	Shuffle []int `desc:"suffled list of entire set of images -- re-shuffle every time through imgidxs"`
 
+	// per-category weighting of trial frequency, indexed by category index -- images in categories with a larger weight are presented proportionally more often (sampled with replacement) -- nil = uniform shuffled presentation
+	CatFreq []float32 `desc:"per-category weighting of trial frequency, indexed by category index -- images in categories with a larger weight are presented proportionally more often (sampled with replacement) -- nil = uniform shuffled presentation"`
+
+	// per-image weighting of trial frequency, indexed by the image index in the ImageList, multiplying any CatFreq weight -- e.g., for sampling hard examples more often based on the Difficulty stats -- nil = not used
+	ImgFreq []float32 `desc:"per-image weighting of trial frequency, indexed by the image index in the ImageList, multiplying any CatFreq weight -- e.g., for sampling hard examples more often based on the Difficulty stats -- nil = not used"`
+
+	// per-category output target strength multiplier, indexed by category index -- values > 1 clamp the target more strongly -- nil = 1 for all
+	CatTarg []float32 `desc:"per-category output target strength multiplier, indexed by category index -- values > 1 clamp the target more strongly -- nil = 1 for all"`
+
 	// indexs of images to present -- from StRow to EdRow
 	ImgIdxs []int `desc:"indexs of images to present -- from StRow to EdRow"`
 
+	// if non-empty, indexes in the ImageList of the only images to present, in order, instead of the shuffled ImgIdxs -- used for the FastTest subset
+	Subset []int `desc:"if non-empty, indexes in the ImageList of the only images to present, in order, instead of the shuffled ImgIdxs -- used for the FastTest subset"`
+
+	// [view: -] if non-empty, probe presentations to show in order on each Step, instead of the images with random transforms -- used for the RF size analysis
+	Probes []ImgProbe `view:"-" desc:"if non-empty, probe presentations to show in order on each Step, instead of the images with random transforms -- used for the RF size analysis"`
+
+	// [view: -] index of the next of the Probes to show
+	ProbeIdx int `view:"-" desc:"index of the next of the Probes to show"`
+
 	// [view: inline] current run of model as provided during Init
 	Run env.Ctr `view:"inline" desc:"current run of model as provided during Init"`
 
@@ -154,6 +234,18 @@ type ImagesEnv struct {
 	// [view: inline] row of item list  -- this is actual counter driving everything
 	Row env.Ctr `view:"inline" desc:"row of item list  -- this is actual counter driving everything"`
 
+	// [def: 0.5] for the priming paradigm, probability that the prime image is related to the target, i.e., from the same category (but a different image if possible) -- otherwise it is from a different category
+	PrimeRelP float32 `def:"0.5" desc:"for the priming paradigm, probability that the prime image is related to the target, i.e., from the same category (but a different image if possible) -- otherwise it is from a different category"`
+
+	// current prime image, for the priming paradigm
+	PrimeImg string `desc:"current prime image, for the priming paradigm"`
+
+	// index of category of the current prime image
+	PrimeCatIdx int `desc:"index of category of the current prime image"`
+
+	// true if the current prime is related to the current target (same category)
+	PrimeRel bool `desc:"true if the current prime is related to the current target (same category)"`
+
 	// current category
 	CurCat string `desc:"current category"`
 
@@ -163,6 +255,9 @@ type ImagesEnv struct {
 	// current image
 	CurImg string `desc:"current image"`
 
+	// index of current image in the ImageList, or -1 if shown directly with ShowImage
+	CurImgIdx int `desc:"index of current image in the ImageList, or -1 if shown directly with ShowImage"`
+
 	// current translation
 	CurTrans mat32.Vec2 `desc:"current translation"`
 
@@ -172,6 +267,18 @@ type ImagesEnv struct {
 	// current rotation
 	CurRot float32 `desc:"current rotation"`
 
+	// current color temperature shift of the illumination
+	CurTemp float32 `desc:"current color temperature shift of the illumination"`
+
+	// current global gain of the illumination
+	CurGain float32 `desc:"current global gain of the illumination"`
+
+	// proportion of the area of the current image that is visible in the input field, per the Aspect policy -- less than 1 for Crop of non-square images
+	CurVisFrac float32 `desc:"proportion of the area of the current image that is visible in the input field, per the Aspect policy -- less than 1 for Crop of non-square images"`
+
+	// proportion of the input field that is filled by the current image, prior to the transforms, per the Aspect policy -- less than 1 for Letterbox of non-square images
+	CurFillFrac float32 `desc:"proportion of the input field that is filled by the current image, prior to the transforms, per the Aspect policy -- less than 1 for Letterbox of non-square images"`
+
 	// [view: -] rendered image as loaded
 	Image image.Image `view:"-" desc:"rendered image as loaded"`
 }
@@ -189,13 +296,25 @@ func (ev *ImagesEnv) Defaults() {
 	ev.TransMax.Set(0.3, 0.3)   // 0.2 easy, 0.3 hard
 	ev.ScaleRange.Set(0.7, 1.2) // 0.8, 1.1 easy, .7-1.2 hard
 	ev.RotateMax = 16           // 8 easy, 16 hard
+	ev.IllumGain.Set(1, 1)
 	// easy:
 	// ev.TransMax.Set(0.2, 0.2)
 	// ev.ScaleRange.Set(0.8, 1.1)
 	// ev.RotateMax = 8
+	ev.BgFill = "Corner"
+	ev.BgColor = "gray"
+	ev.Aspect = "Stretch"
+	ev.InputNorm = "None"
+	ev.NormMean = 0.5
+	ev.NormSD = 0.2
+	ev.NormContrast = 0.3
+	ev.PrimeRelP = 0.5
 	ev.RndPctOn = 0.2
 	ev.RndMinDiff = 0.5
+	ev.RndPatsCheck = "Regen"
 	ev.NOutPer = 5
+	ev.OutLayout = "Block"
+	ev.OutSigma = 1.5
 	ev.Img.Defaults()
 	ev.V1l16.Defaults(0, 24, 8, &ev.Img)
 	ev.V1m16.Defaults(0, 12, 4, &ev.Img)
@@ -217,13 +336,94 @@ func (ev *ImagesEnv) ImageList() []string {
 	return ev.Images.FlatTrain
 }
 
-// MPIAlloc allocate objects based on mpi processor number
-func (ev *ImagesEnv) MPIAlloc() {
-	ws := mpi.WorldSize()
-	nim := ws * (len(ev.ImageList()) / ws) // even multiple of size -- few at end are lost..
-	ev.StRow, ev.EdRow, _ = empi.AllocN(nim)
+// SetSubset sets the Subset of images to present, starting from the
+// first one on the next Step -- nil restores the full set of images
+func (ev *ImagesEnv) SetSubset(subset []int) {
+	ev.Subset = subset
+	ev.Row.Cur = -1
+	if len(subset) > 0 {
+		ev.Row.Max = len(subset)
+	} else {
+		ev.Row.Max = len(ev.ImgIdxs)
+	}
+}
+
+// ImgProbe is a presentation of an image at a given position and size,
+// with no rotation, for probing the receptive fields of units
+type ImgProbe struct {
+
+	// image, as in the ImageList
+	Img string
+
+	// translation of the image, as a proportion of the half-width
+	Trans mat32.Vec2
+
+	// scaling of the image
+	Scale float32
+
+	// index of the position in the grid of probe positions
+	PosIdx int
+
+	// index of the scale in the list of probe scales
+	ScaleIdx int
+}
+
+// SetProbes sets the Probes to show in order, starting from the first one
+// on the next Step -- nil restores the standard presentation of the images
+func (ev *ImagesEnv) SetProbes(probes []ImgProbe) {
+	ev.Probes = probes
+	ev.ProbeIdx = 0
+}
+
+// ShowProbe shows the next of the Probes, wrapping around at the end
+func (ev *ImagesEnv) ShowProbe() {
+	p := &ev.Probes[ev.ProbeIdx%len(ev.Probes)]
+	ev.ProbeIdx++
+	ev.CurTrans = p.Trans
+	ev.CurScale = p.Scale
+	ev.CurRot = 0
+	ev.CurTemp, ev.CurGain = 0, 1
+	ev.ShowImage(p.Img)
+}
+
+// SubsetNPerCat returns a random subset of nPerCat images from each
+// category, as sorted indexes in the ImageList, using given random seed
+// so that the subset is the same on every call and across MPI procs
+func (ev *ImagesEnv) SubsetNPerCat(nPerCat int, seed int64) []int {
+	il := ev.ImageList()
+	byCat := make(map[int][]int)
+	for i, img := range il {
+		ci := ev.Images.CatMap[ev.Images.Cat(img)]
+		byCat[ci] = append(byCat[ci], i)
+	}
+	cats := make([]int, 0, len(byCat))
+	for ci := range byCat {
+		cats = append(cats, ci)
+	}
+	sort.Ints(cats)
+	rnd := erand.NewSysRand(seed)
+	var subset []int
+	for _, ci := range cats {
+		idxs := byCat[ci]
+		n := ints.MinInt(nPerCat, len(idxs))
+		perm := rnd.Perm(len(idxs), -1)
+		for _, pi := range perm[:n] {
+			subset = append(subset, idxs[pi])
+		}
+	}
+	sort.Ints(subset)
+	return subset
+}
+
+// MPIAlloc allocate objects based on mpi processor rank and number of
+// procs in the communicator (the world, or a search color)
+func (ev *ImagesEnv) MPIAlloc(rank, nproc int) {
+	pt := len(ev.ImageList()) / nproc // even multiple of size -- few at end are lost..
+	ev.Rank = rank
+	ev.StRow = pt * rank
+	ev.EdRow = ev.StRow + pt
 	// mpi.PrintAllProcs = true
-	// mpi.Printf("allocated images: n: %d st: %d ed: %d\n", nim, ev.StRow, ev.EdRow)
+	// mpi.Printf("allocated images: n: %d st: %d ed: %d\n", pt, ev.StRow, ev.EdRow)
 	// mpi.PrintAllProcs = false
 }
 
@@ -252,7 +452,9 @@ func (ev *ImagesEnv) Init(run int) {
 	for i := range ev.ImgIdxs {
 		ev.ImgIdxs[i] = ev.StRow + i
 	}
-	ev.Shuffle = ev.Rand.Perm(nitm, -1)
+	ev.Shuffle = make([]int, nitm)
+	ev.Pass = 0
+	ev.ShuffleOrder()
 	ev.Row.Max = len(ev.ImgIdxs)
 	nc := len(ev.Images.Cats)
 	ev.MaxOut = ints.MaxInt(nc, ev.MaxOut)
@@ -307,18 +509,30 @@ func OpenList2JSON(list *[][]string, filename string) error {
 	return json.Unmarshal(b, list)
 }
 
+// ConfigFileNames returns the file names for the saved configuration
+// of current images: categories, test and train lists, and SplitInfo
+func (ev *ImagesEnv) ConfigFileNames() (cfnm, tsfnm, trfnm, sifnm string) {
+	cfnm = fmt.Sprintf("%s_cats.json", ev.ImageFile)
+	tsfnm = fmt.Sprintf("%s_ntest%d_tst.json", ev.ImageFile, ev.Images.NTestPerCat)
+	trfnm = fmt.Sprintf("%s_ntest%d_trn.json", ev.ImageFile, ev.Images.NTestPerCat)
+	sifnm = fmt.Sprintf("%s_ntest%d_split.json", ev.ImageFile, ev.Images.NTestPerCat)
+	return
+}
+
 // OpenConfig opens saved configuration for current images
 func (ev *ImagesEnv) OpenConfig() bool {
-	cfnm := fmt.Sprintf("%s_cats.json", ev.ImageFile)
-	tsfnm := fmt.Sprintf("%s_ntest%d_tst.json", ev.ImageFile, ev.Images.NTestPerCat)
-	trfnm := fmt.Sprintf("%s_ntest%d_trn.json", ev.ImageFile, ev.Images.NTestPerCat)
+	cfnm, tsfnm, trfnm, _ := ev.ConfigFileNames()
 	_, err := os.Stat(tsfnm)
 	if !os.IsNotExist(err) {
 		OpenListJSON(&ev.Images.Cats, cfnm)
 		OpenList2JSON(&ev.Images.ImagesTest, tsfnm)
 		OpenList2JSON(&ev.Images.ImagesTrain, trfnm)
+		ev.Images.RenameCats()
 		ev.Images.ToTrainAll()
 		ev.Images.Flats()
+		if err := ev.VerifyConfig(); err != nil {
+			log.Println(err)
+		}
 		return true
 	}
 	return false
@@ -326,20 +540,199 @@ func (ev *ImagesEnv) OpenConfig() bool {
 
 // SaveConfig saves configuration for current images
 func (ev *ImagesEnv) SaveConfig() {
-	cfnm := fmt.Sprintf("%s_cats.json", ev.ImageFile)
-	tsfnm := fmt.Sprintf("%s_ntest%d_tst.json", ev.ImageFile, ev.Images.NTestPerCat)
-	trfnm := fmt.Sprintf("%s_ntest%d_trn.json", ev.ImageFile, ev.Images.NTestPerCat)
+	cfnm, tsfnm, trfnm, sifnm := ev.ConfigFileNames()
 	SaveListJSON(ev.Images.Cats, cfnm)
 	SaveList2JSON(ev.Images.ImagesTest, tsfnm)
 	SaveList2JSON(ev.Images.ImagesTrain, trfnm)
+	SaveSplitInfoJSON(ev.Images.SplitInfo(), sifnm)
+}
+
+// VerifyConfig checks the currently loaded split against the SplitInfo
+// saved with it, and, if CheckFiles, against the images actually present
+// in Images.Path if that directory exists.  Returns an error describing
+// the first mismatch.  A split saved without SplitInfo (prior to
+// versioning, as for the bundled lists) is a legacy split, which is used
+// as is, with a note that it is not verified.
+func (ev *ImagesEnv) VerifyConfig() error {
+	_, _, _, sifnm := ev.ConfigFileNames()
+	si := &SplitInfo{}
+	if _, err := os.Stat(sifnm); os.IsNotExist(err) {
+		if !ev.Test { // same files for the test env
+			fmt.Printf("ImagesEnv.VerifyConfig: legacy split without split info file %s: not verified\n", sifnm)
+		}
+		return nil
+	}
+	if err := OpenSplitInfoJSON(si, sifnm); err != nil {
+		return err
+	}
+	im := &ev.Images
+	cur := im.SplitInfo()
+	switch {
+	case si.Version != SplitVersion:
+		return fmt.Errorf("ImagesEnv.VerifyConfig: %s: split version %d != current version %d", ev.Nm, si.Version, SplitVersion)
+	case si.Seed != im.SplitSeed:
+		return fmt.Errorf("ImagesEnv.VerifyConfig: %s: split seed %d != configured SplitSeed %d", ev.Nm, si.Seed, im.SplitSeed)
+	case si.SplitByItm != im.SplitByItm:
+		return fmt.Errorf("ImagesEnv.VerifyConfig: %s: split SplitByItm %v != configured %v", ev.Nm, si.SplitByItm, im.SplitByItm)
+	case si.Hash != cur.Hash:
+		return fmt.Errorf("ImagesEnv.VerifyConfig: %s: loaded image lists (n = %d) do not match split info hash (n = %d) -- files have been modified", ev.Nm, cur.NImages, si.NImages)
+	}
+	if !ev.CheckFiles {
+		return nil
+	}
+	if _, err := os.Stat(im.Path); err == nil {
+		all := &Images{}
+		if err := all.OpenPath(im.Path, im.Exts, im.CatSep); err != nil {
+			return err
+		}
+		return im.CheckFiles(all.FlatAll)
+	}
+	return nil
+}
+
+// SaveSplitInfoJSON saves SplitInfo to a JSON-formatted file.
+func SaveSplitInfoJSON(si *SplitInfo, filename string) error {
+	b, err := json.MarshalIndent(si, "", "  ")
+	if err != nil {
+		log.Println(err) // unlikely
+		return err
+	}
+	err = ioutil.WriteFile(string(filename), b, 0644)
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
+
+// OpenSplitInfoJSON opens SplitInfo from a JSON-formatted file.
+func OpenSplitInfoJSON(si *SplitInfo, filename string) error {
+	b, err := ioutil.ReadFile(string(filename))
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return json.Unmarshal(b, si)
+}
+
+// SplitManifest records the categories and train / test image lists
+// actually used by an env, saved alongside weights files so the split
+// used in training can be verified or restored when the weights are loaded.
+type SplitManifest struct {
+	Info  *SplitInfo `desc:"split info for the full split as generated"`
+	Cats  []string   `desc:"categories in use, in output pattern order"`
+	Train [][]string `desc:"training images, organized by category"`
+	Test  [][]string `desc:"testing images, organized by category"`
+}
+
+// SplitManifestFileName returns the file name for the SplitManifest
+// sidecar file for given weights file name.
+func SplitManifestFileName(wtsfnm string) string {
+	fnm := strings.TrimSuffix(wtsfnm, WtsZExt)
+	fnm = strings.TrimSuffix(fnm, ".gz")
+	fnm = strings.TrimSuffix(fnm, ".wts")
+	return fnm + "_split.json"
+}
+
+// SplitManifest returns the SplitManifest for the current images
+func (ev *ImagesEnv) SplitManifest() *SplitManifest {
+	im := &ev.Images
+	return &SplitManifest{Info: im.SplitInfo(), Cats: im.Cats, Train: im.ImagesTrain, Test: im.ImagesTest}
+}
+
+// SaveSplitManifest saves the SplitManifest for the current images
+// to a JSON-formatted file.
+func (ev *ImagesEnv) SaveSplitManifest(filename string) error {
+	b, err := json.Marshal(ev.SplitManifest())
+	if err != nil {
+		log.Println(err) // unlikely
+		return err
+	}
+	err = ioutil.WriteFile(filename, b, 0644)
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
+
+// OpenSplitManifest opens a SplitManifest from a JSON-formatted file.
+func OpenSplitManifest(filename string) (*SplitManifest, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	sm := &SplitManifest{}
+	return sm, json.Unmarshal(b, sm)
+}
+
+// CheckSplitManifest checks the current categories and test set against
+// the given SplitManifest, returning an error describing the differences,
+// e.g., the test set has changed since the weights were trained.
+func (ev *ImagesEnv) CheckSplitManifest(sm *SplitManifest) error {
+	im := &ev.Images
+	var errs []string
+	if ListHash(im.Cats) != ListHash(sm.Cats) {
+		errs = append(errs, fmt.Sprintf("categories differ: current n = %d, saved n = %d", len(im.Cats), len(sm.Cats)))
+	} else {
+		for ci, c := range im.Cats {
+			if sm.Cats[ci] != c {
+				errs = append(errs, fmt.Sprintf("category order differs at: %d: current: %s saved: %s", ci, c, sm.Cats[ci]))
+				break
+			}
+		}
+	}
+	smtest := im.FlatImplCats(sm.Test, sm.Cats)
+	if ListHash(im.FlatTest) != ListHash(smtest) {
+		errs = append(errs, fmt.Sprintf("test set differs: current n = %d, saved n = %d", len(im.FlatTest), len(smtest)))
+	}
+	smtrain := im.FlatImplCats(sm.Train, sm.Cats)
+	if ListHash(im.FlatTrain) != ListHash(smtrain) {
+		errs = append(errs, fmt.Sprintf("train set differs: current n = %d, saved n = %d", len(im.FlatTrain), len(smtrain)))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("ImagesEnv.CheckSplitManifest: %s: split differs from that saved with the weights: %s", ev.Nm, strings.Join(errs, "; "))
+}
+
+// CopyList2 returns a deep copy of given list of lists of image names
+func CopyList2(list [][]string) [][]string {
+	if list == nil {
+		return nil
+	}
+	cp := make([][]string, len(list))
+	for i, l := range list {
+		cp[i] = append([]string(nil), l...)
+	}
+	return cp
+}
+
+// RestoreSplitManifest sets the categories and train / test images
+// from copies of those in the given SplitManifest, so it can be restored
+// into multiple envs.  Init must be called after this.
+func (ev *ImagesEnv) RestoreSplitManifest(sm *SplitManifest) {
+	im := &ev.Images
+	im.Cats = append([]string(nil), sm.Cats...)
+	im.ImagesTrain = CopyList2(sm.Train)
+	im.ImagesTest = CopyList2(sm.Test)
+	im.ToTrainAll()
+	im.Flats()
 }
 
 // ConfigPats configures the output patterns
 func (ev *ImagesEnv) ConfigPats() {
-	if ev.OutRandom {
+	if ev.OutEmbed != "" {
+		ev.ConfigPatsEmbed()
+	} else if ev.OutRandom {
 		ev.ConfigPatsRandom()
 	} else {
-		ev.ConfigPatsLocalist2D()
+		switch ev.OutLayout {
+		case "Scatter":
+			ev.ConfigPatsScatter()
+		case "Gaussian":
+			ev.ConfigPatsGaussian()
+		default:
+			ev.ConfigPatsLocalist2D()
+		}
 	}
 }
 
@@ -378,14 +771,7 @@ func (ev *ImagesEnv) ConfigPatsLocalistPools() {
 // ConfigPatsLocalist2D configures the output patterns: localist case
 // as an overall 2D layer -- NOutPer goes along X axis to be contiguous
 func (ev *ImagesEnv) ConfigPatsLocalist2D() {
-	oshp := []int{ev.OutSize.Y, ev.OutSize.X * ev.NOutPer}
-	oshpnm := []string{"Y", "X"}
-	ev.Output.SetShape(oshp, nil, oshpnm)
-	sch := etable.Schema{
-		{"Name", etensor.STRING, nil, nil},
-		{"Output", etensor.FLOAT32, oshp, oshpnm},
-	}
-	ev.Pats.SetFromSchema(sch, ev.MaxOut)
+	ev.ConfigPatsSchema()
 	for pi := 0; pi < ev.MaxOut; pi++ {
 		out := ev.Pats.CellTensor("Output", pi)
 		si := ev.NOutPer * pi
@@ -412,24 +798,202 @@ func (ev *ImagesEnv) ConfigPatsRandom() {
 	fnm := fmt.Sprintf("rndpats_%dx%d_n%d_on%d_df%d.tsv", ev.OutSize.X, ev.OutSize.Y, ev.MaxOut, nOn, minDiff)
 	_, err := os.Stat(fnm)
 	if !os.IsNotExist(err) {
-		ev.Pats.OpenCSV(gi.FileName(fnm), etable.Tab)
-	} else {
-		out := ev.Pats.Col(1).(*etensor.Float32)
-		patgen.PermutedBinaryMinDiff(out, nOn, 1, 0, minDiff)
-		ev.ConfigPatsName()
-		ev.Pats.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
+		err = ev.OpenRndPats(fnm, nOn, minDiff)
+		if err == nil {
+			return
+		}
+		if ev.RndPatsCheck == "Error" {
+			log.Fatalln(err)
+		}
+		log.Printf("%s -- regenerating\n", err)
+		ev.Pats.SetFromSchema(sch, ev.MaxOut)
 	}
+	out := ev.Pats.Col(1).(*etensor.Float32)
+	patgen.PermutedBinaryMinDiff(out, nOn, 1, 0, minDiff)
+	ev.ConfigPatsName()
+	ev.Pats.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
 }
 
-// NewShuffle generates a new random order of items to present
+// NewShuffle generates a new random order of items to present,
+// for the next pass through the items
 func (ev *ImagesEnv) NewShuffle() {
-	erand.PermuteInts(ev.Shuffle, &ev.Rand)
+	ev.Pass++
+	ev.ShuffleOrder()
+}
+
+// ShuffleSeed returns the seed for the shuffled order of items on given
+// pass, derived from the RndSeed and SeedRun -- it is the same on all
+// MPI procs, so they present disjoint blocks of the same order
+func (ev *ImagesEnv) ShuffleSeed(pass int) int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "shuffle:%d:%d:%d", ev.RndSeed, ev.SeedRun, pass)
+	return int64(h.Sum64() >> 1) // non-negative
+}
+
+// TransSeed returns the seed for the random transforms on given pass,
+// derived from the RndSeed, SeedRun, and Rank -- it differs across MPI
+// procs, so each presents an independent, reproducible sequence
+func (ev *ImagesEnv) TransSeed(pass int) int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "trans:%d:%d:%d:%d", ev.RndSeed, ev.SeedRun, pass, ev.Rank)
+	return int64(h.Sum64() >> 1)
+}
+
+// ShuffleOrder fills the Shuffle list for the current Pass, seeded by
+// ShuffleSeed, and reseeds the Rand for the random transforms by TransSeed,
+// so the order and transforms on each pass are reproducible regardless of
+// the number of random draws on prior passes
+func (ev *ImagesEnv) ShuffleOrder() {
+	rnd := erand.NewSysRand(ev.ShuffleSeed(ev.Pass))
+	ev.Rand.Seed(ev.TransSeed(ev.Pass))
+	switch {
+	case ev.CatFreq != nil || ev.ImgFreq != nil:
+		ev.WeightedShuffle(rnd)
+	case ev.SeqCat:
+		ev.CatShuffle(rnd)
+	default:
+		for i := range ev.Shuffle {
+			ev.Shuffle[i] = i
+		}
+		erand.PermuteInts(ev.Shuffle, rnd)
+	}
+}
+
+// CatShuffle fills the Shuffle list with the items grouped by category,
+// in a random order of categories, with the items within each category in
+// their sequential order, for SeqCat
+func (ev *ImagesEnv) CatShuffle(rnd erand.Rand) {
+	il := ev.ImageList()
+	nc := len(ev.Images.Cats)
+	byCat := make([][]int, nc)
+	for i, img := range il {
+		ci := ev.Images.CatMap[ev.Images.Cat(img)]
+		byCat[ci] = append(byCat[ci], i)
+	}
+	ev.Shuffle = ev.Shuffle[:0]
+	for _, ci := range rnd.Perm(nc, -1) {
+		ev.Shuffle = append(ev.Shuffle, byCat[ci]...)
+	}
+}
+
+// WeightedShuffle fills the Shuffle list by sampling images with
+// replacement, with probability proportional to the CatFreq weight
+// of their category times their ImgFreq weight, using given rand.
+// All mpi procs share the same ShuffleSeed, so they generate the same list.
+func (ev *ImagesEnv) WeightedShuffle(rnd erand.Rand) {
+	il := ev.ImageList()
+	cum := make([]float32, len(il))
+	sum := float32(0)
+	for i, img := range il {
+		w := float32(1)
+		if ev.CatFreq != nil {
+			w = ev.CatFreq[ev.Images.CatMap[ev.Images.Cat(img)]]
+		}
+		if i < len(ev.ImgFreq) {
+			w *= ev.ImgFreq[i]
+		}
+		sum += w
+		cum[i] = sum
+	}
+	if sum <= 0 {
+		for i := range ev.Shuffle {
+			ev.Shuffle[i] = i
+		}
+		erand.PermuteInts(ev.Shuffle, rnd)
+		return
+	}
+	for i := range ev.Shuffle {
+		r := rnd.Float32(-1) * sum
+		ii := sort.Search(len(cum), func(j int) bool { return cum[j] > r })
+		ev.Shuffle[i] = ints.MinInt(ii, len(cum)-1)
+	}
+}
+
+// OpenCatWeights opens a table of per-category weights, in .tsv
+// (or .csv) format with a header row, with a Cat column naming the
+// category, and Freq and / or Targ columns, setting CatFreq and CatTarg.
+// Categories not listed in the table have a weight of 1.
+// For example, the cmp_cat table saved by CompareWts can be edited
+// to weight categories with higher error.
+func (ev *ImagesEnv) OpenCatWeights(fname string) error {
+	dt := &etable.Table{}
+	delim := etable.Tab
+	if filepath.Ext(fname) == ".csv" {
+		delim = etable.Comma
+	}
+	err := dt.OpenCSV(gi.FileName(fname), delim)
+	if err != nil {
+		return err
+	}
+	if dt.ColIdx("Cat") < 0 {
+		return fmt.Errorf("OpenCatWeights: %s: no Cat column", fname)
+	}
+	nc := len(ev.Images.Cats)
+	hasFreq := dt.ColIdx("Freq") >= 0
+	hasTarg := dt.ColIdx("Targ") >= 0
+	if hasFreq {
+		ev.CatFreq = ev.CatWeightsOnes()
+	}
+	if hasTarg {
+		ev.CatTarg = ev.CatWeightsOnes()
+	}
+	for ri := 0; ri < dt.Rows; ri++ {
+		cat := dt.CellString("Cat", ri)
+		ci, ok := ev.Images.CatMap[cat]
+		if !ok || ci >= nc {
+			log.Printf("OpenCatWeights: %s: category not found: %s\n", fname, cat)
+			continue
+		}
+		if hasFreq {
+			ev.CatFreq[ci] = float32(dt.CellFloat("Freq", ri))
+		}
+		if hasTarg {
+			ev.CatTarg[ci] = float32(dt.CellFloat("Targ", ri))
+		}
+	}
+	return nil
+}
+
+// CatWeightsOnes returns a per-category weight list initialized to 1.
+func (ev *ImagesEnv) CatWeightsOnes() []float32 {
+	wts := make([]float32, len(ev.Images.Cats))
+	for i := range wts {
+		wts[i] = 1
+	}
+	return wts
+}
+
+// CatFreqByN multiplies the CatFreq weights by the inverse of the
+// number of images in each category (normalized by the mean number),
+// so that all categories are presented equally often on average,
+// regardless of how many images they have.
+func (ev *ImagesEnv) CatFreqByN() {
+	nc := len(ev.Images.Cats)
+	if nc == 0 {
+		return
+	}
+	ns := make([]int, nc)
+	for _, img := range ev.ImageList() {
+		ns[ev.Images.CatMap[ev.Images.Cat(img)]]++
+	}
+	if ev.CatFreq == nil {
+		ev.CatFreq = ev.CatWeightsOnes()
+	}
+	mn := float32(len(ev.ImageList())) / float32(nc)
+	for ci, n := range ns {
+		if n > 0 {
+			ev.CatFreq[ci] *= mn / float32(n)
+		}
+	}
 }
 
 // CurImage returns current image based on row and
 func (ev *ImagesEnv) CurImage() string {
 	il := ev.ImageList()
 	sz := len(ev.ImgIdxs)
+	if len(ev.Subset) > 0 {
+		sz = len(ev.Subset)
+	}
 	if ev.Row.Cur >= sz {
 		ev.Row.Max = sz
 		ev.Row.Cur = 0
@@ -439,11 +1003,17 @@ func (ev *ImagesEnv) CurImage() string {
 	if r < 0 {
 		r = 0
 	}
-	i := ev.ImgIdxs[r]
-	if !ev.Sequential {
-		i = ev.Shuffle[i]
+	var i int
+	if len(ev.Subset) > 0 {
+		i = ev.Subset[r]
+	} else {
+		i = ev.ImgIdxs[r]
+		if !ev.Sequential {
+			i = ev.Shuffle[i]
+		}
 	}
 	ev.CurImg = il[i]
+	ev.CurImgIdx = i
 	ev.CurCat = ev.Images.Cat(ev.CurImg)
 	ev.CurCatIdx = ev.Images.CatMap[ev.CurCat]
 	return ev.CurImg
@@ -451,10 +1021,14 @@ func (ev *ImagesEnv) CurImage() string {
 
 // OpenImage opens current image
 func (ev *ImagesEnv) OpenImage() error {
-	img := ev.CurImage()
+	return ev.OpenImageName(ev.CurImage())
+}
+
+// OpenImageName opens given image, relative to Images.Path
+func (ev *ImagesEnv) OpenImageName(img string) error {
 	fnm := filepath.Join(ev.Images.Path, img)
 	var err error
-	ev.Image, err = gi.OpenImage(fnm)
+	ev.Image, err = OpenImageFile(fnm)
 	if err != nil {
 		log.Println(err)
 	}
@@ -474,6 +1048,36 @@ func (ev *ImagesEnv) RandTransforms() {
 	}
 	ev.CurScale = ev.ScaleRange.Min + ev.ScaleRange.Range()*ev.Rand.Float32(-1)
 	ev.CurRot = (ev.Rand.Float32(-1)*2 - 1) * ev.RotateMax
+	ev.RandIllum()
+}
+
+// AspectImage fits the image to a square per the Aspect policy, and sets
+// the CurVisFrac and CurFillFrac -- Stretch is done by the resize in
+// V1Img.SetImage
+func (ev *ImagesEnv) AspectImage() {
+	b := ev.Image.Bounds()
+	w, h := b.Dx(), b.Dy()
+	ev.CurVisFrac, ev.CurFillFrac = 1, 1
+	if w == h || w == 0 || h == 0 {
+		return
+	}
+	switch ev.Aspect {
+	case "Letterbox":
+		sz := ints.MaxInt(w, h)
+		dst := image.NewRGBA(image.Rect(0, 0, sz, sz))
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(ev.BgFillColor()), image.ZP, draw.Src)
+		off := image.Point{(sz - w) / 2, (sz - h) / 2}
+		draw.Draw(dst, image.Rectangle{off, off.Add(b.Size())}, ev.Image, b.Min, draw.Src)
+		ev.Image = dst
+		ev.CurFillFrac = float32(w*h) / float32(sz*sz)
+	case "Crop":
+		sz := ints.MinInt(w, h)
+		st := image.Point{b.Min.X + (w-sz)/2, b.Min.Y + (h-sz)/2}
+		dst := image.NewRGBA(image.Rect(0, 0, sz, sz))
+		draw.Draw(dst, dst.Bounds(), ev.Image, st, draw.Src)
+		ev.Image = dst
+		ev.CurVisFrac = float32(sz*sz) / float32(w*h)
+	}
 }
 
 // TransformImage transforms the image according to current translation and scaling
@@ -485,8 +1089,7 @@ func (ev *ImagesEnv) TransformImage() {
 	m := mat32.Translate2D(s.X*.5+tx, s.Y*.5+ty).Scale(ev.CurScale, ev.CurScale).Rotate(mat32.DegToRad(ev.CurRot)).Translate(-s.X*.5, -s.Y*.5)
 	s2d := f64.Aff3{float64(m.XX), float64(m.XY), float64(m.X0), float64(m.YX), float64(m.YY), float64(m.Y0)}
 
-	// use first color in upper left as fill color
-	clr := ev.Image.At(0, 0)
+	clr := ev.BgFillColor()
 	dst := image.NewRGBA(ev.Image.Bounds())
 	src := image.NewUniform(clr)
 	draw.Draw(dst, dst.Bounds(), src, image.ZP, draw.Src)
@@ -495,6 +1098,43 @@ func (ev *ImagesEnv) TransformImage() {
 	ev.Image = dst
 }
 
+// BgFillColor returns the color to fill the background exposed by
+// the image transforms, according to BgFill
+func (ev *ImagesEnv) BgFillColor() color.Color {
+	b := ev.Image.Bounds()
+	switch ev.BgFill {
+	case "Color":
+		clr, err := gist.ColorFromString(ev.BgColor, nil)
+		if err != nil {
+			log.Println(err)
+		}
+		return clr
+	case "Border":
+		var sum [3]uint64
+		n := uint64(0)
+		add := func(x, y int) {
+			r, g, b, _ := ev.Image.At(x, y).RGBA()
+			sum[0] += uint64(r)
+			sum[1] += uint64(g)
+			sum[2] += uint64(b)
+			n++
+		}
+		for x := b.Min.X; x < b.Max.X; x++ {
+			add(x, b.Min.Y)
+			add(x, b.Max.Y-1)
+		}
+		for y := b.Min.Y + 1; y < b.Max.Y-1; y++ {
+			add(b.Min.X, y)
+			add(b.Max.X-1, y)
+		}
+		if n == 0 {
+			return color.Black
+		}
+		return color.RGBA64{uint16(sum[0] / n), uint16(sum[1] / n), uint16(sum[2] / n), 0xFFFF}
+	}
+	return ev.Image.At(b.Min.X, b.Min.Y)
+}
+
 // FilterImage opens and filters current image
 func (ev *ImagesEnv) FilterImage() error {
 	err := ev.OpenImage()
@@ -502,7 +1142,16 @@ func (ev *ImagesEnv) FilterImage() error {
 		fmt.Println(err)
 		return err
 	}
+	ev.FilterOpenImage()
+	return nil
+}
+
+// FilterOpenImage transforms and filters the current open Image
+func (ev *ImagesEnv) FilterOpenImage() {
+	ev.NormImage()
+	ev.AspectImage()
 	ev.TransformImage()
+	ev.IllumImage()
 	ev.Img.SetImage(ev.Image, ev.V1l16.V1sGeom.FiltRt.X)
 	ev.V1l16.Filter()
 	ev.V1m16.Filter()
@@ -517,7 +1166,6 @@ func (ev *ImagesEnv) FilterImage() error {
 		ev.V1Cl8.Filter()
 		ev.V1Cm8.Filter()
 	}
-	return nil
 }
 
 // SetOutput sets output by category
@@ -525,6 +1173,12 @@ func (ev *ImagesEnv) SetOutput(out int) {
 	ev.Output.SetZeros()
 	ot := ev.Pats.CellTensor("Output", out)
 	ev.Output.CopyCellsFrom(ot, 0, 0, ev.Output.Len())
+	if ev.CatTarg != nil && out < len(ev.CatTarg) && ev.CatTarg[out] != 1 {
+		tg := ev.CatTarg[out]
+		for i, v := range ev.Output.Values {
+			ev.Output.Values[i] = v * tg
+		}
+	}
 }
 
 // FloatIdx32 contains a float32 value and its index
@@ -562,8 +1216,37 @@ func ClosestRows32(probe *etensor.Float32, col *etensor.Float32, mfun metric.Fun
 // item with closest fit to given pattern, and 1 if that is error, 0 if correct.
 // also returns a top-two error: if 2nd closest pattern was correct.
 func (ev *ImagesEnv) OutErr(tsr *etensor.Float32, curCatIdx int) (maxi int, err, err2 float64) {
+	maxi, err, err2, _ = ev.OutErrMargin(tsr, curCatIdx)
+	return
+}
+
+// OutErrMargin is OutErr also returning the output margin: the distance
+// (inverse correlation) of the output to the closest other category
+// pattern minus its distance to the correct one -- positive when
+// correct, and larger for more confident correct responses.
+func (ev *ImagesEnv) OutErrMargin(tsr *etensor.Float32, curCatIdx int) (maxi int, err, err2, margin float64) {
 	ocol := ev.Pats.ColByName("Output").(*etensor.Float32)
-	dsts := ClosestRows32(tsr, ocol, metric.InvCorrelation32)
+	mfun := metric.InvCorrelation32
+	if ev.OutEmbed != "" {
+		mfun = metric.InvCosine32
+	}
+	dsts := ClosestRows32(tsr, ocol, mfun)
+	cd, od := float32(-1), float32(-1)
+	for _, d := range dsts {
+		if d.Idx == curCatIdx {
+			if cd < 0 {
+				cd = d.Val
+			}
+		} else if od < 0 {
+			od = d.Val
+		}
+		if cd >= 0 && od >= 0 {
+			break
+		}
+	}
+	if cd >= 0 && od >= 0 {
+		margin = float64(od - cd)
+	}
 	maxi = dsts[0].Idx
 	err = 1.0
 	if maxi == curCatIdx {
@@ -588,12 +1271,71 @@ func (ev *ImagesEnv) Step() bool {
 	if ev.Trial.Incr() {
 		ev.Epoch.Incr()
 	}
+	if len(ev.Probes) > 0 {
+		ev.ShowProbe()
+		return true
+	}
 	ev.RandTransforms()
 	ev.FilterImage()
 	ev.SetOutput(ev.CurCatIdx)
 	return true
 }
 
+// ChoosePrime chooses a prime image for the current (target) image,
+// for the priming paradigm: related with probability PrimeRelP,
+// and otherwise from a random other category.  Call after Step.
+func (ev *ImagesEnv) ChoosePrime() {
+	ev.PrimeImg, ev.PrimeCatIdx, ev.PrimeRel = ev.ChooseRelated(ev.CurCatIdx, ev.CurImg, ev.PrimeRelP)
+}
+
+// ChooseRelated chooses an image that is related to given category index,
+// i.e., from the same category, with probability relP, and otherwise from
+// a random other category, avoiding given image if possible.  Returns the
+// image, its category index, and whether it is related.
+func (ev *ImagesEnv) ChooseRelated(catIdx int, excl string, relP float32) (string, int, bool) {
+	imgs := ev.Images.ImagesTrain
+	if ev.Test {
+		imgs = ev.Images.ImagesTest
+	}
+	nc := len(imgs)
+	rel := nc < 2 || ev.Rand.Float32(-1) < relP
+	ci := catIdx
+	if !rel {
+		ci = ev.Rand.Intn(nc-1, -1)
+		if ci >= catIdx {
+			ci++
+		}
+	}
+	cimgs := imgs[ci]
+	img := ""
+	for try := 0; try < 10; try++ {
+		fn := cimgs[ev.Rand.Intn(len(cimgs), -1)]
+		if ev.Images.CatSep == "" {
+			fn = ev.Images.Cats[ci] + "/" + fn
+		}
+		img = fn
+		if fn != excl {
+			break
+		}
+	}
+	return img, ci, rel
+}
+
+// ShowImage filters given image, using the current transforms,
+// and sets the output for its category, updating CurImg and CurCat.
+// Used for presenting the prime and target in the priming paradigm.
+func (ev *ImagesEnv) ShowImage(img string) {
+	ev.CurImg = img
+	ev.CurImgIdx = -1
+	ev.CurCat = ev.Images.Cat(img)
+	ev.CurCatIdx = ev.Images.CatMap[ev.CurCat]
+	if ev.OpenImageName(img) != nil {
+		return
+	}
+	ev.FilterOpenImage()
+	ev.SetOutput(ev.CurCatIdx)
+}
+
 func (ev *ImagesEnv) Counter(scale env.TimeScales) (cur, prv int, chg bool) {
 	switch scale {
 	case env.Run:
@@ -632,6 +1374,19 @@ func (ev *ImagesEnv) State(element string) etensor.Tensor {
 	return nil
 }
 
+// V1Names returns the names of the V1 filter outputs computed
+// with the current settings, as used in State
+func (ev *ImagesEnv) V1Names() []string {
+	nms := []string{"V1l16", "V1m16", "V1l8", "V1m8"}
+	if ev.High16 {
+		nms = append(nms, "V1h16")
+	}
+	if ev.ColorDoG {
+		nms = append(nms, "V1Cl16", "V1Cm16", "V1Cl8", "V1Cm8")
+	}
+	return nms
+}
+
 func (ev *ImagesEnv) Action(element string, input etensor.Tensor) {
 	// nop
 }
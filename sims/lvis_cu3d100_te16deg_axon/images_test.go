@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRenameCatsChained(t *testing.T) {
+	im := &Images{CatSep: "_"}
+	im.CatRename = map[string]string{"a": "b", "b": "c"}
+	im.Cats = []string{"a", "b", "d"}
+	im.ImagesTrain = [][]string{{"a_1_1.png"}, {"b_1_1.png"}, {"d_1_1.png"}}
+	im.ImagesTest = [][]string{{"a_2_1.png"}, {"b_2_1.png"}, {"d_2_1.png"}}
+	if n := im.RenameCats(); n != 0 {
+		t.Errorf("RenameCats merged: %d, want 0", n)
+	}
+	cats := []string{"b", "c", "d"}
+	trn := [][]string{{"a_1_1.png"}, {"b_1_1.png"}, {"d_1_1.png"}}
+	if !reflect.DeepEqual(im.Cats, cats) || !reflect.DeepEqual(im.ImagesTrain, trn) {
+		t.Fatalf("RenameCats: got cats: %v train: %v, want: %v %v", im.Cats, im.ImagesTrain, cats, trn)
+	}
+	// reapplied, as after loading a saved split
+	if n := im.RenameCats(); n != 0 {
+		t.Errorf("RenameCats again merged: %d, want 0", n)
+	}
+	if !reflect.DeepEqual(im.Cats, cats) || !reflect.DeepEqual(im.ImagesTrain, trn) {
+		t.Errorf("RenameCats again: got cats: %v train: %v, want: %v %v", im.Cats, im.ImagesTrain, cats, trn)
+	}
+	if im.CatMap["c"] != 1 {
+		t.Errorf("CatMap[c] = %d, want 1", im.CatMap["c"])
+	}
+}
+
+func TestRenameCatsMerge(t *testing.T) {
+	im := &Images{CatSep: "_"}
+	im.CatRename = map[string]string{"Car": "car"}
+	im.Cats = []string{"Car", "car", "dog"}
+	im.ImagesAll = [][]string{{"Car_1_1.png"}, {"car_2_1.png"}, {"dog_1_1.png"}}
+	if n := im.RenameCats(); n != 1 {
+		t.Errorf("RenameCats merged: %d, want 1", n)
+	}
+	all := [][]string{{"Car_1_1.png", "car_2_1.png"}, {"dog_1_1.png"}}
+	if !reflect.DeepEqual(im.Cats, []string{"car", "dog"}) || !reflect.DeepEqual(im.ImagesAll, all) {
+		t.Errorf("RenameCats: got cats: %v all: %v", im.Cats, im.ImagesAll)
+	}
+	if im.ImagesTrain != nil {
+		t.Errorf("RenameCats: nil ImagesTrain became: %v", im.ImagesTrain)
+	}
+}
+
+// subsampleImages returns Images with ntrn training images in each of
+// two categories, with 2 images per item
+func subsampleImages(ntrn int) *Images {
+	im := &Images{CatSep: "_"}
+	im.Cats = []string{"car", "dog"}
+	im.ImagesTrain = make([][]string, 2)
+	for ci, cat := range im.Cats {
+		for i := 0; i < ntrn; i++ {
+			im.ImagesTrain[ci] = append(im.ImagesTrain[ci], fmt.Sprintf("%s_%02d_%d.png", cat, i/2, i%2))
+		}
+	}
+	return im
+}
+
+func TestSubsampleTrain(t *testing.T) {
+	tests := []struct {
+		frac   float32
+		minN   int
+		byItem bool
+		n      int
+	}{
+		{0.25, 0, false, 5},
+		{0.5, 0, false, 10},
+		{0.5, 12, false, 12},
+		{0, 0, false, 1},
+		{2, 0, false, 20},
+		{0.3, 0, true, 6},  // 3 of 10 items
+		{0.01, 0, true, 2}, // at least 1 item
+	}
+	var prev [][]string
+	for _, tt := range tests {
+		im := subsampleImages(20)
+		all := im.ImagesTrain
+		im.SubsampleTrain(tt.frac, tt.minN, 3, tt.byItem)
+		for ci, fls := range im.ImagesTrain {
+			if len(fls) != tt.n {
+				t.Errorf("frac: %g minN: %d byItem: %v: %s: kept: %d, want: %d", tt.frac, tt.minN, tt.byItem, im.Cats[ci], len(fls), tt.n)
+			}
+			ai := 0
+			for _, f := range fls { // in the original order
+				for ai < len(all[ci]) && all[ci][ai] != f {
+					ai++
+				}
+				if ai == len(all[ci]) {
+					t.Errorf("frac: %g: %s: %s not in order", tt.frac, im.Cats[ci], f)
+				}
+			}
+			if tt.byItem {
+				itms := make(map[string]int)
+				for _, f := range fls {
+					itms[im.Item(f)]++
+				}
+				for itm, n := range itms {
+					if n != 2 {
+						t.Errorf("frac: %g byItem: %s: item: %s kept images: %d, want: 2", tt.frac, im.Cats[ci], itm, n)
+					}
+				}
+			}
+		}
+		if len(im.FlatTrain) != 2*tt.n {
+			t.Errorf("frac: %g: FlatTrain: %d, want: %d", tt.frac, len(im.FlatTrain), 2*tt.n)
+		}
+		if prev != nil && !tt.byItem && tt.minN == 0 && tt.frac == 0.5 { // nested in the larger fraction
+			for ci, fls := range prev {
+				keep := make(map[string]bool)
+				for _, f := range im.ImagesTrain[ci] {
+					keep[f] = true
+				}
+				for _, f := range fls {
+					if !keep[f] {
+						t.Errorf("frac: 0.25 image %s not kept at frac: 0.5", f)
+					}
+				}
+			}
+		}
+		prev = im.ImagesTrain
+	}
+	im := subsampleImages(20)
+	im.SubsampleTrain(0.25, 0, 3, false)
+	again := subsampleImages(20)
+	again.SubsampleTrain(0.25, 0, 3, false)
+	if !reflect.DeepEqual(im.ImagesTrain, again.ImagesTrain) {
+		t.Errorf("SubsampleTrain not deterministic: %v vs. %v", im.ImagesTrain, again.ImagesTrain)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goki/gi/gi"
+	"golang.org/x/image/tiff"
+)
+
+// imgfile.go has image file decoding with explicit JPEG and TIFF
+// support, including the EXIF orientation, so that real photos are
+// presented upright.
+
+// ImageExts are the image file extensions found in the image directories,
+// matched case-insensitively (e.g., .JPG), as in dirs.ExtFileNames
+var ImageExts = []string{".png", ".jpg", ".jpeg", ".tif", ".tiff"}
+
+// OpenImageFile opens the given image file: JPEG and TIFF files are
+// decoded directly, and rotated / flipped according to their EXIF
+// orientation tag, while other formats use gi.OpenImage.
+func OpenImageFile(fname string) (image.Image, error) {
+	ext := strings.ToLower(filepath.Ext(fname))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".tif" && ext != ".tiff" {
+		return gi.OpenImage(fname)
+	}
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	var img image.Image
+	if ext == ".tif" || ext == ".tiff" {
+		img, err = tiff.Decode(bytes.NewReader(data))
+	} else {
+		img, err = jpeg.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("OpenImageFile: %s: %w", fname, err)
+	}
+	return OrientImage(img, ExifOrientation(data)), nil
+}
+
+// ExifOrientation returns the EXIF orientation tag value (1-8) from the
+// raw data of a JPEG file (APP1 Exif segment) or TIFF file (first IFD),
+// or 1 (upright) if not present.
+func ExifOrientation(data []byte) int {
+	switch {
+	case len(data) > 4 && data[0] == 0xFF && data[1] == 0xD8:
+		for i := 2; i+4 <= len(data); {
+			if data[i] != 0xFF {
+				break
+			}
+			mk := data[i+1]
+			if mk == 0xD9 || mk == 0xDA { // end of image, start of scan
+				break
+			}
+			sz := int(binary.BigEndian.Uint16(data[i+2:]))
+			if mk == 0xE1 && sz >= 8 && i+2+sz <= len(data) && string(data[i+4:i+10]) == "Exif\x00\x00" {
+				return tiffOrientation(data[i+10 : i+2+sz])
+			}
+			i += 2 + sz
+		}
+	case len(data) > 8:
+		return tiffOrientation(data)
+	}
+	return 1
+}
+
+// tiffOrientation returns the orientation tag from the first IFD of
+// TIFF-structured data, as used in both TIFF files and EXIF segments.
+func tiffOrientation(b []byte) int {
+	if len(b) < 8 {
+		return 1
+	}
+	var bo binary.ByteOrder
+	switch string(b[:4]) {
+	case "II*\x00":
+		bo = binary.LittleEndian
+	case "MM\x00*":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(bo.Uint32(b[4:]))
+	if ifd+2 > len(b) {
+		return 1
+	}
+	n := int(bo.Uint16(b[ifd:]))
+	for i := 0; i < n; i++ {
+		e := ifd + 2 + 12*i
+		if e+12 > len(b) {
+			break
+		}
+		if bo.Uint16(b[e:]) == 0x0112 {
+			o := int(bo.Uint16(b[e+8:]))
+			if o >= 1 && o <= 8 {
+				return o
+			}
+			break
+		}
+	}
+	return 1
+}
+
+// OrientImage returns the image transformed according to given EXIF
+// orientation value, so that it is upright: 2 = flip horizontal,
+// 3 = rotate 180, 4 = flip vertical, 5 = transpose, 6 = rotate 90 CW,
+// 7 = transverse, 8 = rotate 90 CCW.  Returns img as-is for 1.
+func OrientImage(img image.Image, orient int) image.Image {
+	if orient <= 1 || orient > 8 {
+		return img
+	}
+	sb := img.Bounds()
+	w, h := sb.Dx(), sb.Dy()
+	dw, dh := w, h
+	if orient >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		for dx := 0; dx < dw; dx++ {
+			var sx, sy int
+			switch orient {
+			case 2:
+				sx, sy = w-1-dx, dy
+			case 3:
+				sx, sy = w-1-dx, h-1-dy
+			case 4:
+				sx, sy = dx, h-1-dy
+			case 5:
+				sx, sy = dy, dx
+			case 6:
+				sx, sy = dy, h-1-dx
+			case 7:
+				sx, sy = w-1-dy, h-1-dx
+			case 8:
+				sx, sy = w-1-dy, dx
+			}
+			dst.Set(dx, dy, img.At(sb.Min.X+sx, sb.Min.Y+sy))
+		}
+	}
+	return dst
+}
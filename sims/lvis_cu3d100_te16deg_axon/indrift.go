@@ -0,0 +1,176 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// indrift.go has the input drift monitor, for catching env and filtering
+// misconfigurations (e.g., a different SepColor, image set, or input
+// normalization) that otherwise only show up as a mysterious loss of
+// accuracy: the average activity (ActAvg.ActMAvg) of each input layer is
+// logged at each training epoch as InAvg_<layer>, and compared with a
+// reference value, with the maximum relative difference over layers
+// logged as InDrift.  The reference is from the Config.Run.InDrift.Ref
+// file, as saved by a previous run known to be good, or else the values
+// at the end of training epoch RefEpoch of the current run, which are
+// saved as an in_ref log for use as such a reference.  When a layer
+// first differs by more than Tol, an alert is printed to the log and to
+// stderr, and again when it returns within Tol.
+
+// InDrift has the state of the input drift monitor
+type InDrift struct {
+
+	// input layers monitored
+	Layers []string
+
+	// reference ActAvg per layer, nil until set
+	Ref map[string]float32
+
+	// layers currently beyond tolerance, for alerting on changes
+	Over map[string]bool
+}
+
+// InDriftStatName returns the stat name of the average activity of given layer
+func InDriftStatName(lay string) string {
+	return "InAvg_" + lay
+}
+
+// ConfigInDrift configures the input drift monitor, opening the
+// reference file if set
+func (ss *Sim) ConfigInDrift() {
+	dc := &ss.Config.Run.InDrift
+	id := &ss.InDrift
+	*id = InDrift{}
+	if !dc.On {
+		return
+	}
+	id.Layers = ss.Net.LayersByType(axon.InputLayer)
+	if dc.Ref == "" {
+		return
+	}
+	dt := &etable.Table{}
+	if err := dt.OpenCSV(gi.FileName(dc.Ref), etable.Tab); err != nil {
+		mpi.Printf("InDrift: could not open reference file: %s: %v -- using RefEpoch\n", dc.Ref, err)
+		return
+	}
+	id.Ref = make(map[string]float32)
+	for ri := 0; ri < dt.Rows; ri++ {
+		id.Ref[dt.CellString("Layer", ri)] = float32(dt.CellFloat("ActAvg", ri))
+	}
+	mpi.Printf("InDrift: opened reference input activity for %d layers from: %s\n", len(id.Ref), dc.Ref)
+}
+
+// InitInDrift resets the alert state, and the reference unless from a
+// file, for a new run
+func (ss *Sim) InitInDrift() {
+	id := &ss.InDrift
+	id.Over = make(map[string]bool)
+	if ss.Config.Run.InDrift.Ref == "" {
+		id.Ref = nil
+	}
+}
+
+// SaveInDriftRef saves the current reference values as an in_ref log
+// file, on rank 0
+func (ss *Sim) SaveInDriftRef() {
+	if ss.MPIRank() != 0 {
+		return
+	}
+	id := &ss.InDrift
+	dt := &etable.Table{}
+	dt.SetFromSchema(etable.Schema{
+		{"Layer", etensor.STRING, nil, nil},
+		{"ActAvg", etensor.FLOAT64, nil, nil},
+	}, len(id.Layers))
+	for ri, lnm := range id.Layers {
+		dt.SetCellString("Layer", ri, lnm)
+		dt.SetCellFloat("ActAvg", ri, float64(id.Ref[lnm]))
+	}
+	fnm := elog.LogFileName("in_ref", ss.Net.Name(), ss.Stats.String("RunName"))
+	if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		mpi.Println(err)
+		return
+	}
+	mpi.Printf("InDrift: saved reference input activity to: %s\n", fnm)
+}
+
+// InDriftAlert prints given alert to the log and to stderr, on rank 0
+func (ss *Sim) InDriftAlert(msg string) {
+	mpi.Printf("%s\n", msg)
+	if ss.MPIRank() == 0 {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+}
+
+// InDriftStats records the average activity of the input layers, and
+// compares it with the reference, alerting on changes in whether each
+// layer is beyond tolerance -- called at the end of the training epoch
+func (ss *Sim) InDriftStats() {
+	dc := &ss.Config.Run.InDrift
+	id := &ss.InDrift
+	if !dc.On || len(id.Layers) == 0 {
+		return
+	}
+	ss.Net.GPU.SyncLayerValsFmGPU()
+	epc := ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur
+	if id.Ref == nil && epc+1 >= dc.RefEpoch {
+		id.Ref = make(map[string]float32)
+		for _, lnm := range id.Layers {
+			id.Ref[lnm] = ss.Net.AxonLayerByName(lnm).LayerVals(0).ActAvg.ActMAvg
+		}
+		ss.SaveInDriftRef()
+	}
+	mx := 0.0
+	for _, lnm := range id.Layers {
+		avg := ss.Net.AxonLayerByName(lnm).LayerVals(0).ActAvg.ActMAvg
+		ss.Stats.SetFloat(InDriftStatName(lnm), float64(avg))
+		ref, ok := id.Ref[lnm]
+		if !ok || ref <= 0 {
+			continue
+		}
+		drift := math.Abs(float64(avg-ref)) / float64(ref)
+		if drift > mx {
+			mx = drift
+		}
+		over := drift > float64(dc.Tol)
+		if over == id.Over[lnm] {
+			continue
+		}
+		id.Over[lnm] = over
+		if over {
+			ss.InDriftAlert(fmt.Sprintf("InDrift ALERT: epoch %d: input layer %s average activity: %g differs from reference: %g by %.0f%% > tolerance: %.0f%% -- check the env and filtering config", epc, lnm, avg, ref, 100*drift, 100*dc.Tol))
+		} else {
+			ss.InDriftAlert(fmt.Sprintf("InDrift: epoch %d: input layer %s average activity: %g back within tolerance of reference: %g", epc, lnm, avg, ref))
+		}
+	}
+	ss.Stats.SetFloat("InDrift", mx)
+}
+
+// ConfigInDriftLogs adds the InAvg stats of each input layer and the
+// InDrift stat to the training epoch log
+func (ss *Sim) ConfigInDriftLogs() {
+	id := &ss.InDrift
+	if !ss.Config.Run.InDrift.On || len(id.Layers) == 0 {
+		return
+	}
+	for _, lnm := range id.Layers {
+		ss.Stats.SetFloat(InDriftStatName(lnm), 0)
+		ss.Logs.AddStatFloatNoAggItem(etime.Train, etime.Epoch, InDriftStatName(lnm))
+	}
+	ss.Stats.SetFloat("InDrift", 0)
+	ss.Logs.AddStatFloatNoAggItem(etime.Train, etime.Epoch, "InDrift")
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/emergent/timer"
+	"github.com/emer/empi/mpi"
+	"github.com/goki/gi/gi"
+)
+
+// infer.go has the inference-only mode, for testing saved weights
+// with reduced memory and no learning computation.
+
+// ConfigInfer configures the network for inference only, after Build:
+// the per-data synapse-level calcium and trace learning state (SynapseCas),
+// which is the bulk of the synapse memory for NData > 1, is collapsed to
+// a single shared synapse, by setting its synapse stride to 0.
+// This is only valid because learning is never run (see InferLearnOff).
+// The remaining synapse learning state (DWt, SWt etc) is in the main
+// Synapses array, which is always allocated by axon.
+func (ss *Sim) ConfigInfer() {
+	net := ss.Net
+	maxData := uint64(net.MaxData)
+	prv := len(net.SynapseCas)
+	net.Ctx.SynapseCaVars.Synapse = 0
+	net.Ctx.SynapseCaVars.Var = maxData
+	net.SetCtxStrides(&ss.Context)
+	net.SynapseCas = make([]float32, uint64(axon.SynapseCaVarsN)*maxData)
+	mpi.Printf("Inference only: synapse Ca learning state reduced from %.1f MB to %d bytes\n", float64(4*prv)/float64(1<<20), 4*len(net.SynapseCas))
+}
+
+// InferLearnOff turns off learning in all projections,
+// for inference only mode -- called after params are applied.
+func (ss *Sim) InferLearnOff() {
+	for _, ly := range ss.Net.Layers {
+		for _, pj := range ly.RcvPrjns {
+			pj.Params.Learn.Learn.SetBool(false)
+		}
+	}
+}
+
+// RunInfer opens the Config.Run.OpenWts weights and runs the full
+// test set, reporting the test error and timing, for the inference only mode.
+func (ss *Sim) RunInfer() error {
+	if ss.Config.Run.OpenWts == "" {
+		return fmt.Errorf("RunInfer: Config.Run.OpenWts weights file must be set for Infer mode")
+	}
+	if err := ss.OpenWeights(gi.FileName(ss.Config.Run.OpenWts)); err != nil {
+		return err
+	}
+	ss.Net.InitActs(&ss.Context)
+	tmr := timer.Time{}
+	tmr.Start()
+	ss.TestAll()
+	tmr.Stop()
+	dt := ss.Logs.Table(etime.Test, etime.Epoch)
+	if dt.Rows > 0 {
+		mpi.Printf("Test PctErr: %g  PctErr2: %g\n", dt.CellFloat("PctErr", dt.Rows-1), dt.CellFloat("PctErr2", dt.Rows-1))
+	}
+	ntrl := ss.Logs.Table(etime.Test, etime.Trial).Rows
+	if ntrl > 0 {
+		mpi.Printf("Test Time: %6.3g   Per Trl Msec: %g\n", tmr.TotalSecs(), 1000*tmr.TotalSecs()/float64(ntrl))
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// inputnorm.go has the normalization of the images prior to the V1
+// filtering, as rendered objects and natural photos differ widely in
+// their luminance and contrast statistics.  It is applied to each image
+// as opened, before the aspect, spatial, and illumination transforms
+// (so the illumination sweep still measures the effects of the
+// illumination), per the InputNorm method:
+//
+//	None      no normalization (default)
+//	ZScore    the luminance is set to NormMean mean and NormSD standard
+//	          deviation, by an affine transform of all channels
+//	Contrast  the RMS contrast (luminance standard deviation / mean) is
+//	          set to NormContrast, keeping the mean luminance
+//	HistEq    histogram equalization of the luminance, with all channels
+//	          scaled by the ratio of the equalized to the original
+//	          luminance, to preserve the hue
+//
+// Luminance is the Rec. 601 weighted sum of R, G, B, in the 0-1 range,
+// and the results are clipped to the valid range.  The method is printed
+// at startup and saved in the weights file metadata as InputNorm.
+
+// ImageLum returns the luminance of each pixel of given image, 0-1
+func ImageLum(img *image.RGBA) []float32 {
+	lum := make([]float32, len(img.Pix)/4)
+	for i := range lum {
+		p := img.Pix[4*i : 4*i+3]
+		lum[i] = (0.299*float32(p[0]) + 0.587*float32(p[1]) + 0.114*float32(p[2])) / 255
+	}
+	return lum
+}
+
+// LumStats returns the mean and standard deviation of given luminance values
+func LumStats(lum []float32) (mean, sd float32) {
+	if len(lum) == 0 {
+		return 0, 0
+	}
+	var sum, ss float64
+	for _, l := range lum {
+		sum += float64(l)
+	}
+	m := sum / float64(len(lum))
+	for _, l := range lum {
+		d := float64(l) - m
+		ss += d * d
+	}
+	return float32(m), float32(math.Sqrt(ss / float64(len(lum))))
+}
+
+// normPix sets pixel value from given 0-1 value, clipped
+func normPix(v float32) uint8 {
+	switch {
+	case v > 1:
+		v = 1
+	case v < 0:
+		v = 0
+	}
+	return uint8(v*255 + 0.5)
+}
+
+// AffineImage transforms all channels of given image as v = off + v * gain,
+// with values in the 0-1 range
+func AffineImage(img *image.RGBA, off, gain float32) {
+	for i := 0; i < len(img.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			img.Pix[i+c] = normPix(off + gain*float32(img.Pix[i+c])/255)
+		}
+	}
+}
+
+// HistEqImage applies histogram equalization to the luminance of given
+// image, given its luminance values, scaling all channels by the ratio
+// of the equalized to the original luminance
+func HistEqImage(img *image.RGBA, lum []float32) {
+	var hist [256]int
+	bins := make([]uint8, len(lum))
+	for i, l := range lum {
+		bins[i] = normPix(l)
+		hist[bins[i]]++
+	}
+	var cdf [256]float32
+	n, cmin := 0, -1
+	for b, h := range hist {
+		n += h
+		if cmin < 0 && h > 0 {
+			cmin = n
+		}
+		cdf[b] = float32(n)
+	}
+	den := float32(len(lum) - cmin)
+	if den <= 0 { // uniform image
+		return
+	}
+	for i, l := range lum {
+		eq := (cdf[bins[i]] - float32(cmin)) / den
+		p := img.Pix[4*i : 4*i+3]
+		for c := range p {
+			if l > 0 {
+				p[c] = normPix(eq * float32(p[c]) / 255 / l)
+			} else {
+				p[c] = normPix(eq)
+			}
+		}
+	}
+}
+
+// NormImage applies the InputNorm normalization to the current image
+func (ev *ImagesEnv) NormImage() {
+	if ev.InputNorm == "" || ev.InputNorm == "None" {
+		return
+	}
+	b := ev.Image.Bounds()
+	img := image.NewRGBA(b)
+	draw.Draw(img, b, ev.Image, b.Min, draw.Src)
+	lum := ImageLum(img)
+	mean, sd := LumStats(lum)
+	switch ev.InputNorm {
+	case "ZScore":
+		if sd > 0 {
+			gain := ev.NormSD / sd
+			AffineImage(img, ev.NormMean-mean*gain, gain)
+		}
+	case "Contrast":
+		if sd > 0 {
+			gain := ev.NormContrast * mean / sd
+			AffineImage(img, mean*(1-gain), gain)
+		}
+	case "HistEq":
+		HistEqImage(img, lum)
+	}
+	ev.Image = img
+}
+
+// InputNormReport returns a summary of the input normalization of given env
+func InputNormReport(ev *ImagesEnv) string {
+	switch ev.InputNorm {
+	case "ZScore":
+		return fmt.Sprintf("Input normalization: ZScore luminance: mean: %g  sd: %g\n", ev.NormMean, ev.NormSD)
+	case "Contrast":
+		return fmt.Sprintf("Input normalization: Contrast: RMS contrast: %g\n", ev.NormContrast)
+	case "HistEq":
+		return "Input normalization: HistEq luminance histogram equalization\n"
+	}
+	return "Input normalization: None\n"
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/etview"
+	"github.com/goki/gi/gi"
+)
+
+// launcher.go has the GUI parameter search launcher, for exploring params
+// without cluster scripts: a small grid of command-line arg values is
+// expanded into all of its combinations, each of which is run in turn as
+// a nogui subprocess of this executable, with its own Params.Tag so the
+// saved files are distinct.  The Launch tab shows a table of the runs,
+// updated from the epoch log file of the current run while it is going,
+// with the headline metrics.  The runs are in the current directory, with
+// the output of each in a _launch.txt file.  Stop kills the current run
+// and stops the launcher.  For running the same grid on a cluster, see
+// Config.Run.Search, which takes the Args of each run in a file.
+
+// LaunchPoll is the interval between updates of the Launch table
+// from the epoch log of the current run
+const LaunchPoll = 5 * time.Second
+
+// LaunchStats are the train epoch log stats shown in the Launch table
+var LaunchStats = []string{"Epoch", "PctErr", "TstPctErr"}
+
+// Launcher launches the runs of a parameter grid from the GUI
+type Launcher struct {
+
+	// parameter grid: space-separated Arg=val1,val2,.. items, with the Config field (e.g., Run.NEpochs) or other command-line arg name, and its values, all combinations of which are run, e.g.: Params.Sheet=Base,Fast Run.NData=8,16
+	Grid string `desc:"parameter grid: space-separated Arg=val1,val2,.. items, with the Config field (e.g., Run.NEpochs) or other command-line arg name, and its values, all combinations of which are run, e.g.: Params.Sheet=Base,Fast Run.NData=8,16"`
+
+	// [def: -NRuns 1 -NEpochs 50] additional command-line args for all runs
+	Args string `def:"-NRuns 1 -NEpochs 50" desc:"additional command-line args for all runs"`
+
+	// [def: grid] prefix of the Params.Tag of the runs, which are numbered in order: grid_0, grid_1, ..
+	Tag string `def:"grid" desc:"prefix of the Params.Tag of the runs, which are numbered in order: grid_0, grid_1, .."`
+
+	// [view: -] table of the runs: Tag, Args, Status, and the LaunchStats from the last row of the epoch log
+	Table etable.Table `view:"-" desc:"table of the runs: Tag, Args, Status, and the LaunchStats from the last row of the epoch log"`
+
+	// [view: -] view of the Table
+	View *etview.TableView `view:"-" desc:"view of the Table"`
+}
+
+func (ln *Launcher) Defaults() {
+	ln.Args = "-NRuns 1 -NEpochs 50"
+	ln.Tag = "grid"
+}
+
+// ConfigTable configures the Table with given run args
+func (ln *Launcher) ConfigTable(runs []string) {
+	sch := etable.Schema{
+		{"Tag", etensor.STRING, nil, nil},
+		{"Args", etensor.STRING, nil, nil},
+		{"Status", etensor.STRING, nil, nil},
+	}
+	for _, st := range LaunchStats {
+		sch = append(sch, etable.Column{Name: st, Type: etensor.FLOAT64})
+	}
+	ln.Table.SetFromSchema(sch, len(runs))
+	for ri, args := range runs {
+		ln.Table.SetCellString("Tag", ri, fmt.Sprintf("%s_%d", ln.Tag, ri))
+		ln.Table.SetCellString("Args", ri, args)
+		ln.Table.SetCellString("Status", ri, "pending")
+	}
+}
+
+// UpdateView updates the table view, if present
+func (ln *Launcher) UpdateView() {
+	if ln.View != nil {
+		ln.View.UpdateTable()
+	}
+}
+
+// GridArgs returns the command-line args string for each combination of
+// the values in given parameter grid, of space-separated Arg=val1,val2,..
+// items, with the values of the first item varying slowest
+func GridArgs(grid string) ([]string, error) {
+	runs := []string{""}
+	for _, it := range strings.Fields(grid) {
+		ei := strings.Index(it, "=")
+		if ei <= 0 || ei == len(it)-1 {
+			return nil, fmt.Errorf("GridArgs: item is not Arg=val1,val2,..: %s", it)
+		}
+		arg := "-" + strings.TrimLeft(it[:ei], "-")
+		var nruns []string
+		for _, rn := range runs {
+			for _, v := range strings.Split(it[ei+1:], ",") {
+				nruns = append(nruns, strings.TrimSpace(rn+" "+arg+" "+v))
+			}
+		}
+		runs = nruns
+	}
+	return runs, nil
+}
+
+// LaunchRun runs the given row of the Launch table as a nogui subprocess,
+// updating the table from its epoch log until it finishes, or until Stop
+// is pressed, in which case it is killed.  Returns false if stopped.
+func (ss *Sim) LaunchRun(exe string, row int) bool {
+	ln := &ss.Launcher
+	tag := ln.Table.CellString("Tag", row)
+	args := append([]string{"-nogui", "-Params.Tag", tag}, strings.Fields(ln.Args)...)
+	args = append(args, strings.Fields(ln.Table.CellString("Args", row))...)
+	netName := ss.Net.Name()
+	out, err := os.Create(fmt.Sprintf("%s_%s_launch.txt", netName, tag))
+	if err != nil {
+		log.Println(err)
+		ln.Table.SetCellString("Status", row, "failed")
+		return true
+	}
+	defer out.Close()
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Start(); err != nil {
+		log.Println(err)
+		ln.Table.SetCellString("Status", row, "failed")
+		return true
+	}
+	ln.Table.SetCellString("Status", row, "running")
+	ln.UpdateView()
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	epcGlob := fmt.Sprintf("%s_%s_*epc.tsv", netName, tag)
+	last := time.Now()
+	for {
+		select {
+		case err := <-done:
+			ss.LaunchUpdate(row, epcGlob)
+			if err != nil {
+				log.Printf("Launch: %s: %s\n", tag, err)
+				ln.Table.SetCellString("Status", row, "failed")
+			} else {
+				ln.Table.SetCellString("Status", row, "done")
+			}
+			ln.UpdateView()
+			return true
+		case <-time.After(time.Second):
+			if ss.GUI.StopNow {
+				cmd.Process.Kill()
+				<-done
+				ln.Table.SetCellString("Status", row, "stopped")
+				ln.UpdateView()
+				return false
+			}
+			if time.Since(last) >= LaunchPoll {
+				ss.LaunchUpdate(row, epcGlob)
+				ln.UpdateView()
+				last = time.Now()
+			}
+		}
+	}
+}
+
+// LaunchUpdate sets the LaunchStats of given row of the Launch table
+// from the last row of the epoch log file matching given glob pattern,
+// if it exists (the train epoch log, not the test one)
+func (ss *Sim) LaunchUpdate(row int, epcGlob string) {
+	ln := &ss.Launcher
+	fnms, _ := filepath.Glob(epcGlob)
+	for _, fnm := range fnms {
+		if strings.HasSuffix(fnm, "_tst_epc.tsv") {
+			continue
+		}
+		dt := &etable.Table{}
+		if err := dt.OpenCSV(gi.FileName(fnm), etable.Tab); err != nil || dt.Rows == 0 {
+			continue
+		}
+		for _, st := range LaunchStats {
+			if dt.ColIdx(st) >= 0 {
+				ln.Table.SetCellFloat(st, row, dt.CellFloat(st, dt.Rows-1))
+			}
+		}
+		return
+	}
+}
+
+// Launch runs each combination of the given parameter grid in turn,
+// with given additional args for all runs -- see Launcher
+func (ss *Sim) Launch(grid, args string) error {
+	ln := &ss.Launcher
+	ln.Grid = grid
+	ln.Args = args
+	runs, err := GridArgs(grid)
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	ln.ConfigTable(runs)
+	ln.UpdateView()
+	for ri := range runs {
+		if !ss.LaunchRun(exe, ri) {
+			break
+		}
+	}
+	return nil
+}
+
+// LaunchGUI launches the runs of the given parameter grid in the
+// background, shown in the Launch tab
+func (ss *Sim) LaunchGUI(grid, args string) {
+	if ss.GUI.IsRunning {
+		return
+	}
+	ss.GUI.IsRunning = true
+	ss.GUI.StopNow = false
+	ss.GUI.ToolBar.UpdateActions()
+	go func() {
+		if err := ss.Launch(grid, args); err != nil {
+			log.Println(err)
+		}
+		ss.GUI.Stopped()
+	}()
+}
+
+// ConfigLaunchGui adds the Launch table tab to the GUI
+func (ss *Sim) ConfigLaunchGui() {
+	ln := &ss.Launcher
+	ln.ConfigTable(nil)
+	tv := ss.GUI.TabView.AddNewTab(etview.KiT_TableView, "Launch").(*etview.TableView)
+	tv.SetTable(&ln.Table, nil)
+	ln.View = tv
+}
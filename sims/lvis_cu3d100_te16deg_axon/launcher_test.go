@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGridArgs(t *testing.T) {
+	tests := []struct {
+		grid string
+		want []string
+		err  bool
+	}{
+		{"", []string{""}, false},
+		{"Run.NEpochs=10", []string{"-Run.NEpochs 10"}, false},
+		{"-Params.Sheet=A,B Run.NData=8,16", []string{
+			"-Params.Sheet A -Run.NData 8",
+			"-Params.Sheet A -Run.NData 16",
+			"-Params.Sheet B -Run.NData 8",
+			"-Params.Sheet B -Run.NData 16",
+		}, false},
+		{"Run.NEpochs", nil, true},
+		{"=10", nil, true},
+		{"Run.NEpochs=", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := GridArgs(tt.grid)
+		if (err != nil) != tt.err {
+			t.Errorf("GridArgs(%q) error: %v, want error: %v", tt.grid, err, tt.err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GridArgs(%q) = %q, want: %q", tt.grid, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,801 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/emergent/params"
+	"github.com/emer/empi/mpi"
+	"github.com/goki/vgpu/vgpu"
+)
+
+// learn.go has sim-level modifications to the standard axon learning
+// process, applied to projections selected by params-style selectors.
+
+// PrjnsBySel returns all the projections in the network that match
+// given params-style selector: .Class, #Name, or Type (e.g., ForwardPrjn)
+func (ss *Sim) PrjnsBySel(sel string) []*axon.Prjn {
+	var pjs []*axon.Prjn
+	for _, ly := range ss.Net.Layers {
+		for _, pj := range ly.RcvPrjns {
+			if params.SelMatch(sel, pj.Name(), pj.Class(), pj.TypeName(), "Prjn") {
+				pjs = append(pjs, pj)
+			}
+		}
+	}
+	return pjs
+}
+
+// SelName returns a version of given selector suitable for use in
+// stat and log column names, without the leading . or #
+func SelName(sel string) string {
+	return strings.TrimLeft(sel, ".#")
+}
+
+//////////////////////////////////////////////////////////////////////////////
+//   WtDecay
+
+// WtDecayStatName returns the name of the stat recording the average
+// decay applied per synapse for given WtDecayConfig
+func WtDecayStatName(wd *WtDecayConfig) string {
+	return "WtDecay_" + SelName(wd.Sel)
+}
+
+// WtDecayWtStatName returns the name of the stat recording the
+// mean weight of projections for given WtDecayConfig
+func WtDecayWtStatName(wd *WtDecayConfig) string {
+	return "WtDecayWt_" + SelName(wd.Sel)
+}
+
+// WtDecayScale returns the factor for the Decay of Config.Params.WtDecay
+// on the current training trial: the WtDecayInterval on every
+// WtDecayInterval trials, and 0 otherwise
+func (ss *Sim) WtDecayScale() float32 {
+	intv := ss.Config.Params.WtDecayInterval
+	if intv <= 1 {
+		return 1
+	}
+	trl := ss.Loops.GetLoop(etime.Train, etime.Trial).Counter.Cur / int(ss.Context.NetIdxs.NData)
+	if trl%intv != 0 {
+		return 0
+	}
+	return float32(intv)
+}
+
+// WtDecayOn returns true if WtDecay changes any DWts on the current
+// trial: the soft bounding applies on every trial, and the decay
+// every WtDecayInterval trials
+func (ss *Sim) WtDecayOn() bool {
+	wds := ss.Config.Params.WtDecay
+	if len(wds) == 0 {
+		return false
+	}
+	if ss.WtDecayScale() > 0 {
+		return true
+	}
+	for i := range wds {
+		if wds[i].SoftMax > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// WtDecay applies the weight decay and soft weight bounding specified in
+// Config.Params.WtDecay to the DWt values of the selected projections,
+// prior to WtFmDWt.  Decay pulls the linear LWt weight toward its
+// neutral 0.5 value, so the effective Wt decays toward SWt.
+// The decay is only applied every WtDecayInterval trials, scaled by the
+// interval, while the soft bounding is applied on every trial.
+// The synapses must be synced from the GPU: see MPIWtFmDWt.
+func (ss *Sim) WtDecay() {
+	if !ss.WtDecayOn() {
+		return
+	}
+	wds := ss.Config.Params.WtDecay
+	scale := ss.WtDecayScale()
+	ctx := &ss.Context
+	for i := range wds {
+		wd := &wds[i]
+		decay := wd.Decay * scale
+		if decay == 0 && wd.SoftMax <= 0 {
+			continue
+		}
+		sumDecay := float32(0)
+		sumWt := float32(0)
+		n := 0
+		for _, pj := range ss.PrjnsBySel(wd.Sel) {
+			if pj.Params.Learn.Learn.IsFalse() {
+				continue
+			}
+			for syi := uint32(0); syi < pj.NSyns; syi++ {
+				syni := pj.SynStIdx + syi
+				dwt := axon.SynV(ctx, syni, axon.DWt)
+				wt := axon.SynV(ctx, syni, axon.Wt)
+				if wd.SoftMax > 0 && dwt > 0 {
+					sb := 1 - wt/wd.SoftMax
+					if sb < 0 {
+						sb = 0
+					}
+					dwt *= sb
+				}
+				dec := decay * (axon.SynV(ctx, syni, axon.LWt) - 0.5)
+				dwt -= dec
+				axon.SetSynV(ctx, syni, axon.DWt, dwt)
+				if dec < 0 {
+					dec = -dec
+				}
+				sumDecay += dec
+				sumWt += wt
+				n++
+			}
+		}
+		if n > 0 {
+			nm := WtDecayStatName(wd)
+			ss.Stats.SetFloat(nm, ss.Stats.Float(nm)+float64(sumDecay/float32(n)))
+			ss.Stats.SetFloat32(WtDecayWtStatName(wd), sumWt/float32(n))
+		}
+	}
+}
+
+// InitWtDecayStats resets the accumulated WtDecay stats -- called at
+// the start of each training epoch.
+func (ss *Sim) InitWtDecayStats() {
+	for i := range ss.Config.Params.WtDecay {
+		wd := &ss.Config.Params.WtDecay[i]
+		ss.Stats.SetFloat(WtDecayStatName(wd), 0)
+	}
+}
+
+// ConfigWtDecayLogs adds epoch-level log items for the total decay
+// applied and mean weight of each WtDecay projection class.
+func (ss *Sim) ConfigWtDecayLogs() {
+	for i := range ss.Config.Params.WtDecay {
+		wd := &ss.Config.Params.WtDecay[i]
+		ss.Stats.SetFloat(WtDecayWtStatName(wd), 0)
+		ss.Logs.AddStatFloatNoAggItem(etime.Train, etime.Epoch, WtDecayStatName(wd), WtDecayWtStatName(wd))
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////
+//   Rewire
+
+// RewireStatName returns the name of the given rewiring stat
+// (NPruned, NGrown, PctSilent) for given RewireConfig
+func RewireStatName(rw *RewireConfig, stat string) string {
+	return "Rewire_" + SelName(rw.Sel) + "_" + stat
+}
+
+// RewireMaskVars are the synapse variables set by SilenceSyn
+var RewireMaskVars = []axon.SynapseVars{axon.Wt, axon.LWt, axon.DWt}
+
+// PrjnSynRanges returns the [start, end) ranges of the network Synapses
+// array holding the given variables of the synapses of given projections,
+// sorted and merged where they overlap or are contiguous -- the ranges
+// depend on the synapse variable strides (synapse vs. variable outer).
+func PrjnSynRanges(ctx *axon.Context, pjs []*axon.Prjn, vars []axon.SynapseVars) [][2]uint32 {
+	var rngs [][2]uint32
+	for _, pj := range pjs {
+		if pj.NSyns == 0 {
+			continue
+		}
+		for _, v := range vars {
+			st := ctx.SynapseVars.Idx(pj.SynStIdx, v)
+			ed := ctx.SynapseVars.Idx(pj.SynStIdx+pj.NSyns-1, v) + 1
+			rngs = append(rngs, [2]uint32{st, ed})
+		}
+	}
+	sort.Slice(rngs, func(i, j int) bool {
+		return rngs[i][0] < rngs[j][0]
+	})
+	var mrg [][2]uint32
+	for _, r := range rngs {
+		if n := len(mrg); n > 0 && r[0] <= mrg[n-1][1] {
+			if r[1] > mrg[n-1][1] {
+				mrg[n-1][1] = r[1]
+			}
+			continue
+		}
+		mrg = append(mrg, r)
+	}
+	return mrg
+}
+
+// prjnSynRegs returns the GPU memory regions for given ranges of the
+// Synapses array, and the staging memory of the Synapses
+func prjnSynRegs(gp *axon.GPU, rngs [][2]uint32) ([]vgpu.MemReg, []float32) {
+	vr, vl, err := gp.Syns.ValByIdxTry("Synapses", 0)
+	if err != nil {
+		panic(err)
+	}
+	base := vl.MemReg(vr)
+	regs := make([]vgpu.MemReg, len(rngs))
+	for i, r := range rngs {
+		regs[i] = base
+		regs[i].Offset += 4 * int(r[0])
+		regs[i].Size = 4 * int(r[1]-r[0])
+	}
+	return regs, vl.Floats32()
+}
+
+// SyncSynRangesFmGPU copies the given ranges of the Synapses array (see
+// PrjnSynRanges) from the GPU, instead of all synapses as in
+// SyncSynapsesFmGPU
+func SyncSynRangesFmGPU(net *axon.Network, rngs [][2]uint32) {
+	gp := &net.GPU
+	if !gp.On || len(rngs) == 0 {
+		return
+	}
+	regs, stg := prjnSynRegs(gp, rngs)
+	gp.Sys.Mem.TransferRegsFmGPU(regs)
+	for _, r := range rngs {
+		copy(net.Synapses[r[0]:r[1]], stg[r[0]:r[1]])
+	}
+}
+
+// SyncSynRangesToGPU copies the given ranges of the Synapses array (see
+// PrjnSynRanges) to the GPU, instead of all synapses as in
+// SyncSynapsesToGPU
+func SyncSynRangesToGPU(net *axon.Network, rngs [][2]uint32) {
+	gp := &net.GPU
+	if !gp.On || len(rngs) == 0 {
+		return
+	}
+	regs, stg := prjnSynRegs(gp, rngs)
+	for _, r := range rngs {
+		copy(stg[r[0]:r[1]], net.Synapses[r[0]:r[1]])
+	}
+	gp.Sys.Mem.TransferRegsToGPU(regs)
+}
+
+// InitRewire initializes the structural plasticity state specified in
+// Config.Params.Rewire, after the weights have been initialized:
+// a random InitSilent proportion of the synapses within each receiving
+// unit's footprint are made silent, providing the pool of potential
+// synapses from which new ones are grown.  Skipped if weights are opened
+// from Config.Run.OpenWts, as those replace the initialized ones.
+func (ss *Sim) InitRewire() {
+	rws := ss.Config.Params.Rewire
+	ss.RewireSilent = nil
+	ss.RewireMaskSyns = nil
+	ss.RewireSyns = nil
+	if len(rws) == 0 {
+		return
+	}
+	if ss.Config.Run.OpenWts != "" {
+		mpi.Printf("Rewire: skipped, as weights are opened from: %s\n", ss.Config.Run.OpenWts)
+		return
+	}
+	ctx := &ss.Context
+	ss.RewireSilent = make(map[*axon.Prjn][]bool)
+	for i := range rws {
+		rw := &rws[i]
+		for _, pj := range ss.PrjnsBySel(rw.Sel) {
+			sil := make([]bool, pj.NSyns)
+			ss.RewireSilent[pj] = sil
+			for lni := uint32(0); lni < pj.Recv.NNeurons; lni++ {
+				syIdxs := pj.RecvSynIdxs(lni)
+				nsil := int(rw.InitSilent * float32(len(syIdxs)))
+				if nsil == 0 {
+					continue
+				}
+				for _, pi := range ss.Net.Rand.Perm(len(syIdxs), -1)[:nsil] {
+					sil[syIdxs[pi]] = true
+				}
+			}
+			for syi, s := range sil {
+				if s {
+					SilenceSyn(ctx, pj.SynStIdx+uint32(syi))
+				}
+			}
+		}
+	}
+	pjs := make([]*axon.Prjn, 0, len(ss.RewireSilent))
+	for pj := range ss.RewireSilent {
+		pjs = append(pjs, pj)
+	}
+	ss.RewireMaskSyns = PrjnSynRanges(ctx, pjs, RewireMaskVars)
+	all := make([]axon.SynapseVars, axon.SynapseVarsN)
+	for i := range all {
+		all[i] = axon.SynapseVars(i)
+	}
+	ss.RewireSyns = PrjnSynRanges(ctx, pjs, all)
+	SyncSynRangesToGPU(ss.Net, ss.RewireSyns)
+	ss.InitRewireStats()
+}
+
+// SilenceSyn sets the weight of given synapse to 0, with no learning
+func SilenceSyn(ctx *axon.Context, syni uint32) {
+	axon.SetSynV(ctx, syni, axon.Wt, 0)
+	axon.SetSynV(ctx, syni, axon.LWt, 0)
+	axon.SetSynV(ctx, syni, axon.DWt, 0)
+}
+
+// RewireMask keeps the silent synapses silent, by zeroing their DWt
+// and weights prior to WtFmDWt.  Because the network connectivity is
+// fixed, silent synapses stand in for the absent connections.
+// Only the RewireMaskVars of the Rewire projections are synced with the GPU.
+func (ss *Sim) RewireMask() {
+	if len(ss.RewireSilent) == 0 {
+		return
+	}
+	ctx := &ss.Context
+	SyncSynRangesFmGPU(ss.Net, ss.RewireMaskSyns)
+	for pj, sil := range ss.RewireSilent {
+		for syi, s := range sil {
+			if s {
+				SilenceSyn(ctx, pj.SynStIdx+uint32(syi))
+			}
+		}
+	}
+	SyncSynRangesToGPU(ss.Net, ss.RewireMaskSyns)
+}
+
+// Rewire performs one step of structural plasticity for the projections
+// in Config.Params.Rewire: within each receiving unit, the active synapses
+// with Wt below PruneThr are pruned (made silent), and the same number
+// of previously silent synapses (if available) are grown, with new random
+// initial weights, so the number of active synapses is conserved.
+// Called every RewireInterval training epochs.
+func (ss *Sim) Rewire() {
+	rws := ss.Config.Params.Rewire
+	if len(rws) == 0 || len(ss.RewireSilent) == 0 {
+		return
+	}
+	ctx := &ss.Context
+	SyncSynRangesFmGPU(ss.Net, ss.RewireSyns)
+	for i := range rws {
+		rw := &rws[i]
+		npruned, ngrown, nsil, ntot := 0, 0, 0, 0
+		for _, pj := range ss.PrjnsBySel(rw.Sel) {
+			sil := ss.RewireSilent[pj]
+			if sil == nil {
+				continue
+			}
+			spct := pj.Params.SWts.Init.SPct
+			smn := pj.Params.SWts.Init.Mean
+			for lni := uint32(0); lni < pj.Recv.NNeurons; lni++ {
+				var prune, pool []uint32
+				for _, syi := range pj.RecvSynIdxs(lni) {
+					if sil[syi] {
+						pool = append(pool, syi)
+					} else if axon.SynV(ctx, pj.SynStIdx+syi, axon.Wt) < rw.PruneThr {
+						prune = append(prune, syi)
+					}
+				}
+				ngrow := len(prune)
+				if ngrow > len(pool) {
+					ngrow = len(pool)
+				}
+				if ngrow > 0 {
+					for _, pi := range ss.Net.Rand.Perm(len(pool), -1)[:ngrow] {
+						syi := pool[pi]
+						sil[syi] = false
+						pj.InitWtsSyn(ctx, pj.SynStIdx+syi, &ss.Net.Rand, smn, spct)
+					}
+				}
+				for _, syi := range prune {
+					sil[syi] = true
+					SilenceSyn(ctx, pj.SynStIdx+syi)
+				}
+				npruned += len(prune)
+				ngrown += ngrow
+			}
+			for _, s := range sil {
+				if s {
+					nsil++
+				}
+			}
+			ntot += len(sil)
+		}
+		ss.AddEvent(fmt.Sprintf("Rewire %s: %d pruned, %d grown", SelName(rw.Sel), npruned, ngrown))
+		ss.Stats.SetInt(RewireStatName(rw, "NPruned"), npruned)
+		ss.Stats.SetInt(RewireStatName(rw, "NGrown"), ngrown)
+		if ntot > 0 {
+			ss.Stats.SetFloat(RewireStatName(rw, "PctSilent"), float64(nsil)/float64(ntot))
+		}
+	}
+	SyncSynRangesToGPU(ss.Net, ss.RewireSyns)
+}
+
+// InitRewireStats resets the per-epoch Rewire counts --
+// called at the start of each training epoch.
+func (ss *Sim) InitRewireStats() {
+	for i := range ss.Config.Params.Rewire {
+		rw := &ss.Config.Params.Rewire[i]
+		ss.Stats.SetInt(RewireStatName(rw, "NPruned"), 0)
+		ss.Stats.SetInt(RewireStatName(rw, "NGrown"), 0)
+	}
+}
+
+// ConfigRewireLogs adds epoch-level log items for the number of synapses
+// pruned and grown, and the proportion silent, for each Rewire projection class.
+func (ss *Sim) ConfigRewireLogs() {
+	for i := range ss.Config.Params.Rewire {
+		rw := &ss.Config.Params.Rewire[i]
+		ss.InitRewireStats()
+		ss.Stats.SetFloat(RewireStatName(rw, "PctSilent"), 0)
+		ss.Logs.AddStatIntNoAggItem(etime.Train, etime.Epoch, RewireStatName(rw, "NPruned"), RewireStatName(rw, "NGrown"))
+		ss.Logs.AddStatFloatNoAggItem(etime.Train, etime.Epoch, RewireStatName(rw, "PctSilent"))
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////
+//   LearnRule
+
+// ConfigLearnRule switches the projections selected in Config.Params.LearnRule
+// to the HipPrjn type, which supports a mix of Hebbian (CPCA) and the standard
+// error-driven learning, for learning rule ablations.  The original type name
+// is added as a class, so .ForwardPrjn and .BackPrjn params still apply,
+// and the type-specific defaults of the original type (e.g., the weaker
+// PrjnScale.Rel of BackPrjn) are carried over as DefParams.
+// Note that HipPrjn weights are updated without the SWt slow weight bounds,
+// so a pure error-driven (Hebb = 0) LearnRule entry is the proper control
+// for a Hebbian one, rather than the standard projections.
+// Must be called in ConfigNet prior to Build.
+func (ss *Sim) ConfigLearnRule() {
+	for i := range ss.Config.Params.LearnRule {
+		lr := &ss.Config.Params.LearnRule[i]
+		pjs := ss.PrjnsBySel(lr.Sel)
+		for _, pj := range pjs {
+			if pj.Typ == axon.HipPrjn {
+				continue
+			}
+			pj.AddClass(pj.PrjnTypeName())
+			LearnRuleDefParams(pj)
+			pj.Typ = axon.HipPrjn
+		}
+		mpi.Printf("LearnRule: %s: Hebb: %g, %d prjns\n", lr.Sel, lr.Hebb, len(pjs))
+	}
+}
+
+// LearnRuleDefParams adds the BackPrjn default PrjnScale.Rel of 0.1 to the
+// DefParams of given projection if it is a BackPrjn, which are applied in
+// Defaults, so it is retained when it is switched to HipPrjn
+func LearnRuleDefParams(pj *axon.Prjn) {
+	if pj.PrjnType() != axon.BackPrjn {
+		return
+	}
+	if pj.DefParams == nil {
+		pj.DefParams = params.Params{}
+	}
+	pj.DefParams["Prjn.PrjnScale.Rel"] = "0.1"
+}
+
+// ApplyLearnRule sets the Hebbian and error-driven learning proportions of
+// the LearnRule projections, and the nominal sending activity for the
+// Hebbian rule from the sending layer -- called at the end of ApplyParams
+func (ss *Sim) ApplyLearnRule() {
+	for i := range ss.Config.Params.LearnRule {
+		lr := &ss.Config.Params.LearnRule[i]
+		for _, pj := range ss.PrjnsBySel(lr.Sel) {
+			hp := &pj.Params.Hip
+			hp.Hebb = lr.Hebb
+			hp.Err = 1 - lr.Hebb
+			hp.SNominal = pj.Send.Params.Inhib.ActAvg.Nominal
+		}
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////
+//   Dropout
+
+// DropoutPrjn records a projection silenced by Dropout for the current
+// trial, with its original values to restore
+type DropoutPrjn struct {
+
+	// the projection
+	Prjn *axon.Prjn
+
+	// original GScale.Scale value
+	Scale float32
+
+	// original Learn.Learn value
+	Learn bool
+}
+
+// Dropout silences entire projections selected by Config.Params.Dropout
+// for the current training trial, each with probability P, by setting
+// the conductance scaling to 0 and turning off learning.  Because
+// the params are shared across data parallel inputs, all NData inputs
+// on the trial see the same dropout.  The projections dropped on the
+// previous trial are restored first, and the params are synced to the
+// GPU only if the set of dropped projections changes.  Called at the
+// start of each training trial.
+func (ss *Sim) Dropout() {
+	dos := ss.Config.Params.Dropout
+	if len(dos) == 0 {
+		ss.DropoutRestore()
+		return
+	}
+	ntrl := ss.Stats.Int("DropoutTrials") + 1
+	ss.Stats.SetInt("DropoutTrials", ntrl)
+	var drops []*axon.Prjn
+	has := make(map[*axon.Prjn]bool)
+	for i := range dos {
+		do := &dos[i]
+		pjs := ss.PrjnsBySel(do.Sel)
+		if do.P <= 0 || len(pjs) == 0 {
+			continue
+		}
+		drop := do.Joint && ss.Net.Rand.Float32(-1) < do.P
+		ndrop := 0
+		for _, pj := range pjs {
+			if !do.Joint {
+				drop = ss.Net.Rand.Float32(-1) < do.P
+			}
+			if !drop {
+				continue
+			}
+			if !has[pj] {
+				has[pj] = true
+				drops = append(drops, pj)
+			}
+			ndrop++
+		}
+		nm := DropoutStatName(do)
+		prv := ss.Stats.Float(nm)
+		ss.Stats.SetFloat(nm, prv+(float64(ndrop)/float64(len(pjs))-prv)/float64(ntrl))
+	}
+	if len(drops) == len(ss.Dropped) {
+		same := true
+		for _, dp := range ss.Dropped {
+			if !has[dp.Prjn] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return
+		}
+	}
+	ss.DropoutUndo()
+	for _, pj := range drops {
+		ss.Dropped = append(ss.Dropped, DropoutPrjn{Prjn: pj, Scale: pj.Params.GScale.Scale, Learn: pj.Params.Learn.Learn.IsTrue()})
+		pj.Params.GScale.Scale = 0
+		pj.Params.Learn.Learn.SetBool(false)
+	}
+	ss.Net.GPU.SyncParamsToGPU()
+}
+
+// DropoutUndo restores the param values of the projections silenced by
+// Dropout, without syncing them to the GPU, returning true if any
+func (ss *Sim) DropoutUndo() bool {
+	if len(ss.Dropped) == 0 {
+		return false
+	}
+	for _, dp := range ss.Dropped {
+		dp.Prjn.Params.GScale.Scale = dp.Scale
+		dp.Prjn.Params.Learn.Learn.SetBool(dp.Learn)
+	}
+	ss.Dropped = nil
+	return true
+}
+
+// DropoutRestore restores the projections silenced by Dropout, and syncs
+// the params to the GPU, if any -- called at the end of each training
+// epoch and at the start of each test epoch, so the full network is
+// tested.  Between training trials, Dropout itself restores them.
+func (ss *Sim) DropoutRestore() {
+	if ss.DropoutUndo() {
+		ss.Net.GPU.SyncParamsToGPU()
+	}
+}
+
+// DropoutStatName returns the name of the stat recording the
+// mean proportion of projections dropped per trial for given DropoutConfig
+func DropoutStatName(do *DropoutConfig) string {
+	return "Dropout_" + SelName(do.Sel)
+}
+
+// InitDropoutStats resets the Dropout stats -- called at
+// the start of each training epoch.
+func (ss *Sim) InitDropoutStats() {
+	ss.Stats.SetInt("DropoutTrials", 0)
+	for i := range ss.Config.Params.Dropout {
+		ss.Stats.SetFloat(DropoutStatName(&ss.Config.Params.Dropout[i]), 0)
+	}
+}
+
+// ConfigDropoutLogs adds the Dropout stats to the training epoch log
+func (ss *Sim) ConfigDropoutLogs() {
+	ss.InitDropoutStats()
+	for i := range ss.Config.Params.Dropout {
+		ss.Logs.AddStatFloatNoAggItem(etime.Train, etime.Epoch, DropoutStatName(&ss.Config.Params.Dropout[i]))
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////
+//   WtChange
+
+// WtChangeSels returns the projection selectors for the WtChange stats,
+// from Config.Log.WtChangeSels, which defaults to the first class name
+// of each projection, in network order, if empty
+func (ss *Sim) WtChangeSels() []string {
+	if len(ss.Config.Log.WtChangeSels) > 0 {
+		return ss.Config.Log.WtChangeSels
+	}
+	var sels []string
+	has := map[string]bool{}
+	for _, ly := range ss.Net.Layers {
+		for _, pj := range ly.RcvPrjns {
+			cls := strings.Fields(pj.Cls)
+			if len(cls) == 0 || has[cls[0]] {
+				continue
+			}
+			has[cls[0]] = true
+			sels = append(sels, "."+cls[0])
+		}
+	}
+	return sels
+}
+
+// WtChangeStatName returns the name of the stat recording the weight
+// change norm of the projections matching given selector: stat is
+// empty for the norm, or Rel for the norm relative to the weights
+func WtChangeStatName(sel, stat string) string {
+	return "WtChange" + stat + "_" + SelName(sel)
+}
+
+// InitWtChange records the weights of the projections selected for the
+// WtChange stats, at the start of each training epoch, if Config.Log.WtChange.
+// This keeps a copy of all the selected weights, and syncs synapses
+// from the GPU twice per epoch.
+func (ss *Sim) InitWtChange() {
+	if !ss.Config.Log.WtChange {
+		return
+	}
+	ctx := &ss.Context
+	if ss.WtChangeWts == nil {
+		ss.WtChangeWts = make(map[*axon.Prjn][]float32)
+	}
+	ss.Net.GPU.SyncSynapsesFmGPU()
+	for _, sel := range ss.WtChangeSels() {
+		for _, pj := range ss.PrjnsBySel(sel) {
+			wts := ss.WtChangeWts[pj]
+			if len(wts) != int(pj.NSyns) {
+				wts = make([]float32, pj.NSyns)
+				ss.WtChangeWts[pj] = wts
+			}
+			for syi := range wts {
+				wts[syi] = axon.SynV(ctx, pj.SynStIdx+uint32(syi), axon.Wt)
+			}
+		}
+	}
+}
+
+// WtChangeStats computes the L2 norm of the change in weights over the
+// training epoch, ||ΔW||, aggregated across the projections matching each
+// of the WtChangeSels, and the same norm relative to the norm of the
+// weights at the start of the epoch, ||ΔW|| / ||W||.
+// Called at the end of each training epoch, prior to Rewire.
+func (ss *Sim) WtChangeStats() {
+	if !ss.Config.Log.WtChange || len(ss.WtChangeWts) == 0 {
+		return
+	}
+	ctx := &ss.Context
+	ss.Net.GPU.SyncSynapsesFmGPU()
+	for _, sel := range ss.WtChangeSels() {
+		sumDSq := 0.0
+		sumSq := 0.0
+		for _, pj := range ss.PrjnsBySel(sel) {
+			wts := ss.WtChangeWts[pj]
+			if len(wts) != int(pj.NSyns) {
+				continue
+			}
+			for syi, pwt := range wts {
+				d := float64(axon.SynV(ctx, pj.SynStIdx+uint32(syi), axon.Wt) - pwt)
+				sumDSq += d * d
+				sumSq += float64(pwt) * float64(pwt)
+			}
+		}
+		nrm := math.Sqrt(sumDSq)
+		rel := 0.0
+		if sumSq > 0 {
+			rel = nrm / math.Sqrt(sumSq)
+		}
+		ss.Stats.SetFloat(WtChangeStatName(sel, ""), nrm)
+		ss.Stats.SetFloat(WtChangeStatName(sel, "Rel"), rel)
+	}
+}
+
+// ConfigWtChangeLogs adds epoch-level log items for the weight change
+// norm, absolute and relative, of each WtChange projection class.
+func (ss *Sim) ConfigWtChangeLogs() {
+	if !ss.Config.Log.WtChange {
+		return
+	}
+	for _, sel := range ss.WtChangeSels() {
+		ss.Stats.SetFloat(WtChangeStatName(sel, ""), 0)
+		ss.Stats.SetFloat(WtChangeStatName(sel, "Rel"), 0)
+		ss.Logs.AddStatFloatNoAggItem(etime.Train, etime.Epoch, WtChangeStatName(sel, ""), WtChangeStatName(sel, "Rel"))
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////
+//   Grow
+
+// GrowPrjn records a projection that is not yet grown per
+// Config.Params.Grow, with its original values to restore
+type GrowPrjn struct {
+
+	// original PrjnScale.Abs value
+	Abs float32
+
+	// original Learn.Learn value
+	Learn bool
+}
+
+// GrowEpoch returns the current training epoch for Grow,
+// 0 before the loops are configured
+func (ss *Sim) GrowEpoch() int {
+	if ss.Loops == nil {
+		return 0
+	}
+	return ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur
+}
+
+// GrowSched sets the state of the projections selected by
+// Config.Params.Grow for the current training epoch: projections are
+// disabled, with PrjnScale.Abs = 0 and no learning, prior to their
+// Epoch, and restored to their params at the start of it.  The state
+// only depends on the epoch, so it is correct when starting from any
+// epoch, e.g., when resuming from saved weights.  Called at the start
+// of each training epoch and run.
+func (ss *Sim) GrowSched() {
+	gcs := ss.Config.Params.Grow
+	if len(gcs) == 0 {
+		return
+	}
+	if ss.GrowOff == nil {
+		ss.GrowOff = make(map[*axon.Prjn]GrowPrjn)
+	}
+	ss.DropoutRestore() // dropout would restore stale values
+	epc := ss.GrowEpoch()
+	chg := false
+	for i := range gcs {
+		gc := &gcs[i]
+		off := epc < gc.Epoch
+		grown := false
+		for _, pj := range ss.PrjnsBySel(gc.Sel) {
+			gp, has := ss.GrowOff[pj]
+			switch {
+			case off && !has:
+				ss.GrowOff[pj] = GrowPrjn{Abs: pj.Params.PrjnScale.Abs, Learn: pj.Params.Learn.Learn.IsTrue()}
+				pj.Params.PrjnScale.Abs = 0
+				pj.Params.Learn.Learn.SetBool(false)
+				chg = true
+			case !off && has:
+				pj.Params.PrjnScale.Abs = gp.Abs
+				pj.Params.Learn.Learn.SetBool(gp.Learn)
+				delete(ss.GrowOff, pj)
+				chg = true
+				grown = true
+			}
+		}
+		if grown {
+			ss.AddEvent(fmt.Sprintf("Grow: %s", gc.Sel))
+		}
+	}
+	if !chg {
+		return
+	}
+	ss.Net.InitGScale(&ss.Context)
+	ss.Net.GPU.SyncParamsToGPU()
+}
+
+// ApplyGrow re-applies the Grow state after the params have been
+// re-applied, recording the new params to restore -- called at the end
+// of ApplyParams
+func (ss *Sim) ApplyGrow() {
+	ss.GrowOff = nil
+	ss.GrowSched()
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+)
+
+// logcols.go has the trial log files with a selection of the log columns,
+// per Config.Log.TrialCols and TestTrialCols, so that cluster runs can keep
+// slim trial logs (e.g., just the category, response and error) instead of
+// writing every trial-level item.  The selected columns are looked up by
+// name in the trial log table on each write, as the MPI gathering of the
+// trial rows swaps the underlying table.
+
+// TrialLogFile is a trial log file with a selection of the log columns
+type TrialLogFile struct {
+
+	// the open file
+	File *os.File
+
+	// names of the columns to write
+	Cols []string
+
+	// table with the selected columns of the log table, for writing
+	Table etable.Table
+
+	// true if the headers have been written
+	WroteHeaders bool
+}
+
+// SetTrialLogFile opens the trial log file of given name for given mode,
+// with given column names, or all columns if empty, as the standard
+// log file.  Column names not in the log are skipped, with a warning.
+func (ss *Sim) SetTrialLogFile(mode etime.Modes, fnm string, cols []string) {
+	if len(cols) == 0 {
+		ss.Logs.SetLogFile(mode, etime.Trial, fnm)
+		return
+	}
+	dt := ss.Logs.Table(mode, etime.Trial)
+	var has []string
+	for _, cn := range cols {
+		if dt.ColByName(cn) == nil {
+			mpi.Printf("%s trial log: column %s not found\n", mode, cn)
+			continue
+		}
+		has = append(has, cn)
+	}
+	f, err := os.Create(fnm)
+	if err != nil {
+		mpi.Println(err)
+		return
+	}
+	if ss.TrialFiles == nil {
+		ss.TrialFiles = make(map[etime.Modes]*TrialLogFile)
+	}
+	ss.TrialFiles[mode] = &TrialLogFile{File: f, Cols: has}
+	mpi.Printf("Saving log to: %s with %d columns\n", fnm, len(has))
+}
+
+// WriteTrialLogRow writes the last row of the trial log for given mode
+// to its trial log file, if open -- called after each trial is logged
+func (ss *Sim) WriteTrialLogRow(mode etime.Modes) {
+	tf := ss.TrialFiles[mode]
+	if tf == nil {
+		return
+	}
+	dt := ss.Logs.Table(mode, etime.Trial)
+	st := &tf.Table
+	st.Cols = st.Cols[:0]
+	for _, cn := range tf.Cols {
+		st.Cols = append(st.Cols, dt.ColByName(cn))
+	}
+	st.ColNames = tf.Cols
+	st.Rows = dt.Rows
+	if !tf.WroteHeaders {
+		st.WriteCSVHeaders(tf.File, etable.Tab)
+		tf.WroteHeaders = true
+	}
+	st.WriteCSVRow(tf.File, dt.Rows-1, etable.Tab)
+}
+
+// CloseLogFiles closes all the open log files,
+// including the trial log files with selected columns
+func (ss *Sim) CloseLogFiles() {
+	ss.Logs.CloseLogFiles()
+	for mode, tf := range ss.TrialFiles {
+		tf.File.Close()
+		delete(ss.TrialFiles, mode)
+	}
+}
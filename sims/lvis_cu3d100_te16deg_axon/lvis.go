@@ -12,8 +12,12 @@ package main
 
 import (
 	"fmt"
+	"hash/fnv"
 	"log"
+	"math/rand"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/emer/axon/axon"
 	"github.com/emer/emergent/decoder"
@@ -44,7 +48,6 @@ import (
 	"github.com/goki/gi/gimain"
 	"github.com/goki/gi/giv"
 	"github.com/goki/ki/ki"
-	"github.com/goki/mat32"
 )
 
 func main() {
@@ -97,6 +100,9 @@ type Sim struct {
 	// decoder for better output
 	Decoder decoder.SoftMax `desc:"decoder for better output"`
 
+	// [view: -] additional decoders, with different hyperparameters -- see Config.Run.Decoders
+	Decoders []*decoder.SoftMax `view:"-" desc:"additional decoders, with different hyperparameters -- see Config.Run.Decoders"`
+
 	// special projections -- see config.go
 	Prjns Prjns `desc:"special projections -- see config.go "`
 
@@ -106,22 +112,175 @@ type Sim struct {
 	// [view: -] a list of random seeds to use for each run
 	RndSeeds erand.Seeds `view:"-" desc:"a list of random seeds to use for each run"`
 
+	// [view: -] random seeds of each component for the current run, by SeedComps name -- see Config.Run.Seed
+	Seeds map[string]int64 `view:"-" desc:"random seeds of each component for the current run, by SeedComps name -- see Config.Run.Seed"`
+
 	// [view: -] mpi communicator
 	Comm *mpi.Comm `view:"-" desc:"mpi communicator"`
 
 	// [view: -] buffer of all dwt weight changes -- for mpi sharing
 	AllDWts []float32 `view:"-" desc:"buffer of all dwt weight changes -- for mpi sharing"`
+
+	// [view: -] pairs of tied 16 and 8 deg projections, with averaged DWts -- see Config.Params.ShareWts
+	SharedPrjns [][2]*axon.Prjn `view:"-" desc:"pairs of tied 16 and 8 deg projections, with averaged DWts -- see Config.Params.ShareWts"`
+
+	// [view: -] map of the trainable projection synapses into AllDWts -- only these are shared over mpi
+	DWtMap DWtMap `view:"-" desc:"map of the trainable projection synapses into AllDWts -- only these are shared over mpi"`
+
+	// [view: -] silent synapse flags for projections with structural plasticity, per synapse in the projection -- see Config.Params.Rewire
+	RewireSilent map[*axon.Prjn][]bool `view:"-" desc:"silent synapse flags for projections with structural plasticity, per synapse in the projection -- see Config.Params.Rewire"`
+
+	// [view: -] ranges of the network Synapses array with the RewireMaskVars of the Rewire projections, synced with the GPU by RewireMask
+	RewireMaskSyns [][2]uint32 `view:"-" desc:"ranges of the network Synapses array with the RewireMaskVars of the Rewire projections, synced with the GPU by RewireMask"`
+
+	// [view: -] ranges of the network Synapses array with all the variables of the Rewire projections, synced with the GPU by Rewire
+	RewireSyns [][2]uint32 `view:"-" desc:"ranges of the network Synapses array with all the variables of the Rewire projections, synced with the GPU by Rewire"`
+
+	// [view: -] projections silenced by Dropout on the current training trial -- see Config.Params.Dropout
+	Dropped []DropoutPrjn `view:"-" desc:"projections silenced by Dropout on the current training trial -- see Config.Params.Dropout"`
+
+	// [view: -] target image for each data index, for the priming paradigm
+	PrimeTargs []string `view:"-" desc:"target image for each data index, for the priming paradigm"`
+
+	// [view: -] category prototype accumulators for test epoch activity, per layer -- see ProtoStats
+	Protos map[string]*CatProtos `view:"-" desc:"category prototype accumulators for test epoch activity, per layer -- see ProtoStats"`
+
+	// [view: -] activity accumulators for the sparseness stats, per layer -- see SparseStats
+	Sparse map[string]*SparseAcc `view:"-" desc:"activity accumulators for the sparseness stats, per layer -- see SparseStats"`
+
+	// [view: -] layer representations of test items per category, for the odd-one-out evaluation -- see Config.Run.OddOneOut
+	OddOneOut OddOneOutReps `view:"-" desc:"layer representations of test items per category, for the odd-one-out evaluation -- see Config.Run.OddOneOut"`
+
+	// [view: -] category activity accumulators for the split-half reliability stats -- see Config.Run.SplitHalf
+	SplitHalf SplitHalf `view:"-" desc:"category activity accumulators for the split-half reliability stats -- see Config.Run.SplitHalf"`
+
+	// [view: -] activity patterns stored over the training epoch for the replay phase -- see Config.Run.Replay
+	Replay Replay `view:"-" desc:"activity patterns stored over the training epoch for the replay phase -- see Config.Run.Replay"`
+
+	// [view: no-inline] GUI compare two layers representational similarity analysis, updated during Test stepping
+	RSA RSACompare `view:"no-inline" desc:"GUI compare two layers representational similarity analysis, updated during Test stepping"`
+
+	// [view: no-inline] GUI unit tuning curve across test categories, updated at the end of each test epoch
+	Tuning TuningCurve `view:"no-inline" desc:"GUI unit tuning curve across test categories, updated at the end of each test epoch"`
+
+	// [view: no-inline] GUI parameter search launcher, running the combinations of a parameter grid as nogui subprocesses
+	Launcher Launcher `view:"no-inline" desc:"GUI parameter search launcher, running the combinations of a parameter grid as nogui subprocesses"`
+
+	// [view: -] response accumulators for the receptive field size analysis -- see Config.Run.RFSize
+	RFSize RFSize `view:"-" desc:"response accumulators for the receptive field size analysis -- see Config.Run.RFSize"`
+
+	// [view: -] per-image training difficulty stats, accumulated across epochs -- see Config.Run.Difficulty
+	Difficulty ImgDifficulty `view:"-" desc:"per-image training difficulty stats, accumulated across epochs -- see Config.Run.Difficulty"`
+
+	// [view: -] allocation of the Config.Run.NTrials per epoch across MPI procs and NData
+	Trials TrialsAllocator `view:"-" desc:"allocation of the Config.Run.NTrials per epoch across MPI procs and NData"`
+
+	// [view: -] object pose parsing from image file names -- see Config.Env.Pose
+	Poses Poses `view:"-" desc:"object pose parsing from image file names -- see Config.Env.Pose"`
+
+	// [view: -] test error vs. object pose stats, per test epoch -- see Config.Env.Pose
+	PoseStats PoseStats `view:"-" desc:"test error vs. object pose stats, per test epoch -- see Config.Env.Pose"`
+
+	// [view: -] state of the same / different task -- see Config.Env.SameDiff
+	SameDiff SameDiff `view:"-" desc:"state of the same / different task -- see Config.Env.SameDiff"`
+
+	// [view: -] state of the identity output head -- see Config.Env.Id
+	Identity Identity `view:"-" desc:"state of the identity output head -- see Config.Env.Id"`
+
+	// [view: -] weights files saved during the current run, for the Config.Log.Wts* retention policy
+	WtsSaved WtsSaved `view:"-" desc:"weights files saved during the current run, for the Config.Log.Wts* retention policy"`
+
+	// [view: -] true if the Output layer is currently clamped as an input for pretraining, per Config.Run.OutClampEpochs
+	OutClamp bool `view:"-" desc:"true if the Output layer is currently clamped as an input for pretraining, per Config.Run.OutClampEpochs"`
+
+	// [view: -] current number of data-parallel trials per training step, per the Config.Run.NDataEpochs schedule
+	NDataTrain int `view:"-" desc:"current number of data-parallel trials per training step, per the Config.Run.NDataEpochs schedule"`
+
+	// [view: -] current values of the Config.Params.Schedule params, NaN if not yet applied
+	SchedVals []float32 `view:"-" desc:"current values of the Config.Params.Schedule params, NaN if not yet applied"`
+
+	// [view: -] base values of the scheduled training env params, by path, for restoring at the start of each run
+	SchedEnvBase map[string]any `view:"-" desc:"base values of the scheduled training env params, by path, for restoring at the start of each run"`
+
+	// [view: -] linear reconstruction decoder of the V1 input from hidden layers -- see Config.Run.Recon
+	Recon Recon `view:"-" desc:"linear reconstruction decoder of the V1 input from hidden layers -- see Config.Run.Recon"`
+
+	// [view: -] CNN baseline trained on the same V1 inputs -- see Config.Run.Baseline
+	Baseline Baseline `view:"-" desc:"CNN baseline trained on the same V1 inputs -- see Config.Run.Baseline"`
+
+	// [view: -] input drift monitor state -- see Config.Run.InDrift
+	InDrift InDrift `view:"-" desc:"input drift monitor state -- see Config.Run.InDrift"`
+
+	// [view: -] movie of layer activity over the cycles of a test trial -- see Config.Log.Movie
+	Movie Movie `view:"-" desc:"movie of layer activity over the cycles of a test trial -- see Config.Log.Movie"`
+
+	// [view: -] snapshot images of layer activity in testing trials -- see Config.Log.Snapshot
+	Snapshot Snapshot `view:"-" desc:"snapshot images of layer activity in testing trials -- see Config.Log.Snapshot"`
+
+	// [view: -] number of training images per category, all and used, if subsampled per Config.Env.TrainFrac
+	TrainCounts *etable.Table `view:"-" desc:"number of training images per category, all and used, if subsampled per Config.Env.TrainFrac"`
+
+	// [view: -] experiment tracking backend, on MPI rank 0, if Config.Log.Track.Type is set
+	Tracker Tracker `view:"-" desc:"experiment tracking backend, on MPI rank 0, if Config.Log.Track.Type is set"`
+
+	// [view: -] trial trigger outputs -- see Config.Log.Trigger
+	Trigger Trigger `view:"-" desc:"trial trigger outputs -- see Config.Log.Trigger"`
+
+	// [view: -] trial event stream outputs -- see Config.Log.Events
+	Events Events `view:"-" desc:"trial event stream outputs -- see Config.Log.Events"`
+
+	// [view: -] profiling state -- see Config.Log.Profile
+	Profile Profile `view:"-" desc:"profiling state -- see Config.Log.Profile"`
+
+	// [view: -] layer clamping intervention state -- see SetClamp
+	Clamp Clamp `view:"-" desc:"layer clamping intervention state -- see SetClamp"`
+
+	// [view: -] per-rank timing of env, cycles, and allreduce, saved in Bench mode
+	BenchTimes BenchTimes `view:"-" desc:"per-rank timing of env, cycles, and allreduce, saved in Bench mode"`
+
+	// [view: -] trial log files with selected columns -- see Config.Log.TrialCols
+	TrialFiles map[etime.Modes]*TrialLogFile `view:"-" desc:"trial log files with selected columns -- see Config.Log.TrialCols"`
+
+	// [view: -] signal handling and resume state -- see Config.Run.Resume
+	Signal SignalState `view:"-" desc:"signal handling and resume state -- see Config.Run.Resume"`
+
+	// [view: -] index of the next NetData file in the on-disk ring buffer -- see Config.Log.NetData
+	NetDataFile int `view:"-" desc:"index of the next NetData file in the on-disk ring buffer -- see Config.Log.NetData"`
+
+	// [view: -] projections that are not yet grown, with their params to restore -- see Config.Params.Grow
+	GrowOff map[*axon.Prjn]GrowPrjn `view:"-" desc:"projections that are not yet grown, with their params to restore -- see Config.Params.Grow"`
+
+	// [view: -] weights at the start of the training epoch, per synapse in the projection, for the WtChange stats -- see Config.Log.WtChange
+	WtChangeWts map[*axon.Prjn][]float32 `view:"-" desc:"weights at the start of the training epoch, per synapse in the projection, for the WtChange stats -- see Config.Log.WtChange"`
+
+	// [view: -] fast accumulators for the ActRFs, during testing
+	FastRFs []*FastRF `view:"-" desc:"fast accumulators for the ActRFs, during testing"`
+
+	// [view: -] copy of the test image for each data index, as the ActRF source
+	ActRFImgs [][]float32 `view:"-" desc:"copy of the test image for each data index, as the ActRF source"`
+
+	// [view: -] mpi communicator for the entire world, when Comm is for a search color
+	WorldComm *mpi.Comm `view:"-" desc:"mpi communicator for the entire world, when Comm is for a search color"`
+
+	// index of the search configuration (MPI color) that this proc is running, when running a search
+	SearchColor int `inactive:"+" desc:"index of the search configuration (MPI color) that this proc is running, when running a search"`
+
+	// [view: -] command-line args for each search configuration, from Config.Run.Search
+	SearchArgs []string `view:"-" desc:"command-line args for each search configuration, from Config.Run.Search"`
 }
 
 // New creates new blank elements and initializes defaults
 func (ss *Sim) New() {
 	ss.Config.Defaults()
 	ss.Prjns.Defaults()
+	ss.RSA.Defaults()
+	ss.Tuning.Defaults()
+	ss.Launcher.Defaults()
 	econfig.Config(&ss.Config, "config.toml")
 	if ss.Config.Run.MPI {
 		ss.MPIInit()
 	}
-	if mpi.WorldRank() != 0 {
+	if ss.MPIRank() != 0 {
 		ss.Config.Log.SaveWts = false
 		ss.Config.Log.NetData = false
 	}
@@ -148,6 +307,14 @@ func (ss *Sim) New() {
 func (ss *Sim) ConfigAll() {
 	ss.ConfigEnv()
 	ss.ConfigNet(ss.Net)
+	ss.ConfigMovie()
+	ss.ConfigSnapshot()
+	ss.ConfigRecon()
+	ss.ConfigBaseline()
+	ss.ConfigInDrift()
+	ss.ConfigTracker()
+	ss.ConfigTrigger()
+	ss.ConfigEvents()
 	ss.ConfigLogs()
 	ss.ConfigLoops()
 	if ss.Config.Params.SaveAll {
@@ -158,6 +325,12 @@ func (ss *Sim) ConfigAll() {
 }
 
 func (ss *Sim) ConfigEnv() {
+	if !ss.IsImagesEnv() {
+		if err := ss.ConfigCustomEnv(); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
 	// Can be called multiple times -- don't re-create
 	var trn, tst *ImagesEnv
 	if len(ss.Envs) == 0 {
@@ -168,35 +341,66 @@ func (ss *Sim) ConfigEnv() {
 		tst = ss.Envs.ByMode(etime.Test).(*ImagesEnv)
 	}
 
-	plus := false // plus images are a bit worse overall -- stranger objects etc.
-
-	var path string
-	if plus {
-		path = "images/CU3D_100_plus_renders"
-		trn.ImageFile = "cu3d100plus"
-	} else {
-		path = "images/CU3D_100_renders_lr20_u30_nb"
-		trn.ImageFile = "cu3d100old"
-	}
+	path := ss.Config.Env.Path
+	trn.ImageFile = ss.Config.Env.ImageFile
 
 	trn.Nm = etime.Train.String()
 	trn.Dsc = "training params and state"
 	trn.Defaults()
-	trn.RndSeed = 73
+	trn.RndSeed = ss.RunSeed(0, "TrainEnv")
 	trn.NOutPer = ss.Config.Env.NOutPer
-	trn.High16 = false // not useful -- may need more tuning?
+	trn.OutLayout = ss.Config.Env.OutLayout
+	trn.OutSigma = ss.Config.Env.OutSigma
+	trn.IllumTempMax = ss.Config.Env.Illum.TempMax
+	trn.IllumGain.Set(ss.Config.Env.Illum.GainMin, ss.Config.Env.Illum.GainMax)
+	trn.Aspect = ss.Config.Env.Aspect
+	trn.InputNorm = ss.Config.Env.Norm.Method
+	trn.NormMean = ss.Config.Env.Norm.Mean
+	trn.NormSD = ss.Config.Env.Norm.SD
+	trn.NormContrast = ss.Config.Env.Norm.Contrast
+	trn.High16 = ss.Config.Env.High16
 	trn.ColorDoG = true
 	trn.Images.NTestPerCat = 2
 	trn.Images.SplitByItm = true
 	tst.OutRandom = ss.Config.Env.RndOutPats
+	trn.RndPatsCheck = ss.Config.Env.RndPatsCheck
 	trn.OutSize.Set(10, 10)
-	trn.Images.SetPath(path, []string{".png"}, "_")
-	trn.OpenConfig()
+	trn.Images.SplitSeed = ss.Config.Env.SplitSeed
+	trn.CheckFiles = ss.Config.Env.CheckFiles
+	trn.Images.SetPath(path, ImageExts, "_")
+	trn.Images.CatRename = nil
+	if ss.Config.Env.CatRename != "" {
+		rn, err := OpenCatRename(ss.Config.Env.CatRename)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		trn.Images.CatRename = rn
+	}
+	if ss.SplitRoot() {
+		newSplit := ss.Config.Env.NewSplit || !trn.OpenConfig()
+		if newSplit {
+			mpi.Printf("Generating new train / test split from: %s with seed: %d\n", path, trn.Images.SplitSeed)
+			trn.Images.OpenPath(path, ImageExts, "_")
+		}
+		reorder, err := trn.ApplyCatMap()
+		if err != nil {
+			mpi.Println(err)
+		}
+		if newSplit || reorder {
+			trn.SaveConfig()
+		}
+	}
+	var split []byte // split from rank 0, under MPI
+	if ss.Config.Run.MPI {
+		var err error
+		if split, err = ss.BcastSplit(trn); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	ss.ConfigV1Color(trn)
 	if ss.Config.Env.Env != nil {
 		params.ApplyMap(trn, ss.Config.Env.Env, ss.Config.Debug)
 	}
-	// trn.Images.OpenPath(path, []string{".png"}, "_")
-	// trn.SaveConfig()
 
 	trn.Validate()
 	trn.Trial.Max = ss.Config.Run.NTrials
@@ -205,24 +409,47 @@ func (ss *Sim) ConfigEnv() {
 	tst.Dsc = "testing params and state"
 	tst.ImageFile = trn.ImageFile
 	tst.Defaults()
-	tst.RndSeed = 73
+	tst.RndSeed = ss.RunSeed(0, "TestEnv")
 	trn.NOutPer = ss.Config.Env.NOutPer
+	tst.OutLayout = trn.OutLayout
+	tst.OutSigma = trn.OutSigma
+	tst.Aspect = trn.Aspect
+	tst.InputNorm = trn.InputNorm
+	tst.NormMean = trn.NormMean
+	tst.NormSD = trn.NormSD
+	tst.NormContrast = trn.NormContrast
 	tst.High16 = trn.High16
 	tst.ColorDoG = trn.ColorDoG
 	tst.Images.NTestPerCat = 2
 	tst.Images.SplitByItm = true
 	tst.OutRandom = ss.Config.Env.RndOutPats
+	tst.RndPatsCheck = trn.RndPatsCheck
 	tst.OutSize.Set(10, 10)
 	tst.Test = true
-	tst.Images.SetPath(path, []string{".png"}, "_")
-	tst.OpenConfig()
-	// tst.Images.OpenPath(path, []string{".png"}, "_")
-	// tst.SaveConfig()
+	tst.Images.SplitSeed = trn.Images.SplitSeed
+	tst.Images.SetPath(path, ImageExts, "_")
+	tst.Images.CatRename = trn.Images.CatRename
+	if split != nil {
+		if err := tst.SetSplitJSON(split); err != nil {
+			log.Fatalln(err)
+		}
+	} else {
+		tst.OpenConfig()
+	}
 	tst.Trial.Max = ss.Config.Run.NTrials
+	ss.ConfigV1Color(tst)
 	if ss.Config.Env.Env != nil {
 		params.ApplyMap(tst, ss.Config.Env.Env, ss.Config.Debug)
 	}
 	tst.Validate()
+	if ss.Config.Env.V1Color.Stats {
+		mpi.Printf("%s", V1ColorReport(trn))
+	}
+	mpi.Printf("%s", InputNormReport(trn))
+	if ss.Net.MetaData == nil {
+		ss.Net.MetaData = map[string]string{}
+	}
+	ss.Net.MetaData["InputNorm"] = trn.InputNorm
 
 	/*
 		// Delete to 60
@@ -248,47 +475,80 @@ func (ss *Sim) ConfigEnv() {
 	confuse := []string{"blade", "flashlight", "pckeyboard", "scissors", "screwdriver", "submarine"}
 	trn.Images.DeleteCats(confuse)
 	tst.Images.DeleteCats(confuse)
+	ss.ApplyTrainFrac(trn)
+
+	trn.CatFreq, trn.CatTarg = nil, nil
+	if ss.Config.Env.CatWeights != "" {
+		if err := trn.OpenCatWeights(ss.Config.Env.CatWeights); err != nil {
+			log.Println(err)
+		}
+	}
+	if ss.Config.Env.CatFreqByN {
+		trn.CatFreqByN()
+	}
+	trn.OutEmbed = ss.Config.Env.OutEmbed
+	tst.OutEmbed = ss.Config.Env.OutEmbed
+	if trn.OutEmbed != "" {
+		if err := trn.OpenEmbeds(); err != nil {
+			log.Fatalln(err)
+		}
+		tst.Embeds = trn.Embeds
+	}
 
 	if ss.Config.Run.MPI {
 		if ss.Config.Debug {
 			mpi.Printf("Did Env MPIAlloc\n")
 		}
-		trn.MPIAlloc()
-		tst.MPIAlloc()
+		trn.MPIAlloc(ss.MPIRank(), ss.MPISize())
+		tst.MPIAlloc(ss.MPIRank(), ss.MPISize())
 	}
 
 	trn.Init(0)
 	tst.Init(0)
 
 	ss.Envs.Add(trn, tst)
+	ss.ConfigPoses()
+	ss.ConfigSameDiff()
+	ss.ConfigIdentity()
 }
 
 func (ss *Sim) ConfigNet(net *axon.Network) {
 	ctx := &ss.Context
 	net.InitName(net, "Lvis")
 	net.SetMaxData(ctx, ss.Config.Run.NData)
-	net.SetRndSeed(ss.RndSeeds[0]) // init new separate random seed, using run = 0
-
-	trn := ss.Envs.ByMode(etime.Train).(*ImagesEnv)
+	net.SetRndSeed(ss.RunSeed(0, "Net")) // init new separate random seed, using run = 0
 
 	v1nrows := 5
-	if trn.V1m16.SepColor {
-		v1nrows += 4
+	hi16 := ss.Config.Env.High16
+	cdog := true
+	if trn := ss.ImagesEnv(etime.Train); trn != nil {
+		if trn.V1m16.SepColor {
+			v1nrows += 4
+		}
+		hi16 = trn.High16
+		cdog = trn.ColorDoG
+	}
+	outY, outX := 10, 10*ss.Config.Env.NOutPer
+	if osh := ss.LvisEnv(etime.Train).State("Output"); osh != nil && osh.NumDims() == 2 {
+		outY, outX = osh.Dim(0), osh.Dim(1) // Output shape after Init, per output patterns
 	}
-	hi16 := trn.High16
-	cdog := trn.ColorDoG
 
+	nsz, err := ss.NetSize()
+	if err != nil {
+		log.Fatalln(err)
+	}
 	v2mNp := 8
 	v2lNp := 4
-	v2Nu := 8
+	v2hNp := 16
+	v3hNp := 8
 	v4Np := 4
-	v4Nu := 10
+	v2Nu, v4Nu := nsz.V2V4Nu(ss.Config.Params.SubPools)
 	if ss.Config.Params.SubPools {
 		v2mNp *= 2
 		v2lNp *= 2
-		v2Nu = 6
+		v2hNp *= 2
+		v3hNp *= 2
 		v4Np = 8
-		v4Nu = 7
 	}
 
 	v1m16 := net.AddLayer4D("V1m16", 16, 16, v1nrows, 4, axon.InputLayer)
@@ -300,10 +560,10 @@ func (ss *Sim) ConfigNet(net *axon.Network) {
 	v1m8.SetClass("V1m")
 	v1l8.SetClass("V1l")
 
-	v1m16.SetRepIdxsShape(ss.CenterPoolIdxs(v1m16, 2), emer.CenterPoolShape(v1m16, 2))
-	v1l16.SetRepIdxsShape(ss.CenterPoolIdxs(v1l16, 2), emer.CenterPoolShape(v1l16, 2))
-	v1m8.SetRepIdxsShape(ss.CenterPoolIdxs(v1m8, 2), emer.CenterPoolShape(v1m8, 2))
-	v1l8.SetRepIdxsShape(ss.CenterPoolIdxs(v1l8, 2), emer.CenterPoolShape(v1l8, 2))
+	ss.SetRepIdxs(v1m16)
+	ss.SetRepIdxs(v1l16)
+	ss.SetRepIdxs(v1m8)
+	ss.SetRepIdxs(v1l8)
 
 	// not useful so far..
 	// clst := net.AddLayer2D("Claustrum", 5, 5, axon.SuperLayer)
@@ -319,10 +579,10 @@ func (ss *Sim) ConfigNet(net *axon.Network) {
 		v1cm8.SetClass("V1Cm")
 		v1cl8.SetClass("V1Cl")
 
-		v1cm16.SetRepIdxsShape(ss.CenterPoolIdxs(v1cm16, 2), emer.CenterPoolShape(v1cm16, 2))
-		v1cl16.SetRepIdxsShape(ss.CenterPoolIdxs(v1cl16, 2), emer.CenterPoolShape(v1cl16, 2))
-		v1cm8.SetRepIdxsShape(ss.CenterPoolIdxs(v1cm8, 2), emer.CenterPoolShape(v1cm8, 2))
-		v1cl8.SetRepIdxsShape(ss.CenterPoolIdxs(v1cl8, 2), emer.CenterPoolShape(v1cl8, 2))
+		ss.SetRepIdxs(v1cm16)
+		ss.SetRepIdxs(v1cl16)
+		ss.SetRepIdxs(v1cm8)
+		ss.SetRepIdxs(v1cl8)
 	}
 
 	v2m16 := net.AddLayer4D("V2m16", v2mNp, v2mNp, v2Nu, v2Nu, axon.SuperLayer)
@@ -334,23 +594,23 @@ func (ss *Sim) ConfigNet(net *axon.Network) {
 	v2m8.SetClass("V2m V2")
 	v2l8.SetClass("V2l V2")
 
-	v2m16.SetRepIdxsShape(ss.CenterPoolIdxs(v2m16, 2), emer.CenterPoolShape(v2m16, 2))
-	v2l16.SetRepIdxsShape(ss.CenterPoolIdxs(v2l16, 2), emer.CenterPoolShape(v2l16, 2))
-	v2m8.SetRepIdxsShape(ss.CenterPoolIdxs(v2m8, 2), emer.CenterPoolShape(v2m8, 2))
-	v2l8.SetRepIdxsShape(ss.CenterPoolIdxs(v2l8, 2), emer.CenterPoolShape(v2l8, 2))
+	ss.SetRepIdxs(v2m16)
+	ss.SetRepIdxs(v2l16)
+	ss.SetRepIdxs(v2m8)
+	ss.SetRepIdxs(v2l8)
 
 	var v1h16, v2h16, v3h16 *axon.Layer
 	if hi16 {
-		v1h16 = net.AddLayer4D("V1h16", 32, 32, 5, 4, axon.InputLayer)
-		v2h16 = net.AddLayer4D("V2h16", 32, 32, v2Nu, v2Nu, axon.SuperLayer)
-		v3h16 = net.AddLayer4D("V3h16", 16, 16, v2Nu, v2Nu, axon.SuperLayer)
+		v1h16 = net.AddLayer4D("V1h16", 32, 32, v1nrows, 4, axon.InputLayer)
+		v2h16 = net.AddLayer4D("V2h16", v2hNp, v2hNp, v2Nu, v2Nu, axon.SuperLayer)
+		v3h16 = net.AddLayer4D("V3h16", v3hNp, v3hNp, v2Nu, v2Nu, axon.SuperLayer)
 		v1h16.SetClass("V1h")
 		v2h16.SetClass("V2h V2")
 		v3h16.SetClass("V3h")
 
-		v1h16.SetRepIdxsShape(ss.CenterPoolIdxs(v1h16, 2), emer.CenterPoolShape(v1h16, 2))
-		v2h16.SetRepIdxsShape(ss.CenterPoolIdxs(v2h16, 2), emer.CenterPoolShape(v2h16, 2))
-		v3h16.SetRepIdxsShape(ss.CenterPoolIdxs(v3h16, 2), emer.CenterPoolShape(v3h16, 2))
+		ss.SetRepIdxs(v1h16)
+		ss.SetRepIdxs(v2h16)
+		ss.SetRepIdxs(v3h16)
 	}
 
 	v4f16 := net.AddLayer4D("V4f16", v4Np, v4Np, v4Nu, v4Nu, axon.SuperLayer)
@@ -358,24 +618,19 @@ func (ss *Sim) ConfigNet(net *axon.Network) {
 	v4f16.SetClass("V4")
 	v4f8.SetClass("V4")
 
-	v4f16.SetRepIdxsShape(ss.CenterPoolIdxs(v4f16, 2), emer.CenterPoolShape(v4f16, 2))
-	v4f8.SetRepIdxsShape(ss.CenterPoolIdxs(v4f8, 2), emer.CenterPoolShape(v4f8, 2))
+	ss.SetRepIdxs(v4f16)
+	ss.SetRepIdxs(v4f8)
 
-	teo16 := net.AddLayer4D("TEOf16", 2, 2, 15, 15, axon.SuperLayer)
-	teo8 := net.AddLayer4D("TEOf8", 2, 2, 15, 15, axon.SuperLayer)
+	teo16 := net.AddLayer4D("TEOf16", nsz.TEONp, nsz.TEONp, nsz.TEONu, nsz.TEONu, axon.SuperLayer)
+	teo8 := net.AddLayer4D("TEOf8", nsz.TEONp, nsz.TEONp, nsz.TEONu, nsz.TEONu, axon.SuperLayer)
 	teo16.SetClass("TEO")
 	teo8.SetClass("TEO")
 
-	te := net.AddLayer4D("TE", 2, 2, 15, 15, axon.SuperLayer)
+	te := net.AddLayer4D("TE", nsz.TENp, nsz.TENp, nsz.TENu, nsz.TENu, axon.SuperLayer)
 
-	var out *axon.Layer
-	if ss.Config.Env.RndOutPats {
-		out = net.AddLayer2D("Output", trn.OutSize.Y, trn.OutSize.X, axon.TargetLayer)
-	} else {
-		// out = net.AddLayer4D("Output", trn.OutSize.Y, trn.OutSize.X, trn.NOutPer, 1, axon.TargetLayer)
-		// 2D layer:
-		out = net.AddLayer2D("Output", trn.OutSize.Y, trn.OutSize.X*trn.NOutPer, axon.TargetLayer)
-	}
+	// out := net.AddLayer4D("Output", trn.OutSize.Y, trn.OutSize.X, trn.NOutPer, 1, axon.TargetLayer)
+	// 2D layer, with NOutPer units per category for localist patterns:
+	out := net.AddLayer2D("Output", outY, outX, axon.TargetLayer)
 
 	full := prjn.NewFull()
 	_ = full
@@ -503,31 +758,35 @@ func (ss *Sim) ConfigNet(net *axon.Network) {
 		outv4.SetClass("OutV4 FmOut")
 	*/
 
-	/*
+	// Lateral inhibitory projections for Config.Params.Inhib Lateral and Mixed.
+	// These were originally HebbPrjn, which is CPU-only -- the InhibPrjn
+	// type here learns with the standard rule, on the GPU too.
+	// With Config.Params.Dale, they are sent by separate interneurons.
+	if ss.InhibLateral() {
 		var v2inhib, v4inhib prjn.Pattern
 		v2inhib = pool1to1
 		v4inhib = pool1to1
-		if ss.SubPools {
+		if ss.Config.Params.SubPools {
 			v2inhib = pj.Prjn2x2Skp2 // pj.Prjn6x6Skp2Lat
 			v4inhib = pj.Prjn2x2Skp2
 		}
 
-			// this extra inhibition drives decorrelation, produces significant learning benefits
-			net.LateralConnectLayerPrjn(v2m16, v2inhib, &axon.HebbPrjn{}).SetType(axon.InhibPrjn)
-			net.LateralConnectLayerPrjn(v2l16, v2inhib, &axon.HebbPrjn{}).SetType(axon.InhibPrjn)
-			net.LateralConnectLayerPrjn(v2m8, v2inhib, &axon.HebbPrjn{}).SetType(axon.InhibPrjn)
-			net.LateralConnectLayerPrjn(v2l8, v2inhib, &axon.HebbPrjn{}).SetType(axon.InhibPrjn)
-			net.LateralConnectLayerPrjn(v4f16, v4inhib, &axon.HebbPrjn{}).SetType(axon.InhibPrjn)
-			net.LateralConnectLayerPrjn(v4f8, v4inhib, &axon.HebbPrjn{}).SetType(axon.InhibPrjn)
-			net.LateralConnectLayerPrjn(teo16, pool1to1, &axon.HebbPrjn{}).SetType(axon.InhibPrjn)
-			net.LateralConnectLayerPrjn(teo8, pool1to1, &axon.HebbPrjn{}).SetType(axon.InhibPrjn)
-			net.LateralConnectLayerPrjn(te, pool1to1, &axon.HebbPrjn{}).SetType(axon.InhibPrjn)
-
-			if hi16 {
-				net.LateralConnectLayerPrjn(v2h16, v2inhib, &axon.HebbPrjn{}).SetType(axon.InhibPrjn)
-				net.LateralConnectLayerPrjn(v3h16, v2inhib, &axon.HebbPrjn{}).SetType(axon.InhibPrjn)
-			}
-	*/
+		// this extra inhibition drives decorrelation, produces significant learning benefits
+		ss.ConnectInhib(net, v2m16, v2m16, v2inhib, "LatInhib")
+		ss.ConnectInhib(net, v2l16, v2l16, v2inhib, "LatInhib")
+		ss.ConnectInhib(net, v2m8, v2m8, v2inhib, "LatInhib")
+		ss.ConnectInhib(net, v2l8, v2l8, v2inhib, "LatInhib")
+		ss.ConnectInhib(net, v4f16, v4f16, v4inhib, "LatInhib")
+		ss.ConnectInhib(net, v4f8, v4f8, v4inhib, "LatInhib")
+		ss.ConnectInhib(net, teo16, teo16, pool1to1, "LatInhib")
+		ss.ConnectInhib(net, teo8, teo8, pool1to1, "LatInhib")
+		ss.ConnectInhib(net, te, te, pool1to1, "LatInhib")
+
+		if hi16 {
+			ss.ConnectInhib(net, v2h16, v2h16, v2inhib, "LatInhib")
+			ss.ConnectInhib(net, v3h16, v3h16, v2inhib, "LatInhib")
+		}
+	}
 
 	///////////////////////
 	// 	Shortcuts:
@@ -589,30 +848,68 @@ func (ss *Sim) ConfigNet(net *axon.Network) {
 
 	out.PlaceBehind(te, 15)
 
+	ss.ConfigSameDiffNet(net, te, out)
+	ss.ConfigIdentityNet(net, te, out)
+	ss.ConfigLearnRule()
+
 	net.Build(ctx)
+	ss.DaleReport(net)
+	if ss.Config.Run.Infer {
+		ss.ConfigInfer()
+	}
 	net.Defaults()
 	net.SetNThreads(ss.Config.Run.NThreads)
 	ss.ApplyParams()
 	net.InitWts(ctx)
 
 	mpi.Println(net.SizeReport(false))
+	if hi16 {
+		mpi.Println(ss.LayersSizeReport([]string{"V1h16", "V2h16", "V3h16"}))
+	}
 
 	// adding each additional layer type improves decoding..
 	layers := []emer.Layer{v4f16, v4f8, teo16, teo8, out}
 	// layers := []emer.Layer{teo16, teo8, out}
 	// layers := []emer.Layer{teo16, teo8}
-	ss.Decoder.InitLayer(len(trn.Images.Cats), layers)
+	ss.Decoder.InitLayer(len(ss.LvisEnv(etime.Train).CatNames()), layers)
 	ss.Decoder.Lrate = 0.05 // 0.05 > 0.1 > 0.2 for larger number of objs!
 	if ss.Config.Run.MPI {
 		ss.Decoder.Comm = ss.Comm
 	}
+	ss.ConfigDecoders()
 }
 
 func (ss *Sim) ApplyParams() {
 	ss.Params.SetAll() // first hard-coded defaults
+	if sh := InhibSheets[ss.Config.Params.Inhib]; sh != "" {
+		ss.Params.SetAllSheet(sh)
+	}
+	if ss.Config.Env.OutEmbed != "" {
+		ss.Params.SetAllSheet("EmbedOutPats")
+	}
+	if ss.Config.Params.Dale != "" {
+		ss.Params.SetAllSheet("Dale")
+	}
 	if ss.Config.Params.Network != nil {
 		ss.Params.SetNetworkMap(ss.Net, ss.Config.Params.Network)
 	}
+	ss.ApplyLearnRule()
+	ss.ApplyGrow()
+	if ss.Config.Run.Infer {
+		ss.InferLearnOff()
+	}
+}
+
+// InhibLateral returns true if Config.Params.Inhib uses the lateral
+// inhibitory projections, and checks that it is a valid configuration
+func (ss *Sim) InhibLateral() bool {
+	inh := ss.Config.Params.Inhib
+	if _, ok := InhibSheets[inh]; !ok {
+		mpi.Printf("Config.Params.Inhib: %s not valid, using: %s\n", inh, InhibFSFFFB)
+		ss.Config.Params.Inhib = InhibFSFFFB
+		return false
+	}
+	return inh == InhibLateral || inh == InhibMixed
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -636,21 +933,16 @@ func (ss *Sim) Init() {
 	ss.ViewUpdt.RecordSyns()
 }
 
-// InitRndSeed initializes the random seed based on current training run number
-func (ss *Sim) InitRndSeed(run int) {
-	ss.RndSeeds.Set(run)
-	ss.RndSeeds.Set(run, &ss.Net.Rand)
-}
-
 // ConfigLoops configures the control loops: Training, Testing
 func (ss *Sim) ConfigLoops() {
 	man := looper.NewManager()
 
 	ss.Context.SlowInterval = int32(4 * 100) // decompensate..
 
-	totND := ss.Config.Run.NData * mpi.WorldSize() // both sources of data parallel
-	totTrls := int(mat32.IntMultipleGE(float32(ss.Config.Run.NTrials), float32(totND)))
-	trls := totTrls / mpi.WorldSize()
+	ss.ValidateNDataSched()
+	ss.Trials = *NewTrialsAllocator(ss.Config.Run.NTrials, ss.Config.Run.NData, ss.MPISize()) // both sources of data parallel
+	mpi.Printf("%s\n", ss.Trials.String())
+	trls := ss.Trials.PerProc
 
 	man.AddStack(etime.Train).
 		AddTime(etime.Run, ss.Config.Run.NRuns).
@@ -675,35 +967,74 @@ func (ss *Sim) ConfigLoops() {
 		}
 		ss.MPIWtFmDWt()
 	})
-	if ss.Config.Debug {
-		man.GetLoop(etime.Train, etime.Epoch).OnStart.Add("ValidateMPIReplicaConsistency",
-			ss.AssertMPIReplicaConsistency)
-	}
+	man.GetLoop(etime.Train, etime.Epoch).OnStart.Add("InitWtDecayStats", ss.InitWtDecayStats)
+	man.GetLoop(etime.Train, etime.Epoch).OnStart.Add("ScheduleParams", ss.ScheduleParams)
+	man.GetLoop(etime.Train, etime.Epoch).OnStart.Add("GrowSched", ss.GrowSched)
+	man.GetLoop(etime.Train, etime.Epoch).OnStart.Add("NDataSched", ss.NDataSched)
+	man.GetLoop(etime.Train, etime.Epoch).OnStart.Add("OutClampSched", ss.OutClampSched)
+	man.GetLoop(etime.Test, etime.Epoch).OnStart.Add("TestNDataOutClamp", func() {
+		ss.SetNData(ss.Config.Run.NData, false)
+		ss.SetOutClamp(false)
+	})
+	man.GetLoop(etime.Train, etime.Epoch).OnStart.Add("InitRewireStats", ss.InitRewireStats)
+	man.GetLoop(etime.Train, etime.Epoch).OnStart.Add("InitWtChange", ss.InitWtChange)
+	man.GetLoop(etime.Train, etime.Epoch).OnStart.Add("InitDropoutStats", ss.InitDropoutStats)
+	man.GetLoop(etime.Train, etime.Trial).OnStart.Add("Dropout", ss.Dropout)
+	man.GetLoop(etime.Train, etime.Epoch).OnEnd.Add("DropoutRestore", ss.DropoutRestore)
+	man.GetLoop(etime.Test, etime.Epoch).OnStart.Add("DropoutRestore", ss.DropoutRestore)
+	man.GetLoop(etime.Train, etime.Epoch).OnStart.Add("ReplicaCheck", ss.ReplicaCheck)
 
 	for m, _ := range man.Stacks {
 		mode := m // For closures
 		stack := man.Stacks[mode]
 		stack.Loops[etime.Trial].OnStart.Add("ApplyInputs", func() {
+			ss.BenchTimes.Env.Start()
 			ss.ApplyInputs()
+			ss.BenchTimes.Env.Stop()
 		})
+		stack.Loops[etime.Trial].OnStart.Add("InitFirstCycStats", ss.InitFirstCycStats)
+		stack.Loops[etime.Cycle].OnEnd.Add("FirstCycStats", ss.FirstCycStats)
 	}
 
+	man.GetLoop(etime.Train, etime.Trial).OnStart.Add("GPUCheck", ss.GPUCheckTrial) // after ApplyInputs
+	man.GetLoop(etime.Train, etime.Trial).OnEnd.Add("CaDiffStats", ss.CaDiffStats)
+	man.GetLoop(etime.Test, etime.Trial).OnStart.Add("MovieStart", ss.MovieStart)
+	man.GetLoop(etime.Test, etime.Cycle).OnEnd.Add("MovieFrame", ss.MovieFrame)
+	man.GetLoop(etime.Test, etime.Trial).OnEnd.Add("MovieSave", ss.MovieSave)
+	man.GetLoop(etime.Test, etime.Cycle).OnStart.Add("ClampCycle", ss.ClampCycle)
+	man.GetLoop(etime.Test, etime.Trial).OnEnd.Add("ClampOff", ss.ClampOff)
+
 	man.GetLoop(etime.Train, etime.Run).OnStart.Add("NewRun", ss.NewRun)
+	man.GetLoop(etime.Train, etime.Run).OnStart.Add("ResumeRun", ss.ResumeRun)
 
 	// Add Testing
 	trainEpoch := man.GetLoop(etime.Train, etime.Epoch)
 	trainEpoch.OnStart.Add("TestAtInterval", func() {
-		if (ss.Config.Run.TestInterval > 0) && ((trainEpoch.Counter.Cur+1)%ss.Config.Run.TestInterval == 0) {
-			// Note the +1 so that it doesn't occur at the 0th timestep.
+		epc := trainEpoch.Counter.Cur + 1 // Note the +1 so that it doesn't occur at the 0th timestep.
+		if (ss.Config.Run.TestInterval > 0) && (epc%ss.Config.Run.TestInterval == 0) {
 			ss.TestAll()
+		} else if (ss.Config.Run.FastTestInterval > 0) && (epc%ss.Config.Run.FastTestInterval == 0) {
+			ss.FastTest()
 		}
 	})
 
+	trainEpoch.OnEnd.Add("ShuffleSeedStat", ss.ShuffleSeedStat)
 	trainEpoch.OnEnd.Add("RandCheck", func() {
 		if ss.Config.Run.MPI {
 			empi.RandCheck(ss.Comm) // prints error message
 		}
 	})
+	trainEpoch.OnEnd.Add("WtChangeStats", ss.WtChangeStats)
+	trainEpoch.OnEnd.Add("Rewire", func() {
+		intv := ss.Config.Params.RewireInterval
+		if intv > 0 && (trainEpoch.Counter.Cur+1)%intv == 0 {
+			ss.Rewire()
+		}
+	})
+	trainEpoch.OnStart.Add("InitReplay", ss.InitReplay)
+	trainEpoch.OnStart.Add("ProfileStart", ss.ProfileStart)
+	trainEpoch.OnEnd.Add("ProfileEpoch", ss.ProfileEpoch)
+	trainEpoch.OnEnd.Add("ReplayEpoch", ss.ReplayEpoch) // before Log, for ReplayN
 
 	/////////////////////////////////////////////
 	// Logging
@@ -711,19 +1042,54 @@ func (ss *Sim) ConfigLoops() {
 	man.GetLoop(etime.Test, etime.Epoch).OnEnd.Add("LogTestErrors", func() {
 		axon.LogTestErrors(&ss.Logs)
 	})
+	man.GetLoop(etime.Test, etime.Epoch).OnStart.Add("InitProtos", ss.InitProtos)
+	man.GetLoop(etime.Test, etime.Epoch).OnEnd.Add("ProtoStats", ss.FullTestOnly(ss.ProtoStats))
+	man.GetLoop(etime.Test, etime.Epoch).OnEnd.Add("TuningCurve", ss.FullTestOnly(ss.TuningCurveUpdate))
+	man.GetLoop(etime.Test, etime.Epoch).OnStart.Add("InitSparse", ss.InitSparse)
+	man.GetLoop(etime.Test, etime.Epoch).OnEnd.Add("SparseStats", ss.FullTestOnly(ss.SparseStats))
+	man.GetLoop(etime.Test, etime.Epoch).OnStart.Add("InitOddOneOut", ss.InitOddOneOut)
+	man.GetLoop(etime.Test, etime.Epoch).OnEnd.Add("OddOneOutStats", ss.FullTestOnly(ss.OddOneOutStats))
+	man.GetLoop(etime.Test, etime.Epoch).OnStart.Add("InitSplitHalf", ss.InitSplitHalf)
+	man.GetLoop(etime.Test, etime.Epoch).OnEnd.Add("SplitHalfStats", ss.FullTestOnly(ss.SplitHalfStats))
+	man.GetLoop(etime.Test, etime.Epoch).OnStart.Add("InitPoseStats", ss.InitPoseStats)
+	man.GetLoop(etime.Train, etime.Epoch).OnStart.Add("InitSameDiff", ss.InitSameDiff)
+	man.GetLoop(etime.Test, etime.Epoch).OnStart.Add("InitSameDiff", ss.InitSameDiff)
+	man.GetLoop(etime.Test, etime.Epoch).OnEnd.Add("PoseEpochStats", ss.FullTestOnly(ss.PoseEpochStats))
 	man.GetLoop(etime.Train, etime.Epoch).OnEnd.Add("PCAStats", func() {
 		trnEpc := man.Stacks[etime.Train].Loops[etime.Epoch].Counter.Cur
 		if (ss.Config.Run.PCAInterval > 0) && (trnEpc%ss.Config.Run.PCAInterval == 0) {
 			if ss.Config.Run.MPI {
 				ss.Logs.MPIGatherTableRows(etime.Analyze, etime.Trial, ss.Comm)
 			}
-			axon.PCAStats(ss.Net, &ss.Logs, &ss.Stats)
+			ss.PCAStats()
 			ss.Logs.ResetLog(etime.Analyze, etime.Trial)
 		}
 	})
 
+	man.GetLoop(etime.Train, etime.Epoch).OnEnd.Add("InDriftStats", ss.InDriftStats)
+
 	man.AddOnEndToAll("Log", ss.Log)
 	axon.LooperResetLogBelow(man, &ss.Logs)
+	man.GetLoop(etime.Train, etime.Epoch).OnEnd.Add("ResetEvents", func() {
+		ss.Stats.SetString("EpcEvent", "") // after Log
+	})
+	man.GetLoop(etime.Test, etime.Epoch).OnEnd.Add("RestoreNDataOutClamp", func() {
+		if ss.NDataTrain > 0 {
+			ss.SetNData(ss.NDataTrain, false) // after Log
+		}
+		ss.SetOutClamp(ss.OutClamp)
+	})
+
+	man.GetLoop(etime.Test, etime.Trial).OnEnd.Add("ProtoRecord", ss.ProtoRecord) // after Log computes TrialStats
+	man.GetLoop(etime.Test, etime.Trial).OnEnd.Add("SparseRecord", ss.SparseRecord)
+	man.GetLoop(etime.Test, etime.Trial).OnEnd.Add("OddOneOutRecord", ss.OddOneOutRecord)
+	man.GetLoop(etime.Test, etime.Trial).OnEnd.Add("SplitHalfRecord", ss.SplitHalfRecord)
+	man.GetLoop(etime.Test, etime.Trial).OnEnd.Add("RFSizeRecord", ss.RFSizeRecord)
+	man.GetLoop(etime.Train, etime.Trial).OnEnd.Add("DifficultyRecord", ss.DifficultyRecord)
+	man.GetLoop(etime.Train, etime.Trial).OnEnd.Add("NaNCheck", ss.NaNCheck)
+	man.GetLoop(etime.Train, etime.Trial).OnEnd.Add("ReplayRecord", ss.ReplayRecord)
+	man.GetLoop(etime.Train, etime.Epoch).OnEnd.Add("DifficultyEpoch", ss.DifficultyEpoch)
+	man.GetLoop(etime.Train, etime.Run).OnEnd.Add("SaveDifficulty", ss.SaveDifficulty)
 
 	man.GetLoop(etime.Train, etime.Trial).OnEnd.Add("LogAnalyze", func() {
 		trnEpc := man.Stacks[etime.Train].Loops[etime.Epoch].Counter.Cur
@@ -732,54 +1098,60 @@ func (ss *Sim) ConfigLoops() {
 		}
 	})
 
-	man.GetLoop(etime.Train, etime.Run).OnEnd.Add("RunStats", func() {
-		ss.Logs.RunStats("PctCor", "FirstZero", "LastZero")
-	})
+	man.GetLoop(etime.Train, etime.Run).OnEnd.Add("RunStats", ss.RunStats)
+	man.GetLoop(etime.Train, etime.Run).OnEnd.Add("ExportResults", ss.ExportResults)
+	man.GetLoop(etime.Train, etime.Run).OnEnd.Add("SavePlots", ss.SavePlots)
+	man.GetLoop(etime.Train, etime.Run).OnStart.Add("TrackStart", ss.TrackStart) // after NewRun
+	man.GetLoop(etime.Train, etime.Epoch).OnEnd.Add("TrackEpoch", ss.TrackEpoch)
+	man.GetLoop(etime.Train, etime.Run).OnEnd.Add("TrackFinish", ss.TrackFinish)
 
 	// Save weights to file at end, to look at later
 	man.GetLoop(etime.Train, etime.Run).OnEnd.Add("SaveWeights", func() { ss.SaveWeights() })
 
-	// lrate schedule
-	// man.GetLoop(etime.Train, etime.Epoch).OnEnd.Add("LrateSched", func() {
-	// 	trnEpc := ss.Loops.Stacks[etime.Train].Loops[etime.Epoch].Counter.Cur
-	// 	switch trnEpc {
-	// 	case 10:
-	// 		mpi.Printf("NData increase back to specified: %d at: %d\n", ss.Config.Run.NData, trnEpc)
-	// 		ss.Context.NetIdxs.NData = uint32(ss.Config.Run.NData)
-	// 	}
-	// })
-
-	man.GetLoop(etime.Train, etime.Epoch).AddNewEvent("SaveWeights", 10, func() { ss.SaveWeights() })
-	man.GetLoop(etime.Train, etime.Epoch).AddNewEvent("SaveWeights", 100, func() { ss.SaveWeights() })
-	man.GetLoop(etime.Train, etime.Epoch).AddNewEvent("SaveWeights", 500, func() { ss.SaveWeights() })
-	man.GetLoop(etime.Train, etime.Epoch).AddNewEvent("SaveWeights", 1000, func() { ss.SaveWeights() })
-	man.GetLoop(etime.Train, etime.Epoch).AddNewEvent("SaveWeights", 1500, func() { ss.SaveWeights() })
+	// periodic and best weights saving, per Config.Log.Wts* retention policy
+	for _, epc := range ss.Config.Log.WtsEpochs {
+		man.GetLoop(etime.Train, etime.Epoch).AddNewEvent("SaveWeights", epc, ss.SaveWeightsKeep)
+	}
+	man.GetLoop(etime.Train, etime.Epoch).OnStart.Add("SaveWeightsInterval", ss.SaveWeightsInterval)
+	man.GetLoop(etime.Test, etime.Epoch).OnEnd.Add("SaveWeightsBest", ss.FullTestOnly(ss.SaveWeightsBest)) // after Log
+
+	if ss.ActRFsOn() {
+		man.GetLoop(etime.Test, etime.Trial).OnEnd.Add("ActRFs", ss.UpdateActRFs)
+	}
+
+	man.GetLoop(etime.Train, etime.Trial).OnEnd.Add("SignalCheck", ss.SignalCheck) // after Log
+	man.GetLoop(etime.Test, etime.Trial).OnEnd.Add("SignalCheck", ss.SignalCheck)
 
 	////////////////////////////////////////////
 	// GUI
 	if !ss.Config.GUI {
-		// man.GetLoop(etime.Test, etime.Trial).Main.Add("NetDataRecord", func() {
-		// 	ss.GUI.NetDataRecord(ss.ViewUpdt.Text)
-		// })
+		man.GetLoop(etime.Test, etime.Trial).OnEnd.Add("NetDataRecord", ss.NetDataRecord)
 	} else {
-		// this is actually fairly expensive
-		man.GetLoop(etime.Test, etime.Trial).OnEnd.Add("ActRFs", func() {
-			for di := 0; di < int(ss.Context.NetIdxs.NData); di++ {
-				ss.Stats.UpdateActRFs(ss.Net, "ActM", 0.01, di)
-			}
-		})
+		man.GetLoop(etime.Test, etime.Trial).OnEnd.Add("RSARecord", ss.RSARecord)
 
 		man.GetLoop(etime.Train, etime.Trial).OnStart.Add("UpdtImage", func() {
-			ss.GUI.Grid("Image").UpdateSig()
+			ss.UpdtImageGrids(etime.Train)
 		})
 		man.GetLoop(etime.Test, etime.Trial).OnStart.Add("UpdtImage", func() {
-			ss.GUI.Grid("Image").UpdateSig()
+			ss.UpdtImageGrids(etime.Test)
 		})
 
+		if ss.Recon.Target != nil {
+			for _, mode := range []etime.Modes{etime.Train, etime.Test} {
+				man.GetLoop(mode, etime.Trial).OnEnd.Add("UpdtRecon", func() {
+					ss.GUI.Grid("Recon").UpdateSig()
+				})
+			}
+		}
+
 		axon.LooperUpdtNetView(man, &ss.ViewUpdt, ss.Net, ss.NetViewCounters)
 		axon.LooperUpdtPlots(man, &ss.GUI)
 	}
 
+	ss.ConfigPrimeLoops(man, trls)
+	ss.ConfigBenchLoops(man)
+	ss.ConfigHooks(man)
+
 	if ss.Config.Debug {
 		mpi.Println(man.DocString())
 	}
@@ -787,13 +1159,169 @@ func (ss *Sim) ConfigLoops() {
 }
 
 // SaveWeights saves weights with filename recording run, epoch
+// along with a sidecar file with the train / test split used in training
+// (see SplitManifest), so it can be verified when the weights are loaded.
 func (ss *Sim) SaveWeights() {
-	ctrString := ss.Stats.PrintVals([]string{"Run", "Epoch"}, []string{"%03d", "%05d"}, "_")
-	axon.SaveWeightsIfConfigSet(ss.Net, ss.Config.Log.SaveWts, ctrString, ss.Stats.String("RunName"))
+	ss.SaveWeightsTag("")
+}
+
+// SaveWeightsTag saves weights as in SaveWeights, with given tag added
+// after the run and epoch in the file name, returning the file name,
+// or "" if not saved (Config.Log.SaveWts is off, or not MPI rank 0).
+func (ss *Sim) SaveWeightsTag(tag string) string {
+	if !ss.Config.Log.SaveWts {
+		return ""
+	}
+	return ss.WriteWeights(tag)
+}
+
+// WriteWeights saves weights as in SaveWeightsTag, regardless of
+// Config.Log.SaveWts, returning the file name, or "" if not MPI rank 0.
+func (ss *Sim) WriteWeights(tag string) string {
+	if ss.MPIRank() != 0 {
+		return ""
+	}
+	ctrString := ss.Stats.PrintVals([]string{"Run", "Epoch"}, []string{"%03d", "%05d"}, "_") + tag
+	fnm := axon.WeightsFileName(ss.Net, ctrString, ss.Stats.String("RunName"))
+	if ss.Config.Log.WtsFormat == "wtsz" {
+		fnm = strings.TrimSuffix(fnm, ".wts.gz") + WtsZExt
+	}
+	fmt.Printf("Saving Weights to: %s\n", fnm)
+	ss.AddEvent("SaveWeights")
+	if ss.Config.Log.WtsFormat == "wtsz" {
+		if err := ss.SaveWtsZ(fnm); err != nil {
+			mpi.Println(err)
+		}
+	} else {
+		ss.Net.SaveWtsJSON(gi.FileName(fnm))
+	}
+	if trn := ss.ImagesEnv(etime.Train); trn != nil {
+		trn.SaveSplitManifest(SplitManifestFileName(fnm))
+	}
+	return fnm
+}
+
+// OpenWeights opens weights from given file, and checks the current
+// train / test split against the one saved with the weights, if present,
+// printing a warning if they differ.  If Config.Env.RestoreSplit is set,
+// the saved split is restored into the envs.
+func (ss *Sim) OpenWeights(fname gi.FileName) error {
+	if strings.HasSuffix(string(fname), WtsZExt) {
+		wh, err := ss.OpenWtsZ(string(fname))
+		if err != nil {
+			return err
+		}
+		mpi.Printf("Opened weights: %s  run: %s %d  epoch: %d  config hash: %s\n", fname, wh.RunName, wh.Run, wh.Epoch, wh.ConfigHash)
+		if err := ss.CheckCats(string(fname), wh.Cats); err != nil {
+			mpi.Println(err)
+		}
+	} else if err := ss.Net.OpenWtsJSON(fname); err != nil {
+		return err
+	}
+	if !ss.IsImagesEnv() {
+		return nil
+	}
+	smfnm := SplitManifestFileName(string(fname))
+	if _, err := os.Stat(smfnm); os.IsNotExist(err) {
+		mpi.Printf("Note: no split manifest file: %s saved with weights -- cannot verify train / test split\n", smfnm)
+		return nil
+	}
+	sm, err := OpenSplitManifest(smfnm)
+	if err != nil {
+		return err
+	}
+	trn := ss.Envs.ByMode(etime.Train).(*ImagesEnv)
+	tst := ss.Envs.ByMode(etime.Test).(*ImagesEnv)
+	if err := tst.CheckSplitManifest(sm); err != nil {
+		if !ss.Config.Env.RestoreSplit {
+			mpi.Printf("WARNING: %s\n", err)
+			return nil
+		}
+		mpi.Printf("Restoring train / test split saved with weights from: %s\n", smfnm)
+		for _, ev := range []*ImagesEnv{trn, tst} {
+			ev.RestoreSplitManifest(sm)
+			if ss.Config.Run.MPI {
+				ev.MPIAlloc(ss.MPIRank(), ss.MPISize())
+			}
+			ev.Init(0)
+		}
+	}
+	return nil
 }
 
-// CenterPoolIdxs returns the unit indexes for 2x2 center pools
+// SetRepIdxs sets the representative unit indexes and shape for given
+// 4D layer, according to the first matching Config.Log.RepPools entry,
+// defaulting to the central 2x2 pools.
+func (ss *Sim) SetRepIdxs(ly *axon.Layer) {
+	mode := "Center"
+	n := 2
+	for _, rp := range ss.Config.Log.RepPools {
+		if params.SelMatch(rp.Sel, ly.Name(), ly.Class(), ly.TypeName(), "Layer") {
+			mode = rp.Mode
+			n = rp.N
+			break
+		}
+	}
+	switch mode {
+	case "Full":
+		ly.SetRepIdxsShape(nil, nil)
+	case "Random":
+		ly.SetRepIdxsShape(ss.RandomPoolIdxs(ly, n), []int{1, n, ly.Shape().Dim(2), ly.Shape().Dim(3)})
+	default:
+		if mode != "Center" {
+			mpi.Printf("SetRepIdxs: layer: %s: Mode %q not recognized, using Center\n", ly.Name(), mode)
+		}
+		ly.SetRepIdxsShape(ss.CenterPoolIdxs(ly, n), emer.CenterPoolShape(ly, n))
+	}
+}
+
+// PoolIdxs returns the unit indexes for the pool at given pool
+// coordinates, in terms of top-level pools if sub-pools are present,
+// in which case only the first such subpool is used.
+func (ss *Sim) PoolIdxs(ly emer.Layer, py, px int) []int {
+	npxact := ly.Shape().Dim(1)
+	nu := ly.Shape().Dim(2) * ly.Shape().Dim(3)
+	nsp := 1
+	if ss.Config.Params.SubPools {
+		nsp = 2
+	}
+	si := (py*nsp*npxact + px*nsp) * nu
+	idxs := make([]int, nu)
+	for ni := range idxs {
+		idxs[ni] = si + ni
+	}
+	return idxs
+}
+
+// RandomPoolIdxs returns the unit indexes for n randomly selected pools,
+// using a random seed based on the layer name so the selection is fixed.
+// if sub-pools are present, then only first such subpool is used.
+func (ss *Sim) RandomPoolIdxs(ly emer.Layer, n int) []int {
+	npy := ly.Shape().Dim(0)
+	npx := ly.Shape().Dim(1)
+	if ss.Config.Params.SubPools {
+		npy /= 2
+		npx /= 2
+	}
+	h := fnv.New64a()
+	h.Write([]byte(ly.Name()))
+	rnd := rand.New(rand.NewSource(int64(h.Sum64())))
+	pis := rnd.Perm(npy * npx)
+	if n > len(pis) {
+		n = len(pis)
+	}
+	pis = pis[:n]
+	sort.Ints(pis)
+	var idxs []int
+	for _, pi := range pis {
+		idxs = append(idxs, ss.PoolIdxs(ly, pi/npx, pi%npx)...)
+	}
+	return idxs
+}
+
+// CenterPoolIdxs returns the unit indexes for n x n center pools
 // if sub-pools are present, then only first such subpool is used.
+// n is limited to the number of pools in each dimension.
 func (ss *Sim) CenterPoolIdxs(ly emer.Layer, n int) []int {
 	npy := ly.Shape().Dim(0)
 	npx := ly.Shape().Dim(1)
@@ -805,15 +1333,21 @@ func (ss *Sim) CenterPoolIdxs(ly emer.Layer, n int) []int {
 		npx /= 2
 		nsp = 2
 	}
+	if n > npy {
+		n = npy
+	}
+	if n > npx {
+		n = npx
+	}
 	cpy := (npy - n) / 2
 	cpx := (npx - n) / 2
 	nt := n * n * nu
 	idxs := make([]int, nt)
 
 	ix := 0
-	for py := 0; py < 2; py++ {
+	for py := 0; py < n; py++ {
 		y := (py + cpy) * nsp
-		for px := 0; px < 2; px++ {
+		for px := 0; px < n; px++ {
 			x := (px + cpx) * nsp
 			si := (y*npxact + x) * nu
 			for ni := 0; ni < nu; ni++ {
@@ -832,14 +1366,29 @@ func (ss *Sim) CenterPoolIdxs(ly emer.Layer, n int) []int {
 func (ss *Sim) ApplyInputs() {
 	ctx := &ss.Context
 	net := ss.Net
-	ev := ss.Envs.ByMode(ctx.Mode).(*ImagesEnv)
+	ev := ss.LvisEnv(ctx.Mode)
+	iev := ss.ImagesEnv(ctx.Mode)
 	net.InitExt(ctx)
 	lays := net.LayersByType(axon.InputLayer, axon.TargetLayer)
+	ss.SameDiffTrial()
 	for di := uint32(0); di < ctx.NetIdxs.NData; di++ {
-		ev.Step()
+		if !ss.SameDiffStep(int(di), iev) {
+			ev.Step()
+		}
 		ss.Stats.SetStringDi("TrialName", int(di), ev.String()) // for logging
-		ss.Stats.SetIntDi("TrlCatIdx", int(di), ev.CurCatIdx)
-		ss.Stats.SetStringDi("TrlCat", int(di), ev.CurCat)
+		cat, catIdx := ev.CurCatName()
+		ss.Stats.SetIntDi("TrlCatIdx", int(di), catIdx)
+		ss.Stats.SetStringDi("TrlCat", int(di), cat)
+		ss.TriggerTrial(int(di), iev)
+		if iev != nil {
+			ss.Stats.SetIntDi("TrlImgIdx", int(di), iev.CurImgIdx)
+			ss.RecordActRFImage(int(di), iev)
+			ss.PoseRecord(int(di), iev)
+			ss.V1ColorRecord(int(di), iev)
+			ss.IdentityRecord(int(di), iev)
+			ss.Stats.SetFloatDi("TrlVisFrac", int(di), float64(iev.CurVisFrac))
+			ss.Stats.SetFloatDi("TrlFillFrac", int(di), float64(iev.CurFillFrac))
+		}
 		for _, lnm := range lays {
 			ly := ss.Net.AxonLayerByName(lnm)
 			pats := ev.State(ly.Nm)
@@ -847,6 +1396,8 @@ func (ss *Sim) ApplyInputs() {
 				ly.ApplyExt(ctx, di, pats)
 			}
 		}
+		ss.ApplySameDiff(di)
+		ss.ApplyIdentity(di)
 	}
 	net.ApplyExts(ctx)
 }
@@ -856,11 +1407,24 @@ func (ss *Sim) ApplyInputs() {
 func (ss *Sim) NewRun() {
 	ctx := &ss.Context
 	ss.InitRndSeed(ss.Loops.GetLoop(etime.Train, etime.Run).Counter.Cur)
+	ss.PrintSeeds()
 	ss.Envs.ByMode(etime.Train).Init(0)
 	ss.Envs.ByMode(etime.Test).Init(0)
 	ctx.Reset()
 	ctx.Mode = etime.Train
 	ss.Net.InitWts(ctx)
+	ss.GrowSched()
+	ss.InitRewire()
+	ss.InitTransplant()
+	ss.InitShareWts() // after Transplant
+	ss.InitDifficulty()
+	ss.WtsSaved.Init()
+	ss.InitSchedule()
+	ss.InitNData()
+	ss.InitRecon()
+	ss.InitBaseline()
+	ss.InitInDrift()
+	ss.InitOutClamp()
 	ss.InitStats()
 	ss.StatCounters(0)
 	ss.Logs.ResetLog(etime.Train, etime.Epoch)
@@ -873,7 +1437,7 @@ func (ss *Sim) TestAll() {
 	ss.Stats.ActRFs.Reset()
 	ss.Loops.ResetAndRun(etime.Test)
 	ss.Loops.Mode = etime.Train // Important to reset Mode back to Train because this is called from within the Train Run.
-	ss.Stats.ActRFsAvgNorm()
+	ss.ActRFsAvgNorm()
 	ss.GUI.ViewActRFs(&ss.Stats.ActRFs)
 
 }
@@ -891,7 +1455,10 @@ func (ss *Sim) RunTestAll() {
 // otherwise it is the confusion row for given category.
 // data goes in the TrlErr = Err column.
 func (ss *Sim) ConfusionTstPlot(cat string) {
-	ev := ss.Envs[etime.Test.String()].(*ImagesEnv)
+	ev := ss.ImagesEnv(etime.Test)
+	if ev == nil {
+		return
+	}
 	ss.Logs.ResetLog(etime.Test, etime.Trial)
 	nc := ss.Stats.Confusion.N.Len()
 	ti := -1
@@ -931,8 +1498,9 @@ func (ss *Sim) InitStats() {
 	ss.Stats.SetInt("TrlDecRespIdx", 0)
 	ss.Stats.SetFloat("TrlDecErr", 0.0)
 	ss.Stats.SetFloat("TrlDecErr2", 0.0)
-	ev := ss.Envs[etime.Train.String()].(*ImagesEnv)
-	ss.Stats.Confusion.InitFromLabels(ev.Images.Cats, 12)
+	ss.Stats.SetString("EpcEvent", "")
+	ss.Stats.SetFloat("PoseSlope", 0.0)
+	ss.Stats.Confusion.InitFromLabels(ss.LvisEnv(etime.Train).CatNames(), 12)
 }
 
 // StatCounters saves current counters to Stats, so they are available for logging etc
@@ -961,7 +1529,12 @@ func (ss *Sim) NetViewCounters(tm etime.Times) {
 		ss.TrialStats(di) // get trial stats for current di
 	}
 	ss.StatCounters(di)
-	ss.ViewUpdt.Text = ss.Stats.Print([]string{"Run", "Epoch", "Trial", "Di", "TrlCat", "TrlResp", "TrialName", "Cycle", "UnitErr", "TrlErr", "CorSim"})
+	ss.ViewUpdt.Text = ss.NetViewText()
+}
+
+// NetViewText returns the counters and stats string shown in the NetView
+func (ss *Sim) NetViewText() string {
+	return ss.Stats.Print([]string{"Run", "Epoch", "Trial", "Di", "TrlCat", "TrlResp", "TrialName", "Cycle", "UnitErr", "TrlErr", "CorSim"})
 }
 
 // TrialStats computes the trial-level statistics.
@@ -974,25 +1547,29 @@ func (ss *Sim) TrialStats(di int) {
 	ss.Stats.SetFloat("UnitErr", out.PctUnitErr(ctx)[di])
 
 	ovt := ss.Stats.SetLayerTensor(ss.Net, "Output", "ActM", di)
-	ev := ss.Envs.ByMode(ctx.Mode).(*ImagesEnv)
-
-	ncats := len(ev.Images.Cats)
+	ev := ss.LvisEnv(ctx.Mode)
+	cats := ev.CatNames()
+	ncats := len(cats)
 
 	curCatIdx := ss.Stats.IntDi("TrlCatIdx", di)
 	curCat := ss.Stats.StringDi("TrlCat", di)
 	ss.Stats.SetInt("TrlCatIdx", curCatIdx)
 	ss.Stats.SetString("TrlCat", curCat)
 
-	rsp, trlErr, trlErr2 := ev.OutErr(ovt, curCatIdx)
+	rsp, trlErr, trlErr2, margin := ev.OutErrMargin(ovt, curCatIdx)
+	if rsp = ss.RespTrialStats(ovt, rsp, trlErr, margin); rsp < 0 { // no response
+		trlErr, trlErr2 = 1, 1
+	}
 	ss.Stats.SetIntDi("TrlRespIdx", di, rsp) // save for stat counter
+	ss.Stats.SetFloatDi("TrlMargin", di, margin)
 	ss.Stats.SetFloatDi("TrlErr", di, trlErr)
 	ss.Stats.SetFloatDi("TrlErr2", di, trlErr2)
 	ss.Stats.SetInt("TrlRespIdx", rsp) // used in logging current trial
 	ss.Stats.SetFloat("TrlErr", trlErr)
 	ss.Stats.SetFloat("TrlErr2", trlErr2)
 	if rsp >= 0 && rsp < ncats {
-		ss.Stats.SetStringDi("TrlResp", di, ev.Images.Cats[rsp])
-		ss.Stats.SetString("TrlResp", ev.Images.Cats[rsp])
+		ss.Stats.SetStringDi("TrlResp", di, cats[rsp])
+		ss.Stats.SetString("TrlResp", cats[rsp])
 	} else {
 		ss.Stats.SetStringDi("TrlResp", di, "none")
 		ss.Stats.SetString("TrlResp", "none")
@@ -1024,6 +1601,9 @@ func (ss *Sim) TrialStats(di int) {
 	}
 	ss.Stats.SetFloat("TrlDecErr2", decErr2)
 	ss.Stats.SetFloat32("TrlOutRT", out.Vals[di].RT)
+	ss.PoseTrialStats(di)
+	ss.SameDiffTrialStats(di)
+	ss.IdentityTrialStats(di)
 }
 
 //////////////////////////////////////////////////////////////////////////////
@@ -1034,8 +1614,12 @@ func (ss *Sim) ConfigLogs() {
 
 	ss.Logs.AddCounterItems(etime.Run, etime.Epoch, etime.Trial, etime.Cycle)
 	ss.Logs.AddStatIntNoAggItem(etime.AllModes, etime.Trial, "Di")
+	ss.Logs.AddStatIntNoAggItem(etime.Train, etime.Epoch, "NData")
 	ss.Logs.AddPerTrlMSec("PerTrlMSec", etime.Run, etime.Epoch, etime.Trial)
 	ss.Logs.AddStatStringItem(etime.AllModes, etime.AllTimes, "RunName")
+	ss.Logs.AddStatStringItem(etime.Train, etime.Run, "Seeds")
+	ss.Logs.AddStatIntNoAggItem(etime.Train, etime.Run, "NTrainImgs")
+	ss.Logs.AddStatStringItem(etime.Train, etime.Epoch, "ShuffleSeed")
 	ss.Logs.AddStatStringItem(etime.AllModes, etime.Trial, "TrlCat", "TrialName", "TrlResp")
 
 	ss.Logs.AddStatAggItem("CorSim", etime.Run, etime.Epoch, etime.Trial)
@@ -1043,14 +1627,43 @@ func (ss *Sim) ConfigLogs() {
 	ss.Logs.AddErrStatAggItems("TrlErr", etime.Run, etime.Epoch, etime.Trial)
 
 	ss.ConfigLogItems()
+	ss.ConfigWtDecayLogs()
+	ss.ConfigScheduleLogs()
+	ss.ConfigRewireLogs()
+	ss.ConfigWtChangeLogs()
+	ss.ConfigDropoutLogs()
+	ss.ConfigFirstCycLogs()
+	ss.ConfigCaDiffLogs()
+	ss.ConfigConfEntLogs()
+	ss.ConfigProtoLogs()
+	ss.ConfigSparseLogs()
+	ss.ConfigOddOneOutLogs()
+	ss.ConfigSplitHalfLogs()
+	ss.ConfigReplayLogs()
+	ss.ConfigReconLogs()
+	ss.ConfigDecodersLogs()
+	ss.ConfigBaselineLogs()
+	ss.ConfigRespLogs()
+	ss.ConfigGainLogs()
+	ss.ConfigReplicaLogs()
+	ss.ConfigOutClampLogs()
+	ss.ConfigSelectivityLogs()
+	ss.ConfigEventLogs()
+	ss.ConfigPoseLogs()
+	ss.ConfigSameDiffLogs()
+	ss.ConfigIdentityLogs()
+	ss.ConfigV1ColorLogs()
+	ss.ConfigInDriftLogs()
 
 	// Copy over Testing items
 	ss.Logs.AddCopyFromFloatItems(etime.Train, []etime.Times{etime.Epoch, etime.Run}, etime.Test, etime.Epoch, "Tst", "CorSim", "UnitErr", "PctCor", "PctErr", "PctErr2", "DecErr", "DecErr2")
+	ss.ConfigFastTestLogs()
 
 	ss.ConfigActRFs()
 
 	axon.LogAddDiagnosticItems(&ss.Logs, ss.Net.LayersByType(axon.SuperLayer, axon.TargetLayer), etime.Train, etime.Epoch, etime.Trial)
 	axon.LogAddPCAItems(&ss.Logs, ss.Net, etime.Train, etime.Run, etime.Epoch, etime.Trial)
+	ss.ConfigDimLogs()
 
 	ss.Logs.AddLayerTensorItems(ss.Net, "Act", etime.Test, etime.Trial, "TargetLayer")
 
@@ -1059,6 +1672,7 @@ func (ss *Sim) ConfigLogs() {
 
 	ss.Logs.PlotItems("CorSim", "PctErr", "PctErr2", "DecErr", "DecErr2")
 
+	ss.ConfigLogsHooks()
 	ss.Logs.CreateTables()
 	ss.Logs.SetContext(&ss.Stats, ss.Net)
 	// don't plot certain combinations we don't use
@@ -1144,6 +1758,23 @@ func (ss *Sim) ConfigLogItems() {
 				ctx.SetTensor(cats.Cols[1])
 			}}})
 
+	if ss.IsImagesEnv() { // effective visible field per Config.Env.Aspect
+		for _, st := range []string{"VisFrac", "FillFrac"} {
+			stnm := "Trl" + st
+			ss.Logs.AddItem(&elog.Item{
+				Name:  st,
+				Type:  etensor.FLOAT64,
+				Plot:  elog.DFalse,
+				Range: minmax.F64{Max: 1},
+				Write: elog.WriteMap{
+					etime.Scope(etime.AllModes, etime.Trial): func(ctx *elog.Context) {
+						ctx.SetFloat64(ss.Stats.FloatDi(stnm, ctx.Di))
+					}, etime.Scope(etime.AllModes, etime.Epoch): func(ctx *elog.Context) {
+						ctx.SetAgg(ctx.Mode, etime.Trial, agg.AggMean)
+					}}})
+		}
+	}
+
 	layers := ss.Net.LayersByType(axon.SuperLayer, axon.TargetLayer)
 	for _, lnm := range layers {
 		clnm := lnm
@@ -1213,6 +1844,51 @@ func (ss *Sim) ConfigLogItems() {
 	}
 }
 
+// AddEvent records a schedule event (lrate change, TransSigma reset,
+// SaveWeights, curriculum change, etc) for the current training epoch,
+// which is written to the Event column of the Train Epoch log and shown
+// on the epoch plot, and printed to stdout.  Multiple events in the same
+// epoch are separated by "; ".
+func (ss *Sim) AddEvent(event string) {
+	trnEpc := ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur
+	mpi.Printf("Event at epoch: %d: %s\n", trnEpc, event)
+	if cur := ss.Stats.String("EpcEvent"); cur != "" {
+		event = cur + "; " + event
+	}
+	ss.Stats.SetString("EpcEvent", event)
+}
+
+// ConfigEventLogs adds the Train Epoch Event column with the AddEvent
+// events, which is plotted as text labels on the epoch plot, and the
+// EventMark column which is 1 for epochs with events and 0 otherwise,
+// plotted as vertical markers spanning the 0-1 range.
+func (ss *Sim) ConfigEventLogs() {
+	ss.Stats.SetString("EpcEvent", "")
+	ss.Logs.AddItem(&elog.Item{
+		Name: "Event",
+		Type: etensor.STRING,
+		Plot: elog.DTrue,
+		Write: elog.WriteMap{
+			etime.Scope(etime.Train, etime.Epoch): func(ctx *elog.Context) {
+				ctx.SetStatString("EpcEvent")
+			}}})
+	ss.Logs.AddItem(&elog.Item{
+		Name:   "EventMark",
+		Type:   etensor.FLOAT64,
+		Plot:   elog.DTrue,
+		Range:  minmax.F64{Max: 1},
+		FixMin: true,
+		FixMax: true,
+		Write: elog.WriteMap{
+			etime.Scope(etime.Train, etime.Epoch): func(ctx *elog.Context) {
+				if ss.Stats.String("EpcEvent") != "" {
+					ctx.SetFloat64(1)
+				} else {
+					ctx.SetFloat64(0)
+				}
+			}}})
+}
+
 // Log is the main logging function, handles special things for different scopes
 func (ss *Sim) Log(mode etime.Modes, time etime.Times) {
 	ctx := &ss.Context
@@ -1233,12 +1909,20 @@ func (ss *Sim) Log(mode etime.Modes, time etime.Times) {
 	case time == etime.Trial:
 		for di := 0; di < int(ctx.NetIdxs.NData); di++ {
 			ss.TrialStats(di)
+			ss.ReconTrial(di)
+			ss.DecodersTrial(di)
+			ss.BaselineTrial(di)
 			ss.StatCounters(di)
 			ss.Logs.LogRowDi(mode, time, row, di)
+			ss.WriteTrialLogRow(mode)
+			ss.EmitTrialEvent(mode, di)
 		}
+		ss.BaselineUpdate()
 		return // don't do reg below
 		// case time == etime.Epoch:
 		// 	mpi.AllPrintf("Epoch trial dt rows: %d\n", ss.Logs.Table(mode, etime.Trial).Rows)
+	case time == etime.Epoch && mode != etime.Analyze:
+		ss.ConfEntStats(mode)
 	}
 
 	ss.Logs.LogRow(mode, time, row) // also logs to file, etc
@@ -1255,8 +1939,13 @@ func (ss *Sim) Log(mode etime.Modes, time etime.Times) {
 
 // ConfigActRFs
 func (ss *Sim) ConfigActRFs() {
-	ss.Stats.SetF32Tensor("Image", &ss.Envs[etime.Test.String()].(*ImagesEnv).Img.Tsr) // image used for actrfs, must be there first
-	ss.Stats.InitActRFs(ss.Net, []string{"V4f16:Image", "V4f16:Output", "TEOf16:Image", "TEOf16:Output", "TEOf8:Image", "TEOf8:Output"}, "ActM")
+	rfs := []string{"V4f16:Output", "TEOf16:Output", "TEOf8:Output"}
+	if tst := ss.ImagesEnv(etime.Test); tst != nil {
+		ss.Stats.SetF32Tensor("Image", &tst.Img.Tsr) // image used for actrfs, must be there first
+		rfs = []string{"V4f16:Image", "V4f16:Output", "TEOf16:Image", "TEOf16:Output", "TEOf8:Image", "TEOf8:Output"}
+	}
+	ss.Stats.InitActRFs(ss.Net, rfs, ActRFVar)
+	ss.ConfigFastRFs()
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////
@@ -1270,6 +1959,27 @@ func (ss *Sim) ConfigNetView(nv *netview.NetView) {
 	// cam.Pose.Quat.SetFromAxisAngle(mat32.Vec3{-1, 0, 0}, 0.4077744)
 }
 
+// UpdtImageGrids updates the Image and V1 filter output grids
+// to show the current trial input for given mode
+func (ss *Sim) UpdtImageGrids(mode etime.Modes) {
+	ev := ss.ImagesEnv(mode)
+	if ev == nil {
+		return
+	}
+	updt := func(nm string, tsr etensor.Tensor) {
+		tg := ss.GUI.Grid(nm)
+		if tg.Tensor != tsr { // switching between train and test env
+			tg.SetTensor(tsr)
+		} else {
+			tg.UpdateSig()
+		}
+	}
+	updt("Image", &ev.Img.Tsr)
+	for _, nm := range ev.V1Names() {
+		updt(nm, ev.State(nm))
+	}
+}
+
 // ConfigGui configures the GoGi gui interface for this simulation,
 func (ss *Sim) ConfigGui() *gi.Window {
 	title := "LVis Object Recognition"
@@ -1285,12 +1995,30 @@ func (ss *Sim) ConfigGui() *gi.Window {
 
 	ss.GUI.AddPlots(title, &ss.Logs)
 
-	tg := ss.GUI.TabView.AddNewTab(etview.KiT_TensorGrid, "Image").(*etview.TensorGrid)
-	tg.SetStretchMax()
-	ss.GUI.SetGrid("Image", tg)
-	tg.SetTensor(&ss.Envs[etime.Train.String()].(*ImagesEnv).Img.Tsr)
+	if trn := ss.ImagesEnv(etime.Train); trn != nil {
+		tg := ss.GUI.TabView.AddNewTab(etview.KiT_TensorGrid, "Image").(*etview.TensorGrid)
+		tg.SetStretchMax()
+		ss.GUI.SetGrid("Image", tg)
+		tg.SetTensor(&trn.Img.Tsr)
+		for _, nm := range trn.V1Names() {
+			tg := ss.GUI.TabView.AddNewTab(etview.KiT_TensorGrid, nm).(*etview.TensorGrid)
+			tg.SetStretchMax()
+			ss.GUI.SetGrid(nm, tg)
+			tg.SetTensor(trn.State(nm))
+		}
+	}
+
+	if ss.Recon.Target != nil {
+		tg := ss.GUI.TabView.AddNewTab(etview.KiT_TensorGrid, "Recon").(*etview.TensorGrid)
+		tg.SetStretchMax()
+		ss.GUI.SetGrid("Recon", tg)
+		tg.SetTensor(&ss.Recon.Out)
+	}
 
 	ss.GUI.AddActRFGridTabs(&ss.Stats.ActRFs)
+	ss.ConfigRSAGui()
+	ss.ConfigTuningGui()
+	ss.ConfigLaunchGui()
 
 	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "Init", Icon: "update",
 		Tooltip: "Initialize everything including network weights, and start over.  Also applies current params.",
@@ -1316,6 +2044,124 @@ func (ss *Sim) ConfigGui() *gi.Window {
 		},
 	})
 
+	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "Prime",
+		Icon:    "step-fwd",
+		Tooltip: "Runs the priming paradigm on the testing items: a related or unrelated prime image followed by the target, recording target response time and error.",
+		Active:  egui.ActiveStopped,
+		Func: func() {
+			if !ss.GUI.IsRunning {
+				ss.GUI.IsRunning = true
+				ss.GUI.ToolBar.UpdateActions()
+				go ss.RunPrimeGUI()
+			}
+		},
+	})
+
+	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "Cool",
+		Icon:    "step-fwd",
+		Tooltip: "Runs the virtual cooling test on the testing items: silences the Config.Run.CoolK most category-selective units in Config.Run.CoolLay, and reports the change in error per category.",
+		Active:  egui.ActiveStopped,
+		Func: func() {
+			if !ss.GUI.IsRunning {
+				ss.GUI.IsRunning = true
+				ss.GUI.ToolBar.UpdateActions()
+				go ss.RunCoolGUI()
+			}
+		},
+	})
+
+	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "Clamp",
+		Icon:    "step-fwd",
+		Tooltip: "Runs the layer clamping test on the testing items: clamps the Config.Run.ClampCat prototype (or ClampFile pattern) onto Config.Run.ClampLay over the ClampStart to ClampEnd cycles, and reports the change in the proportion of ClampCat responses per category.",
+		Active:  egui.ActiveStopped,
+		Func: func() {
+			if !ss.GUI.IsRunning {
+				ss.GUI.IsRunning = true
+				ss.GUI.ToolBar.UpdateActions()
+				go ss.RunClampGUI()
+			}
+		},
+	})
+
+	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "Gain",
+		Icon:    "step-fwd",
+		Tooltip: "Runs the layer gain test on the testing items: scales the excitatory conductance of Config.Run.GainLay by each of the Config.Run.GainFactors, and reports the test error and response time at each.",
+		Active:  egui.ActiveStopped,
+		Func: func() {
+			if !ss.GUI.IsRunning {
+				ss.GUI.IsRunning = true
+				ss.GUI.ToolBar.UpdateActions()
+				go ss.RunGainTestGUI()
+			}
+		},
+	})
+
+	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "Ablate",
+		Icon:    "step-fwd",
+		Tooltip: "Runs the layer ablation sweep on the FastTest subset of the testing items: turns off each Super layer in turn, and reports the increase in test error relative to the intact network.",
+		Active:  egui.ActiveStopped,
+		Func: func() {
+			if !ss.GUI.IsRunning {
+				ss.GUI.IsRunning = true
+				ss.GUI.ToolBar.UpdateActions()
+				go ss.RunAblateGUI()
+			}
+		},
+	})
+
+	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "RF Size",
+		Icon:    "step-fwd",
+		Tooltip: "Runs the receptive field size analysis: presents probe images at a grid of positions and sizes, and estimates the RF centroid and extent of the units in Config.Run.RFSize.Layers.",
+		Active:  egui.ActiveStopped,
+		Func: func() {
+			if !ss.GUI.IsRunning {
+				ss.GUI.IsRunning = true
+				ss.GUI.ToolBar.UpdateActions()
+				go ss.RunRFSizeGUI()
+			}
+		},
+	})
+
+	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "Illum Sweep",
+		Icon:    "step-fwd",
+		Tooltip: "Runs the illumination sweep: tests the full test set at each of the Config.Env.Illum.SweepTemps x SweepGains color temperature shifts and gains, in the Illum table of test error vs. illumination.",
+		Active:  egui.ActiveStopped,
+		Func: func() {
+			if !ss.GUI.IsRunning {
+				ss.GUI.IsRunning = true
+				ss.GUI.ToolBar.UpdateActions()
+				go ss.RunIllumSweepGUI()
+			}
+		},
+	})
+
+	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "Open Wts",
+		Icon:    "file-open",
+		Tooltip: "Opens weights from a file, and checks the current train / test split against the one saved with the weights, warning if it differs (or restoring it if Config.Env.RestoreSplit is set).",
+		Active:  egui.ActiveStopped,
+		Func: func() {
+			giv.CallMethod(ss, "OpenWeights", ss.GUI.ViewPort)
+		},
+	})
+
+	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "Compare Wts",
+		Icon:    "file-open",
+		Tooltip: "Runs the test set through two different weight files, with identical image transforms, and records per-category accuracy and per-image decision flips in cmp_cat and cmp_flips files.",
+		Active:  egui.ActiveStopped,
+		Func: func() {
+			giv.CallMethod(ss, "CompareWtsGUI", ss.GUI.ViewPort)
+		},
+	})
+
+	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "Tuning Curve",
+		Icon:    "file-sheet",
+		Tooltip: "Plots the tuning curve of a TEO or TE unit in the Tuning tab: its mean response to each test category, sorted by response, from the most recent test epoch, or over the whole test set if Full.",
+		Active:  egui.ActiveStopped,
+		Func: func() {
+			giv.CallMethod(ss, "TuningCurveGUI", ss.GUI.ViewPort)
+		},
+	})
+
 	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "Conf To Test",
 		Icon:    "fast-fwd",
 		Tooltip: "Plots accuracy from current confusion probs to test trial log for each category (diagonal of confusion matrix).",
@@ -1325,6 +2171,15 @@ func (ss *Sim) ConfigGui() *gi.Window {
 		},
 	})
 
+	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "Launch",
+		Icon:    "run",
+		Tooltip: "Runs each combination of a parameter grid in turn as a nogui subprocess with its own tag, showing their headline metrics in the Launch tab.  Stop kills the current run.",
+		Active:  egui.ActiveStopped,
+		Func: func() {
+			giv.CallMethod(ss, "LaunchGUI", ss.GUI.ViewPort)
+		},
+	})
+
 	////////////////////////////////////////////////
 	ss.GUI.ToolBar.AddSeparator("log")
 	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "Reset RunLog",
@@ -1343,7 +2198,7 @@ func (ss *Sim) ConfigGui() *gi.Window {
 		Tooltip: "Generate a new initial random seed to get different results.  By default, Init re-establishes the same initial seed every time.",
 		Active:  egui.ActiveAlways,
 		Func: func() {
-			ss.RndSeeds.NewSeeds()
+			ss.NewSeeds()
 		},
 	})
 	ss.GUI.AddToolbarItem(egui.ToolbarItem{Label: "README",
@@ -1382,6 +2237,71 @@ var SimProps = ki.Props{
 				}},
 			},
 		}},
+		{"OpenWeights", ki.Props{
+			"desc": "open weights from a file, and check the train / test split saved with them",
+			"icon": "file-open",
+			"Args": ki.PropSlice{
+				{"File Name", ki.Props{
+					"ext": ".wts,.wts.gz,.wtsz",
+				}},
+			},
+		}},
+		{"TransplantGUI", ki.Props{
+			"desc": "transplant weights from a file into only the layers and projections matching the selectors (space separated: #Name, .Class) -- a report table is in Logs.MiscTables",
+			"icon": "file-open",
+			"Args": ki.PropSlice{
+				{"File Name", ki.Props{
+					"ext": ".wts,.wts.gz,.wtsz",
+				}},
+				{"Selectors", ki.Props{}},
+			},
+		}},
+		{"SaveArch", ki.Props{
+			"desc": "save a summary of the network architecture as Graphviz .dot and .json files",
+			"icon": "file-save",
+			"Args": ki.PropSlice{
+				{"File Name", ki.Props{
+					"ext": ".dot",
+				}},
+			},
+		}},
+		{"TuningCurveGUI", ki.Props{
+			"desc": "plot the tuning curve of a TEO or TE unit: its mean response to each test category, from the most recent test epoch, or over the whole test set if Full",
+			"icon": "file-sheet",
+			"Args": ki.PropSlice{
+				{"Layer", ki.Props{
+					"default-field": "Tuning.Lay",
+				}},
+				{"Unit", ki.Props{
+					"default-field": "Tuning.Unit",
+				}},
+				{"Full", ki.Props{}},
+			},
+		}},
+		{"LaunchGUI", ki.Props{
+			"desc": "run each combination of a parameter grid of space-separated Arg=val1,val2,.. items in turn as a nogui subprocess, with the additional Args for all runs, shown in the Launch tab",
+			"icon": "run",
+			"Args": ki.PropSlice{
+				{"Grid", ki.Props{
+					"default-field": "Launcher.Grid",
+				}},
+				{"Args", ki.Props{
+					"default-field": "Launcher.Args",
+				}},
+			},
+		}},
+		{"CompareWtsGUI", ki.Props{
+			"desc": "run the test set through two weight files and compare per-category accuracy and per-image decisions",
+			"icon": "file-open",
+			"Args": ki.PropSlice{
+				{"A", ki.Props{
+					"ext": ".wts,.wts.gz,.wtsz",
+				}},
+				{"B", ki.Props{
+					"ext": ".wts,.wts.gz,.wtsz",
+				}},
+			},
+		}},
 	},
 }
 
@@ -1396,31 +2316,70 @@ func (ss *Sim) RunNoGUI() {
 	ss.Stats.SetString("RunName", runName) // used for naming logs, stats, etc
 	netName := ss.Net.Name()
 
-	if mpi.WorldRank() == 0 {
+	if ss.Config.Run.Resume != "" {
+		if err := ss.OpenCheckpoint(ss.Config.Run.Resume); err != nil {
+			mpi.Println(err)
+			ss.MPIFinalize()
+			return
+		}
+		ck := ss.Signal.Resume
+		ss.Stats.SetString("RunName", ck.RunName)
+		runName = fmt.Sprintf("%s_resume%05d", ck.RunName, ck.Epoch) // keep the prior logs
+	}
+
+	if ss.Config.Run.DatasetStats {
+		if err := ss.DatasetStats(); err != nil {
+			mpi.Println(err)
+		}
+		ss.MPIFinalize()
+		return
+	}
+
+	if len(ss.Config.Run.ExportLogs) > 0 {
+		if ss.MPIRank() == 0 {
+			if err := ss.ExportLogs(ss.Config.Run.ExportLogs); err != nil {
+				mpi.Println(err)
+			}
+		}
+		ss.MPIFinalize()
+		return
+	}
+
+	if ss.MPIRank() == 0 {
 		elog.SetLogFile(&ss.Logs, ss.Config.Log.Epoch, etime.Train, etime.Epoch, "epc", netName, runName)
 		elog.SetLogFile(&ss.Logs, ss.Config.Log.Run, etime.Train, etime.Run, "run", netName, runName)
 		elog.SetLogFile(&ss.Logs, ss.Config.Log.TestEpoch, etime.Test, etime.Epoch, "tst_epc", netName, runName)
 	}
 	// Special cases for mpi per-node saving of trial data
 	if ss.Config.Log.Trial {
-		fnm := elog.LogFileName(fmt.Sprintf("trl_%d", mpi.WorldRank()), netName, runName)
-		ss.Logs.SetLogFile(etime.Train, etime.Trial, fnm)
+		fnm := elog.LogFileName(fmt.Sprintf("trl_%d", ss.MPIRank()), netName, runName)
+		ss.SetTrialLogFile(etime.Train, fnm, ss.Config.Log.TrialCols)
 	}
 	if ss.Config.Log.TestTrial {
-		fnm := elog.LogFileName(fmt.Sprintf("tst_trl_%d", mpi.WorldRank()), netName, runName)
-		ss.Logs.SetLogFile(etime.Test, etime.Trial, fnm)
+		fnm := elog.LogFileName(fmt.Sprintf("tst_trl_%d", ss.MPIRank()), netName, runName)
+		ss.SetTrialLogFile(etime.Test, fnm, ss.Config.Log.TestTrialCols)
 	}
 
-	netdata := ss.Config.Log.NetData
-	if netdata {
-		mpi.Printf("Saving NetView data from testing\n")
-		ss.GUI.InitNetData(ss.Net, 200)
+	ss.OpenTrigger(netName, runName)
+	ss.OpenEvents(netName, runName)
+	ss.ProfileServe()
+
+	if ss.Config.Log.Arch && ss.MPIRank() == 0 {
+		if err := ss.SaveArch(gi.FileName(netName + "_" + runName + "_arch")); err != nil {
+			mpi.Println(err)
+		}
 	}
 
+	ss.SaveTrainFrac(netName, runName)
+	ss.InitNetData()
+
 	ss.Init()
 
 	mpi.Printf("Running %d Runs starting at %d\n", ss.Config.Run.NRuns, ss.Config.Run.Run)
 	ss.Loops.GetLoop(etime.Train, etime.Run).Counter.SetCurMaxPlusN(ss.Config.Run.Run, ss.Config.Run.NRuns)
+	if ck := ss.Signal.Resume; ck != nil {
+		ss.Loops.GetLoop(etime.Train, etime.Run).Counter.SetCurMax(ck.Run, ck.RunMax)
+	}
 
 	if ss.Config.Run.GPU {
 		if ss.Config.Run.MPI && ss.Config.Run.GPUSameNodeMPI {
@@ -1433,28 +2392,124 @@ func (ss *Sim) RunNoGUI() {
 	}
 	mpi.Printf("Set NThreads to: %d\n", ss.Net.NThreads)
 
+	if len(ss.Config.Run.CompareWts) == 2 {
+		ss.RunNoGUIMode(false, func() error {
+			return ss.CompareWts(gi.FileName(ss.Config.Run.CompareWts[0]), gi.FileName(ss.Config.Run.CompareWts[1]))
+		})
+		return
+	}
+
+	if ss.Config.Run.EvalWts != "" {
+		run := ss.RunEvalWts
+		if ss.Config.Run.EvalDist {
+			run = ss.RunEvalDist
+		}
+		ss.RunNoGUIMode(false, run)
+		return
+	}
+
+	if ss.Config.Run.SeedAgree != "" {
+		ss.RunNoGUIMode(false, ss.RunSeedAgree)
+		return
+	}
+
+	if ss.Config.Run.SWA != "" {
+		ss.RunNoGUIMode(false, ss.RunSWA)
+		return
+	}
+
+	if ss.Config.Run.Prime {
+		ss.RunNoGUIMode(true, func() error {
+			ss.RunPrime()
+			return nil
+		})
+		return
+	}
+
+	if ss.Config.Run.Cool {
+		ss.RunNoGUIMode(true, ss.RunCool)
+		return
+	}
+
+	if ss.Config.Run.Clamp {
+		ss.RunNoGUIMode(true, ss.RunClamp)
+		return
+	}
+
+	if ss.Config.Run.Gain {
+		ss.RunNoGUIMode(true, ss.RunGainTest)
+		return
+	}
+
+	if ss.Config.Run.Ablate {
+		ss.RunNoGUIMode(true, ss.RunAblate)
+		return
+	}
+
+	if ss.Config.Env.Illum.Sweep {
+		ss.RunNoGUIMode(true, ss.RunIllumSweep)
+		return
+	}
+
+	if ss.Config.Run.RFSize.On {
+		ss.RunNoGUIMode(true, ss.RunRFSize)
+		return
+	}
+
+	if ss.Config.Run.Infer {
+		ss.RunNoGUIMode(false, ss.RunInfer)
+		return
+	}
+
+	ss.HandleSignals()
+
 	tmr := timer.Time{}
 	tmr.Start()
 
 	ss.Loops.Run(etime.Train)
+	ss.ProfileStop()
 
 	tmr.Stop()
 	if ss.Config.Bench {
 		tm := tmr.TotalSecs()
-		ptmsec := (tm / float64(ss.Config.Run.NTrials)) * 1000
+		ptmsec := (tm / float64(ss.Trials.EffTotal)) * 1000
 		// note: getting some variability across nodes here -- keeping this as all print
-		mpi.AllPrintf("Total Time: %6.3g   Bench Per Trl Msec: %g\n", tm, ptmsec)
+		mpi.AllPrintf("Total Time: %6.3g   Bench Per Trl Msec: %g   High16: %v\n", tm, ptmsec, ss.Config.Env.High16)
+		ss.BenchTimes.Total = tmr
+		ss.SaveBench(netName, runName)
 	} else {
 		mpi.Printf("Total Time: %6.3g\n", tmr.TotalSecs())
 	}
 	ss.Net.TimerReport()
 
-	ss.Logs.CloseLogFiles()
+	ss.SaveRunStats(netName, runName)
+	ss.CloseLogFiles()
+	ss.MPISearchResults()
+	ss.ShutdownNoGUI()
+}
 
-	if netdata {
-		ss.GUI.SaveNetData(ss.Stats.String("RunName"))
+// RunNoGUIMode runs given alternative nogui mode function instead of
+// training, after opening the Config.Run.OpenWts weights if openWts and
+// set, reporting any error, followed by ShutdownNoGUI
+func (ss *Sim) RunNoGUIMode(openWts bool, fun func() error) {
+	if openWts && ss.Config.Run.OpenWts != "" {
+		if err := ss.OpenWeights(gi.FileName(ss.Config.Run.OpenWts)); err != nil {
+			mpi.Println(err)
+		}
+	}
+	if err := fun(); err != nil {
+		mpi.Println(err)
 	}
+	ss.ShutdownNoGUI()
+}
 
+// ShutdownNoGUI closes all the log files, NetData, trigger and events
+// recordings, and releases the GPU and MPI, at the end of a nogui run
+func (ss *Sim) ShutdownNoGUI() {
+	ss.CloseLogFiles() // safe to call again
+	ss.FlushNetData()
+	ss.CloseTrigger()
+	ss.CloseEvents()
 	ss.Net.GPU.Destroy() // safe even if no GPU
 	ss.MPIFinalize()
 }
@@ -1473,6 +2528,12 @@ func (ss *Sim) MPIInit() {
 	} else {
 		mpi.Printf("MPI running on %d procs\n", mpi.WorldSize())
 	}
+	if ss.Config.Run.MPI && ss.Config.Run.Search != "" {
+		if err := ss.MPISearchInit(); err != nil {
+			mpi.Println(err)
+			ss.Comm.Abort()
+		}
+	}
 }
 
 // MPIFinalize finalizes MPI
@@ -1484,36 +2545,31 @@ func (ss *Sim) MPIFinalize() {
 
 // MPIWtFmDWt updates weights from weight changes, using MPI to integrate
 // DWt changes across parallel nodes, each of which are learning on different
-// sequences of inputs.
+// sequences of inputs.  The synapses are synced from and to the GPU once,
+// around all the CPU-side changes to the DWts.
 func (ss *Sim) MPIWtFmDWt() {
 	ctx := &ss.Context
-	if ss.Config.Run.MPI {
-		ss.Net.CollectDWts(ctx, &ss.AllDWts)
-		ss.Comm.AllReduceF32(mpi.OpSum, ss.AllDWts, nil) // in place
-		ss.Net.SetDWts(ctx, ss.AllDWts, mpi.WorldSize())
+	cpu := ss.Config.Run.MPI || len(ss.SharedPrjns) > 0 || ss.WtDecayOn()
+	if cpu {
+		ss.Net.GPU.SyncSynapsesFmGPU()
 	}
-	ss.Net.WtFmDWt(ctx)
-}
-
-func (ss *Sim) AssertMPIReplicaConsistency() {
-	if ss.Comm.Size() == 1 {
-		return
-	}
-	hash := ss.Net.WtsHash()
-	orig := []uint8(hash)
-	var MPIHashes []byte
-	if ss.Comm.Rank() == 0 {
-		MPIHashes = make([]byte, ss.Comm.Size()*len(orig))
-	}
-	err := ss.Comm.GatherU8(0, MPIHashes, orig)
-	if err != nil {
-		panic(err)
-	}
-	if ss.Comm.Rank() == 0 {
-		for i := 0; i < ss.Comm.Size(); i++ {
-			if string(MPIHashes[i*len(orig):(i+1)*len(orig)]) != hash {
-				panic("Hashes do not match! The models on different nodes have diverged.")
+	if ss.Config.Run.MPI {
+		ss.BenchTimes.AllReduce.Start()
+		ss.CollectDWts() // only trainable prjns
+		if ss.Config.Debug {
+			if err := ss.CheckDWtMap(); err != nil {
+				log.Fatalln(err)
 			}
 		}
+		ss.Comm.AllReduceF32(mpi.OpSum, ss.AllDWts, nil) // in place
+		ss.SetDWts(ss.Comm.Size())
+		ss.BenchTimes.AllReduce.Stop()
+	}
+	ss.ShareDWts()
+	ss.WtDecay()
+	if cpu {
+		ss.Net.GPU.SyncSynapsesToGPU() // gpu will use dwts to update
 	}
+	ss.RewireMask()
+	ss.Net.WtFmDWt(ctx)
 }
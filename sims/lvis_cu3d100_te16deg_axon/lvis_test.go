@@ -0,0 +1,118 @@
+// run like this:
+// go test -v -run TestLvisShort
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emer/emergent/etime"
+)
+
+// smokeCats are the categories of synthetic grating images used for TestLvisShort,
+// each with a different orientation
+var smokeCats = []string{"horiz", "vert", "diag", "anti"}
+
+// MakeGratings writes a small set of synthetic grating images into dir,
+// using the cat_item_n.png naming convention of the CU3D images, with
+// nitm items per category (differing in spatial frequency) and nimg
+// images per item (differing in phase).
+func MakeGratings(dir string, nitm, nimg int) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	sz := 64
+	for ci, cat := range smokeCats {
+		ang := float64(ci) * math.Pi / float64(len(smokeCats))
+		ca, sa := math.Cos(ang), math.Sin(ang)
+		for it := 0; it < nitm; it++ {
+			freq := 2 * math.Pi * float64(3+it) / float64(sz)
+			for ii := 0; ii < nimg; ii++ {
+				phase := float64(ii) * math.Pi / float64(nimg)
+				img := image.NewRGBA(image.Rect(0, 0, sz, sz))
+				for y := 0; y < sz; y++ {
+					for x := 0; x < sz; x++ {
+						v := 0.5 + 0.5*math.Sin(freq*(ca*float64(x)+sa*float64(y))+phase)
+						g := uint8(255 * v)
+						img.Set(x, y, color.RGBA{g, g, uint8(255 - int(g)), 255})
+					}
+				}
+				fnm := filepath.Join(dir, fmt.Sprintf("%s_%03d_%05d.png", cat, it, ii))
+				f, err := os.Create(fnm)
+				if err != nil {
+					return err
+				}
+				err = png.Encode(f, img)
+				f.Close()
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// TestLvisShort builds the full network and runs 2 epochs of training
+// and testing on synthetic gratings, on the CPU with NData = 2,
+// checking that logs are populated and weights remain finite.
+// This catches breakage from emergent / axon API changes in CI-scale time.
+func TestLvisShort(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping full network build in short mode")
+	}
+	dir := t.TempDir()
+	imgs := filepath.Join(dir, "images")
+	if err := MakeGratings(imgs, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	sim := &Sim{}
+	sim.New()
+
+	sim.Config.GUI = false
+	sim.Config.Env.Path = imgs
+	sim.Config.Env.ImageFile = filepath.Join(dir, "gratings")
+	sim.Config.Run.GPU = false
+	sim.Config.Run.NData = 2
+	sim.Config.Run.NRuns = 1
+	sim.Config.Run.NEpochs = 2
+	sim.Config.Run.NTrials = 4
+	sim.Config.Run.TestInterval = 1
+	sim.Config.Log.SaveWts = false
+	sim.Config.Log.Run = false
+	sim.Config.Log.Epoch = false
+
+	sim.ConfigAll()
+	sim.RunNoGUI()
+
+	trn := sim.Envs.ByMode(etime.Train).(*ImagesEnv)
+	if len(trn.Images.Cats) != len(smokeCats) {
+		t.Errorf("number of categories: %d != %d", len(trn.Images.Cats), len(smokeCats))
+	}
+	if rows := sim.Logs.Table(etime.Train, etime.Epoch).Rows; rows != 2 {
+		t.Errorf("train epoch log rows: %d != 2", rows)
+	}
+	if rows := sim.Logs.Table(etime.Test, etime.Trial).Rows; rows == 0 {
+		t.Errorf("test trial log is empty")
+	}
+
+	var wts []float32
+	for _, ly := range sim.Net.Layers {
+		for _, pj := range ly.SndPrjns {
+			pj.SynVals(&wts, "Wt")
+			for i, w := range wts {
+				if math.IsNaN(float64(w)) || math.IsInf(float64(w), 0) {
+					t.Fatalf("prjn: %s weight %d is not finite: %g", pj.Name(), i, w)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/emer/emergent/env"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/emergent/params"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etensor"
+)
+
+// lvisenv.go has the LvisEnv interface, for plugging a custom environment
+// into the sim in place of the ImagesEnv, to reuse the network and training
+// with a different stimulus generator, without modifying images_env.go.
+//
+// To add one, write a type implementing LvisEnv in a new file in this
+// package, and register it in an init() function:
+//
+//	func init() {
+//		LvisEnvTypes["MyEnv"] = func(mode etime.Modes) LvisEnv { return &MyEnv{} }
+//	}
+//
+// and select it with Config.Env.Type = "MyEnv" (-Env.Type MyEnv).
+// The Config.Env.Env map is applied to the custom env as for the ImagesEnv.
+//
+// Features that depend on the images themselves (e.g., ActRFs on the Image,
+// priming, the train / test split, difficulty and pose stats) are only
+// available with the ImagesEnv, and are skipped for custom envs.
+
+// EnvTypeImages is the Config.Env.Type for the standard ImagesEnv
+const EnvTypeImages = "Images"
+
+// LvisEnv is the interface for environments that drive the Lvis network.
+// In addition to the standard env.Env methods, it provides the category
+// labels and the scoring of the Output layer against them.
+type LvisEnv interface {
+	env.Env
+
+	// State must return the input pattern for each of the input layers
+	// of the network, by layer name: V1m16, V1l16, V1m8, V1l8, the color
+	// DoG V1Cm16, V1Cl16, V1Cm8, V1Cl8 (and V1h16 for Config.Env.High16),
+	// and the target pattern for the Output layer, for the current trial,
+	// after Step.  The V1 patterns must have the 4D shape of the
+	// corresponding layer, as in the ImagesEnv.  The Output pattern must
+	// be 2D, and have its shape after Init, as it sets the Output layer size.
+	State(element string) etensor.Tensor
+
+	// CatNames returns the category labels, in category index order --
+	// the number of categories must fit in the Output layer
+	CatNames() []string
+
+	// CurCatName returns the category label and index for the current trial
+	CurCatName() (string, int)
+
+	// OutErrMargin scores the Output activity pattern in given tensor
+	// against given target category index, returning the index of the
+	// category closest to the output, the error (0 = correct, 1 = error),
+	// top-two error (0 if correct is one of the closest two), and margin:
+	// the distance to the closest other category minus the distance to
+	// the correct one.
+	OutErrMargin(tsr *etensor.Float32, curCatIdx int) (maxi int, err, err2, margin float64)
+
+	// MPIAlloc allocates the trials for given MPI proc rank, out of
+	// nproc procs, so each proc runs a different subset
+	MPIAlloc(rank, nproc int)
+
+	// String returns the name of the current trial, for logging
+	String() string
+}
+
+// LvisEnvTypes are the constructors of custom LvisEnv types, by
+// Config.Env.Type name -- see the lvisenv.go comments for how to add one.
+// The constructor is called for the Train and Test modes, and the
+// env Name must be the mode name (Train or Test).
+var LvisEnvTypes = map[string]func(mode etime.Modes) LvisEnv{}
+
+// CatNames returns the category labels, in category index order
+func (ev *ImagesEnv) CatNames() []string {
+	return ev.Images.Cats
+}
+
+// CurCatName returns the current category label and index
+func (ev *ImagesEnv) CurCatName() (string, int) {
+	return ev.CurCat, ev.CurCatIdx
+}
+
+// LvisEnv returns the env for given mode
+func (ss *Sim) LvisEnv(mode etime.Modes) LvisEnv {
+	return ss.Envs.ByMode(mode).(LvisEnv)
+}
+
+// ImagesEnv returns the ImagesEnv for given mode, or nil if a custom
+// LvisEnv is being used -- image-specific features check for nil
+func (ss *Sim) ImagesEnv(mode etime.Modes) *ImagesEnv {
+	ev, _ := ss.Envs.ByMode(mode).(*ImagesEnv)
+	return ev
+}
+
+// IsImagesEnv returns true if the standard ImagesEnv is being used
+func (ss *Sim) IsImagesEnv() bool {
+	return ss.Config.Env.Type == "" || ss.Config.Env.Type == EnvTypeImages
+}
+
+// ConfigCustomEnv configures the train and test envs of the custom
+// Config.Env.Type -- called in ConfigEnv in place of the ImagesEnv config
+func (ss *Sim) ConfigCustomEnv() error {
+	typ := ss.Config.Env.Type
+	newEnv, ok := LvisEnvTypes[typ]
+	if !ok {
+		var typs []string
+		for nm := range LvisEnvTypes {
+			typs = append(typs, nm)
+		}
+		sort.Strings(typs)
+		return fmt.Errorf("Env.Type: %s not found, available: %s %v", typ, EnvTypeImages, typs)
+	}
+	if len(ss.Envs) > 0 { // can be called multiple times -- don't re-create
+		return nil
+	}
+	ss.Config.Run.Difficulty = false // image-specific
+	ss.Config.Env.Pose.On = false
+	for _, mode := range []etime.Modes{etime.Train, etime.Test} {
+		ev := newEnv(mode)
+		if ev.Name() != mode.String() {
+			return fmt.Errorf("Env.Type: %s: env Name: %s must be: %s", typ, ev.Name(), mode)
+		}
+		if ss.Config.Env.Env != nil {
+			params.ApplyMap(ev, ss.Config.Env.Env, ss.Config.Debug)
+		}
+		if err := ev.Validate(); err != nil {
+			return err
+		}
+		if ss.Config.Run.MPI {
+			ev.MPIAlloc(ss.MPIRank(), ss.MPISize())
+		}
+		ev.Init(0)
+		ss.Envs.Add(ev)
+	}
+	mpi.Printf("Using custom env: %s with %d categories\n", typ, len(ss.LvisEnv(etime.Train).CatNames()))
+	return nil
+}
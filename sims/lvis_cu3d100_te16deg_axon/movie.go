@@ -0,0 +1,339 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/colormap"
+)
+
+// movie.go has the recording of a movie of layer activity over the cycles
+// of a selected test trial, exported as an animated GIF or a directory of
+// PNG frames, to show the temporal sweep of activation up the hierarchy.
+// The layers are shown left to right in the montage, bottom aligned, with
+// Y increasing upward and gaps between pools as in the NetView.
+// See Config.Log.Movie.
+
+// MovieDefLayers are the layers recorded if Config.Log.Movie.Layers is empty
+var MovieDefLayers = []string{"V1m16", "V2m16", "V4f16", "TEOf16", "TE", "Output"}
+
+const (
+	// MovieNColors is the number of color map colors in the movie palette
+	MovieNColors = 254
+
+	// MovieNaNIdx is the palette index for NaN values
+	MovieNaNIdx = MovieNColors
+
+	// MovieBgIdx is the palette index for the background
+	MovieBgIdx = MovieNColors + 1
+
+	// MovieLayGap is the gap between layers, in units
+	MovieLayGap = 2
+)
+
+// Movie has the state for recording a movie of layer activity
+type Movie struct {
+
+	// data index of the trial being recorded, -1 if not recording
+	Di int
+
+	// test trial index being recorded
+	Trial int
+
+	// layout and palette of the layers being recorded
+	Montage
+
+	// recorded frames
+	Frames []*image.Paletted
+
+	// cycle of each frame
+	Cycles []int
+
+	// GPU CycleByCycle setting prior to recording
+	CycByCyc bool
+
+	// unit values for current layer
+	Vals etensor.Float32
+}
+
+// Montage is the layout of a set of layers side by side in an image,
+// as used for the movie frames and activity snapshots
+type Montage struct {
+
+	// layers shown in the montage
+	Lays []*axon.Layer
+
+	// x offset of each layer in the montage, in units
+	XOffs []int
+
+	// size of the montage, in units
+	Size image.Point
+
+	// color palette
+	Palette color.Palette
+}
+
+// MovieLayShape returns the size of the given layer in the montage,
+// in units, with a gap between pools for 4D layers
+func MovieLayShape(ly *axon.Layer) image.Point {
+	shp := ly.Shape()
+	if shp.NumDims() == 4 {
+		return image.Point{shp.Dim(1)*(shp.Dim(3)+1) - 1, shp.Dim(0)*(shp.Dim(2)+1) - 1}
+	}
+	return image.Point{shp.Dim(1), shp.Dim(0)}
+}
+
+// ConfigMovie configures the Movie layers, layout and palette
+func (ss *Sim) ConfigMovie() {
+	mc := &ss.Config.Log.Movie
+	mv := &ss.Movie
+	mv.Di = -1
+	mv.Lays = nil
+	if mc.Trial < 0 {
+		return
+	}
+	lnms := mc.Layers
+	if len(lnms) == 0 {
+		lnms = MovieDefLayers
+	}
+	mv.Config(ss.Net, lnms, mc.ColorMap)
+}
+
+// Config configures the layout of the given layers, and the palette
+// from the given color map, reporting any layers not found
+func (mt *Montage) Config(net *axon.Network, lnms []string, colorMap string) {
+	mt.Lays = nil
+	mt.XOffs = nil
+	mt.Size = image.Point{}
+	for _, lnm := range lnms {
+		ly, err := net.LayByNameTry(lnm)
+		if err != nil {
+			mpi.Println("Montage:", err)
+			continue
+		}
+		if len(mt.Lays) > 0 {
+			mt.Size.X += MovieLayGap
+		}
+		mt.Lays = append(mt.Lays, ly)
+		mt.XOffs = append(mt.XOffs, mt.Size.X)
+		lsz := MovieLayShape(ly)
+		mt.Size.X += lsz.X
+		if lsz.Y > mt.Size.Y {
+			mt.Size.Y = lsz.Y
+		}
+	}
+	cm, ok := colormap.AvailMaps[colorMap]
+	if !ok {
+		mpi.Printf("Montage: ColorMap: %s not found, using Viridis\n", colorMap)
+		cm = colormap.AvailMaps["Viridis"]
+	}
+	mt.Palette = make(color.Palette, MovieNColors+2)
+	for i := 0; i < MovieNColors; i++ {
+		mt.Palette[i] = cm.Map(float64(i) / float64(MovieNColors-1))
+	}
+	mt.Palette[MovieNaNIdx] = cm.NoColor
+	mt.Palette[MovieBgIdx] = color.White
+}
+
+// MovieStart starts recording the movie if the Config.Log.Movie.Trial
+// is in the current batch of test trials -- called at the start of the
+// test trial, after ApplyInputs.  On the GPU, the trial is run cycle by
+// cycle so the neuron state is synced every cycle.
+func (ss *Sim) MovieStart() {
+	mc := &ss.Config.Log.Movie
+	mv := &ss.Movie
+	mv.Di = -1
+	if mc.Trial < 0 || len(mv.Lays) == 0 || ss.MPIRank() != 0 {
+		return
+	}
+	trl := ss.Loops.GetLoop(etime.Test, etime.Trial).Counter.Cur
+	if mc.Trial < trl || mc.Trial >= trl+int(ss.Context.NetIdxs.NData) {
+		return
+	}
+	mv.Di = mc.Trial - trl
+	mv.Trial = mc.Trial
+	mv.Frames = nil
+	mv.Cycles = nil
+	if ss.Config.Run.GPU {
+		mv.CycByCyc = ss.Net.GPU.CycleByCycle
+		ss.Net.GPU.CycleByCycle = true
+	}
+}
+
+// MovieFrame records a frame of the movie every Config.Log.Movie.Interval
+// cycles, if recording -- called at the end of each test cycle
+func (ss *Sim) MovieFrame() {
+	mc := &ss.Config.Log.Movie
+	mv := &ss.Movie
+	if mv.Di < 0 {
+		return
+	}
+	cyc := int(ss.Context.Cycle)
+	if mc.Interval > 1 && cyc%mc.Interval != 0 {
+		return
+	}
+	mv.Frames = append(mv.Frames, ss.MovieImage(mv.Di))
+	mv.Cycles = append(mv.Cycles, cyc)
+}
+
+// MovieImage returns the montage image of the current activity
+// of the Movie layers for given data index
+func (ss *Sim) MovieImage(di int) *image.Paletted {
+	mc := &ss.Config.Log.Movie
+	mv := &ss.Movie
+	return mv.Image(mc.Scale, mc.Max, func(ly *axon.Layer) []float32 {
+		if err := ly.UnitValsTensor(&mv.Vals, mc.Var, di); err != nil {
+			return nil
+		}
+		return mv.Vals.Values
+	})
+}
+
+// Image returns the montage image of the unit values of each layer,
+// as returned by the vals function (skipped if nil), with each unit
+// sc pixels in size, and the color map from 0 to max
+func (mt *Montage) Image(sc int, max float32, vals func(ly *axon.Layer) []float32) *image.Paletted {
+	if sc < 1 {
+		sc = 1
+	}
+	img := image.NewPaletted(image.Rect(0, 0, mt.Size.X*sc, mt.Size.Y*sc), mt.Palette)
+	for i := range img.Pix {
+		img.Pix[i] = MovieBgIdx
+	}
+	setUnit := func(x, y int, val float32) { // y from bottom
+		ci := uint8(MovieNaNIdx)
+		if !math.IsNaN(float64(val)) {
+			nv := val / max
+			switch {
+			case nv < 0:
+				nv = 0
+			case nv > 1:
+				nv = 1
+			}
+			ci = uint8(nv*float32(MovieNColors-1) + 0.5)
+		}
+		py := (mt.Size.Y - 1 - y) * sc
+		for yi := 0; yi < sc; yi++ {
+			off := img.PixOffset(x*sc, py+yi)
+			for xi := 0; xi < sc; xi++ {
+				img.Pix[off+xi] = ci
+			}
+		}
+	}
+	for li, ly := range mt.Lays {
+		vals := vals(ly)
+		if vals == nil {
+			continue
+		}
+		xo := mt.XOffs[li]
+		shp := ly.Shape()
+		if shp.NumDims() == 4 {
+			npy, npx, nuy, nux := shp.Dim(0), shp.Dim(1), shp.Dim(2), shp.Dim(3)
+			for py := 0; py < npy; py++ {
+				for px := 0; px < npx; px++ {
+					for uy := 0; uy < nuy; uy++ {
+						for ux := 0; ux < nux; ux++ {
+							idx := ((py*npx+px)*nuy+uy)*nux + ux
+							setUnit(xo+px*(nux+1)+ux, py*(nuy+1)+uy, vals[idx])
+						}
+					}
+				}
+			}
+			continue
+		}
+		ny, nx := shp.Dim(0), shp.Dim(1)
+		for y := 0; y < ny; y++ {
+			for x := 0; x < nx; x++ {
+				setUnit(xo+x, y, vals[y*nx+x])
+			}
+		}
+	}
+	return img
+}
+
+// MovieSave saves the recorded movie, if recording, as an animated GIF
+// or a directory of PNG frames per Config.Log.Movie.Format, named by the
+// trial and training epoch -- called at the end of the test trial.
+func (ss *Sim) MovieSave() {
+	mc := &ss.Config.Log.Movie
+	mv := &ss.Movie
+	if mv.Di < 0 {
+		return
+	}
+	mv.Di = -1
+	if ss.Config.Run.GPU {
+		ss.Net.GPU.CycleByCycle = mv.CycByCyc
+	}
+	if len(mv.Frames) == 0 {
+		return
+	}
+	epc := ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur
+	lnm := fmt.Sprintf("movie_trl%d_%05d", mv.Trial, epc)
+	fnm := strings.TrimSuffix(elog.LogFileName(lnm, ss.Net.Name(), ss.Stats.String("RunName")), ".tsv")
+	var err error
+	if mc.Format == "png" {
+		err = mv.SavePNGs(fnm)
+	} else {
+		fnm += ".gif"
+		err = mv.SaveGIF(fnm, mc.Delay)
+	}
+	if err != nil {
+		mpi.Println(err)
+		return
+	}
+	lnms := make([]string, len(mv.Lays))
+	for li, ly := range mv.Lays {
+		lnms[li] = ly.Name()
+	}
+	mpi.Printf("Saved movie of %s for cycles %d-%d to: %s\n", strings.Join(lnms, ", "), mv.Cycles[0], mv.Cycles[len(mv.Cycles)-1], fnm)
+}
+
+// SaveGIF saves the frames as an animated GIF with given delay
+// between frames, in 100ths of a second
+func (mv *Movie) SaveGIF(fnm string, delay int) error {
+	anim := &gif.GIF{Image: mv.Frames, Delay: make([]int, len(mv.Frames))}
+	for i := range anim.Delay {
+		anim.Delay[i] = delay
+	}
+	f, err := os.Create(fnm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gif.EncodeAll(f, anim)
+}
+
+// SavePNGs saves the frames as numbered PNG files in given directory
+func (mv *Movie) SavePNGs(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i, img := range mv.Frames {
+		f, err := os.Create(filepath.Join(dir, fmt.Sprintf("%04d.png", i)))
+		if err != nil {
+			return err
+		}
+		err = png.Encode(f, img)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/empi/mpi"
+)
+
+// mpidwt.go has the sharing of the DWt weight changes across MPI procs,
+// which only communicates the synapses of the trainable projections,
+// unlike the axon Network CollectDWts / SetDWts that ship all of them,
+// including the zero DWts of frozen projections (Learn.Learn = false),
+// which greatly reduces the bandwidth for staged training configs.
+// The layer-level average activity values are shared for all layers.
+
+// DWtMap maps the synapses of the trainable projections into the
+// buffer of values shared across MPI procs.  It is updated whenever
+// the set of trainable projections changes (e.g., by Dropout), which
+// must be the same on all procs, or the buffers summed across procs would
+// not line up: Dropout draws from Net.Rand, which is seeded the same on
+// all procs, so every proc must consume the same sequence of values from
+// it.  In Debug mode, the buffer size is checked across procs on every
+// update: see CheckDWtMap.
+type DWtMap struct {
+
+	// trainable projections, in buffer order
+	Prjns []*axon.Prjn
+
+	// learning flag for all projections in the network, in order, for detecting changes
+	Learn []bool
+
+	// total number of values in the buffer
+	N int
+
+	// number of synapses in trainable projections
+	NSyns int
+
+	// total number of synapses in all projections
+	NSynsAll int
+
+	// number of values in the buffer if all projections were trainable, for allocating it once
+	NMax int
+}
+
+// Update updates the map if the set of trainable projections in
+// the network has changed, returning true if so
+func (dm *DWtMap) Update(net *axon.Network) bool {
+	pi := 0
+	chg := dm.Learn == nil
+	for _, ly := range net.Layers {
+		for _, pj := range ly.SndPrjns {
+			lrn := !pj.IsOff() && pj.Params.Learn.Learn.IsTrue()
+			if pi >= len(dm.Learn) {
+				dm.Learn = append(dm.Learn, lrn)
+				chg = true
+			} else if dm.Learn[pi] != lrn {
+				dm.Learn[pi] = lrn
+				chg = true
+			}
+			pi++
+		}
+	}
+	if !chg {
+		return false
+	}
+	dm.Prjns = dm.Prjns[:0]
+	dm.N = 0
+	dm.NSyns = 0
+	dm.NSynsAll = 0
+	pi = 0
+	for _, ly := range net.Layers {
+		dm.N += 6 + int(ly.NNeurons) // ActAvg vals, ActAvg
+		if ly.Params.IsLearnTrgAvg() {
+			dm.N += int(ly.NNeurons)
+		}
+		for _, pj := range ly.SndPrjns {
+			dm.NSynsAll += int(pj.NSyns)
+			if dm.Learn[pi] {
+				dm.Prjns = append(dm.Prjns, pj)
+				dm.NSyns += int(pj.NSyns)
+			}
+			pi++
+		}
+	}
+	dm.NMax = dm.N + dm.NSynsAll
+	dm.N += dm.NSyns
+	return true
+}
+
+// CollectDWts collects the layer average activity values and the DWt
+// values of the trainable projections into AllDWts, per the DWtMap.
+// The synapses must be synced from the GPU: see MPIWtFmDWt.
+func (ss *Sim) CollectDWts() {
+	ctx := &ss.Context
+	net := ss.Net
+	dm := &ss.DWtMap
+	if dm.Update(net) && ss.AllDWts == nil {
+		mpi.Printf("MPI DWts: sharing %d of %d synapses, in %d trainable prjns\n", dm.NSyns, dm.NSynsAll, len(dm.Prjns))
+	}
+	if cap(ss.AllDWts) < dm.NMax {
+		ss.AllDWts = make([]float32, dm.NMax)
+	}
+	ss.AllDWts = ss.AllDWts[:dm.N]
+	dwts := ss.AllDWts
+	idx := 0
+	for _, ly := range net.Layers {
+		lv := ly.LayerVals(0)
+		dwts[idx+0] = lv.ActAvg.ActMAvg
+		dwts[idx+1] = lv.ActAvg.ActPAvg
+		dwts[idx+2] = lv.ActAvg.AvgMaxGeM
+		dwts[idx+3] = lv.ActAvg.AvgMaxGiM
+		dwts[idx+4] = lv.ActAvg.GiMult
+		dwts[idx+5] = lv.ActAvg.AdaptThr
+		idx += 6
+		nn := int(ly.NNeurons)
+		for lni := 0; lni < nn; lni++ {
+			dwts[idx+lni] = axon.NrnAvgV(ctx, ly.NeurStIdx+uint32(lni), axon.ActAvg)
+		}
+		idx += nn
+		if ly.Params.IsLearnTrgAvg() {
+			for lni := 0; lni < nn; lni++ {
+				dwts[idx+lni] = axon.NrnAvgV(ctx, ly.NeurStIdx+uint32(lni), axon.DTrgAvg)
+			}
+			idx += nn
+		}
+	}
+	for _, pj := range dm.Prjns {
+		ns := int(pj.NSyns)
+		for syi := 0; syi < ns; syi++ {
+			dwts[idx+syi] = axon.SynV(ctx, pj.SynStIdx+uint32(syi), axon.DWt)
+		}
+		idx += ns
+	}
+}
+
+// SetDWts sets the layer average activity values, averaged over the
+// navg procs, and the summed DWt values of the trainable projections,
+// from AllDWts per the DWtMap.  The synapses must be synced to the GPU
+// afterward: see MPIWtFmDWt.
+func (ss *Sim) SetDWts(navg int) {
+	ctx := &ss.Context
+	net := ss.Net
+	dwts := ss.AllDWts
+	davg := 1 / float32(navg)
+	idx := 0
+	for _, ly := range net.Layers {
+		lv := ly.LayerVals(0)
+		lv.ActAvg.ActMAvg = davg * dwts[idx+0]
+		lv.ActAvg.ActPAvg = davg * dwts[idx+1]
+		lv.ActAvg.AvgMaxGeM = davg * dwts[idx+2]
+		lv.ActAvg.AvgMaxGiM = davg * dwts[idx+3]
+		lv.ActAvg.GiMult = davg * dwts[idx+4]
+		lv.ActAvg.AdaptThr = davg * dwts[idx+5]
+		idx += 6
+		nn := int(ly.NNeurons)
+		for lni := 0; lni < nn; lni++ {
+			axon.SetNrnAvgV(ctx, ly.NeurStIdx+uint32(lni), axon.ActAvg, davg*dwts[idx+lni])
+		}
+		idx += nn
+		if ly.Params.IsLearnTrgAvg() {
+			for lni := 0; lni < nn; lni++ {
+				axon.SetNrnAvgV(ctx, ly.NeurStIdx+uint32(lni), axon.DTrgAvg, dwts[idx+lni])
+			}
+			idx += nn
+		}
+	}
+	for _, pj := range ss.DWtMap.Prjns {
+		ns := int(pj.NSyns)
+		for syi := 0; syi < ns; syi++ {
+			axon.SetSynV(ctx, pj.SynStIdx+uint32(syi), axon.DWt, dwts[idx+syi])
+		}
+		idx += ns
+	}
+}
+
+// CheckDWtMap checks that the DWtMap buffer has the same size on all
+// procs, returning an error if not, which means the set of trainable
+// projections differs across procs -- see DWtMap
+func (ss *Sim) CheckDWtMap() error {
+	ns := []int{ss.DWtMap.N}
+	mn := make([]int, 1)
+	mx := make([]int, 1)
+	ss.Comm.AllReduceInt(mpi.OpMin, mn, ns)
+	ss.Comm.AllReduceInt(mpi.OpMax, mx, ns)
+	if mn[0] != mx[0] {
+		return fmt.Errorf("CheckDWtMap: MPI DWts buffer size differs across procs: min: %d max: %d -- trainable projections are out of sync", mn[0], mx[0])
+	}
+	return nil
+}
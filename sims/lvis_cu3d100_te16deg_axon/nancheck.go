@@ -0,0 +1,212 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// nancheck.go has the periodic scan of the neuron state and synaptic
+// weights for NaN / Inf values, which aborts the run on detection, after
+// dumping the state of the offending layer and saving the weights, so a
+// run that blows up does not continue silently.  See Config.Run.NaNCheck.
+
+// NaNReport records the first NaN / Inf value found in a NaNScan,
+// and the total number of such values
+type NaNReport struct {
+
+	// layer with the first bad value -- the receiving layer for synapses
+	Layer string
+
+	// projection with the first bad value, if in a synapse
+	Prjn string
+
+	// variable with the first bad value
+	Var string
+
+	// unit (neuron within layer, or synapse within projection) index
+	Idx int
+
+	// data index, for neuron variables
+	Di int
+
+	// the bad value
+	Val float32
+
+	// total number of bad values
+	N int
+}
+
+// String returns a description of the first bad value
+func (nr *NaNReport) String() string {
+	if nr.Prjn != "" {
+		return fmt.Sprintf("%d NaN / Inf values, first in prjn: %s var: %s syn: %d val: %g", nr.N, nr.Prjn, nr.Var, nr.Idx, nr.Val)
+	}
+	return fmt.Sprintf("%d NaN / Inf values, first in layer: %s var: %s unit: %d di: %d val: %g", nr.N, nr.Layer, nr.Var, nr.Idx, nr.Di, nr.Val)
+}
+
+// IsBad returns true if the value is NaN or Inf
+func IsBad(v float32) bool {
+	return math.IsNaN(float64(v)) || math.IsInf(float64(v), 0)
+}
+
+// NaNScan scans all neuron variables, and if wts is true, the synaptic
+// weight variables, for NaN / Inf values, returning nil if none are found.
+// The state must be synced from the GPU.
+func (ss *Sim) NaNScan(wts bool) *NaNReport {
+	ctx := &ss.Context
+	nd := ctx.NetIdxs.NData
+	var nr *NaNReport
+	for _, ly := range ss.Net.Layers {
+		if ly.IsOff() {
+			continue
+		}
+		for lni := uint32(0); lni < ly.NNeurons; lni++ {
+			ni := ly.NeurStIdx + lni
+			for vi := axon.NeuronVars(0); vi < axon.NeuronVarsN; vi++ {
+				for di := uint32(0); di < nd; di++ {
+					v := axon.NrnV(ctx, ni, di, vi)
+					if !IsBad(v) {
+						continue
+					}
+					if nr == nil {
+						nr = &NaNReport{Layer: ly.Name(), Var: vi.String(), Idx: int(lni), Di: int(di), Val: v}
+					}
+					nr.N++
+				}
+			}
+		}
+	}
+	if !wts {
+		return nr
+	}
+	for _, ly := range ss.Net.Layers {
+		if ly.IsOff() {
+			continue
+		}
+		for _, pj := range ly.RcvPrjns {
+			if pj.IsOff() {
+				continue
+			}
+			for syi := uint32(0); syi < pj.NSyns; syi++ {
+				syni := pj.SynStIdx + syi
+				for vi := axon.Wt; vi < axon.SynapseVarsN; vi++ {
+					v := axon.SynV(ctx, syni, vi)
+					if !IsBad(v) {
+						continue
+					}
+					if nr == nil {
+						nr = &NaNReport{Layer: ly.Name(), Prjn: pj.Name(), Var: vi.String(), Idx: int(syi), Val: v}
+					}
+					nr.N++
+				}
+			}
+		}
+	}
+	return nr
+}
+
+// NaNDumpLayer returns a table with all the neuron variables for all
+// units and data indexes in given layer
+func (ss *Sim) NaNDumpLayer(ly *axon.Layer) *etable.Table {
+	ctx := &ss.Context
+	nd := ctx.NetIdxs.NData
+	sch := etable.Schema{
+		{"Unit", etensor.INT64, nil, nil},
+		{"Di", etensor.INT64, nil, nil},
+	}
+	for vi := axon.NeuronVars(0); vi < axon.NeuronVarsN; vi++ {
+		sch = append(sch, etable.Column{vi.String(), etensor.FLOAT32, nil, nil})
+	}
+	dt := &etable.Table{}
+	dt.SetFromSchema(sch, int(ly.NNeurons*nd))
+	row := 0
+	for lni := uint32(0); lni < ly.NNeurons; lni++ {
+		ni := ly.NeurStIdx + lni
+		for di := uint32(0); di < nd; di++ {
+			dt.SetCellFloat("Unit", row, float64(lni))
+			dt.SetCellFloat("Di", row, float64(di))
+			for vi := axon.NeuronVars(0); vi < axon.NeuronVarsN; vi++ {
+				dt.SetCellFloatIdx(2+int(vi), row, float64(axon.NrnV(ctx, ni, di, vi)))
+			}
+			row++
+		}
+	}
+	return dt
+}
+
+// NaNCheck scans the network state for NaN / Inf values every
+// Config.Run.NaNCheck training trials, and on detection (on any MPI proc),
+// dumps the state of the offending layer (on each proc that has one),
+// saves the weights (on rank 0), and aborts the run: in nogui mode,
+// the program exits with an error status.  Called at the end of the
+// training trial.
+func (ss *Sim) NaNCheck() {
+	intv := ss.Config.Run.NaNCheck
+	if intv <= 0 {
+		return
+	}
+	trl := ss.Loops.GetLoop(etime.Train, etime.Trial).Counter.Cur / int(ss.Context.NetIdxs.NData)
+	if trl%intv != 0 {
+		return
+	}
+	wts := ss.Config.Run.NaNCheckWts
+	if ss.Config.Run.GPU {
+		ss.Net.GPU.SyncNeuronsFmGPU()
+		if wts {
+			ss.Net.GPU.SyncSynapsesFmGPU()
+		}
+	}
+	nr := ss.NaNScan(wts)
+	nbad := []float64{0}
+	if nr != nil {
+		nbad[0] = float64(nr.N)
+	}
+	if ss.Config.Run.MPI {
+		orig := []float64{nbad[0]}
+		ss.Comm.AllReduceF64(mpi.OpSum, nbad, orig)
+	}
+	if nbad[0] == 0 {
+		return
+	}
+	ctr := ss.Stats.PrintVals([]string{"Run", "Epoch", "Trial"}, []string{"%03d", "%05d", "%05d"}, "_")
+	runName := ss.Stats.String("RunName")
+	if nr != nil {
+		mpi.AllPrintf("NaNCheck: rank %d at %s: %s\n", ss.MPIRank(), ctr, nr)
+		fnm := elog.LogFileName(fmt.Sprintf("nandump_%s_%s_%d", nr.Layer, ctr, ss.MPIRank()), ss.Net.Name(), runName)
+		if err := ss.NaNDumpLayer(ss.Net.AxonLayerByName(nr.Layer)).SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+			mpi.AllPrintf("%v\n", err)
+		} else {
+			mpi.AllPrintf("NaNCheck: saved layer state to: %s\n", fnm)
+		}
+	}
+	if ss.MPIRank() == 0 {
+		if !wts && ss.Config.Run.GPU {
+			ss.Net.GPU.SyncSynapsesFmGPU()
+		}
+		fnm := axon.WeightsFileName(ss.Net, ctr+"_nan", runName)
+		mpi.Printf("NaNCheck: saving weights to: %s\n", fnm)
+		ss.Net.SaveWtsJSON(gi.FileName(fnm))
+	}
+	mpi.Printf("NaNCheck: aborting run: %g NaN / Inf values found at %s\n", nbad[0], ctr)
+	if ss.Config.GUI {
+		ss.Loops.Stop(etime.Run)
+		return
+	}
+	ss.CloseLogFiles()
+	ss.Net.GPU.Destroy()
+	ss.MPIFinalize()
+	os.Exit(1)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+)
+
+// ndata.go has the NData schedule, which sets the number of data-parallel
+// trials per training step (the effective mini-batch size) over training
+// epochs, e.g., using smaller batches early in training.  The network state
+// is allocated for the max Config.Run.NData, so the scheduled values only
+// use a subset of the data indexes, and no buffers need to be reallocated:
+// the Context with the new NData is synced to the GPU, and the training
+// trial loop increment is updated, at the start of the epoch.  Testing
+// always uses the full NData.  See Config.Run.NDataEpochs, NDataVals.
+
+// NDataSchedVal returns the scheduled NData for given training epoch
+func (ss *Sim) NDataSchedVal(epc int) int {
+	rc := &ss.Config.Run
+	nd := rc.NData
+	for i, e := range rc.NDataEpochs {
+		if i >= len(rc.NDataVals) || epc < e {
+			break
+		}
+		nd = rc.NDataVals[i]
+	}
+	return nd
+}
+
+// ValidateNDataSched checks that the scheduled NData values are between 1
+// and the Config.Run.NData, and evenly divide it, so that the trials per
+// epoch (an even multiple of NData) are an even multiple of each value.
+// Invalid values are replaced with the NData.
+func (ss *Sim) ValidateNDataSched() {
+	rc := &ss.Config.Run
+	for i, nd := range rc.NDataVals {
+		if nd >= 1 && nd <= rc.NData && rc.NData%nd == 0 {
+			continue
+		}
+		mpi.Printf("NDataVals: %d must be between 1 and NData: %d, and evenly divide it -- using NData\n", nd, rc.NData)
+		rc.NDataVals[i] = rc.NData
+	}
+}
+
+// SetNData sets the number of data-parallel trials per step, up to the
+// allocated Config.Run.NData, syncing the Context to the GPU.
+// If train is true, the training trial loop increment is set to match,
+// and the value is recorded as the current training NData.
+func (ss *Sim) SetNData(nd int, train bool) {
+	ctx := &ss.Context
+	if train {
+		ss.NDataTrain = nd
+		ss.Stats.SetInt("NData", nd)
+		ss.Loops.GetLoop(etime.Train, etime.Trial).Counter.Inc = nd
+	}
+	if int(ctx.NetIdxs.NData) == nd {
+		return
+	}
+	ctx.NetIdxs.NData = uint32(nd)
+	ss.Net.GPU.SyncContextToGPU()
+}
+
+// NDataSched sets the scheduled NData for the current training epoch
+// -- called at the start of the epoch
+func (ss *Sim) NDataSched() {
+	if len(ss.Config.Run.NDataEpochs) == 0 {
+		return
+	}
+	epc := ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur
+	nd := ss.NDataSchedVal(epc)
+	if nd == ss.NDataTrain {
+		return
+	}
+	ss.SetNData(nd, true)
+	ss.AddEvent(fmt.Sprintf("NData: %d", nd))
+}
+
+// InitNData sets the NData for the start of the run
+func (ss *Sim) InitNData() {
+	ss.SetNData(ss.NDataSchedVal(0), true)
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/emer/empi/mpi"
+	"github.com/goki/gi/gi"
+)
+
+// netdata.go has the on-disk ring buffer for the NetView data recorded
+// from testing trials in nogui mode: records are kept in memory in chunks
+// of Config.Log.NetDataRecs, and each full chunk is written to the next
+// of Config.Log.NetDataFiles files, cycling back to the first, so the data
+// from cluster runs survives crashes, and the total can be larger than
+// memory.  Each chunk is written to a temporary file that is then renamed,
+// so the files are always complete, and each can be opened in the NetView.
+// The records are also used for the activity snapshots (see snapshot.go).
+// See Config.Log.NetData.
+
+// InitNetData initializes the in-memory NetData record, if
+// Config.Log.NetData is set or snapshots are on -- called in RunNoGUI
+func (ss *Sim) InitNetData() {
+	if !ss.Config.Log.NetData && !ss.SnapshotOn() {
+		return
+	}
+	nrecs := ss.Config.Log.NetDataRecs
+	if nrecs <= 0 {
+		nrecs = 200
+	}
+	if ss.Config.Log.NetData {
+		mpi.Printf("Saving NetView data from testing, in files of %d records\n", nrecs)
+	}
+	ss.GUI.InitNetData(ss.Net, nrecs)
+	ss.NetDataFile = 0
+}
+
+// NetDataRecord records the NetView data for the current testing trial,
+// for the first data index, saves any snapshot, and writes the chunk to
+// disk when full
+func (ss *Sim) NetDataRecord() {
+	nd := ss.GUI.NetData
+	if nd == nil {
+		return
+	}
+	ss.StatCounters(0)
+	ss.GUI.NetDataRecord(ss.NetViewText())
+	ss.SnapshotRecord()
+	if nd.Ring.Len >= nd.Ring.Max {
+		ss.FlushNetData()
+	}
+}
+
+// NetDataFileName returns the name of the given on-disk NetData file,
+// named by the network and run
+func (ss *Sim) NetDataFileName(idx int) string {
+	return fmt.Sprintf("%s_%s_%03d.netdata.gz", ss.Net.Name(), ss.Stats.String("RunName"), idx)
+}
+
+// FlushNetData writes the NetData records in memory, if any, to the next
+// on-disk file in the ring buffer, if Config.Log.NetData, and resets the
+// in-memory record
+func (ss *Sim) FlushNetData() {
+	nd := ss.GUI.NetData
+	if nd == nil || nd.Ring.Len == 0 {
+		return
+	}
+	if !ss.Config.Log.NetData {
+		ss.ResetNetData()
+		return
+	}
+	fnm := ss.NetDataFileName(ss.NetDataFile)
+	tmp := strings.TrimSuffix(fnm, ".netdata.gz") + "_tmp.netdata.gz"
+	if err := nd.SaveJSON(gi.FileName(tmp)); err != nil {
+		mpi.Println("NetData:", err)
+		os.Remove(tmp)
+		return
+	}
+	if err := os.Rename(tmp, fnm); err != nil {
+		mpi.Println("NetData:", err)
+		return
+	}
+	ss.NetDataFile++
+	if nf := ss.Config.Log.NetDataFiles; nf > 0 && ss.NetDataFile >= nf {
+		ss.NetDataFile = 0
+	}
+	ss.ResetNetData()
+}
+
+// ResetNetData resets the in-memory NetData record
+func (ss *Sim) ResetNetData() {
+	nd := ss.GUI.NetData
+	nd.Ring.Reset()
+	nd.RastCtr = 0
+	nd.RasterMap = make(map[int]int)
+}
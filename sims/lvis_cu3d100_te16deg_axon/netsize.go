@@ -0,0 +1,85 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// netsize.go has the network size presets, for scaling experiments:
+// each preset sets the number of units per pool in V2 (and V3h), V4,
+// TEO, and TE, and the number of pools in TEO and TE, in a consistent
+// way.  The number of pools in V2 and V4 is determined by the V1 filter
+// topography and the 4x4 skip 2 topographic projections (doubled with
+// SubPools), so it is the same for all presets, and the V4 <-> TEO and
+// TEO <-> TE projections connect to all pools, so they match any size.
+// See Config.Params.NetSize.
+
+// NetSize has the sizes of the hidden layers for one preset.
+// Sizes are the number of units or pools along each side (Y = X).
+type NetSize struct {
+
+	// units per pool in V2 and V3h, without SubPools
+	V2Nu int
+
+	// units per pool in V2 and V3h, with SubPools
+	V2NuSub int
+
+	// units per pool in V4, without SubPools
+	V4Nu int
+
+	// units per pool in V4, with SubPools
+	V4NuSub int
+
+	// pools in TEOf16 and TEOf8
+	TEONp int
+
+	// units per pool in TEOf16 and TEOf8
+	TEONu int
+
+	// pools in TE
+	TENp int
+
+	// units per pool in TE
+	TENu int
+}
+
+// V2V4Nu returns the number of units per pool in V2 and V4
+// depending on whether SubPools are used
+func (ns *NetSize) V2V4Nu(subPools bool) (v2Nu, v4Nu int) {
+	if subPools {
+		return ns.V2NuSub, ns.V4NuSub
+	}
+	return ns.V2Nu, ns.V4Nu
+}
+
+// NetSizes are the network size presets, by name, for
+// Config.Params.NetSize -- standard is the original size
+var NetSizes = map[string]NetSize{
+	"tiny":     {V2Nu: 4, V2NuSub: 3, V4Nu: 5, V4NuSub: 4, TEONp: 2, TEONu: 8, TENp: 2, TENu: 8},
+	"small":    {V2Nu: 6, V2NuSub: 4, V4Nu: 7, V4NuSub: 5, TEONp: 2, TEONu: 11, TENp: 2, TENu: 11},
+	"standard": {V2Nu: 8, V2NuSub: 6, V4Nu: 10, V4NuSub: 7, TEONp: 2, TEONu: 15, TENp: 2, TENu: 15},
+	"large":    {V2Nu: 11, V2NuSub: 8, V4Nu: 14, V4NuSub: 10, TEONp: 3, TEONu: 18, TENp: 3, TENu: 18},
+}
+
+// NetSize returns the network size preset per Config.Params.NetSize,
+// which defaults to standard if empty
+func (ss *Sim) NetSize() (NetSize, error) {
+	nm := ss.Config.Params.NetSize
+	if nm == "" {
+		nm = "standard"
+	}
+	ns, ok := NetSizes[nm]
+	if !ok {
+		var nms []string
+		for n := range NetSizes {
+			nms = append(nms, n)
+		}
+		sort.Strings(nms)
+		return ns, fmt.Errorf("Params.NetSize: %s not found, available: %v", nm, nms)
+	}
+	return ns, nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/emergent/erand"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/metric"
+)
+
+// oddoneout.go has the odd-one-out evaluation, a similarity judgment task
+// comparable to human behavioral data: triplets of test images are formed,
+// two from the same category and one from a different category, and the
+// model's choice of the odd one is the item outside the most similar pair,
+// by the cosine of the layer representations.  The score is the proportion
+// of triplets where the model picks the different-category item (chance
+// = 1/3).  The triplets are drawn from the exemplars recorded over each
+// test epoch, on each MPI proc, with a fixed random seed, so they are
+// comparable across epochs.  See Config.Run.OddOneOut.
+
+// OddOneOutSeed is the default random seed for drawing the triplets,
+// offset by the MPI rank -- see RunSeed
+const OddOneOutSeed = 7331
+
+// OddOneOutStatName returns the name of the odd-one-out stat for given layer
+func OddOneOutStatName(lnm string) string {
+	return "OddOneOut_" + lnm
+}
+
+// OddOneOutReps records the layer representations of the test items,
+// up to Config.Run.OddOneOutMaxPer per category, for each layer
+type OddOneOutReps map[string][][][]float32
+
+// InitOddOneOut resets the recorded representations,
+// at the start of each test epoch
+func (ss *Sim) InitOddOneOut() {
+	if ss.Config.Run.OddOneOut <= 0 {
+		return
+	}
+	ncats := len(ss.LvisEnv(etime.Test).CatNames())
+	ss.OddOneOut = make(OddOneOutReps)
+	for _, lnm := range ss.Config.Run.OddOneOutLays {
+		ss.OddOneOut[lnm] = make([][][]float32, ncats)
+	}
+}
+
+// OddOneOutRecord records the current ActM activity of the OddOneOutLays
+// layers, for all data indexes, for categories not yet at OddOneOutMaxPer.
+// Called at the end of each test trial, after trial stats are computed.
+func (ss *Sim) OddOneOutRecord() {
+	if ss.Config.Run.OddOneOut <= 0 || ss.OddOneOut == nil {
+		return
+	}
+	maxPer := ss.Config.Run.OddOneOutMaxPer
+	for di := 0; di < int(ss.Context.NetIdxs.NData); di++ {
+		cat := ss.Stats.IntDi("TrlCatIdx", di)
+		for lnm, reps := range ss.OddOneOut {
+			if cat < 0 || cat >= len(reps) || len(reps[cat]) >= maxPer {
+				continue
+			}
+			var vals []float32
+			ss.Net.AxonLayerByName(lnm).UnitVals(&vals, "ActM", di)
+			reps[cat] = append(reps[cat], vals)
+		}
+	}
+}
+
+// OddOneOutScore returns the number of correct odd-one-out choices,
+// and the number of triplets, for n triplets drawn from given recorded
+// representations per category, using given random generator
+func OddOneOutScore(reps [][][]float32, n int, rnd *erand.SysRand) (ncor, ntri int) {
+	var pairCats, allCats []int
+	for ci, cr := range reps {
+		if len(cr) >= 2 {
+			pairCats = append(pairCats, ci)
+		}
+		if len(cr) >= 1 {
+			allCats = append(allCats, ci)
+		}
+	}
+	if len(pairCats) == 0 || len(allCats) < 2 {
+		return
+	}
+	for ti := 0; ti < n; ti++ {
+		ca := pairCats[rnd.Intn(len(pairCats), -1)]
+		cb := ca
+		for cb == ca {
+			cb = allCats[rnd.Intn(len(allCats), -1)]
+		}
+		ar := reps[ca]
+		ai := rnd.Intn(len(ar), -1)
+		aj := rnd.Intn(len(ar)-1, -1)
+		if aj >= ai {
+			aj++
+		}
+		a1, a2 := ar[ai], ar[aj]
+		b := reps[cb][rnd.Intn(len(reps[cb]), -1)]
+		same := metric.Cosine32(a1, a2)
+		if same > metric.Cosine32(a1, b) && same > metric.Cosine32(a2, b) {
+			ncor++
+		}
+		ntri++
+	}
+	return
+}
+
+// OddOneOutStats computes the odd-one-out score for each layer from the
+// representations recorded over the test epoch, summed across MPI procs.
+// Called at the end of each test epoch, before logging.
+func (ss *Sim) OddOneOutStats() {
+	if ss.Config.Run.OddOneOut <= 0 || ss.OddOneOut == nil {
+		return
+	}
+	n := ss.Config.Run.OddOneOut / ss.MPISize()
+	if n < 1 {
+		n = 1
+	}
+	for _, lnm := range ss.Config.Run.OddOneOutLays {
+		rnd := erand.NewSysRand(ss.Seeds["OddOneOut"] + int64(ss.MPIRank()))
+		ncor, ntri := OddOneOutScore(ss.OddOneOut[lnm], n, rnd)
+		cts := []float64{float64(ncor), float64(ntri)}
+		if ss.Config.Run.MPI {
+			orig := []float64{cts[0], cts[1]}
+			ss.Comm.AllReduceF64(mpi.OpSum, cts, orig)
+		}
+		score := 0.0
+		if cts[1] > 0 {
+			score = cts[0] / cts[1]
+		}
+		ss.Stats.SetFloat(OddOneOutStatName(lnm), score)
+	}
+}
+
+// ConfigOddOneOutLogs adds log items for the odd-one-out stats at the
+// test epoch level, copied to the train epoch and run logs with a Tst
+// prefix, to track the similarity judgments across training.
+// Layers not found in the network are removed from OddOneOutLays.
+func (ss *Sim) ConfigOddOneOutLogs() {
+	if ss.Config.Run.OddOneOut <= 0 {
+		return
+	}
+	if len(ss.Config.Run.OddOneOutLays) == 0 {
+		ss.Config.Run.OddOneOutLays = []string{"TE"}
+	}
+	var lays, nms []string
+	for _, lnm := range ss.Config.Run.OddOneOutLays {
+		if _, err := ss.Net.LayByNameTry(lnm); err != nil {
+			mpi.Println("OddOneOut:", err)
+			continue
+		}
+		lays = append(lays, lnm)
+		nm := OddOneOutStatName(lnm)
+		ss.Stats.SetFloat(nm, 0)
+		ss.Logs.AddStatFloatNoAggItem(etime.Test, etime.Epoch, nm)
+		nms = append(nms, nm)
+	}
+	ss.Config.Run.OddOneOutLays = lays
+	ss.Logs.AddCopyFromFloatItems(etime.Train, []etime.Times{etime.Epoch, etime.Run}, etime.Test, etime.Epoch, "Tst", nms...)
+}
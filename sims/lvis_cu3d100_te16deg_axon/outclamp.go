@@ -0,0 +1,72 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/etime"
+)
+
+// outclamp.go has the supervised "clamped" pretraining phase, in which
+// the Output layer is driven as an Input layer, fully clamped to the
+// category pattern in both phases, for the first Config.Run.OutClampEpochs
+// training epochs, to shape the top-down weights from the Output before
+// switching to the standard Target mode.  Testing always uses Target mode.
+
+// SetOutClamp sets the Output layer to be clamped as an InputLayer if on,
+// else the standard TargetLayer, syncing the params to the GPU if changed
+func (ss *Sim) SetOutClamp(on bool) {
+	ly := ss.Net.AxonLayerByName("Output")
+	typ := axon.TargetLayer
+	if on {
+		typ = axon.InputLayer
+	}
+	if ly.Params.LayType == typ {
+		return
+	}
+	ly.Typ = typ
+	ly.Params.LayType = typ
+	ss.Net.GPU.SyncParamsToGPU()
+}
+
+// InitOutClamp restores the Output to Target mode at the start of the
+// run -- the clamping is set at the start of the first epoch
+func (ss *Sim) InitOutClamp() {
+	ss.OutClamp = false
+	ss.SetOutClamp(false)
+}
+
+// OutClampSched sets the Output clamping for the current training epoch,
+// logging the switch to Target mode -- called at the start of the epoch
+func (ss *Sim) OutClampSched() {
+	nepc := ss.Config.Run.OutClampEpochs
+	if nepc <= 0 {
+		return
+	}
+	epc := ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur
+	on := epc < nepc
+	ss.OutClamp = on
+	if on {
+		ss.Stats.SetInt("OutClamp", 1)
+	} else {
+		ss.Stats.SetInt("OutClamp", 0)
+	}
+	ss.SetOutClamp(on)
+	if epc == nepc {
+		ss.AddEvent(fmt.Sprintf("OutClamp: off, Output in Target mode after %d epochs", nepc))
+	}
+}
+
+// ConfigOutClampLogs adds the OutClamp epoch log item, which is 1
+// for the clamped pretraining epochs
+func (ss *Sim) ConfigOutClampLogs() {
+	if ss.Config.Run.OutClampEpochs <= 0 {
+		return
+	}
+	ss.Stats.SetInt("OutClamp", 0)
+	ss.Logs.AddStatIntNoAggItem(etime.Train, etime.Epoch, "OutClamp")
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// outlayout.go has the alternative spatial layouts of the localist output
+// patterns, as the layout of the output units interacts with the spatial
+// extent of inhibition in the Output layer.  All use the same 2D Output
+// layer as the standard Block layout, of OutSize.Y x OutSize.X * NOutPer
+// units, with the categories in order:
+//
+//	Block     NOutPer contiguous units along X for each category (standard)
+//	Scatter   NOutPer units for each category at random positions across
+//	          the whole layer, non-overlapping, fixed by OutLayoutSeed
+//	Gaussian  a graded Gaussian blob of OutSigma units width, centered on
+//	          the Block units of each category -- values below
+//	          OutGaussMin are set to 0
+//
+// The response is scored by correlation with the patterns in all cases.
+
+// OutLayoutSeed is the random seed for the Scatter output layout,
+// fixed so that the patterns are the same for all envs and MPI procs
+const OutLayoutSeed = 1049
+
+// OutGaussMin is the minimum value of the Gaussian output patterns,
+// below which they are set to 0
+const OutGaussMin = 0.1
+
+// ConfigPatsSchema sets the shape of the Output state and the
+// schema of the patterns for the 2D localist layouts
+func (ev *ImagesEnv) ConfigPatsSchema() {
+	oshp := []int{ev.OutSize.Y, ev.OutSize.X * ev.NOutPer}
+	oshpnm := []string{"Y", "X"}
+	ev.Output.SetShape(oshp, nil, oshpnm)
+	sch := etable.Schema{
+		{"Name", etensor.STRING, nil, nil},
+		{"Output", etensor.FLOAT32, oshp, oshpnm},
+	}
+	ev.Pats.SetFromSchema(sch, ev.MaxOut)
+}
+
+// ConfigPatsScatter configures the output patterns: localist case
+// with the NOutPer units of each category scattered across the layer
+func (ev *ImagesEnv) ConfigPatsScatter() {
+	ev.ConfigPatsSchema()
+	rnd := rand.New(rand.NewSource(OutLayoutSeed))
+	perm := rnd.Perm(ev.OutSize.Y * ev.OutSize.X * ev.NOutPer)
+	for pi := 0; pi < ev.MaxOut; pi++ {
+		out := ev.Pats.CellTensor("Output", pi)
+		si := ev.NOutPer * pi
+		for i := 0; i < ev.NOutPer; i++ {
+			out.SetFloat1D(perm[si+i], 1)
+		}
+	}
+	ev.ConfigPatsName()
+}
+
+// ConfigPatsGaussian configures the output patterns: localist case
+// with a Gaussian blob of activity centered on the NOutPer units of
+// each category, as in ConfigPatsLocalist2D
+func (ev *ImagesEnv) ConfigPatsGaussian() {
+	ev.ConfigPatsSchema()
+	ny, nx := ev.OutSize.Y, ev.OutSize.X*ev.NOutPer
+	sig := float64(ev.OutSigma)
+	if sig <= 0 {
+		sig = 1
+	}
+	for pi := 0; pi < ev.MaxOut; pi++ {
+		out := ev.Pats.CellTensor("Output", pi)
+		si := ev.NOutPer * pi
+		cy := float64(si / nx)
+		cx := float64(si%nx) + 0.5*float64(ev.NOutPer-1)
+		for y := 0; y < ny; y++ {
+			for x := 0; x < nx; x++ {
+				dy, dx := float64(y)-cy, float64(x)-cx
+				v := math.Exp(-(dx*dx + dy*dy) / (2 * sig * sig))
+				if v < OutGaussMin {
+					continue
+				}
+				out.SetFloat1D(y*nx+x, v)
+			}
+		}
+	}
+	ev.ConfigPatsName()
+}
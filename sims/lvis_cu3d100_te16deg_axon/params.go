@@ -90,6 +90,17 @@ var ParamSets = netparams.Sets{
 				"Layer.Inhib.Layer.Gi":       "1.0",  // 1.1?
 				"Layer.Inhib.Pool.Gi":        "1.05", // was 0.95 but gi mult goes up..
 			}},
+		{Sel: ".V3h", Desc: "pool inhib, sparse activity -- High16 pathway only, same as V4",
+			Params: params.Params{
+				"Layer.Inhib.ActAvg.Nominal": "0.02",  // same as V2, V4
+				"Layer.Inhib.ActAvg.Offset":  "0.008", //
+				"Layer.Inhib.ActAvg.AdaptGi": "true",  //
+				"Layer.Inhib.Pool.On":        "true",  // needs pool-level
+				"Layer.Inhib.Layer.FB":       "1",     //
+				"Layer.Inhib.Pool.FB":        "4",
+				"Layer.Inhib.Layer.Gi":       "1.0",
+				"Layer.Inhib.Pool.Gi":        "1.05",
+			}},
 		{Sel: ".V4", Desc: "pool inhib, sparse activity",
 			Params: params.Params{
 				"Layer.Inhib.ActAvg.Nominal": "0.02",  // .02 1.6.15 SSGi
@@ -140,6 +151,27 @@ var ParamSets = netparams.Sets{
 				"Layer.Learn.RLRate.SpkThr":     "0.1",  // 0.1 def
 				"Layer.Learn.RLRate.Min":        "0.001",
 			}},
+		{Sel: "#SameDiff", Desc: "same / different output head, one of two response groups active -- see Config.Env.SameDiff",
+			Params: params.Params{
+				"Layer.Inhib.Layer.Gi":          "1.2",
+				"Layer.Inhib.Layer.FB":          "4",
+				"Layer.Inhib.ActAvg.Nominal":    "0.5",
+				"Layer.Inhib.ActAvg.AdaptGi":    "true",
+				"Layer.Acts.Clamp.Ge":           "0.8",
+				"Layer.Learn.RLRate.On":         "true",
+				"Layer.Learn.RLRate.SigmoidMin": "0.05",
+			}},
+		{Sel: "#IdOutput", Desc: "identity output head, localist with one unit per object -- see Config.Env.Id",
+			Params: params.Params{
+				"Layer.Inhib.Layer.Gi":          "1.17",
+				"Layer.Inhib.Layer.FB":          "4",
+				"Layer.Inhib.ActAvg.Nominal":    "0.005",
+				"Layer.Inhib.ActAvg.Offset":     "0.01",
+				"Layer.Inhib.ActAvg.AdaptGi":    "true",
+				"Layer.Acts.Clamp.Ge":           "0.8",
+				"Layer.Learn.RLRate.On":         "true",
+				"Layer.Learn.RLRate.SigmoidMin": "0.05",
+			}},
 		// {Sel: "#Claustrum", Desc: "testing -- not working",
 		// 	Params: params.Params{
 		// 		"Layer.Inhib.Layer.Gi":    "0.8",
@@ -219,6 +251,21 @@ var ParamSets = netparams.Sets{
 			Params: params.Params{
 				"Prjn.PrjnScale.Abs": "1.0", // 1.0 prev, 1.2 not better
 			}},
+		{Sel: ".V2V3", Desc: "High16 pathway: same as V2V4",
+			Params: params.Params{
+				"Prjn.PrjnScale.Abs":  "1.0",
+				"Prjn.SWts.Init.Mean": "0.4",
+				"Prjn.SWts.Limit.Min": "0.1",
+				"Prjn.SWts.Limit.Max": "0.7",
+			}},
+		{Sel: ".V3V4", Desc: "High16 pathway: weaker than main V2V4 inputs into V4f16",
+			Params: params.Params{
+				"Prjn.PrjnScale.Abs":  "1.0",
+				"Prjn.PrjnScale.Rel":  "0.5", // untested -- keeps V2m16, V2l16 dominant
+				"Prjn.SWts.Init.Mean": "0.4",
+				"Prjn.SWts.Limit.Min": "0.1",
+				"Prjn.SWts.Limit.Max": "0.7",
+			}},
 		{Sel: "#V2m16ToV4f16", Desc: "weights into V416 getting too high",
 			Params: params.Params{
 				"Prjn.PrjnScale.Abs": "1.0", // was 0.8, but as of #680 1.0 better
@@ -253,6 +300,20 @@ var ParamSets = netparams.Sets{
 				"Prjn.SWts.Limit.Min": "0.1",  // .1-.7 def
 				"Prjn.SWts.Limit.Max": "0.7",  //
 			}},
+		{Sel: ".V3V2", Desc: "High16 pathway: same as V4V2",
+			Params: params.Params{
+				"Prjn.PrjnScale.Rel":  "0.05",
+				"Prjn.SWts.Init.Mean": "0.4",
+				"Prjn.SWts.Limit.Min": "0.1",
+				"Prjn.SWts.Limit.Max": "0.7",
+			}},
+		{Sel: ".V4V3", Desc: "High16 pathway: same as V4V2",
+			Params: params.Params{
+				"Prjn.PrjnScale.Rel":  "0.05",
+				"Prjn.SWts.Init.Mean": "0.4",
+				"Prjn.SWts.Limit.Min": "0.1",
+				"Prjn.SWts.Limit.Max": "0.7",
+			}},
 		// {Sel: ".TEOV2", Desc: "weaker -- not used",
 		// 	Params: params.Params{
 		// 		"Prjn.PrjnScale.Rel": "0.05", // .05 > .02 > .1
@@ -309,6 +370,13 @@ var ParamSets = netparams.Sets{
 				"Layer.Inhib.ActAvg.Nominal": "0.1", // 0.1 seems good
 			}},
 	},
+	"EmbedOutPats": {
+		{Sel: "#Output", Desc: "distributed graded output for text embedding targets -- see Config.Env.OutEmbed",
+			Params: params.Params{
+				"Layer.Inhib.Layer.Gi":       "0.9",
+				"Layer.Inhib.ActAvg.Nominal": "0.2",
+			}},
+	},
 	"LocalOutPats": {
 		{Sel: "#Output", Desc: "high inhib for one-hot output",
 			Params: params.Params{
@@ -328,4 +396,79 @@ var ParamSets = netparams.Sets{
 				"Layer.Inhib.ActAvg.AdaptGi": "true", // true = definitely worse
 			}},
 	},
+	"InhibFFFB": {
+		{Sel: "Layer", Desc: "fast-only FFFB: no slow SS component of FS-FFFB",
+			Params: params.Params{
+				"Layer.Inhib.Layer.SS": "0",
+				"Layer.Inhib.Pool.SS":  "0",
+			}},
+	},
+	"InhibLateral": {
+		{Sel: ".LatInhib", Desc: "lateral inhibition within layer",
+			Params: params.Params{
+				"Prjn.PrjnScale.Abs": "0.3",
+			}},
+		{Sel: ".V2", Desc: "weaker pooled inhib, replaced by lateral",
+			Params: params.Params{
+				"Layer.Inhib.Pool.Gi": "0.9", // 1.05 base
+			}},
+		{Sel: ".V4", Desc: "weaker pooled inhib, replaced by lateral",
+			Params: params.Params{
+				"Layer.Inhib.Pool.Gi": "0.9", // 1.05 base
+			}},
+		{Sel: ".TEO", Desc: "weaker pooled inhib, replaced by lateral",
+			Params: params.Params{
+				"Layer.Inhib.Pool.Gi": "0.85", // 1.0 base
+			}},
+		{Sel: "#TE", Desc: "weaker pooled inhib, replaced by lateral",
+			Params: params.Params{
+				"Layer.Inhib.Pool.Gi": "0.85", // 1.0 base
+			}},
+	},
+	"Dale": {
+		{Sel: ".DaleInh", Desc: "inhibitory interneurons for Config.Params.Dale -- denser activity than the excitatory layers",
+			Params: params.Params{
+				"Layer.Inhib.ActAvg.Nominal": "0.2",
+				"Layer.Inhib.Pool.Gi":        "0.8",
+				"Layer.Inhib.Layer.Gi":       "0.8",
+			}},
+		{Sel: ".ToDaleInh", Desc: "excitatory drive of interneurons from their layer",
+			Params: params.Params{
+				"Prjn.PrjnScale.Abs": "1",
+			}},
+	},
+	"InhibMixed": {
+		{Sel: ".LatInhib", Desc: "lateral inhibition within layer, on top of standard pooled inhib",
+			Params: params.Params{
+				"Prjn.PrjnScale.Abs": "0.1",
+			}},
+	},
+}
+
+// Inhibition configurations for Config.Params.Inhib
+const (
+	// InhibFFFB is fast-only pooled FFFB inhibition, without the slow SS component
+	InhibFFFB = "FFFB"
+
+	// InhibFSFFFB is the standard pooled fast and slow FS-FFFB inhibition
+	InhibFSFFFB = "FSFFFB"
+
+	// InhibLateral adds learned lateral inhibitory projections within
+	// V2, V4, TEO, TE, with weaker pooled inhibition -- these learn with
+	// the standard error-driven rule, not the Hebbian rule of the
+	// original HebbPrjn versions
+	InhibLateral = "Lateral"
+
+	// InhibMixed adds the lateral inhibitory projections on top of
+	// the standard pooled inhibition
+	InhibMixed = "Mixed"
+)
+
+// InhibSheets are the params sheets applied after the Base and extra sheets
+// for each Config.Params.Inhib configuration -- FSFFFB is the Base
+var InhibSheets = map[string]string{
+	InhibFFFB:    "InhibFFFB",
+	InhibFSFFFB:  "",
+	InhibLateral: "InhibLateral",
+	InhibMixed:   "InhibMixed",
 }
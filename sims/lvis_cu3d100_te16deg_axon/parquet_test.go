@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// thriftReader decodes Thrift compact protocol structs into maps from
+// field id to value, for checking the Parquet file metadata
+type thriftReader struct {
+	buf []byte
+	pos int
+	err bool
+}
+
+func (tr *thriftReader) byte() byte {
+	if tr.pos >= len(tr.buf) {
+		tr.err = true
+		return 0
+	}
+	b := tr.buf[tr.pos]
+	tr.pos++
+	return b
+}
+
+func (tr *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(tr.buf[tr.pos:])
+	if n <= 0 {
+		tr.err = true
+		return 0
+	}
+	tr.pos += n
+	return v
+}
+
+func (tr *thriftReader) zigzag() int64 {
+	v := tr.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (tr *thriftReader) value(typ byte) any {
+	switch typ {
+	case thriftI32, thriftI64:
+		return tr.zigzag()
+	case thriftBinary:
+		n := int(tr.varint())
+		if tr.pos+n > len(tr.buf) {
+			tr.err = true
+			return ""
+		}
+		s := string(tr.buf[tr.pos : tr.pos+n])
+		tr.pos += n
+		return s
+	case thriftList:
+		h := tr.byte()
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(tr.varint())
+		}
+		lst := make([]any, n)
+		for i := range lst {
+			lst[i] = tr.value(h & 0x0F)
+		}
+		return lst
+	case thriftStruct:
+		return tr.structure()
+	}
+	tr.err = true
+	return nil
+}
+
+func (tr *thriftReader) structure() map[int16]any {
+	st := make(map[int16]any)
+	id := int16(0)
+	for !tr.err {
+		h := tr.byte()
+		if h == 0 {
+			break
+		}
+		if delta := int16(h >> 4); delta > 0 {
+			id += delta
+		} else {
+			id = int16(tr.zigzag())
+		}
+		st[id] = tr.value(h & 0x0F)
+	}
+	return st
+}
+
+func TestSaveParquet(t *testing.T) {
+	cols := []*ParquetCol{
+		{Name: "Run", Type: ParquetInt32, Ints: []int32{0, 1, 2}},
+		{Name: "Value", Type: ParquetDouble, Floats: []float64{0.5, -1.25, math.Inf(1)}},
+		{Name: "Stat", Type: ParquetByteArray, Strings: []string{"PctErr", "", "UnitErr"}},
+	}
+	for i := 0; i < 20; i++ { // long list header
+		cols = append(cols, &ParquetCol{Name: "X", Type: ParquetInt32, Ints: []int32{int32(i), -1, 1 << 20}})
+	}
+	fnm := filepath.Join(t.TempDir(), "test.parquet")
+	if err := SaveParquet(fnm, cols); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(fnm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := len(b)
+	if n < 12 || string(b[:4]) != "PAR1" || string(b[n-4:]) != "PAR1" {
+		t.Fatalf("missing PAR1 magic")
+	}
+	flen := int(binary.LittleEndian.Uint32(b[n-8 : n-4]))
+	tr := &thriftReader{buf: b[n-8-flen : n-8]}
+	md := tr.structure()
+	if tr.err || tr.pos != flen {
+		t.Fatalf("footer: decode error at: %d of: %d", tr.pos, flen)
+	}
+	if md[3] != int64(3) {
+		t.Errorf("num_rows: %v, want: 3", md[3])
+	}
+	schema := md[2].([]any)
+	if len(schema) != len(cols)+1 {
+		t.Fatalf("schema elements: %d, want: %d", len(schema), len(cols)+1)
+	}
+	root := schema[0].(map[int16]any)
+	if root[5] != int64(len(cols)) {
+		t.Errorf("schema num_children: %v, want: %d", root[5], len(cols))
+	}
+	for ci, pc := range cols {
+		el := schema[ci+1].(map[int16]any)
+		if el[4] != pc.Name || el[1] != int64(pc.Type) || el[3] != int64(0) {
+			t.Errorf("schema %d: got: %v, want name: %s type: %d required", ci, el, pc.Name, pc.Type)
+		}
+		if _, utf8 := el[6]; utf8 != (pc.Type == ParquetByteArray) {
+			t.Errorf("schema %s: converted type: %v", pc.Name, el[6])
+		}
+	}
+	rgs := md[4].([]any)
+	if len(rgs) != 1 {
+		t.Fatalf("row groups: %d, want: 1", len(rgs))
+	}
+	rg := rgs[0].(map[int16]any)
+	if rg[3] != int64(3) {
+		t.Errorf("row group num_rows: %v, want: 3", rg[3])
+	}
+	chunks := rg[1].([]any)
+	if len(chunks) != len(cols) {
+		t.Fatalf("column chunks: %d, want: %d", len(chunks), len(cols))
+	}
+	total := int64(0)
+	for ci, pc := range cols {
+		cm := chunks[ci].(map[int16]any)[3].(map[int16]any)
+		if cm[1] != int64(pc.Type) || cm[5] != int64(3) || !reflect.DeepEqual(cm[3], []any{pc.Name}) {
+			t.Errorf("column %s: metadata: %v", pc.Name, cm)
+		}
+		off, size := int(cm[9].(int64)), int(cm[7].(int64))
+		total += int64(size)
+		pr := &thriftReader{buf: b[off : off+size]}
+		ph := pr.structure()
+		dh, _ := ph[5].(map[int16]any)
+		if pr.err || ph[1] != int64(0) || dh == nil || dh[1] != int64(3) {
+			t.Errorf("column %s: page header: %v", pc.Name, ph)
+			continue
+		}
+		vals := b[off+pr.pos : off+size]
+		if int64(len(vals)) != ph[3] {
+			t.Errorf("column %s: page size: %d, header: %v", pc.Name, len(vals), ph[3])
+		}
+		if !reflect.DeepEqual(vals, pc.PlainValues()) {
+			t.Errorf("column %s: values: %v, want: %v", pc.Name, vals, pc.PlainValues())
+		}
+	}
+	if rg[2] != total {
+		t.Errorf("row group total_byte_size: %v, want: %d", rg[2], total)
+	}
+}
+
+func TestParquetPlainValues(t *testing.T) {
+	pc := &ParquetCol{Type: ParquetByteArray, Strings: []string{"ab", ""}}
+	if want := []byte{2, 0, 0, 0, 'a', 'b', 0, 0, 0, 0}; !reflect.DeepEqual(pc.PlainValues(), want) {
+		t.Errorf("strings: %v, want: %v", pc.PlainValues(), want)
+	}
+	pc = &ParquetCol{Type: ParquetInt32, Ints: []int32{-2}}
+	if want := []byte{0xFE, 0xFF, 0xFF, 0xFF}; !reflect.DeepEqual(pc.PlainValues(), want) {
+		t.Errorf("ints: %v, want: %v", pc.PlainValues(), want)
+	}
+	pc = &ParquetCol{Type: ParquetDouble, Floats: []float64{1}}
+	if want := []byte{0, 0, 0, 0, 0, 0, 0xF0, 0x3F}; !reflect.DeepEqual(pc.PlainValues(), want) {
+		t.Errorf("floats: %v, want: %v", pc.PlainValues(), want)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/emer/emergent/egui"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/eplot"
+	"github.com/emer/etable/etable"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	_ "gonum.org/v1/plot/vg/vgimg" // registers the png format
+)
+
+// plotpng.go has the rendering of the log plots to PNG image files without
+// the GUI, so the standard figures are produced automatically on the
+// cluster: each of the Config.Log.Plots is given as Mode:Time, optionally
+// followed by :Col1,Col2,.. to select the columns, e.g., Train:Epoch or
+// Test:Epoch:PctErr,DecErr, and is rendered at the end of each run (in
+// nogui mode) with the same plot configuration as in the GUI (column
+// ranges, colors, X axis, legend), using the underlying gonum plot of the
+// eplot.Plot2D.  Without columns, those plotted by default in the GUI are
+// used.  Saved as <net>_<run>_plot_<mode>_<time>_<run#>.png files.
+
+// PlotSize is the size of the PNG plot images
+var PlotSize = [2]vg.Length{8 * vg.Inch, 5 * vg.Inch}
+
+// ParsePlotSpec parses given Mode:Time[:Col1,Col2,..] plot spec
+func ParsePlotSpec(spec string) (mode etime.Modes, tm etime.Times, cols []string, err error) {
+	fs := strings.Split(spec, ":")
+	if len(fs) < 2 || len(fs) > 3 {
+		err = fmt.Errorf("plot spec: %q is not Mode:Time[:Col1,Col2,..]", spec)
+		return
+	}
+	if err = mode.FromString(fs[0]); err != nil {
+		err = fmt.Errorf("plot spec: %q: %w", spec, err)
+		return
+	}
+	if err = tm.FromString(fs[1]); err != nil {
+		err = fmt.Errorf("plot spec: %q: %w", spec, err)
+		return
+	}
+	if len(fs) == 3 && fs[2] != "" {
+		cols = strings.Split(fs[2], ",")
+	}
+	return
+}
+
+// PlotColors sets the colors of given plot for an image file, as the GUI
+// colors of the eplot are not set without the GUI, and the background
+// is transparent
+func PlotColors(plt *plot.Plot) {
+	fg := color.Black
+	plt.BackgroundColor = color.White
+	plt.Title.TextStyle.Color = fg
+	plt.Legend.TextStyle.Color = fg
+	for _, ax := range []*plot.Axis{&plt.X, &plt.Y} {
+		ax.Color = fg
+		ax.Label.TextStyle.Color = fg
+		ax.Tick.Color = fg
+		ax.Tick.Label.Color = fg
+	}
+}
+
+// PlotToPNG renders the log plot for given mode and time to given PNG
+// file, with the given columns (in addition to the X axis), or the
+// columns plotted by default in the GUI if none
+func (ss *Sim) PlotToPNG(mode etime.Modes, tm etime.Times, cols []string, fnm string) error {
+	key := etime.Scope(mode, tm)
+	lt, ok := ss.Logs.Tables[key]
+	if !ok {
+		return fmt.Errorf("PlotToPNG: no %s log", key)
+	}
+	if lt.Table.Rows == 0 {
+		return fmt.Errorf("PlotToPNG: %s log is empty", key)
+	}
+	pl := &eplot.Plot2D{}
+	pl.InitName(pl, mode.String()+tm.String()+"Plot")
+	pl.Defaults()
+	pl.Table = etable.NewIdxView(lt.Table)
+	pl.Params.FmMeta(lt.Table)
+	pl.Params.FmMetaMap(lt.Meta)
+	pl.ColsListUpdate()
+	egui.ConfigPlotFromLog(ss.Net.Name(), pl, &ss.Logs, key)
+	if len(cols) > 0 {
+		for _, cp := range pl.Cols {
+			if cp.Col != pl.Params.XAxisCol {
+				cp.On = false
+			}
+		}
+		for _, cn := range cols {
+			cp, err := pl.ColParamsTry(cn)
+			if err != nil {
+				return fmt.Errorf("PlotToPNG: %s: %w", key, err)
+			}
+			cp.On = true
+		}
+	}
+	switch pl.Params.Type {
+	case eplot.XY:
+		pl.GenPlotXY()
+	case eplot.Bar:
+		pl.GenPlotBar()
+	}
+	if pl.GPlot == nil {
+		return fmt.Errorf("PlotToPNG: %s: no columns to plot", key)
+	}
+	PlotColors(pl.GPlot)
+	return pl.GPlot.Save(PlotSize[0], PlotSize[1], fnm)
+}
+
+// PlotFileName returns the PNG file name for the plot of given mode and time
+func (ss *Sim) PlotFileName(mode etime.Modes, tm etime.Times) string {
+	run := ss.Loops.GetLoop(etime.Train, etime.Run).Counter.Cur
+	lnm := fmt.Sprintf("plot_%s_%s_%03d", strings.ToLower(mode.String()), strings.ToLower(tm.String()), run)
+	return strings.TrimSuffix(elog.LogFileName(lnm, ss.Net.Name(), ss.Stats.String("RunName")), ".tsv") + ".png"
+}
+
+// SavePlots renders the Config.Log.Plots to PNG files -- called at the
+// end of each run, in nogui mode, on rank 0
+func (ss *Sim) SavePlots() {
+	if len(ss.Config.Log.Plots) == 0 || ss.Config.GUI || ss.MPIRank() != 0 {
+		return
+	}
+	for _, spec := range ss.Config.Log.Plots {
+		mode, tm, cols, err := ParsePlotSpec(spec)
+		if err != nil {
+			mpi.Println(err)
+			continue
+		}
+		fnm := ss.PlotFileName(mode, tm)
+		if err := ss.PlotToPNG(mode, tm, cols, fnm); err != nil {
+			mpi.Println(err)
+			continue
+		}
+		mpi.Printf("Saved plot: %s to: %s\n", spec, fnm)
+	}
+}
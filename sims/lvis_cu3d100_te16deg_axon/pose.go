@@ -0,0 +1,400 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// pose.go has the object pose / viewpoint metadata, parsed from the image
+// file names, which is logged per trial, and the analysis of test error
+// vs. rotation from the canonical view, for the viewpoint invariance
+// profile of the model.  See PoseConfig for the file name parsing.
+
+// ImgPose is the pose of the object in one image, parsed from its file name.
+// Angles are NaN when not known.
+type ImgPose struct {
+
+	// render view index, -1 if not known
+	View int
+
+	// azimuth (left-right rotation) in degrees
+	Az float32
+
+	// elevation in degrees
+	El float32
+
+	// rotation from the canonical view, in degrees: distance in azimuth
+	// (wrapped to +/- 180) and elevation
+	Rot float32
+}
+
+// Poses parses the poses of the images from their file names, per PoseConfig
+type Poses struct {
+
+	// pose config
+	Cfg *PoseConfig
+
+	// compiled Cfg.Regexp
+	Re *regexp.Regexp
+
+	// azimuth, elevation for each view index, from Cfg.ViewFile
+	Views map[int][2]float32
+
+	// poses already parsed, by image file name
+	Cache map[string]ImgPose
+}
+
+// Config configures the parsing from given config, opening the ViewFile if set
+func (ps *Poses) Config(cfg *PoseConfig) error {
+	ps.Cfg = cfg
+	ps.Cache = make(map[string]ImgPose)
+	ps.Views = nil
+	re, err := regexp.Compile(cfg.Regexp)
+	if err != nil {
+		return fmt.Errorf("Pose.Regexp: %w", err)
+	}
+	ps.Re = re
+	if cfg.ViewFile == "" {
+		return nil
+	}
+	dt := &etable.Table{}
+	delim := etable.Tab
+	if filepath.Ext(cfg.ViewFile) == ".csv" {
+		delim = etable.Comma
+	}
+	if err := dt.OpenCSV(gi.FileName(cfg.ViewFile), delim); err != nil {
+		return err
+	}
+	if dt.ColIdx("View") < 0 {
+		return fmt.Errorf("Pose.ViewFile: %s: no View column", cfg.ViewFile)
+	}
+	hasAz := dt.ColIdx("Az") >= 0
+	hasEl := dt.ColIdx("El") >= 0
+	nan := float32(math.NaN())
+	ps.Views = make(map[int][2]float32, dt.Rows)
+	for ri := 0; ri < dt.Rows; ri++ {
+		ae := [2]float32{nan, nan}
+		if hasAz {
+			ae[0] = float32(dt.CellFloat("Az", ri))
+		}
+		if hasEl {
+			ae[1] = float32(dt.CellFloat("El", ri))
+		}
+		ps.Views[int(dt.CellFloat("View", ri))] = ae
+	}
+	return nil
+}
+
+// Pose returns the pose for given image file name
+func (ps *Poses) Pose(img string) ImgPose {
+	if p, ok := ps.Cache[img]; ok {
+		return p
+	}
+	nan := float32(math.NaN())
+	p := ImgPose{View: -1, Az: nan, El: nan, Rot: nan}
+	if m := ps.Re.FindStringSubmatch(filepath.Base(img)); m != nil {
+		for i, nm := range ps.Re.SubexpNames() {
+			switch nm {
+			case "view":
+				if v, err := strconv.Atoi(m[i]); err == nil {
+					p.View = v
+				}
+			case "az":
+				if v, err := strconv.ParseFloat(m[i], 32); err == nil {
+					p.Az = float32(v)
+				}
+			case "el":
+				if v, err := strconv.ParseFloat(m[i], 32); err == nil {
+					p.El = float32(v)
+				}
+			}
+		}
+	}
+	if ae, ok := ps.Views[p.View]; ok {
+		if math.IsNaN(float64(p.Az)) {
+			p.Az = ae[0]
+		}
+		if math.IsNaN(float64(p.El)) {
+			p.El = ae[1]
+		}
+	}
+	p.Rot = ps.Rot(p.Az, p.El)
+	ps.Cache[img] = p
+	return p
+}
+
+// Rot returns the rotation from the canonical view for given azimuth and
+// elevation: if only one is known, it is the distance along that one,
+// and NaN if neither is known.
+func (ps *Poses) Rot(az, el float32) float32 {
+	d2 := 0.0
+	known := false
+	if !math.IsNaN(float64(az)) {
+		da := math.Mod(float64(az-ps.Cfg.CanonAz), 360)
+		if da > 180 {
+			da -= 360
+		} else if da < -180 {
+			da += 360
+		}
+		d2 += da * da
+		known = true
+	}
+	if !math.IsNaN(float64(el)) {
+		de := float64(el - ps.Cfg.CanonEl)
+		d2 += de * de
+		known = true
+	}
+	if !known {
+		return float32(math.NaN())
+	}
+	return float32(math.Sqrt(d2))
+}
+
+// MaxView returns the max view index over given images, -1 if none
+func (ps *Poses) MaxView(imgs []string) int {
+	mx := -1
+	for _, img := range imgs {
+		if v := ps.Pose(img).View; v > mx {
+			mx = v
+		}
+	}
+	return mx
+}
+
+// PoseStats accumulates test error as a function of rotation from the
+// canonical view, in bins, and as a function of render view index
+type PoseStats struct {
+
+	// bin size in degrees
+	Bin float32
+
+	// number of trials per rotation bin
+	RotN []float64
+
+	// sum of errors per rotation bin
+	RotErr []float64
+
+	// number of trials per view index
+	ViewN []float64
+
+	// sum of errors per view index
+	ViewErr []float64
+
+	// sums for the regression of error on rotation: N, X, Y, XY, XX
+	Reg []float64
+}
+
+// Init initializes for given bin size and number of view indexes
+func (pst *PoseStats) Init(bin float32, nviews int) {
+	pst.Bin = bin
+	nbins := int(math.Ceil(255/float64(bin))) + 1 // sqrt(2) * 180 max, larger in last bin
+	pst.RotN = make([]float64, nbins)
+	pst.RotErr = make([]float64, nbins)
+	pst.ViewN = make([]float64, nviews)
+	pst.ViewErr = make([]float64, nviews)
+	pst.Reg = make([]float64, 5)
+}
+
+// Add adds one trial with given pose and error
+func (pst *PoseStats) Add(p ImgPose, err float64) {
+	if !math.IsNaN(float64(p.Rot)) {
+		bi := int(p.Rot / pst.Bin)
+		if bi >= len(pst.RotN) {
+			bi = len(pst.RotN) - 1
+		}
+		pst.RotN[bi]++
+		pst.RotErr[bi] += err
+		x := float64(p.Rot)
+		pst.Reg[0]++
+		pst.Reg[1] += x
+		pst.Reg[2] += err
+		pst.Reg[3] += x * err
+		pst.Reg[4] += x * x
+	}
+	if p.View >= 0 && p.View < len(pst.ViewN) {
+		pst.ViewN[p.View]++
+		pst.ViewErr[p.View] += err
+	}
+}
+
+// MPISum sums the stats across MPI procs in given comm
+func (pst *PoseStats) MPISum(comm *mpi.Comm) {
+	for _, vals := range [][]float64{pst.RotN, pst.RotErr, pst.ViewN, pst.ViewErr, pst.Reg} {
+		orig := make([]float64, len(vals))
+		copy(orig, vals)
+		comm.AllReduceF64(mpi.OpSum, vals, orig)
+	}
+}
+
+// Slope returns the least-squares slope of error vs. rotation,
+// as the change in error rate per 90 degrees of rotation --
+// 0 = fully viewpoint invariant, NaN if rotation is not known
+func (pst *PoseStats) Slope() float64 {
+	n, sx, sy, sxy, sxx := pst.Reg[0], pst.Reg[1], pst.Reg[2], pst.Reg[3], pst.Reg[4]
+	den := n*sxx - sx*sx
+	if n < 2 || den == 0 {
+		return math.NaN()
+	}
+	return 90 * (n*sxy - sx*sy) / den
+}
+
+// RotTable returns the table of error rate per rotation bin, with Rot
+// as the start of the bin, for the bins with any trials
+func (pst *PoseStats) RotTable() *etable.Table {
+	dt := &etable.Table{}
+	dt.SetFromSchema(etable.Schema{
+		{"Rot", etensor.FLOAT64, nil, nil},
+		{"N", etensor.FLOAT64, nil, nil},
+		{"PctErr", etensor.FLOAT64, nil, nil},
+	}, 0)
+	for bi, n := range pst.RotN {
+		if n == 0 {
+			continue
+		}
+		row := dt.Rows
+		dt.SetNumRows(row + 1)
+		dt.SetCellFloat("Rot", row, float64(bi)*float64(pst.Bin))
+		dt.SetCellFloat("N", row, n)
+		dt.SetCellFloat("PctErr", row, pst.RotErr[bi]/n)
+	}
+	return dt
+}
+
+// ViewTable returns the table of error rate per view index,
+// for the views with any trials
+func (pst *PoseStats) ViewTable() *etable.Table {
+	dt := &etable.Table{}
+	dt.SetFromSchema(etable.Schema{
+		{"View", etensor.INT64, nil, nil},
+		{"N", etensor.FLOAT64, nil, nil},
+		{"PctErr", etensor.FLOAT64, nil, nil},
+	}, 0)
+	for vi, n := range pst.ViewN {
+		if n == 0 {
+			continue
+		}
+		row := dt.Rows
+		dt.SetNumRows(row + 1)
+		dt.SetCellFloat("View", row, float64(vi))
+		dt.SetCellFloat("N", row, n)
+		dt.SetCellFloat("PctErr", row, pst.ViewErr[vi]/n)
+	}
+	return dt
+}
+
+// ConfigPoses configures the pose parsing from Config.Env.Pose --
+// called at the end of ConfigEnv
+func (ss *Sim) ConfigPoses() {
+	if !ss.Config.Env.Pose.On {
+		return
+	}
+	if err := ss.Poses.Config(&ss.Config.Env.Pose); err != nil {
+		mpi.Println(err)
+		ss.Config.Env.Pose.On = false
+	}
+}
+
+// InitPoseStats initializes the PoseStats at the start of the test epoch
+func (ss *Sim) InitPoseStats() {
+	if !ss.Config.Env.Pose.On {
+		return
+	}
+	tst := ss.Envs.ByMode(etime.Test).(*ImagesEnv)
+	ss.PoseStats.Init(ss.Config.Env.Pose.Bin, ss.Poses.MaxView(tst.ImageList())+1)
+}
+
+// PoseRecord records the pose of the current image in the env for given
+// data index, in the TrlView, TrlAz, TrlEl, TrlRot stats.
+// Called in ApplyInputs.
+func (ss *Sim) PoseRecord(di int, ev *ImagesEnv) {
+	if !ss.Config.Env.Pose.On {
+		return
+	}
+	p := ss.Poses.Pose(ev.CurImg)
+	ss.Stats.SetIntDi("TrlView", di, p.View)
+	ss.Stats.SetFloatDi("TrlAz", di, float64(p.Az))
+	ss.Stats.SetFloatDi("TrlEl", di, float64(p.El))
+	ss.Stats.SetFloatDi("TrlRot", di, float64(p.Rot))
+}
+
+// PoseTrialStats adds the current test trial error for given data
+// index to the PoseStats.  Called at the end of TrialStats.
+func (ss *Sim) PoseTrialStats(di int) {
+	if !ss.Config.Env.Pose.On || ss.Context.Mode != etime.Test {
+		return
+	}
+	p := ImgPose{View: ss.Stats.IntDi("TrlView", di), Rot: float32(ss.Stats.FloatDi("TrlRot", di))}
+	ss.PoseStats.Add(p, ss.Stats.FloatDi("TrlErr", di))
+}
+
+// PoseEpochStats computes the PoseSlope stat and the Pose and PoseView
+// tables of test error vs. rotation and view at the end of the test
+// epoch, saving the tables in nogui mode on rank 0.
+func (ss *Sim) PoseEpochStats() {
+	if !ss.Config.Env.Pose.On || len(ss.PoseStats.Reg) == 0 {
+		return
+	}
+	if ss.Config.Run.MPI {
+		ss.PoseStats.MPISum(ss.Comm)
+	}
+	ss.Stats.SetFloat("PoseSlope", ss.PoseStats.Slope())
+	rdt := ss.PoseStats.RotTable()
+	vdt := ss.PoseStats.ViewTable()
+	ss.Logs.MiscTables["Pose"] = rdt
+	ss.Logs.MiscTables["PoseView"] = vdt
+	if ss.Config.GUI || ss.MPIRank() != 0 {
+		return
+	}
+	for nm, dt := range map[string]*etable.Table{"pose": rdt, "pose_view": vdt} {
+		if dt.Rows == 0 {
+			continue
+		}
+		fnm := elog.LogFileName(nm, ss.Net.Name(), ss.Stats.String("RunName"))
+		if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+			mpi.Println(err)
+		}
+	}
+}
+
+// ConfigPoseLogs adds the per-trial pose log items, and the PoseSlope
+// test epoch item, copied to the train epoch and run logs as TstPoseSlope
+func (ss *Sim) ConfigPoseLogs() {
+	if !ss.Config.Env.Pose.On {
+		return
+	}
+	for _, st := range []string{"View", "Az", "El", "Rot"} {
+		stnm := "Trl" + st
+		isInt := st == "View"
+		typ := etensor.FLOAT64
+		if isInt {
+			typ = etensor.INT64
+		}
+		ss.Logs.AddItem(&elog.Item{
+			Name: st,
+			Type: typ,
+			Write: elog.WriteMap{
+				etime.Scope(etime.AllModes, etime.Trial): func(ctx *elog.Context) {
+					if isInt {
+						ctx.SetInt(ss.Stats.IntDi(stnm, ctx.Di))
+					} else {
+						ctx.SetFloat64(ss.Stats.FloatDi(stnm, ctx.Di))
+					}
+				}}})
+	}
+	ss.Logs.AddStatFloatNoAggItem(etime.Test, etime.Epoch, "PoseSlope")
+	ss.Logs.AddCopyFromFloatItems(etime.Train, []etime.Times{etime.Epoch, etime.Run}, etime.Test, etime.Epoch, "Tst", "PoseSlope")
+}
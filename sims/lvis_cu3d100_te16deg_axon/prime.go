@@ -0,0 +1,265 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/emergent/looper"
+	"github.com/emer/empi/empi"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/agg"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/split"
+	"github.com/goki/gi/gi"
+)
+
+// prime.go has the priming / adaptation test paradigm: on each trial,
+// a prime image is presented for Config.Run.PrimeCycles, followed
+// by the target image (a test item) for a standard 200 cycle trial,
+// without resetting activity in between.  The prime is related
+// (same category) or unrelated to the target, and the target
+// response time and error are recorded as a function of relatedness.
+// This runs in the Validate mode stack.
+
+// PrimeCycles returns the number of cycles to present the prime,
+// Config.Run.PrimeCycles rounded up to a multiple of 10, as the GPU
+// only updates inputs at this interval.
+func (ss *Sim) PrimeCycles() int {
+	pc := ss.Config.Run.PrimeCycles
+	return ((pc + 9) / 10) * 10
+}
+
+// ConfigPrimeLoops adds the Validate mode stack for the priming
+// paradigm, with the prime presented at the start of the trial
+// and the target at PrimeCycles, followed by standard minus and plus
+// phases.  This must be called after the standard loop functions
+// have been added to all the other stacks.
+func (ss *Sim) ConfigPrimeLoops(man *looper.Manager, trls int) {
+	ctx := &ss.Context
+	net := ss.Net
+	pc := ss.PrimeCycles()
+
+	man.AddStack(etime.Validate).
+		AddTime(etime.Epoch, 1).
+		AddTimeIncr(etime.Trial, trls, ss.Config.Run.NData).
+		AddTime(etime.Cycle, pc+200)
+
+	trial := man.GetLoop(etime.Validate, etime.Trial)
+	cycle := man.GetLoop(etime.Validate, etime.Cycle)
+
+	trial.OnStart.Add("NewState", func() {
+		net.NewState(ctx)
+		ctx.NewState(etime.Validate)
+		ctx.NewPhase(false)
+	})
+	trial.OnStart.Add("ApplyPrimes", ss.ApplyPrimes)
+	cycle.Main.Add("Cycle", func() {
+		net.GPU.CycleByCycle = false
+		net.Cycle(ctx)
+		ctx.CycleInc()
+	})
+	cycle.OnEnd.Add("PrimeRTStats", ss.PrimeRTStats)
+	cycle.AddNewEvent("Target", pc, func() {
+		ctx.NewPhase(false)
+		ss.ApplyTargets()
+	})
+	cycle.AddNewEvent("Beta1", pc+50, func() { net.SpkSt1(ctx) })
+	cycle.AddNewEvent("Beta2", pc+100, func() { net.SpkSt2(ctx) })
+	cycle.AddNewEvent("PlusPhase", pc+150, func() {
+		net.MinusPhase(ctx)
+		ctx.PlusPhase.SetBool(true)
+		ctx.NewPhase(true)
+		net.PlusPhaseStart(ctx)
+	})
+	trial.OnEnd.Add("PlusPhase:End", func() { net.PlusPhase(ctx) })
+	trial.OnEnd.Add("PrimeRecord", ss.PrimeRecord)
+}
+
+// ApplyPrimeInputs applies the current test env input state to given data index
+func (ss *Sim) ApplyPrimeInputs(ev *ImagesEnv, di uint32) {
+	for _, lnm := range ss.Net.LayersByType(axon.InputLayer, axon.TargetLayer) {
+		ly := ss.Net.AxonLayerByName(lnm)
+		pats := ev.State(ly.Nm)
+		if pats != nil {
+			ly.ApplyExt(&ss.Context, di, pats)
+		}
+	}
+}
+
+// ApplyPrimes steps the test env to the next target for each data index,
+// chooses the prime for it, and applies the prime as input
+func (ss *Sim) ApplyPrimes() {
+	ctx := &ss.Context
+	ev := ss.Envs.ByMode(etime.Test).(*ImagesEnv)
+	ss.Net.InitExt(ctx)
+	nd := int(ctx.NetIdxs.NData)
+	if len(ss.PrimeTargs) != nd {
+		ss.PrimeTargs = make([]string, nd)
+	}
+	for di := 0; di < nd; di++ {
+		ev.Step()
+		ss.PrimeTargs[di] = ev.CurImg
+		ss.Stats.SetStringDi("TrialName", di, ev.String())
+		ss.Stats.SetIntDi("TrlCatIdx", di, ev.CurCatIdx)
+		ss.Stats.SetStringDi("TrlCat", di, ev.CurCat)
+		ss.Stats.SetIntDi("PrimeRT", di, -1)
+		ev.ChoosePrime()
+		ev.ShowImage(ev.PrimeImg)
+		ss.Stats.SetStringDi("PrimeImg", di, ev.PrimeImg)
+		ss.Stats.SetIntDi("PrimeCatIdx", di, ev.PrimeCatIdx)
+		rel := 0
+		if ev.PrimeRel {
+			rel = 1
+		}
+		ss.Stats.SetIntDi("PrimeRel", di, rel)
+		ss.ApplyPrimeInputs(ev, uint32(di))
+	}
+	ss.Net.ApplyExts(ctx)
+}
+
+// ApplyTargets applies the target images as input, replacing the primes,
+// without resetting activity
+func (ss *Sim) ApplyTargets() {
+	ctx := &ss.Context
+	ev := ss.Envs.ByMode(etime.Test).(*ImagesEnv)
+	ss.Net.InitExt(ctx)
+	for di := 0; di < int(ctx.NetIdxs.NData); di++ {
+		ev.ShowImage(ss.PrimeTargs[di])
+		ss.ApplyPrimeInputs(ev, uint32(di))
+	}
+	ss.Net.ApplyExts(ctx)
+}
+
+// PrimeRTStats records the target response time: the first cycle after
+// target onset at which the Output CaSpkP pattern is closest to the
+// target category, checked every 10 cycles within the minus phase.
+func (ss *Sim) PrimeRTStats() {
+	ctx := &ss.Context
+	pc := ss.PrimeCycles()
+	cyc := int(ctx.Cycle) - pc
+	if cyc < 0 || cyc%10 != 0 || ctx.PlusPhase.IsTrue() {
+		return
+	}
+	if ss.Config.Run.GPU {
+		ss.Net.GPU.SyncNeuronsFmGPU()
+	}
+	ev := ss.Envs.ByMode(etime.Test).(*ImagesEnv)
+	out := ss.Net.AxonLayerByName("Output")
+	for di := 0; di < int(ctx.NetIdxs.NData); di++ {
+		if ss.Stats.IntDi("PrimeRT", di) >= 0 {
+			continue
+		}
+		tsr := ss.Stats.F32TensorDi("OutputCyc", di)
+		out.UnitValsTensor(tsr, "CaSpkP", di)
+		_, err, _ := ev.OutErr(tsr, ss.Stats.IntDi("TrlCatIdx", di))
+		if err == 0 {
+			ss.Stats.SetIntDi("PrimeRT", di, cyc)
+		}
+	}
+}
+
+// ConfigPrimeTable configures the table of priming trial results
+func (ss *Sim) ConfigPrimeTable(dt *etable.Table) {
+	dt.SetMetaData("name", "PrimeTrials")
+	dt.SetMetaData("desc", "priming paradigm results per trial")
+	dt.SetFromSchema(etable.Schema{
+		{"Target", etensor.STRING, nil, nil},
+		{"TargCat", etensor.STRING, nil, nil},
+		{"Prime", etensor.STRING, nil, nil},
+		{"PrimeCat", etensor.STRING, nil, nil},
+		{"Related", etensor.FLOAT64, nil, nil},
+		{"RT", etensor.FLOAT64, nil, nil},
+		{"Err", etensor.FLOAT64, nil, nil},
+	}, 0)
+}
+
+// PrimeRecord records the results of the current priming trial
+// for each data index: target error based on the minus phase Output
+// and the response time (150 = not correct within the minus phase).
+func (ss *Sim) PrimeRecord() {
+	ctx := &ss.Context
+	ev := ss.Envs.ByMode(etime.Test).(*ImagesEnv)
+	out := ss.Net.AxonLayerByName("Output")
+	dt := ss.Logs.MiscTables["PrimeTrials"]
+	for di := 0; di < int(ctx.NetIdxs.NData); di++ {
+		tsr := ss.Stats.F32TensorDi("OutputCyc", di)
+		out.UnitValsTensor(tsr, "ActM", di)
+		ci := ss.Stats.IntDi("TrlCatIdx", di)
+		_, err, _ := ev.OutErr(tsr, ci)
+		rt := ss.Stats.IntDi("PrimeRT", di)
+		if rt < 0 {
+			rt = 150
+		}
+		row := dt.Rows
+		dt.SetNumRows(row + 1)
+		dt.SetCellString("Target", row, ss.PrimeTargs[di])
+		dt.SetCellString("TargCat", row, ev.Images.Cats[ci])
+		dt.SetCellString("Prime", row, ss.Stats.StringDi("PrimeImg", di))
+		dt.SetCellString("PrimeCat", row, ev.Images.Cats[ss.Stats.IntDi("PrimeCatIdx", di)])
+		dt.SetCellFloat("Related", row, float64(ss.Stats.IntDi("PrimeRel", di)))
+		dt.SetCellFloat("RT", row, float64(rt))
+		dt.SetCellFloat("Err", row, err)
+	}
+}
+
+// RunPrime runs the priming paradigm over the test items, gathering
+// the results across MPI procs, and computes the summary of mean
+// response time and error for related vs. unrelated primes,
+// which is printed and saved (in nogui mode).
+func (ss *Sim) RunPrime() {
+	if ss.ImagesEnv(etime.Test) == nil {
+		mpi.Println("RunPrime: priming requires the Images env")
+		return
+	}
+	dt := &etable.Table{}
+	ss.ConfigPrimeTable(dt)
+	ss.Logs.MiscTables["PrimeTrials"] = dt
+	ss.Envs.ByMode(etime.Test).Init(0)
+	ss.Loops.ResetAndRun(etime.Validate)
+	ss.Loops.Mode = etime.Train
+	if ss.Config.Run.MPI {
+		all := &etable.Table{}
+		empi.GatherTableRows(all, dt, ss.Comm)
+		dt = all
+		ss.Logs.MiscTables["PrimeTrials"] = dt
+	}
+	ix := etable.NewIdxView(dt)
+	spl := split.GroupBy(ix, []string{"Related"})
+	split.Agg(spl, "RT", agg.AggMean)
+	split.Agg(spl, "Err", agg.AggMean)
+	sdt := spl.AggsToTable(etable.AddAggName)
+	if sdt == nil {
+		return
+	}
+	ss.Logs.MiscTables["PrimeSum"] = sdt
+	for ri := 0; ri < sdt.Rows; ri++ {
+		mpi.Printf("Prime Related: %s\tRT: %g\tErr: %g\n", sdt.CellString("Related", ri), sdt.CellFloat("RT:Mean", ri), sdt.CellFloat("Err:Mean", ri))
+	}
+	if ss.Config.GUI || ss.MPIRank() != 0 {
+		return
+	}
+	netName := ss.Net.Name()
+	runName := ss.Stats.String("RunName")
+	for nm, tb := range map[string]*etable.Table{"prime_trl": dt, "prime_sum": sdt} {
+		fnm := elog.LogFileName(nm, netName, runName)
+		if err := tb.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+			mpi.Println(err)
+		} else {
+			fmt.Printf("Saved priming results to: %s\n", fnm)
+		}
+	}
+}
+
+// RunPrimeGUI runs the priming paradigm, has stop running = false at end -- for gui
+func (ss *Sim) RunPrimeGUI() {
+	ss.GUI.StopNow = false
+	ss.RunPrime()
+	ss.GUI.Stopped()
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	_ "net/http/pprof" // registers the /debug/pprof endpoints
+	"os"
+	"runtime/pprof"
+	"sort"
+
+	"github.com/emer/emergent/etime"
+	"github.com/emer/emergent/timer"
+	"github.com/emer/empi/mpi"
+)
+
+// profile.go has the integrated profiling hooks, for performance triage
+// without ad-hoc instrumentation, per Config.Log.Profile: the standard
+// pprof HTTP endpoints (/debug/pprof) can be served on MPI rank 0 for the
+// whole run, and over the training epochs from Start up to End, a CPU
+// profile, a heap profile at the end, and the time spent in each network
+// function, including each GPU kernel, can be captured on each MPI proc.
+// The GPU kernel timing uses separate shader pipeline runs with a wait
+// after each, which is slower, so it is only on during the profiled epochs.
+// The files are named by the network and run, with a prof_<rank> suffix:
+// .cpu.pprof, .heap.pprof, and _times.tsv, for go tool pprof.
+
+// Profile has the state of the profiling hooks
+type Profile struct {
+
+	// CPU profile file, while profiling
+	CPUFile *os.File
+
+	// true while profiling the epochs
+	On bool
+}
+
+// ProfileServe starts serving the pprof HTTP endpoints at
+// Config.Log.Profile.Addr, if set, on MPI rank 0
+func (ss *Sim) ProfileServe() {
+	addr := ss.Config.Log.Profile.Addr
+	if addr == "" || ss.MPIRank() != 0 {
+		return
+	}
+	go func() {
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			mpi.Println("Profile:", err)
+		}
+	}()
+	mpi.Printf("Serving pprof endpoints at: http://%s/debug/pprof\n", addr)
+}
+
+// ProfileFileName returns the file name for the given profile suffix,
+// named by the network and run, and MPI rank
+func (ss *Sim) ProfileFileName(sfx string) string {
+	return fmt.Sprintf("%s_%s_prof_%d%s", ss.Net.Name(), ss.Stats.String("RunName"), ss.MPIRank(), sfx)
+}
+
+// ProfileStart starts profiling, if the current training epoch is
+// Config.Log.Profile.Start -- called at the start of each training epoch
+func (ss *Sim) ProfileStart() {
+	pc := &ss.Config.Log.Profile
+	pf := &ss.Profile
+	if pf.On || !(pc.CPU || pc.Heap || pc.Times) {
+		return
+	}
+	if ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur != pc.Start {
+		return
+	}
+	pf.On = true
+	if pc.CPU {
+		fnm := ss.ProfileFileName(".cpu.pprof")
+		f, err := os.Create(fnm)
+		if err != nil {
+			mpi.Println("Profile:", err)
+		} else if err := pprof.StartCPUProfile(f); err != nil {
+			mpi.Println("Profile:", err)
+			f.Close()
+		} else {
+			pf.CPUFile = f
+		}
+	}
+	if pc.Times {
+		ss.Net.FunTimes = make(map[string]*timer.Time)
+		ss.Net.RecFunTimes = true
+		ss.Net.GPU.RecFunTimes = true
+	}
+	mpi.Printf("Profile: started at epoch %d\n", pc.Start)
+}
+
+// ProfileEpoch stops profiling, if the current training epoch is the
+// last one before Config.Log.Profile.End -- called at the end of each
+// training epoch
+func (ss *Sim) ProfileEpoch() {
+	if !ss.Profile.On {
+		return
+	}
+	if ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur+1 >= ss.Config.Log.Profile.End {
+		ss.ProfileStop()
+	}
+}
+
+// ProfileStop stops profiling and saves the profiles, if on -- also
+// called at the end of the job
+func (ss *Sim) ProfileStop() {
+	pc := &ss.Config.Log.Profile
+	pf := &ss.Profile
+	if !pf.On {
+		return
+	}
+	pf.On = false
+	if pf.CPUFile != nil {
+		pprof.StopCPUProfile()
+		pf.CPUFile.Close()
+		pf.CPUFile = nil
+	}
+	if pc.Heap {
+		fnm := ss.ProfileFileName(".heap.pprof")
+		if f, err := os.Create(fnm); err != nil {
+			mpi.Println("Profile:", err)
+		} else {
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				mpi.Println("Profile:", err)
+			}
+			f.Close()
+		}
+	}
+	if pc.Times {
+		ss.Net.RecFunTimes = false
+		ss.Net.GPU.RecFunTimes = false
+		if err := ss.SaveFunTimes(ss.ProfileFileName("_times.tsv")); err != nil {
+			mpi.Println("Profile:", err)
+		}
+	}
+	mpi.Printf("Profile: stopped at epoch %d\n", ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur)
+}
+
+// SaveFunTimes saves the time spent in each network function, including
+// the GPU kernels, as recorded in the FunTimes, to given file, sorted by
+// descending time: Function, Secs, Pct, N
+func (ss *Sim) SaveFunTimes(fnm string) error {
+	f, err := os.Create(fnm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fts := ss.Net.FunTimes
+	fnms := make([]string, 0, len(fts))
+	tot := 0.0
+	for fn, ft := range fts {
+		fnms = append(fnms, fn)
+		tot += ft.TotalSecs()
+	}
+	sort.Slice(fnms, func(i, j int) bool {
+		return fts[fnms[i]].TotalSecs() > fts[fnms[j]].TotalSecs()
+	})
+	fmt.Fprintf(f, "Function\tSecs\tPct\tN\n")
+	for _, fn := range fnms {
+		ft := fts[fn]
+		pct := 0.0
+		if tot > 0 {
+			pct = 100 * ft.TotalSecs() / tot
+		}
+		fmt.Fprintf(f, "%s\t%g\t%g\t%d\n", fn, ft.TotalSecs(), pct, ft.N)
+	}
+	return nil
+}
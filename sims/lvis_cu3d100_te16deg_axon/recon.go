@@ -0,0 +1,132 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/decoder"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etensor"
+)
+
+// recon.go has the reconstruction decoder, a linear decoder trained
+// alongside the network (by the delta rule, on training trials) to map
+// the activity of hidden layers (e.g., V2 or V4) back to the V1 input
+// layer, to visualize what information each layer preserves.  The
+// reconstruction error is logged, and the reconstruction of the current
+// trial is shown in the Recon tab in the GUI.  See Config.Run.Recon.
+
+// Recon has the state of the reconstruction decoder
+type Recon struct {
+
+	// the linear decoder
+	Decoder decoder.Linear
+
+	// target layer being reconstructed
+	Target *axon.Layer
+
+	// target values for the current trial
+	Targ etensor.Float32
+
+	// reconstruction for the current trial, in the shape of the Target layer
+	Out etensor.Float32
+}
+
+// ConfigRecon configures the reconstruction decoder per Config.Run.Recon
+func (ss *Sim) ConfigRecon() {
+	rc := &ss.Config.Run.Recon
+	rn := &ss.Recon
+	rn.Target = nil
+	if !rc.On {
+		return
+	}
+	if _, err := ss.Net.LayByNameTry(rc.Target); err != nil {
+		mpi.Println("Recon:", err)
+		return
+	}
+	lnms := rc.Layers
+	if len(lnms) == 0 {
+		lnms = []string{"V4f16"}
+	}
+	var lays []decoder.Layer
+	for _, lnm := range lnms {
+		ly, err := ss.Net.LayByNameTry(lnm)
+		if err != nil {
+			mpi.Println("Recon:", err)
+			continue
+		}
+		lays = append(lays, ly)
+	}
+	if len(lays) == 0 {
+		return
+	}
+	rn.Target = ss.Net.AxonLayerByName(rc.Target)
+	rn.Decoder.InitLayer(rn.Target.Shape().Len(), lays, decoder.IdentityFunc)
+	rn.Decoder.LRate = rc.LRate
+	for i := range rn.Decoder.Weights.Values {
+		rn.Decoder.Weights.Values[i] = 0
+	}
+	if ss.Config.Run.MPI {
+		rn.Decoder.Comm = ss.Comm
+	}
+	rn.Out.SetShape(rn.Target.Shape().Shp, nil, nil)
+	mpi.Printf("Recon: %s from %d inputs\n", rc.Target, rn.Decoder.NInputs)
+}
+
+// InitRecon resets the reconstruction decoder weights, for a new run
+func (ss *Sim) InitRecon() {
+	rn := &ss.Recon
+	if rn.Target == nil {
+		return
+	}
+	for i := range rn.Decoder.Weights.Values {
+		rn.Decoder.Weights.Values[i] = 0
+	}
+}
+
+// ReconTrial computes the reconstruction of the target layer for given
+// data index, and trains the decoder on training trials, setting the
+// ReconErr stat to the mean squared error.  Called when logging the
+// trial, after TrialStats, so it is only trained once per trial.
+func (ss *Sim) ReconTrial(di int) {
+	rn := &ss.Recon
+	if rn.Target == nil {
+		return
+	}
+	dec := &rn.Decoder
+	dec.Decode(ss.Config.Run.Recon.Var, di)
+	rn.Target.UnitValsTensor(&rn.Targ, "Ext", di)
+	var sse float32
+	var err error
+	if ss.Context.Mode == etime.Train {
+		if ss.Config.Run.MPI {
+			sse, err = dec.TrainMPI(rn.Targ.Values)
+		} else {
+			sse, err = dec.Train(rn.Targ.Values)
+		}
+	} else {
+		for ui := range dec.Units {
+			d := rn.Targ.Values[ui] - dec.Units[ui].Act
+			sse += d * d
+		}
+	}
+	if err != nil {
+		return
+	}
+	for ui := range dec.Units {
+		rn.Out.Values[ui] = dec.Units[ui].Act
+	}
+	ss.Stats.SetFloat("ReconErr", float64(sse)/float64(len(dec.Units)))
+}
+
+// ConfigReconLogs adds the log items for the ReconErr stat
+func (ss *Sim) ConfigReconLogs() {
+	if ss.Recon.Target == nil {
+		return
+	}
+	ss.Stats.SetFloat("ReconErr", 0)
+	ss.Logs.AddStatAggItem("ReconErr", etime.Run, etime.Epoch, etime.Trial)
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/etime"
+)
+
+// replay.go has the optional "sleep" replay phase for exploring
+// consolidation and replay hypotheses: the plus phase (ActP) activity of
+// the higher layers (TEO, TE by default) is stored for the first
+// Config.Run.Replay.N training items of each epoch on each MPI proc, which
+// are a random sample given the shuffled order, and at the end of the
+// epoch these patterns are replayed, clamped onto the layers as external
+// inputs (see SetLayerExt), with the rest of the network free to settle
+// over a standard trial of 200 cycles, and learning enabled only in the
+// Replay.Learn projections.  The input layers receive no inputs.  With
+// Replay.Target, the stored Output patterns are presented as targets in
+// the plus phase, so the default Replay.Learn .ToOut projections learn to
+// reproduce the original responses from the replayed activity: without
+// targets, the minus and plus phases are nearly the same, and the
+// error-driven learning is close to zero.  The clamps and learning flags
+// are removed after the replay.
+
+// Replay has the stored activity patterns for the replay phase
+type Replay struct {
+
+	// stored patterns per layer, in the order recorded
+	Pats map[string][][]float32
+
+	// stored Output patterns, for Replay.Target
+	Targs [][]float32
+
+	// original Learn flags of all projections, while replaying
+	Learn map[*axon.Prjn]bool
+}
+
+// ReplayLays returns the layers whose activity is replayed: the
+// Config.Run.Replay.Layers, or TEOf16, TEOf8, TE if empty
+func (ss *Sim) ReplayLays() []string {
+	if len(ss.Config.Run.Replay.Layers) > 0 {
+		return ss.Config.Run.Replay.Layers
+	}
+	return ss.ProtoLays()
+}
+
+// InitReplay resets the stored patterns, at the start of each training epoch
+func (ss *Sim) InitReplay() {
+	if ss.Config.Run.Replay.N <= 0 {
+		return
+	}
+	rp := &ss.Replay
+	rp.Pats = make(map[string][][]float32)
+	rp.Targs = nil
+	for _, lnm := range ss.ReplayLays() {
+		rp.Pats[lnm] = nil
+	}
+}
+
+// ReplayRecord stores the current ActP activity of the ReplayLays layers,
+// and the Output for Replay.Target, for all data indexes, up to
+// Config.Run.Replay.N items per epoch.
+// Called at the end of each training trial.
+func (ss *Sim) ReplayRecord() {
+	rc := &ss.Config.Run.Replay
+	rp := &ss.Replay
+	if rc.N <= 0 || rp.Pats == nil || len(rp.Pats[ss.ReplayLays()[0]]) >= rc.N {
+		return
+	}
+	ss.Net.GPU.SyncNeuronsFmGPU()
+	for di := 0; di < int(ss.Context.NetIdxs.NData); di++ {
+		for lnm, pats := range rp.Pats {
+			if len(pats) >= rc.N {
+				continue
+			}
+			var vals []float32
+			ss.Net.AxonLayerByName(lnm).UnitVals(&vals, "ActP", di)
+			rp.Pats[lnm] = append(pats, vals)
+		}
+		if rc.Target && len(rp.Targs) < rc.N {
+			var vals []float32
+			ss.Net.AxonLayerByName("Output").UnitVals(&vals, "ActP", di)
+			rp.Targs = append(rp.Targs, vals)
+		}
+	}
+}
+
+// SetLayerExt clamps the given values onto the neurons of given layer,
+// for given data index, as external inputs that drive the neurons in place
+// of their synaptic inputs, via the Clamp.Ge of the layer -- this works for
+// any layer, including hidden layers, which have no Exts for ApplyExt.
+// The neuron state must be synced to the GPU after this.
+func SetLayerExt(ctx *axon.Context, ly *axon.Layer, di uint32, vals []float32) {
+	for lni := uint32(0); lni < ly.NNeurons && int(lni) < len(vals); lni++ {
+		ni := ly.NeurStIdx + lni
+		if axon.NrnIsOff(ctx, ni) {
+			continue
+		}
+		axon.SetNrnV(ctx, ni, di, axon.Ext, vals[lni])
+		axon.NrnSetFlag(ctx, ni, di, axon.NeuronHasExt)
+	}
+}
+
+// SetLayerTarget sets the given values as the targets of given Target
+// layer, for given data index, as in ApplyExt, for the plus phase.
+// The neuron state must be synced to the GPU after this.
+func SetLayerTarget(ctx *axon.Context, ly *axon.Layer, di uint32, vals []float32) {
+	clearMask, setMask, toTarg := ly.ApplyExtFlags()
+	for lni := uint32(0); lni < ly.NNeurons && int(lni) < len(vals); lni++ {
+		ly.ApplyExtVal(ctx, lni, di, vals[lni], clearMask, setMask, toTarg)
+	}
+}
+
+// ClearLayerExt removes the external inputs set by SetLayerExt from the
+// neurons of given layer, for all data indexes.  The neuron state must be
+// synced to the GPU after this.
+func ClearLayerExt(ctx *axon.Context, ly *axon.Layer) {
+	for lni := uint32(0); lni < ly.NNeurons; lni++ {
+		ni := ly.NeurStIdx + lni
+		for di := uint32(0); di < ly.MaxData; di++ {
+			axon.SetNrnV(ctx, ni, di, axon.Ext, 0)
+			axon.NrnClearFlag(ctx, ni, di, axon.NeuronHasExt)
+		}
+	}
+}
+
+// ReplayLearnOn turns off learning in all projections except the
+// Replay.Learn ones, saving the original settings for ReplayLearnOff
+func (ss *Sim) ReplayLearnOn() {
+	rp := &ss.Replay
+	lrn := map[*axon.Prjn]bool{}
+	if sel := ss.Config.Run.Replay.Learn; sel != "" {
+		for _, pj := range ss.PrjnsBySel(sel) {
+			lrn[pj] = true
+		}
+	}
+	rp.Learn = make(map[*axon.Prjn]bool)
+	for _, ly := range ss.Net.Layers {
+		for _, pj := range ly.RcvPrjns {
+			on := pj.Params.Learn.Learn.IsTrue()
+			rp.Learn[pj] = on
+			pj.Params.Learn.Learn.SetBool(on && lrn[pj])
+		}
+	}
+	ss.Net.GPU.SyncParamsToGPU()
+}
+
+// ReplayLearnOff restores the learning flags changed by ReplayLearnOn
+func (ss *Sim) ReplayLearnOff() {
+	rp := &ss.Replay
+	for pj, on := range rp.Learn {
+		pj.Params.Learn.Learn.SetBool(on)
+	}
+	rp.Learn = nil
+	ss.Net.GPU.SyncParamsToGPU()
+}
+
+// ReplayTrial runs one replay trial on the current inputs, with the
+// standard minus and plus phase timing, followed by learning
+func (ss *Sim) ReplayTrial() {
+	net := ss.Net
+	ctx := &ss.Context
+	net.NewState(ctx)
+	ctx.NewState(etime.Train)
+	ctx.PlusPhase.SetBool(false)
+	ctx.NewPhase(false)
+	for cyc := 0; cyc < 200; cyc++ {
+		switch cyc {
+		case 50:
+			net.SpkSt1(ctx)
+		case 100:
+			net.SpkSt2(ctx)
+		case 150:
+			net.MinusPhase(ctx)
+			ctx.PlusPhase.SetBool(true)
+			ctx.NewPhase(true)
+			net.PlusPhaseStart(ctx)
+		}
+		net.Cycle(ctx)
+		ctx.CycleInc()
+	}
+	net.PlusPhase(ctx)
+	net.DWt(ctx)
+	ss.MPIWtFmDWt()
+}
+
+// ReplayEpoch replays the patterns stored over the training epoch,
+// NData at a time, setting the ReplayN stat to the number replayed.
+// All MPI procs store the same number of patterns, as they run the
+// same number of trials, so they run the same number of replay trials.
+// Called at the end of each training epoch.
+func (ss *Sim) ReplayEpoch() {
+	rp := &ss.Replay
+	if ss.Config.Run.Replay.N <= 0 || rp.Pats == nil {
+		return
+	}
+	lays := ss.ReplayLays()
+	n := len(rp.Pats[lays[0]])
+	ss.Stats.SetInt("ReplayN", n)
+	if n == 0 {
+		return
+	}
+	net := ss.Net
+	ctx := &ss.Context
+	nd := int(ctx.NetIdxs.NData)
+	ss.ReplayLearnOn()
+	for st := 0; st < n; st += nd {
+		net.InitExt(ctx)
+		net.ApplyExts(ctx)
+		net.GPU.SyncNeuronsFmGPU()
+		for di := 0; di < nd; di++ {
+			pi := (st + di) % n
+			for _, lnm := range lays {
+				SetLayerExt(ctx, net.AxonLayerByName(lnm), uint32(di), rp.Pats[lnm][pi])
+			}
+			if pi < len(rp.Targs) {
+				SetLayerTarget(ctx, net.AxonLayerByName("Output"), uint32(di), rp.Targs[pi])
+			}
+		}
+		net.GPU.SyncNeuronsToGPU()
+		ss.ReplayTrial()
+	}
+	net.GPU.SyncNeuronsFmGPU()
+	for _, lnm := range lays {
+		ClearLayerExt(ctx, net.AxonLayerByName(lnm))
+	}
+	if len(rp.Targs) > 0 {
+		net.AxonLayerByName("Output").InitExt(ctx)
+	}
+	net.GPU.SyncNeuronsToGPU()
+	ss.ReplayLearnOff()
+	rp.Pats = nil
+	rp.Targs = nil
+}
+
+// ConfigReplayLogs adds the ReplayN stat to the training epoch log,
+// if the replay phase is on
+func (ss *Sim) ConfigReplayLogs() {
+	if ss.Config.Run.Replay.N <= 0 {
+		return
+	}
+	ss.Stats.SetInt("ReplayN", 0)
+	ss.Logs.AddStatIntNoAggItem(etime.Train, etime.Epoch, "ReplayN")
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+)
+
+// replica.go has the detection of divergence between the MPI replicas of
+// the network, which should have identical weights as they share all the
+// weight changes: every Config.Run.ReplicaCheck training epochs (or every
+// epoch in Debug mode), a hash of the weights is compared across procs,
+// which only communicates a few numbers.  On a mismatch, the incident is
+// logged, and if Config.Run.ReplicaRecover is set, the rank 0 weights are
+// broadcast to all procs and training continues, otherwise the job is
+// aborted.  The ReplicaDivs stat in the training epoch log has the number
+// of procs that diverged from rank 0.
+
+// ReplicaCheckOn returns true if the MPI replica check
+// is done at the start of the current training epoch
+func (ss *Sim) ReplicaCheckOn() bool {
+	if !ss.Config.Run.MPI || ss.Comm.Size() == 1 {
+		return false
+	}
+	if ss.Config.Debug {
+		return true
+	}
+	iv := ss.Config.Run.ReplicaCheck
+	if iv <= 0 {
+		return false
+	}
+	return ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur%iv == 0
+}
+
+// ReplicaHash returns a hash of the network weights, as a number
+func (ss *Sim) ReplicaHash() uint64 {
+	ss.Net.GPU.SyncSynapsesFmGPU()
+	h := fnv.New64a()
+	h.Write([]byte(ss.Net.WtsHash()))
+	return h.Sum64()
+}
+
+// ReplicaCheck checks that the weights of all MPI procs are the same as
+// those of rank 0, setting the ReplicaDivs stat to the number that are not,
+// and recovering or aborting per Config.Run.ReplicaRecover.
+// Called at the start of each training epoch.
+func (ss *Sim) ReplicaCheck() {
+	ss.Stats.SetInt("ReplicaDivs", 0)
+	if !ss.ReplicaCheckOn() {
+		return
+	}
+	hash := []uint64{ss.ReplicaHash()}
+	rank0 := []uint64{hash[0]}
+	ss.Comm.BcastU64(0, rank0)
+	div := []int{0}
+	if hash[0] != rank0[0] {
+		div[0] = 1
+		mpi.AllPrintf("ReplicaCheck: rank %d weights differ from rank 0\n", ss.Comm.Rank())
+	}
+	ss.Comm.AllReduceInt(mpi.OpSum, div, nil)
+	if div[0] == 0 {
+		return
+	}
+	ss.Stats.SetInt("ReplicaDivs", div[0])
+	if !ss.Config.Run.ReplicaRecover {
+		panic("Hashes do not match! The models on different nodes have diverged.")
+	}
+	if err := ss.ReplicaRecover(); err != nil {
+		mpi.Println("ReplicaCheck:", err)
+		ss.Comm.Abort()
+		return
+	}
+	ss.AddEvent(fmt.Sprintf("Replica divergence: %d procs, restored from rank 0", div[0]))
+}
+
+// ReplicaRecover broadcasts the rank 0 weights to all procs, in the
+// JSON weights format, which all procs then load, including rank 0,
+// so they are identical even if the values are rounded in the JSON.
+// A write error on rank 0 is broadcast as a negative size, so all procs
+// return an error instead of waiting on the weights.
+func (ss *Sim) ReplicaRecover() error {
+	var b bytes.Buffer
+	var err error
+	if ss.Comm.Rank() == 0 {
+		err = ss.Net.WriteWtsJSON(&b)
+	}
+	n := []int{b.Len()}
+	if err != nil {
+		n[0] = -1
+	}
+	ss.Comm.BcastInt(0, n)
+	if n[0] < 0 {
+		if err == nil {
+			err = fmt.Errorf("ReplicaRecover: rank 0 failed to write weights")
+		}
+		return err
+	}
+	wts := make([]uint8, n[0])
+	copy(wts, b.Bytes())
+	ss.Comm.BcastU8(0, wts)
+	return ss.Net.ReadWtsJSON(bytes.NewReader(wts))
+}
+
+// ConfigReplicaLogs adds the ReplicaDivs stat to the training epoch log,
+// if the MPI replica check is on
+func (ss *Sim) ConfigReplicaLogs() {
+	if !ss.Config.Run.MPI || (ss.Config.Run.ReplicaCheck <= 0 && !ss.Config.Debug) {
+		return
+	}
+	ss.Stats.SetInt("ReplicaDivs", 0)
+	ss.Logs.AddStatIntNoAggItem(etime.Train, etime.Epoch, "ReplicaDivs")
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/emergent/etime"
+	"github.com/emer/etable/etensor"
+)
+
+// resp.go has the output response criterion, to model guesses vs.
+// confident responses: the category closest to the Output activity is
+// only taken as the response if the peak Output activity is at least
+// Config.Run.RespThr -- otherwise the trial is scored as no response
+// ("none"), which counts as an error in PctErr, but is logged separately
+// as NoResp, with the errors of actual responses as RespErr (so PctErr =
+// NoResp + RespErr).  Correct responses are further classified by the
+// output margin (see OutErrMargin) as Confident, if it is at least
+// Config.Run.RespConfMargin, or Guess otherwise, and the mean Margin is
+// logged.  All are proportions of trials, logged at the trial, epoch and
+// run levels.
+
+// RespStats are the response criterion stats
+var RespStats = []string{"NoResp", "RespErr", "Confident", "Guess", "Margin"}
+
+// RespTrialStats applies the response criterion to the given Output
+// activity and closest category response, with the given error and output
+// margin, returning the response, -1 if none, and setting the RespStats.
+func (ss *Sim) RespTrialStats(ovt *etensor.Float32, rsp int, err, margin float64) int {
+	omax := float32(0)
+	for _, v := range ovt.Values {
+		if v > omax {
+			omax = v
+		}
+	}
+	noResp, respErr, conf, guess := 0.0, 0.0, 0.0, 0.0
+	switch {
+	case omax < ss.Config.Run.RespThr:
+		rsp = -1
+		noResp = 1
+	case err > 0:
+		respErr = 1
+	case margin >= float64(ss.Config.Run.RespConfMargin):
+		conf = 1
+	default:
+		guess = 1
+	}
+	ss.Stats.SetFloat("NoResp", noResp)
+	ss.Stats.SetFloat("RespErr", respErr)
+	ss.Stats.SetFloat("Confident", conf)
+	ss.Stats.SetFloat("Guess", guess)
+	ss.Stats.SetFloat("Margin", margin)
+	return rsp
+}
+
+// ConfigRespLogs adds the log items for the RespStats, with the test epoch
+// values copied to the train epoch and run logs with a Tst prefix
+func (ss *Sim) ConfigRespLogs() {
+	for _, st := range RespStats {
+		ss.Stats.SetFloat(st, 0)
+		ss.Logs.AddStatAggItem(st, etime.Run, etime.Epoch, etime.Trial)
+	}
+	ss.Logs.AddCopyFromFloatItems(etime.Train, []etime.Times{etime.Epoch, etime.Run}, etime.Test, etime.Epoch, "Tst", RespStats...)
+}
@@ -0,0 +1,337 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+	"github.com/goki/ki/ints"
+	"github.com/goki/mat32"
+)
+
+// rfsize.go has the receptive field size analysis, for comparison to the
+// RF sizes measured in macaque V4 and TEO: test images are presented as
+// probes, shrunk to each of Config.Run.RFSize.Scales, at each position of
+// an NPos x NPos grid, and the mean response of each unit at each position
+// is recorded (over the images).  The RF of each unit, for each probe size,
+// is estimated from the response above its minimum across positions, as
+// the response-weighted centroid of the probe positions, and the extent,
+// the response-weighted standard deviation of the positions around the
+// centroid (the square root of the mean of the X and Y variances), in units
+// of the image half-width.  The per-unit RFs, and the distribution of the
+// extents in each layer, are saved.  The probes run through the Test stack,
+// split across MPI procs.  See Config.Run.RFSize.
+
+// RFSizeThr is the threshold on the range of the mean response of a unit
+// across positions, below which the unit is not responsive to the probes
+const RFSizeThr = 0.01
+
+// RFSize has the response accumulators for the RF size analysis
+type RFSize struct {
+
+	// probe scales
+	Scales []float32
+
+	// probe positions along each axis of the grid
+	Pos []float32
+
+	// sum of the responses of each unit, per layer: [scale][pos][unit]
+	Sum map[string][]float64
+
+	// number of presentations: [scale][pos]
+	N []float64
+}
+
+// RFSizeLays returns the layers for the RF size analysis
+func (ss *Sim) RFSizeLays() []string {
+	if lays := ss.Config.Run.RFSize.Layers; len(lays) > 0 {
+		return lays
+	}
+	return []string{"V4f16", "V4f8", "TEOf16", "TEOf8"}
+}
+
+// InitRFSize initializes the RF size accumulators per Config.Run.RFSize
+func (ss *Sim) InitRFSize() error {
+	rc := &ss.Config.Run.RFSize
+	rs := &ss.RFSize
+	rs.Scales = rc.Scales
+	if len(rs.Scales) == 0 {
+		rs.Scales = []float32{0.25, 0.5}
+	}
+	np := rc.NPos
+	if np < 2 {
+		np = 2
+	}
+	rs.Pos = make([]float32, np)
+	for i := range rs.Pos {
+		rs.Pos[i] = -rc.Range + 2*rc.Range*float32(i)/float32(np-1)
+	}
+	npos := np * np
+	rs.N = make([]float64, len(rs.Scales)*npos)
+	rs.Sum = make(map[string][]float64)
+	for _, lnm := range ss.RFSizeLays() {
+		ly, err := ss.Net.LayByNameTry(lnm)
+		if err != nil {
+			return err
+		}
+		rs.Sum[lnm] = make([]float64, len(rs.N)*ly.Shape().Len())
+	}
+	return nil
+}
+
+// RFSizeProbes returns the probes for this MPI proc: each of NImgs test
+// images, from different categories, at each scale and position, and the
+// number of probes per proc.  The last proc can have fewer probes, but all
+// procs must run the same number of Test trials: those beyond the probes
+// are not recorded.
+func (ss *Sim) RFSizeProbes(ev *ImagesEnv) ([]ImgProbe, int) {
+	rs := &ss.RFSize
+	il := ev.ImageList()
+	imgs := ev.SubsetNPerCat(1, ev.RndSeed)
+	if n := ss.Config.Run.RFSize.NImgs; n > 0 && n < len(imgs) {
+		sel := make([]int, n)
+		for i := range sel {
+			sel[i] = imgs[(i*len(imgs))/n]
+		}
+		imgs = sel
+	}
+	np := len(rs.Pos)
+	var all []ImgProbe
+	for _, ii := range imgs {
+		for si, sc := range rs.Scales {
+			for pi := 0; pi < np*np; pi++ {
+				tr := mat32.Vec2{X: rs.Pos[pi%np], Y: rs.Pos[pi/np]}
+				all = append(all, ImgProbe{Img: il[ii], Trans: tr, Scale: sc, PosIdx: pi, ScaleIdx: si})
+			}
+		}
+	}
+	pp := (len(all) + ss.MPISize() - 1) / ss.MPISize()
+	st := ints.MinInt(pp*ss.MPIRank(), len(all))
+	ed := ints.MinInt(st+pp, len(all))
+	return all[st:ed], pp
+}
+
+// RFSizeRecord adds the current ActM activity of the RFSize layers to the
+// accumulators for the current probe of each data index, if the test env
+// is showing probes.  Called at the end of each test trial.
+func (ss *Sim) RFSizeRecord() {
+	ev := ss.ImagesEnv(etime.Test)
+	if ev == nil || len(ev.Probes) == 0 || ss.RFSize.Sum == nil {
+		return
+	}
+	rs := &ss.RFSize
+	npos := len(rs.Pos) * len(rs.Pos)
+	trl := ss.Loops.GetLoop(etime.Test, etime.Trial).Counter.Cur
+	var vals []float32
+	for di := 0; di < int(ss.Context.NetIdxs.NData); di++ {
+		pi := trl + di
+		if pi >= len(ev.Probes) {
+			continue
+		}
+		p := &ev.Probes[pi]
+		ci := p.ScaleIdx*npos + p.PosIdx
+		rs.N[ci]++
+		for lnm, sum := range rs.Sum {
+			ly := ss.Net.AxonLayerByName(lnm)
+			ly.UnitVals(&vals, "ActM", di)
+			nu := len(vals)
+			for ui, v := range vals {
+				sum[ci*nu+ui] += float64(v)
+			}
+		}
+	}
+}
+
+// UnitRF returns the RF centroid and extent of given unit, with given
+// number of units in the layer, for given scale index, from the layer
+// accumulator, and the range of the mean response across positions.
+// The centroid and extent are NaN if the unit is not responsive.
+func (rs *RFSize) UnitRF(sum []float64, nu, si, ui int) (cx, cy, ext, rng float64) {
+	np := len(rs.Pos)
+	npos := np * np
+	mus := make([]float64, npos)
+	mn, mx := math.Inf(1), math.Inf(-1)
+	for pi := range mus {
+		ci := si*npos + pi
+		if rs.N[ci] > 0 {
+			mus[pi] = sum[ci*nu+ui] / rs.N[ci]
+		}
+		mn = math.Min(mn, mus[pi])
+		mx = math.Max(mx, mus[pi])
+	}
+	rng = mx - mn
+	if rng < RFSizeThr {
+		return math.NaN(), math.NaN(), math.NaN(), rng
+	}
+	ws, sx, sy, sxx, syy := 0.0, 0.0, 0.0, 0.0, 0.0
+	for pi, mu := range mus {
+		w := mu - mn
+		x, y := float64(rs.Pos[pi%np]), float64(rs.Pos[pi/np])
+		ws += w
+		sx += w * x
+		sy += w * y
+		sxx += w * x * x
+		syy += w * y * y
+	}
+	cx, cy = sx/ws, sy/ws
+	vx := math.Max(sxx/ws-cx*cx, 0)
+	vy := math.Max(syy/ws-cy*cy, 0)
+	ext = math.Sqrt(0.5 * (vx + vy))
+	return
+}
+
+// RFSizeTables returns the table of RFs per unit, and the summary of the
+// distribution of the RF extents per layer and scale: number of responsive
+// units, mean, standard deviation, and quartiles of the extent, and the
+// mean eccentricity (distance from the center) of the centroids.
+func (ss *Sim) RFSizeTables() (udt, sdt *etable.Table) {
+	rs := &ss.RFSize
+	udt = &etable.Table{}
+	udt.SetMetaData("name", "RFSizeUnits")
+	udt.SetMetaData("desc", "receptive field centroid and extent per unit")
+	udt.SetFromSchema(etable.Schema{
+		{"Layer", etensor.STRING, nil, nil},
+		{"Unit", etensor.INT64, nil, nil},
+		{"Scale", etensor.FLOAT64, nil, nil},
+		{"CX", etensor.FLOAT64, nil, nil},
+		{"CY", etensor.FLOAT64, nil, nil},
+		{"Extent", etensor.FLOAT64, nil, nil},
+		{"Range", etensor.FLOAT64, nil, nil},
+	}, 0)
+	sdt = &etable.Table{}
+	sdt.SetMetaData("name", "RFSizeLays")
+	sdt.SetMetaData("desc", "distribution of receptive field extents per layer")
+	sdt.SetFromSchema(etable.Schema{
+		{"Layer", etensor.STRING, nil, nil},
+		{"Scale", etensor.FLOAT64, nil, nil},
+		{"N", etensor.FLOAT64, nil, nil},
+		{"Mean", etensor.FLOAT64, nil, nil},
+		{"SD", etensor.FLOAT64, nil, nil},
+		{"Q25", etensor.FLOAT64, nil, nil},
+		{"Median", etensor.FLOAT64, nil, nil},
+		{"Q75", etensor.FLOAT64, nil, nil},
+		{"Ecc", etensor.FLOAT64, nil, nil},
+	}, 0)
+	for _, lnm := range ss.RFSizeLays() {
+		sum := rs.Sum[lnm]
+		nu := ss.Net.LayerByName(lnm).Shape().Len()
+		for si, sc := range rs.Scales {
+			var exts []float64
+			ecc := 0.0
+			for ui := 0; ui < nu; ui++ {
+				cx, cy, ext, rng := rs.UnitRF(sum, nu, si, ui)
+				row := udt.Rows
+				udt.SetNumRows(row + 1)
+				udt.SetCellString("Layer", row, lnm)
+				udt.SetCellFloat("Unit", row, float64(ui))
+				udt.SetCellFloat("Scale", row, float64(sc))
+				udt.SetCellFloat("CX", row, cx)
+				udt.SetCellFloat("CY", row, cy)
+				udt.SetCellFloat("Extent", row, ext)
+				udt.SetCellFloat("Range", row, rng)
+				if !math.IsNaN(ext) {
+					exts = append(exts, ext)
+					ecc += math.Hypot(cx, cy)
+				}
+			}
+			row := sdt.Rows
+			sdt.SetNumRows(row + 1)
+			sdt.SetCellString("Layer", row, lnm)
+			sdt.SetCellFloat("Scale", row, float64(sc))
+			n := len(exts)
+			sdt.SetCellFloat("N", row, float64(n))
+			if n == 0 {
+				continue
+			}
+			sort.Float64s(exts)
+			mu, sd := meanSD(exts)
+			sdt.SetCellFloat("Mean", row, mu)
+			sdt.SetCellFloat("SD", row, sd)
+			sdt.SetCellFloat("Q25", row, exts[n/4])
+			sdt.SetCellFloat("Median", row, exts[n/2])
+			sdt.SetCellFloat("Q75", row, exts[(3*n)/4])
+			sdt.SetCellFloat("Ecc", row, ecc/float64(n))
+		}
+	}
+	return
+}
+
+// RunRFSize runs the RF size analysis, presenting the probes through the
+// Test stack, and gathering the responses across MPI procs.  The summary
+// is printed, and the tables are saved (in nogui mode).
+func (ss *Sim) RunRFSize() error {
+	ev := ss.ImagesEnv(etime.Test)
+	if ev == nil {
+		return fmt.Errorf("RFSize: the RF size analysis requires the Images env")
+	}
+	if err := ss.InitRFSize(); err != nil {
+		return err
+	}
+	rs := &ss.RFSize
+	ev.Init(0)
+	probes, pp := ss.RFSizeProbes(ev)
+	ev.SetProbes(probes)
+	nd := ss.Config.Run.NData
+	trl := ss.Loops.GetLoop(etime.Test, etime.Trial)
+	trl.Counter.Max = ((pp + nd - 1) / nd) * nd
+	mpi.Printf("RFSize: presenting %d probes per proc\n", len(probes))
+	ss.Loops.ResetAndRun(etime.Test)
+	ss.Loops.Mode = etime.Train // as in TestAll
+	trl.Counter.Max = ss.Trials.PerProc
+	ev.SetProbes(nil)
+	if ss.Config.Run.MPI {
+		for _, vals := range append([][]float64{rs.N}, ss.RFSizeSums()...) {
+			orig := make([]float64, len(vals))
+			copy(orig, vals)
+			ss.Comm.AllReduceF64(mpi.OpSum, vals, orig)
+		}
+	}
+	udt, sdt := ss.RFSizeTables()
+	ss.Logs.MiscTables["RFSizeUnits"] = udt
+	ss.Logs.MiscTables["RFSizeLays"] = sdt
+	for ri := 0; ri < sdt.Rows; ri++ {
+		mpi.Printf("RFSize: %s\tScale: %g\tN: %g\tExtent Mean: %.3g\tMedian: %.3g\tEcc: %.3g\n", sdt.CellString("Layer", ri), sdt.CellFloat("Scale", ri), sdt.CellFloat("N", ri), sdt.CellFloat("Mean", ri), sdt.CellFloat("Median", ri), sdt.CellFloat("Ecc", ri))
+	}
+	if ss.Config.GUI || ss.MPIRank() != 0 {
+		return nil
+	}
+	netName := ss.Net.Name()
+	runName := ss.Stats.String("RunName")
+	for nm, tb := range map[string]*etable.Table{"rfsize_units": udt, "rfsize_lays": sdt} {
+		fnm := elog.LogFileName(nm, netName, runName)
+		if err := tb.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+			mpi.Println(err)
+		} else {
+			fmt.Printf("Saved RF size results to: %s\n", fnm)
+		}
+	}
+	return nil
+}
+
+// RFSizeSums returns the layer accumulators, in the order of RFSizeLays
+func (ss *Sim) RFSizeSums() [][]float64 {
+	var sums [][]float64
+	for _, lnm := range ss.RFSizeLays() {
+		sums = append(sums, ss.RFSize.Sum[lnm])
+	}
+	return sums
+}
+
+// RunRFSizeGUI runs the RF size analysis, has stop running = false at end -- for gui
+func (ss *Sim) RunRFSizeGUI() {
+	ss.GUI.StopNow = false
+	if err := ss.RunRFSize(); err != nil {
+		mpi.Println(err)
+	}
+	ss.GUI.Stopped()
+}
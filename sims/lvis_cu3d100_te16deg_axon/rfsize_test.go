@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUnitRF(t *testing.T) {
+	rs := &RFSize{Scales: []float32{1, 0.5}, Pos: []float32{-0.5, 0, 0.5}}
+	npos, nu := 9, 2
+	rs.N = make([]float64, 2*npos)
+	for i := range rs.N {
+		rs.N[i] = 2
+	}
+	sum := make([]float64, 2*npos*nu)
+	set := func(si, px, py, ui int, mu float64) {
+		sum[((si*npos)+py*3+px)*nu+ui] = mu * 2
+	}
+	// scale 0, unit 0: responds at the two lower corners
+	set(0, 0, 0, 0, 1)
+	set(0, 2, 0, 0, 1)
+	// scale 1, unit 0: responds at the right middle only
+	set(1, 2, 1, 0, 0.8)
+	set(1, 2, 2, 0, 1)
+	rs.N[npos+8] = 0 // not presented: ignored
+	// unit 1: responds at the center at scale 0, and too weakly at scale 1
+	for pi := 0; pi < npos; pi++ {
+		set(0, pi%3, pi/3, 1, 0.3)
+		set(1, pi%3, pi/3, 1, 0.005*float64(pi%2))
+	}
+	set(0, 1, 1, 1, 1)
+	tests := []struct {
+		si, ui           int
+		cx, cy, ext, rng float64
+	}{
+		{0, 0, 0, -0.5, math.Sqrt(0.125), 1},
+		{1, 0, 0.5, 0, 0, 0.8},
+		{0, 1, 0, 0, 0, 0.7},
+		{1, 1, math.NaN(), math.NaN(), math.NaN(), 0.005},
+	}
+	near := func(a, b float64) bool {
+		if math.IsNaN(b) {
+			return math.IsNaN(a)
+		}
+		return math.Abs(a-b) < 1e-9
+	}
+	for _, tt := range tests {
+		cx, cy, ext, rng := rs.UnitRF(sum, nu, tt.si, tt.ui)
+		if !near(cx, tt.cx) || !near(cy, tt.cy) || !near(ext, tt.ext) || !near(rng, tt.rng) {
+			t.Errorf("UnitRF scale: %d unit: %d = %g, %g, %g, %g, want: %g, %g, %g, %g", tt.si, tt.ui, cx, cy, ext, rng, tt.cx, tt.cy, tt.ext, tt.rng)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/metric"
+	"github.com/goki/gi/gi"
+)
+
+// rndpats.go has the validation of the saved random output pattern files
+// (rndpats_*.tsv), which are otherwise loaded as-is if present: a file
+// that was edited, truncated, or saved with different settings can
+// silently give patterns that do not match the current OutSize, number of
+// categories (MaxOut), RndPctOn, or RndMinDiff.  The loaded patterns are
+// checked for the shape and number of rows, the number of active units in
+// each pattern, and the minimum difference between each pair of patterns
+// (computed as in patgen.PermutedBinaryMinDiff).  On a mismatch,
+// RndPatsCheck determines whether the patterns are regenerated (with a
+// warning, overwriting the file) or it is an error.
+
+// OpenRndPats opens the random output patterns from given file, and
+// checks that they match the current OutSize and MaxOut, with nOn active
+// units per pattern and at least minDiff differences between patterns
+func (ev *ImagesEnv) OpenRndPats(fnm string, nOn, minDiff int) error {
+	if err := ev.Pats.OpenCSV(gi.FileName(fnm), etable.Tab); err != nil {
+		return fmt.Errorf("rndpats: %s: %w", fnm, err)
+	}
+	if err := ev.CheckRndPats(nOn, minDiff); err != nil {
+		return fmt.Errorf("rndpats: %s: %w", fnm, err)
+	}
+	return nil
+}
+
+// CheckRndPats checks that the random output patterns in Pats match the
+// current OutSize and MaxOut, with nOn active units per pattern and at
+// least minDiff differences between patterns
+func (ev *ImagesEnv) CheckRndPats(nOn, minDiff int) error {
+	if ev.Pats.Rows != ev.MaxOut {
+		return fmt.Errorf("has %d patterns, expected %d", ev.Pats.Rows, ev.MaxOut)
+	}
+	cl, err := ev.Pats.ColByNameTry("Output")
+	if err != nil {
+		return err
+	}
+	out, ok := cl.(*etensor.Float32)
+	if !ok {
+		return fmt.Errorf("Output column is not float32")
+	}
+	if out.NumDims() != 3 || out.Dim(1) != ev.OutSize.Y || out.Dim(2) != ev.OutSize.X {
+		return fmt.Errorf("Output shape is %v, expected %d x %d", out.Shapes()[1:], ev.OutSize.Y, ev.OutSize.X)
+	}
+	rows, cells := out.RowCellSize()
+	for r := 0; r < rows; r++ {
+		n := 0
+		for _, v := range out.Values[r*cells : (r+1)*cells] {
+			switch v {
+			case 1:
+				n++
+			case 0:
+			default:
+				return fmt.Errorf("pattern %d has value %g, expected binary 0 / 1 values", r, v)
+			}
+		}
+		if n != nOn {
+			return fmt.Errorf("pattern %d has %d units on, expected %d", r, n, nOn)
+		}
+	}
+	for r1 := 0; r1 < rows; r1++ {
+		r1v := out.Values[r1*cells : (r1+1)*cells]
+		for r2 := r1 + 1; r2 < rows; r2++ {
+			r2v := out.Values[r2*cells : (r2+1)*cells]
+			df := int(math.Round(float64(.5 * metric.Hamming32(r1v, r2v))))
+			if df < minDiff {
+				return fmt.Errorf("patterns %d and %d differ by %d, expected at least %d", r1, r2, df, minDiff)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/etview"
+	"github.com/emer/etable/metric"
+	"github.com/emer/etable/simat"
+	"github.com/goki/gi/gi"
+)
+
+// rsa.go has the GUI "compare two layers" representational similarity
+// analysis (RSA) viewer, updated during Test stepping.
+
+// RSACatName is the special layer name for the category one-hot pattern
+const RSACatName = "Cat"
+
+// RSACompare compares the representations of two layers (or a layer
+// and the category one-hot pattern), over the most recent N test items:
+// the similarity matrix (correlation) across items is computed for each,
+// and the RSA score is the correlation between the two matrices.
+type RSACompare struct {
+
+	// first layer to compare
+	LayA string `desc:"first layer to compare"`
+
+	// second layer to compare, or Cat for the category one-hot pattern
+	LayB string `desc:"second layer to compare, or Cat for the category one-hot pattern"`
+
+	// [def: ActM] neuron variable to compare
+	Var string `def:"ActM" desc:"neuron variable to compare"`
+
+	// [def: 40] number of most recent test items to compare over
+	N int `def:"40" desc:"number of most recent test items to compare over"`
+
+	// correlation between the similarity matrices of the two layers, over the off-diagonal values
+	RSA float32 `inactive:"+" desc:"correlation between the similarity matrices of the two layers, over the off-diagonal values"`
+
+	// [view: no-inline] similarity matrix for LayA
+	SimA simat.SimMat `view:"no-inline" desc:"similarity matrix for LayA"`
+
+	// [view: no-inline] similarity matrix for LayB
+	SimB simat.SimMat `view:"no-inline" desc:"similarity matrix for LayB"`
+
+	// [view: -] recent patterns for LayA, N rows
+	PatsA etensor.Float32 `view:"-" desc:"recent patterns for LayA, N rows"`
+
+	// [view: -] recent patterns for LayB, N rows
+	PatsB etensor.Float32 `view:"-" desc:"recent patterns for LayB, N rows"`
+
+	// [view: -] category labels for recent items
+	Labels []string `view:"-" desc:"category labels for recent items"`
+
+	// [view: -] number of items recorded, up to N
+	NItems int `view:"-" desc:"number of items recorded, up to N"`
+
+	// [view: -] next row to record into
+	Idx int `view:"-" desc:"next row to record into"`
+
+	// [view: -] label displaying the RSA score
+	Label *gi.Label `view:"-" desc:"label displaying the RSA score"`
+
+	// [view: -] grids displaying SimA, SimB
+	Grids [2]*etview.SimMatGrid `view:"-" desc:"grids displaying SimA, SimB"`
+}
+
+func (rc *RSACompare) Defaults() {
+	rc.LayA = "V4f16"
+	rc.LayB = "TE"
+	rc.Var = "ActM"
+	rc.N = 40
+}
+
+// Reset resets the recorded items
+func (rc *RSACompare) Reset() {
+	rc.NItems = 0
+	rc.Idx = 0
+}
+
+// CheckShape ensures that the buffer of patterns has N rows of given
+// pattern size, resetting if not
+func (rc *RSACompare) CheckShape(pats *etensor.Float32, sz int) {
+	if pats.Len() == 0 || pats.Dim(0) != rc.N || pats.Dim(1) != sz {
+		pats.SetShape([]int{rc.N, sz}, nil, nil)
+		rc.Reset()
+	}
+}
+
+// SetPat records given pattern into the buffer of patterns at current Idx
+func (rc *RSACompare) SetPat(pats *etensor.Float32, vals []float32) {
+	copy(pats.SubSpace([]int{rc.Idx}).(*etensor.Float32).Values, vals)
+}
+
+// SimMat computes the similarity matrix over the recorded items in pats
+func (rc *RSACompare) SimMat(sm *simat.SimMat, pats *etensor.Float32) {
+	n := rc.NItems
+	sm.Init()
+	sm.Mat.SetShape([]int{n, n}, nil, nil)
+	sm.Rows = rc.Labels[:n]
+	sm.Cols = sm.Rows
+	for i := 0; i < n; i++ {
+		pi := pats.SubSpace([]int{i}).(*etensor.Float32).Values
+		for j := 0; j < n; j++ {
+			pj := pats.SubSpace([]int{j}).(*etensor.Float32).Values
+			sm.Mat.SetFloat([]int{i, j}, float64(metric.Correlation32(pi, pj)))
+		}
+	}
+}
+
+// Update computes the similarity matrices and RSA score, and updates the GUI
+func (rc *RSACompare) Update() {
+	if rc.NItems < 2 {
+		return
+	}
+	rc.SimMat(&rc.SimA, &rc.PatsA)
+	rc.SimMat(&rc.SimB, &rc.PatsB)
+	n := rc.NItems
+	var va, vb []float32
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			va = append(va, float32(rc.SimA.Mat.FloatVal([]int{i, j})))
+			vb = append(vb, float32(rc.SimB.Mat.FloatVal([]int{i, j})))
+		}
+	}
+	rc.RSA = metric.Correlation32(va, vb)
+	if rc.Label == nil {
+		return
+	}
+	rc.Label.SetText(fmt.Sprintf("RSA: %s vs. %s (%s, N = %d):  %.4g", rc.LayA, rc.LayB, rc.Var, n, rc.RSA))
+	for i, tg := range rc.Grids {
+		if i == 0 {
+			tg.SetSimMat(&rc.SimA)
+		} else {
+			tg.SetSimMat(&rc.SimB)
+		}
+	}
+}
+
+// RSALayerPat returns the pattern for given layer name (or Cat)
+// for given data index, for the RSA compare
+func (ss *Sim) RSALayerPat(lnm string, di int) ([]float32, error) {
+	if lnm == RSACatName {
+		pat := make([]float32, len(ss.LvisEnv(ss.Context.Mode).CatNames()))
+		if ci := ss.Stats.IntDi("TrlCatIdx", di); ci >= 0 && ci < len(pat) {
+			pat[ci] = 1
+		}
+		return pat, nil
+	}
+	ly, err := ss.Net.LayerByNameTry(lnm)
+	if err != nil {
+		return nil, err
+	}
+	var vals []float32
+	ly.UnitVals(&vals, ss.RSA.Var, di)
+	return vals, nil
+}
+
+// RSARecord records the current trial patterns for all data indexes
+// into the RSA compare buffers, and updates the RSA display.
+// Called at the end of each test trial in the GUI.
+func (ss *Sim) RSARecord() {
+	rc := &ss.RSA
+	if rc.N < 2 || rc.LayA == "" || rc.LayB == "" {
+		return
+	}
+	if len(rc.Labels) != rc.N {
+		rc.Labels = make([]string, rc.N)
+		rc.Reset()
+	}
+	for di := 0; di < int(ss.Context.NetIdxs.NData); di++ {
+		pa, err := ss.RSALayerPat(rc.LayA, di)
+		if err != nil {
+			return
+		}
+		pb, err := ss.RSALayerPat(rc.LayB, di)
+		if err != nil {
+			return
+		}
+		rc.CheckShape(&rc.PatsA, len(pa))
+		rc.CheckShape(&rc.PatsB, len(pb))
+		rc.SetPat(&rc.PatsA, pa)
+		rc.SetPat(&rc.PatsB, pb)
+		rc.Labels[rc.Idx] = ss.Stats.StringDi("TrlCat", di)
+		rc.Idx = (rc.Idx + 1) % rc.N
+		if rc.NItems < rc.N {
+			rc.NItems++
+		}
+	}
+	rc.Update()
+}
+
+// ConfigRSAGui adds the RSA compare tab to the GUI
+func (ss *Sim) ConfigRSAGui() {
+	rc := &ss.RSA
+	ly := ss.GUI.TabView.AddNewTab(gi.KiT_Layout, "RSA").(*gi.Layout)
+	ly.Lay = gi.LayoutVert
+	ly.SetStretchMax()
+	rc.Label = gi.AddNewLabel(ly, "rsa", "RSA: set RSA LayA, LayB in the Sim and step through Test trials")
+	row := gi.AddNewLayout(ly, "grids", gi.LayoutHoriz)
+	row.SetStretchMax()
+	rc.SimA.Init()
+	rc.SimB.Init()
+	rc.Grids[0] = etview.AddNewSimMatGrid(row, "SimA", &rc.SimA)
+	rc.Grids[1] = etview.AddNewSimMatGrid(row, "SimB", &rc.SimB)
+}
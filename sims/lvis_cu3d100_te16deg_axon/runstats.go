@@ -0,0 +1,154 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// runstats.go has the cross-run seed variance report: the headline
+// metrics of the training run log (one row per run) are summarized across
+// runs with their mean, standard deviation, standard error, min and max,
+// and a bootstrap 95% confidence interval of the mean, resampling the runs
+// RunStatsNBoot times.  The report is the RunStats misc table, updated at
+// the end of each run, and is saved as a run_stats.tsv file at the end of
+// multi-run jobs in nogui mode.  Missing (NaN) values are excluded, as are
+// the -1 values of FirstZero and LastZero for runs that never reached zero
+// errors.
+
+// RunStatsCols are the headline metrics of the run log summarized in the
+// RunStats report, if present in the log
+var RunStatsCols = []string{"PctCor", "PctErr", "PctErr2", "DecErr", "DecErr2", "TstPctErr", "TstPctErr2", "TstDecErr", "TstDecErr2", "FirstZero", "LastZero"}
+
+// RunStatsNBoot is the number of bootstrap resamples for the
+// confidence interval of the mean
+const RunStatsNBoot = 1000
+
+// RunStatsSeed is the random seed for the bootstrap resampling,
+// fixed so that the report is reproducible
+const RunStatsSeed = 3371
+
+// RunStatsVals returns the values of given column of given run log,
+// excluding missing values
+func RunStatsVals(dt *etable.Table, col string) []float64 {
+	vals := make([]float64, 0, dt.Rows)
+	for ri := 0; ri < dt.Rows; ri++ {
+		v := dt.CellFloat(col, ri)
+		if math.IsNaN(v) || ((col == "FirstZero" || col == "LastZero") && v < 0) {
+			continue
+		}
+		vals = append(vals, v)
+	}
+	return vals
+}
+
+// BootstrapCI returns the lower and upper bounds of the 95% bootstrap
+// percentile confidence interval of the mean of given values, from
+// nboot resamples with given random source
+func BootstrapCI(vals []float64, nboot int, rnd *rand.Rand) (lo, hi float64) {
+	n := len(vals)
+	if n == 0 {
+		return math.NaN(), math.NaN()
+	}
+	means := make([]float64, nboot)
+	for bi := range means {
+		sum := 0.0
+		for i := 0; i < n; i++ {
+			sum += vals[rnd.Intn(n)]
+		}
+		means[bi] = sum / float64(n)
+	}
+	sort.Float64s(means)
+	return means[int(0.025*float64(nboot-1))], means[int(0.975*float64(nboot-1))]
+}
+
+// RunStatsTable returns the report of the given columns of given run log,
+// one row per column present in the log: Stat, N, Mean, SD, SEM, Min, Max,
+// CILo, CIHi
+func RunStatsTable(dt *etable.Table, cols []string) *etable.Table {
+	rt := &etable.Table{}
+	rt.SetFromSchema(etable.Schema{
+		{"Stat", etensor.STRING, nil, nil},
+		{"N", etensor.INT64, nil, nil},
+		{"Mean", etensor.FLOAT64, nil, nil},
+		{"SD", etensor.FLOAT64, nil, nil},
+		{"SEM", etensor.FLOAT64, nil, nil},
+		{"Min", etensor.FLOAT64, nil, nil},
+		{"Max", etensor.FLOAT64, nil, nil},
+		{"CILo", etensor.FLOAT64, nil, nil},
+		{"CIHi", etensor.FLOAT64, nil, nil},
+	}, 0)
+	rnd := rand.New(rand.NewSource(RunStatsSeed))
+	for _, col := range cols {
+		if dt.ColIdx(col) < 0 {
+			continue
+		}
+		vals := RunStatsVals(dt, col)
+		n := len(vals)
+		mean, sd, sem := math.NaN(), math.NaN(), math.NaN()
+		mn, mx := math.NaN(), math.NaN()
+		if n > 0 {
+			mn, mx = vals[0], vals[0]
+			sum := 0.0
+			for _, v := range vals {
+				sum += v
+				mn = math.Min(mn, v)
+				mx = math.Max(mx, v)
+			}
+			mean = sum / float64(n)
+		}
+		if n > 1 {
+			sq := 0.0
+			for _, v := range vals {
+				sq += (v - mean) * (v - mean)
+			}
+			sd = math.Sqrt(sq / float64(n-1))
+			sem = sd / math.Sqrt(float64(n))
+		}
+		lo, hi := BootstrapCI(vals, RunStatsNBoot, rnd)
+		row := rt.Rows
+		rt.AddRows(1)
+		rt.SetCellString("Stat", row, col)
+		rt.SetCellFloat("N", row, float64(n))
+		rt.SetCellFloat("Mean", row, mean)
+		rt.SetCellFloat("SD", row, sd)
+		rt.SetCellFloat("SEM", row, sem)
+		rt.SetCellFloat("Min", row, mn)
+		rt.SetCellFloat("Max", row, mx)
+		rt.SetCellFloat("CILo", row, lo)
+		rt.SetCellFloat("CIHi", row, hi)
+	}
+	return rt
+}
+
+// RunStats updates the RunStats misc table from the training run log
+// -- called at the end of each run
+func (ss *Sim) RunStats() {
+	ss.Logs.MiscTables["RunStats"] = RunStatsTable(ss.Logs.Table(etime.Train, etime.Run), RunStatsCols)
+}
+
+// SaveRunStats saves the RunStats report as a run_stats.tsv file named by
+// the network and run, at the end of multi-run jobs, on MPI rank 0
+func (ss *Sim) SaveRunStats(netName, runName string) {
+	rt := ss.Logs.MiscTables["RunStats"]
+	if ss.Config.Run.NRuns < 2 || rt == nil || ss.MPIRank() != 0 {
+		return
+	}
+	fnm := elog.LogFileName("run_stats", netName, runName)
+	if err := rt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		mpi.Println(err)
+		return
+	}
+	mpi.Printf("Saved run stats to: %s\n", fnm)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+func TestBootstrapCI(t *testing.T) {
+	tests := []struct {
+		vals   []float64
+		lo, hi float64 // bounds on the CI bounds
+	}{
+		{[]float64{2, 2, 2}, 2, 2},
+		{[]float64{1}, 1, 1},
+		{[]float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, 1, 8},
+	}
+	for _, tt := range tests {
+		lo, hi := BootstrapCI(tt.vals, 1000, rand.New(rand.NewSource(1)))
+		if lo < tt.lo || hi > tt.hi || lo > hi {
+			t.Errorf("BootstrapCI(%v) = %g, %g, want within: %g, %g", tt.vals, lo, hi, tt.lo, tt.hi)
+		}
+		mean := 0.0
+		for _, v := range tt.vals {
+			mean += v
+		}
+		mean /= float64(len(tt.vals))
+		if lo > mean || hi < mean {
+			t.Errorf("BootstrapCI(%v) = %g, %g, excludes mean: %g", tt.vals, lo, hi, mean)
+		}
+	}
+	if lo, hi := BootstrapCI(nil, 1000, rand.New(rand.NewSource(1))); !math.IsNaN(lo) || !math.IsNaN(hi) {
+		t.Errorf("BootstrapCI(nil) = %g, %g, want NaN", lo, hi)
+	}
+}
+
+func TestRunStatsTable(t *testing.T) {
+	dt := &etable.Table{}
+	dt.SetFromSchema(etable.Schema{
+		{"Run", etensor.INT64, nil, nil},
+		{"PctErr", etensor.FLOAT64, nil, nil},
+		{"FirstZero", etensor.FLOAT64, nil, nil},
+	}, 4)
+	for ri, v := range []float64{0.1, 0.2, 0.3, math.NaN()} {
+		dt.SetCellFloat("Run", ri, float64(ri))
+		dt.SetCellFloat("PctErr", ri, v)
+	}
+	for ri, v := range []float64{10, -1, 20, -1} {
+		dt.SetCellFloat("FirstZero", ri, v)
+	}
+	rt := RunStatsTable(dt, []string{"PctErr", "DecErr", "FirstZero"})
+	if rt.Rows != 2 {
+		t.Fatalf("RunStatsTable rows: %d, want: 2", rt.Rows)
+	}
+	tests := []struct {
+		stat             string
+		n                int
+		mean, sd, mn, mx float64
+	}{
+		{"PctErr", 3, 0.2, 0.1, 0.1, 0.3},
+		{"FirstZero", 2, 15, math.Sqrt(50), 10, 20},
+	}
+	for ri, tt := range tests {
+		near := func(col string, want float64) {
+			if v := rt.CellFloat(col, ri); math.Abs(v-want) > 1e-9 {
+				t.Errorf("%s %s: %g, want: %g", tt.stat, col, v, want)
+			}
+		}
+		if st := rt.CellString("Stat", ri); st != tt.stat {
+			t.Errorf("row %d Stat: %s, want: %s", ri, st, tt.stat)
+		}
+		near("N", float64(tt.n))
+		near("Mean", tt.mean)
+		near("SD", tt.sd)
+		near("SEM", tt.sd/math.Sqrt(float64(tt.n)))
+		near("Min", tt.mn)
+		near("Max", tt.mx)
+		if lo, hi := rt.CellFloat("CILo", ri), rt.CellFloat("CIHi", ri); lo < tt.mn || hi > tt.mx || lo > hi {
+			t.Errorf("%s CI: %g, %g, want within: %g, %g", tt.stat, lo, hi, tt.mn, tt.mx)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/emergent/prjn"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/agg"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// samediff.go has the same / different two-alternative forced choice
+// task, for match-to-sample style experiments: images are presented in
+// pairs of sequential trials, a sample followed by a probe, which is from
+// the same category as the sample with probability Config.Env.SameDiff.PSame,
+// and otherwise from a different category.  On probe trials, the SameDiff
+// output layer, which receives from TE alongside the Output layer, is
+// trained to report whether the probe matches the sample, relying on
+// the activity carried over from the sample trial (Decay.Act = 0).
+// On sample trials, the SameDiff layer has no target.  Each pair uses a
+// single step of the env, so an epoch covers half as many env items.
+// See Config.Env.SameDiff.
+
+// SameDiffResps are the responses of the SameDiff layer,
+// in order of the groups of NOutPer units in the layer
+var SameDiffResps = []string{"Same", "Diff"}
+
+// SameDiff has the state of the same / different task
+type SameDiff struct {
+
+	// number of trials in the current epoch, alternating sample and probe
+	N int
+
+	// true if the current trial is a probe trial, else a sample trial
+	Probe bool
+
+	// sample image for each data index
+	SampImgs []string
+
+	// category index of the sample for each data index
+	SampCats []int
+
+	// target pattern for the SameDiff layer
+	Targ etensor.Float32
+}
+
+// ConfigSameDiff checks that the same / different task can be used with
+// the current env -- called at the end of ConfigEnv
+func (ss *Sim) ConfigSameDiff() {
+	if !ss.Config.Env.SameDiff.On {
+		return
+	}
+	if ss.ImagesEnv(etime.Train) == nil {
+		mpi.Println("SameDiff: the same / different task requires the Images env")
+		ss.Config.Env.SameDiff.On = false
+	}
+}
+
+// ConfigSameDiffNet adds the SameDiff output layer, with NOutPer units
+// per response, bidirectionally connected to given TE layer, placed
+// behind given Output layer -- called in ConfigNet prior to Build
+func (ss *Sim) ConfigSameDiffNet(net *axon.Network, te, out *axon.Layer) {
+	if !ss.Config.Env.SameDiff.On {
+		return
+	}
+	nper := ss.Config.Env.NOutPer
+	sd := net.AddLayer2D("SameDiff", 1, len(SameDiffResps)*nper, axon.TargetLayer)
+	tesd, sdte := net.BidirConnectLayers(te, sd, prjn.NewFull())
+	tesd.SetClass("ToOut ToSameDiff")
+	sdte.SetClass("FmOut FmSameDiff")
+	sd.PlaceBehind(out, 2)
+	ss.SameDiff.Targ.SetShape([]int{1, len(SameDiffResps) * nper}, nil, nil)
+}
+
+// InitSameDiff starts a new epoch with a sample trial,
+// so pairs do not span epochs or modes
+func (ss *Sim) InitSameDiff() {
+	ss.SameDiff.N = 0
+	ss.SameDiff.Probe = false
+}
+
+// SameDiffTrial advances to the next trial of the sample / probe
+// sequence -- called at the start of ApplyInputs
+func (ss *Sim) SameDiffTrial() {
+	sd := &ss.SameDiff
+	if !ss.Config.Env.SameDiff.On {
+		return
+	}
+	sd.Probe = sd.N%2 == 1
+	sd.N++
+	nd := int(ss.Context.NetIdxs.NData)
+	if len(sd.SampCats) != nd {
+		sd.SampImgs = make([]string, nd)
+		sd.SampCats = make([]int, nd)
+	}
+}
+
+// SameDiffStep presents the next sample or probe image in given env for
+// given data index, returning false if the task is off, in which case the
+// env is stepped as usual.  Sets the SDSame stat: 1 for a same-category
+// probe, 0 for a different one, and -1 for a sample trial.
+func (ss *Sim) SameDiffStep(di int, ev *ImagesEnv) bool {
+	if !ss.Config.Env.SameDiff.On || ev == nil {
+		return false
+	}
+	sd := &ss.SameDiff
+	if !sd.Probe {
+		ev.Step()
+		sd.SampImgs[di] = ev.CurImg
+		sd.SampCats[di] = ev.CurCatIdx
+		ss.Stats.SetIntDi("SDSame", di, -1)
+		return true
+	}
+	img, _, same := ev.ChooseRelated(sd.SampCats[di], sd.SampImgs[di], ss.Config.Env.SameDiff.PSame)
+	ev.RandTransforms()
+	ev.ShowImage(img)
+	sm := 0
+	if same {
+		sm = 1
+	}
+	ss.Stats.SetIntDi("SDSame", di, sm)
+	return true
+}
+
+// ApplySameDiff applies the SameDiff target for given data index on
+// probe trials -- called in ApplyInputs after the other layers
+func (ss *Sim) ApplySameDiff(di uint32) {
+	sd := &ss.SameDiff
+	if !ss.Config.Env.SameDiff.On || !sd.Probe {
+		return
+	}
+	sm := ss.Stats.IntDi("SDSame", int(di))
+	resp := 1
+	if sm == 1 {
+		resp = 0
+	}
+	nper := ss.Config.Env.NOutPer
+	for i := range sd.Targ.Values {
+		sd.Targ.Values[i] = 0
+		if i/nper == resp {
+			sd.Targ.Values[i] = 1
+		}
+	}
+	ss.Net.AxonLayerByName("SameDiff").ApplyExt(&ss.Context, di, &sd.Targ)
+}
+
+// SameDiffTrialStats sets the SDResp and SDErr stats for given data index:
+// the response is the group of SameDiff units with the highest mean ActM,
+// and SDErr is 1 if it does not match the probe, and NaN on sample trials.
+// Called at the end of TrialStats.
+func (ss *Sim) SameDiffTrialStats(di int) {
+	if !ss.Config.Env.SameDiff.On {
+		return
+	}
+	sm := ss.Stats.IntDi("SDSame", di)
+	if sm < 0 {
+		ss.Stats.SetString("SDResp", "")
+		ss.Stats.SetFloat("SDErr", math.NaN())
+		return
+	}
+	var vals []float32
+	ss.Net.AxonLayerByName("SameDiff").UnitVals(&vals, "ActM", di)
+	nper := ss.Config.Env.NOutPer
+	resp := 0
+	mx := float32(-1)
+	for ri := range SameDiffResps {
+		sum := float32(0)
+		for _, v := range vals[ri*nper : (ri+1)*nper] {
+			sum += v
+		}
+		if sum > mx {
+			mx = sum
+			resp = ri
+		}
+	}
+	err := 1.0
+	if (resp == 0) == (sm == 1) {
+		err = 0
+	}
+	ss.Stats.SetString("SDResp", SameDiffResps[resp])
+	ss.Stats.SetFloat("SDErr", err)
+}
+
+// ConfigSameDiffLogs adds the log items for the same / different task:
+// SDSame and SDResp per trial, SDErr per trial and aggregated over probe
+// trials, and the SDSameErr and SDDiffErr test epoch error for same and
+// different probes.  The test epoch SDErr is copied to the train epoch
+// and run logs as TstSDErr.
+func (ss *Sim) ConfigSameDiffLogs() {
+	if !ss.Config.Env.SameDiff.On {
+		return
+	}
+	ss.Stats.SetFloat("SDErr", 0)
+	ss.Logs.AddStatIntNoAggItem(etime.AllModes, etime.Trial, "SDSame")
+	ss.Logs.AddStatStringItem(etime.AllModes, etime.Trial, "SDResp")
+	ss.Logs.AddStatAggItem("SDErr", etime.Run, etime.Epoch, etime.Trial)
+	for sm, nm := range []string{"SDDiffErr", "SDSameErr"} {
+		sm := float64(sm)
+		ss.Logs.AddItem(&elog.Item{
+			Name: nm,
+			Type: etensor.FLOAT64,
+			Write: elog.WriteMap{
+				etime.Scope(etime.Test, etime.Epoch): func(ctx *elog.Context) {
+					ix := etable.NewIdxView(ctx.Logs.Table(etime.Test, etime.Trial))
+					ix.Filter(func(et *etable.Table, row int) bool {
+						return et.CellFloat("SDSame", row) == sm
+					})
+					ctx.SetFloat64(agg.Mean(ix, "SDErr")[0])
+				}}})
+	}
+	ss.Logs.AddCopyFromFloatItems(etime.Train, []etime.Times{etime.Epoch, etime.Run}, etime.Test, etime.Epoch, "Tst", "SDErr")
+}
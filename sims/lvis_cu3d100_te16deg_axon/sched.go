@@ -0,0 +1,177 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/emer/emergent/etime"
+	"github.com/emer/emergent/params"
+	"github.com/emer/empi/mpi"
+)
+
+// sched.go has the param scheduler, which sets selected network params
+// to values scheduled over training epochs, e.g., annealing the inhibition
+// Gi from higher values early in training, to force sparsity, to lower
+// values later.  The params are re-applied and synced to the GPU at the
+// start of each epoch in which a scheduled value changes, and the current
+// values are logged in the train epoch log.  With the Env selector, the
+// Path is a field of the training env instead, e.g., TransSigma, to turn
+// off the gaussian translations for the last epochs of training.  Each
+// change is recorded as an event (see AddEvent): Lrate for learning rate
+// params, TransSigma, and Schedule for all others.  See
+// Config.Params.Schedule.
+
+// ScheduleEnvSel is the ScheduleConfig.Sel for params of the training env
+const ScheduleEnvSel = "Env"
+
+// ScheduleStatName returns the name of the stat recording the current
+// value of the param for given ScheduleConfig, e.g., Sched_V4_Gi
+func ScheduleStatName(sc *ScheduleConfig) string {
+	pth := strings.Split(sc.Path, ".")
+	return "Sched_" + SelName(sc.Sel) + "_" + pth[len(pth)-1]
+}
+
+// Val returns the scheduled value at given epoch, and false if the
+// epoch is before the first scheduled epoch, so the base params apply
+func (sc *ScheduleConfig) Val(epc int) (float32, bool) {
+	n := len(sc.Epochs)
+	if len(sc.Vals) < n {
+		n = len(sc.Vals)
+	}
+	if n == 0 || epc < sc.Epochs[0] {
+		return 0, false
+	}
+	for i := 1; i < n; i++ {
+		if epc >= sc.Epochs[i] {
+			continue
+		}
+		if !sc.Interp {
+			return sc.Vals[i-1], true
+		}
+		pe, ne := sc.Epochs[i-1], sc.Epochs[i]
+		f := float32(epc-pe) / float32(ne-pe)
+		return sc.Vals[i-1] + f*(sc.Vals[i]-sc.Vals[i-1]), true
+	}
+	return sc.Vals[n-1], true
+}
+
+// ScheduleEvent returns the event for the change of the param for given
+// ScheduleConfig to given value
+func ScheduleEvent(sc *ScheduleConfig, val float32) string {
+	switch {
+	case sc.Sel == ScheduleEnvSel && sc.Path == "TransSigma":
+		return fmt.Sprintf("TransSigma: %g", val)
+	case strings.Contains(sc.Path, "LRate"):
+		return fmt.Sprintf("Lrate: %s %s: %g", sc.Sel, sc.Path, val)
+	}
+	return fmt.Sprintf("Schedule: %s %s: %g", sc.Sel, sc.Path, val)
+}
+
+// EnvFieldFloat returns the float32 value of the field at given
+// dotted path in given env, for restoring scheduled env params
+func EnvFieldFloat(ev any, path string) (float32, error) {
+	v := reflect.Indirect(reflect.ValueOf(ev))
+	for _, fnm := range strings.Split(path, ".") {
+		v = reflect.Indirect(v.FieldByName(fnm))
+		if !v.IsValid() {
+			return 0, fmt.Errorf("Schedule: env field not found: %s", path)
+		}
+	}
+	if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+		return 0, fmt.Errorf("Schedule: env field is not a float: %s", path)
+	}
+	return float32(v.Float()), nil
+}
+
+// InitSchedule restores the base params at the start of a run, if
+// the schedule was applied in a prior run, and resets the logged values.
+func (ss *Sim) InitSchedule() {
+	scs := ss.Config.Params.Schedule
+	if len(scs) == 0 {
+		return
+	}
+	if len(ss.SchedVals) > 0 {
+		ss.ApplyParams()
+		ss.Net.GPU.SyncParamsToGPU()
+	}
+	if len(ss.SchedEnvBase) > 0 {
+		if err := params.ApplyMap(ss.Envs.ByMode(etime.Train), ss.SchedEnvBase, ss.Config.Debug); err != nil {
+			mpi.Println(err)
+		}
+	}
+	ss.SchedEnvBase = make(map[string]any)
+	ss.SchedVals = make([]float32, len(scs))
+	for i := range scs {
+		ss.SchedVals[i] = float32(math.NaN())
+		ss.Stats.SetFloat32(ScheduleStatName(&scs[i]), ss.SchedVals[i])
+	}
+}
+
+// ScheduleParams applies the scheduled param values for the current
+// training epoch, for those that have changed -- called at the start
+// of each training epoch
+func (ss *Sim) ScheduleParams() {
+	scs := ss.Config.Params.Schedule
+	if len(scs) == 0 {
+		return
+	}
+	epc := ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur
+	trn := ss.Envs.ByMode(etime.Train)
+	vals := make(map[string]any)
+	envVals := make(map[string]any)
+	var events []string
+	for i := range scs {
+		sc := &scs[i]
+		val, ok := sc.Val(epc)
+		if !ok || val == ss.SchedVals[i] {
+			continue
+		}
+		if sc.Sel == ScheduleEnvSel {
+			if _, has := ss.SchedEnvBase[sc.Path]; !has {
+				base, err := EnvFieldFloat(trn, sc.Path)
+				if err != nil {
+					mpi.Println(err)
+					continue
+				}
+				ss.SchedEnvBase[sc.Path] = base
+			}
+			envVals[sc.Path] = val
+		} else {
+			vals[sc.Sel+":"+sc.Path] = val
+		}
+		ss.SchedVals[i] = val
+		ss.Stats.SetFloat32(ScheduleStatName(sc), val)
+		events = append(events, ScheduleEvent(sc, val))
+	}
+	if len(envVals) > 0 {
+		if err := params.ApplyMap(trn, envVals, ss.Config.Debug); err != nil {
+			mpi.Println(err)
+		}
+	}
+	if len(vals) > 0 {
+		if err := ss.Params.SetNetworkMap(ss.Net, vals); err != nil {
+			mpi.Println(err)
+			return
+		}
+		ss.Net.GPU.SyncParamsToGPU()
+	}
+	for _, ev := range events {
+		ss.AddEvent(ev)
+	}
+}
+
+// ConfigScheduleLogs adds epoch-level log items for the current value
+// of each scheduled param
+func (ss *Sim) ConfigScheduleLogs() {
+	for i := range ss.Config.Params.Schedule {
+		sc := &ss.Config.Params.Schedule[i]
+		ss.Stats.SetFloat32(ScheduleStatName(sc), float32(math.NaN()))
+		ss.Logs.AddStatFloatNoAggItem(etime.Train, etime.Epoch, ScheduleStatName(sc))
+	}
+}
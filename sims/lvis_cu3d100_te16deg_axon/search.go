@@ -0,0 +1,184 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/emer/emergent/econfig"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// search.go has distributed hyperparameter search over MPI colors:
+// the MPI world is split into separate communicators, one per
+// configuration in the Config.Run.Search list, each of which trains
+// independently, and world rank 0 aggregates the results.
+
+// OpenSearchList opens a hyperparameter search list file, returning
+// the command-line args string for each configuration: one per
+// non-empty line, with # starting a comment.
+func OpenSearchList(fname string) ([]string, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var cfgs []string
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		ln := scan.Text()
+		if ci := strings.Index(ln, "#"); ci >= 0 {
+			ln = ln[:ci]
+		}
+		ln = strings.TrimSpace(ln)
+		if ln == "" {
+			continue
+		}
+		cfgs = append(cfgs, ln)
+	}
+	return cfgs, scan.Err()
+}
+
+// MPISearchInit splits the MPI world into one communicator (color) per
+// configuration in the Config.Run.Search list, with contiguous blocks of
+// ranks, and applies the args for this proc's configuration to the Config.
+// Each configuration gets a different Params.Tag, so saved files are
+// distinct.  Must be called by all procs, after the world Comm is created.
+func (ss *Sim) MPISearchInit() error {
+	cfgs, err := OpenSearchList(ss.Config.Run.Search)
+	if err != nil {
+		return err
+	}
+	nc := len(cfgs)
+	ws := mpi.WorldSize()
+	if nc == 0 || ws%nc != 0 {
+		return fmt.Errorf("MPISearchInit: number of MPI procs: %d must be an even multiple of number of search configurations: %d", ws, nc)
+	}
+	ss.SearchArgs = cfgs
+	ss.WorldComm = ss.Comm
+	per := ws / nc
+	ss.SearchColor = mpi.WorldRank() / per
+	ranks := make([]int, per)
+	for i := range ranks {
+		ranks[i] = ss.SearchColor*per + i
+	}
+	ss.Comm, err = mpi.NewComm(ranks)
+	if err != nil {
+		return err
+	}
+	if _, err := econfig.SetFromArgs(&ss.Config, strings.Fields(cfgs[ss.SearchColor])); err != nil {
+		return err
+	}
+	ctag := fmt.Sprintf("c%d", ss.SearchColor)
+	if ss.Config.Params.Tag != "" {
+		ss.Config.Params.Tag += "_" + ctag
+	} else {
+		ss.Config.Params.Tag = ctag
+	}
+	mpi.Printf("MPI search: %d configurations on %d procs each\n", nc, per)
+	return nil
+}
+
+// MPIRank returns the rank of this proc within the MPI communicator
+// used for data-parallel training, which is the world unless running
+// a search.
+func (ss *Sim) MPIRank() int {
+	if !ss.Config.Run.MPI || ss.Comm == nil {
+		return 0
+	}
+	return ss.Comm.Rank()
+}
+
+// MPISize returns the number of procs in the MPI communicator
+// used for data-parallel training, which is the world unless running
+// a search.
+func (ss *Sim) MPISize() int {
+	if !ss.Config.Run.MPI || ss.Comm == nil {
+		return 1
+	}
+	return ss.Comm.Size()
+}
+
+// SearchStats returns the final training and testing percent error for
+// this configuration, averaged over the runs in the Train Run log,
+// or from the last Train Epoch if no runs have completed.
+func (ss *Sim) SearchStats() (trnErr, tstErr float64) {
+	dt := ss.Logs.Table(etime.Train, etime.Run)
+	if dt.Rows == 0 {
+		dt = ss.Logs.Table(etime.Train, etime.Epoch)
+		if dt.Rows == 0 {
+			return
+		}
+		return dt.CellFloat("PctErr", dt.Rows-1), dt.CellFloat("TstPctErr", dt.Rows-1)
+	}
+	for ri := 0; ri < dt.Rows; ri++ {
+		trnErr += dt.CellFloat("PctErr", ri)
+		tstErr += dt.CellFloat("TstPctErr", ri)
+	}
+	n := float64(dt.Rows)
+	return trnErr / n, tstErr / n
+}
+
+// MPISearchResults gathers the SearchStats from each configuration to
+// world rank 0, which saves them in the search results table, and prints
+// the best configuration.  Must be called by all procs at the end.
+func (ss *Sim) MPISearchResults() {
+	if ss.WorldComm == nil {
+		return
+	}
+	const nv = 3
+	trnErr, tstErr := ss.SearchStats()
+	orig := []float64{float64(ss.SearchColor), trnErr, tstErr}
+	ws := mpi.WorldSize()
+	var all []float64
+	if mpi.WorldRank() == 0 {
+		all = make([]float64, ws*nv)
+	}
+	err := ss.WorldComm.GatherF64(0, all, orig)
+	if err != nil {
+		mpi.Println(err)
+		return
+	}
+	if mpi.WorldRank() != 0 {
+		return
+	}
+	nc := len(ss.SearchArgs)
+	per := ws / nc
+	dt := &etable.Table{}
+	dt.SetFromSchema(etable.Schema{
+		{"Color", etensor.INT64, nil, nil},
+		{"Args", etensor.STRING, nil, nil},
+		{"PctErr", etensor.FLOAT64, nil, nil},
+		{"TstPctErr", etensor.FLOAT64, nil, nil},
+	}, nc)
+	best := 0
+	for ci := 0; ci < nc; ci++ {
+		vals := all[ci*per*nv : (ci*per+1)*nv] // from color leader
+		dt.SetCellFloat("Color", ci, vals[0])
+		dt.SetCellString("Args", ci, ss.SearchArgs[ci])
+		dt.SetCellFloat("PctErr", ci, vals[1])
+		dt.SetCellFloat("TstPctErr", ci, vals[2])
+		if vals[2] < dt.CellFloat("TstPctErr", best) {
+			best = ci
+		}
+	}
+	ss.Logs.MiscTables["Search"] = dt
+	base := strings.TrimSuffix(filepath.Base(ss.Config.Run.Search), filepath.Ext(ss.Config.Run.Search))
+	fnm := elog.LogFileName("results", ss.Net.Name(), base)
+	if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		mpi.Println(err)
+		return
+	}
+	mpi.Printf("Saved search results to: %s\nBest configuration: %d: %s  TstPctErr: %g\n", fnm, best, ss.SearchArgs[best], dt.CellFloat("TstPctErr", best))
+}
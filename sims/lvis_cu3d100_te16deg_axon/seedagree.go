@@ -0,0 +1,247 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// seedagree.go has the test-retest reliability of the model decisions
+// across networks trained from different random seeds (e.g., the final
+// weights of different runs), quantifying how idiosyncratic the errors
+// are: the identical test sequence is run through each network (see
+// TestWts), and the decisions are compared per image and per pair of
+// seeds.  Per image (seed_agree log): the fraction of seeds in error, the
+// modal response and the fraction of seeds giving it, and the fraction of
+// seed pairs giving the same response.  Per pair of seeds (seed_pairs
+// log): the decision agreement, the fraction of images both got wrong vs.
+// the fraction expected by chance if the errors were independent, the
+// fraction of those with the same wrong response, and the correlation of
+// the error patterns (phi coefficient of the 0/1 errors).  Errors that are
+// mostly idiosyncratic have ErrCor near 0, while errors driven by the
+// images themselves have high ErrCor.  See Config.Run.SeedAgree.
+
+// SeedAgreeErrCor returns the correlation (phi coefficient) of two 0/1
+// error vectors from the counts of images with both in error (n11),
+// only a (n10), only b (n01), and neither (n00), or NaN if either has
+// no variance
+func SeedAgreeErrCor(n11, n10, n01, n00 int) float64 {
+	a1 := float64(n11 + n10)
+	a0 := float64(n01 + n00)
+	b1 := float64(n11 + n01)
+	b0 := float64(n10 + n00)
+	den := a1 * a0 * b1 * b0
+	if den == 0 {
+		return math.NaN()
+	}
+	return (float64(n11)*float64(n00) - float64(n10)*float64(n01)) / math.Sqrt(den)
+}
+
+// SeedAgree tests each of the weights files matching the given glob
+// pattern, in sorted order, on the full test set, and returns the per-image
+// agreement table and the per-pair reliability table, for the images
+// tested with all files.  Files that fail to open are reported and skipped.
+// The current weights are replaced by those from the last file.
+func (ss *Sim) SeedAgree(pattern string) (imgs, pairs *etable.Table, err error) {
+	fnms, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(fnms)
+	var files []string
+	var resps []map[string]string
+	var errs []map[string]float64
+	cats := make(map[string]string)
+	for _, fnm := range fnms {
+		mpi.Printf("SeedAgree: testing: %s\n", fnm)
+		dt, err := ss.TestWtsTable(fnm)
+		if err != nil {
+			mpi.Println(err)
+			continue
+		}
+		rsp := make(map[string]string, dt.Rows)
+		er := make(map[string]float64, dt.Rows)
+		for ri := 0; ri < dt.Rows; ri++ {
+			tnm := dt.CellString("TrialName", ri)
+			rsp[tnm] = dt.CellString("TrlResp", ri)
+			er[tnm] = dt.CellFloat("Err", ri)
+			cats[tnm] = dt.CellString("TrlCat", ri)
+		}
+		files = append(files, fnm)
+		resps = append(resps, rsp)
+		errs = append(errs, er)
+	}
+	nf := len(files)
+	if nf < 2 {
+		return nil, nil, fmt.Errorf("SeedAgree: need at least 2 weights files, have: %d matching: %s", nf, pattern)
+	}
+	var tnms []string
+	for tnm := range resps[0] {
+		all := true
+		for fi := 1; fi < nf; fi++ {
+			if _, ok := resps[fi][tnm]; !ok {
+				all = false
+				break
+			}
+		}
+		if all {
+			tnms = append(tnms, tnm)
+		}
+	}
+	sort.Strings(tnms)
+
+	imgs = &etable.Table{}
+	imgs.SetFromSchema(etable.Schema{
+		{"TrialName", etensor.STRING, nil, nil},
+		{"Cat", etensor.STRING, nil, nil},
+		{"NSeeds", etensor.INT64, nil, nil},
+		{"PctErr", etensor.FLOAT64, nil, nil},
+		{"Modal", etensor.STRING, nil, nil},
+		{"ModalAgree", etensor.FLOAT64, nil, nil},
+		{"PairAgree", etensor.FLOAT64, nil, nil},
+	}, len(tnms))
+	npr := float64(nf*(nf-1)) / 2
+	for ri, tnm := range tnms {
+		nerr := 0.0
+		cnt := make(map[string]int)
+		for fi := 0; fi < nf; fi++ {
+			nerr += errs[fi][tnm]
+			cnt[resps[fi][tnm]]++
+		}
+		modal := ""
+		mx := 0
+		nsame := 0
+		for rsp, n := range cnt {
+			if n > mx || (n == mx && rsp < modal) {
+				mx = n
+				modal = rsp
+			}
+			nsame += n * (n - 1) / 2
+		}
+		imgs.SetCellString("TrialName", ri, tnm)
+		imgs.SetCellString("Cat", ri, cats[tnm])
+		imgs.SetCellFloat("NSeeds", ri, float64(nf))
+		imgs.SetCellFloat("PctErr", ri, nerr/float64(nf))
+		imgs.SetCellString("Modal", ri, modal)
+		imgs.SetCellFloat("ModalAgree", ri, float64(mx)/float64(nf))
+		imgs.SetCellFloat("PairAgree", ri, float64(nsame)/npr)
+	}
+
+	pairs = &etable.Table{}
+	pairs.SetFromSchema(etable.Schema{
+		{"FileA", etensor.STRING, nil, nil},
+		{"FileB", etensor.STRING, nil, nil},
+		{"N", etensor.INT64, nil, nil},
+		{"PctErrA", etensor.FLOAT64, nil, nil},
+		{"PctErrB", etensor.FLOAT64, nil, nil},
+		{"Agree", etensor.FLOAT64, nil, nil},
+		{"BothErr", etensor.FLOAT64, nil, nil},
+		{"ChanceBothErr", etensor.FLOAT64, nil, nil},
+		{"SameErr", etensor.FLOAT64, nil, nil},
+		{"ErrCor", etensor.FLOAT64, nil, nil},
+	}, 0)
+	n := float64(len(tnms))
+	for a := 0; a < nf; a++ {
+		for b := a + 1; b < nf; b++ {
+			n11, n10, n01, n00 := 0, 0, 0, 0
+			nagree, nsame := 0, 0
+			for _, tnm := range tnms {
+				ra, rb := resps[a][tnm], resps[b][tnm]
+				if ra == rb {
+					nagree++
+				}
+				ea, eb := errs[a][tnm] > 0, errs[b][tnm] > 0
+				switch {
+				case ea && eb:
+					n11++
+					if ra == rb {
+						nsame++
+					}
+				case ea:
+					n10++
+				case eb:
+					n01++
+				default:
+					n00++
+				}
+			}
+			row := pairs.Rows
+			pairs.SetNumRows(row + 1)
+			pairs.SetCellString("FileA", row, files[a])
+			pairs.SetCellString("FileB", row, files[b])
+			pairs.SetCellFloat("N", row, n)
+			if n == 0 {
+				continue
+			}
+			pa := float64(n11+n10) / n
+			pb := float64(n11+n01) / n
+			pairs.SetCellFloat("PctErrA", row, pa)
+			pairs.SetCellFloat("PctErrB", row, pb)
+			pairs.SetCellFloat("Agree", row, float64(nagree)/n)
+			pairs.SetCellFloat("BothErr", row, float64(n11)/n)
+			pairs.SetCellFloat("ChanceBothErr", row, pa*pb)
+			if n11 > 0 {
+				pairs.SetCellFloat("SameErr", row, float64(nsame)/float64(n11))
+			} else {
+				pairs.SetCellFloat("SameErr", row, math.NaN())
+			}
+			pairs.SetCellFloat("ErrCor", row, SeedAgreeErrCor(n11, n10, n01, n00))
+		}
+	}
+	return imgs, pairs, nil
+}
+
+// RunSeedAgree runs SeedAgree on the Config.Run.SeedAgree weights files,
+// and saves the per-image and per-pair tables as seed_agree and seed_pairs
+// log files on rank 0, printing the mean agreement and error correlation
+// over pairs
+func (ss *Sim) RunSeedAgree() error {
+	imgs, pairs, err := ss.SeedAgree(ss.Config.Run.SeedAgree)
+	if err != nil {
+		return err
+	}
+	ss.Logs.MiscTables["SeedAgree"] = imgs
+	ss.Logs.MiscTables["SeedPairs"] = pairs
+	if ss.MPIRank() != 0 {
+		return nil
+	}
+	agree, cor := 0.0, 0.0
+	ncor := 0
+	for ri := 0; ri < pairs.Rows; ri++ {
+		agree += pairs.CellFloat("Agree", ri)
+		if ec := pairs.CellFloat("ErrCor", ri); !math.IsNaN(ec) {
+			cor += ec
+			ncor++
+		}
+	}
+	agree /= float64(pairs.Rows)
+	if ncor > 0 {
+		cor /= float64(ncor)
+	} else {
+		cor = math.NaN()
+	}
+	mpi.Printf("SeedAgree: %d images, %d pairs of seeds: mean Agree: %g  mean ErrCor: %g\n", imgs.Rows, pairs.Rows, agree, cor)
+	runName := ss.Stats.String("RunName")
+	fnm := elog.LogFileName("seed_agree", ss.Net.Name(), runName)
+	if err := imgs.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	mpi.Printf("Saved per-image agreement across seeds to: %s\n", fnm)
+	fnm = elog.LogFileName("seed_pairs", ss.Net.Name(), runName)
+	if err := pairs.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	mpi.Printf("Saved agreement and error correlation by pair of seeds to: %s\n", fnm)
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSeedAgreeErrCor(t *testing.T) {
+	tests := []struct {
+		n11, n10, n01, n00 int
+		cor                float64
+	}{
+		{5, 0, 0, 5, 1},  // identical errors
+		{0, 5, 5, 0, -1}, // complementary errors
+		{1, 1, 1, 1, 0},  // independent
+		{2, 1, 1, 6, (12.0 - 1) / math.Sqrt(3*7*3*7)},
+		{0, 0, 3, 7, math.NaN()}, // a never in error
+		{10, 0, 0, 0, math.NaN()},
+		{0, 0, 0, 0, math.NaN()},
+	}
+	for _, tt := range tests {
+		cor := SeedAgreeErrCor(tt.n11, tt.n10, tt.n01, tt.n00)
+		if math.IsNaN(tt.cor) != math.IsNaN(cor) || (!math.IsNaN(cor) && math.Abs(cor-tt.cor) > 1e-12) {
+			t.Errorf("SeedAgreeErrCor(%d, %d, %d, %d) = %g, want: %g", tt.n11, tt.n10, tt.n01, tt.n00, cor, tt.cor)
+		}
+	}
+}
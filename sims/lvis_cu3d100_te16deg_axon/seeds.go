@@ -0,0 +1,122 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/emer/emergent/erand"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+)
+
+// seeds.go has the random seed registry: all the sources of randomness
+// in the sim are seeded at the start of each run from seeds derived from
+// Config.Run.Seed and the run number, and the seeds are printed and
+// logged in the Seeds column of the training run log, so that a run can
+// be fully reproduced.  The components are the network (weight init,
+// Rewire, Dropout), the global rand source (used by the decoder and
+// other library code), the train and test envs (image order and
+// transforms), the odd-one-out triplets, and the split-half partitions.
+// If Config.Run.Seed is 0, the original seeds are used: RndSeeds by run
+// for the network and global source, 73 for the envs, OddOneOutSeed,
+// and SplitHalfSeed.
+//
+// The env image order is reshuffled on each pass through the images from
+// a seed derived from the env seed, run, and pass, which is the same on
+// all MPI procs, and the random transforms are reseeded per pass and MPI
+// rank, so the presentation on each proc is reproducible -- the pass and
+// shuffle seed are logged in the ShuffleSeed column of the train epoch log.
+
+// SeedComps are the names of the components in the seed registry
+var SeedComps = []string{"Net", "Global", "TrainEnv", "TestEnv", "OddOneOut", "SplitHalf"}
+
+// SeedEnv is an optional interface for a LvisEnv that can be seeded by
+// the seed registry -- the seed must take effect at the next Init
+type SeedEnv interface {
+	SetRndSeed(seed int64)
+}
+
+// SetRndSeed sets the random seed used at the next Init
+func (ev *ImagesEnv) SetRndSeed(seed int64) {
+	ev.RndSeed = seed
+}
+
+// RunSeed returns the seed for given component for given run number,
+// derived from Config.Run.Seed, or the original seed if it is 0
+func (ss *Sim) RunSeed(run int, comp string) int64 {
+	base := ss.Config.Run.Seed
+	if base == 0 {
+		switch comp {
+		case "TrainEnv", "TestEnv":
+			return 73
+		case "OddOneOut":
+			return OddOneOutSeed
+		case "SplitHalf":
+			return SplitHalfSeed
+		}
+		return ss.RndSeeds[run]
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d:%d:%s", base, run, comp)
+	return int64(h.Sum64() >> 1) // non-negative
+}
+
+// InitRndSeed seeds all the components in the seed registry
+// for given training run number, recording the seeds in Seeds
+func (ss *Sim) InitRndSeed(run int) {
+	if ss.Seeds == nil {
+		ss.Seeds = make(map[string]int64)
+	}
+	for _, comp := range SeedComps {
+		ss.Seeds[comp] = ss.RunSeed(run, comp)
+	}
+	erand.NewGlobalRand().Seed(ss.Seeds["Global"])
+	ss.Net.Rand.Seed(ss.Seeds["Net"])
+	for _, mode := range []etime.Modes{etime.Train, etime.Test} {
+		if ev, ok := ss.Envs.ByMode(mode).(SeedEnv); ok {
+			ev.SetRndSeed(ss.Seeds[mode.String()+"Env"])
+		}
+		if ev := ss.ImagesEnv(mode); ev != nil {
+			ev.SeedRun = run
+		}
+	}
+	ss.Stats.SetString("Seeds", ss.SeedsString())
+}
+
+// ShuffleSeedStat records the shuffle seed of the current pass through
+// the training images in the ShuffleSeed stat, for the epoch log
+func (ss *Sim) ShuffleSeedStat() {
+	ev := ss.ImagesEnv(etime.Train)
+	if ev == nil {
+		return
+	}
+	ss.Stats.SetString("ShuffleSeed", fmt.Sprintf("%d:%d", ev.Pass, ev.ShuffleSeed(ev.Pass)))
+}
+
+// SeedsString returns the current seeds, as space-separated comp:seed
+func (ss *Sim) SeedsString() string {
+	strs := make([]string, len(SeedComps))
+	for i, comp := range SeedComps {
+		strs[i] = fmt.Sprintf("%s:%d", comp, ss.Seeds[comp])
+	}
+	return strings.Join(strs, " ")
+}
+
+// PrintSeeds prints the seeds for the current run
+func (ss *Sim) PrintSeeds() {
+	mpi.Printf("Seeds: %s\n", ss.SeedsString())
+}
+
+// NewSeeds generates new random seeds based on the current time,
+// including a new Config.Run.Seed if it is set
+func (ss *Sim) NewSeeds() {
+	ss.RndSeeds.NewSeeds()
+	if ss.Config.Run.Seed != 0 {
+		ss.Config.Run.Seed = ss.RndSeeds[0]
+	}
+}
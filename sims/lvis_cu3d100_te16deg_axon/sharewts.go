@@ -0,0 +1,127 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/empi/mpi"
+)
+
+// sharewts.go has the shared weights between the 16 and 8 degree
+// pathways, which are scale-shifted copies of each other: each
+// projection in the 16 deg pathway is tied to the corresponding one in
+// the 8 deg pathway (e.g., V2m16 -> V4f16 with V2m8 -> V4f8, and
+// TEOf16 -> TE with TEOf8 -> TE), by starting them with the same weights
+// and averaging their DWts prior to WtFmDWt, so they stay identical.
+// Only projections with the same connectivity are tied, which excludes
+// the random V1 shortcuts.  See Config.Params.ShareWts.
+
+// ScaleTwinName returns the name of the corresponding layer in the
+// other scale pathway, swapping a 16 suffix for 8 and vice-versa,
+// or the same name for layers that are not scale-specific
+func ScaleTwinName(nm string) string {
+	switch {
+	case strings.HasSuffix(nm, "16"):
+		return strings.TrimSuffix(nm, "16") + "8"
+	case strings.HasSuffix(nm, "8"):
+		return strings.TrimSuffix(nm, "8") + "16"
+	}
+	return nm
+}
+
+// SameConnectivity returns true if the two projections have the same
+// connectivity, synapse by synapse, in layer-relative neuron indexes
+func SameConnectivity(ctx *axon.Context, a, b *axon.Prjn) bool {
+	if a.NSyns != b.NSyns {
+		return false
+	}
+	ars, ass := a.Recv.NeurStIdx, a.Send.NeurStIdx
+	brs, bss := b.Recv.NeurStIdx, b.Send.NeurStIdx
+	for syi := uint32(0); syi < a.NSyns; syi++ {
+		asy, bsy := a.SynStIdx+syi, b.SynStIdx+syi
+		if axon.SynI(ctx, asy, axon.SynRecvIdx)-ars != axon.SynI(ctx, bsy, axon.SynRecvIdx)-brs {
+			return false
+		}
+		if axon.SynI(ctx, asy, axon.SynSendIdx)-ass != axon.SynI(ctx, bsy, axon.SynSendIdx)-bss {
+			return false
+		}
+	}
+	return true
+}
+
+// InitShareWts finds the pairs of projections to tie between the 16 and
+// 8 deg pathways, and copies the weights of the 16 deg projection to the
+// 8 deg one, if Config.Params.ShareWts is set -- called at the start of
+// each run, after the weights are initialized
+func (ss *Sim) InitShareWts() {
+	ss.SharedPrjns = nil
+	if !ss.Config.Params.ShareWts {
+		return
+	}
+	ctx := &ss.Context
+	net := ss.Net
+	nsyns := 0
+	nskip := 0
+	for _, ly := range net.Layers {
+		if !strings.HasSuffix(ly.Nm, "16") {
+			continue
+		}
+		tly := net.AxonLayerByName(ScaleTwinName(ly.Nm))
+		if tly == nil {
+			continue
+		}
+		used := make(map[*axon.Prjn]bool) // duplicate prjns are paired in order
+		for _, pj := range ly.RcvPrjns {
+			for _, tpj := range tly.RcvPrjns {
+				if used[tpj] || tpj.Send.Nm != ScaleTwinName(pj.Send.Nm) || tpj.Typ != pj.Typ {
+					continue
+				}
+				used[tpj] = true
+				if !SameConnectivity(ctx, pj, tpj) {
+					nskip++
+					break
+				}
+				ss.SharedPrjns = append(ss.SharedPrjns, [2]*axon.Prjn{pj, tpj})
+				nsyns += int(pj.NSyns)
+				break
+			}
+		}
+	}
+	for _, sp := range ss.SharedPrjns {
+		pj, tpj := sp[0], sp[1]
+		for syi := uint32(0); syi < pj.NSyns; syi++ {
+			syni, tsyni := pj.SynStIdx+syi, tpj.SynStIdx+syi
+			for _, sv := range []axon.SynapseVars{axon.Wt, axon.LWt, axon.SWt} {
+				axon.SetSynV(ctx, tsyni, sv, axon.SynV(ctx, syni, sv))
+			}
+		}
+	}
+	net.GPU.SyncSynapsesToGPU()
+	mpi.Printf("ShareWts: tied %d prjn pairs, %d shared synapses, %d pairs skipped with different connectivity\n", len(ss.SharedPrjns), nsyns, nskip)
+}
+
+// ShareDWts averages the DWts of each pair of tied projections, prior
+// to WtFmDWt, if both are learning (e.g., not dropped out).
+// The synapses must be synced from the GPU: see MPIWtFmDWt.
+func (ss *Sim) ShareDWts() {
+	if len(ss.SharedPrjns) == 0 {
+		return
+	}
+	ctx := &ss.Context
+	for _, sp := range ss.SharedPrjns {
+		pj, tpj := sp[0], sp[1]
+		if pj.IsOff() || tpj.IsOff() || pj.Params.Learn.Learn.IsFalse() || tpj.Params.Learn.Learn.IsFalse() {
+			continue
+		}
+		for syi := uint32(0); syi < pj.NSyns; syi++ {
+			syni, tsyni := pj.SynStIdx+syi, tpj.SynStIdx+syi
+			dwt := 0.5 * (axon.SynV(ctx, syni, axon.DWt) + axon.SynV(ctx, tsyni, axon.DWt))
+			axon.SetSynV(ctx, syni, axon.DWt, dwt)
+			axon.SetSynV(ctx, tsyni, axon.DWt, dwt)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestScaleTwinName(t *testing.T) {
+	tests := []struct {
+		nm, twin string
+	}{
+		{"V2m16", "V2m8"},
+		{"V4f8", "V4f16"},
+		{"TEOf16", "TEOf8"},
+		{"V1h16", "V1h8"},
+		{"TE", "TE"},
+		{"Output", "Output"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ScaleTwinName(tt.nm); got != tt.twin {
+			t.Errorf("ScaleTwinName(%s) = %s, want: %s", tt.nm, got, tt.twin)
+		}
+		if got := ScaleTwinName(ScaleTwinName(tt.nm)); got != tt.nm {
+			t.Errorf("ScaleTwinName twice (%s) = %s", tt.nm, got)
+		}
+	}
+}
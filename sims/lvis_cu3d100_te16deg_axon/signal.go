@@ -0,0 +1,205 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/goki/gi/gi"
+)
+
+// signal.go has the graceful handling of SIGTERM and SIGUSR1 in nogui
+// mode, which cluster schedulers send before killing a job: the signal is
+// checked every Config.Run.SignalInterval training or testing trials, and
+// then the weights are saved along with a resume checkpoint, the logs are
+// closed, and the sim exits cleanly.
+// Under MPI, all procs agree to stop at the same trial.  The checkpoint
+// can be passed to Config.Run.Resume to continue the run from the start
+// of the epoch in which it was interrupted.
+
+// Checkpoint records the state needed to resume an interrupted run
+type Checkpoint struct {
+
+	// name of the run, for naming the weights and logs
+	RunName string
+
+	// run counter when interrupted
+	Run int
+
+	// max of the run counter, i.e., the last run + 1
+	RunMax int
+
+	// training epoch when interrupted -- the run is resumed at the
+	// start of this epoch
+	Epoch int
+
+	// training trial when interrupted, for reference
+	Trial int
+
+	// weights file saved at the interruption
+	Wts string
+
+	// base name of the stats state files saved at the interruption,
+	// per MPI rank -- see SaveCkptStats
+	Stats string
+
+	// the signal that caused the interruption
+	Signal string
+
+	// wall clock time of the interruption, in RFC3339 format
+	Time string
+}
+
+// SignalState has the state for the signal handling and resume
+type SignalState struct {
+
+	// channel receiving the signals, nil if not handling signals
+	Chan chan os.Signal
+
+	// set to 1 when a signal has been received, accessed atomically
+	Flag int32
+
+	// name of the signal received
+	Name atomic.Value
+
+	// number of trials since the last check, per Config.Run.SignalInterval
+	NTrials int
+
+	// checkpoint to resume from at the start of the first run, if non-nil
+	Resume *Checkpoint
+}
+
+// HandleSignals starts handling SIGTERM and SIGUSR1, setting the flag
+// checked by SignalCheck at the end of the training and testing trials
+func (ss *Sim) HandleSignals() {
+	sg := &ss.Signal
+	sg.Chan = make(chan os.Signal, 1)
+	signal.Notify(sg.Chan, syscall.SIGTERM, syscall.SIGUSR1)
+	go func() {
+		for sig := range sg.Chan {
+			mpi.AllPrintf("Received %v on rank %d: stopping at the next signal check\n", sig, ss.MPIRank())
+			sg.Name.Store(sig.String())
+			atomic.StoreInt32(&sg.Flag, 1)
+		}
+	}()
+}
+
+// SignalCheck checks whether a signal has been received on any proc,
+// every Config.Run.SignalInterval trials, and if so, saves the weights and
+// a resume checkpoint, closes the logs, and exits -- called at the end of
+// each training and testing trial.  All procs run the same number of
+// trials, so they check on the same trial.
+func (ss *Sim) SignalCheck() {
+	sg := &ss.Signal
+	if sg.Chan == nil {
+		return
+	}
+	sg.NTrials++
+	if sg.NTrials < ss.Config.Run.SignalInterval {
+		return
+	}
+	sg.NTrials = 0
+	flag := []int{int(atomic.LoadInt32(&sg.Flag))}
+	if ss.Config.Run.MPI {
+		orig := []int{flag[0]}
+		ss.Comm.AllReduceInt(mpi.OpMax, flag, orig)
+	}
+	if flag[0] == 0 {
+		return
+	}
+	signal.Stop(sg.Chan)
+	ss.AddEvent("Interrupted")
+	if err := ss.SaveCheckpoint(); err != nil {
+		mpi.Println(err)
+	}
+	ss.ShutdownNoGUI()
+	os.Exit(0)
+}
+
+// SaveCheckpoint saves the weights and the resume checkpoint for the
+// current trial, on MPI rank 0, regardless of Config.Log.SaveWts,
+// along with the stats state of each proc
+func (ss *Sim) SaveCheckpoint() error {
+	runName := ss.Stats.String("RunName")
+	stats := ss.Net.Name() + "_" + runName + "_ckpt_stats"
+	if err := ss.SaveCkptStats(stats); err != nil {
+		mpi.AllPrintf("%s\n", err)
+		stats = ""
+	}
+	if ss.MPIRank() != 0 {
+		return nil
+	}
+	ck := &Checkpoint{RunName: runName, Stats: stats, Time: time.Now().Format(time.RFC3339)}
+	runLp := ss.Loops.GetLoop(etime.Train, etime.Run)
+	ck.Run, ck.RunMax = runLp.Counter.Cur, runLp.Counter.Max
+	ck.Epoch = ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur
+	ck.Trial = ss.Loops.GetLoop(etime.Train, etime.Trial).Counter.Cur
+	if nm, ok := ss.Signal.Name.Load().(string); ok {
+		ck.Signal = nm
+	}
+	ck.Wts = ss.WriteWeights("_ckpt")
+	fnm := ss.Net.Name() + "_" + runName + "_ckpt.json"
+	b, err := json.MarshalIndent(ck, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(fnm, b, 0644); err != nil {
+		return fmt.Errorf("SaveCheckpoint: %w", err)
+	}
+	mpi.Printf("Saved resume checkpoint to: %s\n", fnm)
+	return nil
+}
+
+// OpenCheckpoint opens the resume checkpoint from given file,
+// to resume the run per Config.Run.Resume
+func (ss *Sim) OpenCheckpoint(fnm string) error {
+	b, err := os.ReadFile(fnm)
+	if err != nil {
+		return fmt.Errorf("OpenCheckpoint: %w", err)
+	}
+	ck := &Checkpoint{}
+	if err := json.Unmarshal(b, ck); err != nil {
+		return fmt.Errorf("OpenCheckpoint: %s: %w", fnm, err)
+	}
+	if ck.Wts == "" {
+		return fmt.Errorf("OpenCheckpoint: %s: no weights file saved", fnm)
+	}
+	ss.Signal.Resume = ck
+	return nil
+}
+
+// ResumeRun opens the checkpoint weights and sets the epoch counter to
+// resume the run, if a checkpoint is pending -- called at the start of
+// the run, after NewRun, along with the stats state (see OpenCkptStats).
+// The random number state is not restored, so the trial order differs
+// from the uninterrupted run.
+func (ss *Sim) ResumeRun() {
+	ck := ss.Signal.Resume
+	if ck == nil {
+		return
+	}
+	ss.Signal.Resume = nil
+	if err := ss.OpenWeights(gi.FileName(ck.Wts)); err != nil {
+		mpi.Println(err)
+		return
+	}
+	if ck.Stats != "" {
+		if err := ss.OpenCkptStats(ck.Stats); err != nil {
+			mpi.Println(err)
+		}
+	}
+	ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur = ck.Epoch
+	ss.StatCounters(0)
+	ss.AddEvent("Resumed")
+	mpi.Printf("Resuming run: %d at epoch: %d from weights: %s\n", ck.Run, ck.Epoch, ck.Wts)
+}
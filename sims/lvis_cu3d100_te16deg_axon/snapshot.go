@@ -0,0 +1,126 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+	"strings"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+)
+
+// snapshot.go has the NetView-style snapshot images of layer activity,
+// rendered offscreen from the NetData recording of testing trials in
+// nogui mode, so activity figures can be produced from cluster runs
+// without re-running in the GUI.  At each snapshot epoch, the first
+// Config.Log.Snapshot.NTrials recorded testing trials are saved as PNG
+// files, with the layers side by side as in the Movie frames.  The NetData
+// is recorded in memory for the snapshots even if Config.Log.NetData is
+// off, in which case it is not saved.  See Config.Log.Snapshot.
+
+// Snapshot has the state for the snapshot images
+type Snapshot struct {
+
+	// layout and palette of the layers shown
+	Montage
+
+	// training epoch of the current snapshots
+	Epoch int
+
+	// number of snapshots saved in the current epoch
+	N int
+
+	// unit values for current layer
+	Vals []float32
+}
+
+// SnapshotOn returns true if snapshots are configured,
+// in nogui mode on MPI rank 0
+func (ss *Sim) SnapshotOn() bool {
+	sc := &ss.Config.Log.Snapshot
+	return !ss.Config.GUI && ss.MPIRank() == 0 && (len(sc.Epochs) > 0 || sc.Interval > 0)
+}
+
+// ConfigSnapshot configures the Snapshot layers, layout and palette
+func (ss *Sim) ConfigSnapshot() {
+	sc := &ss.Config.Log.Snapshot
+	sn := &ss.Snapshot
+	sn.Lays = nil
+	sn.Epoch = -1
+	if !ss.SnapshotOn() {
+		return
+	}
+	lnms := sc.Layers
+	if len(lnms) == 0 {
+		lnms = MovieDefLayers
+	}
+	sn.Config(ss.Net, lnms, sc.ColorMap)
+}
+
+// SnapshotEpoch returns true if given training epoch is a snapshot epoch
+func (ss *Sim) SnapshotEpoch(epc int) bool {
+	sc := &ss.Config.Log.Snapshot
+	if sc.Interval > 0 && epc%sc.Interval == 0 {
+		return true
+	}
+	for _, e := range sc.Epochs {
+		if e == epc {
+			return true
+		}
+	}
+	return false
+}
+
+// SnapshotRecord saves a snapshot of the current NetData record, if this
+// is a snapshot epoch and fewer than NTrials have been saved -- called
+// in NetDataRecord after the trial is recorded
+func (ss *Sim) SnapshotRecord() {
+	sc := &ss.Config.Log.Snapshot
+	sn := &ss.Snapshot
+	nd := ss.GUI.NetData
+	if len(sn.Lays) == 0 || nd == nil {
+		return
+	}
+	epc := ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur
+	if epc != sn.Epoch {
+		sn.Epoch = epc
+		sn.N = 0
+	}
+	if sn.N >= sc.NTrials || !ss.SnapshotEpoch(epc) {
+		return
+	}
+	img := sn.Image(sc.Scale, sc.Max, func(ly *axon.Layer) []float32 {
+		nu := ly.Shape().Len()
+		if cap(sn.Vals) < nu {
+			sn.Vals = make([]float32, nu)
+		}
+		sn.Vals = sn.Vals[:nu]
+		for ui := range sn.Vals {
+			sn.Vals[ui], _ = nd.UnitVal(ly.Name(), sc.Var, ui, -1, 0)
+		}
+		return sn.Vals
+	})
+	trl := ss.Stats.Int("Trial")
+	lnm := fmt.Sprintf("snap_%05d_trl%d", epc, trl)
+	fnm := strings.TrimSuffix(elog.LogFileName(lnm, ss.Net.Name(), ss.Stats.String("RunName")), ".tsv") + ".png"
+	f, err := os.Create(fnm)
+	if err != nil {
+		mpi.Println("Snapshot:", err)
+		return
+	}
+	err = png.Encode(f, img)
+	f.Close()
+	if err != nil {
+		mpi.Println("Snapshot:", err)
+		return
+	}
+	sn.N++
+	mpi.Printf("Saved snapshot of %s: %s to: %s\n", sc.Var, ss.Stats.String("TrialName"), fnm)
+}
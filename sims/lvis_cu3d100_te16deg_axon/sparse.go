@@ -0,0 +1,192 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+)
+
+// sparse.go has the population and lifetime sparseness stats, computed
+// from the test epoch activity, using the Treves-Rolls measure, normalized
+// as in Vinje & Gallant (2000), so 0 = dense (all values equal) and 1 =
+// maximally sparse (only one nonzero value):
+//
+//	S = (1 - (sum(r)/n)^2 / (sum(r^2)/n)) / (1 - 1/n)
+//
+// Population sparseness is over the units in the layer for each test
+// trial, averaged over trials, and lifetime sparseness is over the test
+// trials for each unit, averaged over units -- these capture the
+// distributional sparsity of the representations for comparison with
+// V4 / IT recordings, unlike the mean activity.  See Config.Run.Sparseness.
+
+// TrevesRolls returns the normalized Treves-Rolls sparseness for
+// given sum and sum of squares of n values -- 0 if all values are 0
+func TrevesRolls(sum, sumSq, n float64) float64 {
+	if sumSq <= 0 || n <= 1 {
+		return 0
+	}
+	mn := sum / n
+	return (1 - mn*mn/(sumSq/n)) / (1 - 1/n)
+}
+
+// SparseAcc accumulates the activity for the sparseness stats for a layer
+type SparseAcc struct {
+
+	// sum over trials of the population sparseness
+	PopSum float64
+
+	// number of trials
+	N float64
+
+	// sum over trials of each unit's activity
+	Sum []float64
+
+	// sum over trials of each unit's squared activity
+	SumSq []float64
+}
+
+// Init initializes the accumulator for given number of units
+func (sa *SparseAcc) Init(nu int) {
+	sa.PopSum = 0
+	sa.N = 0
+	if len(sa.Sum) != nu {
+		sa.Sum = make([]float64, nu)
+		sa.SumSq = make([]float64, nu)
+		return
+	}
+	for i := range sa.Sum {
+		sa.Sum[i] = 0
+		sa.SumSq[i] = 0
+	}
+}
+
+// Add adds the activity values for one trial
+func (sa *SparseAcc) Add(vals []float32) {
+	sum, sq := 0.0, 0.0
+	for i, v := range vals {
+		fv := float64(v)
+		v2 := fv * fv
+		sum += fv
+		sq += v2
+		sa.Sum[i] += fv
+		sa.SumSq[i] += v2
+	}
+	sa.PopSum += TrevesRolls(sum, sq, float64(len(vals)))
+	sa.N++
+}
+
+// MPIReduce sums the accumulated values across procs in given comm
+func (sa *SparseAcc) MPIReduce(comm *mpi.Comm) {
+	pn := []float64{sa.PopSum, sa.N}
+	for _, vals := range [][]float64{pn, sa.Sum, sa.SumSq} {
+		orig := make([]float64, len(vals))
+		copy(orig, vals)
+		comm.AllReduceF64(mpi.OpSum, vals, orig)
+	}
+	sa.PopSum, sa.N = pn[0], pn[1]
+}
+
+// Sparseness returns the mean population sparseness over trials,
+// and the mean lifetime sparseness over units
+func (sa *SparseAcc) Sparseness() (pop, life float64) {
+	if sa.N == 0 {
+		return
+	}
+	pop = sa.PopSum / sa.N
+	for i, s := range sa.Sum {
+		life += TrevesRolls(s, sa.SumSq[i], sa.N)
+	}
+	life /= float64(len(sa.Sum))
+	return
+}
+
+// SparseLays returns the layers for the sparseness stats: the
+// Config.Run.SparseLays, or all the hidden (Super) layers if empty
+func (ss *Sim) SparseLays() []string {
+	if len(ss.Config.Run.SparseLays) > 0 {
+		return ss.Config.Run.SparseLays
+	}
+	return ss.Net.LayersByType(axon.SuperLayer)
+}
+
+// InitSparse resets the sparseness accumulators,
+// at the start of each test epoch
+func (ss *Sim) InitSparse() {
+	if !ss.Config.Run.Sparseness {
+		return
+	}
+	if ss.Sparse == nil {
+		ss.Sparse = make(map[string]*SparseAcc)
+	}
+	for _, lnm := range ss.SparseLays() {
+		sa, ok := ss.Sparse[lnm]
+		if !ok {
+			sa = &SparseAcc{}
+			ss.Sparse[lnm] = sa
+		}
+		sa.Init(int(ss.Net.AxonLayerByName(lnm).NNeurons))
+	}
+}
+
+// SparseRecord adds the current ActM activity of each SparseLays layer
+// to its accumulator, for all data indexes.
+// Called at the end of each test trial.
+func (ss *Sim) SparseRecord() {
+	if !ss.Config.Run.Sparseness || ss.Sparse == nil {
+		return
+	}
+	var vals []float32
+	for di := 0; di < int(ss.Context.NetIdxs.NData); di++ {
+		for _, lnm := range ss.SparseLays() {
+			ss.Net.AxonLayerByName(lnm).UnitVals(&vals, "ActM", di)
+			ss.Sparse[lnm].Add(vals)
+		}
+	}
+}
+
+// SparseStats computes the sparseness stats from the accumulated test
+// epoch activity, gathered across MPI procs.
+// Called at the end of each test epoch, before logging.
+func (ss *Sim) SparseStats() {
+	if !ss.Config.Run.Sparseness || ss.Sparse == nil {
+		return
+	}
+	for _, lnm := range ss.SparseLays() {
+		sa := ss.Sparse[lnm]
+		if ss.Config.Run.MPI {
+			sa.MPIReduce(ss.Comm)
+		}
+		pop, life := sa.Sparseness()
+		ss.Stats.SetFloat(lnm+"_PopSparse", pop)
+		ss.Stats.SetFloat(lnm+"_LifeSparse", life)
+	}
+}
+
+// ConfigSparseLogs adds log items for the sparseness stats at the test
+// epoch level, copied to the train epoch and run logs with a Tst prefix.
+// Layers not found in the network are removed from SparseLays.
+func (ss *Sim) ConfigSparseLogs() {
+	if !ss.Config.Run.Sparseness {
+		return
+	}
+	var lays, nms []string
+	for _, lnm := range ss.SparseLays() {
+		if _, err := ss.Net.LayByNameTry(lnm); err != nil {
+			mpi.Println("Sparseness:", err)
+			continue
+		}
+		lays = append(lays, lnm)
+		for _, st := range []string{"_PopSparse", "_LifeSparse"} {
+			nm := lnm + st
+			ss.Stats.SetFloat(nm, 0)
+			ss.Logs.AddStatFloatNoAggItem(etime.Test, etime.Epoch, nm)
+			nms = append(nms, nm)
+		}
+	}
+	ss.Config.Run.SparseLays = lays
+	ss.Logs.AddCopyFromFloatItems(etime.Train, []etime.Times{etime.Epoch, etime.Run}, etime.Test, etime.Epoch, "Tst", nms...)
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/emergent/erand"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/metric"
+)
+
+// splithalf.go has the split-half reliability of the category-mean
+// activation patterns of each layer, which is the noise ceiling against
+// which RSA correlations of the layer with external (e.g., neural or
+// behavioral) data can be interpreted.  The test exemplars of each
+// category are assigned to two random halves, balanced per category on
+// each MPI proc, and the category-mean patterns of each half give a
+// category x category dissimilarity matrix (1 - correlation).  The
+// reliability is the correlation between the two halves' matrices,
+// averaged over Config.Run.SplitHalf independent random partitions:
+//
+//	_SplitHalf  r         correlation of the half-data matrices
+//	_NoiseCeil  2r/(1+r)  Spearman-Brown corrected for the full data
+//
+// The partitions are drawn with a fixed random seed at each test epoch,
+// so they are comparable across epochs.
+
+// SplitHalfSeed is the default random seed for the split-half
+// partitions, offset by the MPI rank -- see RunSeed
+const SplitHalfSeed = 4271
+
+// SplitHalf has the state for the split-half reliability stats
+type SplitHalf struct {
+
+	// category activity accumulators of the two halves of each partition, per layer
+	Halves map[string][][2]*CatProtos
+
+	// half of the last unpaired exemplar per partition and category, -1 if none, to balance the halves
+	Pend [][]int
+
+	// random generator for the partitions
+	Rnd *erand.SysRand
+}
+
+// SplitHalfLays returns the layers for the split-half stats: the
+// Config.Run.SplitHalfLays, or the ProtoLays if empty
+func (ss *Sim) SplitHalfLays() []string {
+	if len(ss.Config.Run.SplitHalfLays) > 0 {
+		return ss.Config.Run.SplitHalfLays
+	}
+	return ss.ProtoLays()
+}
+
+// InitSplitHalf resets the split-half accumulators and reseeds the
+// partitions, at the start of each test epoch
+func (ss *Sim) InitSplitHalf() {
+	nsp := ss.Config.Run.SplitHalf
+	if nsp <= 0 {
+		return
+	}
+	sh := &ss.SplitHalf
+	ncats := len(ss.LvisEnv(etime.Test).CatNames())
+	sh.Rnd = erand.NewSysRand(ss.Seeds["SplitHalf"] + int64(ss.MPIRank()))
+	sh.Pend = make([][]int, nsp)
+	for si := range sh.Pend {
+		pd := make([]int, ncats)
+		for ci := range pd {
+			pd[ci] = -1
+		}
+		sh.Pend[si] = pd
+	}
+	sh.Halves = make(map[string][][2]*CatProtos)
+	for _, lnm := range ss.SplitHalfLays() {
+		nu := int(ss.Net.AxonLayerByName(lnm).NNeurons)
+		hs := make([][2]*CatProtos, nsp)
+		for si := range hs {
+			for h := range hs[si] {
+				cp := &CatProtos{}
+				cp.Init(ncats, nu)
+				hs[si][h] = cp
+			}
+		}
+		sh.Halves[lnm] = hs
+	}
+}
+
+// SplitHalfRecord adds the current ActM activity of each SplitHalfLays
+// layer to one half of each partition, for all data indexes: the first of
+// each pair of exemplars of a category goes to a random half, and the
+// second to the other half.
+// Called at the end of each test trial, after trial stats are computed.
+func (ss *Sim) SplitHalfRecord() {
+	sh := &ss.SplitHalf
+	if ss.Config.Run.SplitHalf <= 0 || sh.Halves == nil {
+		return
+	}
+	var vals []float32
+	halves := make([]int, len(sh.Pend))
+	for di := 0; di < int(ss.Context.NetIdxs.NData); di++ {
+		cat := ss.Stats.IntDi("TrlCatIdx", di)
+		if cat < 0 || cat >= len(sh.Pend[0]) {
+			continue
+		}
+		for si, pd := range sh.Pend {
+			if pd[cat] < 0 {
+				halves[si] = sh.Rnd.Intn(2, -1)
+				pd[cat] = halves[si]
+			} else {
+				halves[si] = 1 - pd[cat]
+				pd[cat] = -1
+			}
+		}
+		for lnm, hs := range sh.Halves {
+			ss.Net.AxonLayerByName(lnm).UnitVals(&vals, "ActM", di)
+			for si, h := range halves {
+				hs[si][h].Add(cat, vals)
+			}
+		}
+	}
+}
+
+// CatRDM returns the upper triangle of the category x category
+// dissimilarity matrix (1 - correlation) of the category-mean patterns
+// of given accumulator, for given categories
+func (cp *CatProtos) CatRDM(cats []int) []float64 {
+	nu := cp.NUnits
+	means := make([][]float64, len(cats))
+	for i, ci := range cats {
+		mn := make([]float64, nu)
+		n := cp.N[ci]
+		for ui, v := range cp.Sum[ci*nu : (ci+1)*nu] {
+			mn[ui] = v / n
+		}
+		means[i] = mn
+	}
+	var rdm []float64
+	for ai := range means {
+		for bi := ai + 1; bi < len(means); bi++ {
+			rdm = append(rdm, 1-metric.Correlation64(means[ai], means[bi]))
+		}
+	}
+	return rdm
+}
+
+// SplitHalfRel returns the split-half reliability of the category
+// dissimilarity matrices of given partitions, as the mean correlation
+// between the two halves over the partitions, and its Spearman-Brown
+// correction for the full data.  Only categories with exemplars in both
+// halves are used, and partitions with fewer than 3 such categories
+// are skipped.
+func SplitHalfRel(hs [][2]*CatProtos) (r, ceil float64) {
+	np := 0
+	for _, h := range hs {
+		var cats []int
+		for ci := range h[0].N {
+			if h[0].N[ci] > 0 && h[1].N[ci] > 0 {
+				cats = append(cats, ci)
+			}
+		}
+		if len(cats) < 3 {
+			continue
+		}
+		r += metric.Correlation64(h[0].CatRDM(cats), h[1].CatRDM(cats))
+		np++
+	}
+	if np == 0 {
+		return 0, 0
+	}
+	r /= float64(np)
+	if r > -1 {
+		ceil = 2 * r / (1 + r)
+	}
+	return
+}
+
+// SplitHalfStats computes the split-half reliability stats for each
+// layer from the accumulated test epoch activity, summed across MPI procs.
+// Called at the end of each test epoch, before logging.
+func (ss *Sim) SplitHalfStats() {
+	sh := &ss.SplitHalf
+	if ss.Config.Run.SplitHalf <= 0 || sh.Halves == nil {
+		return
+	}
+	for _, lnm := range ss.SplitHalfLays() {
+		hs := sh.Halves[lnm]
+		if ss.Config.Run.MPI {
+			for _, h := range hs {
+				h[0].MPIReduce(ss.Comm)
+				h[1].MPIReduce(ss.Comm)
+			}
+		}
+		r, ceil := SplitHalfRel(hs)
+		ss.Stats.SetFloat(lnm+"_SplitHalf", r)
+		ss.Stats.SetFloat(lnm+"_NoiseCeil", ceil)
+	}
+}
+
+// ConfigSplitHalfLogs adds log items for the split-half reliability stats
+// at the test epoch level, copied to the train epoch and run logs with a
+// Tst prefix.  Layers not found in the network are removed from
+// SplitHalfLays.
+func (ss *Sim) ConfigSplitHalfLogs() {
+	if ss.Config.Run.SplitHalf <= 0 {
+		return
+	}
+	var lays, nms []string
+	for _, lnm := range ss.SplitHalfLays() {
+		if _, err := ss.Net.LayByNameTry(lnm); err != nil {
+			mpi.Println("SplitHalf:", err)
+			continue
+		}
+		lays = append(lays, lnm)
+		for _, st := range []string{"_SplitHalf", "_NoiseCeil"} {
+			nm := lnm + st
+			ss.Stats.SetFloat(nm, 0)
+			ss.Logs.AddStatFloatNoAggItem(etime.Test, etime.Epoch, nm)
+			nms = append(nms, nm)
+		}
+	}
+	ss.Config.Run.SplitHalfLays = lays
+	ss.Logs.AddCopyFromFloatItems(etime.Train, []etime.Times{etime.Epoch, etime.Run}, etime.Test, etime.Epoch, "Tst", nms...)
+}
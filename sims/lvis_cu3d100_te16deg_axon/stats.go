@@ -0,0 +1,678 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/agg"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/minmax"
+	"github.com/goki/gi/gi"
+)
+
+// stats.go has additional statistics beyond the standard trial-level
+// output error stats computed in TrialStats.
+
+//////////////////////////////////////////////////////////////////////////////
+//   FirstCyc
+
+// FirstCycLays returns the names of the layers for which the first
+// activity cycle stat is computed: all Super layers and the Output layer.
+func (ss *Sim) FirstCycLays() []string {
+	return ss.Net.LayersByType(axon.SuperLayer, axon.TargetLayer)
+}
+
+// InitFirstCycStats resets the first cycle stats to -1 = not yet reached,
+// at the start of each trial.
+func (ss *Sim) InitFirstCycStats() {
+	if ss.Config.Run.FirstCycInterval <= 0 {
+		return
+	}
+	lays := ss.FirstCycLays()
+	for di := 0; di < int(ss.Context.NetIdxs.NData); di++ {
+		for _, lnm := range lays {
+			ss.Stats.SetIntDi(lnm+"_FirstActCyc", di, -1)
+		}
+		ss.Stats.SetIntDi("FirstCorCyc", di, -1)
+	}
+}
+
+// FirstCycStats records the first cycle within the trial at which each
+// FirstCycLays layer has a maximum CaSpkP above Config.Run.FirstActThr,
+// and the first cycle at which the Output layer CaSpkP pattern is closest
+// to the correct category, within the minus phase (the Output is clamped
+// in the plus phase during training).  Called at the end of every cycle, and checks
+// every Config.Run.FirstCycInterval cycles.
+func (ss *Sim) FirstCycStats() {
+	intv := ss.Config.Run.FirstCycInterval
+	ctx := &ss.Context
+	cyc := int(ctx.Cycle)
+	if intv <= 0 || cyc%intv != 0 {
+		return
+	}
+	if ss.Config.Run.GPU {
+		ss.Net.GPU.SyncNeuronsFmGPU()
+	}
+	ev := ss.LvisEnv(ctx.Mode)
+	lays := ss.FirstCycLays()
+	out := ss.Net.AxonLayerByName("Output")
+	for di := 0; di < int(ctx.NetIdxs.NData); di++ {
+		for _, lnm := range lays {
+			nm := lnm + "_FirstActCyc"
+			if ss.Stats.IntDi(nm, di) >= 0 {
+				continue
+			}
+			ly := ss.Net.AxonLayerByName(lnm)
+			if ly.Pool(0, uint32(di)).AvgMax.CaSpkP.Cycle.Max > ss.Config.Run.FirstActThr {
+				ss.Stats.SetIntDi(nm, di, cyc)
+			}
+		}
+		if ctx.PlusPhase.IsTrue() || ss.Stats.IntDi("FirstCorCyc", di) >= 0 {
+			continue
+		}
+		tsr := ss.Stats.F32TensorDi("OutputCyc", di)
+		out.UnitValsTensor(tsr, "CaSpkP", di)
+		_, err, _, _ := ev.OutErrMargin(tsr, ss.Stats.IntDi("TrlCatIdx", di))
+		if err == 0 {
+			ss.Stats.SetIntDi("FirstCorCyc", di, cyc)
+		}
+	}
+}
+
+// FirstCycVal returns the given first cycle stat for given data index,
+// with the not-reached value of -1 replaced by the number of cycles
+// in the trial, so that averages remain meaningful.
+func (ss *Sim) FirstCycVal(nm string, di int) float64 {
+	cyc := ss.Stats.IntDi(nm, di)
+	if cyc < 0 {
+		cyc = int(ss.Context.Cycle)
+	}
+	return float64(cyc)
+}
+
+// ConfigFirstCycLogs adds log items for the first activity and
+// first correct cycle stats, at trial, epoch and run levels.
+func (ss *Sim) ConfigFirstCycLogs() {
+	if ss.Config.Run.FirstCycInterval <= 0 {
+		return
+	}
+	nms := []string{"FirstCorCyc"}
+	for _, lnm := range ss.FirstCycLays() {
+		nms = append(nms, lnm+"_FirstActCyc")
+	}
+	for _, nm := range nms {
+		stnm := nm
+		ss.Logs.AddItem(&elog.Item{
+			Name:   stnm,
+			Type:   etensor.FLOAT64,
+			Plot:   elog.DFalse,
+			FixMin: true,
+			Range:  minmax.F64{Max: 200},
+			Write: elog.WriteMap{
+				etime.Scope(etime.AllModes, etime.Trial): func(ctx *elog.Context) {
+					ctx.SetFloat64(ss.FirstCycVal(stnm, ctx.Di))
+				}, etime.Scope(etime.AllModes, etime.Epoch): func(ctx *elog.Context) {
+					ctx.SetAgg(ctx.Mode, etime.Trial, agg.AggMean)
+				}, etime.Scope(etime.Train, etime.Run): func(ctx *elog.Context) {
+					ix := ctx.LastNRows(ctx.Mode, etime.Epoch, 5)
+					ctx.SetFloat64(agg.Mean(ix, ctx.Item.Name)[0])
+				}}})
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////
+//   Size
+
+// LayersSizeReport returns a report of the number of neurons and
+// receiving synapses in the given layers, and their approximate
+// memory usage, as a proportion of the whole network.
+// Used to estimate the cost of optional pathways such as High16.
+func (ss *Sim) LayersSizeReport(lays []string) string {
+	net := ss.Net
+	maxData := int(net.MaxData)
+	memNeuron := 4 * (int(axon.NeuronVarsN)*maxData + int(axon.NeuronAvgVarsN) + int(axon.NeuronIdxsN))
+	memSynapse := 4 * (int(axon.SynapseVarsN) + int(axon.SynapseCaVarsN)*maxData + int(axon.SynapseIdxsN))
+	nn := 0
+	ns := 0
+	for _, lnm := range lays {
+		ly := net.AxonLayerByName(lnm)
+		nn += int(ly.NNeurons)
+		for _, pj := range ly.RcvPrjns {
+			ns += int(pj.NSyns)
+		}
+	}
+	mem := nn*memNeuron + ns*memSynapse
+	totMem := int(net.NNeurons)*memNeuron + int(net.NSyns)*memSynapse
+	mb := float64(1 << 20)
+	return fmt.Sprintf("%v:\t Neurons: %d (%.2g%%)\t Syns: %d (%.2g%%)\t Mem: %.1f MB (%.2g%% of %.1f MB)\n", lays,
+		nn, 100*float64(nn)/float64(net.NNeurons), ns, 100*float64(ns)/float64(net.NSyns),
+		float64(mem)/mb, 100*float64(mem)/float64(totMem), float64(totMem)/mb)
+}
+
+//////////////////////////////////////////////////////////////////////////////
+//   Protos
+
+// CatProtos accumulates the activity of one layer per category
+// over a test epoch, for computing the category prototypes
+// (mean activity vectors) and distances of exemplars from them,
+// and the per-unit category selectivity.
+type CatProtos struct {
+
+	// number of units in the layer
+	NUnits int
+
+	// sum of activity per category: [ncats][NUnits]
+	Sum []float64
+
+	// sum of squared norm of the exemplar activity vectors per category
+	SumSq []float64
+
+	// sum of squared activity per category: [ncats][NUnits]
+	SumSqU []float64
+
+	// number of exemplars per category
+	N []float64
+}
+
+// Init allocates and zeros the sums for given number of categories and units
+func (cp *CatProtos) Init(ncats, nunits int) {
+	cp.NUnits = nunits
+	cp.Sum = make([]float64, ncats*nunits)
+	cp.SumSq = make([]float64, ncats)
+	cp.SumSqU = make([]float64, ncats*nunits)
+	cp.N = make([]float64, ncats)
+}
+
+// Add adds the given exemplar activity vector for given category
+func (cp *CatProtos) Add(cat int, vals []float32) {
+	if cat < 0 || cat >= len(cp.N) || len(vals) != cp.NUnits {
+		return
+	}
+	sum := cp.Sum[cat*cp.NUnits : (cat+1)*cp.NUnits]
+	sumsq := cp.SumSqU[cat*cp.NUnits : (cat+1)*cp.NUnits]
+	sq := 0.0
+	for i, v := range vals {
+		v2 := float64(v) * float64(v)
+		sum[i] += float64(v)
+		sumsq[i] += v2
+		sq += v2
+	}
+	cp.SumSq[cat] += sq
+	cp.N[cat]++
+}
+
+// MPIReduce sums the accumulated values across procs in given comm
+func (cp *CatProtos) MPIReduce(comm *mpi.Comm) {
+	for _, vals := range [][]float64{cp.Sum, cp.SumSq, cp.SumSqU, cp.N} {
+		orig := make([]float64, len(vals))
+		copy(orig, vals)
+		comm.AllReduceF64(mpi.OpSum, vals, orig)
+	}
+}
+
+// Dists returns the mean within-category distance of the exemplars
+// to their category prototype (as the root-mean-squared Euclidean distance
+// per category, averaged over categories), the mean Euclidean distance
+// between the prototypes of all pairs of categories, and their ratio
+// (within / between), which decreases as categorical clustering emerges.
+// Categories without any exemplars are skipped.
+func (cp *CatProtos) Dists() (within, between, ratio float64) {
+	nu := cp.NUnits
+	var cats []int
+	for ci, n := range cp.N {
+		if n > 0 {
+			cats = append(cats, ci)
+		}
+	}
+	if len(cats) == 0 {
+		return
+	}
+	protos := make([]float64, len(cp.Sum))
+	for _, ci := range cats {
+		n := cp.N[ci]
+		pss := 0.0
+		for i := ci * nu; i < (ci+1)*nu; i++ {
+			protos[i] = cp.Sum[i] / n
+			pss += protos[i] * protos[i]
+		}
+		within += math.Sqrt(math.Max(cp.SumSq[ci]/n-pss, 0))
+	}
+	within /= float64(len(cats))
+	npair := 0
+	for ai, ca := range cats {
+		pa := protos[ca*nu : (ca+1)*nu]
+		for _, cb := range cats[ai+1:] {
+			pb := protos[cb*nu : (cb+1)*nu]
+			ds := 0.0
+			for i := range pa {
+				d := pa[i] - pb[i]
+				ds += d * d
+			}
+			between += math.Sqrt(ds)
+			npair++
+		}
+	}
+	if npair == 0 {
+		return
+	}
+	between /= float64(npair)
+	if between > 0 {
+		ratio = within / between
+	}
+	return
+}
+
+// Selectivity computes the category selectivity of each unit into the
+// given tensors, which are set to the layer shape: sparse is the
+// (lifetime) sparseness of the mean response across categories,
+// which is 0 for equal responses to all categories and 1 for a response
+// to only one category, and dprime is the d-prime discriminability of
+// the responses to the unit's best category vs. all other categories.
+// Returns the mean of each over the units.
+func (cp *CatProtos) Selectivity(shape []int, sparse, dprime *etensor.Float32) (sparseMean, dprimeMean float64) {
+	nu := cp.NUnits
+	sparse.SetShape(shape, nil, nil)
+	dprime.SetShape(shape, nil, nil)
+	var cats []int
+	totN := 0.0
+	for ci, n := range cp.N {
+		if n > 0 {
+			cats = append(cats, ci)
+			totN += n
+		}
+	}
+	nc := float64(len(cats))
+	if nc < 2 {
+		return
+	}
+	for ui := 0; ui < nu; ui++ {
+		sm, sq := 0.0, 0.0
+		best, bestMu := -1, 0.0
+		totSum, totSq := 0.0, 0.0
+		for _, ci := range cats {
+			i := ci*nu + ui
+			mu := cp.Sum[i] / cp.N[ci]
+			sm += mu
+			sq += mu * mu
+			totSum += cp.Sum[i]
+			totSq += cp.SumSqU[i]
+			if best < 0 || mu > bestMu {
+				best, bestMu = ci, mu
+			}
+		}
+		sp := 0.0
+		if sq > 0 {
+			sp = (1 - (sm*sm/nc)/sq) / (1 - 1/nc)
+		}
+		bi := best*nu + ui
+		bn := cp.N[best]
+		bVar := math.Max(cp.SumSqU[bi]/bn-bestMu*bestMu, 0)
+		rn := totN - bn
+		rMu := (totSum - cp.Sum[bi]) / rn
+		rVar := math.Max((totSq-cp.SumSqU[bi])/rn-rMu*rMu, 0)
+		dp := 0.0
+		if sd := math.Sqrt(0.5 * (bVar + rVar)); sd > 0 {
+			dp = (bestMu - rMu) / sd
+		}
+		sparse.Values[ui] = float32(sp)
+		dprime.Values[ui] = float32(dp)
+		sparseMean += sp
+		dprimeMean += dp
+	}
+	sparseMean /= float64(nu)
+	dprimeMean /= float64(nu)
+	return
+}
+
+// BestCat returns the category with the highest mean activity of given
+// unit, among categories with exemplars, or -1 if none
+func (cp *CatProtos) BestCat(ui int) int {
+	best, bestMu := -1, 0.0
+	for ci, n := range cp.N {
+		if n == 0 {
+			continue
+		}
+		mu := cp.Sum[ci*cp.NUnits+ui] / n
+		if best < 0 || mu > bestMu {
+			best, bestMu = ci, mu
+		}
+	}
+	return best
+}
+
+// ProtoLays returns the names of the layers for which the category
+// prototype stats are computed: the TEO and TE layers.
+func (ss *Sim) ProtoLays() []string {
+	return []string{"TEOf16", "TEOf8", "TE"}
+}
+
+// ProtosOn returns true if the category activity accumulators are
+// needed, for the Protos or Selectivity stats, or the Cool or Clamp tests
+func (ss *Sim) ProtosOn() bool {
+	return ss.Config.Run.Protos || ss.Config.Run.Selectivity || ss.Config.Run.Cool || ss.Config.Run.Clamp
+}
+
+// InitProtos resets the category prototype accumulators,
+// at the start of each test epoch.
+func (ss *Sim) InitProtos() {
+	if !ss.ProtosOn() {
+		return
+	}
+	ncats := len(ss.LvisEnv(etime.Test).CatNames())
+	if ss.Protos == nil {
+		ss.Protos = make(map[string]*CatProtos)
+	}
+	for _, lnm := range ss.ProtoLays() {
+		ly := ss.Net.AxonLayerByName(lnm)
+		cp, ok := ss.Protos[lnm]
+		if !ok {
+			cp = &CatProtos{}
+			ss.Protos[lnm] = cp
+		}
+		cp.Init(ncats, int(ly.NNeurons))
+	}
+}
+
+// ProtoRecord adds the current ActM activity of each ProtoLays layer
+// to its category accumulator, for all data indexes.
+// Called at the end of each test trial, after trial stats are computed.
+func (ss *Sim) ProtoRecord() {
+	if !ss.ProtosOn() || ss.Protos == nil {
+		return
+	}
+	var vals []float32
+	for di := 0; di < int(ss.Context.NetIdxs.NData); di++ {
+		cat := ss.Stats.IntDi("TrlCatIdx", di)
+		for _, lnm := range ss.ProtoLays() {
+			ly := ss.Net.AxonLayerByName(lnm)
+			ly.UnitVals(&vals, "ActM", di)
+			ss.Protos[lnm].Add(cat, vals)
+		}
+	}
+}
+
+// ProtoStats computes the category prototype distance stats and
+// unit selectivity stats from the accumulated test epoch activity,
+// gathered across MPI procs.
+// Called at the end of each test epoch, before logging.
+func (ss *Sim) ProtoStats() {
+	if !ss.ProtosOn() || ss.Protos == nil {
+		return
+	}
+	for _, lnm := range ss.ProtoLays() {
+		cp := ss.Protos[lnm]
+		if ss.Config.Run.MPI {
+			cp.MPIReduce(ss.Comm)
+		}
+		if ss.Config.Run.Protos {
+			within, between, ratio := cp.Dists()
+			ss.Stats.SetFloat(lnm+"_ProtoWithin", within)
+			ss.Stats.SetFloat(lnm+"_ProtoBetween", between)
+			ss.Stats.SetFloat(lnm+"_ProtoRatio", ratio)
+		}
+		if ss.Config.Run.Selectivity {
+			ly := ss.Net.AxonLayerByName(lnm)
+			sp, dp := cp.Selectivity(ly.Shp.Shp, ss.Stats.F32Tensor(lnm+"_Sparse"), ss.Stats.F32Tensor(lnm+"_DPrime"))
+			ss.Stats.SetFloat(lnm+"_SparseMean", sp)
+			ss.Stats.SetFloat(lnm+"_DPrimeMean", dp)
+		}
+	}
+}
+
+// ConfigProtoLogs adds log items for the category prototype stats
+// at the test epoch level, copied to the train epoch and run logs
+// with a Tst prefix, to track the emergence of categorical clustering.
+func (ss *Sim) ConfigProtoLogs() {
+	if !ss.Config.Run.Protos {
+		return
+	}
+	var nms []string
+	for _, lnm := range ss.ProtoLays() {
+		for _, st := range []string{"_ProtoWithin", "_ProtoBetween", "_ProtoRatio"} {
+			nm := lnm + st
+			ss.Logs.AddStatFloatNoAggItem(etime.Test, etime.Epoch, nm)
+			nms = append(nms, nm)
+		}
+	}
+	ss.Logs.AddCopyFromFloatItems(etime.Train, []etime.Times{etime.Epoch, etime.Run}, etime.Test, etime.Epoch, "Tst", nms...)
+}
+
+// ConfigSelectivityLogs adds log items for the unit selectivity stats:
+// the per-unit sparseness and d-prime tensors at the test epoch level,
+// and the layer means, which are copied to the train epoch and run logs
+// with a Tst prefix, to track the emergence of IT-like selectivity.
+func (ss *Sim) ConfigSelectivityLogs() {
+	if !ss.Config.Run.Selectivity {
+		return
+	}
+	var nms []string
+	for _, lnm := range ss.ProtoLays() {
+		ly := ss.Net.AxonLayerByName(lnm)
+		for _, st := range []string{"_Sparse", "_DPrime"} {
+			nm := lnm + st
+			ss.Logs.AddItem(&elog.Item{
+				Name:      nm,
+				Type:      etensor.FLOAT32,
+				CellShape: ly.Shp.Shp,
+				Write: elog.WriteMap{
+					etime.Scope(etime.Test, etime.Epoch): func(ctx *elog.Context) {
+						ctx.SetTensor(ss.Stats.F32Tensor(nm))
+					}}})
+			ss.Logs.AddStatFloatNoAggItem(etime.Test, etime.Epoch, nm+"Mean")
+			nms = append(nms, nm+"Mean")
+		}
+	}
+	ss.Logs.AddCopyFromFloatItems(etime.Train, []etime.Times{etime.Epoch, etime.Run}, etime.Test, etime.Epoch, "Tst", nms...)
+}
+
+//////////////////////////////////////////////////////////////////////////////
+//   Difficulty
+
+// ImgDifficulty accumulates per-image training error and output margin
+// across epochs, for identifying systematically hard exemplars.
+// Indexes are the image indexes in the training ImageList.
+type ImgDifficulty struct {
+
+	// number of training presentations of each image
+	N []float64
+
+	// sum of training error of each image
+	SumErr []float64
+
+	// sum of output margin of each image -- see OutErrMargin
+	SumMargin []float64
+
+	// running average error rate of each image, over epochs in which it was presented, -1 if not yet presented
+	RunErr []float64
+
+	// number of presentations of each image in the current epoch
+	EpcN []float64
+
+	// sum of error of each image in the current epoch
+	EpcErr []float64
+
+	// sum of margin of each image in the current epoch
+	EpcMargin []float64
+}
+
+// Init allocates and zeros the accumulators for given number of images
+func (id *ImgDifficulty) Init(nimg int) {
+	id.N = make([]float64, nimg)
+	id.SumErr = make([]float64, nimg)
+	id.SumMargin = make([]float64, nimg)
+	id.RunErr = make([]float64, nimg)
+	for i := range id.RunErr {
+		id.RunErr[i] = -1
+	}
+	id.EpcN = make([]float64, nimg)
+	id.EpcErr = make([]float64, nimg)
+	id.EpcMargin = make([]float64, nimg)
+}
+
+// Add adds one presentation of given image in the current epoch
+func (id *ImgDifficulty) Add(img int, err, margin float64) {
+	if img < 0 || img >= len(id.N) {
+		return
+	}
+	id.EpcN[img]++
+	id.EpcErr[img] += err
+	id.EpcMargin[img] += margin
+}
+
+// EpochUpdate sums the current epoch values across MPI procs in given
+// comm (if non-nil), adds them to the totals, and updates the running
+// average error rate with given rate dt, then resets the epoch values.
+func (id *ImgDifficulty) EpochUpdate(comm *mpi.Comm, dt float64) {
+	if comm != nil {
+		for _, vals := range [][]float64{id.EpcN, id.EpcErr, id.EpcMargin} {
+			orig := make([]float64, len(vals))
+			copy(orig, vals)
+			comm.AllReduceF64(mpi.OpSum, vals, orig)
+		}
+	}
+	for i, n := range id.EpcN {
+		if n == 0 {
+			continue
+		}
+		id.N[i] += n
+		id.SumErr[i] += id.EpcErr[i]
+		id.SumMargin[i] += id.EpcMargin[i]
+		err := id.EpcErr[i] / n
+		if id.RunErr[i] < 0 {
+			id.RunErr[i] = err
+		} else {
+			id.RunErr[i] += dt * (err - id.RunErr[i])
+		}
+		id.EpcN[i] = 0
+		id.EpcErr[i] = 0
+		id.EpcMargin[i] = 0
+	}
+}
+
+// Table returns a table of the difficulty stats for each presented image,
+// with given image names, sorted by RunErr from hardest to easiest,
+// then by lowest mean Margin.
+func (id *ImgDifficulty) Table(imgs []string, cat func(img string) string) *etable.Table {
+	var idxs []int
+	for i, n := range id.N {
+		if n > 0 {
+			idxs = append(idxs, i)
+		}
+	}
+	sort.SliceStable(idxs, func(a, b int) bool {
+		ia, ib := idxs[a], idxs[b]
+		if id.RunErr[ia] != id.RunErr[ib] {
+			return id.RunErr[ia] > id.RunErr[ib]
+		}
+		return id.SumMargin[ia]/id.N[ia] < id.SumMargin[ib]/id.N[ib]
+	})
+	dt := &etable.Table{}
+	dt.SetFromSchema(etable.Schema{
+		{"Image", etensor.STRING, nil, nil},
+		{"Cat", etensor.STRING, nil, nil},
+		{"N", etensor.INT64, nil, nil},
+		{"Err", etensor.FLOAT64, nil, nil},
+		{"RunErr", etensor.FLOAT64, nil, nil},
+		{"Margin", etensor.FLOAT64, nil, nil},
+	}, len(idxs))
+	for ri, i := range idxs {
+		n := id.N[i]
+		dt.SetCellString("Image", ri, imgs[i])
+		dt.SetCellString("Cat", ri, cat(imgs[i]))
+		dt.SetCellFloat("N", ri, n)
+		dt.SetCellFloat("Err", ri, id.SumErr[i]/n)
+		dt.SetCellFloat("RunErr", ri, id.RunErr[i])
+		dt.SetCellFloat("Margin", ri, id.SumMargin[i]/n)
+	}
+	return dt
+}
+
+// HardFreq returns per-image trial frequency weights for sampling hard
+// examples more often: 1 + gain * RunErr, with images not yet presented
+// treated as maximally hard.
+func (id *ImgDifficulty) HardFreq(gain float32) []float32 {
+	fr := make([]float32, len(id.RunErr))
+	for i, re := range id.RunErr {
+		if re < 0 {
+			re = 1
+		}
+		fr[i] = 1 + gain*float32(re)
+	}
+	return fr
+}
+
+// InitDifficulty resets the per-image difficulty stats for the training
+// images -- called at the start of each run.
+func (ss *Sim) InitDifficulty() {
+	trn := ss.ImagesEnv(etime.Train)
+	if trn == nil {
+		return
+	}
+	trn.ImgFreq = nil
+	if ss.Config.Run.HardSample > 0 && !ss.Config.Run.Difficulty {
+		mpi.Println("HardSample requires the Difficulty stats -- turning on Difficulty")
+		ss.Config.Run.Difficulty = true
+	}
+	if !ss.Config.Run.Difficulty {
+		return
+	}
+	ss.Difficulty.Init(len(trn.ImageList()))
+}
+
+// DifficultyRecord adds the error and output margin of the current
+// training trial to the per-image difficulty stats, for all data indexes.
+// Called at the end of each training trial, after trial stats are computed.
+func (ss *Sim) DifficultyRecord() {
+	if !ss.Config.Run.Difficulty {
+		return
+	}
+	for di := 0; di < int(ss.Context.NetIdxs.NData); di++ {
+		ss.Difficulty.Add(ss.Stats.IntDi("TrlImgIdx", di), ss.Stats.FloatDi("TrlErr", di), ss.Stats.FloatDi("TrlMargin", di))
+	}
+}
+
+// DifficultyEpoch updates the per-image difficulty stats at the end of
+// each training epoch, gathering across MPI procs, and updates the
+// hard-example sampling weights if Config.Run.HardSample > 0.
+func (ss *Sim) DifficultyEpoch() {
+	if !ss.Config.Run.Difficulty {
+		return
+	}
+	var comm *mpi.Comm
+	if ss.Config.Run.MPI {
+		comm = ss.Comm
+	}
+	ss.Difficulty.EpochUpdate(comm, ss.Config.Run.DifficultyDt)
+	if ss.Config.Run.HardSample > 0 {
+		trn := ss.Envs.ByMode(etime.Train).(*ImagesEnv)
+		trn.ImgFreq = ss.Difficulty.HardFreq(ss.Config.Run.HardSample)
+	}
+}
+
+// SaveDifficulty saves the per-image difficulty table, in Logs.MiscTables,
+// and to a file on rank 0 in nogui mode -- called at the end of each run.
+func (ss *Sim) SaveDifficulty() {
+	if !ss.Config.Run.Difficulty {
+		return
+	}
+	trn := ss.Envs.ByMode(etime.Train).(*ImagesEnv)
+	dt := ss.Difficulty.Table(trn.ImageList(), trn.Images.Cat)
+	ss.Logs.MiscTables["Difficulty"] = dt
+	if ss.Config.GUI || ss.MPIRank() != 0 {
+		return
+	}
+	fnm := elog.LogFileName("difficulty", ss.Net.Name(), ss.Stats.String("RunName"))
+	if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		mpi.Println(err)
+	}
+}
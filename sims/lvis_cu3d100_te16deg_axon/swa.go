@@ -0,0 +1,303 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/emergent/weights"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// swa.go has checkpoint-averaged weights (stochastic weight averaging,
+// SWA): the weights of the last Config.Run.SWAK periodic weights files
+// of the last run matching the Config.Run.SWA glob pattern are averaged
+// into a new weights file, named as the last one with a _swa<K> tag.
+// All of the saved values are averaged: the synaptic weights and the
+// unit-level adapting values (ActAvg, TrgAvg etc), with the other metadata
+// taken from the last file.  The last weights and the averaged ones are
+// then tested on the full test set, with the EvalWtsStats saved as a swa
+// log, to test whether weight averaging improves generalization.
+
+// WtsFileTag returns the tag after the run and epoch counters in given
+// weights file name, as added by SaveWeightsTag (e.g., _best), which is ""
+// for the periodic checkpoints, and false if the counters are not found
+func WtsFileTag(fname string) (string, bool) {
+	base := filepath.Base(fname)
+	ms := wtsCtrsRe.FindAllStringIndex(base, -1)
+	if len(ms) == 0 {
+		return "", false
+	}
+	rest := base[ms[len(ms)-1][1]:]
+	for _, ext := range []string{".wts.gz", WtsZExt, ".wts"} {
+		if strings.HasSuffix(rest, ext) {
+			return strings.TrimSuffix(rest, ext), true
+		}
+	}
+	return rest, true
+}
+
+// SWAFiles returns the last k (0 = all) periodic weights files matching
+// given glob pattern from the last run, in order of epoch: files with a
+// tag after the run and epoch (e.g., _best, or prior SWA outputs) are
+// excluded.  Returns an error if fewer than k files are found.
+func SWAFiles(pattern string, k int) ([]string, error) {
+	all, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var fnms []string
+	lastRun := -1
+	for _, fnm := range all {
+		if tag, ok := WtsFileTag(fnm); !ok || tag != "" {
+			continue
+		}
+		fnms = append(fnms, fnm)
+		if run, _ := WtsFileCtrs(fnm); run > lastRun {
+			lastRun = run
+		}
+	}
+	if len(fnms) == 0 {
+		return nil, fmt.Errorf("SWA: no periodic weights files match: %s", pattern)
+	}
+	run := fnms[:0]
+	for _, fnm := range fnms {
+		if r, _ := WtsFileCtrs(fnm); r == lastRun {
+			run = append(run, fnm)
+		}
+	}
+	fnms = run
+	sort.SliceStable(fnms, func(i, j int) bool {
+		_, ei := WtsFileCtrs(fnms[i])
+		_, ej := WtsFileCtrs(fnms[j])
+		if ei != ej {
+			return ei < ej
+		}
+		return fnms[i] < fnms[j]
+	})
+	if len(fnms) < k {
+		return nil, fmt.Errorf("SWA: only %d periodic weights files of run: %d match: %s, need: %d", len(fnms), lastRun, pattern, k)
+	}
+	if k > 0 {
+		fnms = fnms[len(fnms)-k:]
+	}
+	return fnms, nil
+}
+
+// SWAFileName returns the name of the averaged weights file for given
+// last weights file and number of files averaged
+func SWAFileName(last string, k int) string {
+	ext := filepath.Ext(last)
+	base := strings.TrimSuffix(last, ext)
+	if ext == ".gz" {
+		ext = filepath.Ext(base) + ext
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	return fmt.Sprintf("%s_swa%d%s", base, k, ext)
+}
+
+// addVals adds vals to sum, which must be the same length
+func addVals(name string, sum, vals []float32) error {
+	if len(sum) != len(vals) {
+		return fmt.Errorf("%s: number of values: %d differs from: %d", name, len(vals), len(sum))
+	}
+	for i, v := range vals {
+		sum[i] += v
+	}
+	return nil
+}
+
+// scaleVals multiplies vals by s
+func scaleVals(vals []float32, s float32) {
+	for i := range vals {
+		vals[i] *= s
+	}
+}
+
+// SWAAddNet adds the values of weights network nw to those of sum,
+// which must have the same layers, projections, and synapses
+func SWAAddNet(sum, nw *weights.Network) error {
+	if len(sum.Layers) != len(nw.Layers) {
+		return fmt.Errorf("number of layers: %d differs from: %d", len(nw.Layers), len(sum.Layers))
+	}
+	for li := range sum.Layers {
+		sl, nl := &sum.Layers[li], &nw.Layers[li]
+		if sl.Layer != nl.Layer || len(sl.Prjns) != len(nl.Prjns) {
+			return fmt.Errorf("layer %d: %s differs from: %s", li, nl.Layer, sl.Layer)
+		}
+		for un, uv := range sl.Units {
+			if err := addVals(sl.Layer+" "+un, uv, nl.Units[un]); err != nil {
+				return err
+			}
+		}
+		for pi := range sl.Prjns {
+			sp, np := &sl.Prjns[pi], &nl.Prjns[pi]
+			pnm := sp.From + "To" + sl.Layer
+			if sp.From != np.From || len(sp.Rs) != len(np.Rs) {
+				return fmt.Errorf("prjn %s differs", pnm)
+			}
+			for mn, mv := range sp.MetaVals {
+				if err := addVals(pnm+" "+mn, mv, np.MetaVals[mn]); err != nil {
+					return err
+				}
+			}
+			for ri := range sp.Rs {
+				sr, nr := &sp.Rs[ri], &np.Rs[ri]
+				if err := addVals(pnm+" Wt", sr.Wt, nr.Wt); err != nil {
+					return err
+				}
+				if err := addVals(pnm+" Wt1", sr.Wt1, nr.Wt1); err != nil {
+					return err
+				}
+				if err := addVals(pnm+" Wt2", sr.Wt2, nr.Wt2); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// SWAScaleNet multiplies all of the values of weights network nw by s
+func SWAScaleNet(nw *weights.Network, s float32) {
+	for li := range nw.Layers {
+		lw := &nw.Layers[li]
+		for _, uv := range lw.Units {
+			scaleVals(uv, s)
+		}
+		for pi := range lw.Prjns {
+			pw := &lw.Prjns[pi]
+			for _, mv := range pw.MetaVals {
+				scaleVals(mv, s)
+			}
+			for ri := range pw.Rs {
+				rw := &pw.Rs[ri]
+				scaleVals(rw.Wt, s)
+				scaleVals(rw.Wt1, s)
+				scaleVals(rw.Wt2, s)
+			}
+		}
+	}
+}
+
+// SWAWts averages the weights of given files into the network, and
+// saves them as a new weights file (on rank 0), returning its name.
+// The metadata is taken from the last file.
+func (ss *Sim) SWAWts(fnms []string) (string, error) {
+	last := fnms[len(fnms)-1]
+	avg, err := OpenWtsNet(last)
+	if err != nil {
+		return "", err
+	}
+	for _, fnm := range fnms[:len(fnms)-1] {
+		nw, err := OpenWtsNet(fnm)
+		if err != nil {
+			return "", err
+		}
+		if err := SWAAddNet(avg, nw); err != nil {
+			return "", fmt.Errorf("SWA: %s: %w", fnm, err)
+		}
+	}
+	SWAScaleNet(avg, 1/float32(len(fnms)))
+	if err := ss.Net.SetWts(avg); err != nil {
+		return "", err
+	}
+	ss.Net.GPU.SyncAllToGPU()
+	fnm := SWAFileName(last, len(fnms))
+	if ss.MPIRank() != 0 {
+		return fnm, nil
+	}
+	if strings.HasSuffix(fnm, WtsZExt) {
+		err = ss.SaveWtsZ(fnm)
+	} else {
+		err = ss.Net.SaveWtsJSON(gi.FileName(fnm))
+	}
+	if err != nil {
+		return "", err
+	}
+	if trn := ss.ImagesEnv(etime.Train); trn != nil {
+		trn.SaveSplitManifest(SplitManifestFileName(fnm))
+	}
+	mpi.Printf("SWA: saved average of %d weights files to: %s\n", len(fnms), fnm)
+	return fnm, nil
+}
+
+// SWA averages the last k weights files matching given glob pattern,
+// and tests the last weights and the averaged ones on the full test set,
+// returning a table with the File and the EvalWtsStats for each.
+// The averaged weights are left in the network.
+func (ss *Sim) SWA(pattern string, k int) (*etable.Table, error) {
+	fnms, err := SWAFiles(pattern, k)
+	if err != nil {
+		return nil, err
+	}
+	sch := etable.Schema{
+		{"Wts", etensor.STRING, nil, nil},
+		{"File", etensor.STRING, nil, nil},
+		{"NFiles", etensor.INT64, nil, nil},
+	}
+	for _, st := range EvalWtsStats {
+		sch = append(sch, etable.Column{Name: st, Type: etensor.FLOAT64})
+	}
+	dt := &etable.Table{}
+	dt.SetMetaData("name", "SWA")
+	dt.SetMetaData("desc", "test stats of the last vs. checkpoint-averaged weights")
+	dt.SetFromSchema(sch, 0)
+	addRow := func(wts, fnm string, n int) {
+		et := ss.Logs.Table(etime.Test, etime.Epoch)
+		if et.Rows == 0 {
+			return
+		}
+		row := dt.Rows
+		dt.SetNumRows(row + 1)
+		dt.SetCellString("Wts", row, wts)
+		dt.SetCellString("File", row, fnm)
+		dt.SetCellFloat("NFiles", row, float64(n))
+		for _, st := range EvalWtsStats {
+			dt.SetCellFloat(st, row, et.CellFloat(st, et.Rows-1))
+		}
+		mpi.Printf("SWA: %s: %s  PctErr: %g\n", wts, fnm, dt.CellFloat("PctErr", row))
+	}
+	last := fnms[len(fnms)-1]
+	if err := ss.TestWts(last); err != nil {
+		return nil, err
+	}
+	addRow("Last", last, 1)
+	fnm, err := ss.SWAWts(fnms)
+	if err != nil {
+		return nil, err
+	}
+	ss.Net.InitActs(&ss.Context)
+	ss.TestAll()
+	addRow("SWA", fnm, len(fnms))
+	return dt, nil
+}
+
+// RunSWA runs SWA on the Config.Run.SWA weights files, and saves the
+// table as a swa log file on rank 0
+func (ss *Sim) RunSWA() error {
+	dt, err := ss.SWA(ss.Config.Run.SWA, ss.Config.Run.SWAK)
+	if err != nil {
+		return err
+	}
+	ss.Logs.MiscTables["SWA"] = dt
+	if ss.MPIRank() != 0 {
+		return nil
+	}
+	fnm := elog.LogFileName("swa", ss.Net.Name(), ss.Stats.String("RunName"))
+	if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	mpi.Printf("Saved SWA evaluation to: %s\n", fnm)
+	return nil
+}
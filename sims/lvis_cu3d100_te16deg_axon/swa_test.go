@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSWAFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, fnm := range []string{
+		"LVis_Base_000_00010.wts.gz",
+		"LVis_Base_000_00020.wts.gz",
+		"LVis_Base_001_00005.wts.gz",
+		"LVis_Base_001_00010.wts.gz",
+		"LVis_Base_001_00015_best.wts.gz",
+		"LVis_Base_001_00020.wts.gz",
+		"LVis_Base_001_00020_swa2.wts.gz",
+		"LVis_Base_001_00020_final.wts.gz",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, fnm), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pat := filepath.Join(dir, "*.wts.gz")
+	tests := []struct {
+		k    int
+		want []string
+		err  bool
+	}{
+		{2, []string{"LVis_Base_001_00010.wts.gz", "LVis_Base_001_00020.wts.gz"}, false},
+		{3, []string{"LVis_Base_001_00005.wts.gz", "LVis_Base_001_00010.wts.gz", "LVis_Base_001_00020.wts.gz"}, false},
+		{0, []string{"LVis_Base_001_00005.wts.gz", "LVis_Base_001_00010.wts.gz", "LVis_Base_001_00020.wts.gz"}, false},
+		{4, nil, true},
+	}
+	for _, tt := range tests {
+		fnms, err := SWAFiles(pat, tt.k)
+		if tt.err {
+			if err == nil {
+				t.Errorf("SWAFiles k: %d: expected error, got: %v", tt.k, fnms)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SWAFiles k: %d: %v", tt.k, err)
+			continue
+		}
+		var got []string
+		for _, fnm := range fnms {
+			got = append(got, filepath.Base(fnm))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SWAFiles k: %d: got: %v, want: %v", tt.k, got, tt.want)
+		}
+	}
+	if _, err := SWAFiles(filepath.Join(dir, "*.wtsz"), 1); err == nil {
+		t.Errorf("SWAFiles: expected error for no matching files")
+	}
+}
+
+func TestWtsFileTag(t *testing.T) {
+	tests := []struct {
+		fnm string
+		tag string
+		ok  bool
+	}{
+		{"LVis_Base_001_00020.wts.gz", "", true},
+		{"dir/LVis_Base_001_00020_best.wts.gz", "_best", true},
+		{"LVis_Base_001_00020_swa5.wtsz", "_swa5", true},
+		{"LVis_Base.wts.gz", "", false},
+	}
+	for _, tt := range tests {
+		tag, ok := WtsFileTag(tt.fnm)
+		if tag != tt.tag || ok != tt.ok {
+			t.Errorf("WtsFileTag(%s) = %q, %v, want: %q, %v", tt.fnm, tag, ok, tt.tag, tt.ok)
+		}
+	}
+}
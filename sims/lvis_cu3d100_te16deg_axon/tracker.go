@@ -0,0 +1,358 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// tracker.go has the optional experiment tracking sink, which pushes the
+// config, the train epoch log rows, and the final run log metrics to an
+// external tracking server (on MPI rank 0), so cluster runs can be monitored
+// and compared with other models.  Backends implement the Tracker interface
+// and are registered in TrackerTypes.  See Config.Log.Track.
+
+// Tracker is an experiment tracking backend
+type Tracker interface {
+
+	// Start starts a new tracked run with given name and flattened config
+	Start(run string, config map[string]string) error
+
+	// Log records the metric values at given step (training epoch)
+	Log(step int, vals map[string]float64) error
+
+	// Finish records the final metric values and ends the run
+	Finish(vals map[string]float64) error
+}
+
+// TrackerTypes are the available Tracker backends, by Config.Log.Track.Type
+var TrackerTypes = map[string]func(tc *TrackConfig) Tracker{
+	"http":   func(tc *TrackConfig) Tracker { return &HTTPTracker{Config: tc} },
+	"mlflow": func(tc *TrackConfig) Tracker { return &MLflowTracker{Config: tc} },
+}
+
+// TrackPost sends given value as JSON to given address, with the bearer token
+// from the TokenEnv environment variable if set, and decodes the JSON
+// response into resp if non-nil.
+func TrackPost(tc *TrackConfig, method, addr string, val, resp any) error {
+	var body io.Reader
+	if val != nil {
+		b, err := json.Marshal(val)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, addr, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if tc.TokenEnv != "" {
+		if tok := os.Getenv(tc.TokenEnv); tok != "" {
+			req.Header.Set("Authorization", "Bearer "+tok)
+		}
+	}
+	cl := &http.Client{Timeout: time.Duration(tc.Timeout) * time.Second}
+	res, err := cl.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", method, addr, res.Status, strings.TrimSpace(string(msg)))
+	}
+	if resp == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(resp)
+}
+
+// TrackVals returns the finite numeric values in the last row of the table,
+// for scalar columns, by column name -- NaN and Inf cannot be encoded in JSON
+func TrackVals(dt *etable.Table) map[string]float64 {
+	vals := make(map[string]float64)
+	if dt == nil || dt.Rows == 0 {
+		return vals
+	}
+	row := dt.Rows - 1
+	for ci, cl := range dt.Cols {
+		if cl.NumDims() != 1 || cl.DataType() == etensor.STRING {
+			continue
+		}
+		v := cl.FloatVal1D(row)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		vals[dt.ColNames[ci]] = v
+	}
+	return vals
+}
+
+// FlattenConfig returns the given config as a flat map of dotted
+// field paths to string values, via its JSON encoding
+func FlattenConfig(cfg any) map[string]string {
+	flat := make(map[string]string)
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return flat
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return flat
+	}
+	var flatten func(pfx string, v any)
+	flatten = func(pfx string, v any) {
+		switch vt := v.(type) {
+		case map[string]any:
+			for k, kv := range vt {
+				if pfx != "" {
+					k = pfx + "." + k
+				}
+				flatten(k, kv)
+			}
+		case string:
+			flat[pfx] = vt
+		default:
+			b, _ := json.Marshal(vt)
+			flat[pfx] = string(b)
+		}
+	}
+	flatten("", m)
+	return flat
+}
+
+////////////////////////////////////////////////////////////////////
+//  HTTPTracker
+
+// HTTPTracker is a generic Tracker that posts each event as a JSON
+// object to the Config.Log.Track.URL, with fields: event (start, log,
+// or finish), project, run, and step, config, or metrics -- for use with
+// a simple collector service or a relay to other trackers.
+type HTTPTracker struct {
+
+	// tracking config
+	Config *TrackConfig
+
+	// name of the current run
+	Run string
+}
+
+func (ht *HTTPTracker) post(ev map[string]any) error {
+	ev["project"] = ht.Config.Project
+	ev["run"] = ht.Run
+	ev["time"] = time.Now().Unix()
+	return TrackPost(ht.Config, "POST", ht.Config.URL, ev, nil)
+}
+
+func (ht *HTTPTracker) Start(run string, config map[string]string) error {
+	ht.Run = run
+	return ht.post(map[string]any{"event": "start", "config": config})
+}
+
+func (ht *HTTPTracker) Log(step int, vals map[string]float64) error {
+	return ht.post(map[string]any{"event": "log", "step": step, "metrics": vals})
+}
+
+func (ht *HTTPTracker) Finish(vals map[string]float64) error {
+	return ht.post(map[string]any{"event": "finish", "metrics": vals})
+}
+
+////////////////////////////////////////////////////////////////////
+//  MLflowTracker
+
+// MLflowTracker is a Tracker using the MLflow REST API, at the server
+// given by Config.Log.Track.URL, with the Project as the experiment name
+// (created if it does not exist), and the config as the run params.
+type MLflowTracker struct {
+
+	// tracking config
+	Config *TrackConfig
+
+	// experiment id for the Project
+	ExptID string
+
+	// id of the current run
+	RunID string
+}
+
+// MLflowMaxBatch is the max number of params or metrics in a log-batch call
+const MLflowMaxBatch = 100
+
+func (mt *MLflowTracker) api(method, path string, val, resp any) error {
+	return TrackPost(mt.Config, method, strings.TrimSuffix(mt.Config.URL, "/")+"/api/2.0/mlflow/"+path, val, resp)
+}
+
+// Expt gets the experiment id for the Project, creating it if needed
+func (mt *MLflowTracker) Expt() error {
+	if mt.ExptID != "" {
+		return nil
+	}
+	var get struct {
+		Experiment struct {
+			ExperimentID string `json:"experiment_id"`
+		} `json:"experiment"`
+	}
+	if err := mt.api("GET", "experiments/get-by-name?experiment_name="+url.QueryEscape(mt.Config.Project), nil, &get); err == nil {
+		mt.ExptID = get.Experiment.ExperimentID
+		return nil
+	}
+	var crt struct {
+		ExperimentID string `json:"experiment_id"`
+	}
+	if err := mt.api("POST", "experiments/create", map[string]any{"name": mt.Config.Project}, &crt); err != nil {
+		return err
+	}
+	mt.ExptID = crt.ExperimentID
+	return nil
+}
+
+func (mt *MLflowTracker) Start(run string, config map[string]string) error {
+	if err := mt.Expt(); err != nil {
+		return err
+	}
+	var crt struct {
+		Run struct {
+			Info struct {
+				RunID string `json:"run_id"`
+			} `json:"info"`
+		} `json:"run"`
+	}
+	err := mt.api("POST", "runs/create", map[string]any{"experiment_id": mt.ExptID, "run_name": run, "start_time": time.Now().UnixMilli()}, &crt)
+	if err != nil {
+		return err
+	}
+	mt.RunID = crt.Run.Info.RunID
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var params []map[string]string
+	for i, k := range keys {
+		v := config[k]
+		if len(v) > 500 { // MLflow param value limit
+			v = v[:500]
+		}
+		params = append(params, map[string]string{"key": k, "value": v})
+		if len(params) == MLflowMaxBatch || i == len(keys)-1 {
+			if err := mt.api("POST", "runs/log-batch", map[string]any{"run_id": mt.RunID, "params": params}, nil); err != nil {
+				return err
+			}
+			params = nil
+		}
+	}
+	return nil
+}
+
+func (mt *MLflowTracker) Log(step int, vals map[string]float64) error {
+	if mt.RunID == "" {
+		return nil
+	}
+	ts := time.Now().UnixMilli()
+	var mets []map[string]any
+	for k, v := range vals {
+		mets = append(mets, map[string]any{"key": k, "value": v, "timestamp": ts, "step": step})
+		if len(mets) == MLflowMaxBatch {
+			if err := mt.api("POST", "runs/log-batch", map[string]any{"run_id": mt.RunID, "metrics": mets}, nil); err != nil {
+				return err
+			}
+			mets = nil
+		}
+	}
+	if len(mets) == 0 {
+		return nil
+	}
+	return mt.api("POST", "runs/log-batch", map[string]any{"run_id": mt.RunID, "metrics": mets}, nil)
+}
+
+func (mt *MLflowTracker) Finish(vals map[string]float64) error {
+	if mt.RunID == "" {
+		return nil
+	}
+	fvals := make(map[string]float64, len(vals))
+	for k, v := range vals {
+		fvals["Final_"+k] = v
+	}
+	if err := mt.Log(0, fvals); err != nil {
+		return err
+	}
+	err := mt.api("POST", "runs/update", map[string]any{"run_id": mt.RunID, "status": "FINISHED", "end_time": time.Now().UnixMilli()}, nil)
+	mt.RunID = ""
+	return err
+}
+
+////////////////////////////////////////////////////////////////////
+//  Sim
+
+// ConfigTracker configures the Tracker per Config.Log.Track, on MPI rank 0
+func (ss *Sim) ConfigTracker() {
+	tc := &ss.Config.Log.Track
+	ss.Tracker = nil
+	if tc.Type == "" || ss.MPIRank() != 0 {
+		return
+	}
+	fun, ok := TrackerTypes[tc.Type]
+	if !ok {
+		mpi.Printf("Track: Type: %s not found in TrackerTypes\n", tc.Type)
+		return
+	}
+	if tc.Project == "" {
+		tc.Project = ss.Net.Name()
+	}
+	ss.Tracker = fun(tc)
+}
+
+// TrackError reports a tracking error -- tracking is best-effort,
+// so errors do not affect the run
+func TrackError(ev string, err error) {
+	if err != nil {
+		mpi.Printf("Track: %s: %v\n", ev, err)
+	}
+}
+
+// TrackStart starts tracking a new run -- called at the start of the run
+func (ss *Sim) TrackStart() {
+	if ss.Tracker == nil {
+		return
+	}
+	run := fmt.Sprintf("%s_%03d", ss.Stats.String("RunName"), ss.Loops.GetLoop(etime.Train, etime.Run).Counter.Cur)
+	TrackError("Start", ss.Tracker.Start(run, FlattenConfig(&ss.Config)))
+}
+
+// TrackEpoch pushes the last train epoch log row -- called at
+// the end of the training epoch, after Log
+func (ss *Sim) TrackEpoch() {
+	if ss.Tracker == nil {
+		return
+	}
+	epc := ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur
+	TrackError("Log", ss.Tracker.Log(epc, TrackVals(ss.Logs.Table(etime.Train, etime.Epoch))))
+}
+
+// TrackFinish pushes the final run log metrics -- called at
+// the end of the run, after Log and RunStats
+func (ss *Sim) TrackFinish() {
+	if ss.Tracker == nil {
+		return
+	}
+	TrackError("Finish", ss.Tracker.Finish(TrackVals(ss.Logs.Table(etime.Train, etime.Run))))
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/emergent/elog"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+	"github.com/goki/ki/ints"
+)
+
+// trainfrac.go has the subsampling of the training images to a fraction
+// per category (Config.Env.TrainFrac), for studying the sample efficiency
+// of the architecture and for faster prototyping.  The selection is
+// deterministic (see Images.SubsampleTrain), and the number of training
+// images per category before and after is recorded in the TrainCounts
+// table, which is saved as a train_frac log file in nogui mode.
+
+// ApplyTrainFrac subsamples the training images of given env per
+// Config.Env.TrainFrac, records the counts, and sets the NTrainImgs stat
+// -- called in ConfigEnv after the categories are selected
+func (ss *Sim) ApplyTrainFrac(ev *ImagesEnv) {
+	ec := &ss.Config.Env
+	im := &ev.Images
+	ss.TrainCounts = nil
+	if ec.TrainFrac <= 0 || ec.TrainFrac >= 1 {
+		ss.Stats.SetInt("NTrainImgs", len(im.FlatTrain))
+		return
+	}
+	nc := len(im.Cats)
+	dt := &etable.Table{}
+	dt.SetFromSchema(etable.Schema{
+		{Name: "Cat", Type: etensor.STRING},
+		{Name: "NAll", Type: etensor.INT64},
+		{Name: "NUsed", Type: etensor.INT64},
+	}, nc)
+	for ci, fls := range im.ImagesTrain {
+		dt.SetCellString("Cat", ci, im.Cats[ci])
+		dt.SetCellFloat("NAll", ci, float64(len(fls)))
+	}
+	nall := len(im.FlatTrain)
+	im.SubsampleTrain(ec.TrainFrac, ec.TrainFracMin, ec.TrainFracSeed, ec.TrainFracItems)
+	minN, maxN := -1, 0
+	for ci, fls := range im.ImagesTrain {
+		n := len(fls)
+		dt.SetCellFloat("NUsed", ci, float64(n))
+		if minN < 0 || n < minN {
+			minN = n
+		}
+		maxN = ints.MaxInt(maxN, n)
+	}
+	ss.TrainCounts = dt
+	ss.Stats.SetInt("NTrainImgs", len(im.FlatTrain))
+	mpi.Printf("TrainFrac: %g: using %d of %d training images, per category: min: %d, max: %d\n", ec.TrainFrac, len(im.FlatTrain), nall, minN, maxN)
+}
+
+// SaveTrainFrac saves the TrainCounts table, if subsampling,
+// as a train_frac log file, on rank 0
+func (ss *Sim) SaveTrainFrac(netName, runName string) {
+	dt := ss.TrainCounts
+	if dt == nil || ss.MPIRank() != 0 {
+		return
+	}
+	fnm := elog.LogFileName("train_frac", netName, runName)
+	if err := dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		mpi.Println(err)
+		return
+	}
+	mpi.Printf("Saved training image counts per category to: %s\n", fnm)
+}
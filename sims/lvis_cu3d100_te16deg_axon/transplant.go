@@ -0,0 +1,277 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/params"
+	"github.com/emer/emergent/weights"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// transplant.go has partial weight loading: weights from a saved file
+// are transplanted into selected layers and projections of the current
+// network, which can be configured differently from the one that saved
+// them, for staged and transfer-learning experiments.  Everything that
+// is not transplanted keeps its random initial weights.
+
+// Selectors use the params conventions: #Name for a layer or projection
+// name (projections are named SendToRecv), or .Class for a class, which
+// includes the layer or projection type (e.g., .SuperLayer).  Selecting
+// a layer transplants its unit-level values (ActAvg, TrgAvg) and all of
+// its receiving projections.
+
+// OpenWtsNet opens all of the weights in given file, which can be
+// standard .wts or .wts.gz JSON, or the .wtsz format
+func OpenWtsNet(filename string) (*weights.Network, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	switch {
+	case strings.HasSuffix(filename, WtsZExt):
+		wh, base, err := ReadWtsZHeader(bufio.NewReader(fp))
+		if err != nil {
+			return nil, err
+		}
+		wn := &weights.Network{Network: wh.Network, MetaData: wh.MetaData}
+		for i := range wh.Layers {
+			lw, err := ReadWtsZLayer(fp, base, wh, &wh.Layers[i])
+			if err != nil {
+				return nil, err
+			}
+			wn.Layers = append(wn.Layers, *lw)
+		}
+		return wn, nil
+	case filepath.Ext(filename) == ".gz":
+		gzr, err := gzip.NewReader(fp)
+		if err != nil {
+			return nil, err
+		}
+		defer gzr.Close()
+		return weights.NetReadJSON(gzr)
+	}
+	return weights.NetReadJSON(bufio.NewReader(fp))
+}
+
+// TransplantSelMatch returns true if any of the selectors matches
+// an object with given name and class
+func TransplantSelMatch(sels []string, name, cls, typ string) bool {
+	for _, sel := range sels {
+		if params.SelMatch(strings.TrimSpace(sel), name, cls, typ, "") {
+			return true
+		}
+	}
+	return false
+}
+
+// TransplantUnitsCheck returns an error if the unit-level values
+// in given saved layer weights do not match the size of the layer
+func TransplantUnitsCheck(ly *axon.Layer, lw *weights.Layer) error {
+	for vnm, vals := range lw.Units {
+		if len(vals) != int(ly.NNeurons) {
+			return fmt.Errorf("%s: %d values in file vs. %d neurons", vnm, len(vals), ly.NNeurons)
+		}
+	}
+	return nil
+}
+
+// TransplantPrjnCheck returns an error if the saved projection weights
+// do not match the shape and connectivity of given projection
+func TransplantPrjnCheck(pj *axon.Prjn, pw *weights.Prjn) error {
+	nr := int(pj.Recv.NNeurons)
+	ns := int(pj.Send.NNeurons)
+	if len(pw.Rs) != nr {
+		return fmt.Errorf("%d recv neurons in file vs. %d", len(pw.Rs), nr)
+	}
+	for i := range pw.Rs {
+		pr := &pw.Rs[i]
+		if pr.Ri < 0 || pr.Ri >= nr {
+			return fmt.Errorf("recv index: %d out of range", pr.Ri)
+		}
+		if rn := int(pj.RecvCon[pr.Ri].N); len(pr.Si) != rn {
+			return fmt.Errorf("recv neuron: %d has %d connections in file vs. %d", pr.Ri, len(pr.Si), rn)
+		}
+		for _, si := range pr.Si {
+			if si < 0 || si >= ns {
+				return fmt.Errorf("recv neuron: %d sending index: %d out of range for %d sending neurons", pr.Ri, si, ns)
+			}
+		}
+	}
+	return nil
+}
+
+// TransplantWts loads the weights from given file into only the layers
+// and projections matching the given selectors, checking that shapes
+// and connectivity match, and returns a report table with one row for
+// each layer (unit-level values, empty Prjn) and receiving projection,
+// with Status: Transplanted, Random (not selected), Missing (not in file),
+// or Mismatch (with the reason in Note).
+func (ss *Sim) TransplantWts(filename string, sels []string) (*etable.Table, error) {
+	wn, err := OpenWtsNet(filename)
+	if err != nil {
+		return nil, err
+	}
+	lws := make(map[string]*weights.Layer, len(wn.Layers))
+	for i := range wn.Layers {
+		lws[wn.Layers[i].Layer] = &wn.Layers[i]
+	}
+	net := ss.Net
+	net.GPU.SyncAllFmGPU()
+
+	dt := &etable.Table{}
+	dt.SetFromSchema(etable.Schema{
+		{"Layer", etensor.STRING, nil, nil},
+		{"Prjn", etensor.STRING, nil, nil},
+		{"Status", etensor.STRING, nil, nil},
+		{"Note", etensor.STRING, nil, nil},
+	}, 0)
+	addRow := func(lnm, pnm, status, note string) {
+		row := dt.Rows
+		dt.SetNumRows(row + 1)
+		dt.SetCellString("Layer", row, lnm)
+		dt.SetCellString("Prjn", row, pnm)
+		dt.SetCellString("Status", row, status)
+		dt.SetCellString("Note", row, note)
+	}
+
+	for _, ly := range net.Layers {
+		if ly.IsOff() {
+			continue
+		}
+		lnm := ly.Name()
+		lsel := TransplantSelMatch(sels, lnm, ly.Class(), ly.TypeName())
+		lw := lws[lnm]
+		switch {
+		case !lsel:
+			addRow(lnm, "", "Random", "")
+		case lw == nil:
+			addRow(lnm, "", "Missing", "layer not in weights file")
+		default:
+			if err := TransplantUnitsCheck(ly, lw); err != nil {
+				addRow(lnm, "", "Mismatch", err.Error())
+			} else {
+				lu := *lw
+				lu.Prjns = nil
+				ly.SetWts(&lu)
+				addRow(lnm, "", "Transplanted", "")
+			}
+		}
+		nfrom := make(map[string]int) // multiple prjns from same sender are in order
+		for _, pj := range ly.RcvPrjns {
+			snm := pj.Send.Name()
+			fi := nfrom[snm]
+			nfrom[snm]++
+			pnm := pj.Name()
+			if !lsel && !TransplantSelMatch(sels, pnm, pj.Class(), pj.TypeName()) {
+				addRow(lnm, pnm, "Random", "")
+				continue
+			}
+			var pw *weights.Prjn
+			if lw != nil {
+				n := 0
+				for i := range lw.Prjns {
+					if lw.Prjns[i].From != snm {
+						continue
+					}
+					if n == fi {
+						pw = &lw.Prjns[i]
+						break
+					}
+					n++
+				}
+			}
+			if pw == nil {
+				addRow(lnm, pnm, "Missing", "projection not in weights file")
+				continue
+			}
+			if err := TransplantPrjnCheck(pj, pw); err != nil {
+				addRow(lnm, pnm, "Mismatch", err.Error())
+				continue
+			}
+			if err := pj.SetWts(pw); err != nil {
+				addRow(lnm, pnm, "Mismatch", err.Error())
+				continue
+			}
+			addRow(lnm, pnm, "Transplanted", "")
+		}
+	}
+	net.GPU.SyncAllToGPU()
+	ss.Logs.MiscTables["Transplant"] = dt
+	return dt, nil
+}
+
+// TransplantSummary returns a summary of the transplant report table:
+// counts of each status, and the names of the items that are not
+// Transplanted or Random
+func TransplantSummary(dt *etable.Table) string {
+	counts := make(map[string]int)
+	var probs []string
+	for ri := 0; ri < dt.Rows; ri++ {
+		st := dt.CellString("Status", ri)
+		counts[st]++
+		if st == "Transplanted" || st == "Random" {
+			continue
+		}
+		nm := dt.CellString("Layer", ri)
+		if pnm := dt.CellString("Prjn", ri); pnm != "" {
+			nm = pnm
+		}
+		probs = append(probs, fmt.Sprintf("  %s: %s %s", nm, st, dt.CellString("Note", ri)))
+	}
+	sum := fmt.Sprintf("Transplanted: %d  Random: %d  Missing: %d  Mismatch: %d", counts["Transplanted"], counts["Random"], counts["Missing"], counts["Mismatch"])
+	if len(probs) > 0 {
+		sum += "\n" + strings.Join(probs, "\n")
+	}
+	return sum
+}
+
+// InitTransplant transplants the Config.Run.Transplant weights into the
+// Config.Run.TransplantSel layers and projections, if set, at the start
+// of each run, after the weights are initialized.  The report table is
+// saved on rank 0.
+func (ss *Sim) InitTransplant() {
+	fnm := ss.Config.Run.Transplant
+	if fnm == "" {
+		return
+	}
+	dt, err := ss.TransplantWts(fnm, ss.Config.Run.TransplantSel)
+	if err != nil {
+		mpi.Println(err)
+		return
+	}
+	mpi.Printf("Transplanted weights from: %s\n%s\n", fnm, TransplantSummary(dt))
+	if ss.MPIRank() != 0 || ss.Config.GUI {
+		return
+	}
+	tfnm := elog.LogFileName("transplant", ss.Net.Name(), ss.Stats.String("RunName"))
+	if err := dt.SaveCSV(gi.FileName(tfnm), etable.Tab, etable.Headers); err != nil {
+		mpi.Println(err)
+	}
+}
+
+// TransplantGUI transplants weights from given file into the layers and
+// projections matching the space-separated selectors, for the GUI
+func (ss *Sim) TransplantGUI(filename gi.FileName, sels string) {
+	dt, err := ss.TransplantWts(string(filename), strings.Fields(sels))
+	if err != nil {
+		mpi.Println(err)
+		return
+	}
+	fmt.Println(TransplantSummary(dt))
+	ss.GUI.UpdateNetView()
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "fmt"
+
+// trials.go has the TrialsAllocator, which is a copy of the one in the
+// leabra version of this sim (each sim is a self-contained main package),
+// so that both scale the number of trials per epoch with the MPI world
+// size in the same way -- any change must be made to both copies.
+
+// TrialsAllocator computes the number of trials per epoch for each MPI
+// proc, from the total desired number of trials per epoch across all
+// procs, the number of data-parallel trials per step on each proc (NData),
+// and the number of procs.  The total is rounded up to an even multiple
+// of NData * NProcs, so every proc runs the same whole number of steps,
+// and at least the desired total number of trials are run.
+type TrialsAllocator struct {
+
+	// desired total number of trials per epoch, across all procs
+	Total int
+
+	// number of data-parallel trials per step on each proc -- 1 if not data parallel
+	NData int
+
+	// number of MPI procs
+	NProcs int
+
+	// computed: effective total number of trials per epoch, across all procs -- an even multiple of NData * NProcs, >= Total
+	EffTotal int
+
+	// computed: number of trials per epoch on each proc -- an even multiple of NData
+	PerProc int
+
+	// computed: number of NData steps per epoch on each proc
+	Steps int
+}
+
+// NewTrialsAllocator returns a new TrialsAllocator for given total
+// number of trials, NData and number of procs, with the trials allocated
+func NewTrialsAllocator(total, ndata, nprocs int) *TrialsAllocator {
+	ta := &TrialsAllocator{Total: total, NData: ndata, NProcs: nprocs}
+	ta.Alloc()
+	return ta
+}
+
+// Alloc computes the per-proc allocation of trials
+func (ta *TrialsAllocator) Alloc() {
+	if ta.NData < 1 {
+		ta.NData = 1
+	}
+	if ta.NProcs < 1 {
+		ta.NProcs = 1
+	}
+	stepN := ta.NData * ta.NProcs
+	ta.Steps = (ta.Total + stepN - 1) / stepN
+	if ta.Steps < 1 {
+		ta.Steps = 1
+	}
+	ta.PerProc = ta.Steps * ta.NData
+	ta.EffTotal = ta.PerProc * ta.NProcs
+}
+
+// String returns a report of the allocation, including the effective total
+func (ta *TrialsAllocator) String() string {
+	str := fmt.Sprintf("Trials per epoch: %d total = %d procs x %d per proc (%d steps x NData: %d)", ta.EffTotal, ta.NProcs, ta.PerProc, ta.Steps, ta.NData)
+	if ta.EffTotal != ta.Total {
+		str += fmt.Sprintf(", rounded up from: %d", ta.Total)
+	}
+	return str
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+)
+
+// trigger.go has the trial-synchronized stimulus trigger output, for
+// synchronizing external analysis or presentation software with the
+// model's trials, e.g., for model vs. experiment comparisons: at the onset
+// of each trial, when the inputs are applied, a record with the wall clock
+// time, trial counters, image, and transforms is written to a per-proc
+// sidecar file and / or sent as a JSON UDP message.  See Config.Log.Trigger.
+
+// TrialTrigger is the trigger record for one trial and data index
+type TrialTrigger struct {
+
+	// wall clock time of the trial onset, in RFC3339 format with nanoseconds
+	Time string
+
+	// MPI rank of the proc running the trial
+	Rank int
+
+	// Train or Test
+	Mode string
+
+	// run, epoch, trial counters, as in the trial logs
+	Run, Epoch, Trial int
+
+	// data index within the trial
+	Di int
+
+	// image file name, empty for custom envs
+	Image string
+
+	// category of the image
+	Cat string
+
+	// translation of the image, as a proportion of the half-width
+	TransX, TransY float32
+
+	// scaling of the image
+	Scale float32
+
+	// in-plane rotation of the image, in degrees
+	Rot float32
+}
+
+// TriggerCols are the column names of the trigger sidecar file
+var TriggerCols = []string{"Time", "Rank", "Mode", "Run", "Epoch", "Trial", "Di", "Image", "Cat", "TransX", "TransY", "Scale", "Rot"}
+
+// TSV returns the trigger as a tab-separated line, per TriggerCols
+func (tt *TrialTrigger) TSV() string {
+	return fmt.Sprintf("%s\t%d\t%s\t%d\t%d\t%d\t%d\t%s\t%s\t%g\t%g\t%g\t%g\n", tt.Time, tt.Rank, tt.Mode, tt.Run, tt.Epoch, tt.Trial, tt.Di, tt.Image, tt.Cat, tt.TransX, tt.TransY, tt.Scale, tt.Rot)
+}
+
+// Trigger has the outputs for the trial triggers
+type Trigger struct {
+
+	// sidecar file, if open
+	File *os.File
+
+	// UDP connection, if open
+	Conn net.Conn
+}
+
+// ConfigTrigger opens the UDP connection for the trial triggers,
+// if Config.Log.Trigger.UDP is set
+func (ss *Sim) ConfigTrigger() {
+	tc := &ss.Config.Log.Trigger
+	if tc.UDP == "" || ss.Trigger.Conn != nil {
+		return
+	}
+	conn, err := net.Dial("udp", tc.UDP)
+	if err != nil {
+		mpi.Println("Trigger:", err)
+		return
+	}
+	ss.Trigger.Conn = conn
+}
+
+// OpenTrigger opens the trigger sidecar file for this proc, named by the
+// network and run, if Config.Log.Trigger.File is set (in nogui mode)
+func (ss *Sim) OpenTrigger(netName, runName string) {
+	if !ss.Config.Log.Trigger.File {
+		return
+	}
+	fnm := elog.LogFileName(fmt.Sprintf("trigger_%d", ss.MPIRank()), netName, runName)
+	f, err := os.Create(fnm)
+	if err != nil {
+		mpi.Println("Trigger:", err)
+		return
+	}
+	f.WriteString(strings.Join(TriggerCols, "\t") + "\n")
+	ss.Trigger.File = f
+	mpi.Printf("Saving trial triggers to: %s\n", fnm)
+}
+
+// CloseTrigger closes the trigger file and UDP connection
+func (ss *Sim) CloseTrigger() {
+	tg := &ss.Trigger
+	if tg.File != nil {
+		tg.File.Close()
+		tg.File = nil
+	}
+	if tg.Conn != nil {
+		tg.Conn.Close()
+		tg.Conn = nil
+	}
+}
+
+// TriggerTrial emits the trigger for the current trial onset, for given
+// data index, with the image and transforms from given env (nil for
+// custom envs).  Called in ApplyInputs after the env is stepped.
+func (ss *Sim) TriggerTrial(di int, ev *ImagesEnv) {
+	tg := &ss.Trigger
+	if tg.File == nil && tg.Conn == nil {
+		return
+	}
+	mode := ss.Context.Mode
+	if mode != etime.Test && !(mode == etime.Train && ss.Config.Log.Trigger.Train) {
+		return
+	}
+	st := ss.Loops.Stacks[mode]
+	tt := &TrialTrigger{Time: time.Now().Format(time.RFC3339Nano), Rank: ss.MPIRank(), Mode: mode.String(), Di: di}
+	tt.Run = ss.Loops.GetLoop(etime.Train, etime.Run).Counter.Cur
+	tt.Epoch = st.Loops[etime.Epoch].Counter.Cur
+	tt.Trial = st.Loops[etime.Trial].Counter.Cur + di
+	tt.Cat = ss.Stats.StringDi("TrlCat", di)
+	if ev != nil {
+		tt.Image = ev.CurImg
+		tt.TransX, tt.TransY = ev.CurTrans.X, ev.CurTrans.Y
+		tt.Scale, tt.Rot = ev.CurScale, ev.CurRot
+	}
+	if tg.File != nil {
+		if _, err := tg.File.WriteString(tt.TSV()); err != nil {
+			mpi.Println("Trigger:", err)
+			tg.File = nil
+		}
+	}
+	if tg.Conn != nil {
+		b, _ := json.Marshal(tt)
+		tg.Conn.Write(b) // best-effort: no listener is not an error
+	}
+}
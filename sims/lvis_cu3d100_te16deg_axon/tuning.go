@@ -0,0 +1,167 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/emer/emergent/etime"
+	"github.com/emer/etable/eplot"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// tuning.go has the GUI unit tuning curve plot: the mean response of a
+// selected TEO or TE unit to each test category, sorted from the best
+// category down, computed from the category accumulators of the most
+// recent test epoch (see ProtoRecord), for finding and characterizing
+// category-selective units.
+
+// TuningCurve is the tuning curve of one unit across test categories
+type TuningCurve struct {
+
+	// [def: TE] layer of the unit: one of the ProtoLays layers, TEOf16, TEOf8, or TE
+	Lay string `def:"TE" desc:"layer of the unit: one of the ProtoLays layers, TEOf16, TEOf8, or TE"`
+
+	// index of the unit within the layer
+	Unit int `desc:"index of the unit within the layer"`
+
+	// [view: -] tuning curve table: Cat, mean ActM response, its standard error, and number of trials, sorted by descending response
+	Table etable.Table `view:"-" desc:"tuning curve table: Cat, mean ActM response, its standard error, and number of trials, sorted by descending response"`
+
+	// [view: -] plot of the Table
+	Plot *eplot.Plot2D `view:"-" desc:"plot of the Table"`
+}
+
+func (tc *TuningCurve) Defaults() {
+	tc.Lay = "TE"
+}
+
+// ConfigTable configures the Table with given number of rows
+func (tc *TuningCurve) ConfigTable(rows int) {
+	sch := etable.Schema{
+		{"Cat", etensor.STRING, nil, nil},
+		{"Act", etensor.FLOAT64, nil, nil},
+		{"SEM", etensor.FLOAT64, nil, nil},
+		{"N", etensor.FLOAT64, nil, nil},
+	}
+	tc.Table.SetFromSchema(sch, rows)
+}
+
+// TuningCurve computes the tuning curve of given unit in given layer
+// from the category accumulators of the most recent test epoch, and
+// updates the Tuning plot.  Requires Config.Run.Protos or Selectivity.
+func (ss *Sim) TuningCurve(lay string, unit int) error {
+	tc := &ss.Tuning
+	tc.Lay = lay
+	tc.Unit = unit
+	if !ss.ProtosOn() || ss.Protos == nil {
+		return fmt.Errorf("TuningCurve: no category accumulators: set Config.Run.Selectivity and run the test")
+	}
+	cp, ok := ss.Protos[lay]
+	if !ok {
+		return fmt.Errorf("TuningCurve: layer %s not found, must be one of: %v", lay, ss.ProtoLays())
+	}
+	if unit < 0 || unit >= cp.NUnits {
+		return fmt.Errorf("TuningCurve: unit %d out of range for layer %s with %d units", unit, lay, cp.NUnits)
+	}
+	cats := ss.LvisEnv(etime.Test).CatNames()
+	var cis []int
+	for ci, n := range cp.N {
+		if n > 0 && ci < len(cats) {
+			cis = append(cis, ci)
+		}
+	}
+	tc.ConfigTable(len(cis))
+	for ri, ci := range cis {
+		i := ci*cp.NUnits + unit
+		n := cp.N[ci]
+		mu := cp.Sum[i] / n
+		sd := math.Sqrt(math.Max(cp.SumSqU[i]/n-mu*mu, 0))
+		tc.Table.SetCellString("Cat", ri, cats[ci])
+		tc.Table.SetCellFloat("Act", ri, mu)
+		tc.Table.SetCellFloat("SEM", ri, sd/math.Sqrt(n))
+		tc.Table.SetCellFloat("N", ri, n)
+	}
+	sort.Stable(tuningSorter{&tc.Table})
+	if tc.Plot != nil {
+		tc.Plot.Params.Title = fmt.Sprintf("Tuning Curve: %s unit %d", lay, unit)
+		tc.Plot.GoUpdate()
+	}
+	return nil
+}
+
+// tuningSorter sorts the tuning curve table rows by descending Act
+type tuningSorter struct {
+	dt *etable.Table
+}
+
+func (ts tuningSorter) Len() int { return ts.dt.Rows }
+func (ts tuningSorter) Less(i, j int) bool {
+	return ts.dt.CellFloat("Act", i) > ts.dt.CellFloat("Act", j)
+}
+func (ts tuningSorter) Swap(i, j int) {
+	for _, col := range ts.dt.Cols {
+		if col.DataType() == etensor.STRING {
+			ci, cj := col.StringVal1D(i), col.StringVal1D(j)
+			col.SetString1D(i, cj)
+			col.SetString1D(j, ci)
+		} else {
+			ci, cj := col.FloatVal1D(i), col.FloatVal1D(j)
+			col.SetFloat1D(i, cj)
+			col.SetFloat1D(j, ci)
+		}
+	}
+}
+
+// TuningCurveUpdate updates the tuning curve of the current Tuning unit
+// at the end of each test epoch, when the GUI is active
+func (ss *Sim) TuningCurveUpdate() {
+	if ss.Tuning.Plot == nil {
+		return
+	}
+	ss.TuningCurve(ss.Tuning.Lay, ss.Tuning.Unit)
+}
+
+// TuningCurveGUI plots the tuning curve of given unit in given layer,
+// from the most recent test epoch, or after running the whole test
+// set if full is true
+func (ss *Sim) TuningCurveGUI(lay string, unit int, full bool) {
+	if !full {
+		if err := ss.TuningCurve(lay, unit); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+	if ss.GUI.IsRunning {
+		return
+	}
+	ss.Tuning.Lay = lay
+	ss.Tuning.Unit = unit
+	ss.GUI.IsRunning = true
+	ss.GUI.StopNow = false
+	ss.GUI.ToolBar.UpdateActions()
+	go func() {
+		ss.TestAll() // tuning curve is updated at the end of the test epoch
+		ss.GUI.Stopped()
+	}()
+}
+
+// ConfigTuningGui adds the Tuning plot tab to the GUI
+func (ss *Sim) ConfigTuningGui() {
+	tc := &ss.Tuning
+	tc.ConfigTable(0)
+	plt := ss.GUI.TabView.AddNewTab(eplot.KiT_Plot2D, "Tuning").(*eplot.Plot2D)
+	plt.Params.Title = "Tuning Curve"
+	plt.Params.Type = eplot.Bar
+	plt.Params.XAxisCol = "Cat"
+	plt.SetTable(&tc.Table)
+	cp := plt.SetColParams("Act", eplot.On, eplot.FixMin, 0, eplot.FloatMax, 0)
+	cp.ErrCol = "SEM"
+	tc.Plot = plt
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/emer/emergent/elog"
+	"github.com/emer/emergent/etime"
+	"github.com/emer/etable/agg"
+	"github.com/emer/etable/etensor"
+)
+
+// v1color.go has the configuration of the color channels of the V1 inputs,
+// and the per-trial stats of the actual activity of the V1C color DoG
+// inputs for each channel.  Color enters the network in two ways:
+//
+//   - V1m16, V1l16, V1m8, V1l8 (and V1h16): gabor filters on the
+//     White-Black, Red-Green, and Blue-Yellow opponents, with each pool
+//     having 5 rows of 4 angles: 1 length-sum, 2 end-stop, and 2 simple
+//     (on, off) that are the max over the 3 opponents, or with SepColor,
+//     9 rows: the 2 simple rows are White-Black only, plus 2 Red-Green and
+//     2 Blue-Yellow simple rows.
+//   - V1Cm16, V1Cl16, V1Cm8, V1Cl8: color DoG filters, with 2x2 units per
+//     pool: on, off by Red-Green, Blue-Yellow, with kWTA inhibition over
+//     the pool, or separately per channel with SepKWTA, using RGGi, BYGi.
+//
+// The Config.Env.V1Color params are applied to the filters only if On.
+// With Stats, the V1C_RGAct, V1C_BYAct stats are the average activity of
+// each channel over the 4 V1C inputs, and V1C_RGPctAct, V1C_BYPctAct the
+// proportion of units above ActThr, logged per trial and averaged per
+// epoch, and the V1ColorReport is printed at startup.
+
+// V1ColorStats are the names of the V1 color input activity stats
+func V1ColorStats() []string {
+	var nms []string
+	for _, ch := range ColorChans {
+		nms = append(nms, "V1C_"+ch+"Act", "V1C_"+ch+"PctAct")
+	}
+	return nms
+}
+
+// ColorVises returns the color DoG filters of the env,
+// in the order of the V1C layers
+func (ev *ImagesEnv) ColorVises() []*ColorVis {
+	return []*ColorVis{&ev.V1Cm16, &ev.V1Cl16, &ev.V1Cm8, &ev.V1Cl8}
+}
+
+// Vises returns the V1 gabor filters of the env
+func (ev *ImagesEnv) Vises() []*Vis {
+	return []*Vis{&ev.V1m16, &ev.V1l16, &ev.V1h16, &ev.V1m8, &ev.V1l8}
+}
+
+// ConfigV1Color applies the Config.Env.V1Color params to the filters of
+// given env, if On -- called after Defaults, so the Env params can override
+func (ss *Sim) ConfigV1Color(ev *ImagesEnv) {
+	vc := &ss.Config.Env.V1Color
+	if !vc.On {
+		return
+	}
+	for _, vi := range ev.Vises() {
+		vi.SepColor = vc.SepColor
+	}
+	for _, cv := range ev.ColorVises() {
+		cv.SepKWTA = vc.SepKWTA
+		cv.ChanGi = [2]float32{vc.RGGi, vc.BYGi}
+	}
+}
+
+// V1ColorReport returns a summary of the color channels of the V1 inputs
+// for the given env, with the resulting number of rows per V1 pool
+func V1ColorReport(ev *ImagesEnv) string {
+	var b strings.Builder
+	vi := &ev.V1m16
+	switch {
+	case !vi.Color:
+		fmt.Fprintf(&b, "V1 inputs: 5 rows per pool: 2 White-Black simple, 1 length-sum, 2 end-stop -- Color off\n")
+	case vi.SepColor:
+		fmt.Fprintf(&b, "V1 inputs: 9 rows per pool: 2 White-Black simple, 1 length-sum, 2 end-stop, 2 Red-Green, 2 Blue-Yellow simple -- SepColor, ColorGain: %g\n", vi.ColorGain)
+	default:
+		fmt.Fprintf(&b, "V1 inputs: 5 rows per pool: 2 simple (max over White-Black, Red-Green, Blue-Yellow), 1 length-sum, 2 end-stop -- ColorGain: %g\n", vi.ColorGain)
+	}
+	if !ev.ColorDoG {
+		b.WriteString("V1C color DoG inputs: off\n")
+		return b.String()
+	}
+	cv := &ev.V1Cm16
+	switch {
+	case !cv.KWTA.On:
+		b.WriteString("V1C color DoG inputs: 2x2 per pool (On, Off x Red-Green, Blue-Yellow), no kWTA\n")
+	case cv.SepKWTA:
+		fmt.Fprintf(&b, "V1C color DoG inputs: 2x2 per pool (On, Off x Red-Green, Blue-Yellow), kWTA per channel: Red-Green Gi: %g, Blue-Yellow Gi: %g\n", cv.ChanGi[0], cv.ChanGi[1])
+	default:
+		fmt.Fprintf(&b, "V1C color DoG inputs: 2x2 per pool (On, Off x Red-Green, Blue-Yellow), kWTA over both channels: Gi: %g\n", cv.KWTA.PoolFFFB.Gi)
+	}
+	return b.String()
+}
+
+// V1ColorRecord records the V1C color input activity stats for the
+// current image of given env, for given data index, if Stats
+func (ss *Sim) V1ColorRecord(di int, ev *ImagesEnv) {
+	if !ss.Config.Env.V1Color.Stats || !ev.ColorDoG {
+		return
+	}
+	cvs := ev.ColorVises()
+	for ch, cnm := range ColorChans {
+		var avg, pct float32
+		for _, cv := range cvs {
+			a, p := cv.ChanAct(ch, ss.Config.Env.V1Color.ActThr)
+			avg += a
+			pct += p
+		}
+		n := float64(len(cvs))
+		ss.Stats.SetFloatDi("V1C_"+cnm+"Act", di, float64(avg)/n)
+		ss.Stats.SetFloatDi("V1C_"+cnm+"PctAct", di, float64(pct)/n)
+	}
+}
+
+// ConfigV1ColorLogs adds the V1C color input activity stats to the trial
+// logs, with the epoch averages, if Stats and the color DoG inputs are used
+func (ss *Sim) ConfigV1ColorLogs() {
+	trn := ss.ImagesEnv(etime.Train)
+	if !ss.Config.Env.V1Color.Stats || trn == nil || !trn.ColorDoG {
+		return
+	}
+	for _, st := range V1ColorStats() {
+		stnm := st
+		ss.Logs.AddItem(&elog.Item{
+			Name: stnm,
+			Type: etensor.FLOAT64,
+			Write: elog.WriteMap{
+				etime.Scope(etime.AllModes, etime.Trial): func(ctx *elog.Context) {
+					ctx.SetFloat64(ss.Stats.FloatDi(stnm, ctx.Di))
+				}, etime.Scope(etime.AllModes, etime.Epoch): func(ctx *elog.Context) {
+					ctx.SetAggItem(ctx.Mode, etime.Trial, stnm, agg.AggMean)
+				}}})
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/emer/emergent/etime"
+	"github.com/emer/emergent/weights"
+	"github.com/klauspost/compress/zstd"
+)
+
+// wtsfile.go has the .wtsz weights file format, which has a header
+// with metadata about the run, followed by each layer's standard JSON
+// weights compressed separately (zstd by default), so that specific
+// layers can be read without decompressing the entire file.
+
+// File layout: WtsZMagic, uint32 little-endian length of the header,
+// header JSON (WtsZHeader), then the compressed layer blocks, at the
+// offsets recorded in the header relative to the end of the header.
+
+const (
+	// WtsZExt is the file extension for the .wtsz weights format
+	WtsZExt = ".wtsz"
+
+	// WtsZMagic identifies the .wtsz weights format
+	WtsZMagic = "LVISWTSZ"
+
+	// WtsZVersion is the current version of the .wtsz weights format
+	WtsZVersion = 1
+)
+
+// WtsZLayer records where the compressed weights for one layer are
+type WtsZLayer struct {
+
+	// layer name
+	Layer string
+
+	// offset of the compressed layer weights, relative to the end of the header
+	Offset int64
+
+	// size of the compressed layer weights
+	Size int64
+}
+
+// WtsZHeader is the metadata header of a .wtsz weights file
+type WtsZHeader struct {
+
+	// format version -- see WtsZVersion
+	Version int
+
+	// network name
+	Network string
+
+	// run name, including the params tag
+	RunName string
+
+	// run counter when saved
+	Run int
+
+	// training epoch counter when saved
+	Epoch int
+
+	// hash of the full Config used for the run, for checking that the weights match a given configuration
+	ConfigHash string
+
+	// categories, in order of the output units
+	Cats []string
+
+	// compression used for the layer blocks: zstd, gzip, or none
+	Compress string
+
+	// compression level used
+	Level int
+
+	// network-level metadata, as saved in standard weights files
+	MetaData map[string]string
+
+	// the layers, in order
+	Layers []WtsZLayer
+}
+
+// Layer returns the record for given layer, or nil if not present
+func (wh *WtsZHeader) Layer(name string) *WtsZLayer {
+	for i := range wh.Layers {
+		if wh.Layers[i].Layer == name {
+			return &wh.Layers[i]
+		}
+	}
+	return nil
+}
+
+// ConfigHash returns a hash of the full Config, as a hex string
+func (ss *Sim) ConfigHash() string {
+	b, err := json.Marshal(&ss.Config)
+	if err != nil {
+		return ""
+	}
+	h := fnv.New64a()
+	h.Write(b)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// compressBlock compresses given data using given method and level
+func compressBlock(data []byte, method string, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch method {
+	case "zstd":
+		w, err = zstd.NewWriter(&buf, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	case "gzip":
+		w, err = gzip.NewWriterLevel(&buf, level)
+	case "none", "":
+		return data, nil
+	default:
+		err = fmt.Errorf("unknown weights compression: %q", method)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressReader returns a reader that decompresses r using given method
+func decompressReader(r io.Reader, method string) (io.ReadCloser, error) {
+	switch method {
+	case "zstd":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case "gzip":
+		return gzip.NewReader(r)
+	case "none", "":
+		return io.NopCloser(r), nil
+	}
+	return nil, fmt.Errorf("unknown weights compression: %q", method)
+}
+
+// SaveWtsZ saves the network weights to given file in the .wtsz format,
+// using Config.Log.WtsCompress and WtsLevel compression, with the header
+// metadata recording the current run, epoch, config hash and categories.
+func (ss *Sim) SaveWtsZ(filename string) error {
+	net := ss.Net
+	net.GPU.SyncAllFmGPU()
+	comp := ss.Config.Log.WtsCompress
+	wh := &WtsZHeader{Version: WtsZVersion, Network: net.Name(), RunName: ss.Stats.String("RunName"), Run: ss.Stats.Int("Run"), Epoch: ss.Stats.Int("Epoch"), ConfigHash: ss.ConfigHash(), Compress: comp, Level: ss.Config.Log.WtsLevel, MetaData: net.MetaData}
+	wh.Cats = ss.LvisEnv(etime.Train).CatNames()
+	var blocks [][]byte
+	off := int64(0)
+	var lb bytes.Buffer
+	for _, ly := range net.Layers {
+		if ly.IsOff() {
+			continue
+		}
+		lb.Reset()
+		ly.WriteWtsJSON(&lb, 0)
+		blk, err := compressBlock(lb.Bytes(), comp, wh.Level)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, blk)
+		wh.Layers = append(wh.Layers, WtsZLayer{Layer: ly.Name(), Offset: off, Size: int64(len(blk))})
+		off += int64(len(blk))
+	}
+	hb, err := json.Marshal(wh)
+	if err != nil {
+		return err
+	}
+	fp, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	bw := bufio.NewWriter(fp)
+	bw.WriteString(WtsZMagic)
+	binary.Write(bw, binary.LittleEndian, uint32(len(hb)))
+	bw.Write(hb)
+	for _, blk := range blocks {
+		bw.Write(blk)
+	}
+	return bw.Flush()
+}
+
+// ReadWtsZHeader reads the .wtsz header from given reader, returning
+// the header and the size of the magic and header, which is the base
+// for the layer block offsets
+func ReadWtsZHeader(r io.Reader) (*WtsZHeader, int64, error) {
+	magic := make([]byte, len(WtsZMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, 0, err
+	}
+	if string(magic) != WtsZMagic {
+		return nil, 0, fmt.Errorf("not a .wtsz weights file")
+	}
+	var hlen uint32
+	if err := binary.Read(r, binary.LittleEndian, &hlen); err != nil {
+		return nil, 0, err
+	}
+	hb := make([]byte, hlen)
+	if _, err := io.ReadFull(r, hb); err != nil {
+		return nil, 0, err
+	}
+	wh := &WtsZHeader{}
+	if err := json.Unmarshal(hb, wh); err != nil {
+		return nil, 0, err
+	}
+	if wh.Version > WtsZVersion {
+		return nil, 0, fmt.Errorf(".wtsz weights file version: %d is newer than supported: %d", wh.Version, WtsZVersion)
+	}
+	return wh, int64(len(WtsZMagic)) + 4 + int64(hlen), nil
+}
+
+// OpenWtsZHeader opens just the header of the given .wtsz weights file
+func OpenWtsZHeader(filename string) (*WtsZHeader, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	wh, _, err := ReadWtsZHeader(bufio.NewReader(fp))
+	return wh, err
+}
+
+// ReadWtsZLayer reads and decompresses the weights for given layer record
+// from a .wtsz file, with base the size of the magic and header
+func ReadWtsZLayer(r io.ReaderAt, base int64, wh *WtsZHeader, lz *WtsZLayer) (*weights.Layer, error) {
+	dr, err := decompressReader(io.NewSectionReader(r, base+lz.Offset, lz.Size), wh.Compress)
+	if err != nil {
+		return nil, err
+	}
+	defer dr.Close()
+	lw, err := weights.LayReadJSON(dr)
+	if err == nil && lw == nil {
+		err = fmt.Errorf("layer: %s could not be read", lz.Layer)
+	}
+	return lw, err
+}
+
+// OpenWtsZ opens the network weights from given .wtsz file,
+// for only the given layers if any are specified, reading
+// and decompressing only the blocks for those layers.
+// Layers in the file that are not in the network are skipped with
+// an error returned at the end.  Returns the header.
+func (ss *Sim) OpenWtsZ(filename string, layers ...string) (*WtsZHeader, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	wh, base, err := ReadWtsZHeader(bufio.NewReader(fp))
+	if err != nil {
+		return nil, err
+	}
+	lays := layers
+	if len(lays) == 0 {
+		for _, lz := range wh.Layers {
+			lays = append(lays, lz.Layer)
+		}
+	}
+	var errs []string
+	for _, lnm := range lays {
+		lz := wh.Layer(lnm)
+		if lz == nil {
+			errs = append(errs, fmt.Sprintf("layer: %s not in weights file", lnm))
+			continue
+		}
+		ly, err := ss.Net.LayerByNameTry(lnm)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		lw, err := ReadWtsZLayer(fp, base, wh, lz)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if err := ly.SetWts(lw); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if wh.MetaData != nil {
+		if ss.Net.MetaData == nil {
+			ss.Net.MetaData = make(map[string]string)
+		}
+		for k, v := range wh.MetaData {
+			ss.Net.MetaData[k] = v
+		}
+	}
+	ss.Net.GPU.SyncAllToGPU()
+	if len(errs) > 0 {
+		return wh, fmt.Errorf("OpenWtsZ: %s: %s", filename, strings.Join(errs, "; "))
+	}
+	return wh, nil
+}
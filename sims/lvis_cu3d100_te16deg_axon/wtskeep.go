@@ -0,0 +1,118 @@
+// Copyright (c) 2023, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"os"
+
+	"github.com/emer/emergent/etime"
+	"github.com/emer/empi/mpi"
+)
+
+// wtskeep.go has the retention policy for the weights saved during a run:
+// at the Config.Log.WtsEpochs and every WtsInterval epochs, keeping only
+// the last WtsKeep of these, plus the best weights by the WtsBest test stat.
+// Superseded files, and their split manifest sidecars, are deleted.
+
+// WtsSaved records the weights files saved during the current run
+type WtsSaved struct {
+
+	// periodic weights files, in order saved
+	Files []string
+
+	// current best weights file
+	Best string
+
+	// value of the WtsBest stat for the Best weights
+	BestVal float64
+}
+
+// Init resets the record for a new run, so the files saved
+// in prior runs are not affected
+func (ws *WtsSaved) Init() {
+	ws.Files = nil
+	ws.Best = ""
+	ws.BestVal = math.NaN()
+}
+
+// RemoveWtsFile removes given weights file and its split manifest
+func RemoveWtsFile(fnm string) {
+	if err := os.Remove(fnm); err != nil {
+		mpi.Println(err)
+		return
+	}
+	os.Remove(SplitManifestFileName(fnm)) // may not exist
+	mpi.Printf("Removed superseded weights: %s\n", fnm)
+}
+
+// SaveWeightsKeep saves the weights during the run, and deletes the
+// oldest ones saved during the run beyond Config.Log.WtsKeep
+func (ss *Sim) SaveWeightsKeep() {
+	fnm := ss.SaveWeightsTag("")
+	if fnm == "" {
+		return
+	}
+	ws := &ss.WtsSaved
+	ws.Files = append(ws.Files, fnm)
+	keep := ss.Config.Log.WtsKeep
+	if keep <= 0 {
+		return
+	}
+	for len(ws.Files) > keep {
+		RemoveWtsFile(ws.Files[0])
+		ws.Files = ws.Files[1:]
+	}
+}
+
+// SaveWeightsInterval saves the weights every Config.Log.WtsInterval
+// training epochs -- called at the start of the epoch
+func (ss *Sim) SaveWeightsInterval() {
+	intv := ss.Config.Log.WtsInterval
+	epc := ss.Loops.GetLoop(etime.Train, etime.Epoch).Counter.Cur
+	if intv <= 0 || epc == 0 || epc%intv != 0 {
+		return
+	}
+	for _, we := range ss.Config.Log.WtsEpochs {
+		if we == epc { // already saved
+			return
+		}
+	}
+	ss.SaveWeightsKeep()
+}
+
+// SaveWeightsBest saves the weights whenever the Config.Log.WtsBest test
+// epoch stat improves, replacing the prior best weights -- called at the
+// end of the test epoch, after Log
+func (ss *Sim) SaveWeightsBest() {
+	stat := ss.Config.Log.WtsBest
+	if stat == "" || !ss.Config.Log.SaveWts || ss.MPIRank() != 0 {
+		return
+	}
+	dt := ss.Logs.Table(etime.Test, etime.Epoch)
+	if dt.Rows == 0 {
+		return
+	}
+	if dt.ColByName(stat) == nil {
+		mpi.Printf("SaveWeightsBest: WtsBest stat: %s not found in the test epoch log\n", stat)
+		ss.Config.Log.WtsBest = ""
+		return
+	}
+	val := dt.CellFloat(stat, dt.Rows-1)
+	ws := &ss.WtsSaved
+	if !math.IsNaN(ws.BestVal) && ((ss.Config.Log.WtsBestMax && val <= ws.BestVal) || (!ss.Config.Log.WtsBestMax && val >= ws.BestVal)) {
+		return
+	}
+	fnm := ss.SaveWeightsTag("_best")
+	if fnm == "" {
+		return
+	}
+	if ws.Best != "" && ws.Best != fnm {
+		RemoveWtsFile(ws.Best)
+	}
+	ws.Best = fnm
+	ws.BestVal = val
+	mpi.Printf("Saved best weights by %s: %g\n", stat, val)
+}